@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/server/restapi/services"
+)
+
+// Names of the apps served besides the root agent
+const (
+	appCodePipeline = "CodePipelineAgent"
+	appBugFix       = "BugFixPipeline"
+	appChat         = "ChatAgent"
+	appPRReview     = "PRReviewAgent"
+)
+
+// newRootAgent creates the configured root agent: the task router, or the agent of the pipeline mode
+func newRootAgent(config agents.PipelineConfig, taskRouter bool) (agent.Agent, error) {
+	// The task router sends bug fixes, refactorings, docs, and questions to shorter pipelines
+	if taskRouter {
+		return agents.NewTaskRouterAgent(config)
+	}
+	return agents.NewAgent(config)
+}
+
+// appNames are the apps served besides the root agent, in the order they are created
+var appNames = []string{appCodePipeline, appBugFix, appChat, appPRReview}
+
+// newApp creates the app called name, one of appNames, with config. The app takes its
+// default name and description.
+func newApp(name string, config agents.PipelineConfig) (agent.Agent, error) {
+	c := config
+	c.Name, c.Description = "", ""
+	c.Progress = nil
+	// Each app checkpoints separately, as its stages differ
+	if c.CheckpointDir != "" {
+		c.CheckpointDir = filepath.Join(c.CheckpointDir, name)
+	}
+	switch name {
+	case appCodePipeline:
+		c.Mode = agents.ModePipeline
+		return agents.NewCodePipelineAgent(c)
+	case appBugFix:
+		c.Mode = agents.ModePipeline
+		return agents.NewBugFixPipeline(c)
+	case appChat:
+		c.Mode = agents.ModeChat
+		return agents.NewChatAgent(c)
+	case appPRReview:
+		return agents.NewPRReviewAgent(agents.PRReviewConfig{Model: config.Model, RepoDir: cmp.Or(config.WorkspaceDir, tools.DefaultWorkspaceDir)})
+	}
+	return nil, fmt.Errorf("unknown app %q", name)
+}
+
+// newReplayAgent creates the agent of app, the root agent called rootName or one of appNames,
+// with config for a replay of one of its runs with llm in workspaceDir. Every stage uses llm,
+// and the replay does not resume from the checkpoints of the run.
+func newReplayAgent(app string, config agents.PipelineConfig, llm model.LLM, workspaceDir, rootName string, taskRouter bool) (agent.Agent, error) {
+	config.Model, config.Models, config.DesignModels = llm, nil, nil
+	config.ModelRouting = agents.ModelRouting{}
+	config.WorkspaceDir, config.CheckpointDir = workspaceDir, ""
+	if app == rootName {
+		return newRootAgent(config, taskRouter)
+	}
+	return newApp(app, config)
+}
+
+// newAgentLoader serves rootAgent as the default app, along with the code pipeline, the bug
+// fix pipeline, the chat agent, and the pull-request review agent of the workspace as apps
+// named after them. An app named like rootAgent is left out, since rootAgent serves it, and
+// so is the review agent until the workspace exists, since it reviews the checkout there.
+func newAgentLoader(config agents.PipelineConfig, rootAgent agent.Agent) (services.AgentLoader, error) {
+	workspaceDir := cmp.Or(config.WorkspaceDir, tools.DefaultWorkspaceDir)
+	others := make([]agent.Agent, 0, len(appNames))
+	for _, name := range appNames {
+		if name == rootAgent.Name() {
+			continue
+		}
+		if name == appPRReview {
+			if info, err := os.Stat(workspaceDir); err != nil || !info.IsDir() {
+				log.Printf("Not serving %s: workspace %s is not a directory", appPRReview, workspaceDir)
+				continue
+			}
+		}
+		ag, err := newApp(name, config)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create app %s: %w", name, err)
+		}
+		others = append(others, ag)
+	}
+	loader, err := services.NewMultiAgentLoader(rootAgent, others...)
+	if err != nil {
+		return nil, err
+	}
+	names := loader.ListAgents()
+	slices.Sort(names)
+	log.Printf("Serving apps %v with default %s", names, rootAgent.Name())
+	return loader, nil
+}
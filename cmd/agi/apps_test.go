@@ -0,0 +1,101 @@
+package main
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestNewAgentLoader(t *testing.T) {
+	tests := []struct {
+		name       string
+		mode       string
+		taskRouter bool
+		missingDir bool
+		wantRoot   string
+		wantApps   []string
+	}{
+		{
+			name:     "pipeline",
+			wantRoot: "CodePipelineAgent",
+			wantApps: []string{"BugFixPipeline", "ChatAgent", "CodePipelineAgent", "PRReviewAgent"},
+		},
+		{
+			name:     "chat",
+			mode:     agents.ModeChat,
+			wantRoot: "ChatAgent",
+			wantApps: []string{"BugFixPipeline", "ChatAgent", "CodePipelineAgent", "PRReviewAgent"},
+		},
+		{
+			name:       "task router",
+			taskRouter: true,
+			wantRoot:   "TaskRouterAgent",
+			wantApps:   []string{"BugFixPipeline", "ChatAgent", "CodePipelineAgent", "PRReviewAgent", "TaskRouterAgent"},
+		},
+		{
+			name:       "missing workspace",
+			missingDir: true,
+			wantRoot:   "CodePipelineAgent",
+			wantApps:   []string{"BugFixPipeline", "ChatAgent", "CodePipelineAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := agents.PipelineConfig{Model: fake.New("fake-model"), WorkspaceDir: t.TempDir(), Mode: tt.mode}
+			if tt.missingDir {
+				config.WorkspaceDir = filepath.Join(config.WorkspaceDir, "missing")
+			}
+			rootAgent, err := newRootAgent(config, tt.taskRouter)
+			if err != nil {
+				t.Fatalf("newRootAgent() error = %v", err)
+			}
+
+			loader, err := newAgentLoader(config, rootAgent)
+			if err != nil {
+				t.Fatalf("newAgentLoader() error = %v", err)
+			}
+			if got := loader.RootAgent().Name(); got != tt.wantRoot {
+				t.Errorf("RootAgent() = %s, want %s", got, tt.wantRoot)
+			}
+			apps := loader.ListAgents()
+			slices.Sort(apps)
+			if !slices.Equal(apps, tt.wantApps) {
+				t.Errorf("ListAgents() = %v, want %v", apps, tt.wantApps)
+			}
+			for _, app := range tt.wantApps {
+				if ag, err := loader.LoadAgent(app); err != nil || ag.Name() != app {
+					t.Errorf("LoadAgent(%s) = %v, %v", app, ag, err)
+				}
+			}
+		})
+	}
+}
+
+func TestNewReplayAgent(t *testing.T) {
+	tests := []struct {
+		app     string
+		want    string
+		wantErr bool
+	}{
+		{app: "CodePipelineAgent", want: "CodePipelineAgent"},
+		{app: "TaskRouterAgent", want: "TaskRouterAgent"},
+		{app: "BugFixPipeline", want: "BugFixPipeline"},
+		{app: "Unknown", wantErr: true},
+	}
+	config := agents.PipelineConfig{Model: fake.New("fake-model"), WorkspaceDir: t.TempDir(), CheckpointDir: t.TempDir()}
+	for _, tt := range tests {
+		t.Run(tt.app, func(t *testing.T) {
+			ag, err := newReplayAgent(tt.app, config, fake.New("replay-model"), t.TempDir(), "TaskRouterAgent", true)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newReplayAgent() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && ag.Name() != tt.want {
+				t.Errorf("newReplayAgent() = %s, want %s", ag.Name(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+)
+
+// authRealm is the realm of the authentication challenges of the web server
+const authRealm = "agi"
+
+// clientKey is the context key of the client a request was authenticated as
+type clientKey struct{}
+
+// requestClient returns the client the request of ctx was authenticated as, such as
+// key:1a2b3c4d5e6f for an API key or oidc:<subject> for a token, or "" without authentication
+func requestClient(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+	return client
+}
+
+// authenticator is the authentication middleware of the web server. It accepts requests
+// with one of the static API keys or with a bearer token of the OIDC provider.
+type authenticator struct {
+	// apiKeys are the SHA-256 digests of the API keys, compared in constant time
+	apiKeys [][sha256.Size]byte
+	// oidc validates the OIDC bearer tokens, nil without a provider
+	oidc *oidcVerifier
+}
+
+// newAuthenticator returns the authenticator of config, or nil when config enables no
+// authentication
+func newAuthenticator(config AuthConfig) *authenticator {
+	if len(config.APIKeys) == 0 && config.OIDCIssuer == "" {
+		return nil
+	}
+	a := &authenticator{}
+	for _, key := range config.APIKeys {
+		a.apiKeys = append(a.apiKeys, sha256.Sum256([]byte(key)))
+	}
+	if config.OIDCIssuer != "" {
+		a.oidc = newOIDCVerifier(config.OIDCIssuer, config.OIDCAudience)
+	}
+	return a
+}
+
+// wrap returns next behind the authenticator; requests without valid credentials get 401
+// Unauthorized. A nil authenticator lets every request through.
+func (a *authenticator) wrap(next http.Handler) http.Handler {
+	if a == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// CORS preflight requests carry no credentials
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if client, ok := a.authenticate(r); ok {
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientKey{}, client)))
+			return
+		}
+		if a.apiKeys != nil {
+			// Browsers prompt for Basic credentials, so the web UI can send an API key as the password
+			w.Header().Add("WWW-Authenticate", `Basic realm="`+authRealm+`"`)
+		}
+		w.Header().Add("WWW-Authenticate", `Bearer realm="`+authRealm+`"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+}
+
+// authenticate reports whether r carries an API key, as a bearer token, in the X-API-Key
+// header, or as a Basic password, or a valid OIDC bearer token, and returns the client it
+// identifies; API keys are identified by a prefix of their digest, never by the key itself
+func (a *authenticator) authenticate(r *http.Request) (string, bool) {
+	bearer := ""
+	if scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+		bearer = strings.TrimSpace(token)
+	}
+	key := r.Header.Get("X-API-Key")
+	if _, password, ok := r.BasicAuth(); ok && key == "" {
+		key = password
+	}
+	if key == "" {
+		key = bearer
+	}
+	if key != "" {
+		if digest, ok := a.validAPIKey(key); ok {
+			return "key:" + hex.EncodeToString(digest[:6]), true
+		}
+	}
+	if a.oidc == nil || bearer == "" {
+		return "", false
+	}
+	subject, err := a.oidc.verify(r.Context(), bearer)
+	if err != nil {
+		log.Printf("Rejected bearer token for %s %s: %v", r.Method, r.URL.Path, err)
+		return "", false
+	}
+	return "oidc:" + subject, true
+}
+
+// validAPIKey reports whether key is one of the API keys, in a time that does not depend on
+// which key or how much of it matches, and returns its digest
+func (a *authenticator) validAPIKey(key string) ([sha256.Size]byte, bool) {
+	digest := sha256.Sum256([]byte(key))
+	valid := 0
+	for _, apiKey := range a.apiKeys {
+		valid |= subtle.ConstantTimeCompare(digest[:], apiKey[:])
+	}
+	return digest, valid == 1
+}
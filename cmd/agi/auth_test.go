@@ -0,0 +1,85 @@
+package main
+
+import (
+	"cmp"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAuthenticator(t *testing.T) {
+	p := newTestProvider(t)
+	a := newAuthenticator(AuthConfig{APIKeys: []string{"key-1", "key-2"}, OIDCIssuer: p.server.URL, OIDCAudience: "agi"})
+	handler := a.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Client", requestClient(r.Context()))
+	}))
+
+	tests := []struct {
+		name       string
+		method     string
+		header     map[string]string
+		basic      string
+		wantStatus int
+		wantClient string
+	}{
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+		{name: "API key as bearer token", header: map[string]string{"Authorization": "Bearer key-1"}, wantStatus: http.StatusOK, wantClient: "key:"},
+		{name: "lowercase scheme", header: map[string]string{"Authorization": "bearer key-2"}, wantStatus: http.StatusOK},
+		{name: "API key header", header: map[string]string{"X-API-Key": "key-2"}, wantStatus: http.StatusOK},
+		{name: "API key as Basic password", basic: "key-1", wantStatus: http.StatusOK},
+		{name: "wrong API key", header: map[string]string{"X-API-Key": "key-3"}, wantStatus: http.StatusUnauthorized},
+		{name: "prefix of an API key", header: map[string]string{"Authorization": "Bearer key"}, wantStatus: http.StatusUnauthorized},
+		{name: "OIDC token", header: map[string]string{"Authorization": "Bearer " + p.token(t, "RS256", "rsa", p.claims(nil))}, wantStatus: http.StatusOK, wantClient: "oidc:dev@example.com"},
+		{name: "expired OIDC token", header: map[string]string{"Authorization": "Bearer " + p.token(t, "RS256", "rsa", p.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}))}, wantStatus: http.StatusUnauthorized},
+		{name: "OIDC token in the API key header", header: map[string]string{"X-API-Key": p.token(t, "RS256", "rsa", p.claims(nil))}, wantStatus: http.StatusUnauthorized},
+		{name: "CORS preflight", method: http.MethodOptions, header: map[string]string{"Access-Control-Request-Method": "POST"}, wantStatus: http.StatusOK},
+		{name: "OPTIONS without preflight", method: http.MethodOptions, wantStatus: http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(cmp.Or(tt.method, http.MethodPost), "/api/run", nil)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			if tt.basic != "" {
+				req.SetBasicAuth("agi", tt.basic)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if client := rec.Header().Get("X-Client"); !strings.HasPrefix(client, tt.wantClient) || strings.Contains(client, "key-1") {
+				t.Errorf("client = %q, want %q", client, tt.wantClient)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				challenges := strings.Join(rec.Header().Values("WWW-Authenticate"), ", ")
+				if !strings.Contains(challenges, `Basic realm="agi"`) || !strings.Contains(challenges, `Bearer realm="agi"`) {
+					t.Errorf("WWW-Authenticate = %q, want Basic and Bearer challenges", challenges)
+				}
+			}
+		})
+	}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	if a := newAuthenticator(AuthConfig{}); a != nil {
+		t.Fatalf("newAuthenticator() without credentials = %+v, want nil", a)
+	}
+	var open *authenticator
+	rec := httptest.NewRecorder()
+	open.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/run", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("open server status = %d, want 200", rec.Code)
+	}
+
+	// Servers with only OIDC do not ask browsers for Basic credentials
+	oidcOnly := newAuthenticator(AuthConfig{OIDCIssuer: "https://accounts.example.com", OIDCAudience: "agi"})
+	rec = httptest.NewRecorder()
+	oidcOnly.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/list-apps", nil))
+	if got := rec.Header().Values("WWW-Authenticate"); rec.Code != http.StatusUnauthorized || len(got) != 1 || got[0] != `Bearer realm="agi"` {
+		t.Errorf("OIDC-only server = %d %q, want 401 with a Bearer challenge", rec.Code, got)
+	}
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// chatCommand is the launcher argument that starts the interactive terminal instead of a launcher
+const chatCommand = "chat"
+
+// maxToolArgs caps the length of the tool call arguments shown in the terminal
+const maxToolArgs = 120
+
+// chatHelp lists the commands of the interactive terminal
+const chatHelp = `Commands:
+  /new   start a new session
+  /help  show this help
+  /exit  quit (or Ctrl-D)
+`
+
+// chatTerminal shows a conversation with the agent in the terminal: the streamed replies,
+// the tool calls and their results, the progress of each pipeline stage, and the token
+// counts. It is the progress listener of the pipeline and receives the events of each turn.
+type chatTerminal struct {
+	mu  sync.Mutex
+	out io.Writer
+	// author is the agent whose text the current line continues, or "" at the start of a line
+	author string
+	// streamed is the partial text of author printed since its last complete response
+	streamed string
+	// turn is the token usage of the current turn
+	turn agents.TokenUsage
+	// stages is the token usage of each stage of the current turn
+	stages map[string]int
+}
+
+// newChatTerminal creates a terminal that writes to out
+func newChatTerminal(out io.Writer) *chatTerminal {
+	return &chatTerminal{out: out, stages: map[string]int{}}
+}
+
+// StageStarted implements agents.ProgressListener
+func (t *chatTerminal) StageStarted(stage string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.println("▸ %s", stage)
+}
+
+// StageCompleted implements agents.ProgressListener
+func (t *chatTerminal) StageCompleted(stage string, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.println("✗ %s: %v", stage, err)
+		return
+	}
+	t.println("✓ %s · %d tokens", stage, t.stages[stage])
+}
+
+// TokensUsed implements agents.ProgressListener
+func (t *chatTerminal) TokensUsed(stage, agent string, usage agents.TokenUsage) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stages[stage] += usage.TotalTokens
+}
+
+// FileWritten implements agents.ProgressListener; the fileWrite results already show the files
+func (t *chatTerminal) FileWritten(stage, agent, path string) {}
+
+// event shows the text, tool calls, and tool results of event and counts its tokens
+func (t *chatTerminal) event(event *session.Event) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if usage := event.UsageMetadata; usage != nil && !event.Partial {
+		t.turn.PromptTokens += int(usage.PromptTokenCount)
+		t.turn.CompletionTokens += int(usage.CandidatesTokenCount)
+		t.turn.TotalTokens += int(usage.TotalTokenCount)
+	}
+	if event.Content == nil {
+		return
+	}
+	for _, part := range event.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			t.println("  → %s %s", part.FunctionCall.Name, toolArgs(part.FunctionCall.Args))
+		case part.FunctionResponse != nil:
+			t.println("  ← %s %s", part.FunctionResponse.Name, toolResult(part.FunctionResponse.Response))
+		case part.Text != "" && !part.Thought:
+			t.text(event.Author, part.Text, event.Partial)
+		}
+	}
+	if !event.Partial && event.Author == t.author {
+		t.streamed = ""
+	}
+}
+
+// text prints the reply text of author. A complete response after streamed parts either
+// repeats them, in which case only the rest is printed, or is the last part of the stream.
+func (t *chatTerminal) text(author, text string, partial bool) {
+	if !partial && t.streamed != "" && author == t.author {
+		text = strings.TrimPrefix(text, t.streamed)
+	}
+	if author != t.author {
+		t.endLine()
+		t.streamed = ""
+		fmt.Fprintf(t.out, "[%s] ", author)
+		t.author = author
+	}
+	fmt.Fprint(t.out, text)
+	if partial {
+		t.streamed += text
+	}
+}
+
+// turnCompleted shows the duration and token usage of the turn that ended with err and
+// resets the counts for the next turn
+func (t *chatTerminal) turnCompleted(duration time.Duration, err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		t.println("✗ %v", err)
+	}
+	t.println("── %s · %d tokens (prompt %d, completion %d)", duration.Round(100*time.Millisecond),
+		t.turn.TotalTokens, t.turn.PromptTokens, t.turn.CompletionTokens)
+	t.turn = agents.TokenUsage{}
+	t.stages = map[string]int{}
+}
+
+// println prints a status line, ending the reply text in progress first
+func (t *chatTerminal) println(format string, args ...any) {
+	t.endLine()
+	fmt.Fprintf(t.out, format+"\n", args...)
+}
+
+// endLine ends the reply text in progress, if any
+func (t *chatTerminal) endLine() {
+	if t.author != "" {
+		fmt.Fprintln(t.out)
+		t.author, t.streamed = "", ""
+	}
+}
+
+// toolArgs formats the arguments of a tool call, shortened to maxToolArgs characters
+func toolArgs(args map[string]any) string {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return fmt.Sprint(args)
+	}
+	if s := []rune(string(data)); len(s) > maxToolArgs {
+		return string(s[:maxToolArgs]) + "…"
+	}
+	return string(data)
+}
+
+// toolResult summarizes the result of a tool call as ok or its error
+func toolResult(response map[string]any) string {
+	if msg, _ := response["error"].(string); msg != "" {
+		return "error: " + msg
+	}
+	if success, ok := response["success"].(bool); ok && !success {
+		return "failed"
+	}
+	return "ok"
+}
+
+// runChat reads requests from in and sends each to rootAgent in one session, showing the
+// replies on terminal, until in ends, the user types /exit, or ctx is canceled
+func runChat(ctx context.Context, rootAgent agent.Agent, terminal *chatTerminal, in io.Reader) error {
+	r, sessionService, err := newLocalRunner(rootAgent)
+	if err != nil {
+		return err
+	}
+	sessionID, err := newLocalSession(ctx, sessionService)
+	if err != nil {
+		return err
+	}
+
+	// Lines are read in the background so that ctx can end the chat while it waits for input
+	lines := make(chan string)
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(in)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	fmt.Fprintf(terminal.out, "Chatting with %s. Type /help for commands.\n", rootAgent.Name())
+	for {
+		fmt.Fprint(terminal.out, "> ")
+		var line string
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(terminal.out)
+			return nil
+		case l, ok := <-lines:
+			if !ok {
+				fmt.Fprintln(terminal.out)
+				return nil
+			}
+			line = strings.TrimSpace(l)
+		}
+
+		switch line {
+		case "":
+			continue
+		case "/exit", "/quit":
+			return nil
+		case "/help":
+			fmt.Fprint(terminal.out, chatHelp)
+			continue
+		case "/new":
+			if sessionID, err = newLocalSession(ctx, sessionService); err != nil {
+				return err
+			}
+			fmt.Fprintln(terminal.out, "Started a new session")
+			continue
+		}
+
+		start := time.Now()
+		var turnErr error
+		msg := genai.NewContentFromText(line, genai.RoleUser)
+		for event, err := range r.Run(ctx, headlessUser, sessionID, msg, agent.RunConfig{StreamingMode: agent.StreamingModeSSE}) {
+			if err != nil {
+				if turnErr == nil {
+					turnErr = err
+				}
+				continue
+			}
+			terminal.event(event)
+		}
+		terminal.turnCompleted(time.Since(start), turnErr)
+	}
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// textEvent returns an event of author with text
+func textEvent(author, text string, partial bool) *session.Event {
+	return &session.Event{
+		Author:      author,
+		LLMResponse: model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel), Partial: partial},
+	}
+}
+
+// partsEvent returns an event of author with parts
+func partsEvent(author string, parts ...*genai.Part) *session.Event {
+	return &session.Event{Author: author, LLMResponse: model.LLMResponse{Content: &genai.Content{Role: genai.RoleModel, Parts: parts}}}
+}
+
+func TestChatTerminal(t *testing.T) {
+	tests := []struct {
+		name   string
+		events []*session.Event
+		want   string
+	}{
+		{
+			name:   "complete text",
+			events: []*session.Event{textEvent("ChatAgent", "Hello!", false)},
+			want:   "[ChatAgent] Hello!",
+		},
+		{
+			name: "streamed text repeated by the complete response",
+			events: []*session.Event{
+				textEvent("ChatAgent", "Hel", true),
+				textEvent("ChatAgent", "lo", true),
+				textEvent("ChatAgent", "Hello!", false),
+			},
+			want: "[ChatAgent] Hello!",
+		},
+		{
+			name: "streamed text ended by its last part",
+			events: []*session.Event{
+				textEvent("ChatAgent", "Hel", true),
+				textEvent("ChatAgent", "lo!", false),
+			},
+			want: "[ChatAgent] Hello!",
+		},
+		{
+			name: "authors and tool calls",
+			events: []*session.Event{
+				textEvent("DesignAgent", "design", false),
+				partsEvent("CodeWriterAgent", &genai.Part{FunctionCall: &genai.FunctionCall{Name: "fileWrite", Args: map[string]any{"path": "calc.go"}}}),
+				partsEvent("CodeWriterAgent",
+					&genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "fileWrite", Response: map[string]any{"success": true}}},
+					&genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "goBuild", Response: map[string]any{"error": "no go.mod"}}}),
+				textEvent("CodeWriterAgent", "wrote calc.go", false),
+			},
+			want: "[DesignAgent] design\n" +
+				"  → fileWrite {\"path\":\"calc.go\"}\n" +
+				"  ← fileWrite ok\n" +
+				"  ← goBuild error: no go.mod\n" +
+				"[CodeWriterAgent] wrote calc.go",
+		},
+		{
+			name:   "long tool arguments",
+			events: []*session.Event{partsEvent("ChatAgent", &genai.Part{FunctionCall: &genai.FunctionCall{Name: "fileWrite", Args: map[string]any{"content": strings.Repeat("x", 200)}}})},
+			want:   "  → fileWrite {\"content\":\"" + strings.Repeat("x", maxToolArgs-12) + "…\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			terminal := newChatTerminal(&out)
+			for _, event := range tt.events {
+				terminal.event(event)
+			}
+			if out.String() != tt.want {
+				t.Errorf("output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestChatTerminalProgress(t *testing.T) {
+	var out strings.Builder
+	terminal := newChatTerminal(&out)
+	terminal.StageStarted("DesignAgent")
+	terminal.event(textEvent("DesignAgent", "design", true))
+	terminal.TokensUsed("DesignAgent", "DesignAgent", agents.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120})
+	terminal.event(&session.Event{Author: "DesignAgent", LLMResponse: model.LLMResponse{
+		UsageMetadata: &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 20, TotalTokenCount: 120},
+	}})
+	terminal.StageCompleted("DesignAgent", nil)
+	terminal.StageStarted("CodeWriterAgent")
+	terminal.StageCompleted("CodeWriterAgent", errors.New("timed out"))
+	terminal.turnCompleted(1500*time.Millisecond, nil)
+	terminal.turnCompleted(time.Second, errors.New("canceled"))
+
+	want := "▸ DesignAgent\n" +
+		"[DesignAgent] design\n" +
+		"✓ DesignAgent · 120 tokens\n" +
+		"▸ CodeWriterAgent\n" +
+		"✗ CodeWriterAgent: timed out\n" +
+		"── 1.5s · 120 tokens (prompt 100, completion 20)\n" +
+		"✗ canceled\n" +
+		"── 1s · 0 tokens (prompt 0, completion 0)\n"
+	if out.String() != want {
+		t.Errorf("output = %q, want %q", out.String(), want)
+	}
+}
+
+func TestRunChat(t *testing.T) {
+	llm := fake.New("fake-model",
+		fake.Stream("Hel", "lo!"),
+		fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": "package calc\n"}),
+		fake.Text("wrote calc.go"),
+	)
+	rootAgent, err := agents.NewAgent(agents.PipelineConfig{Model: llm, WorkspaceDir: t.TempDir(), Mode: agents.ModeChat})
+	if err != nil {
+		t.Fatalf("NewAgent() error = %v", err)
+	}
+
+	var out strings.Builder
+	in := strings.NewReader("hi\n\n/help\nwrite calc.go\n/new\n/exit\nnever sent\n")
+	if err := runChat(context.Background(), rootAgent, newChatTerminal(&out), in); err != nil {
+		t.Fatalf("runChat() error = %v", err)
+	}
+	for _, want := range []string{
+		"Chatting with ChatAgent",
+		"> [ChatAssistantAgent] Hello!\n── ",
+		"Commands:",
+		"  → fileWrite {",
+		"  ← fileWrite ok\n[ChatAssistantAgent] wrote calc.go\n── ",
+		"Started a new session",
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output = %q, want it to contain %q", out.String(), want)
+		}
+	}
+	if got := len(llm.Requests()); got != 3 {
+		t.Errorf("model requests = %d, want 3", got)
+	}
+}
@@ -0,0 +1,471 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"gopkg.in/yaml.v3"
+)
+
+// defaultConfigFile is the configuration file read from the working directory when AGI_CONFIG is not set
+const defaultConfigFile = "agi.yaml"
+
+// Model providers accepted in ModelConfig.Provider
+const (
+	providerOllama = "ollama"
+	providerTGI    = "tgi"
+	providerGemini = "gemini"
+)
+
+// Defaults of the model configuration
+const (
+	defaultModelName       = "gpt-oss:120b-cloud"
+	defaultOllamaBaseURL   = "http://localhost:11434"
+	defaultGeminiModelName = "gemini-2.5-flash"
+)
+
+// Launchers accepted in ServerConfig.Launcher
+const (
+	launcherConsole = "console"
+	launcherWeb     = "web"
+)
+
+// Config is the configuration of the agi command, read from a YAML or JSON file and
+// overridden by environment variables
+type Config struct {
+	// Model is the model of every stage the model routing does not send elsewhere
+	Model ModelConfig `yaml:"model"`
+	// WorkspaceDir is the directory the agents work in (defaults to ./workspace)
+	WorkspaceDir string `yaml:"workspace_dir"`
+	// ReadOnly takes the tools that write files or run commands away from every agent
+	ReadOnly bool `yaml:"read_only"`
+	// SymlinkPolicy is follow or deny (defaults to follow)
+	SymlinkPolicy string `yaml:"symlink_policy"`
+	// TaskRouter classifies each request and routes it to a matching pipeline
+	TaskRouter bool `yaml:"task_router"`
+	// PipelineFile is a pipeline configuration file that replaces Pipeline, relative to this file
+	PipelineFile string `yaml:"pipeline_file"`
+	// Pipeline holds the pipeline options, with the keys of a pipeline configuration file
+	Pipeline agents.PipelineConfig `yaml:"pipeline"`
+	// Server selects how the agent is served
+	Server ServerConfig `yaml:"server"`
+}
+
+// ModelConfig selects the model and where it is served
+type ModelConfig struct {
+	// Provider is ollama, tgi, or gemini (defaults to ollama)
+	Provider string `yaml:"provider"`
+	// Name is the model name: an Ollama model tag, the display name of the TGI model, or a Gemini model
+	Name string `yaml:"name"`
+	// BaseURL is the model server endpoint (defaults to the provider's local endpoint)
+	BaseURL string `yaml:"base_url"`
+	// Token is the bearer token of a TGI endpoint, such as a Hugging Face Inference Endpoint,
+	// or the Gemini API key (defaults to GOOGLE_API_KEY or GEMINI_API_KEY for Gemini)
+	Token string `yaml:"token"`
+	// Options are the sampling options, such as temperature and top_p, with the names of the provider's API
+	Options map[string]any `yaml:"options"`
+	// Warmup preloads the model at startup (defaults to true)
+	Warmup bool `yaml:"warmup"`
+}
+
+// ServerConfig selects the launcher used when the command runs without arguments, and
+// the TLS, authentication, run limits, metrics and profiling endpoints, session storage, and
+// graceful shutdown of the web server
+type ServerConfig struct {
+	// Launcher is console or web (defaults to console)
+	Launcher string `yaml:"launcher"`
+	// Port is the port of the web server (defaults to 8080)
+	Port int `yaml:"port"`
+	// Services are the web sub-servers to start: api, webui, and a2a (defaults to api and webui)
+	Services []string `yaml:"services"`
+	// Metrics serves the Prometheus metrics of the models, tools, and pipeline stages at /metrics of the web server
+	Metrics bool `yaml:"metrics"`
+	// PprofAddress is the admin address, such as localhost:6060, that serves the net/http/pprof
+	// endpoints in every mode (default: none)
+	PprofAddress string `yaml:"pprof_address"`
+	// DrainTimeout is how long the web server waits on shutdown for the runs in progress, which
+	// stop after their current stage, before it cancels them (defaults to 30s)
+	DrainTimeout time.Duration `yaml:"drain_timeout"`
+	// SessionDB is the SQLite database that stores the sessions, so they survive restarts
+	// (default: none, sessions are kept in memory)
+	SessionDB string `yaml:"session_db"`
+	// HistoryDir keeps a record of each pipeline run there, for the run history and replay API
+	// (default: none)
+	HistoryDir string `yaml:"history_dir"`
+	// Auth requires credentials for every request to the web server (default: none)
+	Auth AuthConfig `yaml:"auth"`
+	// TLS serves the web server over HTTPS (default: plain HTTP)
+	TLS TLSConfig `yaml:"tls"`
+	// Limits caps the runs of the web server (default: none)
+	Limits LimitsConfig `yaml:"limits"`
+}
+
+// LimitsConfig caps the runs of the web server, so the clients share the model backend; zero
+// disables a limit
+type LimitsConfig struct {
+	// MaxConcurrentRuns is the most runs in progress on the server; more runs wait for a slot
+	MaxConcurrentRuns int `yaml:"max_concurrent_runs"`
+	// MaxSessionRuns is the most runs in progress in a session
+	MaxSessionRuns int `yaml:"max_session_runs"`
+	// RunsPerMinute is the most runs each client starts per minute, counted per API key or
+	// OIDC subject, else per session
+	RunsPerMinute int `yaml:"runs_per_minute"`
+	// QueueTimeout is how long a run waits for a slot before it is rejected (defaults to 1m)
+	QueueTimeout time.Duration `yaml:"queue_timeout"`
+}
+
+// TLSConfig selects the certificate of the web server: certificate and key files, or
+// certificates issued over ACME for the domains
+type TLSConfig struct {
+	// CertFile is the PEM certificate chain, loaded again when it changes
+	CertFile string `yaml:"cert_file"`
+	// KeyFile is the PEM private key of the certificate
+	KeyFile string `yaml:"key_file"`
+	// ACMEDomains are the domains to request Let's Encrypt certificates for; the server must be
+	// reachable on port 443 at each of them
+	ACMEDomains []string `yaml:"acme_domains"`
+	// ACMECacheDir keeps the ACME account and certificates across restarts (defaults to acme-cache)
+	ACMECacheDir string `yaml:"acme_cache_dir"`
+	// ACMEEmail is the contact address for the certificates, such as for expiry notices
+	ACMEEmail string `yaml:"acme_email"`
+}
+
+// scheme returns the URL scheme of the web server, https or http
+func (t TLSConfig) scheme() string {
+	if t.CertFile != "" || len(t.ACMEDomains) > 0 {
+		return "https"
+	}
+	return "http"
+}
+
+// AuthConfig selects the credentials the web server accepts. Requests need one of the API
+// keys or a bearer token of the OIDC provider; without either, the server is open.
+type AuthConfig struct {
+	// APIKeys are the static API keys accepted as bearer tokens, X-API-Key headers, or Basic passwords
+	APIKeys []string `yaml:"api_keys"`
+	// OIDCIssuer is the issuer URL of the OIDC provider whose signed bearer tokens are accepted,
+	// such as https://accounts.google.com
+	OIDCIssuer string `yaml:"oidc_issuer"`
+	// OIDCAudience is the audience the tokens must be issued for, such as the client ID
+	OIDCAudience string `yaml:"oidc_audience"`
+}
+
+// loadConfig reads the configuration file named by AGI_CONFIG, or agi.yaml in the working
+// directory when it exists, and applies the environment and command-line overrides
+func loadConfig(flags *cliFlags) (*Config, error) {
+	path := os.Getenv("AGI_CONFIG")
+	if path == "" {
+		if _, err := os.Stat(defaultConfigFile); err == nil {
+			path = defaultConfigFile
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("failed to read config %s: %w", defaultConfigFile, err)
+		}
+	}
+	return readConfig(path, flags)
+}
+
+// readConfig reads the configuration file at path, or starts from the defaults when path
+// is empty, then applies the pipeline file, the environment overrides, and flags when not nil
+func readConfig(path string, flags *cliFlags) (*Config, error) {
+	config := &Config{Model: ModelConfig{Warmup: true}}
+	baseDir := "."
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config %s: %w", path, err)
+		}
+		// JSON is valid YAML, so one decoder handles both formats
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, fmt.Errorf("failed to parse config %s: %w", path, err)
+		}
+		baseDir = filepath.Dir(path)
+		config.Pipeline.ResolvePaths(baseDir)
+	}
+
+	// AGI_PIPELINE_CONFIG is relative to the working directory, pipeline_file to the config file
+	if pipelineFile := os.Getenv("AGI_PIPELINE_CONFIG"); pipelineFile != "" {
+		config.PipelineFile = pipelineFile
+	} else if config.PipelineFile != "" && !filepath.IsAbs(config.PipelineFile) {
+		config.PipelineFile = filepath.Join(baseDir, config.PipelineFile)
+	}
+	if config.PipelineFile != "" {
+		pipeline, err := agents.LoadPipelineConfig(config.PipelineFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Pipeline = pipeline
+	}
+
+	if err := config.applyEnv(); err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		if err := flags.apply(config); err != nil {
+			return nil, err
+		}
+	}
+	if err := config.setDefaults(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// applyEnv overrides the configuration with the environment variables that are set
+func (c *Config) applyEnv() error {
+	envString("AGI_MODEL_PROVIDER", &c.Model.Provider)
+	if c.Model.Provider == "" || c.Model.Provider == providerOllama {
+		envString("OLLAMA_MODEL", &c.Model.Name)
+		envString("OLLAMA_BASE_URL", &c.Model.BaseURL)
+	}
+	envString("AGI_MODEL", &c.Model.Name)
+	envString("AGI_MODEL_BASE_URL", &c.Model.BaseURL)
+	envString("AGI_MODEL_TOKEN", &c.Model.Token)
+	envString("AGI_WORKSPACE_DIR", &c.WorkspaceDir)
+	envString("AGI_SYMLINK_POLICY", &c.SymlinkPolicy)
+
+	p := &c.Pipeline
+	envString("AGI_CHECKPOINT_DIR", &p.CheckpointDir)
+	envString("AGI_QUARANTINE_DIR", &p.QuarantineDir)
+	envString("AGI_PROMPT_DIR", &p.PromptDir)
+	envString("AGI_SANDBOX_IMAGE", &p.Sandbox.Image)
+	envString("AGI_SANDBOX_NETWORK", &p.Sandbox.Network)
+	envString("AGI_BULK_MODEL", &p.ModelRouting.Bulk)
+	envString("AGI_CRITICAL_MODEL", &p.ModelRouting.Critical)
+	envString("AGI_MODE", &p.Mode)
+	envList("AGI_ENV_VARS", &p.EnvVars)
+
+	envString("AGI_SERVER_LAUNCHER", &c.Server.Launcher)
+	envList("AGI_SERVER_SERVICES", &c.Server.Services)
+	envString("AGI_SERVER_PPROF_ADDRESS", &c.Server.PprofAddress)
+	envString("AGI_SERVER_SESSION_DB", &c.Server.SessionDB)
+	envString("AGI_SERVER_HISTORY_DIR", &c.Server.HistoryDir)
+	envList("AGI_SERVER_API_KEYS", &c.Server.Auth.APIKeys)
+	envString("AGI_SERVER_OIDC_ISSUER", &c.Server.Auth.OIDCIssuer)
+	envString("AGI_SERVER_OIDC_AUDIENCE", &c.Server.Auth.OIDCAudience)
+	envString("AGI_SERVER_TLS_CERT", &c.Server.TLS.CertFile)
+	envString("AGI_SERVER_TLS_KEY", &c.Server.TLS.KeyFile)
+	envList("AGI_SERVER_ACME_DOMAINS", &c.Server.TLS.ACMEDomains)
+	envString("AGI_SERVER_ACME_CACHE_DIR", &c.Server.TLS.ACMECacheDir)
+	envString("AGI_SERVER_ACME_EMAIL", &c.Server.TLS.ACMEEmail)
+
+	bools := map[string]*bool{
+		"OLLAMA_WARMUP":       &c.Model.Warmup,
+		"AGI_READ_ONLY":       &c.ReadOnly,
+		"AGI_TASK_ROUTER":     &c.TaskRouter,
+		"AGI_DESIGN_APPROVAL": &p.RequireDesignApproval,
+		"AGI_ROLLBACK":        &p.Rollback,
+		"AGI_DRY_RUN":         &p.DryRun,
+		"AGI_SERVER_METRICS":  &c.Server.Metrics,
+	}
+	for name, target := range bools {
+		if err := envBool(name, target); err != nil {
+			return err
+		}
+	}
+	ints := map[string]*int{
+		"AGI_SERVER_PORT":                &c.Server.Port,
+		"AGI_SERVER_MAX_CONCURRENT_RUNS": &c.Server.Limits.MaxConcurrentRuns,
+		"AGI_SERVER_MAX_SESSION_RUNS":    &c.Server.Limits.MaxSessionRuns,
+		"AGI_SERVER_RUNS_PER_MINUTE":     &c.Server.Limits.RunsPerMinute,
+	}
+	for name, target := range ints {
+		if err := envInt(name, target); err != nil {
+			return err
+		}
+	}
+	durations := map[string]*time.Duration{
+		"AGI_SERVER_DRAIN_TIMEOUT": &c.Server.DrainTimeout,
+		"AGI_SERVER_QUEUE_TIMEOUT": &c.Server.Limits.QueueTimeout,
+	}
+	for name, target := range durations {
+		if value := os.Getenv(name); value != "" {
+			timeout, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s %q: %w", name, value, err)
+			}
+			*target = timeout
+		}
+	}
+	return nil
+}
+
+// setDefaults fills in the unset model options and checks the provider and launcher
+func (c *Config) setDefaults() error {
+	switch c.Model.Provider {
+	case "", providerOllama:
+		c.Model.Provider = providerOllama
+		if c.Model.BaseURL == "" {
+			c.Model.BaseURL = defaultOllamaBaseURL
+		}
+		if c.Model.Name == "" {
+			c.Model.Name = defaultModelName
+		}
+	case providerTGI:
+		// The TGI client defaults the endpoint, and TGI serves whatever model it was started with
+	case providerGemini:
+		if c.Model.Name == "" {
+			c.Model.Name = defaultGeminiModelName
+		}
+	default:
+		return fmt.Errorf("unknown model provider %q (want %s, %s, or %s)", c.Model.Provider, providerOllama, providerTGI, providerGemini)
+	}
+	if c.WorkspaceDir != "" {
+		c.Pipeline.WorkspaceDir = c.WorkspaceDir
+	}
+	switch c.Server.Launcher {
+	case "", launcherConsole, launcherWeb:
+	default:
+		return fmt.Errorf("unknown launcher %q (want %s or %s)", c.Server.Launcher, launcherConsole, launcherWeb)
+	}
+	switch {
+	case c.Server.DrainTimeout < 0:
+		return fmt.Errorf("invalid drain timeout %s: must not be negative", c.Server.DrainTimeout)
+	case c.Server.DrainTimeout == 0:
+		c.Server.DrainTimeout = defaultDrainTimeout
+	}
+	if err := c.Server.Auth.validate(); err != nil {
+		return err
+	}
+	if err := c.Server.Limits.setDefaults(); err != nil {
+		return err
+	}
+	return c.Server.TLS.setDefaults()
+}
+
+// setDefaults checks that the limits are not negative and defaults the queue timeout
+func (l *LimitsConfig) setDefaults() error {
+	if l.MaxConcurrentRuns < 0 || l.MaxSessionRuns < 0 || l.RunsPerMinute < 0 || l.QueueTimeout < 0 {
+		return fmt.Errorf("invalid run limits %+v: must not be negative", *l)
+	}
+	if l.QueueTimeout == 0 {
+		l.QueueTimeout = defaultQueueTimeout
+	}
+	return nil
+}
+
+// setDefaults checks that the certificate comes with its key and from one source, and
+// defaults the ACME cache directory
+func (t *TLSConfig) setDefaults() error {
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return errors.New("TLS certificate and key must be set together")
+	}
+	if t.CertFile != "" && len(t.ACMEDomains) > 0 {
+		return errors.New("TLS certificate files and ACME domains are exclusive")
+	}
+	if len(t.ACMEDomains) > 0 && t.ACMECacheDir == "" {
+		t.ACMECacheDir = defaultACMECacheDir
+	}
+	return nil
+}
+
+// validate checks that the API keys are set and that an OIDC provider has an HTTPS issuer
+// and an audience
+func (a AuthConfig) validate() error {
+	if slices.Contains(a.APIKeys, "") {
+		return errors.New("invalid API keys: a key must not be empty")
+	}
+	if a.OIDCIssuer == "" {
+		if a.OIDCAudience != "" {
+			return errors.New("OIDC audience set without an OIDC issuer")
+		}
+		return nil
+	}
+	issuer, err := url.Parse(a.OIDCIssuer)
+	if err != nil {
+		return fmt.Errorf("invalid OIDC issuer %q: %w", a.OIDCIssuer, err)
+	}
+	// Plain HTTP is accepted only for a provider on this machine, such as in development
+	if issuer.Scheme != "https" && (issuer.Scheme != "http" || !isLoopback(issuer.Hostname())) {
+		return fmt.Errorf("invalid OIDC issuer %q: must be an https URL", a.OIDCIssuer)
+	}
+	if a.OIDCAudience == "" {
+		return errors.New("OIDC issuer set without an OIDC audience, such as the client ID")
+	}
+	return nil
+}
+
+// launcherArgs returns the launcher arguments that start the configured server, nil for
+// the default console launcher
+func (s ServerConfig) launcherArgs() []string {
+	if s.Launcher != launcherWeb {
+		if s.Launcher == launcherConsole {
+			return []string{launcherConsole}
+		}
+		return nil
+	}
+	args := []string{launcherWeb}
+	if s.Port != 0 {
+		args = append(args, "-port", strconv.Itoa(s.Port))
+	}
+	services := s.Services
+	if len(services) == 0 {
+		services = []string{"api", "webui"}
+	}
+	for _, service := range services {
+		args = append(args, service)
+		// The web UI and the API server find each other at localhost:8080 unless told otherwise
+		if s.Port == 0 {
+			continue
+		}
+		switch service {
+		case "api":
+			args = append(args, "-webui_address", fmt.Sprintf("localhost:%d", s.Port))
+		case "webui":
+			args = append(args, "-api_server_address", fmt.Sprintf("%s://localhost:%d/api", s.TLS.scheme(), s.Port))
+		case "a2a":
+			args = append(args, "-a2a_agent_url", fmt.Sprintf("%s://localhost:%d", s.TLS.scheme(), s.Port))
+		}
+	}
+	return args
+}
+
+// envString sets target to the environment variable name when it is set and not empty
+func envString(name string, target *string) {
+	if value := os.Getenv(name); value != "" {
+		*target = value
+	}
+}
+
+// envList sets target to the comma- or space-separated values of the environment variable name when it is set
+func envList(name string, target *[]string) {
+	if value := os.Getenv(name); value != "" {
+		*target = strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+	}
+}
+
+// envInt sets target to the integer environment variable name when it is set
+func envInt(name string, target *int) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	*target = n
+	return nil
+}
+
+// envBool sets target to the boolean environment variable name when it is set
+func envBool(name string, target *bool) error {
+	value := os.Getenv(name)
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", name, value, err)
+	}
+	*target = b
+	return nil
+}
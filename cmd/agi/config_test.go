@@ -0,0 +1,286 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// configEnv are the environment variables read by loadConfig
+var configEnv = []string{
+	"AGI_CONFIG", "AGI_PIPELINE_CONFIG", "AGI_MODEL_PROVIDER", "OLLAMA_MODEL", "OLLAMA_BASE_URL",
+	"AGI_MODEL", "AGI_MODEL_BASE_URL", "AGI_MODEL_TOKEN", "AGI_WORKSPACE_DIR", "AGI_SYMLINK_POLICY",
+	"AGI_CHECKPOINT_DIR", "AGI_QUARANTINE_DIR", "AGI_PROMPT_DIR", "AGI_SANDBOX_IMAGE",
+	"AGI_SANDBOX_NETWORK", "AGI_BULK_MODEL", "AGI_CRITICAL_MODEL", "AGI_MODE", "AGI_ENV_VARS",
+	"AGI_SERVER_LAUNCHER", "AGI_SERVER_SERVICES", "AGI_SERVER_PORT", "AGI_SERVER_METRICS", "AGI_SERVER_PPROF_ADDRESS", "AGI_SERVER_DRAIN_TIMEOUT", "AGI_SERVER_SESSION_DB",
+	"AGI_SERVER_HISTORY_DIR", "AGI_SERVER_API_KEYS", "AGI_SERVER_OIDC_ISSUER", "AGI_SERVER_OIDC_AUDIENCE",
+	"AGI_SERVER_TLS_CERT", "AGI_SERVER_TLS_KEY", "AGI_SERVER_ACME_DOMAINS", "AGI_SERVER_ACME_CACHE_DIR", "AGI_SERVER_ACME_EMAIL",
+	"AGI_SERVER_MAX_CONCURRENT_RUNS", "AGI_SERVER_MAX_SESSION_RUNS", "AGI_SERVER_RUNS_PER_MINUTE", "AGI_SERVER_QUEUE_TIMEOUT", "OLLAMA_WARMUP",
+	"AGI_READ_ONLY", "AGI_TASK_ROUTER", "AGI_DESIGN_APPROVAL", "AGI_ROLLBACK", "AGI_DRY_RUN",
+}
+
+// clearConfigEnv unsets the configuration environment variables for the test
+func clearConfigEnv(t *testing.T) {
+	for _, name := range configEnv {
+		t.Setenv(name, "")
+	}
+}
+
+const testConfigYAML = `model:
+  name: qwen2.5-coder:7b
+  base_url: http://ollama:11434
+workspace_dir: /srv/workspace
+read_only: true
+pipeline:
+  mode: chat
+  prompt_dir: prompts
+  rollback: true
+  model_routing:
+    bulk: qwen2.5-coder:1.5b
+server:
+  launcher: web
+  port: 9090
+  drain_timeout: 2m
+  session_db: /srv/agi.db
+  history_dir: /srv/runs
+  limits:
+    max_concurrent_runs: 1
+    runs_per_minute: 10
+  auth:
+    api_keys: [file-key]
+    oidc_issuer: https://accounts.example.com
+    oidc_audience: agi
+`
+
+func TestReadConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+		return path
+	}
+	configPath := writeConfig("agi.yaml", testConfigYAML)
+	writeConfig("pipeline.yaml", "name: FilePipeline\nmin_coverage: 90\n")
+	pipelinePath := writeConfig("with-pipeline.yaml", "pipeline_file: pipeline.yaml\npipeline:\n  mode: chat\n")
+
+	tests := []struct {
+		name        string
+		path        string
+		env         map[string]string
+		check       func(t *testing.T, config *Config)
+		errContains string
+	}{
+		{
+			name: "defaults",
+			check: func(t *testing.T, config *Config) {
+				want := ModelConfig{Provider: providerOllama, Name: defaultModelName, BaseURL: defaultOllamaBaseURL, Warmup: true}
+				if !reflect.DeepEqual(config.Model, want) {
+					t.Errorf("Model = %+v, want %+v", config.Model, want)
+				}
+				if config.Server.launcherArgs() != nil || config.Pipeline.WorkspaceDir != "" || config.Server.DrainTimeout != defaultDrainTimeout {
+					t.Errorf("config = %+v, want the default launcher, workspace, and drain timeout", config)
+				}
+			},
+		},
+		{
+			name: "legacy environment",
+			env:  map[string]string{"OLLAMA_MODEL": "llama3.2", "OLLAMA_WARMUP": "false", "AGI_ENV_VARS": "A, B", "AGI_DESIGN_APPROVAL": "true"},
+			check: func(t *testing.T, config *Config) {
+				if config.Model.Name != "llama3.2" || config.Model.Warmup {
+					t.Errorf("Model = %+v, want llama3.2 without warm-up", config.Model)
+				}
+				if !reflect.DeepEqual(config.Pipeline.EnvVars, []string{"A", "B"}) || !config.Pipeline.RequireDesignApproval {
+					t.Errorf("Pipeline = %+v, want env vars A and B with design approval", config.Pipeline)
+				}
+			},
+		},
+		{
+			name: "file",
+			path: configPath,
+			check: func(t *testing.T, config *Config) {
+				if config.Model.Name != "qwen2.5-coder:7b" || config.Model.BaseURL != "http://ollama:11434" || !config.Model.Warmup {
+					t.Errorf("Model = %+v, want the file model with warm-up", config.Model)
+				}
+				if !config.ReadOnly || config.Pipeline.WorkspaceDir != "/srv/workspace" {
+					t.Errorf("config = %+v, want read-only in /srv/workspace", config)
+				}
+				p := config.Pipeline
+				if p.Mode != "chat" || !p.Rollback || p.ModelRouting.Bulk != "qwen2.5-coder:1.5b" || p.PromptDir != filepath.Join(dir, "prompts") {
+					t.Errorf("Pipeline = %+v, want the file pipeline options", p)
+				}
+				want := []string{"web", "-port", "9090", "api", "-webui_address", "localhost:9090", "webui", "-api_server_address", "http://localhost:9090/api"}
+				if got := config.Server.launcherArgs(); !reflect.DeepEqual(got, want) {
+					t.Errorf("launcherArgs() = %q, want %q", got, want)
+				}
+				if config.Server.DrainTimeout != 2*time.Minute || config.Server.SessionDB != "/srv/agi.db" || config.Server.HistoryDir != "/srv/runs" {
+					t.Errorf("Server = %+v, want a 2m drain timeout, the session database, and the history directory", config.Server)
+				}
+				wantLimits := LimitsConfig{MaxConcurrentRuns: 1, RunsPerMinute: 10, QueueTimeout: defaultQueueTimeout}
+				if config.Server.Limits != wantLimits {
+					t.Errorf("Limits = %+v, want %+v", config.Server.Limits, wantLimits)
+				}
+				wantAuth := AuthConfig{APIKeys: []string{"file-key"}, OIDCIssuer: "https://accounts.example.com", OIDCAudience: "agi"}
+				if !reflect.DeepEqual(config.Server.Auth, wantAuth) {
+					t.Errorf("Auth = %+v, want %+v", config.Server.Auth, wantAuth)
+				}
+			},
+		},
+		{
+			name: "environment overrides the file",
+			path: configPath,
+			env:  map[string]string{"AGI_MODEL": "gpt-oss:20b", "AGI_READ_ONLY": "false", "AGI_MODE": "pipeline", "AGI_SERVER_LAUNCHER": "console", "AGI_SERVER_DRAIN_TIMEOUT": "45s", "AGI_SERVER_SESSION_DB": "sessions.db", "AGI_SERVER_HISTORY_DIR": "history", "AGI_SERVER_API_KEYS": "key-1,key-2", "AGI_SERVER_MAX_SESSION_RUNS": "1", "AGI_SERVER_QUEUE_TIMEOUT": "5s"},
+			check: func(t *testing.T, config *Config) {
+				if config.Model.Name != "gpt-oss:20b" || config.ReadOnly || config.Pipeline.Mode != "pipeline" {
+					t.Errorf("config = %+v, want the environment values", config)
+				}
+				if got := config.Server.launcherArgs(); !reflect.DeepEqual(got, []string{"console"}) {
+					t.Errorf("launcherArgs() = %q, want console", got)
+				}
+				if config.Server.DrainTimeout != 45*time.Second || config.Server.SessionDB != "sessions.db" || config.Server.HistoryDir != "history" {
+					t.Errorf("Server = %+v, want a 45s drain timeout, sessions.db, and the history directory", config.Server)
+				}
+				if l := config.Server.Limits; l.MaxConcurrentRuns != 1 || l.MaxSessionRuns != 1 || l.QueueTimeout != 5*time.Second {
+					t.Errorf("Limits = %+v, want the file limits with the environment session limit and queue timeout", l)
+				}
+				if !reflect.DeepEqual(config.Server.Auth.APIKeys, []string{"key-1", "key-2"}) {
+					t.Errorf("APIKeys = %q, want the environment keys", config.Server.Auth.APIKeys)
+				}
+			},
+		},
+		{
+			name: "pipeline file relative to the config",
+			path: pipelinePath,
+			check: func(t *testing.T, config *Config) {
+				if config.Pipeline.Name != "FilePipeline" || config.Pipeline.MinCoverage != 90 || config.Pipeline.Mode != "" {
+					t.Errorf("Pipeline = %+v, want the pipeline file only", config.Pipeline)
+				}
+			},
+		},
+		{
+			name: "tgi provider ignores the Ollama variables",
+			env:  map[string]string{"AGI_MODEL_PROVIDER": "tgi", "OLLAMA_BASE_URL": "http://ollama:11434", "AGI_MODEL_TOKEN": "secret"},
+			check: func(t *testing.T, config *Config) {
+				want := ModelConfig{Provider: providerTGI, Token: "secret", Warmup: true}
+				if !reflect.DeepEqual(config.Model, want) {
+					t.Errorf("Model = %+v, want %+v", config.Model, want)
+				}
+			},
+		},
+		{
+			name:        "unknown provider",
+			env:         map[string]string{"AGI_MODEL_PROVIDER": "openai"},
+			errContains: `unknown model provider "openai"`,
+		},
+		{
+			name:        "invalid boolean",
+			env:         map[string]string{"AGI_READ_ONLY": "yes please"},
+			errContains: "invalid AGI_READ_ONLY",
+		},
+		{
+			name:        "invalid drain timeout",
+			env:         map[string]string{"AGI_SERVER_DRAIN_TIMEOUT": "30"},
+			errContains: "invalid AGI_SERVER_DRAIN_TIMEOUT",
+		},
+		{
+			name:        "negative drain timeout",
+			env:         map[string]string{"AGI_SERVER_DRAIN_TIMEOUT": "-1s"},
+			errContains: "must not be negative",
+		},
+		{
+			name:        "OIDC issuer without audience",
+			env:         map[string]string{"AGI_SERVER_OIDC_ISSUER": "https://accounts.example.com"},
+			errContains: "without an OIDC audience",
+		},
+		{
+			name:        "plain HTTP OIDC issuer",
+			env:         map[string]string{"AGI_SERVER_OIDC_ISSUER": "http://accounts.example.com", "AGI_SERVER_OIDC_AUDIENCE": "agi"},
+			errContains: "must be an https URL",
+		},
+		{
+			name:        "OIDC audience without issuer",
+			env:         map[string]string{"AGI_SERVER_OIDC_AUDIENCE": "agi"},
+			errContains: "without an OIDC issuer",
+		},
+		{
+			name:        "invalid run limit",
+			env:         map[string]string{"AGI_SERVER_MAX_CONCURRENT_RUNS": "one"},
+			errContains: "invalid AGI_SERVER_MAX_CONCURRENT_RUNS",
+		},
+		{
+			name:        "negative run limit",
+			env:         map[string]string{"AGI_SERVER_RUNS_PER_MINUTE": "-1"},
+			errContains: "must not be negative",
+		},
+		{
+			name:        "TLS certificate without key",
+			env:         map[string]string{"AGI_SERVER_TLS_CERT": "tls.crt"},
+			errContains: "must be set together",
+		},
+		{
+			name:        "TLS certificate and ACME domains",
+			env:         map[string]string{"AGI_SERVER_TLS_CERT": "tls.crt", "AGI_SERVER_TLS_KEY": "tls.key", "AGI_SERVER_ACME_DOMAINS": "agi.example.com"},
+			errContains: "exclusive",
+		},
+		{
+			name:        "missing file",
+			path:        filepath.Join(dir, "missing.yaml"),
+			errContains: "failed to read config",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearConfigEnv(t)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			config, err := readConfig(tt.path, nil)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("readConfig() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readConfig() error = %v", err)
+			}
+			tt.check(t, config)
+		})
+	}
+}
+
+func TestServerConfig_LauncherArgsTLS(t *testing.T) {
+	server := ServerConfig{Launcher: launcherWeb, Port: 8443, Services: []string{"webui", "a2a"}, TLS: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"}}
+	want := []string{"web", "-port", "8443", "webui", "-api_server_address", "https://localhost:8443/api", "a2a", "-a2a_agent_url", "https://localhost:8443"}
+	if got := server.launcherArgs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("launcherArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	clearConfigEnv(t)
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := os.WriteFile(defaultConfigFile, []byte("model:\n  name: from-default-file\n"), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	other := filepath.Join(dir, "other.json")
+	if err := os.WriteFile(other, []byte(`{"model": {"name": "from-agi-config"}}`), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	config, err := loadConfig(nil)
+	if err != nil || config.Model.Name != "from-default-file" {
+		t.Fatalf("loadConfig() = %+v, %v, want the model of agi.yaml", config, err)
+	}
+	t.Setenv("AGI_CONFIG", other)
+	config, err = loadConfig(nil)
+	if err != nil || config.Model.Name != "from-agi-config" {
+		t.Fatalf("loadConfig() = %+v, %v, want the model of AGI_CONFIG", config, err)
+	}
+}
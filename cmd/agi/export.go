@@ -0,0 +1,347 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+)
+
+// exportCommand is the launcher argument that downloads the export of a session from a
+// running server
+const exportCommand = "export"
+
+// exportKeyword is the web launcher argument that serves the session exports
+const exportKeyword = "export"
+
+// exportPath is the route of the session exports, next to the session routes of the API server
+const exportPath = "/api/apps/{app_name}/users/{user_id}/sessions/{session_id}/export"
+
+// Files of an export archive next to the workspace directory
+const (
+	exportWorkspace  = "workspace/"
+	exportTranscript = "transcript.md"
+	exportReview     = "review.md"
+)
+
+// reviewReports are the session state keys of the review stages, with their titles in
+// the review report of an export
+var reviewReports = []struct {
+	key   string
+	title string
+}{
+	{key: "review_comments", title: "Code Review"},
+	{key: "quality_report", title: "Quality Report"},
+	{key: "security_findings", title: "Security Findings"},
+	{key: "pr_review", title: "Pull Request Review"},
+}
+
+// exportWriteTimeout is how long the export of a session may take to send, as it outlives
+// the write timeout of the server
+const exportWriteTimeout = 10 * time.Minute
+
+// writeSessionExport writes an archive in format to w with the workspace files sess wrote,
+// except those config denies or ignores, the transcript of sess, and its review report, and
+// returns the number of files written
+func writeSessionExport(ctx context.Context, w io.Writer, config tools.Config, sess session.Session, workspaceDir, format string) (int, error) {
+	entries := []tools.ArchiveEntry{{Name: exportTranscript, Content: []byte(sessionTranscript(sess))}}
+	if report := reviewReport(sess.State()); report != "" {
+		entries = append(entries, tools.ArchiveEntry{Name: exportReview, Content: []byte(report)})
+	}
+	// A session that wrote no files has no workspace to export
+	if _, err := os.Stat(workspaceDir); errors.Is(err, os.ErrNotExist) {
+		workspaceDir = ""
+	}
+	return tools.WriteExportArchive(ctx, w, config, workspaceDir, sessionFiles(sess), exportWorkspace, format, entries)
+}
+
+// sessionFiles returns the workspace paths of the files the file tools wrote in sess; the
+// apps share one workspace, so the other files may belong to other sessions
+func sessionFiles(sess session.Session) []string {
+	var files []string
+	for event := range sess.Events().All() {
+		if event.Partial || event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			response := part.FunctionResponse
+			if response == nil {
+				continue
+			}
+			if success, _ := response.Response["success"].(bool); !success {
+				continue
+			}
+			switch response.Name {
+			case "fileWrite", "fileEdit":
+				if path, _ := response.Response["path"].(string); path != "" {
+					files = append(files, path)
+				}
+			case "applyPatch":
+				if dryRun, _ := response.Response["dryRun"].(bool); dryRun {
+					continue
+				}
+				patched, _ := response.Response["files"].([]any)
+				for _, file := range patched {
+					file, _ := file.(map[string]any)
+					if path, _ := file["path"].(string); path != "" {
+						files = append(files, path)
+					}
+				}
+			}
+		}
+	}
+	return files
+}
+
+// sessionTranscript returns the messages, tool calls, and tool results of sess as Markdown
+func sessionTranscript(sess session.Session) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Transcript of session %s\n\nApp: %s  \nUser: %s\n", sess.ID(), sess.AppName(), sess.UserID())
+	author := ""
+	for event := range sess.Events().All() {
+		if event.Partial || event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			line := ""
+			switch {
+			case part.FunctionCall != nil:
+				line = fmt.Sprintf("- → `%s` %s\n", part.FunctionCall.Name, toolArgs(part.FunctionCall.Args))
+			case part.FunctionResponse != nil:
+				line = fmt.Sprintf("- ← `%s` %s\n", part.FunctionResponse.Name, toolResult(part.FunctionResponse.Response))
+			case part.Text != "" && !part.Thought:
+				line = strings.TrimSpace(part.Text) + "\n"
+			default:
+				continue
+			}
+			if event.Author != author {
+				author = event.Author
+				fmt.Fprintf(&b, "\n## %s · %s\n\n", author, event.Timestamp.UTC().Format(time.RFC3339))
+			}
+			b.WriteString(line)
+		}
+	}
+	return b.String()
+}
+
+// reviewReport returns the review stage outputs in state as Markdown, or "" when no review ran
+func reviewReport(state session.State) string {
+	var b strings.Builder
+	for _, report := range reviewReports {
+		value, err := state.Get(report.key)
+		if err != nil {
+			continue
+		}
+		text := strings.TrimSpace(fmt.Sprint(value))
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "## %s\n\n%s\n\n", report.title, text)
+	}
+	if b.Len() == 0 {
+		return ""
+	}
+	return "# Review Report\n\n" + strings.TrimSuffix(b.String(), "\n")
+}
+
+// exportLauncher is the web sublauncher that serves session exports: the workspace with the
+// transcript and review report of a session, as a zip or tar.gz download
+type exportLauncher struct {
+	// workspaceDir is the workspace the pipelines write to
+	workspaceDir string
+	// config is the tool configuration whose denied and ignored paths are left out
+	config tools.Config
+}
+
+// Keyword implements web.Sublauncher
+func (exportLauncher) Keyword() string {
+	return exportKeyword
+}
+
+// Parse implements web.Sublauncher; the export sublauncher has no flags
+func (exportLauncher) Parse(args []string) ([]string, error) {
+	return args, nil
+}
+
+// CommandLineSyntax implements web.Sublauncher
+func (exportLauncher) CommandLineSyntax() string {
+	return ""
+}
+
+// SimpleDescription implements web.Sublauncher
+func (exportLauncher) SimpleDescription() string {
+	return "serves the workspace with the transcript and review report of a session as a zip or tar.gz download"
+}
+
+// SetupSubrouters implements web.Sublauncher
+func (l exportLauncher) SetupSubrouters(router *mux.Router, adkConfig *adk.Config) error {
+	router.HandleFunc(exportPath, func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		format := cmp.Or(r.URL.Query().Get("format"), tools.ArchiveZip)
+		contentType := map[string]string{tools.ArchiveZip: "application/zip", tools.ArchiveTarGz: "application/gzip"}[format]
+		if contentType == "" {
+			http.Error(w, fmt.Sprintf("unknown format %q: use %s or %s", format, tools.ArchiveZip, tools.ArchiveTarGz), http.StatusBadRequest)
+			return
+		}
+		resp, err := adkConfig.SessionService.Get(r.Context(), &session.GetRequest{
+			AppName:   vars["app_name"],
+			UserID:    vars["user_id"],
+			SessionID: vars["session_id"],
+		})
+		if err != nil {
+			http.Error(w, fmt.Sprintf("session not found: %v", err), http.StatusNotFound)
+			return
+		}
+
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(exportWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Failed to extend the write deadline of export %s: %v", vars["session_id"], err)
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s", strconv.Quote(vars["session_id"]+"."+format)))
+		files, err := writeSessionExport(r.Context(), w, l.config, resp.Session, l.workspaceDir, format)
+		if err != nil {
+			// The archive is partly sent, so the client sees a truncated download
+			log.Printf("Failed to export session %s: %v", vars["session_id"], err)
+			return
+		}
+		log.Printf("Exported session %s with %d files", vars["session_id"], files)
+	}).Methods(http.MethodGet)
+	return nil
+}
+
+// UserMessage implements web.Sublauncher
+func (exportLauncher) UserMessage(webURL string, printer func(v ...any)) {
+	printer(fmt.Sprintf("   export:   session downloads at %s%s", webURL, exportPath))
+}
+
+// exportFlags are the flags of agi export
+type exportFlags struct {
+	// server is the URL of the agi web server
+	server string
+	// app is the app of the session
+	app string
+	// user is the user of the session
+	user string
+	// session is the ID of the session
+	session string
+	// format is zip or tar.gz
+	format string
+	// out is the file to write, or - for stdout
+	out string
+	// token is the API key or bearer token of the server, if it requires credentials
+	token string
+}
+
+// parseExportFlags parses the arguments of agi export; server is the default server URL and
+// token the default credential
+func parseExportFlags(args []string, server, token string) (*exportFlags, error) {
+	f := &exportFlags{}
+	fs := flag.NewFlagSet("agi export", flag.ContinueOnError)
+	fs.StringVar(&f.server, "server", server, "URL of the agi web server")
+	fs.StringVar(&f.app, "app", "", "app of the session, such as CodePipelineAgent")
+	fs.StringVar(&f.user, "user", "user", "user of the session")
+	fs.StringVar(&f.session, "session", "", "ID of the session to export")
+	fs.StringVar(&f.format, "format", tools.ArchiveZip, "archive format: zip or tar.gz")
+	fs.StringVar(&f.out, "out", "", "file to write, or - for stdout (default: <session>.<format>)")
+	fs.StringVar(&f.token, "token", token, "API key or OIDC bearer token of the server (default: the first configured API key)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: agi [flags] export --app CodePipelineAgent --session ID [--out project.zip]\n\n"+
+			"Downloads the workspace with the transcript and review report of a session from a running web server.\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if f.app == "" || f.session == "" {
+		return nil, fmt.Errorf("--app and --session are required")
+	}
+	f.out = cmp.Or(f.out, f.session+"."+f.format)
+	return f, nil
+}
+
+// exportServerURL returns the URL of the configured web server; its ACME certificates are
+// valid only for its domains
+func exportServerURL(server ServerConfig) string {
+	host := "localhost"
+	if len(server.TLS.ACMEDomains) > 0 {
+		host = server.TLS.ACMEDomains[0]
+	}
+	return fmt.Sprintf("%s://%s:%d", server.TLS.scheme(), host, cmp.Or(server.Port, 8080))
+}
+
+// exportToken returns the first API key of the configured web server, or "" when it has none
+func exportToken(server ServerConfig) string {
+	if len(server.Auth.APIKeys) == 0 {
+		return ""
+	}
+	return server.Auth.APIKeys[0]
+}
+
+// runExport downloads the export of a session to f.out, or to stdout for -
+func runExport(ctx context.Context, f *exportFlags, stdout io.Writer) error {
+	exportURL := strings.TrimSuffix(f.server, "/") + "/api/apps/" + url.PathEscape(f.app) +
+		"/users/" + url.PathEscape(f.user) + "/sessions/" + url.PathEscape(f.session) +
+		"/export?format=" + url.QueryEscape(f.format)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, exportURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid server URL %s: %w", f.server, err)
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to download export: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to download export: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	if f.out == "-" {
+		if _, err := io.Copy(stdout, resp.Body); err != nil {
+			return fmt.Errorf("failed to download export: %w", err)
+		}
+		return nil
+	}
+	// Write to a temporary file next to the export, so a failed download leaves no partial file
+	tmp, err := os.CreateTemp(filepath.Dir(f.out), ".export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", f.out, err)
+	}
+	defer os.Remove(tmp.Name())
+	size, err := io.Copy(tmp, resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download export: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", f.out, err)
+	}
+	if err := os.Rename(tmp.Name(), f.out); err != nil {
+		return fmt.Errorf("failed to create %s: %w", f.out, err)
+	}
+	fmt.Fprintf(stdout, "Exported session %s to %s (%d bytes)\n", f.session, f.out, size)
+	return nil
+}
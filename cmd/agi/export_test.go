@@ -0,0 +1,237 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// newExportSession creates a session with a request, a tool call, and a review in sessions
+func newExportSession(t *testing.T, sessions session.Service) session.Session {
+	t.Helper()
+	ctx := context.Background()
+	created, err := sessions.Create(ctx, &session.CreateRequest{
+		AppName:   "CodePipelineAgent",
+		UserID:    "user",
+		SessionID: "s1",
+		State:     map[string]any{"review_comments": "Approved: the calculator handles division by zero."},
+	})
+	if err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+	events := []struct {
+		author  string
+		content *genai.Content
+	}{
+		{author: "user", content: genai.NewContentFromText("Build a calculator package", genai.RoleUser)},
+		{author: "CodeWriterAgent", content: genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromFunctionCall("fileWrite", map[string]any{"path": "calc.go"}),
+		}, genai.RoleModel)},
+		{author: "CodeWriterAgent", content: genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromFunctionResponse("fileWrite", map[string]any{"success": true, "path": "calc.go"}),
+		}, genai.RoleUser)},
+		{author: "CodeWriterAgent", content: genai.NewContentFromText("Created calc.go", genai.RoleModel)},
+		{author: "FixerAgent", content: genai.NewContentFromParts([]*genai.Part{
+			genai.NewPartFromFunctionResponse("applyPatch", map[string]any{"success": true, "files": []any{map[string]any{"path": "pkg/div.go"}, map[string]any{"path": ".env"}}}),
+			genai.NewPartFromFunctionResponse("fileEdit", map[string]any{"success": false, "path": "failed.go"}),
+		}, genai.RoleUser)},
+	}
+	for _, e := range events {
+		event := session.NewEvent("invocation")
+		event.Author = e.author
+		event.LLMResponse.Content = e.content
+		if err := sessions.AppendEvent(ctx, created.Session, event); err != nil {
+			t.Fatalf("AppendEvent() error = %v", err)
+		}
+	}
+	return created.Session
+}
+
+func TestSessionTranscript(t *testing.T) {
+	sess := newExportSession(t, inmemory.New())
+
+	transcript := sessionTranscript(sess)
+	for _, want := range []string{
+		"# Transcript of session s1",
+		"## user · ",
+		"Build a calculator package\n",
+		"## CodeWriterAgent · ",
+		"- → `fileWrite` {\"path\":\"calc.go\"}\n",
+		"- ← `fileWrite` ok\n",
+		"Created calc.go\n",
+	} {
+		if !strings.Contains(transcript, want) {
+			t.Errorf("transcript does not contain %q:\n%s", want, transcript)
+		}
+	}
+	if got := strings.Count(transcript, "## CodeWriterAgent"); got != 1 {
+		t.Errorf("transcript has %d CodeWriterAgent headings, want 1", got)
+	}
+
+	want := "# Review Report\n\n## Code Review\n\nApproved: the calculator handles division by zero.\n"
+	if got := reviewReport(sess.State()); got != want {
+		t.Errorf("reviewReport() = %q, want %q", got, want)
+	}
+}
+
+func TestExport(t *testing.T) {
+	workspaceDir := t.TempDir()
+	// Only the files the session wrote are exported, except denied ones such as .env
+	for name, content := range map[string]string{"calc.go": "package calc\n", "pkg/div.go": "package pkg\n", ".env": "TOKEN=secret\n", "failed.go": "package calc\n", "other.go": "package other\n"} {
+		path := filepath.Join(workspaceDir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create workspace directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write workspace file: %v", err)
+		}
+	}
+	sessions := inmemory.New()
+	newExportSession(t, sessions)
+
+	router := mux.NewRouter()
+	config := tools.Config{DeniedPaths: []string{".env"}}
+	if err := (exportLauncher{workspaceDir: workspaceDir, config: config}).SetupSubrouters(router, &adk.Config{SessionService: sessions}); err != nil {
+		t.Fatalf("SetupSubrouters() error = %v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	out := filepath.Join(t.TempDir(), "s1.zip")
+	var stdout bytes.Buffer
+	if err := runExport(context.Background(), &exportFlags{server: server.URL, app: "CodePipelineAgent", user: "user", session: "s1", format: "zip", out: out}, &stdout); err != nil {
+		t.Fatalf("runExport() error = %v", err)
+	}
+	if !strings.HasPrefix(stdout.String(), "Exported session s1 to "+out) {
+		t.Errorf("runExport() output = %q", stdout.String())
+	}
+	r, err := zip.OpenReader(out)
+	if err != nil {
+		t.Fatalf("failed to open export: %v", err)
+	}
+	defer r.Close()
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	if files["workspace/calc.go"] != "package calc\n" || files["workspace/pkg/div.go"] != "package pkg\n" || !strings.Contains(files["transcript.md"], "Build a calculator package") || !strings.Contains(files["review.md"], "Code Review") {
+		t.Errorf("export files = %v, want the workspace, transcript, and review", files)
+	}
+	if len(files) != 4 {
+		t.Errorf("export files = %v, want only the files the session wrote that are not denied", files)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+	}{
+		{name: "tar.gz", path: "/api/apps/CodePipelineAgent/users/user/sessions/s1/export?format=tar.gz", wantStatus: http.StatusOK},
+		{name: "unknown session", path: "/api/apps/CodePipelineAgent/users/user/sessions/missing/export", wantStatus: http.StatusNotFound},
+		{name: "unknown format", path: "/api/apps/CodePipelineAgent/users/user/sessions/s1/export?format=rar", wantStatus: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Get(server.URL + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("GET %s status = %d, want %d", tt.path, resp.StatusCode, tt.wantStatus)
+			}
+		})
+	}
+
+	// A server that requires credentials gets the token as a bearer token
+	protected := httptest.NewServer(newAuthenticator(AuthConfig{APIKeys: []string{"secret"}}).wrap(router))
+	defer protected.Close()
+	if err := runExport(context.Background(), &exportFlags{server: protected.URL, app: "CodePipelineAgent", user: "user", session: "s1", format: "zip", out: out, token: "secret"}, io.Discard); err != nil {
+		t.Errorf("runExport() with a token error = %v", err)
+	}
+	err = runExport(context.Background(), &exportFlags{server: protected.URL, app: "CodePipelineAgent", user: "user", session: "s1", format: "zip", out: out}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("runExport() without a token error = %v, want 401", err)
+	}
+
+	err = runExport(context.Background(), &exportFlags{server: server.URL, app: "CodePipelineAgent", user: "user", session: "missing", format: "zip", out: out + ".missing"}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("runExport() of an unknown session error = %v, want 404", err)
+	}
+	if _, statErr := os.Stat(out + ".missing"); !os.IsNotExist(statErr) {
+		t.Errorf("failed export left a file: %v", statErr)
+	}
+}
+
+func TestParseExportFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    exportFlags
+		wantErr bool
+	}{
+		{
+			name: "defaults",
+			args: []string{"--app", "CodePipelineAgent", "--session", "s1"},
+			want: exportFlags{server: "http://localhost:9090", app: "CodePipelineAgent", user: "user", session: "s1", format: "zip", out: "s1.zip"},
+		},
+		{
+			name: "all flags",
+			args: []string{"--server", "http://agi:8080", "--app", "BugFixPipeline", "--user", "dev", "--session", "s2", "--format", "tar.gz", "--out", "-", "--token", "secret"},
+			want: exportFlags{server: "http://agi:8080", app: "BugFixPipeline", user: "dev", session: "s2", format: "tar.gz", out: "-", token: "secret"},
+		},
+		{name: "missing session", args: []string{"--app", "CodePipelineAgent"}, wantErr: true},
+		{name: "unexpected argument", args: []string{"--app", "A", "--session", "s1", "extra"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseExportFlags(tt.args, exportServerURL(ServerConfig{Port: 9090}), "")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseExportFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && *got != tt.want {
+				t.Errorf("parseExportFlags() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportServerURL(t *testing.T) {
+	tests := []struct {
+		name   string
+		server ServerConfig
+		want   string
+	}{
+		{name: "default", want: "http://localhost:8080"},
+		{name: "port", server: ServerConfig{Port: 9090}, want: "http://localhost:9090"},
+		{name: "certificate files", server: ServerConfig{Port: 8443, TLS: TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"}}, want: "https://localhost:8443"},
+		{name: "ACME", server: ServerConfig{Port: 443, TLS: TLSConfig{ACMEDomains: []string{"agi.example.com"}}}, want: "https://agi.example.com:443"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := exportServerURL(tt.server); got != tt.want {
+				t.Errorf("exportServerURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// cliFlags are the command-line flags of agi, which override the configuration file and
+// the environment
+type cliFlags struct {
+	// provider is the model provider: ollama, tgi, or gemini
+	provider string
+	// model is the model name
+	model string
+	// baseURL is the model endpoint
+	baseURL string
+	// options are the model options, as key=value pairs separated by commas or a JSON object
+	options string
+	// metrics serves the Prometheus metrics at /metrics of the web server
+	metrics bool
+	// pprofAddress is the admin address that serves the net/http/pprof endpoints
+	pprofAddress string
+	// drainTimeout is how long the web server waits for runs in progress on shutdown
+	drainTimeout time.Duration
+	// sessionDB is the SQLite database that stores the sessions
+	sessionDB string
+	// historyDir keeps a record of each pipeline run
+	historyDir string
+	// tlsCert is the PEM certificate chain of the web server
+	tlsCert string
+	// tlsKey is the PEM private key of the web server
+	tlsKey string
+	// acmeDomains are the comma-separated domains to request ACME certificates for
+	acmeDomains string
+	// maxConcurrentRuns is the most runs in progress on the web server
+	maxConcurrentRuns int
+	// runsPerMinute is the most runs each client starts per minute
+	runsPerMinute int
+}
+
+// newFlagSet returns the flag set of the agi flags, which are stored in f
+func newFlagSet(f *cliFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet("agi", flag.ContinueOnError)
+	fs.StringVar(&f.provider, "provider", "", "model provider: ollama, tgi, or gemini")
+	fs.StringVar(&f.model, "model", "", "model name, such as qwen2.5-coder:7b or gemini-2.5-flash")
+	fs.StringVar(&f.baseURL, "base-url", "", "model endpoint, such as http://localhost:11434")
+	fs.StringVar(&f.options, "options", "", `model options as key=value pairs, such as temperature=0.2,top_p=0.9, or a JSON object`)
+	fs.BoolVar(&f.metrics, "metrics", false, "serve Prometheus metrics at /metrics of the web server")
+	fs.StringVar(&f.pprofAddress, "pprof-address", "", "admin address, such as localhost:6060, that serves the pprof endpoints at /debug/pprof/")
+	fs.DurationVar(&f.drainTimeout, "drain-timeout", 0, "how long the web server waits on shutdown for runs in progress, such as 2m (default 30s)")
+	fs.StringVar(&f.sessionDB, "session-db", "", "SQLite database that stores the sessions, such as agi.db, so they survive restarts")
+	fs.StringVar(&f.historyDir, "history-dir", "", "directory that keeps a record of each pipeline run, for the run history and replay API")
+	fs.StringVar(&f.tlsCert, "tls-cert", "", "PEM certificate chain that serves the web server over HTTPS, with --tls-key")
+	fs.StringVar(&f.tlsKey, "tls-key", "", "PEM private key of --tls-cert")
+	fs.StringVar(&f.acmeDomains, "acme-domains", "", "comma-separated domains to serve HTTPS with Let's Encrypt certificates for, such as agi.example.com")
+	fs.IntVar(&f.maxConcurrentRuns, "max-concurrent-runs", 0, "most runs in progress on the web server; more runs wait for a slot (default: no limit)")
+	fs.IntVar(&f.runsPerMinute, "runs-per-minute", 0, "most runs each API key or session starts per minute (default: no limit)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: agi [flags] [command] [arguments]\n\nFlags:\n")
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nCommands:\n"+
+			"  serve [launcher arguments]  serve the agent (the default), such as serve web -port 8080 api webui\n"+
+			"  run --prompt-file task.md   run the pipeline once\n"+
+			"  chat                        converse in the terminal\n"+
+			"  export --app A --session S  download the workspace, transcript, and review of a session\n"+
+			"  version                     print the build information\n")
+	}
+	return fs
+}
+
+// parseFlags parses the agi flags at the start of args and returns them with the launcher
+// arguments that follow, such as web -port 8080 api webui
+func parseFlags(args []string) (*cliFlags, []string, error) {
+	f := &cliFlags{}
+	fs := newFlagSet(f)
+
+	// The agi flags end at the first argument that is not one of them, so the flags of
+	// the default console launcher still reach it
+	end := 0
+	for end < len(args) {
+		name, _, hasValue := strings.Cut(strings.TrimLeft(args[end], "-"), "=")
+		if !strings.HasPrefix(args[end], "-") || fs.Lookup(name) == nil {
+			if name == "h" || name == "help" {
+				end++
+			}
+			break
+		}
+		end++
+		// Boolean flags such as --metrics take no separate value
+		if isBool, ok := fs.Lookup(name).Value.(interface{ IsBoolFlag() bool }); ok && isBool.IsBoolFlag() {
+			continue
+		}
+		if !hasValue && end < len(args) {
+			end++
+		}
+	}
+	if err := fs.Parse(args[:end]); err != nil {
+		return nil, nil, err
+	}
+	return f, args[end:], nil
+}
+
+// apply overrides the model configuration with the flags that are set. A provider other
+// than the configured one starts from its own defaults, since the configured model,
+// endpoint, token, and options belong to the other provider.
+func (f *cliFlags) apply(config *Config) error {
+	if f.provider != "" {
+		if f.provider != cmp.Or(config.Model.Provider, providerOllama) {
+			config.Model = ModelConfig{Warmup: config.Model.Warmup}
+		}
+		config.Model.Provider = f.provider
+	}
+	if f.model != "" {
+		config.Model.Name = f.model
+	}
+	if f.baseURL != "" {
+		config.Model.BaseURL = f.baseURL
+	}
+	if f.metrics {
+		config.Server.Metrics = true
+	}
+	if f.pprofAddress != "" {
+		config.Server.PprofAddress = f.pprofAddress
+	}
+	if f.drainTimeout != 0 {
+		config.Server.DrainTimeout = f.drainTimeout
+	}
+	if f.sessionDB != "" {
+		config.Server.SessionDB = f.sessionDB
+	}
+	if f.historyDir != "" {
+		config.Server.HistoryDir = f.historyDir
+	}
+	if f.maxConcurrentRuns != 0 {
+		config.Server.Limits.MaxConcurrentRuns = f.maxConcurrentRuns
+	}
+	if f.runsPerMinute != 0 {
+		config.Server.Limits.RunsPerMinute = f.runsPerMinute
+	}
+	// A certificate flag replaces configured ACME domains and the reverse, unless both are given
+	if f.tlsCert != "" || f.tlsKey != "" {
+		config.Server.TLS.CertFile, config.Server.TLS.KeyFile = f.tlsCert, f.tlsKey
+		if f.acmeDomains == "" {
+			config.Server.TLS.ACMEDomains = nil
+		}
+	}
+	if f.acmeDomains != "" {
+		config.Server.TLS.ACMEDomains = strings.FieldsFunc(f.acmeDomains, func(r rune) bool { return r == ',' || r == ' ' })
+		if f.tlsCert == "" && f.tlsKey == "" {
+			config.Server.TLS.CertFile, config.Server.TLS.KeyFile = "", ""
+		}
+	}
+	if f.options != "" {
+		options, err := parseOptions(f.options)
+		if err != nil {
+			return err
+		}
+		if config.Model.Options == nil {
+			config.Model.Options = make(map[string]any, len(options))
+		}
+		maps.Copy(config.Model.Options, options)
+	}
+	return nil
+}
+
+// parseOptions parses model options given as key=value pairs separated by commas, with
+// numbers and booleans converted, or as a JSON object
+func parseOptions(value string) (map[string]any, error) {
+	options := make(map[string]any)
+	if strings.HasPrefix(strings.TrimSpace(value), "{") {
+		if err := json.Unmarshal([]byte(value), &options); err != nil {
+			return nil, fmt.Errorf("invalid options %q: %w", value, err)
+		}
+		return options, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, raw, ok := strings.Cut(pair, "=")
+		key, raw = strings.TrimSpace(key), strings.TrimSpace(raw)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid option %q: want key=value", pair)
+		}
+		// YAML scalars give numbers and booleans their types, and anything else stays a string
+		var typed any
+		if err := yaml.Unmarshal([]byte(raw), &typed); err != nil || typed == nil {
+			typed = raw
+		}
+		options[key] = typed
+	}
+	return options, nil
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		want     cliFlags
+		wantArgs []string
+		wantErr  bool
+	}{
+		{
+			name: "no arguments",
+			want: cliFlags{},
+		},
+		{
+			name:     "flags before the launcher",
+			args:     []string{"--provider", "gemini", "-model=gemini-2.5-pro", "--base-url=http://proxy", "web", "-port", "9090", "api"},
+			want:     cliFlags{provider: "gemini", model: "gemini-2.5-pro", baseURL: "http://proxy"},
+			wantArgs: []string{"web", "-port", "9090", "api"},
+		},
+		{
+			name:     "console launcher flags",
+			args:     []string{"--options", "temperature=0.2", "-streaming_mode", "none"},
+			want:     cliFlags{options: "temperature=0.2"},
+			wantArgs: []string{"-streaming_mode", "none"},
+		},
+		{
+			name:     "server flags",
+			args:     []string{"--metrics", "--pprof-address", "localhost:6060", "--drain-timeout", "1m", "--session-db", "agi.db", "--history-dir", "runs", "web", "api"},
+			want:     cliFlags{metrics: true, pprofAddress: "localhost:6060", drainTimeout: time.Minute, sessionDB: "agi.db", historyDir: "runs"},
+			wantArgs: []string{"web", "api"},
+		},
+		{
+			name:    "missing value",
+			args:    []string{"--model"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, args, err := parseFlags(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseFlags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if *flags != tt.want {
+				t.Errorf("flags = %+v, want %+v", *flags, tt.want)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %q, want %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		value       string
+		want        map[string]any
+		errContains string
+	}{
+		{
+			name:  "pairs",
+			value: "temperature=0.2, top_k=40,do_sample=true,stop=END",
+			want:  map[string]any{"temperature": 0.2, "top_k": 40, "do_sample": true, "stop": "END"},
+		},
+		{
+			name:  "json",
+			value: `{"temperature": 0.2, "stop": ["\n\n"]}`,
+			want:  map[string]any{"temperature": 0.2, "stop": []any{"\n\n"}},
+		},
+		{
+			name:        "missing value",
+			value:       "temperature",
+			errContains: "want key=value",
+		},
+		{
+			name:        "malformed json",
+			value:       `{"temperature":`,
+			errContains: "invalid options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseOptions(tt.value)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseOptions() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseOptions() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseOptions() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlagsOverrideConfig(t *testing.T) {
+	clearConfigEnv(t)
+	t.Setenv("OLLAMA_BASE_URL", "http://ollama:11434")
+	t.Setenv("AGI_MODEL", "qwen2.5-coder:7b")
+
+	tests := []struct {
+		name  string
+		flags cliFlags
+		want  ModelConfig
+	}{
+		{
+			name:  "same provider keeps the endpoint",
+			flags: cliFlags{provider: "ollama", model: "llama3.2", options: "temperature=0.1"},
+			want:  ModelConfig{Provider: providerOllama, Name: "llama3.2", BaseURL: "http://ollama:11434", Options: map[string]any{"temperature": 0.1}, Warmup: true},
+		},
+		{
+			name:  "other provider starts from its defaults",
+			flags: cliFlags{provider: "gemini"},
+			want:  ModelConfig{Provider: providerGemini, Name: defaultGeminiModelName, Warmup: true},
+		},
+		{
+			name:  "base url",
+			flags: cliFlags{baseURL: "http://gpu:11434"},
+			want:  ModelConfig{Provider: providerOllama, Name: "qwen2.5-coder:7b", BaseURL: "http://gpu:11434", Warmup: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := readConfig("", &tt.flags)
+			if err != nil {
+				t.Fatalf("readConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(config.Model, tt.want) {
+				t.Errorf("Model = %+v, want %+v", config.Model, tt.want)
+			}
+		})
+	}
+
+	config, err := readConfig("", &cliFlags{metrics: true, pprofAddress: "localhost:6060", drainTimeout: time.Minute, sessionDB: "agi.db", historyDir: "runs"})
+	if err != nil || !config.Server.Metrics || config.Server.PprofAddress != "localhost:6060" || config.Server.DrainTimeout != time.Minute || config.Server.SessionDB != "agi.db" || config.Server.HistoryDir != "runs" {
+		t.Errorf("readConfig() = %+v, %v, want metrics, pprof, a 1m drain timeout, the session database, and the history directory", config, err)
+	}
+	if _, err := readConfig("", &cliFlags{provider: "openai"}); err == nil || !strings.Contains(err.Error(), "unknown model provider") {
+		t.Errorf("readConfig() error = %v, want an unknown provider", err)
+	}
+
+	config, err = readConfig("", &cliFlags{maxConcurrentRuns: 2, runsPerMinute: 6})
+	if err != nil || config.Server.Limits.MaxConcurrentRuns != 2 || config.Server.Limits.RunsPerMinute != 6 {
+		t.Errorf("readConfig() Limits = %+v, %v, want 2 concurrent runs and 6 runs per minute", config.Server.Limits, err)
+	}
+
+	// Certificate flags replace configured ACME domains, and the reverse
+	t.Setenv("AGI_SERVER_ACME_DOMAINS", "agi.example.com")
+	config, err = readConfig("", &cliFlags{tlsCert: "tls.crt", tlsKey: "tls.key"})
+	if err != nil || config.Server.TLS.CertFile != "tls.crt" || config.Server.TLS.KeyFile != "tls.key" || config.Server.TLS.ACMEDomains != nil {
+		t.Errorf("readConfig() TLS = %+v, %v, want the certificate files", config.Server.TLS, err)
+	}
+	t.Setenv("AGI_SERVER_ACME_DOMAINS", "")
+	t.Setenv("AGI_SERVER_TLS_CERT", "tls.crt")
+	t.Setenv("AGI_SERVER_TLS_KEY", "tls.key")
+	config, err = readConfig("", &cliFlags{acmeDomains: "a.example.com,b.example.com"})
+	want := TLSConfig{ACMEDomains: []string{"a.example.com", "b.example.com"}, ACMECacheDir: defaultACMECacheDir}
+	if err != nil || !reflect.DeepEqual(config.Server.TLS, want) {
+		t.Errorf("readConfig() TLS = %+v, %v, want %+v", config.Server.TLS, err, want)
+	}
+	if _, err := readConfig("", &cliFlags{tlsCert: "tls.crt", tlsKey: "tls.key", acmeDomains: "agi.example.com"}); err == nil || !strings.Contains(err.Error(), "exclusive") {
+		t.Errorf("readConfig() with certificate files and ACME domains error = %v, want exclusive", err)
+	}
+}
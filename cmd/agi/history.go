@@ -0,0 +1,587 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// historyKeyword is the web launcher argument that serves the run history
+const historyKeyword = "history"
+
+// Routes of the run history
+const (
+	historyRunsPath       = "/api/history/runs"
+	historyRunPath        = "/api/history/runs/{run_id}"
+	historyTranscriptPath = "/api/history/runs/{run_id}/transcript"
+	historyReplayPath     = "/api/history/runs/{run_id}/replay"
+)
+
+// replayWorkspaces is the directory of the history that holds the workspaces of the replays
+const replayWorkspaces = "workspaces"
+
+// errInvalidSessionID is returned for replays into a session whose ID is not a plain name
+var errInvalidSessionID = errors.New("invalid session ID")
+
+// Statuses of recorded runs
+const (
+	runRunning   = "running"
+	runCompleted = "completed"
+	runFailed    = "failed"
+)
+
+// runRecord is the record of a pipeline run in the run history
+type runRecord struct {
+	runInfo
+	// Transcript is the messages, tool calls, and tool results of the run
+	Transcript []progressEvent `json:"transcript,omitempty"`
+}
+
+// runInfo is a run record without its transcript, which listing the runs leaves out
+type runInfo struct {
+	// ID is the invocation ID of the run
+	ID string `json:"id"`
+	// AppName, UserID, and SessionID are the session of the run
+	AppName   string `json:"appName"`
+	UserID    string `json:"userId"`
+	SessionID string `json:"sessionId"`
+	// Model is the model the run used, except for routed stages
+	Model string `json:"model"`
+	// ReplayOf is the run this run replays
+	ReplayOf string `json:"replayOf,omitempty"`
+	// Input is the request of the run
+	Input string `json:"input"`
+	// Status is running, completed, or failed
+	Status string `json:"status"`
+	// Started and Completed are when the run started and completed
+	Started   time.Time `json:"started"`
+	Completed time.Time `json:"completed,omitzero"`
+	// Usage is the token usage of the stages
+	Usage agents.TokenUsage `json:"usage"`
+	// Stages are the stage invocations, in the order they started
+	Stages []stageRecord `json:"stages"`
+	// Files are the workspace files the run wrote, with their size and hash once it completed
+	Files []fileRecord `json:"files"`
+	// WorkspaceDir is the workspace the run wrote to
+	WorkspaceDir string `json:"workspaceDir"`
+}
+
+// stageRecord is a stage invocation of a recorded run
+type stageRecord struct {
+	// Stage is the stage name
+	Stage string `json:"stage"`
+	// Started is when the stage started
+	Started time.Time `json:"started"`
+	// DurationMS is how long the stage took, in milliseconds
+	DurationMS int64 `json:"durationMs"`
+	// Usage is the token usage of the stage
+	Usage agents.TokenUsage `json:"usage"`
+	// Output is the last message of the stage
+	Output string `json:"output,omitempty"`
+	// Error is the first error the stage reported
+	Error string `json:"error,omitempty"`
+
+	// done is set once the stage completed
+	done bool
+}
+
+// fileRecord is a workspace file a recorded run wrote
+type fileRecord struct {
+	// Path is the workspace-relative path of the file
+	Path string `json:"path"`
+	// Size is the size in bytes of the file when the run completed
+	Size int64 `json:"size"`
+	// SHA256 is the hex SHA-256 of the file when the run completed, empty when it was gone
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// replayInfo describes a replay running in a session
+type replayInfo struct {
+	// of is the ID of the replayed run
+	of string
+	// model is the model of the replay
+	model string
+	// workspaceDir is the workspace of the replay
+	workspaceDir string
+	// runID is the ID of the replay, set by its first event
+	runID string
+}
+
+// runHistory records the pipeline runs of the web server from their progress events, as a
+// JSON file per run in a directory, and replays them
+type runHistory struct {
+	// dir holds the records
+	dir string
+	// model is the name of the configured model
+	model string
+	// workspaceDir is the workspace of the runs other than replays
+	workspaceDir string
+	// newModel creates the model called name for a replay
+	newModel func(ctx context.Context, name string) (model.LLM, error)
+	// newAgent creates the agent of app for a replay with llm that writes to workspaceDir
+	newAgent func(app string, llm model.LLM, workspaceDir string) (agent.Agent, error)
+
+	mu sync.Mutex
+	// running are the records of the runs in progress, by run ID
+	running map[string]*runRecord
+	// replays are the running replays, by session key
+	replays map[string]*replayInfo
+}
+
+// newRunHistory returns the run history stored in dir, which is created if needed, of the
+// runs of the model called modelName in workspaceDir
+func newRunHistory(dir, modelName, workspaceDir string) (*runHistory, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create run history %s: %w", dir, err)
+	}
+	return &runHistory{
+		dir:          dir,
+		model:        modelName,
+		workspaceDir: workspaceDir,
+		running:      make(map[string]*runRecord),
+		replays:      make(map[string]*replayInfo),
+	}, nil
+}
+
+// record adds the progress event e to the record of its run, and saves the record when a
+// stage or the run completes
+func (h *runHistory) record(e progressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	rec, ok := h.running[e.RunID]
+	if !ok {
+		rec = &runRecord{runInfo: runInfo{
+			ID:           e.RunID,
+			AppName:      e.app,
+			UserID:       e.user,
+			SessionID:    e.SessionID,
+			Model:        h.model,
+			Status:       runRunning,
+			Started:      e.Time,
+			WorkspaceDir: h.workspaceDir,
+		}}
+		if replay, ok := h.replays[e.session]; ok && replay.runID == "" {
+			replay.runID = e.RunID
+			rec.ReplayOf, rec.Model, rec.WorkspaceDir = replay.of, replay.model, replay.workspaceDir
+		}
+		h.running[e.RunID] = rec
+	}
+
+	switch e.Type {
+	case progressRunStarted:
+		rec.Input, rec.Started = e.Text, e.Time
+	case progressStageStarted:
+		rec.Stages = append(rec.Stages, stageRecord{Stage: e.Stage, Started: e.Time})
+	case progressMessage:
+		if stage := rec.openStage(e.Stage); stage != nil {
+			stage.Output = e.Text
+		}
+		rec.Transcript = append(rec.Transcript, e)
+	case progressToolCall, progressToolResult:
+		rec.Transcript = append(rec.Transcript, e)
+	case progressFileWritten:
+		if !slices.ContainsFunc(rec.Files, func(f fileRecord) bool { return f.Path == e.Path }) {
+			rec.Files = append(rec.Files, fileRecord{Path: e.Path})
+		}
+	case progressStageCompleted:
+		if stage := rec.openStage(e.Stage); stage != nil {
+			stage.DurationMS, stage.Error, stage.done = e.DurationMS, e.Error, true
+			if e.Usage != nil {
+				stage.Usage = *e.Usage
+			}
+		}
+		if e.Usage != nil {
+			rec.Usage.PromptTokens += e.Usage.PromptTokens
+			rec.Usage.CompletionTokens += e.Usage.CompletionTokens
+			rec.Usage.TotalTokens += e.Usage.TotalTokens
+		}
+		h.save(rec)
+	case progressRunCompleted:
+		rec.Status, rec.Completed = runCompleted, e.Time
+		if slices.ContainsFunc(rec.Stages, func(s stageRecord) bool { return s.Error != "" }) {
+			rec.Status = runFailed
+		}
+		for i, f := range rec.Files {
+			rec.Files[i] = fileManifest(rec.WorkspaceDir, f.Path)
+		}
+		h.save(rec)
+		delete(h.running, e.RunID)
+	}
+}
+
+// openStage returns the last invocation of stage that has not completed, or nil
+func (r *runRecord) openStage(stage string) *stageRecord {
+	for i := len(r.Stages) - 1; i >= 0; i-- {
+		if r.Stages[i].Stage == stage && !r.Stages[i].done {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// fileManifest returns the record of the file at path in workspaceDir with its size and hash
+func fileManifest(workspaceDir, path string) fileRecord {
+	f := fileRecord{Path: path}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workspaceDir, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return f
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if f.Size, err = io.Copy(hash, file); err != nil {
+		return fileRecord{Path: f.Path}
+	}
+	f.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return f
+}
+
+// save writes rec to its file, replacing it at once so readers never see a partial record
+func (h *runHistory) save(rec *runRecord) {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		log.Printf("Failed to encode run %s: %v", rec.ID, err)
+		return
+	}
+	tmp, err := os.CreateTemp(h.dir, ".run-*")
+	if err != nil {
+		log.Printf("Failed to save run %s: %v", rec.ID, err)
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), h.path(rec.ID))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		log.Printf("Failed to save run %s: %v", rec.ID, err)
+	}
+}
+
+// path returns the file of the record of the run id
+func (h *runHistory) path(id string) string {
+	return filepath.Join(h.dir, id+".json")
+}
+
+// get returns the record of the run id, or an error wrapping os.ErrNotExist for unknown runs
+func (h *runHistory) get(id string) (*runRecord, error) {
+	rec := &runRecord{}
+	if err := h.load(id, rec, func(running *runRecord) {
+		rec.runInfo = running.runInfo
+		rec.Stages, rec.Files, rec.Transcript = slices.Clone(running.Stages), slices.Clone(running.Files), slices.Clone(running.Transcript)
+	}); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// info returns the record of the run id without its transcript, which is not decoded, or an
+// error wrapping os.ErrNotExist for unknown runs
+func (h *runHistory) info(id string) (*runInfo, error) {
+	info := &runInfo{}
+	if err := h.load(id, info, func(running *runRecord) {
+		*info = running.runInfo
+		info.Stages, info.Files = slices.Clone(running.Stages), slices.Clone(running.Files)
+	}); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// load decodes the record of the run id into v, or calls copyRunning with the record of the
+// run when it is in progress and has not saved a record yet
+func (h *runHistory) load(id string, v any, copyRunning func(*runRecord)) error {
+	if !localName(id) {
+		return fmt.Errorf("invalid run ID %q: %w", id, os.ErrNotExist)
+	}
+	h.mu.Lock()
+	if rec, ok := h.running[id]; ok {
+		if _, err := os.Stat(h.path(id)); errors.Is(err, os.ErrNotExist) {
+			copyRunning(rec)
+			h.mu.Unlock()
+			return nil
+		}
+	}
+	h.mu.Unlock()
+	data, err := os.ReadFile(h.path(id))
+	if err != nil {
+		return fmt.Errorf("failed to read run %s: %w", id, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode run %s: %w", id, err)
+	}
+	return nil
+}
+
+// localName reports whether id names a single file or directory within a directory, so it
+// cannot reach outside it
+func localName(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`) && filepath.IsLocal(id)
+}
+
+// list returns the records of the runs that match filter, newest first, without transcripts
+func (h *runHistory) list(filter func(*runInfo) bool) ([]*runInfo, error) {
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read run history: %w", err)
+	}
+	runs := []*runInfo{}
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".json")
+		if !ok || entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		info, err := h.info(id)
+		if err != nil {
+			log.Printf("Skipping run %s of the history: %v", id, err)
+			continue
+		}
+		if filter(info) {
+			runs = append(runs, info)
+		}
+	}
+	slices.SortFunc(runs, func(a, b *runInfo) int { return b.Started.Compare(a.Started) })
+	return runs, nil
+}
+
+// transcript returns the request, messages, tool calls, and tool results of rec as Markdown
+func (rec *runRecord) transcript() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Transcript of run %s\n\nApp: %s  \nUser: %s  \nSession: %s  \nModel: %s\n", rec.ID, rec.AppName, rec.UserID, rec.SessionID, rec.Model)
+	if rec.ReplayOf != "" {
+		fmt.Fprintf(&b, "Replay of: %s\n", rec.ReplayOf)
+	}
+	fmt.Fprintf(&b, "\n## Request\n\n%s\n", strings.TrimSpace(rec.Input))
+	author := ""
+	for _, e := range rec.Transcript {
+		if e.Agent != author {
+			author = e.Agent
+			fmt.Fprintf(&b, "\n## %s · %s\n\n", author, e.Time.UTC().Format(time.RFC3339))
+		}
+		switch e.Type {
+		case progressToolCall:
+			fmt.Fprintf(&b, "- → `%s` %s\n", e.Tool, toolArgs(e.Args))
+		case progressToolResult:
+			fmt.Fprintf(&b, "- ← `%s` %s\n", e.Tool, toolResult(e.Result))
+		default:
+			b.WriteString(strings.TrimSpace(e.Text) + "\n")
+		}
+	}
+	return b.String()
+}
+
+// replay runs the request of original again with the model called modelName in the session
+// sessionID of its app and user, which is created if it does not exist, and returns the ID of
+// the new run. The replay writes to a workspace of its own in the history, so it leaves the
+// workspace of the original run alone, and hub reports its progress.
+func (h *runHistory) replay(ctx context.Context, hub *progressHub, sessions session.Service, original *runRecord, modelName, sessionID string) (string, error) {
+	if sessionID != "" && !localName(sessionID) {
+		return "", fmt.Errorf("%w %q", errInvalidSessionID, sessionID)
+	}
+	llm, err := h.newModel(ctx, modelName)
+	if err != nil {
+		return "", err
+	}
+	if _, err := sessions.Get(ctx, &session.GetRequest{AppName: original.AppName, UserID: original.UserID, SessionID: sessionID}); sessionID == "" || err != nil {
+		created, err := sessions.Create(ctx, &session.CreateRequest{AppName: original.AppName, UserID: original.UserID, SessionID: sessionID})
+		if err != nil {
+			return "", fmt.Errorf("failed to create session: %w", err)
+		}
+		sessionID = created.Session.ID()
+	}
+	// The workspace is named by the server, as session IDs come from clients
+	workspaceDir := filepath.Join(h.dir, replayWorkspaces, uuid.NewString())
+	ag, err := h.newAgent(original.AppName, llm, workspaceDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to create app %s: %w", original.AppName, err)
+	}
+	r, err := runner.New(runner.Config{AppName: original.AppName, Agent: ag, SessionService: sessions})
+	if err != nil {
+		return "", fmt.Errorf("failed to create runner: %w", err)
+	}
+
+	key := sessionKey(original.AppName, original.UserID, sessionID)
+	replay := &replayInfo{of: original.ID, model: llm.Name(), workspaceDir: workspaceDir}
+	h.mu.Lock()
+	if _, ok := h.replays[key]; ok {
+		h.mu.Unlock()
+		return "", fmt.Errorf("session %s already runs a replay", sessionID)
+	}
+	h.replays[key] = replay
+	h.mu.Unlock()
+	log.Printf("Replaying run %s with model %s in session %s", original.ID, llm.Name(), sessionID)
+
+	// The replay completes like a run request of the session
+	hub.startRequest(key)
+	msg := genai.NewContentFromText(original.Input, genai.RoleUser)
+	for _, err := range r.Run(ctx, original.UserID, sessionID, msg, agent.RunConfig{}) {
+		if err != nil {
+			log.Printf("Replay of run %s failed: %v", original.ID, err)
+		}
+	}
+	hub.endRequest(key)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.replays, key)
+	if replay.runID == "" {
+		return "", fmt.Errorf("replay of run %s recorded no stage", original.ID)
+	}
+	return replay.runID, nil
+}
+
+// historyLauncher is the web sublauncher that serves the run history: the records and
+// transcripts of the pipeline runs, and their replays with another model
+type historyLauncher struct {
+	// history records the runs
+	history *runHistory
+	// hub reports the progress of the replays
+	hub *progressHub
+}
+
+// Keyword implements web.Sublauncher
+func (historyLauncher) Keyword() string {
+	return historyKeyword
+}
+
+// Parse implements web.Sublauncher; the history sublauncher has no flags
+func (historyLauncher) Parse(args []string) ([]string, error) {
+	return args, nil
+}
+
+// CommandLineSyntax implements web.Sublauncher
+func (historyLauncher) CommandLineSyntax() string {
+	return ""
+}
+
+// SimpleDescription implements web.Sublauncher
+func (historyLauncher) SimpleDescription() string {
+	return "serves the records and transcripts of the pipeline runs, and replays them with another model"
+}
+
+// SetupSubrouters implements web.Sublauncher
+func (l historyLauncher) SetupSubrouters(router *mux.Router, adkConfig *adk.Config) error {
+	router.HandleFunc(historyRunsPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		runs, err := l.history.list(func(rec *runInfo) bool {
+			for name, value := range map[string]string{"app": rec.AppName, "user": rec.UserID, "session": rec.SessionID} {
+				if want := query.Get(name); want != "" && want != value {
+					return false
+				}
+			}
+			return true
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, runs)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(historyRunPath, func(w http.ResponseWriter, r *http.Request) {
+		if rec, ok := l.run(w, r); ok {
+			writeJSON(w, rec)
+		}
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(historyTranscriptPath, func(w http.ResponseWriter, r *http.Request) {
+		if rec, ok := l.run(w, r); ok {
+			w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+			io.WriteString(w, rec.transcript())
+		}
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(historyReplayPath, func(w http.ResponseWriter, r *http.Request) {
+		original, ok := l.run(w, r)
+		if !ok {
+			return
+		}
+		var req struct {
+			// Model is the model of the replay, the configured model if empty
+			Model string `json:"model"`
+			// SessionID is the session of the replay, a new session if empty
+			SessionID string `json:"sessionId"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid replay request: %v", err), http.StatusBadRequest)
+				return
+			}
+		}
+		// The replay answers once it completes, which takes longer than the write timeout of the server
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			log.Printf("Failed to clear the write deadline of the replay of run %s: %v", original.ID, err)
+		}
+		runID, err := l.history.replay(r.Context(), l.hub, adkConfig.SessionService, original, cmp.Or(req.Model, l.history.model), req.SessionID)
+		if errors.Is(err, errInvalidSessionID) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("replay failed: %v", err), http.StatusInternalServerError)
+			return
+		}
+		rec, err := l.history.get(runID)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("replay not recorded: %v", err), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, rec)
+	}).Methods(http.MethodPost)
+	return nil
+}
+
+// UserMessage implements web.Sublauncher
+func (historyLauncher) UserMessage(webURL string, printer func(v ...any)) {
+	printer(fmt.Sprintf("   history:  run history at %s%s", webURL, historyRunsPath))
+}
+
+// run returns the record of the run of the request r, and answers requests for unknown runs
+func (l historyLauncher) run(w http.ResponseWriter, r *http.Request) (*runRecord, bool) {
+	rec, err := l.history.get(mux.Vars(r)["run_id"])
+	if errors.Is(err, os.ErrNotExist) {
+		http.Error(w, "run not found", http.StatusNotFound)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+	return rec, true
+}
+
+// writeJSON writes v to w as JSON
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("Failed to write response: %v", err)
+	}
+}
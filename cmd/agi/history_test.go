@@ -0,0 +1,226 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// recordRun runs the design stage of the test run id through the hooks of h, writing main.go
+// to the workspace of the history, and completes the run
+func recordRun(t *testing.T, h *progressHub, id string, stageErr error) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(h.history.workspaceDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to write main.go: %v", err)
+	}
+	ctx := context.Background()
+	hooks := h.hooks()
+	written := &genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "fileWrite", Response: map[string]any{"success": true, "path": "main.go"}}}
+	hooks.BeforeStage(ctx, testStage(id))
+	hooks.StageEvent(ctx, testStage(id), modelEvent(false, genai.NewPartFromFunctionCall("fileWrite", map[string]any{"path": "main.go"})))
+	hooks.StageEvent(ctx, testStage(id), modelEvent(false, written))
+	hooks.StageEvent(ctx, testStage(id), modelEvent(false, genai.NewPartFromText("Design done")))
+	hooks.AfterStage(ctx, agents.StageResult{StageInfo: testStage(id), Duration: time.Second, Usage: agents.TokenUsage{PromptTokens: 10, CompletionTokens: 5, TotalTokens: 15}, Err: stageErr})
+	h.startRequest(sessionKey("agi", "user", "s1"))
+	h.endRequest(sessionKey("agi", "user", "s1"))
+}
+
+func TestRunHistory(t *testing.T) {
+	history, err := newRunHistory(filepath.Join(t.TempDir(), "runs"), "fake-model", t.TempDir())
+	if err != nil {
+		t.Fatalf("newRunHistory() error = %v", err)
+	}
+	h := newProgressHub()
+	h.history = history
+
+	// Runs in progress are served from memory until their first stage completes
+	h.hooks().BeforeStage(context.Background(), testStage("run-0"))
+	rec, err := history.get("run-0")
+	if err != nil || rec.Status != runRunning || rec.Input != "Build a calculator" || len(rec.Stages) != 1 {
+		t.Fatalf("get() of a running run = %+v, %v; want it running with its request and stage", rec, err)
+	}
+
+	recordRun(t, h, "run-1", nil)
+	rec, err = history.get("run-1")
+	if err != nil {
+		t.Fatalf("get() error = %v", err)
+	}
+	if rec.Status != runCompleted || rec.AppName != "agi" || rec.UserID != "user" || rec.SessionID != "s1" || rec.Model != "fake-model" || rec.Completed.IsZero() {
+		t.Errorf("record = %+v, want the completed run of session s1 with fake-model", rec)
+	}
+	if len(rec.Stages) != 1 || rec.Stages[0].Output != "Design done" || rec.Stages[0].DurationMS != 1000 || rec.Stages[0].Usage.TotalTokens != 15 {
+		t.Errorf("stages = %+v, want the design stage with its output, duration, and usage", rec.Stages)
+	}
+	if rec.Usage.PromptTokens != 10 || rec.Usage.TotalTokens != 15 {
+		t.Errorf("usage = %+v, want the usage of the stages", rec.Usage)
+	}
+	sum := sha256.Sum256([]byte("package main\n"))
+	if want := []fileRecord{{Path: "main.go", Size: 13, SHA256: hex.EncodeToString(sum[:])}}; !slices.Equal(rec.Files, want) {
+		t.Errorf("files = %+v, want %+v", rec.Files, want)
+	}
+	if got := eventSummary(rec.Transcript); !slices.Equal(got, []string{"tool_call fileWrite", "tool_result fileWrite", "message Design done"}) {
+		t.Errorf("transcript = %q, want the tool calls and messages", got)
+	}
+
+	recordRun(t, h, "run-2", errors.New("no design"))
+	if rec, err := history.get("run-2"); err != nil || rec.Status != runFailed {
+		t.Errorf("get() of a run with a failed stage = %+v, %v; want it failed", rec, err)
+	}
+
+	runs, err := history.list(func(rec *runInfo) bool { return rec.SessionID == "s1" })
+	if err != nil {
+		t.Fatalf("list() error = %v", err)
+	}
+	var ids []string
+	for _, rec := range runs {
+		ids = append(ids, rec.ID)
+	}
+	if !slices.Equal(ids, []string{"run-2", "run-1", "run-0"}) {
+		t.Errorf("list() = %q, want the runs newest first", ids)
+	}
+
+	for _, id := range []string{"run-3", "../run-1", ""} {
+		if _, err := history.get(id); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("get(%q) error = %v, want os.ErrNotExist", id, err)
+		}
+	}
+}
+
+func TestRunRecord_Transcript(t *testing.T) {
+	rec := &runRecord{
+		runInfo: runInfo{ID: "run-1", AppName: "agi", UserID: "user", SessionID: "s1", Model: "fake-model", ReplayOf: "run-0", Input: "Build a calculator\n"},
+		Transcript: []progressEvent{
+			{Type: progressToolCall, Agent: "DesignAgent", Tool: "fileRead", Args: map[string]any{"path": "go.mod"}},
+			{Type: progressToolResult, Agent: "DesignAgent", Tool: "fileRead", Result: map[string]any{"success": true}},
+			{Type: progressMessage, Agent: "DesignAgent", Text: "Design done"},
+			{Type: progressMessage, Agent: "CodeWriterAgent", Text: "Wrote calc.go"},
+		},
+	}
+	got := rec.transcript()
+	for _, want := range []string{"# Transcript of run run-1", "Replay of: run-0", "## Request\n\nBuild a calculator\n", "## DesignAgent", "`fileRead`", "Design done\n", "## CodeWriterAgent"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("transcript() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Count(got, "## DesignAgent") != 1 {
+		t.Errorf("transcript() = %q, want one heading per run of messages of an agent", got)
+	}
+}
+
+func TestHistoryLauncher(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "runs")
+	history, err := newRunHistory(dir, "fake-model", t.TempDir())
+	if err != nil {
+		t.Fatalf("newRunHistory() error = %v", err)
+	}
+	h := newProgressHub()
+	h.history = history
+	history.newModel = func(_ context.Context, name string) (model.LLM, error) {
+		return fake.New(name,
+			fake.Text("design: a calculator"),
+			fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": "package calc\n"}),
+			fake.Text("wrote calc.go"),
+			fake.Text("no tests needed"),
+			fake.Text("No major issues found."),
+		), nil
+	}
+	history.newAgent = func(app string, llm model.LLM, workspaceDir string) (agent.Agent, error) {
+		return agents.NewAgent(agents.PipelineConfig{Model: llm, WorkspaceDir: workspaceDir, SkipBuild: true, SkipTests: true, Hooks: h.hooks()})
+	}
+	recordRun(t, h, "run-1", nil)
+
+	sessions := inmemory.New()
+	router := mux.NewRouter()
+	if err := (historyLauncher{history: history, hub: h}).SetupSubrouters(router, &adk.Config{SessionService: sessions}); err != nil {
+		t.Fatalf("SetupSubrouters() error = %v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	get := func(path string) (int, string) {
+		t.Helper()
+		resp, err := http.Get(server.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, string(body)
+	}
+	tests := []struct {
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{path: "/api/history/runs", wantStatus: http.StatusOK, wantBody: `"id":"run-1"`},
+		{path: "/api/history/runs?session=s2", wantStatus: http.StatusOK, wantBody: "[]"},
+		{path: "/api/history/runs/run-1", wantStatus: http.StatusOK, wantBody: `"transcript":[`},
+		{path: "/api/history/runs/run-1/transcript", wantStatus: http.StatusOK, wantBody: "## Request\n\nBuild a calculator"},
+		{path: "/api/history/runs/run-2", wantStatus: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		status, body := get(tt.path)
+		if status != tt.wantStatus || !strings.Contains(body, tt.wantBody) {
+			t.Errorf("GET %s = %d %q, want %d with %q", tt.path, status, body, tt.wantStatus, tt.wantBody)
+		}
+	}
+	if _, body := get("/api/history/runs"); strings.Contains(body, `"transcript"`) {
+		t.Errorf("GET /api/history/runs = %q, want the runs without their transcripts", body)
+	}
+
+	// A replay runs the request again with another model in a workspace of its own
+	resp, err := http.Post(server.URL+"/api/history/runs/run-1/replay", "application/json", strings.NewReader(`{"model":"other-model"}`))
+	if err != nil {
+		t.Fatalf("POST replay error = %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	replay := &runRecord{}
+	if err := json.Unmarshal(body, replay); err != nil || resp.StatusCode != http.StatusOK {
+		t.Fatalf("replay = %d %q, want its record", resp.StatusCode, body)
+	}
+	if replay.ReplayOf != "run-1" || replay.Model != "other-model" || replay.Status != runCompleted || replay.Input != "Build a calculator" || replay.SessionID == "s1" {
+		t.Errorf("replay = %+v, want a completed replay of run-1 with other-model in a new session", replay)
+	}
+	if want := filepath.Join(dir, replayWorkspaces); filepath.Dir(replay.WorkspaceDir) != want || len(replay.Files) != 1 || replay.Files[0].SHA256 == "" {
+		t.Errorf("replay workspace %s with files %+v, want calc.go in a workspace in %s", replay.WorkspaceDir, replay.Files, want)
+	}
+	if _, err := os.Stat(filepath.Join(history.workspaceDir, "calc.go")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("replay wrote to the workspace of the original run: %v", err)
+	}
+
+	// Session IDs that are not plain names are refused, so no replay writes outside the history
+	for _, body := range []string{`{`, `{"sessionId":"../../escape"}`, `{"sessionId":"a/b"}`} {
+		resp, err = http.Post(server.URL+"/api/history/runs/run-1/replay", "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("POST replay error = %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("replay with body %s status = %d, want 400", body, resp.StatusCode)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "..", "escape")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("replay with a ../ session ID wrote outside the history: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultQueueTimeout is how long a run waits for a free slot when the server runs its
+// maximum of concurrent runs
+const defaultQueueTimeout = time.Minute
+
+// maxRunBodyBytes is the largest run request body the server reads to find its session
+const maxRunBodyBytes = 10 << 20
+
+// bucketPruneInterval is how often the rate limiter forgets the clients whose budget is full
+const bucketPruneInterval = 10 * time.Minute
+
+// runLimiter is the middleware that limits the runs of the web server: the runs each client
+// starts per minute, the concurrent runs of each session, and the concurrent runs of the
+// server, which protect a single model backend from being overwhelmed
+type runLimiter struct {
+	// config holds the limits
+	config LimitsConfig
+	// slots holds a token for each run in progress on the server, nil without a server limit
+	slots chan struct{}
+	// now returns the current time, for tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// buckets are the run budgets of the clients
+	buckets map[string]*runBucket
+	// sessions are the numbers of runs in progress of each session
+	sessions map[string]int
+	// pruned is when the full buckets were last forgotten
+	pruned time.Time
+}
+
+// runBucket is the token bucket of a client: each run takes a token, and tokens come back
+// at the configured rate up to a minute's worth
+type runBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// newRunLimiter returns the limiter of config, or nil when config sets no limit
+func newRunLimiter(config LimitsConfig) *runLimiter {
+	if config.MaxConcurrentRuns == 0 && config.MaxSessionRuns == 0 && config.RunsPerMinute == 0 {
+		return nil
+	}
+	l := &runLimiter{
+		config:   config,
+		now:      time.Now,
+		buckets:  make(map[string]*runBucket),
+		sessions: make(map[string]int),
+	}
+	if config.MaxConcurrentRuns > 0 {
+		l.slots = make(chan struct{}, config.MaxConcurrentRuns)
+	}
+	return l
+}
+
+// wrap returns next with the run requests limited. Runs over a client or session limit get
+// 429 Too Many Requests; runs over the server limit wait for a slot up to the queue timeout
+// and then get 503 Service Unavailable. A nil limiter lets every run through.
+func (l *runLimiter) wrap(next http.Handler) http.Handler {
+	if l == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isRunRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		session, ok := runSession(w, r)
+		if !ok {
+			return
+		}
+		if l.config.RunsPerMinute > 0 {
+			client := runClient(r)
+			if retryAfter, ok := l.allow(client); !ok {
+				log.Printf("Rate limited run of %s", client)
+				tooManyRuns(w, http.StatusTooManyRequests, retryAfter, fmt.Sprintf("more than %d runs per minute", l.config.RunsPerMinute))
+				return
+			}
+		}
+		if l.config.MaxSessionRuns > 0 && session != "" {
+			if !l.startSession(session) {
+				tooManyRuns(w, http.StatusTooManyRequests, time.Second, "a run is already in progress in this session")
+				return
+			}
+			defer l.endSession(session)
+		}
+		if l.slots != nil {
+			if !l.acquire(r) {
+				if r.Context().Err() == nil {
+					tooManyRuns(w, http.StatusServiceUnavailable, l.config.QueueTimeout, "the server is busy with other runs")
+				}
+				return
+			}
+			defer func() { <-l.slots }()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allow takes a token from the bucket of client, or returns how long until the next token
+func (l *runLimiter) allow(client string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	capacity := float64(l.config.RunsPerMinute)
+	perSecond := capacity / 60
+	if now.Sub(l.pruned) >= bucketPruneInterval {
+		for c, b := range l.buckets {
+			if b.tokens+now.Sub(b.updated).Seconds()*perSecond >= capacity {
+				delete(l.buckets, c)
+			}
+		}
+		l.pruned = now
+	}
+
+	b, ok := l.buckets[client]
+	if !ok {
+		b = &runBucket{tokens: capacity, updated: now}
+		l.buckets[client] = b
+	}
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updated).Seconds()*perSecond)
+	b.updated = now
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / perSecond * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+// startSession records a run of session, unless the session runs its maximum
+func (l *runLimiter) startSession(session string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sessions[session] >= l.config.MaxSessionRuns {
+		return false
+	}
+	l.sessions[session]++
+	return true
+}
+
+// endSession records the end of a run of session
+func (l *runLimiter) endSession(session string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.sessions[session]--; l.sessions[session] <= 0 {
+		delete(l.sessions, session)
+	}
+}
+
+// acquire waits for a free run slot of the server until the queue timeout or until the
+// request is canceled, and reports whether it got one
+func (l *runLimiter) acquire(r *http.Request) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+	log.Printf("Run queued: %d runs in progress", len(l.slots))
+	timer := time.NewTimer(l.config.QueueTimeout)
+	defer timer.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-r.Context().Done():
+		return false
+	}
+}
+
+// tooManyRuns rejects a run with status and the time after which to retry it
+func tooManyRuns(w http.ResponseWriter, status int, retryAfter time.Duration, reason string) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	http.Error(w, "run rejected: "+reason, status)
+}
+
+// runSession returns the app, user, and session of a REST run request, or "" for other
+// requests, such as A2A requests and replays. The body is restored for the handler. Bodies
+// larger than maxRunBodyBytes are answered with 413 Request Entity Too Large, and ok is false.
+func runSession(w http.ResponseWriter, r *http.Request) (session string, ok bool) {
+	if r.Body == nil || !strings.HasSuffix(r.URL.Path, "/run") && !strings.HasSuffix(r.URL.Path, "/run_sse") {
+		return "", true
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRunBodyBytes))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if maxErr := (*http.MaxBytesError)(nil); errors.As(err, &maxErr) {
+		http.Error(w, fmt.Sprintf("run request larger than %d bytes", maxErr.Limit), http.StatusRequestEntityTooLarge)
+		return "", false
+	}
+	if err != nil {
+		return "", true
+	}
+	var run struct {
+		AppName   string `json:"appName"`
+		UserID    string `json:"userId"`
+		SessionID string `json:"sessionId"`
+	}
+	if json.Unmarshal(body, &run) != nil || run.SessionID == "" {
+		return "", true
+	}
+	return sessionKey(run.AppName, run.UserID, run.SessionID), true
+}
+
+// runClient returns the client a run is counted against: the authenticated client, else the
+// remote address. Unauthenticated runs are not counted by the session of their body, which
+// the client chooses.
+func runClient(r *http.Request) string {
+	if client := requestClient(r.Context()); client != "" {
+		return client
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// runRequest returns a REST run request of session
+func runRequest(session string) *http.Request {
+	body := `{"appName": "agi", "userId": "user", "sessionId": "` + session + `", "newMessage": {"parts": [{"text": "hi"}]}}`
+	return httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body))
+}
+
+func TestRunLimiter_RunsPerMinute(t *testing.T) {
+	l := newRunLimiter(LimitsConfig{RunsPerMinute: 2})
+	now := time.Now()
+	l.now = func() time.Time { return now }
+	handler := l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	run := func(req *http.Request) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+	authenticated := func(client string) *http.Request {
+		req := runRequest("other")
+		return req.WithContext(context.WithValue(req.Context(), clientKey{}, client))
+	}
+
+	for i := range 2 {
+		if rec := run(runRequest("s1")); rec.Code != http.StatusOK {
+			t.Fatalf("run %d status = %d, want 200", i+1, rec.Code)
+		}
+	}
+	rec := run(runRequest("s1"))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "30" {
+		t.Errorf("third run = %d, Retry-After %q; want 429 after 30s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	// Unauthenticated clients are counted by address, whatever session they name
+	if rec := run(runRequest("s2")); rec.Code != http.StatusTooManyRequests {
+		t.Errorf("run of another session of the client status = %d, want 429", rec.Code)
+	}
+	other := runRequest("s1")
+	other.RemoteAddr = "198.51.100.7:4321"
+	if rec := run(other); rec.Code != http.StatusOK {
+		t.Errorf("run of another address status = %d, want 200", rec.Code)
+	}
+	if rec := run(httptest.NewRequest(http.MethodGet, "/api/list-apps", nil)); rec.Code != http.StatusOK {
+		t.Errorf("other request status = %d, want 200", rec.Code)
+	}
+
+	// Authenticated clients are counted across their sessions
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if rec := run(authenticated("key:abc")); rec.Code != want {
+			t.Errorf("authenticated run %d status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+	if rec := run(authenticated("oidc:dev")); rec.Code != http.StatusOK {
+		t.Errorf("run of another client status = %d, want 200", rec.Code)
+	}
+
+	now = now.Add(30 * time.Second)
+	if rec := run(runRequest("s1")); rec.Code != http.StatusOK {
+		t.Errorf("run after the refill status = %d, want 200", rec.Code)
+	}
+	now = now.Add(bucketPruneInterval)
+	run(runRequest("s2"))
+	if len(l.buckets) != 1 {
+		t.Errorf("buckets after pruning = %d, want only the client of the last run", len(l.buckets))
+	}
+}
+
+func TestRunLimiter_SessionRuns(t *testing.T) {
+	l := newRunLimiter(LimitsConfig{MaxSessionRuns: 1})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	handler := l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The handler reads the request the limiter already read
+		body, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(body), `"sessionId": "s1"`) {
+			close(started)
+			<-release
+		}
+	}))
+
+	done := make(chan int)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, runRequest("s1"))
+		done <- rec.Code
+	}()
+	<-started
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, runRequest("s1"))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Errorf("second run of the session status = %d, want 429", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, runRequest("s2"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("run of another session status = %d, want 200", rec.Code)
+	}
+	close(release)
+	if code := <-done; code != http.StatusOK {
+		t.Errorf("first run status = %d, want 200", code)
+	}
+	if len(l.sessions) != 0 {
+		t.Errorf("sessions after the runs = %v, want none", l.sessions)
+	}
+}
+
+func TestRunLimiter_ConcurrentRuns(t *testing.T) {
+	tests := []struct {
+		name       string
+		release    bool
+		wantStatus int
+	}{
+		{name: "queued run starts when a slot frees", release: true, wantStatus: http.StatusOK},
+		{name: "queued run rejected after the queue timeout", wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newRunLimiter(LimitsConfig{MaxConcurrentRuns: 1, QueueTimeout: 200 * time.Millisecond})
+			started := make(chan struct{}, 2)
+			release := make(chan struct{})
+			handler := l.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				started <- struct{}{}
+				<-release
+			}))
+			first := make(chan int)
+			go func() {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, runRequest("s1"))
+				first <- rec.Code
+			}()
+			<-started
+
+			second := make(chan *httptest.ResponseRecorder)
+			go func() {
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, runRequest("s2"))
+				second <- rec
+			}()
+			if tt.release {
+				time.Sleep(20 * time.Millisecond)
+				release <- struct{}{}
+				<-started
+				close(release)
+			}
+			rec := <-second
+			if !tt.release {
+				close(release)
+			}
+			<-first
+			if rec.Code != tt.wantStatus {
+				t.Errorf("queued run status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusServiceUnavailable && rec.Header().Get("Retry-After") != "1" {
+				t.Errorf("Retry-After = %q, want 1", rec.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestNewRunLimiter(t *testing.T) {
+	if l := newRunLimiter(LimitsConfig{QueueTimeout: time.Minute}); l != nil {
+		t.Fatalf("newRunLimiter() without limits = %+v, want nil", l)
+	}
+	var open *runLimiter
+	rec := httptest.NewRecorder()
+	open.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, runRequest("s1"))
+	if rec.Code != http.StatusOK {
+		t.Errorf("run without limits status = %d, want 200", rec.Code)
+	}
+}
+
+func TestRunSession(t *testing.T) {
+	tests := []struct {
+		name string
+		req  *http.Request
+		want string
+	}{
+		{name: "REST run", req: runRequest("s1"), want: "agi/user/s1"},
+		{name: "streamed run", req: httptest.NewRequest(http.MethodPost, "/api/run_sse", strings.NewReader(`{"appName":"a","userId":"u","sessionId":"s"}`)), want: "a/u/s"},
+		{name: "A2A", req: httptest.NewRequest(http.MethodPost, "/a2a/invoke", strings.NewReader(`{"sessionId":"s"}`))},
+		{name: "replay", req: httptest.NewRequest(http.MethodPost, "/api/history/runs/r/replay", strings.NewReader(`{"sessionId":"s"}`))},
+		{name: "invalid body", req: httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(`not json`))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, ok := runSession(httptest.NewRecorder(), tt.req); got != tt.want || !ok {
+				t.Errorf("runSession() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunSession_TooLarge(t *testing.T) {
+	body := `{"sessionId": "s1", "newMessage": {"parts": [{"text": "` + strings.Repeat("x", maxRunBodyBytes) + `"}]}}`
+	rec := httptest.NewRecorder()
+	if _, ok := runSession(rec, httptest.NewRequest(http.MethodPost, "/api/run", strings.NewReader(body))); ok || rec.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("runSession() of a large body = %v, status %d; want 413", ok, rec.Code)
+	}
+}
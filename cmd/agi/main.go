@@ -1,69 +1,243 @@
 package main
 
 import (
+	"cmp"
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 
 	"com.github.dimetron.adk-go-agi/pkg/agents"
-	ollamamodel "com.github.dimetron.adk-go-agi/pkg/model/ollama"
+	modelmetrics "com.github.dimetron.adk-go-agi/pkg/model/metrics"
+	"com.github.dimetron.adk-go-agi/pkg/session/sqlite"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
 	"google.golang.org/adk/cmd/launcher/adk"
-	"google.golang.org/adk/cmd/launcher/full"
-	"google.golang.org/adk/server/restapi/services"
+	adkmodel "google.golang.org/adk/model"
 )
 
 func main() {
 	// Create context with signal handling for graceful shutdown
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
+	// A second signal stops the process at once instead of waiting for the drain
+	context.AfterFunc(ctx, cancel)
 
-	// Initialize the Ollama model using the official Ollama Go API client
-	// You can change the model name to any model you have installed in Ollama
-	// Examples: "llama3.2", "mistral", "codellama", "gemma2", "qwen2.5-coder", etc.
-	ollamaBaseURL := os.Getenv("OLLAMA_BASE_URL")
-	if ollamaBaseURL == "" {
-		ollamaBaseURL = "http://localhost:11434"
+	// Flags such as --model come before the launcher arguments
+	flags, args, err := parseFlags(os.Args[1:])
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return
+		}
+		log.Fatalf("invalid flags: %s", err)
 	}
 
-	modelName := os.Getenv("OLLAMA_MODEL")
-	if modelName == "" {
-		//modelName = "gpt-oss:120b-cloud" // Default Ollama model
-		modelName = "gpt-oss:120b-cloud"
+	// agi version identifies the binary without reading the configuration
+	if len(args) > 0 && args[0] == versionCommand {
+		readBuildInfo().write(os.Stdout)
+		return
 	}
 
-	log.Printf("Initializing Ollama model: %s at %s", modelName, ollamaBaseURL)
+	// Read agi.yaml, or the file in AGI_CONFIG, with the environment and flag overrides
+	config, err := loadConfig(flags)
+	if err != nil {
+		log.Fatalf("failed to load configuration: %s", err)
+	}
+
+	// Profiling runs on its own admin address, so it works in every mode
+	if config.Server.PprofAddress != "" {
+		if _, err := startPprofServer(ctx, config.Server.PprofAddress); err != nil {
+			log.Fatalf("failed to start pprof: %s", err)
+		}
+	}
 
-	model, err := ollamamodel.NewModel(ctx, &ollamamodel.Config{
-		ModelName: modelName,
-		BaseURL:   ollamaBaseURL,
-		Options: map[string]interface{}{
-			"temperature": 0.7,
-			"top_p":       0.9,
-		},
-	})
+	// agi run executes the pipeline once, agi chat converses in the terminal, and agi export
+	// downloads a session, instead of starting a launcher like agi serve
+	var run *runFlags
+	var terminal *chatTerminal
+	if len(args) > 0 {
+		switch args[0] {
+		case runCommand:
+			if run, err = parseRunFlags(args[1:]); err != nil {
+				if errors.Is(err, flag.ErrHelp) {
+					return
+				}
+				log.Printf("invalid run arguments: %s", err)
+				os.Exit(exitUsage)
+			}
+			run.apply(config)
+		case chatCommand:
+			if len(args) > 1 {
+				log.Fatalf("unexpected chat arguments: %s", strings.Join(args[1:], " "))
+			}
+			terminal = newChatTerminal(os.Stdout)
+		case exportCommand:
+			export, err := parseExportFlags(args[1:], exportServerURL(config.Server), exportToken(config.Server))
+			if err != nil {
+				if errors.Is(err, flag.ErrHelp) {
+					return
+				}
+				log.Fatalf("invalid export arguments: %s", err)
+			}
+			if err := runExport(ctx, export, os.Stdout); err != nil {
+				log.Fatalf("export failed: %s", err)
+			}
+			return
+		}
+	}
+
+	log.Printf("Initializing %s model: %s at %s", config.Model.Provider, config.Model.Name, config.Model.BaseURL)
+	model, err := newModel(ctx, config.Model, config.Model.Name)
 	if err != nil {
-		log.Fatalf("failed to create Ollama model: %s", err)
+		log.Fatalf("failed to create model: %s", err)
+	}
+
+	// Agents cannot change the workspace in read-only mode, for deployments open to untrusted users
+	tools.ToolConfig.ReadOnly = config.ReadOnly
+	if config.SymlinkPolicy != "" {
+		tools.ToolConfig.SymlinkPolicy = config.SymlinkPolicy
 	}
 
-	// Create the code pipeline agent using the factory function
-	rootAgent, err := agents.NewCodePipelineAgent(agents.PipelineConfig{
-		Model: model,
-	})
+	pipelineConfig := config.Pipeline
+	pipelineConfig.Model = model
+	if pipelineConfig.Models, err = newRoutedModels(ctx, config.Model, pipelineConfig.ModelRouting); err != nil {
+		log.Fatalf("failed to create routed models: %s", err)
+	}
+	// The metrics endpoint exports the requests of every model and the runs of every stage
+	if config.Server.Metrics {
+		pipelineConfig.Model = modelmetrics.Wrap(pipelineConfig.Model)
+		for name, llm := range pipelineConfig.Models {
+			pipelineConfig.Models[name] = modelmetrics.Wrap(llm)
+		}
+		pipelineConfig.Hooks = agents.MetricsHooks()
+	}
+	if pipelineConfig.DryRun {
+		printPlan(pipelineConfig)
+		return
+	}
+
+	summary := &runSummary{}
+	if run != nil {
+		pipelineConfig.Progress = summary
+	} else if terminal != nil {
+		pipelineConfig.Progress = terminal
+	}
+
+	// The web server drains on shutdown by stopping runs before their next stage, streams
+	// the progress of the runs, and records them in the run history
+	drain := make(chan struct{})
+	progress := newProgressHub()
+	var history *runHistory
+	if run == nil && terminal == nil {
+		pipelineConfig.Drain = drain
+		pipelineConfig.Hooks = agents.CombineHooks(pipelineConfig.Hooks, progress.hooks())
+		if config.Server.HistoryDir != "" {
+			history, err = newRunHistory(config.Server.HistoryDir, config.Model.Name, cmp.Or(pipelineConfig.WorkspaceDir, tools.DefaultWorkspaceDir))
+			if err != nil {
+				log.Fatalf("failed to open run history: %s", err)
+			}
+			progress.history = history
+			log.Printf("Recording runs in %s", config.Server.HistoryDir)
+		}
+	}
+
+	rootAgent, err := newRootAgent(pipelineConfig, config.TaskRouter)
 	if err != nil {
-		log.Fatalf("failed to create code pipeline agent: %s", err)
+		log.Fatalf("failed to create root agent: %s", err)
+	}
+
+	// Preload the model so the first request doesn't pay the model load cost.
+	// Set OLLAMA_WARMUP=false to skip (e.g. when the server starts before Ollama).
+	if config.Model.Warmup {
+		if w, ok := model.(interface{ Warmup(context.Context) error }); ok {
+			if err := w.Warmup(ctx); err != nil {
+				log.Printf("Model warm-up failed, continuing without preload: %v", err)
+			}
+		}
 	}
 
 	// The rootAgent can now be used by the ADK framework.
 	log.Printf("Successfully created root agent: %s", rootAgent.Name())
 
-	config := &adk.Config{
-		AgentLoader: services.NewSingleAgentLoader(rootAgent),
+	// Replays run the apps again with the model they ask for
+	if history != nil {
+		history.newModel = func(ctx context.Context, name string) (adkmodel.LLM, error) {
+			llm, err := newModel(ctx, config.Model, name)
+			if err != nil || !config.Server.Metrics {
+				return llm, err
+			}
+			return modelmetrics.Wrap(llm), nil
+		}
+		history.newAgent = func(app string, llm adkmodel.LLM, workspaceDir string) (agent.Agent, error) {
+			return newReplayAgent(app, pipelineConfig, llm, workspaceDir, rootAgent.Name(), config.TaskRouter)
+		}
+	}
+
+	if run != nil {
+		code := runHeadless(ctx, rootAgent, run, summary, os.Stdout)
+		cancel()
+		os.Exit(code)
 	}
-	l := full.NewLauncher()
-	err = l.Execute(ctx, config, os.Args[1:])
+	if terminal != nil {
+		// The terminal shows the progress, so only warnings and errors are logged
+		slog.SetLogLoggerLevel(slog.LevelWarn)
+		if err := runChat(ctx, rootAgent, terminal, os.Stdin); err != nil {
+			log.Fatalf("chat failed: %v", err)
+		}
+		return
+	}
+
+	// The other pipelines are served as apps selectable by name, with the root agent as the default
+	agentLoader, err := newAgentLoader(pipelineConfig, rootAgent)
+	if err != nil {
+		log.Fatalf("failed to create apps: %s", err)
+	}
+	adkConfig := &adk.Config{
+		AgentLoader: agentLoader,
+	}
+	// Sessions are kept in memory unless a database stores them across restarts; the web
+	// launcher closes the database on shutdown
+	if config.Server.SessionDB != "" {
+		sessions, err := sqlite.New(config.Server.SessionDB)
+		if err != nil {
+			log.Fatalf("failed to open sessions: %s", err)
+		}
+		adkConfig.SessionService = sessions
+		log.Printf("Storing sessions in %s", config.Server.SessionDB)
+	}
+	// Launcher arguments take precedence over the server section of the configuration
+	args = serveArgs(args, config.Server)
+	if config.Server.Metrics {
+		if args = withSublauncher(args, metricsKeyword); len(args) == 0 || args[0] != launcherWeb {
+			log.Printf("Metrics are served only by the web launcher")
+		}
+	}
+	// Sessions can be downloaded with their workspace, and runs followed, from every web server
+	args = withSublauncher(withSublauncher(args, exportKeyword), progressKeyword)
+	if history != nil {
+		args = withSublauncher(args, historyKeyword)
+	}
+	l, err := newLauncher(config.Server, drain, cmp.Or(pipelineConfig.WorkspaceDir, tools.DefaultWorkspaceDir), progress, history)
+	if err != nil {
+		log.Fatalf("failed to create launcher: %s", err)
+	}
+	err = l.Execute(ctx, adkConfig, args)
 	if err != nil {
 		log.Fatalf("run failed: %v\n\n%s", err, l.CommandLineSyntax())
 	}
 }
+
+// printPlan prints the plan of the pipeline of config to stdout
+func printPlan(config agents.PipelineConfig) {
+	plan, err := agents.PlanPipeline(config)
+	if err != nil {
+		log.Fatalf("invalid pipeline configuration: %s", err)
+	}
+	fmt.Print(plan.String())
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	modelmetrics "com.github.dimetron.adk-go-agi/pkg/model/metrics"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/adk/cmd/launcher/adk"
+)
+
+// metricsKeyword is the web launcher argument that serves the Prometheus metrics
+const metricsKeyword = "metrics"
+
+// metricsPath is the path of the Prometheus endpoint of the web server
+const metricsPath = "/metrics"
+
+// newMetricsHandler returns the Prometheus endpoint with the model, tool, and stage metrics
+// and the Go runtime and process metrics
+func newMetricsHandler() (http.Handler, error) {
+	registry := prometheus.NewRegistry()
+	for _, register := range []func(prometheus.Registerer) error{modelmetrics.Register, tools.RegisterMetrics, agents.RegisterMetrics} {
+		if err := register(registry); err != nil {
+			return nil, fmt.Errorf("failed to register metrics: %w", err)
+		}
+	}
+	if err := registry.Register(collectors.NewGoCollector()); err != nil {
+		return nil, fmt.Errorf("failed to register Go metrics: %w", err)
+	}
+	if err := registry.Register(collectors.NewProcessCollector(collectors.ProcessCollectorOpts{})); err != nil {
+		return nil, fmt.Errorf("failed to register process metrics: %w", err)
+	}
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{}), nil
+}
+
+// metricsLauncher is the web sublauncher that serves the Prometheus metrics at /metrics
+type metricsLauncher struct{}
+
+// Keyword implements web.Sublauncher
+func (metricsLauncher) Keyword() string {
+	return metricsKeyword
+}
+
+// Parse implements web.Sublauncher; the metrics sublauncher has no flags
+func (metricsLauncher) Parse(args []string) ([]string, error) {
+	return args, nil
+}
+
+// CommandLineSyntax implements web.Sublauncher
+func (metricsLauncher) CommandLineSyntax() string {
+	return ""
+}
+
+// SimpleDescription implements web.Sublauncher
+func (metricsLauncher) SimpleDescription() string {
+	return "serves the Prometheus metrics of the models, tools, and pipeline stages at " + metricsPath
+}
+
+// SetupSubrouters implements web.Sublauncher
+func (metricsLauncher) SetupSubrouters(router *mux.Router, adkConfig *adk.Config) error {
+	handler, err := newMetricsHandler()
+	if err != nil {
+		return err
+	}
+	router.Handle(metricsPath, handler).Methods(http.MethodGet)
+	return nil
+}
+
+// UserMessage implements web.Sublauncher
+func (metricsLauncher) UserMessage(webURL string, printer func(v ...any)) {
+	printer(fmt.Sprintf("   metrics:  Prometheus metrics at %s%s", webURL, metricsPath))
+}
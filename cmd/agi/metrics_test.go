@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	modelmetrics "com.github.dimetron.adk-go-agi/pkg/model/metrics"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/model"
+)
+
+func TestMetricsLauncher(t *testing.T) {
+	llm := modelmetrics.Wrap(fake.New("metrics-endpoint-model", fake.Text("hello")))
+	for range llm.GenerateContent(context.Background(), &model.LLMRequest{}, false) {
+	}
+
+	router := mux.NewRouter()
+	if err := (metricsLauncher{}).SetupSubrouters(router, nil); err != nil {
+		t.Fatalf("SetupSubrouters() error = %v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + metricsPath)
+	if err != nil {
+		t.Fatalf("GET %s error = %v", metricsPath, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s status = %d, want 200", metricsPath, resp.StatusCode)
+	}
+	for _, want := range []string{`agi_model_requests_total{model="metrics-endpoint-model",status="ok"} 1`, "go_goroutines"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
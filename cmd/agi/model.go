@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log"
+	"maps"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	ollamamodel "com.github.dimetron.adk-go-agi/pkg/model/ollama"
+	tgimodel "com.github.dimetron.adk-go-agi/pkg/model/tgi"
+	adkmodel "google.golang.org/adk/model"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/genai"
+)
+
+// ollamaOptions are the default sampling options of every Ollama model
+var ollamaOptions = map[string]interface{}{
+	"temperature": 0.7,
+	"top_p":       0.9,
+}
+
+// newModel creates the model called name with the provider, endpoint, and options of config
+func newModel(ctx context.Context, config ModelConfig, name string) (adkmodel.LLM, error) {
+	switch config.Provider {
+	case providerTGI:
+		var params tgimodel.Parameters
+		if err := decodeOptions(config.Options, &params); err != nil {
+			return nil, fmt.Errorf("invalid TGI options: %w", err)
+		}
+		llm, err := tgimodel.NewModel(ctx, &tgimodel.Config{
+			ModelName:  name,
+			BaseURL:    config.BaseURL,
+			Token:      config.Token,
+			Parameters: params,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create TGI model %s: %w", name, err)
+		}
+		return llm, nil
+	case providerGemini:
+		// Gemini takes the generation options with each request rather than with the client
+		options := make(map[string]any, len(config.Options))
+		for key, value := range config.Options {
+			options[camelCase(key)] = value
+		}
+		var generation genai.GenerateContentConfig
+		if err := decodeOptions(options, &generation); err != nil {
+			return nil, fmt.Errorf("invalid Gemini options: %w", err)
+		}
+		llm, err := gemini.NewModel(ctx, name, &genai.ClientConfig{
+			APIKey:      config.Token,
+			HTTPOptions: genai.HTTPOptions{BaseURL: config.BaseURL},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Gemini model %s: %w", name, err)
+		}
+		if len(options) == 0 {
+			return llm, nil
+		}
+		return &optionsModel{LLM: llm, options: generation}, nil
+	default:
+		options := maps.Clone(ollamaOptions)
+		maps.Copy(options, config.Options)
+		llm, err := ollamamodel.NewModel(ctx, &ollamamodel.Config{
+			ModelName: name,
+			BaseURL:   config.BaseURL,
+			Options:   options,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Ollama model %s: %w", name, err)
+		}
+		return llm, nil
+	}
+}
+
+// newRoutedModels creates a model for each model named by the routing policy, with the
+// provider and endpoint of config
+func newRoutedModels(ctx context.Context, config ModelConfig, routing agents.ModelRouting) (map[string]adkmodel.LLM, error) {
+	models := make(map[string]adkmodel.LLM)
+	for _, name := range routing.ModelNames() {
+		log.Printf("Initializing routed %s model: %s", config.Provider, name)
+		llm, err := newModel(ctx, config, name)
+		if err != nil {
+			return nil, err
+		}
+		models[name] = llm
+	}
+	return models, nil
+}
+
+// decodeOptions converts the model options into target through their JSON names, and fails
+// on options target does not have
+func decodeOptions(options map[string]any, target any) error {
+	data, err := json.Marshal(options)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(target)
+}
+
+// camelCase converts a snake_case option name such as top_p to topP
+func camelCase(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		if parts[i] != "" {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
+
+// optionsModel fills in the generation options of the requests that do not set them
+type optionsModel struct {
+	adkmodel.LLM
+	options genai.GenerateContentConfig
+}
+
+// GenerateContent implements adkmodel.LLM
+func (m *optionsModel) GenerateContent(ctx context.Context, req *adkmodel.LLMRequest, stream bool) iter.Seq2[*adkmodel.LLMResponse, error] {
+	// The request config may be shared with the agent, so the options go into a copy
+	var config genai.GenerateContentConfig
+	if req.Config != nil {
+		config = *req.Config
+	}
+	if config.Temperature == nil {
+		config.Temperature = m.options.Temperature
+	}
+	if config.TopP == nil {
+		config.TopP = m.options.TopP
+	}
+	if config.TopK == nil {
+		config.TopK = m.options.TopK
+	}
+	if config.Seed == nil {
+		config.Seed = m.options.Seed
+	}
+	if config.MaxOutputTokens == 0 {
+		config.MaxOutputTokens = m.options.MaxOutputTokens
+	}
+	if config.StopSequences == nil {
+		config.StopSequences = m.options.StopSequences
+	}
+	req.Config = &config
+	return m.LLM.GenerateContent(ctx, req, stream)
+}
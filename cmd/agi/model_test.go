@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	adkmodel "google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func TestNewModel(t *testing.T) {
+	tests := []struct {
+		name        string
+		config      ModelConfig
+		wantName    string
+		errContains string
+	}{
+		{
+			name:     "ollama",
+			config:   ModelConfig{Provider: providerOllama, BaseURL: defaultOllamaBaseURL, Options: map[string]any{"num_ctx": 8192}},
+			wantName: "qwen2.5-coder:7b",
+		},
+		{
+			name:     "tgi",
+			config:   ModelConfig{Provider: providerTGI, Options: map[string]any{"max_new_tokens": 512, "temperature": 0.2}},
+			wantName: "qwen2.5-coder:7b",
+		},
+		{
+			name:        "unknown tgi option",
+			config:      ModelConfig{Provider: providerTGI, Options: map[string]any{"num_ctx": 8192}},
+			errContains: "invalid TGI options",
+		},
+		{
+			name:     "gemini",
+			config:   ModelConfig{Provider: providerGemini, Token: "test-key", Options: map[string]any{"top_p": 0.5}},
+			wantName: "qwen2.5-coder:7b",
+		},
+		{
+			name:        "unknown gemini option",
+			config:      ModelConfig{Provider: providerGemini, Token: "test-key", Options: map[string]any{"num_ctx": 8192}},
+			errContains: "invalid Gemini options",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm, err := newModel(context.Background(), tt.config, "qwen2.5-coder:7b")
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("newModel() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("newModel() error = %v", err)
+			}
+			if llm.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", llm.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestOptionsModel(t *testing.T) {
+	temperature, topP := float32(0.2), float32(0.5)
+	mdl := fake.New("fake-model", fake.Text("one"), fake.Text("two"))
+	llm := &optionsModel{LLM: mdl, options: genai.GenerateContentConfig{Temperature: &temperature, TopP: &topP, MaxOutputTokens: 256}}
+
+	own := float32(0.9)
+	shared := &genai.GenerateContentConfig{Temperature: &own}
+	for _, req := range []*adkmodel.LLMRequest{{}, {Config: shared}} {
+		for _, err := range llm.GenerateContent(context.Background(), req, false) {
+			if err != nil {
+				t.Fatalf("GenerateContent() error = %v", err)
+			}
+		}
+	}
+
+	requests := mdl.Requests()
+	if got := requests[0].Config; *got.Temperature != temperature || *got.TopP != topP || got.MaxOutputTokens != 256 {
+		t.Errorf("first request config = %+v, want the options", got)
+	}
+	if got := requests[1].Config; *got.Temperature != own || *got.TopP != topP {
+		t.Errorf("second request config = %+v, want its own temperature with the options", got)
+	}
+	if shared.TopP != nil {
+		t.Errorf("shared config = %+v, want it unchanged", shared)
+	}
+}
+
+func TestCamelCase(t *testing.T) {
+	for name, want := range map[string]string{"top_p": "topP", "max_output_tokens": "maxOutputTokens", "temperature": "temperature", "topK": "topK"} {
+		if got := camelCase(name); got != want {
+			t.Errorf("camelCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	// Register the hashes of the accepted algorithms
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// oidcLeeway is the clock skew allowed between the server and the OIDC provider
+const oidcLeeway = time.Minute
+
+// oidcRefreshInterval is the least time between two fetches of the provider keys, so
+// tokens with unknown key IDs cannot make the server flood the provider
+const oidcRefreshInterval = time.Minute
+
+// oidcAlgorithms are the JWS algorithms accepted for bearer tokens, with their hashes
+var oidcAlgorithms = map[string]crypto.Hash{
+	"RS256": crypto.SHA256,
+	"RS384": crypto.SHA384,
+	"RS512": crypto.SHA512,
+	"ES256": crypto.SHA256,
+	"ES384": crypto.SHA384,
+	"ES512": crypto.SHA512,
+}
+
+// oidcVerifier validates the signed JWT bearer tokens of an OIDC provider. It discovers the
+// signing keys of the provider on the first token and fetches them again for unknown key IDs.
+type oidcVerifier struct {
+	// issuer is the issuer URL of the provider, which the tokens must name
+	issuer string
+	// audience is the audience the tokens must be issued for
+	audience string
+	// client fetches the discovery document and the keys
+	client *http.Client
+	// now returns the current time, for tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// keys are the signing keys of the provider by key ID
+	keys map[string]crypto.PublicKey
+	// fetched is when the keys were last fetched, successfully or not
+	fetched time.Time
+	// fetchErr is the error of the last fetch, if it failed
+	fetchErr error
+	// fetching is closed when the fetch in progress completes, and is nil when there is none
+	fetching chan struct{}
+}
+
+// newOIDCVerifier returns a verifier of the tokens issuer issues for audience
+func newOIDCVerifier(issuer, audience string) *oidcVerifier {
+	return &oidcVerifier{
+		issuer:   strings.TrimSuffix(issuer, "/"),
+		audience: audience,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}
+}
+
+// oidcClaims are the claims of a token the verifier checks
+type oidcClaims struct {
+	Issuer    string       `json:"iss"`
+	Subject   string       `json:"sub"`
+	Audience  oidcAudience `json:"aud"`
+	Expiry    *json.Number `json:"exp"`
+	NotBefore *json.Number `json:"nbf"`
+}
+
+// oidcAudience is the aud claim, a string or a list of strings
+type oidcAudience []string
+
+func (a *oidcAudience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = oidcAudience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid aud claim: %w", err)
+	}
+	*a = list
+	return nil
+}
+
+// verify checks the signature, issuer, audience, and lifetime of token and returns its subject
+func (v *oidcVerifier) verify(ctx context.Context, token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+	var header struct {
+		Algorithm string `json:"alg"`
+		KeyID     string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", fmt.Errorf("invalid token header: %w", err)
+	}
+	hash, ok := oidcAlgorithms[header.Algorithm]
+	if !ok {
+		return "", fmt.Errorf("unsupported token algorithm %q", header.Algorithm)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("invalid token signature: %w", err)
+	}
+	key, err := v.key(ctx, header.KeyID)
+	if err != nil {
+		return "", err
+	}
+	h := hash.New()
+	h.Write([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(key, header.Algorithm, hash, h.Sum(nil), signature); err != nil {
+		return "", err
+	}
+
+	var claims oidcClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", fmt.Errorf("invalid token claims: %w", err)
+	}
+	if strings.TrimSuffix(claims.Issuer, "/") != v.issuer {
+		return "", fmt.Errorf("token issued by %q, want %q", claims.Issuer, v.issuer)
+	}
+	if !slices.Contains(claims.Audience, v.audience) {
+		return "", fmt.Errorf("token issued for %q, want %q", claims.Audience, v.audience)
+	}
+	now := v.now()
+	if claims.Expiry == nil {
+		return "", errors.New("token has no expiry")
+	}
+	if expiry, err := numericDate(*claims.Expiry); err != nil || !now.Before(expiry.Add(oidcLeeway)) {
+		return "", errors.New("token expired")
+	}
+	if claims.NotBefore != nil {
+		if notBefore, err := numericDate(*claims.NotBefore); err != nil || now.Add(oidcLeeway).Before(notBefore) {
+			return "", errors.New("token not valid yet")
+		}
+	}
+	return claims.Subject, nil
+}
+
+// key returns the signing key with id, fetching the keys of the provider when it is unknown
+func (v *oidcVerifier) key(ctx context.Context, id string) (crypto.PublicKey, error) {
+	v.mu.Lock()
+	if key, ok := v.keys[id]; ok {
+		v.mu.Unlock()
+		return key, nil
+	}
+	if !v.fetched.IsZero() && v.now().Sub(v.fetched) < oidcRefreshInterval {
+		v.mu.Unlock()
+		return nil, fmt.Errorf("unknown token key %q", id)
+	}
+	fetching := v.fetching
+	if fetching == nil {
+		// The fetch outlives the request that started it, so a client that disconnects cannot
+		// leave the verifier without keys; the client timeout bounds it
+		fetching = make(chan struct{})
+		v.fetching = fetching
+		go v.refresh(context.WithoutCancel(ctx), fetching)
+	}
+	v.mu.Unlock()
+
+	select {
+	case <-fetching:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if key, ok := v.keys[id]; ok {
+		return key, nil
+	}
+	if v.fetchErr != nil {
+		return nil, fmt.Errorf("failed to fetch the keys of %s: %w", v.issuer, v.fetchErr)
+	}
+	return nil, fmt.Errorf("unknown token key %q", id)
+}
+
+// refresh fetches the keys of the provider and closes done when they are stored. The
+// previous keys are kept when the fetch fails.
+func (v *oidcVerifier) refresh(ctx context.Context, done chan struct{}) {
+	keys, err := v.fetchKeys(ctx)
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	// A failed fetch also waits for the refresh interval, so a provider outage is not retried on every request
+	v.fetched = v.now()
+	v.fetchErr = err
+	if err == nil {
+		v.keys = keys
+	}
+	v.fetching = nil
+	close(done)
+}
+
+// fetchKeys reads the discovery document of the provider and then its signing keys
+func (v *oidcVerifier) fetchKeys(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	var discovery struct {
+		Issuer  string `json:"issuer"`
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := v.getJSON(ctx, v.issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, err
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != v.issuer {
+		return nil, fmt.Errorf("discovery document names issuer %q", discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := v.getJSON(ctx, discovery.JWKSURI, &set); err != nil {
+		return nil, err
+	}
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			// Keys of other types do not sign the tokens the verifier accepts
+			continue
+		}
+		keys[k.KeyID] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no supported signing keys")
+	}
+	return keys, nil
+}
+
+// getJSON decodes the JSON document at url into target
+func (v *oidcVerifier) getJSON(ctx context.Context, url string, target any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode %s: %w", url, err)
+	}
+	return nil
+}
+
+// jsonWebKey is an RSA or EC public key of a JSON Web Key Set
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// publicKey returns the RSA or ECDSA public key of k
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA modulus: %w", err)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid RSA exponent: %w", err)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.New("invalid RSA exponent")
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		curve := map[string]elliptic.Curve{"P-256": elliptic.P256(), "P-384": elliptic.P384(), "P-521": elliptic.P521()}[k.Curve]
+		if curve == nil {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point: %w", err)
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EC point: %w", err)
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid EC point")
+		}
+		return ecdsa.ParseUncompressedPublicKey(curve, append(append([]byte{4}, x...), y...))
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+// verifySignature checks the JWS signature of digest with key for algorithm
+func verifySignature(key crypto.PublicKey, algorithm string, hash crypto.Hash, digest, signature []byte) error {
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		if !strings.HasPrefix(algorithm, "RS") {
+			break
+		}
+		if err := rsa.VerifyPKCS1v15(key, hash, digest, signature); err != nil {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	case *ecdsa.PublicKey:
+		size := (key.Curve.Params().BitSize + 7) / 8
+		if !strings.HasPrefix(algorithm, "ES") || len(signature) != 2*size {
+			break
+		}
+		// JWS signatures are r and s concatenated, not ASN.1
+		r := new(big.Int).SetBytes(signature[:size])
+		s := new(big.Int).SetBytes(signature[size:])
+		if !ecdsa.Verify(key, digest, r, s) {
+			return errors.New("invalid token signature")
+		}
+		return nil
+	}
+	return fmt.Errorf("token algorithm %s does not match its key", algorithm)
+}
+
+// decodeSegment decodes a base64url JSON segment of a token into v
+func decodeSegment(segment string, v any) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(strings.NewReader(string(data)))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// numericDate returns the time of a JWT NumericDate, seconds since the epoch
+func numericDate(n json.Number) (time.Time, error) {
+	seconds, err := n.Float64()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
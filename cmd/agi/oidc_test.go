@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testProvider is an OIDC provider with an RSA and an EC signing key
+type testProvider struct {
+	server   *httptest.Server
+	rsaKey   *rsa.PrivateKey
+	ecKey    *ecdsa.PrivateKey
+	keyFetch int
+	// beforeKeys, when set, runs before the keys are served
+	beforeKeys func()
+}
+
+// newTestProvider starts an OIDC provider that serves its discovery document and keys
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate EC key: %v", err)
+	}
+	p := &testProvider{rsaKey: rsaKey, ecKey: ecKey}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": p.server.URL, "jwks_uri": p.server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		if p.beforeKeys != nil {
+			p.beforeKeys()
+		}
+		p.keyFetch++
+		b64 := base64.RawURLEncoding.EncodeToString
+		ecBytes, _ := ecKey.PublicKey.Bytes()
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kty": "RSA", "kid": "rsa", "use": "sig", "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+			{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecBytes[1:33]), "y": b64(ecBytes[33:])},
+			{"kty": "oct", "kid": "symmetric", "k": "c2VjcmV0"},
+		}})
+	})
+	p.server = httptest.NewServer(mux)
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+// token returns a token signed with alg, RS256 or ES256, by the key kid with claims
+func (p *testProvider) token(t *testing.T, alg, kid string, claims map[string]any) string {
+	t.Helper()
+	segment := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("failed to encode token: %v", err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	input := segment(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"}) + "." + segment(claims)
+	digest := sha256.Sum256([]byte(input))
+	var signature []byte
+	var err error
+	switch alg {
+	case "RS256":
+		signature, err = rsa.SignPKCS1v15(rand.Reader, p.rsaKey, crypto.SHA256, digest[:])
+	case "ES256":
+		var r, s *big.Int
+		r, s, err = ecdsa.Sign(rand.Reader, p.ecKey, digest[:])
+		if err == nil {
+			signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+		}
+	}
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return input + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// claims returns valid claims of p for the agi audience, with overrides
+func (p *testProvider) claims(overrides map[string]any) map[string]any {
+	claims := map[string]any{
+		"iss": p.server.URL,
+		"sub": "dev@example.com",
+		"aud": "agi",
+		"exp": time.Now().Add(time.Hour).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	p := newTestProvider(t)
+	tamper := func(token string) string {
+		parts := strings.Split(token, ".")
+		parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"` + p.server.URL + `","aud":"agi","sub":"admin","exp":9999999999}`))
+		return strings.Join(parts, ".")
+	}
+	tests := []struct {
+		name    string
+		token   string
+		wantSub string
+		wantErr string
+	}{
+		{name: "RS256", token: p.token(t, "RS256", "rsa", p.claims(nil)), wantSub: "dev@example.com"},
+		{name: "ES256", token: p.token(t, "ES256", "ec", p.claims(nil)), wantSub: "dev@example.com"},
+		{name: "audience list", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"aud": []string{"other", "agi"}})), wantSub: "dev@example.com"},
+		{name: "within the leeway", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"exp": time.Now().Add(-30 * time.Second).Unix()})), wantSub: "dev@example.com"},
+		{name: "expired", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"exp": time.Now().Add(-time.Hour).Unix()})), wantErr: "expired"},
+		{name: "no expiry", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"exp": nil})), wantErr: "no expiry"},
+		{name: "not valid yet", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"nbf": time.Now().Add(time.Hour).Unix()})), wantErr: "not valid yet"},
+		{name: "other audience", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"aud": "other"})), wantErr: "issued for"},
+		{name: "other issuer", token: p.token(t, "RS256", "rsa", p.claims(map[string]any{"iss": "https://evil.example.com"})), wantErr: "issued by"},
+		{name: "tampered claims", token: tamper(p.token(t, "RS256", "rsa", p.claims(nil))), wantErr: "invalid token signature"},
+		{name: "algorithm of another key", token: p.token(t, "ES256", "rsa", p.claims(nil)), wantErr: "does not match"},
+		{name: "unsigned", token: strings.Split(p.token(t, "RS256", "rsa", p.claims(nil)), ".")[0] + ".e30.", wantErr: "invalid token"},
+		{name: "algorithm none", token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + ".e30.", wantErr: "unsupported token algorithm"},
+		{name: "symmetric key", token: base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","kid":"symmetric"}`)) + ".e30.c2ln", wantErr: "unsupported token algorithm"},
+		{name: "unknown key", token: p.token(t, "RS256", "rotated", p.claims(nil)), wantErr: "unknown token key"},
+		{name: "malformed", token: "not-a-token", wantErr: "malformed"},
+	}
+	v := newOIDCVerifier(p.server.URL+"/", "agi")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, err := v.verify(context.Background(), tt.token)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("verify() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || sub != tt.wantSub {
+				t.Errorf("verify() = %q, %v; want %q", sub, err, tt.wantSub)
+			}
+		})
+	}
+	// Unknown key IDs fetch the keys again at most once per refresh interval
+	if p.keyFetch != 1 {
+		t.Errorf("keys fetched %d times, want 1", p.keyFetch)
+	}
+	v.now = func() time.Time { return time.Now().Add(oidcRefreshInterval) }
+	if _, err := v.verify(context.Background(), p.token(t, "RS256", "rotated", p.claims(nil))); err == nil {
+		t.Error("verify() of an unknown key succeeded")
+	}
+	if p.keyFetch != 2 {
+		t.Errorf("keys fetched %d times after the refresh interval, want 2", p.keyFetch)
+	}
+}
+
+func TestOIDCVerifier_ClientDisconnects(t *testing.T) {
+	p := newTestProvider(t)
+	started, release := make(chan struct{}), make(chan struct{})
+	p.beforeKeys = func() {
+		close(started)
+		<-release
+	}
+	v := newOIDCVerifier(p.server.URL, "agi")
+
+	// A client that disconnects while the keys are fetched does not fail the fetch
+	ctx, cancel := context.WithCancel(context.Background())
+	token := p.token(t, "RS256", "unknown", p.claims(nil))
+	done := make(chan error, 1)
+	go func() {
+		_, err := v.verify(ctx, token)
+		done <- err
+	}()
+	<-started
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("verify() of a disconnected client error = %v, want context.Canceled", err)
+	}
+	close(release)
+
+	if sub, err := v.verify(context.Background(), p.token(t, "RS256", "rsa", p.claims(nil))); err != nil || sub != "dev@example.com" {
+		t.Errorf("verify() after the disconnect = %q, %v; want dev@example.com", sub, err)
+	}
+	if p.keyFetch != 1 {
+		t.Errorf("keys fetched %d times, want 1", p.keyFetch)
+	}
+}
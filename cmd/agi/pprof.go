@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+// pprofShutdownTimeout bounds how long the profiling server waits for requests in progress
+// when it shuts down
+const pprofShutdownTimeout = 5 * time.Second
+
+// newPprofHandler returns the net/http/pprof endpoints under /debug/pprof/
+func newPprofHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
+
+// startPprofServer serves the profiling endpoints at address, separately from the agent
+// server, until ctx is done, and returns the address it listens on
+func startPprofServer(ctx context.Context, address string) (net.Addr, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on pprof address %s: %w", address, err)
+	}
+	// Profiles expose the command line and the code, so they are meant for the local machine
+	if host, _, err := net.SplitHostPort(address); err == nil && !isLoopback(host) {
+		log.Printf("Warning: pprof endpoints are reachable beyond this machine at %s", address)
+	}
+
+	server := &http.Server{
+		Handler:           newPprofHandler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("pprof server failed: %v", err)
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), pprofShutdownTimeout)
+		defer cancel()
+		_ = server.Shutdown(shutdownCtx)
+	}()
+	log.Printf("Serving pprof at http://%s/debug/pprof/", listener.Addr())
+	return listener.Addr(), nil
+}
+
+// isLoopback reports whether host is localhost or a loopback address
+func isLoopback(host string) bool {
+	ip := net.ParseIP(host)
+	return host == "localhost" || (ip != nil && ip.IsLoopback())
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStartPprofServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	addr, err := startPprofServer(ctx, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("startPprofServer() error = %v", err)
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/debug/pprof/", want: "goroutine"},
+		{path: "/debug/pprof/goroutine?debug=1", want: "goroutine profile:"},
+		{path: "/debug/pprof/cmdline", want: "agi.test"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			resp, err := http.Get("http://" + addr.String() + tt.path)
+			if err != nil {
+				t.Fatalf("GET %s error = %v", tt.path, err)
+			}
+			defer resp.Body.Close()
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read %s: %v", tt.path, err)
+			}
+			if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), tt.want) {
+				t.Errorf("GET %s = %d %.200q, want 200 with %q", tt.path, resp.StatusCode, body, tt.want)
+			}
+		})
+	}
+
+	if _, err := startPprofServer(ctx, addr.String()); err == nil {
+		t.Error("startPprofServer() on a used address error = nil")
+	}
+
+	// The server stops with ctx
+	cancel()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := http.Get("http://" + addr.String() + "/debug/pprof/")
+		if err != nil {
+			break
+		}
+		resp.Body.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("pprof server still serving after ctx was canceled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// progressKeyword is the web launcher argument that streams the progress of runs
+const progressKeyword = "progress"
+
+// Routes of the progress streams: of a run, named by its invocation ID, and of every run
+// of a session, which can be subscribed to before the run starts, over WebSocket or as
+// server-sent events
+const (
+	progressRunPath     = "/api/runs/{run_id}/progress"
+	progressSessionPath = "/api/apps/{app_name}/users/{user_id}/sessions/{session_id}/progress"
+	progressStreamPath  = "/api/apps/{app_name}/users/{user_id}/sessions/{session_id}/stream"
+)
+
+// Types of the progress events
+const (
+	progressRunStarted     = "run_started"
+	progressStageStarted   = "stage_started"
+	progressToolCall       = "tool_call"
+	progressToolResult     = "tool_result"
+	progressChunk          = "chunk"
+	progressMessage        = "message"
+	progressFileWritten    = "file_written"
+	progressStageCompleted = "stage_completed"
+	progressRunCompleted   = "run_completed"
+)
+
+const (
+	// maxProgressEvents is how many recent events the hub keeps of each run and session
+	maxProgressEvents = 1000
+	// progressBuffer is how many events a subscriber may fall behind before it is dropped
+	progressBuffer = 256
+	// progressRetention is how long the hub keeps the events of a run or session without
+	// subscribers after its last event
+	progressRetention = 15 * time.Minute
+	// progressPingInterval is how often progress streams are kept alive with a ping or heartbeat
+	progressPingInterval = 30 * time.Second
+	// progressWriteTimeout bounds the write of an event to a subscriber
+	progressWriteTimeout = 10 * time.Second
+)
+
+// progressEvent is a structured event of the progress of a run
+type progressEvent struct {
+	// ID orders the events of the server, for subscribers resuming after the last one they got
+	ID uint64 `json:"id"`
+	// Type is the kind of event, such as stage_started or tool_call
+	Type string `json:"type"`
+	// RunID is the invocation ID of the run
+	RunID string `json:"runId"`
+	// SessionID is the session of the run
+	SessionID string `json:"sessionId"`
+	// Stage is the pipeline stage the event belongs to
+	Stage string `json:"stage,omitempty"`
+	// Agent is the agent of the stage, or nested within it, that produced the event
+	Agent string `json:"agent,omitempty"`
+	// Time is when the event happened
+	Time time.Time `json:"time"`
+	// Tool is the tool of tool_call and tool_result events
+	Tool string `json:"tool,omitempty"`
+	// Args are the arguments of tool_call events
+	Args map[string]any `json:"args,omitempty"`
+	// Result is the response of tool_result events
+	Result map[string]any `json:"result,omitempty"`
+	// Text is the text of chunk and message events, and the request of run_started events
+	Text string `json:"text,omitempty"`
+	// Path is the workspace file of file_written events
+	Path string `json:"path,omitempty"`
+	// DurationMS is how long the stage of stage_completed events took, in milliseconds
+	DurationMS int64 `json:"durationMs,omitempty"`
+	// Usage is the token usage of the stage of stage_completed events
+	Usage *agents.TokenUsage `json:"usage,omitempty"`
+	// Error is the first error the stage of stage_completed events reported
+	Error string `json:"error,omitempty"`
+
+	// app and user are the app and user of the session of the run
+	app, user string
+	// session is the app, user, and session key of the run
+	session string
+}
+
+// progressHub collects the progress events of the pipeline runs of the web server from the
+// stage hooks and fans them out to the subscribers of each run and session. It keeps the
+// recent events, so subscribers that connect late or reconnect get the events they missed.
+type progressHub struct {
+	// now returns the current time, for tests
+	now func() time.Time
+
+	mu sync.Mutex
+	// lastID is the ID of the last event
+	lastID uint64
+	// runs are the events of the runs, by invocation ID
+	runs map[string]*progressLog
+	// sessions are the events of the sessions, by sessionKey
+	sessions map[string]*progressLog
+	// requests are the numbers of run requests in progress of each session
+	requests map[string]int
+	// pruned is when the idle logs were last forgotten
+	pruned time.Time
+	// history records the runs, nil without a run history
+	history *runHistory
+}
+
+// progressLog is the recent events of a run or session and its subscribers
+type progressLog struct {
+	// session is the session key of a run, empty for the log of a session
+	session string
+	// events are the recent events, oldest first
+	events []progressEvent
+	// subscribers receive the events as they are published
+	subscribers map[*progressSubscriber]struct{}
+	// done is set once the run completed
+	done bool
+	// updated is when the last event was published
+	updated time.Time
+}
+
+// progressSubscriber receives the events of a run or session
+type progressSubscriber struct {
+	// events is closed when the run completes or the subscriber is dropped
+	events chan progressEvent
+	// dropped is set when the subscriber fell behind or the server shut down, and its events stopped
+	dropped atomic.Bool
+	// log is the log the subscriber listens to
+	log *progressLog
+}
+
+// newProgressHub returns an empty progress hub
+func newProgressHub() *progressHub {
+	return &progressHub{
+		now:      time.Now,
+		runs:     make(map[string]*progressLog),
+		sessions: make(map[string]*progressLog),
+		requests: make(map[string]int),
+	}
+}
+
+// sessionKey returns the key of the session id of the app and user
+func sessionKey(app, user, id string) string {
+	return app + "/" + user + "/" + id
+}
+
+// hooks returns the stage hooks that publish the progress of the runs, for PipelineConfig.Hooks
+func (h *progressHub) hooks() agents.Hooks {
+	base := func(stage agents.StageInfo, eventType string) progressEvent {
+		return progressEvent{
+			Type:      eventType,
+			RunID:     stage.InvocationID,
+			SessionID: stage.SessionID,
+			Stage:     stage.Stage,
+			app:       stage.AppName,
+			user:      stage.UserID,
+			session:   sessionKey(stage.AppName, stage.UserID, stage.SessionID),
+		}
+	}
+	return agents.Hooks{
+		BeforeStage: func(_ context.Context, stage agents.StageInfo) {
+			started := base(stage, progressRunStarted)
+			started.Stage = ""
+			started.Text = contentText(stage.UserContent)
+			h.publish(started, base(stage, progressStageStarted))
+		},
+		StageEvent: func(_ context.Context, stage agents.StageInfo, event *session.Event) {
+			for _, e := range eventProgress(base(stage, ""), event) {
+				h.publish(e)
+			}
+		},
+		AfterStage: func(_ context.Context, result agents.StageResult) {
+			e := base(result.StageInfo, progressStageCompleted)
+			e.DurationMS = result.Duration.Milliseconds()
+			e.Usage = &result.Usage
+			if result.Err != nil {
+				e.Error = result.Err.Error()
+			}
+			h.publish(e)
+		},
+	}
+}
+
+// eventProgress returns the progress events of the session event of the stage of base: the
+// streamed text chunks, and the messages, tool calls, tool results, and file writes
+func eventProgress(base progressEvent, event *session.Event) []progressEvent {
+	if event.Content == nil {
+		return nil
+	}
+	base.Agent = event.Author
+	var events []progressEvent
+	add := func(eventType string, set func(e *progressEvent)) {
+		e := base
+		e.Type = eventType
+		set(&e)
+		events = append(events, e)
+	}
+	for _, part := range event.Content.Parts {
+		switch {
+		case part.Thought:
+		case event.Partial:
+			if part.Text != "" {
+				add(progressChunk, func(e *progressEvent) { e.Text = part.Text })
+			}
+		case part.FunctionCall != nil:
+			add(progressToolCall, func(e *progressEvent) {
+				e.Tool, e.Args = part.FunctionCall.Name, part.FunctionCall.Args
+			})
+		case part.FunctionResponse != nil:
+			response := part.FunctionResponse
+			add(progressToolResult, func(e *progressEvent) { e.Tool, e.Result = response.Name, response.Response })
+			path, _ := response.Response["path"].(string)
+			if success, _ := response.Response["success"].(bool); response.Name == "fileWrite" && success && path != "" {
+				add(progressFileWritten, func(e *progressEvent) { e.Path = path })
+			}
+		case part.Text != "":
+			add(progressMessage, func(e *progressEvent) { e.Text = part.Text })
+		}
+	}
+	return events
+}
+
+// publish numbers events and records them in the logs of their run and session, for their
+// subscribers, and in the run history. A run_started event is left out once its run has
+// events, and a run_completed event closes the stream of the run.
+func (h *progressHub) publish(events ...progressEvent) {
+	h.mu.Lock()
+	now := h.now()
+	h.prune(now)
+	published := make([]progressEvent, 0, len(events))
+	for _, e := range events {
+		run, ok := h.runs[e.RunID]
+		if ok && e.Type == progressRunStarted {
+			continue
+		}
+		h.lastID++
+		e.ID, e.Time = h.lastID, now
+		if !ok {
+			run = &progressLog{session: e.session, subscribers: make(map[*progressSubscriber]struct{})}
+			h.runs[e.RunID] = run
+		}
+		run.append(e, now)
+		h.sessionLog(e.session).append(e, now)
+		if e.Type == progressRunCompleted {
+			run.done = true
+			for sub := range run.subscribers {
+				close(sub.events)
+			}
+			clear(run.subscribers)
+		}
+		published = append(published, e)
+	}
+	h.mu.Unlock()
+
+	if h.history != nil {
+		for _, e := range published {
+			h.history.record(e)
+		}
+	}
+}
+
+// contentText returns the text of the parts of content
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range content.Parts {
+		if !part.Thought {
+			b.WriteString(part.Text)
+		}
+	}
+	return b.String()
+}
+
+// sessionLog returns the log of the session key, which is created on first use
+func (h *progressHub) sessionLog(key string) *progressLog {
+	l, ok := h.sessions[key]
+	if !ok {
+		l = &progressLog{subscribers: make(map[*progressSubscriber]struct{}), updated: h.now()}
+		h.sessions[key] = l
+	}
+	return l
+}
+
+// append records e in l, forgetting the oldest events past maxProgressEvents, and sends it to
+// the subscribers; subscribers that fell behind are dropped
+func (l *progressLog) append(e progressEvent, now time.Time) {
+	l.events = append(l.events, e)
+	if extra := len(l.events) - maxProgressEvents; extra > 0 {
+		l.events = slices.Delete(l.events, 0, extra)
+	}
+	l.updated = now
+	for sub := range l.subscribers {
+		select {
+		case sub.events <- e:
+		default:
+			sub.dropped.Store(true)
+			close(sub.events)
+			delete(l.subscribers, sub)
+		}
+	}
+}
+
+// since returns the events of l after the event with ID after
+func (l *progressLog) since(after uint64) []progressEvent {
+	i, _ := slices.BinarySearchFunc(l.events, after+1, func(e progressEvent, id uint64) int {
+		return cmp.Compare(e.ID, id)
+	})
+	return slices.Clone(l.events[i:])
+}
+
+// subscribe adds a subscriber to l and returns it with the events after the event with ID
+// after. The subscriber is nil when the run of l completed, as no events follow.
+func (l *progressLog) subscribe(after uint64) (*progressSubscriber, []progressEvent) {
+	backlog := l.since(after)
+	if l.done {
+		return nil, backlog
+	}
+	sub := &progressSubscriber{events: make(chan progressEvent, progressBuffer), log: l}
+	l.subscribers[sub] = struct{}{}
+	return sub, backlog
+}
+
+// subscribeRun subscribes to the events of the run with the invocation ID run, and reports
+// whether the hub knows the run
+func (h *progressHub) subscribeRun(run string, after uint64) (*progressSubscriber, []progressEvent, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(h.now())
+	l, ok := h.runs[run]
+	if !ok {
+		return nil, nil, false
+	}
+	sub, backlog := l.subscribe(after)
+	return sub, backlog, true
+}
+
+// subscribeSession subscribes to the events of the runs of the session key
+func (h *progressHub) subscribeSession(key string, after uint64) (*progressSubscriber, []progressEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.prune(h.now())
+	return h.sessionLog(key).subscribe(after)
+}
+
+// unsubscribe removes sub from its log
+func (h *progressHub) unsubscribe(sub *progressSubscriber) {
+	if sub == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := sub.log.subscribers[sub]; ok {
+		delete(sub.log.subscribers, sub)
+		close(sub.events)
+	}
+}
+
+// close drops the subscribers, so their streams end when the server shuts down. A nil hub
+// has no subscribers.
+func (h *progressHub) close() {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, logs := range []map[string]*progressLog{h.runs, h.sessions} {
+		for _, l := range logs {
+			for sub := range l.subscribers {
+				sub.dropped.Store(true)
+				close(sub.events)
+			}
+			clear(l.subscribers)
+		}
+	}
+}
+
+// prune forgets the logs without subscribers whose last event is older than progressRetention
+func (h *progressHub) prune(now time.Time) {
+	if now.Sub(h.pruned) < progressRetention {
+		return
+	}
+	h.pruned = now
+	for _, logs := range []map[string]*progressLog{h.runs, h.sessions} {
+		for key, l := range logs {
+			if len(l.subscribers) == 0 && now.Sub(l.updated) >= progressRetention {
+				delete(logs, key)
+			}
+		}
+	}
+}
+
+// wrap returns next with the run requests tracked, so the runs of a session complete once
+// its last run request returns. A nil hub tracks nothing.
+func (h *progressHub) wrap(next http.Handler) http.Handler {
+	if h == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isRunRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		key, ok := runSession(w, r)
+		if !ok {
+			return
+		}
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		h.startRequest(key)
+		defer h.endRequest(key)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// startRequest records a run request of the session key
+func (h *progressHub) startRequest(key string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.requests[key]++
+}
+
+// endRequest records the end of a run request of the session key, and completes the runs of
+// the session once none is left
+func (h *progressHub) endRequest(key string) {
+	h.mu.Lock()
+	if h.requests[key]--; h.requests[key] > 0 {
+		h.mu.Unlock()
+		return
+	}
+	delete(h.requests, key)
+	var completed []progressEvent
+	for id, run := range h.runs {
+		if run.session == key && !run.done {
+			last := run.events[len(run.events)-1]
+			completed = append(completed, progressEvent{Type: progressRunCompleted, RunID: id, SessionID: last.SessionID, app: last.app, user: last.user, session: key})
+		}
+	}
+	h.mu.Unlock()
+	for _, e := range completed {
+		h.publish(e)
+	}
+}
+
+// progressUpgrader upgrades progress requests to WebSocket connections; browsers may connect
+// only from the origin of the server
+var progressUpgrader = websocket.Upgrader{}
+
+// streamProgress upgrades the request r to a WebSocket connection and sends it the backlog
+// and then the events of sub as JSON text messages, until the run completes, the
+// subscriber is dropped, or the client goes away
+func (h *progressHub) streamProgress(w http.ResponseWriter, r *http.Request, sub *progressSubscriber, backlog []progressEvent) {
+	defer h.unsubscribe(sub)
+	conn, err := progressUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader already answered the request with the error
+		log.Printf("Failed to open progress stream: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	// The client sends nothing but control messages, which are read until it goes away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+	write := func(e progressEvent) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(progressWriteTimeout)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(e)
+	}
+	closeWith := func(code int, reason string) {
+		_ = conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(progressWriteTimeout))
+	}
+
+	for _, e := range backlog {
+		if err := write(e); err != nil {
+			return
+		}
+	}
+	if sub == nil {
+		closeWith(websocket.CloseNormalClosure, "run completed")
+		return
+	}
+	ping := time.NewTicker(progressPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				if sub.dropped.Load() {
+					closeWith(websocket.CloseTryAgainLater, "stream ended, reconnect with the last event ID")
+				} else {
+					closeWith(websocket.CloseNormalClosure, "run completed")
+				}
+				return
+			}
+			if err := write(e); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(progressWriteTimeout)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// progressAfter returns the ID of the last event the client of r got, from the Last-Event-ID
+// header of reconnecting event sources or the after query parameter, or 0 for a new
+// subscription
+func progressAfter(r *http.Request) (uint64, error) {
+	after := cmp.Or(r.Header.Get("Last-Event-ID"), r.URL.Query().Get("after"))
+	if after == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(after, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event ID %q", after)
+	}
+	return id, nil
+}
+
+// progressLauncher is the web sublauncher that streams the structured progress events of
+// runs and sessions over WebSocket, and of sessions as server-sent events
+type progressLauncher struct {
+	// hub publishes the events of the runs
+	hub *progressHub
+}
+
+// Keyword implements web.Sublauncher
+func (progressLauncher) Keyword() string {
+	return progressKeyword
+}
+
+// Parse implements web.Sublauncher; the progress sublauncher has no flags
+func (progressLauncher) Parse(args []string) ([]string, error) {
+	return args, nil
+}
+
+// CommandLineSyntax implements web.Sublauncher
+func (progressLauncher) CommandLineSyntax() string {
+	return ""
+}
+
+// SimpleDescription implements web.Sublauncher
+func (progressLauncher) SimpleDescription() string {
+	return "streams the stages, tool calls, text chunks, and file writes of runs over WebSocket and as server-sent events"
+}
+
+// SetupSubrouters implements web.Sublauncher
+func (l progressLauncher) SetupSubrouters(router *mux.Router, adkConfig *adk.Config) error {
+	router.HandleFunc(progressRunPath, func(w http.ResponseWriter, r *http.Request) {
+		after, err := progressAfter(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		sub, backlog, ok := l.hub.subscribeRun(mux.Vars(r)["run_id"], after)
+		if !ok {
+			http.Error(w, "run not found", http.StatusNotFound)
+			return
+		}
+		l.hub.streamProgress(w, r, sub, backlog)
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(progressSessionPath, func(w http.ResponseWriter, r *http.Request) {
+		if sub, backlog, ok := l.subscribeSession(w, r, adkConfig.SessionService); ok {
+			l.hub.streamProgress(w, r, sub, backlog)
+		}
+	}).Methods(http.MethodGet)
+
+	router.HandleFunc(progressStreamPath, func(w http.ResponseWriter, r *http.Request) {
+		if sub, backlog, ok := l.subscribeSession(w, r, adkConfig.SessionService); ok {
+			l.hub.streamEvents(w, r, sub, backlog)
+		}
+	}).Methods(http.MethodGet)
+	return nil
+}
+
+// subscribeSession subscribes to the events of the session of the request r after the event
+// it names. It answers requests for unknown sessions and reports whether it subscribed.
+func (l progressLauncher) subscribeSession(w http.ResponseWriter, r *http.Request, sessions session.Service) (*progressSubscriber, []progressEvent, bool) {
+	after, err := progressAfter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, nil, false
+	}
+	vars := mux.Vars(r)
+	if _, err := sessions.Get(r.Context(), &session.GetRequest{
+		AppName:   vars["app_name"],
+		UserID:    vars["user_id"],
+		SessionID: vars["session_id"],
+	}); err != nil {
+		http.Error(w, fmt.Sprintf("session not found: %v", err), http.StatusNotFound)
+		return nil, nil, false
+	}
+	sub, backlog := l.hub.subscribeSession(sessionKey(vars["app_name"], vars["user_id"], vars["session_id"]), after)
+	return sub, backlog, true
+}
+
+// UserMessage implements web.Sublauncher
+func (progressLauncher) UserMessage(webURL string, printer func(v ...any)) {
+	printer(fmt.Sprintf("   progress: WebSocket progress of runs at %s%s and of sessions at %s%s", webURL, progressRunPath, webURL, progressSessionPath))
+	printer(fmt.Sprintf("   progress: server-sent events of sessions at %s%s", webURL, progressStreamPath))
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// testStage is the stage of the test runs, in session s1 of app agi and user user
+func testStage(run string) agents.StageInfo {
+	return agents.StageInfo{
+		Stage: "DesignAgent", AppName: "agi", UserID: "user", SessionID: "s1", InvocationID: run,
+		UserContent: genai.NewContentFromText("Build a calculator", genai.RoleUser),
+	}
+}
+
+// modelEvent returns an event of the design agent with parts
+func modelEvent(partial bool, parts ...*genai.Part) *session.Event {
+	event := session.NewEvent("run-1")
+	event.Author = "DesignAgent"
+	event.Partial = partial
+	event.Content = &genai.Content{Role: genai.RoleModel, Parts: parts}
+	return event
+}
+
+// eventSummary returns the type and main field of each of events
+func eventSummary(events []progressEvent) []string {
+	var got []string
+	for _, e := range events {
+		got = append(got, strings.TrimSpace(fmt.Sprintf("%s %s%s%s", e.Type, e.Tool, e.Text, e.Path)))
+	}
+	return got
+}
+
+func TestEventProgress(t *testing.T) {
+	written := &genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "fileWrite", Response: map[string]any{"success": true, "path": "main.go"}}}
+	failed := &genai.Part{FunctionResponse: &genai.FunctionResponse{Name: "fileWrite", Response: map[string]any{"success": false, "path": "main.go"}}}
+	tests := []struct {
+		name  string
+		event *session.Event
+		want  []string
+	}{
+		{name: "streamed chunk", event: modelEvent(true, genai.NewPartFromText("Des")), want: []string{"chunk Des"}},
+		{name: "thought", event: modelEvent(true, &genai.Part{Text: "hmm", Thought: true})},
+		{name: "message", event: modelEvent(false, genai.NewPartFromText("Design done")), want: []string{"message Design done"}},
+		{name: "tool call", event: modelEvent(false, genai.NewPartFromFunctionCall("fileRead", map[string]any{"path": "go.mod"})), want: []string{"tool_call fileRead"}},
+		{name: "file write", event: modelEvent(false, written), want: []string{"tool_result fileWrite", "file_written main.go"}},
+		{name: "failed file write", event: modelEvent(false, failed), want: []string{"tool_result fileWrite"}},
+		{name: "no content", event: session.NewEvent("run-1")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := eventProgress(progressEvent{RunID: "run-1", Stage: "DesignAgent"}, tt.event)
+			if got := eventSummary(events); !slices.Equal(got, tt.want) {
+				t.Errorf("eventProgress() = %q, want %q", got, tt.want)
+			}
+			for _, e := range events {
+				if e.RunID != "run-1" || e.Stage != "DesignAgent" || e.Agent != "DesignAgent" {
+					t.Errorf("event %+v, want the run, stage, and agent", e)
+				}
+			}
+		})
+	}
+}
+
+func TestProgressHub(t *testing.T) {
+	h := newProgressHub()
+	now := time.Now()
+	h.now = func() time.Time { return now }
+	hooks := h.hooks()
+	ctx := context.Background()
+
+	follower, _ := h.subscribeSession(sessionKey("agi", "user", "s1"), 0)
+	hooks.BeforeStage(ctx, testStage("run-1"))
+	hooks.StageEvent(ctx, testStage("run-1"), modelEvent(false, genai.NewPartFromText("design")))
+	run, backlog, ok := h.subscribeRun("run-1", 0)
+	if !ok || !slices.Equal(eventSummary(backlog), []string{"run_started Build a calculator", "stage_started", "message design"}) {
+		t.Fatalf("subscribeRun() backlog = %q, %v; want the events so far", eventSummary(backlog), ok)
+	}
+	if _, resumed, _ := h.subscribeRun("run-1", backlog[0].ID); len(resumed) != 2 || resumed[0].ID != backlog[1].ID {
+		t.Errorf("subscribeRun() after the first event = %q, want the rest", eventSummary(resumed))
+	}
+	// Later stages of the run do not start it again
+	hooks.BeforeStage(ctx, agents.StageInfo{Stage: "CodeWriterAgent", AppName: "agi", UserID: "user", SessionID: "s1", InvocationID: "run-1"})
+	if _, _, ok := h.subscribeRun("run-2", 0); ok {
+		t.Error("subscribeRun() of an unknown run found it")
+	}
+
+	// The run completes when its run request returns
+	handler := h.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hooks.AfterStage(ctx, agents.StageResult{StageInfo: testStage("run-1"), Duration: 2 * time.Second, Err: errors.New("no design")})
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), runRequest("s1"))
+	var got []progressEvent
+	for e := range run.events {
+		got = append(got, e)
+	}
+	if !slices.Equal(eventSummary(got), []string{"stage_started", "stage_completed", "run_completed"}) {
+		t.Fatalf("run events = %q, want the next stage and the completion of the stage and run", eventSummary(got))
+	}
+	if got[1].DurationMS != 2000 || got[1].Error != "no design" || got[1].Usage == nil {
+		t.Errorf("stage_completed = %+v, want its duration, error, and usage", got[1])
+	}
+	if sub, backlog, _ := h.subscribeRun("run-1", 0); sub != nil || len(backlog) != 6 {
+		t.Errorf("subscribeRun() of the completed run = %v, %d events; want no subscriber and its 6 events", sub, len(backlog))
+	}
+	if len(follower.events) != 6 {
+		t.Fatalf("session subscriber got %d events, want the 6 of the run", len(follower.events))
+	}
+	for range 6 {
+		<-follower.events
+	}
+
+	// Subscribers that fall behind are dropped: the first stage of run-2 starts it too
+	for range progressBuffer - 1 {
+		hooks.BeforeStage(ctx, testStage("run-2"))
+	}
+	if follower.dropped.Load() {
+		t.Fatal("session subscriber dropped before its buffer filled")
+	}
+	hooks.BeforeStage(ctx, testStage("run-2"))
+	if !follower.dropped.Load() {
+		t.Error("session subscriber not dropped after it fell behind")
+	}
+	h.unsubscribe(follower)
+
+	// Idle logs are forgotten
+	now = now.Add(progressRetention)
+	if _, _, ok := h.subscribeRun("run-1", 0); ok || len(h.sessions) != 0 {
+		t.Errorf("logs after the retention: run found %v, %d sessions; want none", ok, len(h.sessions))
+	}
+}
+
+func TestProgressLog_MaxEvents(t *testing.T) {
+	l := &progressLog{subscribers: make(map[*progressSubscriber]struct{})}
+	for i := range maxProgressEvents + 10 {
+		l.append(progressEvent{ID: uint64(i + 1)}, time.Now())
+	}
+	if len(l.events) != maxProgressEvents || l.events[0].ID != 11 {
+		t.Errorf("log of %d events from %d, want the last %d", len(l.events), l.events[0].ID, maxProgressEvents)
+	}
+	if got := l.since(maxProgressEvents + 8); len(got) != 2 {
+		t.Errorf("since() = %d events, want 2", len(got))
+	}
+}
+
+func TestProgressLauncher(t *testing.T) {
+	h := newProgressHub()
+	sessions := inmemory.New()
+	if _, err := sessions.Create(context.Background(), &session.CreateRequest{AppName: "agi", UserID: "user", SessionID: "s1"}); err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+	router := mux.NewRouter()
+	if err := (progressLauncher{hub: h}).SetupSubrouters(router, &adk.Config{SessionService: sessions}); err != nil {
+		t.Fatalf("SetupSubrouters() error = %v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http")
+
+	for _, path := range []string{"/api/runs/run-1/progress", "/api/apps/agi/users/user/sessions/s2/progress"} {
+		if _, resp, err := websocket.DefaultDialer.Dial(wsURL+path, nil); err == nil || resp.StatusCode != http.StatusNotFound {
+			t.Errorf("Dial(%s) = %v, want 404", path, err)
+		}
+	}
+	if _, resp, err := websocket.DefaultDialer.Dial(wsURL+"/api/apps/agi/users/user/sessions/s1/progress?after=x", nil); err == nil || resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("Dial() with an invalid event ID = %v, want 400", err)
+	}
+
+	// A client following the session gets the events of its runs
+	sessionConn, _, err := websocket.DefaultDialer.Dial(wsURL+"/api/apps/agi/users/user/sessions/s1/progress", nil)
+	if err != nil {
+		t.Fatalf("Dial() session error = %v", err)
+	}
+	defer sessionConn.Close()
+	ctx := context.Background()
+	hooks := h.hooks()
+	hooks.BeforeStage(ctx, testStage("run-1"))
+	runConn, _, err := websocket.DefaultDialer.Dial(wsURL+"/api/runs/run-1/progress", nil)
+	if err != nil {
+		t.Fatalf("Dial() run error = %v", err)
+	}
+	defer runConn.Close()
+	hooks.StageEvent(ctx, testStage("run-1"), modelEvent(true, genai.NewPartFromText("Des")))
+
+	h.startRequest(sessionKey("agi", "user", "s1"))
+	h.endRequest(sessionKey("agi", "user", "s1"))
+
+	want := []string{"run_started Build a calculator", "stage_started", "chunk Des", "run_completed"}
+	for name, conn := range map[string]*websocket.Conn{"session": sessionConn, "run": runConn} {
+		var got []progressEvent
+		for range want {
+			var e progressEvent
+			if err := conn.ReadJSON(&e); err != nil {
+				t.Fatalf("%s ReadJSON() error = %v", name, err)
+			}
+			got = append(got, e)
+		}
+		if !slices.Equal(eventSummary(got), want) || got[0].RunID != "run-1" || got[0].SessionID != "s1" {
+			t.Errorf("%s events = %+v, want %q of run-1", name, got, want)
+		}
+	}
+	// The run stream closes once the run completes
+	if _, _, err := runConn.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
+		t.Errorf("run stream after completion error = %v, want a normal close", err)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// runCommand is the launcher argument that runs the pipeline once instead of serving it
+const runCommand = "run"
+
+// Exit codes of agi run
+const (
+	// exitFailed means the run or one of its stages failed
+	exitFailed = 1
+	// exitUsage means the run could not start, such as for a missing prompt file
+	exitUsage = 2
+)
+
+// headlessApp and headlessUser name the sessions of agi run and agi chat
+const (
+	headlessApp  = "agi"
+	headlessUser = "agi"
+)
+
+// runFlags are the flags of agi run
+type runFlags struct {
+	// promptFile is the file with the request, or - for stdin
+	promptFile string
+	// out is the workspace directory, overriding the configured one
+	out string
+	// timeout bounds the run (0 for none)
+	timeout time.Duration
+}
+
+// parseRunFlags parses the arguments of agi run
+func parseRunFlags(args []string) (*runFlags, error) {
+	f := &runFlags{}
+	fs := flag.NewFlagSet("agi run", flag.ContinueOnError)
+	fs.StringVar(&f.promptFile, "prompt-file", "", "file with the request to run, or - to read it from stdin")
+	fs.StringVar(&f.out, "out", "", "workspace directory the pipeline writes to (default: the configured workspace)")
+	fs.DurationVar(&f.timeout, "timeout", 0, "maximum duration of the run, such as 30m (default: none)")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: agi [flags] run --prompt-file task.md [--out ./workspace] [--timeout 30m]\n\n"+
+			"Runs the pipeline once, prints a summary, and exits with 0 on success, %d when the run fails, and %d when it cannot start.\n\nFlags:\n",
+			exitFailed, exitUsage)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	if fs.NArg() > 0 {
+		return nil, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+	if f.promptFile == "" {
+		return nil, fmt.Errorf("--prompt-file is required")
+	}
+	return f, nil
+}
+
+// apply changes the configuration for a headless run
+func (f *runFlags) apply(config *Config) {
+	if f.out != "" {
+		config.Pipeline.WorkspaceDir = f.out
+	}
+	// Nobody can approve the design of a headless run
+	if config.Pipeline.RequireDesignApproval {
+		log.Printf("Design approval is skipped in headless runs")
+		config.Pipeline.RequireDesignApproval = false
+	}
+}
+
+// readPrompt reads the request from the prompt file, or from stdin for -
+func (f *runFlags) readPrompt(stdin io.Reader) (string, error) {
+	var data []byte
+	var err error
+	if f.promptFile == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(f.promptFile)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read prompt %s: %w", f.promptFile, err)
+	}
+	prompt := strings.TrimSpace(string(data))
+	if prompt == "" {
+		return "", fmt.Errorf("prompt %s is empty", f.promptFile)
+	}
+	return prompt, nil
+}
+
+// runSummary is the progress listener of a headless run, which collects its summary
+type runSummary struct {
+	mu sync.Mutex
+	// stages are the stages that completed, in order
+	stages []string
+	// failures are the errors of the failed stages
+	failures []string
+	// usage is the token usage of all model responses
+	usage agents.TokenUsage
+	// files are the workspace files written, in the order they were first written
+	files []string
+}
+
+// StageStarted implements agents.ProgressListener
+func (s *runSummary) StageStarted(stage string) {
+	log.Printf("Stage %s started", stage)
+}
+
+// StageCompleted implements agents.ProgressListener
+func (s *runSummary) StageCompleted(stage string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stages = append(s.stages, stage)
+	if err != nil {
+		s.failures = append(s.failures, fmt.Sprintf("%s: %v", stage, err))
+	}
+}
+
+// TokensUsed implements agents.ProgressListener
+func (s *runSummary) TokensUsed(stage, agent string, usage agents.TokenUsage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage.PromptTokens += usage.PromptTokens
+	s.usage.CompletionTokens += usage.CompletionTokens
+	s.usage.TotalTokens += usage.TotalTokens
+}
+
+// FileWritten implements agents.ProgressListener
+func (s *runSummary) FileWritten(stage, agent, path string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !slices.Contains(s.files, path) {
+		s.files = append(s.files, path)
+	}
+}
+
+// write prints the summary of a run that took duration and ended with runErr
+func (s *runSummary) write(w io.Writer, duration time.Duration, runErr error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := "succeeded"
+	if runErr != nil || len(s.failures) > 0 {
+		status = "failed"
+	}
+	fmt.Fprintf(w, "Run %s in %s\n", status, duration.Round(time.Millisecond))
+	fmt.Fprintf(w, "Stages: %d completed, %d failed\n", len(s.stages), len(s.failures))
+	for _, failure := range s.failures {
+		fmt.Fprintf(w, "  %s\n", failure)
+	}
+	if runErr != nil {
+		fmt.Fprintf(w, "Error: %v\n", runErr)
+	}
+	fmt.Fprintf(w, "Tokens: %d (prompt %d, completion %d)\n", s.usage.TotalTokens, s.usage.PromptTokens, s.usage.CompletionTokens)
+	fmt.Fprintf(w, "Files written: %d\n", len(s.files))
+	for _, file := range s.files {
+		fmt.Fprintf(w, "  %s\n", file)
+	}
+}
+
+// exitCode returns the exit code of a run that ended with runErr
+func (s *runSummary) exitCode(runErr error) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if runErr != nil || len(s.failures) > 0 {
+		return exitFailed
+	}
+	return 0
+}
+
+// runHeadless sends the prompt of f to rootAgent once, prints the summary collected by
+// summary to out, and returns the exit code
+func runHeadless(ctx context.Context, rootAgent agent.Agent, f *runFlags, summary *runSummary, out io.Writer) int {
+	prompt, err := f.readPrompt(os.Stdin)
+	if err != nil {
+		log.Printf("Headless run failed: %v", err)
+		return exitUsage
+	}
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	runErr := runOnce(ctx, rootAgent, prompt)
+	if runErr == nil && ctx.Err() != nil {
+		runErr = ctx.Err()
+	}
+	summary.write(out, time.Since(start), runErr)
+	return summary.exitCode(runErr)
+}
+
+// runOnce runs rootAgent with prompt in a new in-memory session and returns the first
+// error the run reported
+func runOnce(ctx context.Context, rootAgent agent.Agent, prompt string) error {
+	r, sessionService, err := newLocalRunner(rootAgent)
+	if err != nil {
+		return err
+	}
+	sessionID, err := newLocalSession(ctx, sessionService)
+	if err != nil {
+		return err
+	}
+
+	var runErr error
+	msg := genai.NewContentFromText(prompt, genai.RoleUser)
+	for _, err := range r.Run(ctx, headlessUser, sessionID, msg, agent.RunConfig{}) {
+		if err != nil && runErr == nil {
+			runErr = err
+		}
+	}
+	return runErr
+}
+
+// newLocalRunner creates a runner of rootAgent that keeps its sessions in memory
+func newLocalRunner(rootAgent agent.Agent) (*runner.Runner, session.Service, error) {
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{AppName: headlessApp, Agent: rootAgent, SessionService: sessionService})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create runner: %w", err)
+	}
+	return r, sessionService, nil
+}
+
+// newLocalSession creates a session of the headless user and returns its ID
+func newLocalSession(ctx context.Context, sessionService session.Service) (string, error) {
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: headlessApp, UserID: headlessUser})
+	if err != nil {
+		return "", fmt.Errorf("failed to create session: %w", err)
+	}
+	return created.Session.ID(), nil
+}
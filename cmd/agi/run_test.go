@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestParseRunFlags(t *testing.T) {
+	tests := []struct {
+		name        string
+		args        []string
+		want        runFlags
+		errContains string
+	}{
+		{
+			name: "all flags",
+			args: []string{"--prompt-file", "task.md", "--out", "./out", "--timeout", "30m"},
+			want: runFlags{promptFile: "task.md", out: "./out", timeout: 30 * time.Minute},
+		},
+		{
+			name:        "missing prompt file",
+			args:        []string{"--out", "./out"},
+			errContains: "--prompt-file is required",
+		},
+		{
+			name:        "extra arguments",
+			args:        []string{"--prompt-file", "task.md", "web"},
+			errContains: "unexpected arguments: web",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseRunFlags(tt.args)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("parseRunFlags() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseRunFlags() error = %v", err)
+			}
+			if *got != tt.want {
+				t.Errorf("parseRunFlags() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFlagsApply(t *testing.T) {
+	config := &Config{Pipeline: agents.PipelineConfig{WorkspaceDir: "./workspace", RequireDesignApproval: true}}
+	(&runFlags{out: "./out"}).apply(config)
+	if config.Pipeline.WorkspaceDir != "./out" || config.Pipeline.RequireDesignApproval {
+		t.Errorf("Pipeline = %+v, want workspace ./out without design approval", config.Pipeline)
+	}
+}
+
+func TestReadPrompt(t *testing.T) {
+	dir := t.TempDir()
+	task := filepath.Join(dir, "task.md")
+	if err := os.WriteFile(task, []byte("\nBuild a calculator\n"), 0644); err != nil {
+		t.Fatalf("failed to write prompt: %v", err)
+	}
+	empty := filepath.Join(dir, "empty.md")
+	if err := os.WriteFile(empty, []byte(" \n"), 0644); err != nil {
+		t.Fatalf("failed to write prompt: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		promptFile  string
+		want        string
+		errContains string
+	}{
+		{name: "file", promptFile: task, want: "Build a calculator"},
+		{name: "stdin", promptFile: "-", want: "Build a stack"},
+		{name: "empty", promptFile: empty, errContains: "is empty"},
+		{name: "missing", promptFile: filepath.Join(dir, "missing.md"), errContains: "failed to read prompt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := (&runFlags{promptFile: tt.promptFile}).readPrompt(strings.NewReader("Build a stack\n"))
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("readPrompt() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("readPrompt() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunHeadless(t *testing.T) {
+	dir := t.TempDir()
+	task := filepath.Join(dir, "task.md")
+	if err := os.WriteFile(task, []byte("Build a calculator"), 0644); err != nil {
+		t.Fatalf("failed to write prompt: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		turns    []fake.Turn
+		wantCode int
+		wantOut  []string
+	}{
+		{
+			name: "success",
+			turns: []fake.Turn{
+				fake.Text("design: a calculator"),
+				fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": "package calc\n"}),
+				fake.Text("wrote calc.go"),
+				fake.Text("no tests needed"),
+				fake.Text("No major issues found."),
+			},
+			wantOut: []string{"Run succeeded", "Stages: 4 completed, 0 failed", "Files written: 1\n  calc.go\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := &runSummary{}
+			rootAgent, err := agents.NewAgent(agents.PipelineConfig{
+				Model:        fake.New("fake-model", tt.turns...),
+				WorkspaceDir: t.TempDir(),
+				SkipBuild:    true,
+				SkipTests:    true,
+				Progress:     summary,
+			})
+			if err != nil {
+				t.Fatalf("NewAgent() error = %v", err)
+			}
+
+			var out strings.Builder
+			code := runHeadless(context.Background(), rootAgent, &runFlags{promptFile: task}, summary, &out)
+			if code != tt.wantCode {
+				t.Errorf("runHeadless() = %d, want %d; summary:\n%s", code, tt.wantCode, out.String())
+			}
+			for _, want := range tt.wantOut {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("summary = %q, want it to contain %q", out.String(), want)
+				}
+			}
+		})
+	}
+
+	var out strings.Builder
+	if code := runHeadless(context.Background(), nil, &runFlags{promptFile: filepath.Join(dir, "missing.md")}, &runSummary{}, &out); code != exitUsage {
+		t.Errorf("runHeadless() with a missing prompt = %d, want %d", code, exitUsage)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	summary := &runSummary{}
+	summary.StageCompleted("DesignAgent", nil)
+	summary.TokensUsed("DesignAgent", "DesignAgent", agents.TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120})
+	summary.FileWritten("CodeWriterAgent", "CodeWriterAgent", "calc.go")
+	summary.FileWritten("CodeWriterAgent", "CodeWriterAgent", "calc.go")
+	if code := summary.exitCode(nil); code != 0 {
+		t.Errorf("exitCode() = %d, want 0", code)
+	}
+	if code := summary.exitCode(context.Canceled); code != exitFailed {
+		t.Errorf("exitCode() of a failed run = %d, want %d", code, exitFailed)
+	}
+
+	summary.StageCompleted("BuildAgent", errors.New("build failed"))
+	var out strings.Builder
+	summary.write(&out, 1500*time.Millisecond, nil)
+	want := "Run failed in 1.5s\nStages: 2 completed, 1 failed\n  BuildAgent: build failed\nTokens: 120 (prompt 100, completion 20)\nFiles written: 1\n  calc.go\n"
+	if out.String() != want {
+		t.Errorf("write() = %q, want %q", out.String(), want)
+	}
+	if code := summary.exitCode(nil); code != exitFailed {
+		t.Errorf("exitCode() with a failed stage = %d, want %d", code, exitFailed)
+	}
+}
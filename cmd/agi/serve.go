@@ -0,0 +1,17 @@
+package main
+
+// serveCommand is the launcher argument that starts the agent server with the launcher
+// arguments that follow; agi without a command does the same
+const serveCommand = "serve"
+
+// serveArgs returns the launcher arguments of agi serve, or of agi without a command: the
+// arguments given, or those of the server configuration when there are none
+func serveArgs(args []string, server ServerConfig) []string {
+	if len(args) > 0 && args[0] == serveCommand {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return server.launcherArgs()
+	}
+	return args
+}
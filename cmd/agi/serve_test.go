@@ -0,0 +1,29 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestServeArgs(t *testing.T) {
+	web := ServerConfig{Launcher: launcherWeb, Port: 9090, Services: []string{"api"}}
+	tests := []struct {
+		name   string
+		args   []string
+		server ServerConfig
+		want   []string
+	}{
+		{name: "serve with launcher arguments", args: []string{"serve", "web", "-port", "8081", "api"}, server: web, want: []string{"web", "-port", "8081", "api"}},
+		{name: "serve with the configured server", args: []string{"serve"}, server: web, want: []string{"web", "-port", "9090", "api", "-webui_address", "localhost:9090"}},
+		{name: "serve with the default launcher", args: []string{"serve"}, want: nil},
+		{name: "launcher arguments without serve", args: []string{"console"}, server: web, want: []string{"console"}},
+		{name: "no arguments", server: web, want: []string{"web", "-port", "9090", "api", "-webui_address", "localhost:9090"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := serveArgs(tt.args, tt.server); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("serveArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+)
+
+// streamEvents sends the backlog and then the events of sub to the client of r as
+// server-sent events, each with its ID and type, and a heartbeat comment while no event
+// comes. The stream ends when the subscriber is dropped, or the run completes, and event
+// sources reconnect with the Last-Event-ID of the last event they got.
+func (h *progressHub) streamEvents(w http.ResponseWriter, r *http.Request, sub *progressSubscriber, backlog []progressEvent) {
+	defer h.unsubscribe(sub)
+	rc := http.NewResponseController(w)
+	// write writes and flushes, with a deadline of its own, as the stream outlives the
+	// write timeout of the server
+	write := func(send func(io.Writer) error) error {
+		if err := rc.SetWriteDeadline(time.Now().Add(progressWriteTimeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		if err := send(w); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	heartbeat := func(w io.Writer) error {
+		_, err := io.WriteString(w, ": heartbeat\n\n")
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Proxies such as nginx pass the events on instead of buffering them
+	w.Header().Set("X-Accel-Buffering", "no")
+	if err := write(heartbeat); err != nil {
+		log.Printf("Failed to open event stream: %v", err)
+		return
+	}
+	for _, e := range backlog {
+		if err := write(func(w io.Writer) error { return writeEvent(w, e) }); err != nil {
+			return
+		}
+	}
+	if sub == nil {
+		return
+	}
+	ticker := time.NewTicker(progressPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case e, ok := <-sub.events:
+			if !ok {
+				return
+			}
+			if err := write(func(w io.Writer) error { return writeEvent(w, e) }); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := write(heartbeat); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+// writeEvent writes e to w as a server-sent event named after its type, with its JSON as data
+func writeEvent(w io.Writer, e progressEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %d: %w", e.ID, err)
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// readEvent reads the next server-sent event from events, skipping comments, and returns
+// its ID, type, and data
+func readEvent(t *testing.T, events *bufio.Reader) (id, eventType string, data progressEvent) {
+	t.Helper()
+	for {
+		line, err := events.ReadString('\n')
+		if err != nil {
+			t.Fatalf("failed to read event: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && id != "":
+			return id, eventType, data
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			eventType = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &data); err != nil {
+				t.Fatalf("invalid event data %q: %v", line, err)
+			}
+		}
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	h := newProgressHub()
+	sessions := inmemory.New()
+	if _, err := sessions.Create(context.Background(), &session.CreateRequest{AppName: "agi", UserID: "user", SessionID: "s1"}); err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+	router := mux.NewRouter()
+	if err := (progressLauncher{hub: h}).SetupSubrouters(router, &adk.Config{SessionService: sessions}); err != nil {
+		t.Fatalf("SetupSubrouters() error = %v", err)
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/apps/agi/users/user/sessions/s2/stream")
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("stream of an unknown session status = %d, want 404", resp.StatusCode)
+	}
+
+	ctx := context.Background()
+	hooks := h.hooks()
+	hooks.BeforeStage(ctx, testStage("run-1"))
+	hooks.StageEvent(ctx, testStage("run-1"), modelEvent(true, genai.NewPartFromText("Des")))
+
+	// A reconnecting event source gets the events after the last one it got
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/apps/agi/users/user/sessions/s1/stream", nil)
+	req.Header.Set("Last-Event-ID", "2")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET error = %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("stream = %d %q, want 200 text/event-stream", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	events := bufio.NewReader(resp.Body)
+	if line, _ := events.ReadString('\n'); line != ": heartbeat\n" {
+		t.Errorf("first line = %q, want a heartbeat", line)
+	}
+
+	hooks.StageEvent(ctx, testStage("run-1"), modelEvent(false, genai.NewPartFromFunctionCall("fileRead", map[string]any{"path": "go.mod"})))
+	var got []string
+	for range 2 {
+		id, eventType, data := readEvent(t, events)
+		if id != strconv.FormatUint(data.ID, 10) || eventType != data.Type || data.RunID != "run-1" {
+			t.Errorf("event %s %s = %+v, want its ID, type, and run", id, eventType, data)
+		}
+		got = append(got, id+" "+eventSummary([]progressEvent{data})[0])
+	}
+	if want := []string{"3 chunk Des", "4 tool_call fileRead"}; !slices.Equal(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// The stream ends when the server shuts down
+	h.close()
+	if rest, err := io.ReadAll(events); err != nil || len(rest) != 0 {
+		t.Errorf("stream after shutdown = %q, %v; want its end", rest, err)
+	}
+}
+
+func TestProgressAfter(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		header  string
+		want    uint64
+		wantErr bool
+	}{
+		{name: "new subscription", url: "/stream"},
+		{name: "query", url: "/stream?after=7", want: 7},
+		{name: "Last-Event-ID", url: "/stream", header: "9", want: 9},
+		{name: "Last-Event-ID of a reconnect wins", url: "/stream?after=7", header: "12", want: 12},
+		{name: "invalid", url: "/stream?after=x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+			got, err := progressAfter(req)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("progressAfter() = %d, %v; want %d, error %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
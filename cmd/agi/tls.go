@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// defaultACMECacheDir is the directory that keeps the ACME account and certificates
+const defaultACMECacheDir = "acme-cache"
+
+// newTLSConfig returns the TLS configuration of the web server for config: the certificate
+// and key files, or certificates issued by Let's Encrypt over ACME for the domains. It
+// returns nil when config enables no TLS.
+func newTLSConfig(config TLSConfig) (*tls.Config, error) {
+	switch {
+	case config.CertFile != "":
+		certs := &certReloader{certFile: config.CertFile, keyFile: config.KeyFile}
+		if err := certs.load(); err != nil {
+			return nil, err
+		}
+		return &tls.Config{MinVersion: tls.VersionTLS12, GetCertificate: certs.getCertificate}, nil
+	case len(config.ACMEDomains) > 0:
+		// The certificates are requested over the TLS-ALPN-01 challenge, on the port of the server
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(config.ACMEDomains...),
+			Cache:      autocert.DirCache(config.ACMECacheDir),
+			Email:      config.ACMEEmail,
+		}
+		tlsConfig := m.TLSConfig()
+		tlsConfig.MinVersion = tls.VersionTLS12
+		return tlsConfig, nil
+	default:
+		return nil, nil
+	}
+}
+
+// certReloader serves a certificate from files and loads them again when they change, so
+// renewed certificates are served without a restart
+type certReloader struct {
+	// certFile is the PEM certificate chain
+	certFile string
+	// keyFile is the PEM private key
+	keyFile string
+
+	mu sync.Mutex
+	// cert is the certificate loaded last
+	cert *tls.Certificate
+	// modTime is the latest modification time of the files when they were loaded
+	modTime time.Time
+}
+
+// getCertificate implements tls.Config.GetCertificate. A certificate that fails to load is
+// logged, and the previous one is served until the files are fixed.
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	modTime, err := r.filesModTime()
+	changed := err == nil && modTime.After(r.modTime)
+	r.mu.Unlock()
+	if changed {
+		if err := r.load(); err != nil {
+			log.Printf("Failed to reload the TLS certificate, serving the previous one: %v", err)
+		} else {
+			log.Printf("Reloaded the TLS certificate from %s", r.certFile)
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cert, nil
+}
+
+// load reads the certificate and key files
+func (r *certReloader) load() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	modTime, err := r.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		// A failed load is not retried until the files change again
+		r.modTime = modTime
+		return fmt.Errorf("failed to load TLS certificate %s and key %s: %w", r.certFile, r.keyFile, err)
+	}
+	r.cert, r.modTime = &cert, modTime
+	return nil
+}
+
+// filesModTime returns the latest modification time of the certificate and key files
+func (r *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, name := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("failed to read TLS file: %w", err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
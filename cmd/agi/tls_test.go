@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/cmd/launcher/adk"
+)
+
+// writeTestCert writes a self-signed certificate for localhost named commonName and its key
+// to dir and returns their paths
+func writeTestCert(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1)},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("failed to create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("failed to encode key: %v", err)
+	}
+	certFile, keyFile = filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("failed to write key: %v", err)
+	}
+	return certFile, keyFile
+}
+
+// servedName returns the common name of the certificate config serves
+func servedName(t *testing.T, config *tls.Config) string {
+	t.Helper()
+	cert, err := config.GetCertificate(&tls.ClientHelloInfo{ServerName: "localhost"})
+	if err != nil || cert == nil {
+		t.Fatalf("GetCertificate() = %v, %v", cert, err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("failed to parse certificate: %v", err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "agi-test")
+
+	tests := []struct {
+		name    string
+		config  TLSConfig
+		wantNil bool
+		wantErr bool
+		check   func(t *testing.T, config *tls.Config)
+	}{
+		{name: "plain HTTP", wantNil: true},
+		{
+			name:   "certificate files",
+			config: TLSConfig{CertFile: certFile, KeyFile: keyFile},
+			check: func(t *testing.T, config *tls.Config) {
+				if got := servedName(t, config); got != "agi-test" {
+					t.Errorf("served certificate %q, want agi-test", got)
+				}
+			},
+		},
+		{name: "missing certificate", config: TLSConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile}, wantErr: true},
+		{name: "key of the wrong file", config: TLSConfig{CertFile: certFile, KeyFile: certFile}, wantErr: true},
+		{
+			name:   "ACME",
+			config: TLSConfig{ACMEDomains: []string{"agi.example.com"}, ACMECacheDir: filepath.Join(dir, "acme")},
+			check: func(t *testing.T, config *tls.Config) {
+				if !slices.Contains(config.NextProtos, "acme-tls/1") || config.GetCertificate == nil {
+					t.Errorf("ACME config = %+v, want the TLS-ALPN-01 challenge", config)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := newTLSConfig(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newTLSConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (config == nil) != tt.wantNil {
+				t.Fatalf("newTLSConfig() = %v, want nil %v", config, tt.wantNil)
+			}
+			if config != nil && config.MinVersion != tls.VersionTLS12 {
+				t.Errorf("MinVersion = %x, want TLS 1.2", config.MinVersion)
+			}
+			if tt.check != nil {
+				tt.check(t, config)
+			}
+		})
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeTestCert(t, dir, "first")
+	config, err := newTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("newTLSConfig() error = %v", err)
+	}
+	if got := servedName(t, config); got != "first" {
+		t.Fatalf("served certificate %q, want first", got)
+	}
+
+	// A renewed certificate is served once its files change
+	later := time.Now().Add(time.Minute)
+	writeTestCert(t, dir, "renewed")
+	for _, name := range []string{certFile, keyFile} {
+		if err := os.Chtimes(name, later, later); err != nil {
+			t.Fatalf("failed to touch %s: %v", name, err)
+		}
+	}
+	if got := servedName(t, config); got != "renewed" {
+		t.Errorf("served certificate %q after renewal, want renewed", got)
+	}
+
+	// A broken certificate keeps the previous one
+	if err := os.WriteFile(certFile, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("failed to write certificate: %v", err)
+	}
+	later = later.Add(time.Minute)
+	if err := os.Chtimes(certFile, later, later); err != nil {
+		t.Fatalf("failed to touch %s: %v", certFile, err)
+	}
+	if got := servedName(t, config); got != "renewed" {
+		t.Errorf("served certificate %q after a broken renewal, want renewed", got)
+	}
+}
+
+func TestWebLauncher_TLS(t *testing.T) {
+	certFile, keyFile := writeTestCert(t, t.TempDir(), "agi-test")
+	release := make(chan struct{})
+	close(release)
+	w := newWebLauncher(defaultDrainTimeout, nil, blockingLauncher{started: make(chan struct{}), release: release})
+	var err error
+	if w.tls, err = newTLSConfig(TLSConfig{CertFile: certFile, KeyFile: keyFile}); err != nil {
+		t.Fatalf("newTLSConfig() error = %v", err)
+	}
+	if _, err := w.Parse([]string{"-port", "0", "blocking"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	addr := make(chan net.Addr, 1)
+	w.listening = func(a net.Addr) { addr <- a }
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	go func() {
+		stopped <- w.Run(ctx, &adk.Config{SessionService: inmemory.New()})
+	}()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	port := (<-addr).(*net.TCPAddr).Port
+
+	pemData, err := os.ReadFile(certFile)
+	if err != nil {
+		t.Fatalf("failed to read certificate: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(pemData)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err := client.Post(fmt.Sprintf("https://localhost:%d/api/run", port), "application/json", nil)
+	if err != nil {
+		t.Fatalf("HTTPS request error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "done" || resp.TLS == nil {
+		t.Errorf("HTTPS response = %q, TLS %v; want done over TLS", body, resp.TLS != nil)
+	}
+
+	if resp, err := http.Post(fmt.Sprintf("http://localhost:%d/api/run", port), "application/json", nil); err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("plain HTTP request status = %d, want 400", resp.StatusCode)
+		}
+	}
+}
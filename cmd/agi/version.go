@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// versionCommand is the launcher argument that prints the build information and exits
+const versionCommand = "version"
+
+// Build information, set by make build with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.buildDate=2025-01-02T03:04:05Z"
+var (
+	version   = "dev"
+	commit    = ""
+	buildDate = ""
+)
+
+// Modules whose versions are part of the build information
+const (
+	adkModule    = "google.golang.org/adk"
+	ollamaModule = "github.com/ollama/ollama"
+)
+
+// buildInfo identifies the agi binary
+type buildInfo struct {
+	// Version is the semantic version of agi, or dev for untagged builds
+	Version string
+	// Commit is the git commit agi was built from
+	Commit string
+	// BuildDate is when agi was built
+	BuildDate string
+	// GoVersion is the Go version agi was built with
+	GoVersion string
+	// ADKVersion is the version of the ADK module
+	ADKVersion string
+	// OllamaVersion is the version of the Ollama client module
+	OllamaVersion string
+}
+
+// readBuildInfo returns the build information of the running binary
+func readBuildInfo() buildInfo {
+	info, _ := debug.ReadBuildInfo()
+	return newBuildInfo(info)
+}
+
+// newBuildInfo combines the values set with -ldflags with info, which is nil when the
+// binary was built without module support. The version control settings Go embeds stand in
+// for the commit and build date when they were not set.
+func newBuildInfo(info *debug.BuildInfo) buildInfo {
+	b := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+	if info == nil {
+		return b
+	}
+	// go install module@version stamps the version of the main module
+	if b.Version == "dev" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		b.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch {
+		case setting.Key == "vcs.revision" && b.Commit == "":
+			b.Commit = setting.Value
+		case setting.Key == "vcs.time" && b.BuildDate == "":
+			b.BuildDate = setting.Value
+		}
+	}
+	for _, dep := range info.Deps {
+		depVersion := dep.Version
+		if dep.Replace != nil {
+			depVersion = dep.Replace.Version
+		}
+		switch dep.Path {
+		case adkModule:
+			b.ADKVersion = depVersion
+		case ollamaModule:
+			b.OllamaVersion = depVersion
+		}
+	}
+	return b
+}
+
+// write prints the build information, with unknown for the values that are not known
+func (b buildInfo) write(w io.Writer) {
+	value := func(s string) string {
+		if s == "" {
+			return "unknown"
+		}
+		return s
+	}
+	fmt.Fprintf(w, "agi %s\n", value(b.Version))
+	fmt.Fprintf(w, "  commit:  %s\n", value(b.Commit))
+	fmt.Fprintf(w, "  built:   %s\n", value(b.BuildDate))
+	fmt.Fprintf(w, "  go:      %s\n", value(b.GoVersion))
+	fmt.Fprintf(w, "  adk:     %s\n", value(b.ADKVersion))
+	fmt.Fprintf(w, "  ollama:  %s\n", value(b.OllamaVersion))
+}
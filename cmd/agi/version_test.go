@@ -0,0 +1,81 @@
+package main
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestNewBuildInfo(t *testing.T) {
+	info := &debug.BuildInfo{
+		Main: debug.Module{Path: "com.github.dimetron.adk-go-agi", Version: "v0.3.0"},
+		Deps: []*debug.Module{
+			{Path: adkModule, Version: "v0.1.0"},
+			{Path: ollamaModule, Version: "v0.12.10", Replace: &debug.Module{Path: "../ollama", Version: "v0.12.11"}},
+			{Path: "gopkg.in/yaml.v3", Version: "v3.0.1"},
+		},
+		Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.time", Value: "2025-01-01T00:00:00Z"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		ldflags [3]string
+		info    *debug.BuildInfo
+		want    buildInfo
+	}{
+		{
+			name:    "ldflags",
+			ldflags: [3]string{"v1.2.3", "abc1234", "2025-02-03T04:05:06Z"},
+			info:    info,
+			want:    buildInfo{Version: "v1.2.3", Commit: "abc1234", BuildDate: "2025-02-03T04:05:06Z", ADKVersion: "v0.1.0", OllamaVersion: "v0.12.11"},
+		},
+		{
+			name:    "embedded build information",
+			ldflags: [3]string{"dev", "", ""},
+			info:    info,
+			want:    buildInfo{Version: "v0.3.0", Commit: "0123456789abcdef", BuildDate: "2025-01-01T00:00:00Z", ADKVersion: "v0.1.0", OllamaVersion: "v0.12.11"},
+		},
+		{
+			name:    "development build",
+			ldflags: [3]string{"dev", "", ""},
+			info:    &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}},
+			want:    buildInfo{Version: "dev"},
+		},
+		{
+			name:    "no build information",
+			ldflags: [3]string{"dev", "", ""},
+			want:    buildInfo{Version: "dev"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			saved := [3]string{version, commit, buildDate}
+			t.Cleanup(func() { version, commit, buildDate = saved[0], saved[1], saved[2] })
+			version, commit, buildDate = tt.ldflags[0], tt.ldflags[1], tt.ldflags[2]
+
+			tt.want.GoVersion = runtime.Version()
+			if got := newBuildInfo(tt.info); got != tt.want {
+				t.Errorf("newBuildInfo() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildInfoWrite(t *testing.T) {
+	var out strings.Builder
+	buildInfo{Version: "v1.2.3", Commit: "abc1234", GoVersion: "go1.25.3", ADKVersion: "v0.1.0"}.write(&out)
+	want := "agi v1.2.3\n" +
+		"  commit:  abc1234\n" +
+		"  built:   unknown\n" +
+		"  go:      go1.25.3\n" +
+		"  adk:     v0.1.0\n" +
+		"  ollama:  unknown\n"
+	if out.String() != want {
+		t.Errorf("write() = %q, want %q", out.String(), want)
+	}
+}
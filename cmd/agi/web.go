@@ -0,0 +1,368 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/cmd/launcher"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/cmd/launcher/console"
+	"google.golang.org/adk/cmd/launcher/universal"
+	"google.golang.org/adk/cmd/launcher/web"
+	"google.golang.org/adk/cmd/launcher/web/a2a"
+	"google.golang.org/adk/cmd/launcher/web/api"
+	"google.golang.org/adk/cmd/launcher/web/webui"
+	"google.golang.org/adk/session"
+)
+
+// defaultDrainTimeout is how long the web server waits for runs in progress when it shuts down
+const defaultDrainTimeout = 30 * time.Second
+
+// drainStopTimeout bounds how long the web server waits for the canceled runs to return
+// once the drain timeout passes
+const drainStopTimeout = 5 * time.Second
+
+// newLauncher returns the console launcher and the web launcher with the progress, export,
+// api, a2a, and webui sublaunchers, with the history sublauncher when the run history is
+// kept, and with the metrics sublauncher when server enables metrics. The history, progress,
+// and export routes come first, so the /api/ routes of the api sublauncher do not hide them. The web server serves HTTPS with the TLS options of server,
+// requires its credentials, caps its runs, streams their progress from the progress hub,
+// drains runs for the drain timeout on shutdown, and closes drain when it starts.
+func newLauncher(server ServerConfig, drain chan struct{}, workspaceDir string, progress *progressHub, history *runHistory) (launcher.Launcher, error) {
+	sublaunchers := []web.Sublauncher{progressLauncher{hub: progress}, exportLauncher{workspaceDir: workspaceDir, config: tools.ToolConfig}, api.NewLauncher(), a2a.NewLauncher(), webui.NewLauncher()}
+	if history != nil {
+		sublaunchers = slices.Insert(sublaunchers, 0, web.Sublauncher(historyLauncher{history: history, hub: progress}))
+	}
+	if server.Metrics {
+		sublaunchers = append(sublaunchers, metricsLauncher{})
+	}
+	w := newWebLauncher(server.DrainTimeout, drain, sublaunchers...)
+	w.auth = newAuthenticator(server.Auth)
+	w.limits = newRunLimiter(server.Limits)
+	w.progress = progress
+	var err error
+	if w.tls, err = newTLSConfig(server.TLS); err != nil {
+		return nil, err
+	}
+	return universal.NewLauncher(console.NewLauncher(), w), nil
+}
+
+// withSublauncher adds the sublauncher keyword to launcher arguments that start the web
+// server without it
+func withSublauncher(args []string, keyword string) []string {
+	if len(args) == 0 || args[0] != launcherWeb || slices.Contains(args[1:], keyword) {
+		return args
+	}
+	return append(slices.Clip(args), keyword)
+}
+
+// webLauncher is the web launcher of ADK with a graceful shutdown: when its context is
+// done it stops accepting runs and waits up to the drain timeout for the runs in progress,
+// which stop after their current stage, before it closes the server
+type webLauncher struct {
+	// flags are the web launcher flags
+	flags *flag.FlagSet
+	// port is the port of the server
+	port int
+	// sublaunchers are the sublaunchers that can be selected by keyword
+	sublaunchers []web.Sublauncher
+	// active are the sublaunchers selected on the command line, in the order of sublaunchers
+	active []web.Sublauncher
+	// drainTimeout is how long the server waits for runs in progress when it shuts down
+	drainTimeout time.Duration
+	// drain is closed when the server starts to shut down, for PipelineConfig.Drain
+	drain chan struct{}
+	// runs tracks the runs in progress
+	runs runTracker
+	// auth requires credentials for every request, nil for an open server
+	auth *authenticator
+	// tls serves HTTPS, nil for plain HTTP
+	tls *tls.Config
+	// limits caps the runs, nil without limits
+	limits *runLimiter
+	// progress completes the progress streams of the runs, nil without progress streams
+	progress *progressHub
+	// listening is called with the address of the server once it listens, for tests
+	listening func(net.Addr)
+}
+
+// newWebLauncher returns the draining web launcher with sublaunchers
+func newWebLauncher(drainTimeout time.Duration, drain chan struct{}, sublaunchers ...web.Sublauncher) *webLauncher {
+	w := &webLauncher{
+		flags:        flag.NewFlagSet(launcherWeb, flag.ContinueOnError),
+		sublaunchers: sublaunchers,
+		drainTimeout: drainTimeout,
+		drain:        drain,
+	}
+	w.flags.IntVar(&w.port, "port", 8080, "Localhost port for the server")
+	return w
+}
+
+// Keyword implements launcher.SubLauncher
+func (w *webLauncher) Keyword() string {
+	return launcherWeb
+}
+
+// Parse implements launcher.SubLauncher. It parses the web flags and then the arguments
+// of each sublauncher keyword that follows, and returns the arguments left over.
+func (w *webLauncher) Parse(args []string) ([]string, error) {
+	byKeyword := make(map[string]web.Sublauncher, len(w.sublaunchers))
+	for _, l := range w.sublaunchers {
+		if _, ok := byKeyword[l.Keyword()]; ok {
+			return nil, fmt.Errorf("sublauncher keyword %q is not unique", l.Keyword())
+		}
+		byKeyword[l.Keyword()] = l
+	}
+	if err := w.flags.Parse(args); err != nil {
+		return nil, fmt.Errorf("failed to parse web flags: %w", err)
+	}
+
+	rest := w.flags.Args()
+	selected := make(map[string]bool)
+	for len(rest) > 0 {
+		l, ok := byKeyword[rest[0]]
+		if !ok {
+			break
+		}
+		if selected[rest[0]] {
+			return rest, fmt.Errorf("the keyword %q is specified more than once", rest[0])
+		}
+		var err error
+		if rest, err = l.Parse(rest[1:]); err != nil {
+			return nil, fmt.Errorf("the %q launcher cannot parse arguments: %w", l.Keyword(), err)
+		}
+		selected[l.Keyword()] = true
+	}
+	w.active = w.active[:0]
+	for _, l := range w.sublaunchers {
+		if selected[l.Keyword()] {
+			w.active = append(w.active, l)
+		}
+	}
+	return rest, nil
+}
+
+// CommandLineSyntax implements launcher.SubLauncher
+func (w *webLauncher) CommandLineSyntax() string {
+	var b strings.Builder
+	w.flags.SetOutput(&b)
+	w.flags.PrintDefaults()
+	fmt.Fprintf(&b, "  You may specify sublaunchers:\n")
+	for _, l := range w.sublaunchers {
+		fmt.Fprintf(&b, "    * %s - %s\n", l.Keyword(), l.SimpleDescription())
+	}
+	fmt.Fprintf(&b, "  Sublaunchers syntax:\n")
+	for _, l := range w.sublaunchers {
+		fmt.Fprintf(&b, "    %s\n  %s\n", l.Keyword(), l.CommandLineSyntax())
+	}
+	return b.String()
+}
+
+// SimpleDescription implements launcher.SubLauncher
+func (w *webLauncher) SimpleDescription() string {
+	return "starts web server with additional sub-servers specified by sublaunchers, and drains runs on shutdown"
+}
+
+// Run implements launcher.SubLauncher. It serves until ctx is done and then shuts the
+// server down gracefully.
+func (w *webLauncher) Run(ctx context.Context, config *adk.Config) error {
+	if config.SessionService == nil {
+		config.SessionService = inmemory.New()
+	}
+	if len(w.active) == 0 {
+		keywords := make([]string, len(w.sublaunchers))
+		for i, l := range w.sublaunchers {
+			keywords[i] = l.Keyword()
+		}
+		return fmt.Errorf("no active sublaunchers found - please specify them in the command line. Possible values: %v", keywords)
+	}
+
+	router := web.BuildBaseRouter()
+	for _, l := range w.active {
+		if err := l.SetupSubrouters(router, config); err != nil {
+			return fmt.Errorf("%s subrouter setup failed: %w", l.Keyword(), err)
+		}
+	}
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", w.port))
+	if err != nil {
+		return fmt.Errorf("server failed: %w", err)
+	}
+	scheme := "http"
+	if w.tls != nil {
+		scheme = "https"
+	}
+	webURL := fmt.Sprintf("%s://localhost:%d", scheme, listener.Addr().(*net.TCPAddr).Port)
+	log.Printf("Web server starts on %s", webURL)
+	if w.auth == nil {
+		// The agents write files and run commands, so an open server is meant for the local machine
+		log.Printf("Warning: the web server accepts requests without credentials; set server.auth to require them")
+	}
+	for _, l := range w.active {
+		l.UserMessage(webURL, log.Println)
+	}
+	if w.listening != nil {
+		w.listening(listener.Addr())
+	}
+
+	// The timeouts of the ADK web launcher
+	server := &http.Server{
+		WriteTimeout: 15 * time.Second,
+		ReadTimeout:  15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+		Handler:      w.auth.wrap(w.runs.wrap(w.limits.wrap(w.progress.wrap(router)))),
+		TLSConfig:    w.tls,
+	}
+	served := make(chan error, 1)
+	go func() {
+		if w.tls != nil {
+			// The certificates come from the TLS configuration, not from files
+			served <- server.ServeTLS(listener, "", "")
+			return
+		}
+		served <- server.Serve(listener)
+	}()
+	select {
+	case err := <-served:
+		return fmt.Errorf("server failed: %w", err)
+	case <-ctx.Done():
+	}
+	return w.shutdown(server, config.SessionService)
+}
+
+// shutdown stops accepting runs, ends the progress streams, waits up to the drain timeout
+// for the runs in progress, cancels those left, and closes the session service so its state
+// is persisted
+func (w *webLauncher) shutdown(server *http.Server, sessions session.Service) error {
+	inProgress := w.runs.stop()
+	if w.drain != nil {
+		close(w.drain)
+	}
+	// Progress streams never go idle, so they end for the server to shut down
+	w.progress.close()
+	log.Printf("Shutting down, waiting up to %s for %d runs in progress", w.drainTimeout, inProgress)
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), w.drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(drainCtx); err != nil {
+		log.Printf("Drain timeout passed, canceling %d runs in progress", w.runs.inProgress())
+		_ = server.Close()
+		if !w.runs.wait(drainStopTimeout) {
+			log.Printf("Runs still in progress after %s, exiting without them", drainStopTimeout)
+		}
+	}
+
+	if closer, ok := sessions.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			return fmt.Errorf("failed to close session service: %w", err)
+		}
+	}
+	log.Printf("Server stopped")
+	return nil
+}
+
+// runTracker counts the run requests in progress and rejects new ones once stopped
+type runTracker struct {
+	mu       sync.Mutex
+	stopped  bool
+	runs     int
+	finished chan struct{}
+}
+
+// wrap returns next with the run requests tracked; new runs get 503 Service Unavailable
+// once the tracker is stopped
+func (t *runTracker) wrap(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isRunRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !t.start() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		defer t.done()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// start records a new run, unless the tracker is stopped
+func (t *runTracker) start() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stopped {
+		return false
+	}
+	t.runs++
+	return true
+}
+
+// done records the end of a run
+func (t *runTracker) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.runs--
+	if t.runs == 0 && t.finished != nil {
+		close(t.finished)
+		t.finished = nil
+	}
+}
+
+// stop rejects new runs and returns the number in progress
+func (t *runTracker) stop() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.stopped = true
+	return t.runs
+}
+
+// inProgress returns the number of runs in progress
+func (t *runTracker) inProgress() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.runs
+}
+
+// wait waits up to timeout for the runs in progress to end and reports whether they did
+func (t *runTracker) wait(timeout time.Duration) bool {
+	t.mu.Lock()
+	if t.runs == 0 {
+		t.mu.Unlock()
+		return true
+	}
+	if t.finished == nil {
+		t.finished = make(chan struct{})
+	}
+	finished := t.finished
+	t.mu.Unlock()
+
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// isRunRequest reports whether r starts an agent run: a REST run, streamed or not, a replay
+// of the run history, or an A2A request
+func isRunRequest(r *http.Request) bool {
+	if r.Method != http.MethodPost {
+		return false
+	}
+	return strings.HasSuffix(r.URL.Path, "/run") || strings.HasSuffix(r.URL.Path, "/run_sse") ||
+		strings.HasSuffix(r.URL.Path, "/replay") || r.URL.Path == "/a2a/invoke"
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/cmd/launcher/adk"
+	"google.golang.org/adk/session"
+)
+
+// blockingLauncher is a web sublauncher with a run endpoint that blocks until release is
+// closed or the request is canceled
+type blockingLauncher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (blockingLauncher) Keyword() string                       { return "blocking" }
+func (blockingLauncher) Parse(args []string) ([]string, error) { return args, nil }
+func (blockingLauncher) CommandLineSyntax() string             { return "" }
+func (blockingLauncher) SimpleDescription() string             { return "blocks runs" }
+func (blockingLauncher) UserMessage(string, func(v ...any))    {}
+
+func (l blockingLauncher) SetupSubrouters(router *mux.Router, adkConfig *adk.Config) error {
+	router.HandleFunc("/api/run", func(w http.ResponseWriter, r *http.Request) {
+		close(l.started)
+		select {
+		case <-l.release:
+			fmt.Fprint(w, "done")
+		case <-r.Context().Done():
+		}
+	}).Methods(http.MethodPost)
+	return nil
+}
+
+// apiStub is a web sublauncher with the api keyword and no routes
+type apiStub struct{ blockingLauncher }
+
+func (apiStub) Keyword() string { return "api" }
+
+// closingSessions is a session service that records Close
+type closingSessions struct {
+	session.Service
+	closed bool
+}
+
+func (s *closingSessions) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestIsRunRequest(t *testing.T) {
+	tests := []struct {
+		method string
+		path   string
+		want   bool
+	}{
+		{method: http.MethodPost, path: "/api/run", want: true},
+		{method: http.MethodPost, path: "/api/run_sse", want: true},
+		{method: http.MethodPost, path: "/a2a/invoke", want: true},
+		{method: http.MethodPost, path: "/api/history/runs/run-1/replay", want: true},
+		{method: http.MethodGet, path: "/api/list-apps", want: false},
+		{method: http.MethodGet, path: "/api/run", want: false},
+		{method: http.MethodPost, path: "/api/apps/agi/users/u/sessions", want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			if got := isRunRequest(httptest.NewRequest(tt.method, tt.path, nil)); got != tt.want {
+				t.Errorf("isRunRequest() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTracker(t *testing.T) {
+	var tracker runTracker
+	release := make(chan struct{})
+	handler := tracker.wrap(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isRunRequest(r) {
+			<-release
+		}
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/run", nil))
+	}()
+	for tracker.inProgress() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+
+	if got := tracker.stop(); got != 1 {
+		t.Errorf("stop() = %d, want 1 run in progress", got)
+	}
+	rejected := httptest.NewRecorder()
+	handler.ServeHTTP(rejected, httptest.NewRequest(http.MethodPost, "/api/run_sse", nil))
+	if rejected.Code != http.StatusServiceUnavailable {
+		t.Errorf("run after stop status = %d, want 503", rejected.Code)
+	}
+	other := httptest.NewRecorder()
+	handler.ServeHTTP(other, httptest.NewRequest(http.MethodGet, "/api/list-apps", nil))
+	if other.Code != http.StatusOK {
+		t.Errorf("other request after stop status = %d, want 200", other.Code)
+	}
+
+	if tracker.wait(10 * time.Millisecond) {
+		t.Error("wait() = true with a run in progress")
+	}
+	close(release)
+	<-done
+	if !tracker.wait(time.Second) {
+		t.Error("wait() = false after the run ended")
+	}
+}
+
+func TestWebLauncher_Shutdown(t *testing.T) {
+	tests := []struct {
+		name         string
+		drainTimeout time.Duration
+		release      bool
+		wantBody     string
+	}{
+		{name: "run completes within the drain timeout", drainTimeout: 5 * time.Second, release: true, wantBody: "done"},
+		{name: "run canceled after the drain timeout", drainTimeout: 50 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocking := blockingLauncher{started: make(chan struct{}), release: make(chan struct{})}
+			drain := make(chan struct{})
+			w := newWebLauncher(tt.drainTimeout, drain, blocking)
+			if rest, err := w.Parse([]string{"-port", "0", "blocking"}); err != nil || len(rest) != 0 {
+				t.Fatalf("Parse() = %q, %v", rest, err)
+			}
+			addr := make(chan net.Addr, 1)
+			w.listening = func(a net.Addr) { addr <- a }
+			sessions := &closingSessions{Service: inmemory.New()}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			stopped := make(chan error, 1)
+			go func() {
+				stopped <- w.Run(ctx, &adk.Config{SessionService: sessions})
+			}()
+			url := fmt.Sprintf("http://127.0.0.1:%d/api/run", (<-addr).(*net.TCPAddr).Port)
+
+			type result struct {
+				body string
+				err  error
+			}
+			ran := make(chan result, 1)
+			go func() {
+				resp, err := http.Post(url, "application/json", nil)
+				if err != nil {
+					ran <- result{err: err}
+					return
+				}
+				defer resp.Body.Close()
+				var body [16]byte
+				n, _ := resp.Body.Read(body[:])
+				ran <- result{body: string(body[:n])}
+			}()
+			<-blocking.started
+
+			cancel()
+			select {
+			case <-drain:
+			case <-time.After(5 * time.Second):
+				t.Fatal("drain not closed on shutdown")
+			}
+			if tt.release {
+				close(blocking.release)
+			}
+
+			select {
+			case err := <-stopped:
+				if err != nil {
+					t.Fatalf("Run() error = %v", err)
+				}
+			case <-time.After(10 * time.Second):
+				t.Fatal("Run() did not return after shutdown")
+			}
+			got := <-ran
+			if tt.wantBody != "" && (got.err != nil || got.body != tt.wantBody) {
+				t.Errorf("run in progress = %q, %v; want %q", got.body, got.err, tt.wantBody)
+			}
+			if tt.wantBody == "" && got.body != "" {
+				t.Errorf("run in progress = %q, want it canceled", got.body)
+			}
+			if !sessions.closed {
+				t.Error("session service not closed on shutdown")
+			}
+			if _, err := http.Post(url, "application/json", nil); err == nil {
+				t.Error("server still accepting runs after shutdown")
+			}
+		})
+	}
+}
+
+func TestWebLauncher_Parse(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantActive []string
+		wantRest   []string
+		wantErr    bool
+	}{
+		{name: "sublaunchers", args: []string{"-port", "9090", "api", "metrics"}, wantActive: []string{"api", "metrics"}},
+		{name: "active in declaration order", args: []string{"metrics", "api"}, wantActive: []string{"api", "metrics"}},
+		{name: "unknown arguments left over", args: []string{"api", "extra"}, wantActive: []string{"api"}, wantRest: []string{"extra"}},
+		{name: "repeated keyword", args: []string{"api", "api"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := newWebLauncher(defaultDrainTimeout, nil, blockingLauncher{}, apiStub{}, metricsLauncher{})
+			rest, err := w.Parse(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var active []string
+			for _, l := range w.active {
+				active = append(active, l.Keyword())
+			}
+			if !slices.Equal(active, tt.wantActive) || !slices.Equal(rest, tt.wantRest) {
+				t.Errorf("Parse() active %q, rest %q; want %q, %q", active, rest, tt.wantActive, tt.wantRest)
+			}
+		})
+	}
+}
+
+func TestWithSublauncher(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{name: "web", args: []string{"web", "-port", "9090", "api"}, want: []string{"web", "-port", "9090", "api", "metrics"}},
+		{name: "already served", args: []string{"web", "metrics", "api"}, want: []string{"web", "metrics", "api"}},
+		{name: "console", args: []string{"console"}, want: []string{"console"}},
+		{name: "default launcher", args: nil, want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := withSublauncher(tt.args, metricsKeyword); !slices.Equal(got, tt.want) {
+				t.Errorf("withSublauncher() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
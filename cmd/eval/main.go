@@ -0,0 +1,87 @@
+// Command eval runs the code pipeline over a task corpus with several Ollama models and
+// prints a Markdown comparison report.
+//
+//	go run ./cmd/eval -models qwen2.5-coder:7b,gpt-oss:20b
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/eval"
+	ollamamodel "com.github.dimetron.adk-go-agi/pkg/model/ollama"
+)
+
+func main() {
+	corpusPath := flag.String("corpus", "", "YAML task corpus (default: the built-in corpus)")
+	models := flag.String("models", os.Getenv("OLLAMA_MODEL"), "comma-separated Ollama models to compare")
+	workDir := flag.String("workdir", "", "directory for the task workspaces (default: a temporary directory)")
+	jsonPath := flag.String("json", "", "also write the report as JSON to this file")
+	timeout := flag.Duration("timeout", 30*time.Minute, "timeout of each pipeline run")
+	flag.Parse()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if *models == "" {
+		log.Fatal("no models to compare: set -models or OLLAMA_MODEL")
+	}
+	baseURL := os.Getenv("OLLAMA_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:11434"
+	}
+
+	tasks := eval.DefaultCorpus()
+	if *corpusPath != "" {
+		var err error
+		if tasks, err = eval.LoadCorpus(*corpusPath); err != nil {
+			log.Fatalf("failed to load corpus: %s", err)
+		}
+	}
+
+	var candidates []eval.Candidate
+	for _, name := range strings.Split(*models, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		llm, err := ollamamodel.NewModel(ctx, &ollamamodel.Config{ModelName: name, BaseURL: baseURL})
+		if err != nil {
+			log.Fatalf("failed to create Ollama model %s: %s", name, err)
+		}
+		candidates = append(candidates, eval.Candidate{
+			Name:   name,
+			Config: agents.PipelineConfig{Model: llm, LoopPipeline: true},
+		})
+	}
+
+	runner := &eval.Runner{Tasks: tasks, Candidates: candidates, WorkDir: *workDir, Timeout: *timeout}
+	report, err := runner.Run(ctx)
+	if err != nil {
+		log.Fatalf("evaluation failed: %s", err)
+	}
+	log.Printf("Workspaces are in %s", report.WorkDir)
+
+	if *jsonPath != "" {
+		f, err := os.Create(*jsonPath)
+		if err != nil {
+			log.Fatalf("failed to create JSON report: %s", err)
+		}
+		if err := report.WriteJSON(f); err != nil {
+			log.Fatalf("failed to write JSON report: %s", err)
+		}
+		if err := f.Close(); err != nil {
+			log.Fatalf("failed to write JSON report: %s", err)
+		}
+	}
+	if err := report.WriteMarkdown(os.Stdout); err != nil {
+		log.Fatalf("failed to write report: %s", err)
+	}
+}
@@ -0,0 +1,72 @@
+package agents
+
+import (
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// Styles the acceptance test stage writes tests in
+const (
+	AcceptanceTable  = "table"
+	AcceptanceGinkgo = "ginkgo"
+)
+
+// acceptanceStyleInstructions maps each acceptance test style to the style section of the acceptance test stage instruction
+var acceptanceStyleInstructions = map[string]string{
+	AcceptanceTable: `
+
+**Style: table-driven tests with the standard testing package**
+- One TestXxx function per feature, named after the behavior it accepts
+- A []struct table per scenario group with name, given inputs, and expected outcome fields, run with t.Run(tt.name, ...)
+- Use only the standard library; no assertion packages`,
+	AcceptanceGinkgo: `
+
+**Style: Ginkgo v2 and Gomega BDD specs**
+- Import github.com/onsi/ginkgo/v2 and github.com/onsi/gomega with dot imports
+- A suite_test.go per package with RegisterFailHandler(Fail) and RunSpecs
+- Describe per feature, Context per precondition, It per acceptance criterion; use DescribeTable with Entry for example tables
+- Add github.com/onsi/ginkgo/v2 and github.com/onsi/gomega to the require block of go.mod if it exists`,
+}
+
+// acceptanceTestStage describes the acceptance test agent stage; withAcceptanceStyle adds the test style
+func acceptanceTestStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "AcceptanceTestAgent",
+		Description: "Turns a natural-language specification into executable acceptance tests.",
+		OutputKey:   "acceptance_tests",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("AcceptanceTestAgent"),
+	}
+}
+
+// withAcceptanceStyle adds the conventions of style to the acceptance test stage
+func withAcceptanceStyle(spec stageSpec, style string) stageSpec {
+	spec.Instruction += acceptanceStyleInstructions[style]
+	return spec
+}
+
+// NewAcceptanceTestPipeline creates a pipeline that only turns the natural-language
+// specification in the request into executable acceptance tests, in AcceptanceStyle,
+// for teams that write the implementation themselves. It uses the settings of config
+// other than its stages.
+func NewAcceptanceTestPipeline(config PipelineConfig) (agent.Agent, error) {
+	if config.Name == "" {
+		config.Name = "AcceptanceTestPipeline"
+	}
+	if config.Description == "" {
+		config.Description = "Turns a specification into executable acceptance tests."
+	}
+	config.Stages = []StageConfig{{Builtin: builtinAcceptanceTests}}
+	config.PreStages = nil
+	config.PostStages = nil
+	config.LoopPipeline = false
+	config.FixOnReview = false
+	config.RequireDesignApproval = false
+	return NewCodePipelineAgent(config)
+}
@@ -0,0 +1,80 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestAcceptanceTestPipeline_Run(t *testing.T) {
+	const specTest = "package calc_test\n\nimport (\n\t\"testing\"\n\n\t\"example.com/calc\"\n)\n\nfunc TestAdd(t *testing.T) {\n\tif got := calc.Add(2, 2); got != 4 {\n\t\tt.Fatalf(\"Add(2, 2) = %d, want 4\", got)\n\t}\n}\n"
+
+	tests := []struct {
+		name      string
+		config    PipelineConfig
+		wantStyle string
+	}{
+		{
+			name:      "table-driven by default",
+			wantStyle: "table-driven tests with the standard testing package",
+		},
+		{
+			name:      "ginkgo",
+			config:    PipelineConfig{AcceptanceStyle: AcceptanceGinkgo, LoopPipeline: true, Stages: []StageConfig{{Builtin: builtinDesign}}},
+			wantStyle: "Ginkgo v2 and Gomega BDD specs",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model",
+				fake.FunctionCall("fileWrite", map[string]any{"path": "calc/calc_test.go", "content": specTest}),
+				fake.Text("## Acceptance Criteria\n- adds two numbers -> calc/calc_test.go:TestAdd"),
+			)
+			tt.config.Model = mdl
+			tt.config.WorkspaceDir = t.TempDir()
+			pipeline, err := NewAcceptanceTestPipeline(tt.config)
+			if err != nil {
+				t.Fatalf("NewAcceptanceTestPipeline() error = %v", err)
+			}
+
+			events, state := runAgent(t, pipeline, "Add returns the sum of two integers.")
+
+			// Only the acceptance tests are written, not an implementation
+			var authors []string
+			for _, event := range events {
+				if !slices.Contains(authors, event.Author) {
+					authors = append(authors, event.Author)
+				}
+			}
+			if want := []string{"AcceptanceTestAgent"}; !slices.Equal(authors, want) {
+				t.Errorf("authors = %v, want %v", authors, want)
+			}
+			if got := stateString(t, state, "acceptance_tests"); !strings.Contains(got, "TestAdd") {
+				t.Errorf("state[acceptance_tests] = %q, want the criteria summary", got)
+			}
+			if _, err := os.Stat(filepath.Join(tt.config.WorkspaceDir, "calc", "calc_test.go")); err != nil {
+				t.Errorf("acceptance test not written: %v", err)
+			}
+			instruction := mdl.Requests()[0].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(instruction, tt.wantStyle) {
+				t.Errorf("instruction does not ask for %q: %q", tt.wantStyle, instruction)
+			}
+		})
+	}
+}
+
+func TestNewAcceptanceTestPipeline_UnknownStyle(t *testing.T) {
+	_, err := NewAcceptanceTestPipeline(PipelineConfig{
+		Model:           fake.New("fake-model"),
+		WorkspaceDir:    t.TempDir(),
+		AcceptanceStyle: "cucumber",
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown acceptance test style "cucumber"`) {
+		t.Errorf("NewAcceptanceTestPipeline() error = %v, want unknown acceptance test style", err)
+	}
+}
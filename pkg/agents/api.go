@@ -0,0 +1,81 @@
+package agents
+
+import (
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// apiSpecFile is the workspace path of the OpenAPI specification written by the API design stage
+const apiSpecFile = "openapi.yaml"
+
+// noAPIRequired is the API design stage output for requests that are not web services
+const noAPIRequired = "No API required."
+
+// apiDesignStage describes the API design agent stage
+func apiDesignStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "APIDesignAgent",
+		Description: "Writes an OpenAPI specification for web-service requests.",
+		OutputKey:   "api_spec",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("APIDesignAgent") + apiSpecFile + ` to extend it
+- fileWrite: Save the specification to ` + apiSpecFile + ` in the workspace root
+
+**Process:**
+1. Decide whether the request is a web service; if it is not, reply exactly "` + noAPIRequired + `" and stop without writing files
+2. Derive the resources, operations, and error cases from the design
+3. Write ` + apiSpecFile + ` with fileWrite
+
+**Specification Rules:**
+- openapi: 3.0.3, with info.title, info.version, and a servers entry
+- A unique operationId per operation in lowerCamelCase; the code generates one handler and one client method per operationId
+- Request and response bodies reference named schemas under components/schemas
+- Document every status code returned, using a shared Error schema for 4xx and 5xx responses
+- Mark required properties and give formats (int64, date-time, uuid) where they apply
+
+**Output Format:**
+## Endpoints
+- [METHOD] [path] [operationId] - [purpose]
+
+## Specification
+` + "```yaml" + `
+[the complete ` + apiSpecFile + `]
+` + "```" + `
+
+**REQUIRED: Write the specification now. Do not ask for confirmation.**`,
+	}
+}
+
+// apiSpecInstruction is appended to stages that implement the API contract
+const apiSpecInstruction = `
+
+**API Contract (` + apiSpecFile + `; ignore if "` + noAPIRequired + `"):**
+{api_spec?}
+
+When there is an API contract, it is authoritative: implement one HTTP handler per operationId with the exact paths, methods, status codes, and schemas, generate a typed client with one method per operationId, and do not add undocumented endpoints.`
+
+// withAPISpec gives a stage the OpenAPI contract written by the API design stage
+func withAPISpec(spec stageSpec) stageSpec {
+	spec.Instruction += apiSpecInstruction
+	return spec
+}
+
+// apiCodegenInstruction is appended to the code writer when it generates the API code
+const apiCodegenInstruction = `
+
+**Generated API Code:**
+Do not write the contract types and server interface by hand. Call oapiCodegen with spec "` + apiSpecFile + `", an output such as "internal/api/api.gen.go", and generate ["types", "std-http-server"], then implement the generated ServerInterface. Never edit the generated file; change ` + apiSpecFile + ` and generate it again instead.`
+
+// withAPICodegen gives a stage that implements the API contract the oapiCodegen tool
+func withAPICodegen(spec stageSpec, workspaceDir string) stageSpec {
+	spec.Tools = append(slices.Clip(spec.Tools), tools.NewOapiCodegenToolWithWorkspace(workspaceDir))
+	spec.Instruction += apiCodegenInstruction
+	return spec
+}
@@ -0,0 +1,133 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestPipelineStages_APIDesign(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			config: PipelineConfig{SkipBuild: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "after the design",
+			config: PipelineConfig{SkipBuild: true, APIDesign: true},
+			want:   []string{"DesignAgent", "APIDesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "before the planner",
+			config: PipelineConfig{SkipBuild: true, APIDesign: true, Planner: true},
+			want:   []string{"DesignAgent", "APIDesignAgent", "PlannerAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			var names []string
+			for _, spec := range stages {
+				names = append(names, spec.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("stages = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIDesign_Run(t *testing.T) {
+	spec := "## Endpoints\n- GET /users/{id} getUser - fetch a user\n\n## Specification\n```yaml\nopenapi: 3.0.3\n```"
+	mdl := fake.New("fake-model",
+		fake.Text("design: a user REST service"),
+		fake.FunctionCall("fileWrite", map[string]any{"path": apiSpecFile, "content": "openapi: 3.0.3\n"}),
+		fake.Text(spec),
+		fake.Text("Created pkg/api/handlers.go"),
+		fake.Text("Created pkg/api/handlers_test.go"),
+		fake.Text("No major issues found."),
+	)
+	workspaceDir := t.TempDir()
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: workspaceDir,
+		SkipBuild:    true,
+		SkipTests:    true,
+		APIDesign:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a user REST service")
+
+	if got := stateString(t, state, "api_spec"); got != spec {
+		t.Errorf("state[api_spec] = %q, want %q", got, spec)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspaceDir, apiSpecFile)); err != nil || string(data) != "openapi: 3.0.3\n" {
+		t.Errorf("%s = %q, %v; want the written specification", apiSpecFile, data, err)
+	}
+
+	// The code writer implements the contract
+	writer := mdl.Requests()[3].Config.SystemInstruction.Parts[0].Text
+	for _, want := range []string{"GET /users/{id} getUser", "one HTTP handler per operationId"} {
+		if !strings.Contains(writer, want) {
+			t.Errorf("code writer instruction does not contain %q", want)
+		}
+	}
+}
+
+func TestAPICodegen(t *testing.T) {
+	tests := []struct {
+		name       string
+		apiCodegen bool
+	}{
+		{name: "disabled", apiCodegen: false},
+		{name: "enabled", apiCodegen: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model",
+				fake.Text("design: a user REST service"),
+				fake.Text("## Endpoints\n- GET /users/{id} getUser - fetch a user"),
+				fake.Text("Created internal/api/api.gen.go"),
+				fake.Text("Created internal/api/server_test.go"),
+				fake.Text("No major issues found."),
+			)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				SkipBuild:    true,
+				SkipTests:    true,
+				APIDesign:    true,
+				APICodegen:   tt.apiCodegen,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+			runAgent(t, pipeline, "Build a user REST service")
+
+			writer := mdl.Requests()[2]
+			_, hasTool := writer.Tools["oapiCodegen"]
+			instructed := strings.Contains(writer.Config.SystemInstruction.Parts[0].Text, "implement the generated ServerInterface")
+			if hasTool != tt.apiCodegen || instructed != tt.apiCodegen {
+				t.Errorf("code writer has oapiCodegen = %v and instruction = %v, want %v", hasTool, instructed, tt.apiCodegen)
+			}
+		})
+	}
+}
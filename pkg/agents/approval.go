@@ -0,0 +1,135 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Values stored under the design_approval state key
+const (
+	approvalPending  = "pending"
+	approvalApproved = "approved"
+)
+
+// approvalWords are the first words of a reply that approve a pending design
+var approvalWords = []string{"approve", "approved", "lgtm", "yes", "ok", "okay", "continue", "proceed"}
+
+// approvalRequestMessage is shown to the user when the pipeline pauses for approval
+const approvalRequestMessage = `Design ready for review. The pipeline is paused before any code is written.
+
+Reply "approve" to continue with this design, or reply with feedback to revise it.`
+
+// approvalStage describes the human-in-the-loop design approval stage
+func approvalStage() stageSpec {
+	return stageSpec{
+		Name:        "DesignApprovalAgent",
+		Description: "Pauses the pipeline until a user approves the design.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newApprovalAgent()
+		},
+	}
+}
+
+// withDesignApproval inserts the approval stage after the design stage
+func withDesignApproval(stages []stageSpec) ([]stageSpec, error) {
+	i := slices.IndexFunc(stages, func(spec stageSpec) bool {
+		return spec.Builtin == builtinDesign
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("design approval requires a %s stage", builtinDesign)
+	}
+	stages[i] = withApprovalCheck(stages[i])
+	return slices.Insert(stages, i+1, approvalStage()), nil
+}
+
+// withApprovalCheck makes the design stage reuse the pending design when the user's
+// reply approves it, and record the reply as feedback otherwise
+func withApprovalCheck(design stageSpec) stageSpec {
+	design.BeforeAgentCallbacks = append(design.BeforeAgentCallbacks, func(ctx agent.CallbackContext) (*genai.Content, error) {
+		if readStateString(ctx.ReadonlyState(), "design_approval") != approvalPending {
+			return nil, nil
+		}
+
+		reply := contentText(ctx.UserContent())
+		if isApproval(reply) {
+			slog.Info("Design approved by user, skipping design stage")
+			if err := ctx.State().Set("design_approval", approvalApproved); err != nil {
+				return nil, err
+			}
+			return genai.NewContentFromText("Design approved. Continuing with implementation.", genai.RoleModel), nil
+		}
+
+		slog.Info("Design feedback received, revising design")
+		if err := ctx.State().Set("design_feedback", reply); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	})
+	design.Instruction += `
+
+**Reviewer Feedback on the Previous Design (address all of it if present):**
+{design_feedback?}`
+	return design
+}
+
+// newApprovalAgent creates an agent that pauses the pipeline for approval of a new design.
+// It escalates, which stops the enclosing sequential pipeline, and records a pending
+// approval in state; the design stage resumes from it on the next user message.
+func newApprovalAgent() (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "DesignApprovalAgent",
+		Description: "Pauses the pipeline until a user approves the design.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				if readStateString(ctx.Session().State(), "design_approval") == approvalApproved {
+					// Consume the approval so the next request is reviewed again
+					event.Actions.StateDelta["design_approval"] = ""
+					event.Actions.StateDelta["design_feedback"] = ""
+					yield(event, nil)
+					return
+				}
+
+				slog.Info("Pausing pipeline for design approval")
+				event.LLMResponse.Content = genai.NewContentFromText(approvalRequestMessage, genai.RoleModel)
+				event.Actions.StateDelta["design_approval"] = approvalPending
+				event.Actions.Escalate = true
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// isApproval reports whether reply approves the pending design
+func isApproval(reply string) bool {
+	fields := strings.Fields(strings.ToLower(reply))
+	if len(fields) == 0 {
+		return false
+	}
+	first := strings.Trim(fields[0], ".,!:;\"'")
+	for _, word := range approvalWords {
+		if first == word {
+			return true
+		}
+	}
+	return false
+}
+
+// contentText concatenates the text parts of content
+func contentText(content *genai.Content) string {
+	if content == nil {
+		return ""
+	}
+	var b strings.Builder
+	for _, part := range content.Parts {
+		b.WriteString(part.Text)
+	}
+	return b.String()
+}
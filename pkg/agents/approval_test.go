@@ -0,0 +1,120 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestIsApproval(t *testing.T) {
+	tests := []struct {
+		reply string
+		want  bool
+	}{
+		{reply: "approve", want: true},
+		{reply: "Approved!", want: true},
+		{reply: "LGTM, ship it", want: true},
+		{reply: "yes", want: true},
+		{reply: "", want: false},
+		{reply: "Please add a Sub function", want: false},
+		{reply: "not approved", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.reply, func(t *testing.T) {
+			if got := isApproval(tt.reply); got != tt.want {
+				t.Errorf("isApproval(%q) = %v, want %v", tt.reply, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDesignApproval_Run(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.Text("design v1"),
+		fake.Text("design v2 with Sub"),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:                 mdl,
+		WorkspaceDir:          t.TempDir(),
+		SkipBuild:             true,
+		SkipTests:             true,
+		RequireDesignApproval: true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	ctx := context.Background()
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{AppName: "test-app", Agent: pipeline, SessionService: sessionService})
+	if err != nil {
+		t.Fatalf("runner.New() error = %v", err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "test-app", UserID: "test-user"})
+	if err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+
+	// send runs one user turn and returns the event authors and the resulting state
+	send := func(text string) ([]string, session.State) {
+		t.Helper()
+		var authors []string
+		msg := genai.NewContentFromText(text, genai.RoleUser)
+		for event, err := range r.Run(ctx, "test-user", created.Session.ID(), msg, agent.RunConfig{}) {
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+			authors = append(authors, event.Author)
+		}
+		got, err := sessionService.Get(ctx, &session.GetRequest{AppName: "test-app", UserID: "test-user", SessionID: created.Session.ID()})
+		if err != nil {
+			t.Fatalf("session Get() error = %v", err)
+		}
+		return authors, got.Session.State()
+	}
+
+	authors, state := send("Build a calculator package")
+	if want := "DesignAgent,DesignApprovalAgent"; strings.Join(authors, ",") != want {
+		t.Fatalf("first turn authors = %v, want %s", authors, want)
+	}
+	if got := stateString(t, state, "design_approval"); got != approvalPending {
+		t.Errorf("design_approval = %q, want %q", got, approvalPending)
+	}
+
+	authors, state = send("Please add a Sub function")
+	if want := "DesignAgent,DesignAgent,DesignApprovalAgent"; strings.Join(authors, ",") != want {
+		t.Fatalf("feedback turn authors = %v, want %s", authors, want)
+	}
+	if got := stateString(t, state, "design"); got != "design v2 with Sub" {
+		t.Errorf("design = %q, want the revised design", got)
+	}
+	if got := mdl.Requests()[1].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "Please add a Sub function") {
+		t.Errorf("revised design instruction does not contain the feedback: %q", got)
+	}
+
+	authors, state = send("approve")
+	want := "DesignAgent,DesignApprovalAgent,CodeWriterAgent,TDDExpertAgent,CodeReviewerAgent"
+	if strings.Join(authors, ",") != want {
+		t.Fatalf("approval turn authors = %v, want %s", authors, want)
+	}
+	if got := stateString(t, state, "design"); got != "design v2 with Sub" {
+		t.Errorf("design = %q, want the approved design to be kept", got)
+	}
+	if got := stateString(t, state, "design_approval"); got != "" {
+		t.Errorf("design_approval = %q, want it consumed", got)
+	}
+	if got := mdl.Calls(); got != 5 {
+		t.Errorf("model calls = %d, want 5", got)
+	}
+}
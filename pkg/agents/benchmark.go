@@ -0,0 +1,22 @@
+package agents
+
+import (
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// benchmarkStage describes the benchmark agent stage
+func benchmarkStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "BenchmarkAgent",
+		Description: "Writes Go benchmarks for the performance-sensitive functions of the design.",
+		OutputKey:   "benchmark_code",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("BenchmarkAgent"),
+	}
+}
@@ -0,0 +1,89 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestPipelineStages_Benchmarks(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			config: PipelineConfig{SkipBuild: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "after the tests",
+			config: PipelineConfig{SkipBuild: true, Benchmarks: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "BenchmarkAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "between documentation and security review",
+			config: PipelineConfig{SkipBuild: true, Benchmarks: true, Documentation: true, SecurityReview: true},
+			want: []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "DocumentationAgent",
+				"BenchmarkAgent", "SecurityReviewAgent", "CodeReviewerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			var names []string
+			for _, spec := range stages {
+				names = append(names, spec.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("stages = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestBenchmark_Run(t *testing.T) {
+	summary := "## Benchmarks\n- pkg/calc/calc_bench_test.go BenchmarkAdd: small and large inputs"
+	mdl := fake.New("fake-model",
+		fake.Text("design: Add is on the hot path"),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text(summary),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		Benchmarks:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a calculator package")
+
+	if got := stateString(t, state, "benchmark_code"); got != summary {
+		t.Errorf("state[benchmark_code] = %q, want %q", got, summary)
+	}
+
+	request := mdl.Requests()[3]
+	if got := len(request.Tools); got != 2 {
+		t.Errorf("benchmark stage has %d tools, want 2", got)
+	}
+	instruction := request.Config.SystemInstruction.Parts[0].Text
+	for _, want := range []string{"Add is on the hot path", "b.ReportAllocs()"} {
+		if !strings.Contains(instruction, want) {
+			t.Errorf("benchmark instruction does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Condition evaluates the session state of a run to choose a branch
+type Condition func(state session.State) bool
+
+// ReviewApproved returns a condition that holds when the review stored under outputKey
+// reports no critical issues
+func ReviewApproved(outputKey string) Condition {
+	return func(state session.State) bool {
+		return reviewApproved(readStateString(state, outputKey))
+	}
+}
+
+// BranchConfig holds configuration for creating a branch agent
+type BranchConfig struct {
+	// Name is the name of the branch agent
+	Name string
+	// Description is the description of the branch agent
+	Description string
+	// Condition chooses the branch from the session state
+	Condition Condition
+	// Then are the agents run in order when the condition holds; empty completes the branch
+	Then []agent.Agent
+	// Else are the agents run in order when the condition does not hold; empty completes the branch
+	Else []agent.Agent
+}
+
+// NewBranchAgent creates an agent that evaluates its condition on the session state when
+// it runs and then runs either the Then or the Else agents, so the control flow of a
+// pipeline can react to the results of earlier stages. Like sequentialagent, an
+// escalation ends the branch and is passed on to the enclosing pipeline.
+func NewBranchAgent(config BranchConfig) (agent.Agent, error) {
+	if config.Name == "" {
+		return nil, fmt.Errorf("branch name cannot be empty")
+	}
+	if config.Condition == nil {
+		return nil, fmt.Errorf("branch %s has no condition", config.Name)
+	}
+	subAgents := make([]agent.Agent, 0, len(config.Then)+len(config.Else))
+	for _, ag := range append(append([]agent.Agent{}, config.Then...), config.Else...) {
+		if ag == nil {
+			return nil, fmt.Errorf("branch %s has a nil agent", config.Name)
+		}
+		subAgents = append(subAgents, ag)
+	}
+
+	return agent.New(agent.Config{
+		Name:        config.Name,
+		Description: config.Description,
+		SubAgents:   subAgents,
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				taken, branch := "then", config.Then
+				if !config.Condition(ctx.Session().State()) {
+					taken, branch = "else", config.Else
+				}
+				slog.Info("Branch condition evaluated", "branch", config.Name, "taken", taken, "agents", len(branch))
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(branchSummary(taken, branch), genai.RoleModel)
+				if !yield(event, nil) {
+					return
+				}
+
+				for _, ag := range branch {
+					escalated := false
+					for event, err := range ag.Run(ctx) {
+						if event != nil && event.Actions.Escalate {
+							escalated = true
+						}
+						if !yield(event, err) || err != nil {
+							return
+						}
+					}
+					if escalated {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// branchSummary describes the branch taken and the agents it runs
+func branchSummary(taken string, branch []agent.Agent) string {
+	if len(branch) == 0 {
+		return fmt.Sprintf("Condition chose the %s branch; nothing to run.", taken)
+	}
+	names := make([]string, 0, len(branch))
+	for _, ag := range branch {
+		names = append(names, ag.Name())
+	}
+	return fmt.Sprintf("Condition chose the %s branch; running %s.", taken, strings.Join(names, ", "))
+}
+
+// newReviewBranch creates the branch that completes the run when the review stored
+// under review_comments approves the code, and runs the fixer and the checks of its
+// changes otherwise
+func newReviewBranch(config PipelineConfig, fixer stageSpec) (agent.Agent, error) {
+	fixStages, err := newFixStages(config, fixer)
+	if err != nil {
+		return nil, err
+	}
+	return NewBranchAgent(BranchConfig{
+		Name:        "ReviewBranchAgent",
+		Description: "Runs the fixer when the review reports critical issues.",
+		Condition:   ReviewApproved("review_comments"),
+		Else:        fixStages,
+	})
+}
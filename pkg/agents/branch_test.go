@@ -0,0 +1,174 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+)
+
+func TestBranchAgent_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		review      string
+		otherwise   bool
+		wantAuthors []string
+	}{
+		{
+			name:        "condition holds",
+			review:      "No critical issues found.",
+			wantAuthors: []string{"review", "Branch", "ShipAgent"},
+		},
+		{
+			name:        "condition does not hold",
+			review:      "## Critical Issues\n- [calc.go:Div] division by zero",
+			wantAuthors: []string{"review", "Branch", "FixAgent", "RecheckAgent"},
+		},
+		{
+			name:        "empty branch completes",
+			review:      "No critical issues found.",
+			otherwise:   true,
+			wantAuthors: []string{"review", "Branch"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := BranchConfig{
+				Name:      "Branch",
+				Condition: ReviewApproved("review"),
+				Then:      []agent.Agent{newNoteAgent(t, "ShipAgent", "shipped")},
+				Else:      []agent.Agent{newNoteAgent(t, "FixAgent", "fixed"), newNoteAgent(t, "RecheckAgent", "rechecked")},
+			}
+			if tt.otherwise {
+				config.Then = nil
+			}
+			branch, err := NewBranchAgent(config)
+			if err != nil {
+				t.Fatalf("NewBranchAgent() error = %v", err)
+			}
+			pipeline, err := sequentialagent.New(sequentialagent.Config{AgentConfig: agent.Config{
+				Name:      "Pipeline",
+				SubAgents: []agent.Agent{newNoteAgent(t, "review", tt.review), branch},
+			}})
+			if err != nil {
+				t.Fatalf("sequentialagent.New() error = %v", err)
+			}
+
+			events, _ := runAgent(t, pipeline, "Ship it")
+
+			var authors []string
+			for _, event := range events {
+				authors = append(authors, event.Author)
+			}
+			if !slices.Equal(authors, tt.wantAuthors) {
+				t.Errorf("authors = %v, want %v", authors, tt.wantAuthors)
+			}
+		})
+	}
+}
+
+func TestNewBranchAgent_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  BranchConfig
+		wantErr string
+	}{
+		{name: "no name", config: BranchConfig{Condition: ReviewApproved("review")}, wantErr: "branch name cannot be empty"},
+		{name: "no condition", config: BranchConfig{Name: "Branch"}, wantErr: "branch Branch has no condition"},
+		{name: "nil agent", config: BranchConfig{Name: "Branch", Condition: ReviewApproved("review"), Else: []agent.Agent{nil}}, wantErr: "branch Branch has a nil agent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewBranchAgent(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewBranchAgent() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFixOnReview_Run(t *testing.T) {
+	critical := "## Critical Issues (Must Fix)\n- [calc.go:Div] division by zero is not handled"
+
+	tests := []struct {
+		name        string
+		review      string
+		turns       []fake.Turn
+		wantAuthors []string
+	}{
+		{
+			name:        "approved review completes",
+			review:      "No critical issues found.",
+			wantAuthors: []string{"DesignAgent", "CodeWriterAgent", "CodeReviewerAgent", "ReviewBranchAgent"},
+		},
+		{
+			name:        "critical issues run the fixer once",
+			review:      critical,
+			turns:       []fake.Turn{fake.Text("## Fixes Applied\n- [calc.go:Div] return an error on zero")},
+			wantAuthors: []string{"DesignAgent", "CodeWriterAgent", "CodeReviewerAgent", "ReviewBranchAgent", "FixerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			turns := append([]fake.Turn{fake.Text("design"), fake.Text("Created pkg/calc/calc.go"), fake.Text(tt.review)}, tt.turns...)
+			mdl := fake.New("fake-model", turns...)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				FixOnReview:  true,
+				WorkspaceDir: t.TempDir(),
+				SkipBuild:    true,
+				Stages:       []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}, {Builtin: builtinCodeReviewer}},
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			events, _ := runAgent(t, pipeline, "Build a calculator package")
+
+			var authors []string
+			for _, event := range events {
+				authors = append(authors, event.Author)
+			}
+			if !slices.Equal(authors, tt.wantAuthors) {
+				t.Errorf("authors = %v, want %v", authors, tt.wantAuthors)
+			}
+			if got := mdl.Calls(); got != len(turns) {
+				t.Errorf("model calls = %d, want %d", got, len(turns))
+			}
+		})
+	}
+}
+
+func TestFixOnReview_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PipelineConfig
+		wantErr string
+	}{
+		{
+			name:    "with loop pipeline",
+			config:  PipelineConfig{FixOnReview: true, LoopPipeline: true},
+			wantErr: "loop_pipeline and fix_on_review cannot both be set",
+		},
+		{
+			name:    "without a reviewer",
+			config:  PipelineConfig{FixOnReview: true, Stages: []StageConfig{{Builtin: builtinDesign}}},
+			wantErr: "fix on review requires a code_reviewer stage",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Model = fake.New("fake-model")
+			tt.config.WorkspaceDir = t.TempDir()
+			if _, err := NewCodePipelineAgent(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewCodePipelineAgent() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
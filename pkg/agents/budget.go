@@ -0,0 +1,190 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// budgetExceeded is stored under the budget_status state key once a run exhausts its budget
+const budgetExceeded = "exceeded"
+
+// ModelPrice is the price of a model in US dollars per million tokens
+type ModelPrice struct {
+	// InputPerMillion is the price of a million prompt tokens
+	InputPerMillion float64 `yaml:"input_per_million"`
+	// OutputPerMillion is the price of a million completion tokens
+	OutputPerMillion float64 `yaml:"output_per_million"`
+}
+
+// budgetUsage is the token usage and cost of a run so far
+type budgetUsage struct {
+	tokens int
+	cost   float64
+}
+
+// String formats the usage for logs and messages
+func (u budgetUsage) String() string {
+	return fmt.Sprintf("%d tokens, $%.4f", u.tokens, u.cost)
+}
+
+// runBudget tracks the token usage of each pipeline run against the configured limits
+type runBudget struct {
+	maxTokens int
+	maxCost   float64
+	// model is the name of the pipeline model, used by stages without a model of their own
+	model string
+	// prices are the prices of the models the stages use, keyed by model name
+	prices map[string]ModelPrice
+
+	mu sync.Mutex
+	// usage is the usage of the run in each session. Runs are tracked by session because
+	// llmagent and parallelagent give their sub-runs invocation IDs of their own.
+	usage map[string]*budgetUsage
+}
+
+// newRunBudget returns the budget configured by config, or nil if it sets no limits
+func newRunBudget(config PipelineConfig) (*runBudget, error) {
+	if config.MaxTotalTokens <= 0 && config.MaxCost <= 0 {
+		return nil, nil
+	}
+	b := &runBudget{
+		maxTokens: config.MaxTotalTokens,
+		maxCost:   config.MaxCost,
+		model:     config.Model.Name(),
+		prices:    make(map[string]ModelPrice),
+		usage:     make(map[string]*budgetUsage),
+	}
+	if config.MaxCost > 0 {
+		for _, llm := range append([]model.LLM{config.Model}, routedModels(config)...) {
+			price, ok := config.Prices[llm.Name()]
+			if !ok {
+				return nil, fmt.Errorf("max cost requires a price for model %q", llm.Name())
+			}
+			b.prices[llm.Name()] = price
+		}
+	}
+	return b, nil
+}
+
+// add records the token usage of a response of the named model in the session's run and
+// returns its total usage
+func (b *runBudget) add(sessionID, modelName string, usage *genai.GenerateContentResponseUsageMetadata) budgetUsage {
+	prompt, completion := int(usage.PromptTokenCount), int(usage.CandidatesTokenCount)
+	tokens := int(usage.TotalTokenCount)
+	if tokens == 0 {
+		tokens = prompt + completion
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u, ok := b.usage[sessionID]
+	if !ok {
+		u = &budgetUsage{}
+		b.usage[sessionID] = u
+	}
+	u.tokens += tokens
+	price := b.prices[modelName]
+	u.cost += (float64(prompt)*price.InputPerMillion + float64(completion)*price.OutputPerMillion) / 1e6
+	return *u
+}
+
+// exceeded reports whether the session's run has exhausted the budget, and its usage
+func (b *runBudget) exceeded(sessionID string) (budgetUsage, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	u, ok := b.usage[sessionID]
+	if !ok {
+		return budgetUsage{}, false
+	}
+	return *u, (b.maxTokens > 0 && u.tokens >= b.maxTokens) || (b.maxCost > 0 && u.cost >= b.maxCost)
+}
+
+// release forgets the usage of the session's run
+func (b *runBudget) release(sessionID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.usage, sessionID)
+}
+
+// markExceeded records the exhausted budget in state
+func (b *runBudget) markExceeded(ctx agent.CallbackContext, usage budgetUsage) error {
+	if readStateString(ctx.ReadonlyState(), "budget_status") == budgetExceeded {
+		return nil
+	}
+	slog.Warn("Run budget exceeded, skipping remaining model calls",
+		"usage", usage.String(),
+		"max_tokens", b.maxTokens,
+		"max_cost", b.maxCost)
+	if err := ctx.State().Set("budget_status", budgetExceeded); err != nil {
+		return err
+	}
+	return ctx.State().Set("budget_usage", usage.String())
+}
+
+// withBudget makes an LLM stage count its token usage against b, and skip its work
+// once the budget is exhausted: the stage is skipped if it has not started, and its
+// next model call is replaced by a budget-exceeded response if it has
+func withBudget(spec stageSpec, b *runBudget) stageSpec {
+	if spec.Custom != nil {
+		return spec
+	}
+	modelName := b.model
+	if spec.Model != nil {
+		modelName = spec.Model.Name()
+	}
+	spec.BeforeAgentCallbacks = append(spec.BeforeAgentCallbacks, func(ctx agent.CallbackContext) (*genai.Content, error) {
+		usage, exceeded := b.exceeded(ctx.SessionID())
+		if !exceeded {
+			return nil, nil
+		}
+		if err := b.markExceeded(ctx, usage); err != nil {
+			return nil, err
+		}
+		return genai.NewContentFromText(fmt.Sprintf("Skipped: the run budget is exhausted (%s).", usage), genai.RoleModel), nil
+	})
+	spec.BeforeModelCallbacks = append(spec.BeforeModelCallbacks, func(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+		usage, exceeded := b.exceeded(ctx.SessionID())
+		if !exceeded {
+			return nil, nil
+		}
+		if err := b.markExceeded(ctx, usage); err != nil {
+			return nil, err
+		}
+		return &model.LLMResponse{
+			Content: genai.NewContentFromText(fmt.Sprintf("Stopped: the run budget is exhausted (%s).", usage), genai.RoleModel),
+		}, nil
+	})
+	spec.AfterModelCallbacks = append(spec.AfterModelCallbacks, func(ctx agent.CallbackContext, resp *model.LLMResponse, err error) (*model.LLMResponse, error) {
+		if err == nil && resp != nil && resp.UsageMetadata != nil && !resp.Partial {
+			b.add(ctx.SessionID(), modelName, resp.UsageMetadata)
+		}
+		return nil, nil
+	})
+	return spec
+}
+
+// resetBudget returns a callback for the root pipeline that starts each run with an
+// unused budget
+func resetBudget(b *runBudget) agent.BeforeAgentCallback {
+	return func(ctx agent.CallbackContext) (*genai.Content, error) {
+		b.release(ctx.SessionID())
+		return nil, nil
+	}
+}
+
+// releaseBudget returns a callback for the root pipeline that forgets the usage of the
+// finished run
+func releaseBudget(b *runBudget) agent.AfterAgentCallback {
+	return func(ctx agent.CallbackContext) (*genai.Content, error) {
+		if usage, _ := b.exceeded(ctx.SessionID()); usage.tokens > 0 {
+			slog.Info("Run budget usage", "usage", usage.String())
+		}
+		b.release(ctx.SessionID())
+		return nil, nil
+	}
+}
@@ -0,0 +1,133 @@
+package agents
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/genai"
+)
+
+// usageTurn returns a text turn reporting the given token usage
+func usageTurn(text string, prompt, completion int32) fake.Turn {
+	turn := fake.Text(text)
+	turn.Usage = &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     prompt,
+		CandidatesTokenCount: completion,
+		TotalTokenCount:      prompt + completion,
+	}
+	return turn
+}
+
+func TestRunBudget(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       PipelineConfig
+		usage        [][2]int32
+		wantTokens   int
+		wantCost     float64
+		wantExceeded bool
+		wantErr      bool
+	}{
+		{
+			name:   "under the token limit",
+			config: PipelineConfig{MaxTotalTokens: 1000},
+			usage:  [][2]int32{{300, 100}, {400, 100}},
+			// 900 tokens
+			wantTokens: 900,
+		},
+		{
+			name:         "token limit reached",
+			config:       PipelineConfig{MaxTotalTokens: 1000},
+			usage:        [][2]int32{{600, 100}, {250, 50}},
+			wantTokens:   1000,
+			wantExceeded: true,
+		},
+		{
+			name: "cost limit reached",
+			config: PipelineConfig{MaxCost: 0.01, Prices: map[string]ModelPrice{
+				"fake-model": {InputPerMillion: 3, OutputPerMillion: 15},
+			}},
+			usage:        [][2]int32{{2000, 300}, {1000, 200}},
+			wantTokens:   3500,
+			wantCost:     0.0165,
+			wantExceeded: true,
+		},
+		{
+			name:    "cost limit without a price",
+			config:  PipelineConfig{MaxCost: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Model = fake.New("fake-model")
+			b, err := newRunBudget(tt.config)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newRunBudget() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			for _, u := range tt.usage {
+				b.add("session-1", "fake-model", &genai.GenerateContentResponseUsageMetadata{
+					PromptTokenCount:     u[0],
+					CandidatesTokenCount: u[1],
+				})
+			}
+
+			usage, exceeded := b.exceeded("session-1")
+			if usage.tokens != tt.wantTokens || math.Abs(usage.cost-tt.wantCost) > 1e-9 || exceeded != tt.wantExceeded {
+				t.Errorf("exceeded() = %v, %v; want %d tokens, $%v, %v", usage, exceeded, tt.wantTokens, tt.wantCost, tt.wantExceeded)
+			}
+			if _, exceeded := b.exceeded("session-2"); exceeded {
+				t.Error("another session shares the usage of session-1")
+			}
+			b.release("session-1")
+			if usage, _ := b.exceeded("session-1"); usage.tokens != 0 {
+				t.Errorf("usage after release = %v, want none", usage)
+			}
+		})
+	}
+}
+
+func TestBudget_StopsRunGracefully(t *testing.T) {
+	mdl := fake.New("fake-model",
+		usageTurn("design", 800, 300),
+		usageTurn("Created pkg/calc/calc.go", 900, 200),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:          mdl,
+		WorkspaceDir:   t.TempDir(),
+		SkipBuild:      true,
+		SkipTests:      true,
+		MaxTotalTokens: 1000,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+	if got := mdl.Calls(); got != 1 {
+		t.Errorf("model calls = %d, want 1", got)
+	}
+
+	var skipped []string
+	for _, event := range events {
+		if strings.Contains(contentText(event.Content), "run budget is exhausted (1100 tokens") {
+			skipped = append(skipped, event.Author)
+		}
+	}
+	if want := "CodeWriterAgent,TDDExpertAgent,CodeReviewerAgent"; strings.Join(skipped, ",") != want {
+		t.Errorf("skipped stages = %v, want %s", skipped, want)
+	}
+
+	if got := stateString(t, state, "budget_status"); got != budgetExceeded {
+		t.Errorf("state[budget_status] = %q, want %q", got, budgetExceeded)
+	}
+	if got := stateString(t, state, "design"); got != "design" {
+		t.Errorf("state[design] = %q, want the partial output kept", got)
+	}
+}
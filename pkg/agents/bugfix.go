@@ -0,0 +1,220 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// NewBugFixPipeline creates a pipeline that fixes the bug described in the request in the
+// existing code of the workspace. It reproduces the bug with a failing test, locates the
+// root cause, and patches the code until the tests pass, failing the run if they still
+// fail after MaxTestIterations patches. Without a failing test after MaxTestIterations
+// attempts, it locates and patches the bug from the report alone. It uses the model,
+// workspace, test, and stage retry and timeout settings of config; stage customizations
+// do not apply.
+func NewBugFixPipeline(config PipelineConfig) (agent.Agent, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+	if config.Name == "" {
+		config.Name = "BugFixPipeline"
+	}
+	if config.Description == "" {
+		config.Description = "Reproduces, locates, patches, and verifies a bug in existing code."
+	}
+	if config.WorkspaceDir == "" {
+		config.WorkspaceDir = tools.DefaultWorkspaceDir
+	}
+	if err := applySandbox(config); err != nil {
+		return nil, err
+	}
+	maxIterations := config.MaxTestIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxTestIterations
+	}
+
+	slog.Info("Creating bug fix pipeline",
+		"name", config.Name,
+		"model", config.Model.Name(),
+		"workspace", config.WorkspaceDir)
+
+	reproduce, locate, patch := reproduceBugStage(config.WorkspaceDir), locateBugStage(config.WorkspaceDir), patchStage(config.WorkspaceDir)
+	for _, spec := range []*stageSpec{&reproduce, &locate, &patch} {
+		instruction, err := stagePrompt(config, spec.Name)
+		if err != nil {
+			return nil, err
+		}
+		spec.Instruction = instruction
+	}
+	if !detectToolSupport(config.Model) {
+		slog.Warn("Model does not support tool calling; stages will return file contents inline",
+			"model", config.Model.Name())
+		reproduce, locate, patch = withoutTools(reproduce), withoutTools(locate), withoutTools(patch)
+	} else {
+		reproduce, patch = withSyntaxCheck(reproduce), withSyntaxCheck(patch)
+	}
+
+	reproduceAgent, err := newLLMStage(config, reproduce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", reproduce.Name, err)
+	}
+	check, err := newReproductionCheckAgent(config.WorkspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reproduction check: %w", err)
+	}
+	reproduceLoop, err := newBoundedLoopAgent(agent.Config{
+		Name:        "ReproduceLoopAgent",
+		Description: "Writes a test until it fails because of the bug.",
+		SubAgents:   []agent.Agent{reproduceAgent, check},
+	}, maxIterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create reproduce loop: %w", err)
+	}
+
+	locateAgent, err := newLLMStage(config, locate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", locate.Name, err)
+	}
+
+	patchAgent, err := newLLMStage(config, patch)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", patch.Name, err)
+	}
+	runner, err := newTestRunnerAgent(config.WorkspaceDir, config.MinCoverage, maxIterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create test runner: %w", err)
+	}
+	patchLoop, err := newBoundedLoopAgent(agent.Config{
+		Name:        "PatchLoopAgent",
+		Description: "Patches the code until the tests pass.",
+		SubAgents:   []agent.Agent{patchAgent, runner},
+	}, maxIterations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create patch loop: %w", err)
+	}
+
+	verify, err := newFixVerificationAgent()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create fix verification: %w", err)
+	}
+
+	return sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{
+			Name:        config.Name,
+			Description: config.Description,
+			SubAgents:   []agent.Agent{reproduceLoop, locateAgent, patchLoop, verify},
+		},
+	})
+}
+
+// reproduceBugStage describes the stage that writes a test reproducing the bug
+func reproduceBugStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "ReproduceBugAgent",
+		Description: "Writes a test that fails because of the reported bug.",
+		OutputKey:   "bug_reproduction",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("ReproduceBugAgent"),
+	}
+}
+
+// locateBugStage describes the stage that finds the root cause of the reproduced bug
+func locateBugStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "LocateBugAgent",
+		Description: "Finds the root cause of the reproduced bug.",
+		OutputKey:   "bug_location",
+		Tools:       []tool.Tool{tools.NewFileReadToolWithWorkspace(workspaceDir)},
+		Instruction: prompts.Default("LocateBugAgent"),
+	}
+}
+
+// patchStage describes the stage that fixes the located bug
+func patchStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "PatchAgent",
+		Description: "Fixes the root cause of the bug.",
+		OutputKey:   "bug_fix",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("PatchAgent"),
+	}
+}
+
+// newReproductionCheckAgent creates an agent that runs the workspace tests after the
+// reproduction test is written and escalates once a test fails, or when the tests cannot
+// be run. The result is stored under the bug_reproduced and reproduction_output state keys.
+func newReproductionCheckAgent(workspaceDir string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "ReproductionCheckAgent",
+		Description: "Checks that the reproduction test fails.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				result := runTests(ctx, workspaceDir, 0)
+				reproduced := result.Status == testStatusFailed &&
+					!strings.Contains(result.Summary, "[build failed]") &&
+					!strings.Contains(result.Summary, "[setup failed]") &&
+					!strings.Contains(result.Summary, "no packages to test")
+
+				summary := result.Summary
+				switch {
+				case reproduced:
+					slog.Info("Bug reproduced by a failing test")
+				case result.Status == testStatusSkipped:
+					slog.Warn("Could not check the reproduction, tests were skipped")
+				case result.Status == testStatusFailed:
+					summary = "The reproduction test does not compile or run; fix it so it fails only because of the bug.\n\n" + summary
+				default:
+					summary = "All tests pass, so the bug is not reproduced. Write a test that fails while the bug is present."
+				}
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(summary, genai.RoleModel)
+				event.Actions.StateDelta["bug_reproduced"] = reproduced
+				event.Actions.StateDelta["reproduction_output"] = summary
+				event.Actions.Escalate = reproduced || result.Status == testStatusSkipped
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// newFixVerificationAgent creates an agent that reports an error unless the last test
+// run after the patch passed or could not be run
+func newFixVerificationAgent() (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "FixVerificationAgent",
+		Description: "Fails the run when the tests still fail after the fix.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				state := ctx.Session().State()
+				status := readStateString(state, "test_status")
+				event := session.NewEvent(ctx.InvocationID())
+				if status == testStatusPassed || status == testStatusSkipped {
+					event.LLMResponse.Content = genai.NewContentFromText("Bug fix verified: "+readStateString(state, "test_output"), genai.RoleModel)
+					yield(event, nil)
+					return
+				}
+
+				slog.Error("Bug fix not verified", "test_status", status)
+				event.LLMResponse.Content = genai.NewContentFromText("Bug fix not verified: the tests still fail.", genai.RoleModel)
+				yield(event, fmt.Errorf("bug fix not verified: test status is %q after the patch", status))
+			}
+		},
+	})
+}
@@ -0,0 +1,110 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+// buggyWorkspace is a small module whose Add subtracts
+var buggyWorkspace = map[string]string{
+	"go.mod":           "module example.com/calc\n\ngo 1.21\n",
+	"pkg/calc/calc.go": "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a - b }\n",
+}
+
+func TestBugFixPipeline_Run(t *testing.T) {
+	const (
+		reproTest = "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif got := Add(2, 2); got != 4 {\n\t\tt.Fatalf(\"Add(2, 2) = %d, want 4\", got)\n\t}\n}\n"
+		fixed     = "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a + b }\n"
+		stillBad  = "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a * b - b }\n"
+	)
+	writeTest := fake.FunctionCall("fileWrite", map[string]any{"path": "pkg/calc/calc_test.go", "content": reproTest})
+
+	tests := []struct {
+		name        string
+		turns       []fake.Turn
+		errContains string
+	}{
+		{
+			name: "reproduces, locates, and fixes the bug",
+			turns: []fake.Turn{
+				writeTest,
+				fake.Text("pkg/calc/calc_test.go:TestAdd fails with Add(2, 2) = 0"),
+				fake.Text("pkg/calc/calc.go:Add subtracts instead of adding"),
+				fake.FunctionCall("fileWrite", map[string]any{"path": "pkg/calc/calc.go", "content": fixed}),
+				fake.Text("pkg/calc/calc.go:Add now adds"),
+			},
+		},
+		{
+			name: "patch that does not fix the bug",
+			turns: []fake.Turn{
+				writeTest,
+				fake.Text("pkg/calc/calc_test.go:TestAdd fails"),
+				fake.Text("pkg/calc/calc.go:Add subtracts"),
+				fake.FunctionCall("fileWrite", map[string]any{"path": "pkg/calc/calc.go", "content": stillBad}),
+				fake.Text("pkg/calc/calc.go:Add changed"),
+			},
+			errContains: `bug fix not verified: test status is "failed"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, buggyWorkspace)
+			mdl := fake.New("fake-model", tt.turns...)
+
+			pipeline, err := NewBugFixPipeline(PipelineConfig{
+				Model:             mdl,
+				WorkspaceDir:      workspaceDir,
+				MaxTestIterations: 1,
+			})
+			if err != nil {
+				t.Fatalf("NewBugFixPipeline() error = %v", err)
+			}
+
+			events, runErr := runAgentCollectingError(t, pipeline, "Add(2, 2) returns 0 instead of 4")
+			if tt.errContains != "" {
+				if runErr == nil || !strings.Contains(runErr.Error(), tt.errContains) {
+					t.Fatalf("Run() error = %v, want it to contain %q", runErr, tt.errContains)
+				}
+			} else if runErr != nil {
+				t.Fatalf("Run() error = %v", runErr)
+			}
+
+			var authors []string
+			for _, event := range events {
+				if !slices.Contains(authors, event.Author) {
+					authors = append(authors, event.Author)
+				}
+			}
+			want := []string{"ReproduceBugAgent", "ReproductionCheckAgent", "LocateBugAgent", "PatchAgent", "TestRunnerAgent", "FixVerificationAgent"}
+			if tt.errContains != "" {
+				// The failed verification is reported as the run error
+				want = want[:len(want)-1]
+			}
+			if !slices.Equal(authors, want) {
+				t.Errorf("authors = %v, want %v", authors, want)
+			}
+
+			// The later stages see the failing reproduction and the located root cause
+			requests := mdl.Requests()
+			locate := requests[2].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(locate, "Add(2, 2) = 0, want 4") {
+				t.Errorf("locate instruction does not contain the failing test output: %q", locate)
+			}
+			patch := requests[3].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(patch, "Add subtracts") {
+				t.Errorf("patch instruction does not contain the root cause: %q", patch)
+			}
+		})
+	}
+}
+
+func TestNewBugFixPipeline_NilModel(t *testing.T) {
+	if _, err := NewBugFixPipeline(PipelineConfig{WorkspaceDir: t.TempDir()}); err == nil {
+		t.Error("NewBugFixPipeline() error = nil, want an error")
+	}
+}
@@ -0,0 +1,108 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Build result values stored under the build_status state key
+const (
+	buildStatusPassed  = "passed"
+	buildStatusFailed  = "failed"
+	buildStatusSkipped = "skipped"
+)
+
+// defaultWorkspaceModule is the module path used when the generated code has no go.mod
+const defaultWorkspaceModule = tools.DefaultModulePath
+
+// buildStage describes the build verification stage
+func buildStage() stageSpec {
+	return stageSpec{
+		Name:        "BuildAgent",
+		Description: "Compiles the generated code and records compiler errors.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newBuildAgent("BuildAgent", config.WorkspaceDir)
+		},
+	}
+}
+
+// newBuildAgent creates an agent named name that runs `go build ./...` in the workspace
+// and stores the result under the build_status and build_output state keys
+func newBuildAgent(name, workspaceDir string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        name,
+		Description: "Compiles the generated code and records compiler errors.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				status, output := runBuild(ctx, workspaceDir)
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(output, genai.RoleModel)
+				event.Actions.StateDelta["build_status"] = status
+				event.Actions.StateDelta["build_output"] = output
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// runBuild compiles the workspace and returns the build status and a summary for the next stages
+func runBuild(ctx context.Context, workspaceDir string) (string, string) {
+	if err := ensureGoModule(ctx, workspaceDir); err != nil {
+		slog.Warn("Skipping build, workspace module could not be initialized", "error", err)
+		return buildStatusSkipped, fmt.Sprintf("Build skipped: %v", err)
+	}
+
+	result, err := tools.RunCommand(ctx, workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"build", "./..."},
+	})
+	if err != nil {
+		slog.Warn("Skipping build, go build could not be run", "error", err)
+		return buildStatusSkipped, fmt.Sprintf("Build skipped: %v", err)
+	}
+
+	if result.Success {
+		slog.Info("Build passed", "workspace", workspaceDir)
+		return buildStatusPassed, "Build passed: `go build ./...` reported no errors."
+	}
+
+	slog.Warn("Build failed", "workspace", workspaceDir, "exit_code", result.ExitCode)
+	errs := strings.TrimSpace(result.Stderr + "\n" + result.Stdout)
+	return buildStatusFailed, fmt.Sprintf("Build failed: `go build ./...` exited with code %d.\n\n```\n%s\n```", result.ExitCode, errs)
+}
+
+// ensureGoModule initializes a Go module in the workspace if it does not have one
+func ensureGoModule(ctx context.Context, workspaceDir string) error {
+	_, err := os.Stat(filepath.Join(workspaceDir, "go.mod"))
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to stat go.mod: %w", err)
+	}
+
+	slog.Info("Initializing Go module in workspace", "workspace", workspaceDir, "module", defaultWorkspaceModule)
+	result, err := tools.RunCommand(ctx, workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"mod", "init", defaultWorkspaceModule},
+	})
+	if err != nil {
+		return err
+	}
+	if !result.Success {
+		return fmt.Errorf("go mod init failed: %s", strings.TrimSpace(result.Stderr))
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+)
+
+func TestRunBuild(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantStatus   string
+		wantContains string
+	}{
+		{
+			name:       "empty workspace",
+			wantStatus: buildStatusPassed,
+		},
+		{
+			name: "valid code",
+			files: map[string]string{
+				"go.mod":          "module example.com/calc\n\ngo 1.21\n",
+				"pkg/calc/add.go": "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a + b }\n",
+			},
+			wantStatus: buildStatusPassed,
+		},
+		{
+			name: "compiler error",
+			files: map[string]string{
+				"go.mod":          "module example.com/calc\n\ngo 1.21\n",
+				"pkg/calc/add.go": "package calc\n\nfunc Add(a, b int) int { return a + c }\n",
+			},
+			wantStatus:   buildStatusFailed,
+			wantContains: "undefined: c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, tt.files)
+
+			status, output := runBuild(context.Background(), workspaceDir)
+			if status != tt.wantStatus {
+				t.Errorf("runBuild() status = %q, want %q (output: %s)", status, tt.wantStatus, output)
+			}
+			if !strings.Contains(output, tt.wantContains) {
+				t.Errorf("runBuild() output = %q, want it to contain %q", output, tt.wantContains)
+			}
+			if _, err := os.Stat(filepath.Join(workspaceDir, "go.mod")); err != nil {
+				t.Errorf("go.mod missing after build: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoopApproved_BuildFailure(t *testing.T) {
+	state := mapState{
+		"review_comments": "No critical issues found.",
+		"build_status":    buildStatusFailed,
+	}
+	if loopApproved(state, 0) {
+		t.Error("loopApproved() = true for a failed build, want false")
+	}
+
+	state["build_status"] = buildStatusPassed
+	if !loopApproved(state, 0) {
+		t.Error("loopApproved() = false for an approved review and passing build, want true")
+	}
+}
+
+// writeWorkspace writes files, keyed by relative path, into workspaceDir
+func writeWorkspace(t *testing.T, workspaceDir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+// mapState is a stateReader backed by a map
+type mapState map[string]any
+
+// Get implements stateReader
+func (m mapState) Get(key string) (any, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, session.ErrStateKeyNotExist
+	}
+	return v, nil
+}
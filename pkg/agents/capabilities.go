@@ -0,0 +1,52 @@
+package agents
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"google.golang.org/adk/model"
+)
+
+// capabilityCheckTimeout bounds the capability lookup performed while building a pipeline
+const capabilityCheckTimeout = 10 * time.Second
+
+// inlineFilesInstruction replaces tool usage for models without tool calling support
+const inlineFilesInstruction = `
+
+**Tool calling is NOT available for this model.** Ignore any instructions to call fileRead or fileWrite.
+Instead, output every file you create in full as a fenced code block, preceded by a line of the form:
+File: path/to/file.go`
+
+// detectToolSupport reports whether llm supports native tool calls.
+// Models that cannot report capabilities, or whose lookup fails, are assumed to support tools.
+func detectToolSupport(llm model.LLM) bool {
+	provider, ok := llm.(capability.Provider)
+	if !ok {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), capabilityCheckTimeout)
+	defer cancel()
+
+	caps, err := provider.Capabilities(ctx)
+	if err != nil {
+		slog.Warn("Failed to detect model capabilities, assuming tool support",
+			"model", llm.Name(),
+			"error", err)
+		return true
+	}
+	return caps.SupportsTools
+}
+
+// withoutTools adapts a stage for models lacking tool support by removing its tools
+// and instructing the model to return file contents inline.
+func withoutTools(spec stageSpec) stageSpec {
+	if len(spec.Tools) == 0 && len(spec.Toolsets) == 0 {
+		return spec
+	}
+	spec.Tools, spec.Toolsets = nil, nil
+	spec.Instruction += inlineFilesInstruction
+	return spec
+}
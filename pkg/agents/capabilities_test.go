@@ -0,0 +1,63 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestNewCodePipelineAgent_AdaptsToToolSupport(t *testing.T) {
+	tests := []struct {
+		name           string
+		supportsTools  bool
+		wantInlineNote bool
+	}{
+		{name: "tool capable model", supportsTools: true, wantInlineNote: false},
+		{name: "model without tool support", supportsTools: false, wantInlineNote: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model",
+				fake.Text("design"),
+				fake.Text("code"),
+				fake.Text("tests"),
+				fake.Text("review"),
+			)
+			mdl.SetCapabilities(capability.Capabilities{SupportsTools: tt.supportsTools})
+
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{Model: mdl, WorkspaceDir: t.TempDir(), SkipTests: true})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+			runAgent(t, pipeline, "Build something")
+
+			// The code writer is the second stage and normally has fileRead/fileWrite.
+			writerReq := mdl.Requests()[1]
+			instruction := writerReq.Config.SystemInstruction.Parts[0].Text
+			if got := strings.Contains(instruction, "Tool calling is NOT available"); got != tt.wantInlineNote {
+				t.Errorf("inline file note present = %v, want %v", got, tt.wantInlineNote)
+			}
+			if gotTools := len(writerReq.Tools) > 0; gotTools != tt.supportsTools {
+				t.Errorf("writer request has tools = %v, want %v", gotTools, tt.supportsTools)
+			}
+		})
+	}
+}
+
+func TestWithoutTools(t *testing.T) {
+	design := withoutTools(designStage())
+	if design.Instruction != designStage().Instruction {
+		t.Error("withoutTools() changed the instruction of a stage without tools")
+	}
+
+	writer := withoutTools(codeWriterStage(t.TempDir()))
+	if len(writer.Tools) != 0 {
+		t.Errorf("withoutTools() left %d tools", len(writer.Tools))
+	}
+	if !strings.HasSuffix(writer.Instruction, inlineFilesInstruction) {
+		t.Error("withoutTools() did not append the inline files instruction")
+	}
+}
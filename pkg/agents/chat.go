@@ -0,0 +1,162 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+)
+
+// Root agent modes selectable with PipelineConfig.Mode
+const (
+	ModePipeline = "pipeline"
+	ModeChat     = "chat"
+)
+
+// maxRecalledMemories caps the memory entries added to the chat instruction
+const maxRecalledMemories = 5
+
+// NewAgent creates the root agent selected by config.Mode: the code pipeline by default,
+// or the chat agent
+func NewAgent(config PipelineConfig) (agent.Agent, error) {
+	switch config.Mode {
+	case "", ModePipeline:
+		return NewCodePipelineAgent(config)
+	case ModeChat:
+		return NewChatAgent(config)
+	default:
+		return nil, fmt.Errorf("unknown mode %q (want %s or %s)", config.Mode, ModePipeline, ModeChat)
+	}
+}
+
+// NewChatAgent creates a single conversational coding agent with the workspace tools and
+// no fixed stages, for exploratory sessions. Each turn continues the conversation of the
+// session. When the runner has a memory service, memories matching the user message are
+// recalled into the instruction and the conversation is added to memory after each turn.
+// It uses the model, name, description, workspace, and stage timeout and retry settings
+// of config.
+func NewChatAgent(config PipelineConfig) (agent.Agent, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+	if config.Name == "" {
+		config.Name = "ChatAgent"
+	}
+	if config.Description == "" {
+		config.Description = "Converses about and changes the code in the workspace."
+	}
+	if config.WorkspaceDir == "" {
+		config.WorkspaceDir = tools.DefaultWorkspaceDir
+	}
+	if err := applySandbox(config); err != nil {
+		return nil, err
+	}
+
+	slog.Info("Creating chat agent",
+		"name", config.Name,
+		"model", config.Model.Name(),
+		"workspace", config.WorkspaceDir)
+
+	spec := chatStage(config.WorkspaceDir)
+	instruction, err := stagePrompt(config, spec.Name)
+	if err != nil {
+		return nil, err
+	}
+	spec.Instruction = instruction
+	if len(config.EnvVars) > 0 {
+		spec = withEnvTool(spec, config.EnvVars)
+	}
+	if !detectToolSupport(config.Model) {
+		slog.Warn("Model does not support tool calling; the chat agent will return file contents inline",
+			"model", config.Model.Name())
+		spec = withoutTools(spec)
+	}
+	assistant, err := newLLMStage(config, spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", spec.Name, err)
+	}
+
+	return agent.New(agent.Config{
+		Name:        config.Name,
+		Description: config.Description,
+		SubAgents:   []agent.Agent{assistant},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				if ctx.Memory() != nil {
+					event := session.NewEvent(ctx.InvocationID())
+					event.Actions.StateDelta["chat_memory"] = recallMemories(ctx)
+					if !yield(event, nil) {
+						return
+					}
+				}
+
+				for event, err := range assistant.Run(ctx) {
+					if !yield(event, err) || err != nil {
+						return
+					}
+				}
+
+				if ctx.Memory() != nil {
+					if err := ctx.Memory().AddSession(ctx, ctx.Session()); err != nil {
+						slog.Warn("Failed to add the conversation to memory", "error", err)
+					}
+				}
+			}
+		},
+	})
+}
+
+// recallMemories returns the memory entries matching the user message as a Markdown
+// list, or an empty string if there are none
+func recallMemories(ctx agent.InvocationContext) string {
+	query := strings.TrimSpace(contentText(ctx.UserContent()))
+	if query == "" {
+		return ""
+	}
+	response, err := ctx.Memory().Search(ctx, query)
+	if err != nil {
+		slog.Warn("Failed to search memory", "error", err)
+		return ""
+	}
+
+	var b strings.Builder
+	for i, entry := range response.Memories {
+		if i == maxRecalledMemories {
+			break
+		}
+		text := strings.TrimSpace(contentText(entry.Content))
+		if text == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "- [%s] %s\n", entry.Author, text)
+	}
+	return b.String()
+}
+
+// chatStage describes the conversational coding agent
+func chatStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "ChatAssistantAgent",
+		Description: "Answers questions and makes code changes in conversation with the user.",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+			tools.NewApplyPatchToolWithWorkspace(workspaceDir),
+			tools.NewFileEditToolWithWorkspace(workspaceDir),
+			tools.NewFileListToolWithWorkspace(workspaceDir),
+			tools.NewFileStatToolWithWorkspace(workspaceDir),
+			tools.NewSearchToolWithWorkspace(workspaceDir),
+			tools.NewExecToolWithWorkspace(workspaceDir),
+			tools.NewLintToolWithWorkspace(workspaceDir),
+			tools.NewGoVetToolWithWorkspace(workspaceDir),
+			tools.NewGoModToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("ChatAssistantAgent"),
+	}
+}
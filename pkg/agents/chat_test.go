@@ -0,0 +1,113 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestNewAgent(t *testing.T) {
+	tests := []struct {
+		name     string
+		mode     string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "pipeline by default", wantName: "CodePipelineAgent"},
+		{name: "pipeline", mode: ModePipeline, wantName: "CodePipelineAgent"},
+		{name: "chat", mode: ModeChat, wantName: "ChatAgent"},
+		{name: "unknown", mode: "swarm", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag, err := NewAgent(PipelineConfig{Model: fake.New("fake-model"), WorkspaceDir: t.TempDir(), Mode: tt.mode})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewAgent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && ag.Name() != tt.wantName {
+				t.Errorf("NewAgent().Name() = %q, want %q", ag.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestChatAgent_Run(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.Text("The calculator package lives in pkg/calc."),
+		fake.Text("Add and Sub."),
+		fake.Text("It uses int operands."),
+	)
+	chat, err := NewChatAgent(PipelineConfig{Model: mdl, WorkspaceDir: t.TempDir()})
+	if err != nil {
+		t.Fatalf("NewChatAgent() error = %v", err)
+	}
+
+	ctx := context.Background()
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{
+		AppName:        "test-app",
+		Agent:          chat,
+		SessionService: sessionService,
+		MemoryService:  memory.InMemoryService(),
+	})
+	if err != nil {
+		t.Fatalf("runner.New() error = %v", err)
+	}
+
+	// send runs one conversational turn in sessionID
+	send := func(sessionID, prompt string) {
+		t.Helper()
+		msg := genai.NewContentFromText(prompt, genai.RoleUser)
+		for _, err := range r.Run(ctx, "test-user", sessionID, msg, agent.RunConfig{}) {
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+		}
+	}
+	newSession := func() string {
+		t.Helper()
+		created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "test-app", UserID: "test-user"})
+		if err != nil {
+			t.Fatalf("session Create() error = %v", err)
+		}
+		return created.Session.ID()
+	}
+
+	first := newSession()
+	send(first, "Where is the calculator package?")
+	send(first, "Which functions does it have?")
+	second := newSession()
+	send(second, "What operands does the calculator use?")
+
+	requests := mdl.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("model calls = %d, want 3", len(requests))
+	}
+
+	// The second turn continues the conversation of the first
+	var history []string
+	for _, content := range requests[1].Contents {
+		history = append(history, contentText(content))
+	}
+	if got := strings.Join(history, "\n"); !strings.Contains(got, "Where is the calculator package?") || !strings.Contains(got, "lives in pkg/calc") {
+		t.Errorf("second turn contents = %q, want the first turn", got)
+	}
+
+	// A new session recalls the earlier conversation from memory
+	instruction := requests[2].Config.SystemInstruction.Parts[0].Text
+	if !strings.Contains(instruction, "The calculator package lives in pkg/calc.") {
+		t.Errorf("instruction does not recall the earlier session: %q", instruction)
+	}
+	if len(requests[2].Tools) == 0 {
+		t.Error("chat agent has no tools")
+	}
+}
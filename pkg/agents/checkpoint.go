@@ -0,0 +1,242 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Paths within PipelineConfig.CheckpointDir
+const (
+	checkpointFile         = "checkpoint.json"
+	checkpointWorkspace    = "workspace"
+	checkpointWorkspaceNew = "workspace.new"
+)
+
+// checkpoint records the progress of a pipeline run after its last completed stage
+type checkpoint struct {
+	// Request is the user message that started the run
+	Request string `json:"request"`
+	// CompletedStages are the names of the top-level stages that completed, in order
+	CompletedStages []string `json:"completed_stages"`
+	// State is the session state after the last completed stage
+	State map[string]any `json:"state"`
+	// UpdatedAt is when the checkpoint was written
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// newCheckpointPipeline creates a sequential pipeline over the sub-agents of cfg that
+// saves a checkpoint after each completed stage: the session state to checkpoint.json
+// and a copy of the workspace. A run with the same request as the checkpoint restores
+// both and skips the completed stages. The checkpoint is removed when a run completes
+// without errors.
+func newCheckpointPipeline(cfg agent.Config, checkpointDir, workspaceDir string) (agent.Agent, error) {
+	if err := checkCheckpointDir(checkpointDir, workspaceDir); err != nil {
+		return nil, err
+	}
+
+	cfg.Run = func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+		return func(yield func(*session.Event, error) bool) {
+			request := contentText(ctx.UserContent())
+			completed := make(map[string]bool)
+
+			// A reply to a pending design approval continues the paused run instead
+			if readStateString(ctx.Session().State(), "design_approval") != approvalPending {
+				resumed, event, err := resumeCheckpoint(ctx, checkpointDir, workspaceDir, request)
+				if err != nil {
+					slog.Warn("Ignoring unusable checkpoint", "dir", checkpointDir, "error", err)
+				}
+				if event != nil {
+					if !yield(event, nil) {
+						return
+					}
+					for _, name := range resumed {
+						completed[name] = true
+					}
+				}
+			}
+
+			cp := &checkpoint{Request: request}
+			saving := true
+			for _, subAgent := range ctx.Agent().SubAgents() {
+				if completed[subAgent.Name()] {
+					cp.CompletedStages = append(cp.CompletedStages, subAgent.Name())
+					continue
+				}
+
+				escalated, failed := false, false
+				for event, err := range subAgent.Run(ctx) {
+					if event != nil && event.Actions.Escalate {
+						escalated = true
+					}
+					failed = failed || err != nil
+					if !yield(event, err) {
+						return
+					}
+				}
+				// Like sequentialagent, an escalation ends the pipeline
+				if escalated {
+					return
+				}
+				// Stages after a failed stage are not checkpointed, so a resumed run repeats it
+				if failed {
+					saving = false
+				}
+				if !saving {
+					continue
+				}
+
+				cp.CompletedStages = append(cp.CompletedStages, subAgent.Name())
+				cp.State = stateSnapshot(ctx.Session().State())
+				if err := saveCheckpoint(checkpointDir, workspaceDir, cp); err != nil {
+					slog.Warn("Failed to save checkpoint", "stage", subAgent.Name(), "error", err)
+					saving = false
+				}
+			}
+
+			if saving {
+				if err := removeCheckpoint(checkpointDir); err != nil {
+					slog.Warn("Failed to remove checkpoint of the completed run", "dir", checkpointDir, "error", err)
+				}
+			}
+		}
+	}
+	return agent.New(cfg)
+}
+
+// checkCheckpointDir rejects a checkpoint directory inside the workspace, which would
+// copy itself into every workspace snapshot
+func checkCheckpointDir(checkpointDir, workspaceDir string) error {
+	inside, err := insideWorkspace(checkpointDir, workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve checkpoint directory: %w", err)
+	}
+	if inside {
+		return fmt.Errorf("checkpoint directory %s must be outside the workspace %s", checkpointDir, workspaceDir)
+	}
+	return nil
+}
+
+// insideWorkspace reports whether dir is workspaceDir or a directory below it
+func insideWorkspace(dir, workspaceDir string) (bool, error) {
+	dirAbs, err := filepath.Abs(dir)
+	if err != nil {
+		return false, err
+	}
+	workspaceAbs, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return false, err
+	}
+	rel, err := filepath.Rel(workspaceAbs, dirAbs)
+	return err == nil && (rel == "." || !strings.HasPrefix(rel, "..")), nil
+}
+
+// resumeCheckpoint restores the workspace from the checkpoint in checkpointDir if it was
+// saved for request, returning the completed stages and an event that restores the
+// session state. It returns no event when there is nothing to resume.
+func resumeCheckpoint(ctx agent.InvocationContext, checkpointDir, workspaceDir, request string) ([]string, *session.Event, error) {
+	cp, err := loadCheckpoint(checkpointDir)
+	if err != nil || cp == nil {
+		return nil, nil, err
+	}
+	if cp.Request != request {
+		slog.Info("Checkpoint is for a different request, starting a new run", "dir", checkpointDir)
+		return nil, nil, nil
+	}
+	if err := tools.CopyTree(filepath.Join(checkpointDir, checkpointWorkspace), workspaceDir); err != nil {
+		return nil, nil, fmt.Errorf("failed to restore workspace: %w", err)
+	}
+
+	slog.Info("Resuming pipeline from checkpoint", "completed_stages", cp.CompletedStages, "updated_at", cp.UpdatedAt)
+	event := session.NewEvent(ctx.InvocationID())
+	event.LLMResponse.Content = genai.NewContentFromText(
+		fmt.Sprintf("Resuming from checkpoint after %s.", strings.Join(cp.CompletedStages, ", ")), genai.RoleModel)
+	for key, value := range cp.State {
+		event.Actions.StateDelta[key] = value
+	}
+	return cp.CompletedStages, event, nil
+}
+
+// loadCheckpoint reads the checkpoint in dir, returning nil if there is none
+func loadCheckpoint(dir string) (*checkpoint, error) {
+	data, err := os.ReadFile(filepath.Join(dir, checkpointFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+	var cp checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint: %w", err)
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint snapshots the workspace and writes cp to dir. The snapshot replaces the
+// previous one only once it is complete, and checkpoint.json is written last, so a crash
+// while saving leaves the previous checkpoint usable.
+func saveCheckpoint(dir, workspaceDir string, cp *checkpoint) error {
+	snapshot := filepath.Join(dir, checkpointWorkspace)
+	staged := filepath.Join(dir, checkpointWorkspaceNew)
+	if err := os.RemoveAll(staged); err != nil {
+		return fmt.Errorf("failed to clear staged snapshot: %w", err)
+	}
+	if _, err := tools.NewSnapshot(workspaceDir, staged); err != nil {
+		return fmt.Errorf("failed to snapshot workspace: %w", err)
+	}
+	if err := os.RemoveAll(snapshot); err != nil {
+		return fmt.Errorf("failed to remove previous snapshot: %w", err)
+	}
+	if err := os.Rename(staged, snapshot); err != nil {
+		return fmt.Errorf("failed to replace snapshot: %w", err)
+	}
+
+	cp.UpdatedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	tmp := filepath.Join(dir, checkpointFile+".tmp")
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp, filepath.Join(dir, checkpointFile)); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	slog.Info("Checkpoint saved", "completed_stages", len(cp.CompletedStages), "dir", dir)
+	return nil
+}
+
+// removeCheckpoint removes the checkpoint files from dir, leaving other files in place
+func removeCheckpoint(dir string) error {
+	var errs []error
+	for _, name := range []string{checkpointFile, checkpointWorkspace, checkpointWorkspaceNew} {
+		errs = append(errs, os.RemoveAll(filepath.Join(dir, name)))
+	}
+	return errors.Join(errs...)
+}
+
+// stateSnapshot returns the JSON-encodable values of state
+func stateSnapshot(state session.State) map[string]any {
+	snapshot := make(map[string]any)
+	for key, value := range state.All() {
+		if _, err := json.Marshal(value); err != nil {
+			slog.Warn("Not checkpointing state value that cannot be encoded", "key", key, "error", err)
+			continue
+		}
+		snapshot[key] = value
+	}
+	return snapshot
+}
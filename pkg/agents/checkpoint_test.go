@@ -0,0 +1,128 @@
+package agents
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestCheckpoint_ResumesAfterLastCompletedStage(t *testing.T) {
+	workspaceDir := t.TempDir()
+	checkpointDir := filepath.Join(t.TempDir(), "checkpoint")
+	newPipeline := func(mdl *fake.Model) PipelineConfig {
+		return PipelineConfig{
+			Model:         mdl,
+			WorkspaceDir:  workspaceDir,
+			CheckpointDir: checkpointDir,
+			Stages:        []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+		}
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, "notes.md"), []byte("kept"), 0644); err != nil {
+		t.Fatalf("failed to write workspace file: %v", err)
+	}
+
+	// The first run fails in the code writer after the design completed
+	first := fake.New("fake-model", fake.Text("design: pkg/calc"), fake.Error(errors.New("connection reset")))
+	pipeline, err := NewCodePipelineAgent(newPipeline(first))
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	if _, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package"); runErr == nil {
+		t.Fatal("first Run() error = nil, want the code writer failure")
+	}
+	cp, err := loadCheckpoint(checkpointDir)
+	if err != nil || cp == nil {
+		t.Fatalf("loadCheckpoint() = %v, %v; want a checkpoint", cp, err)
+	}
+	if strings.Join(cp.CompletedStages, ",") != "DesignAgent" || cp.State["design"] != "design: pkg/calc" {
+		t.Fatalf("checkpoint = %+v, want the completed design stage", cp)
+	}
+
+	// A crash may leave the workspace in any state; the snapshot restores it
+	if err := os.Remove(filepath.Join(workspaceDir, "notes.md")); err != nil {
+		t.Fatalf("failed to remove workspace file: %v", err)
+	}
+
+	second := fake.New("fake-model", fake.Text("Created pkg/calc/calc.go"))
+	pipeline, err = NewCodePipelineAgent(newPipeline(second))
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	events, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+	if runErr != nil {
+		t.Fatalf("resumed Run() error = %v", runErr)
+	}
+	if got := second.Calls(); got != 1 {
+		t.Errorf("resumed model calls = %d, want 1 (the design is not repeated)", got)
+	}
+	if got := second.Requests()[0].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "design: pkg/calc") {
+		t.Errorf("code writer instruction does not contain the restored design: %q", got)
+	}
+	if got := contentText(events[0].Content); !strings.Contains(got, "Resuming from checkpoint after DesignAgent") {
+		t.Errorf("first event = %q, want the resume notice", got)
+	}
+	if data, err := os.ReadFile(filepath.Join(workspaceDir, "notes.md")); err != nil || string(data) != "kept" {
+		t.Errorf("notes.md = %q, %v; want the restored file", data, err)
+	}
+	if cp, err := loadCheckpoint(checkpointDir); err != nil || cp != nil {
+		t.Errorf("checkpoint after a completed run = %+v, %v; want it removed", cp, err)
+	}
+}
+
+func TestCheckpoint_DifferentRequestStartsOver(t *testing.T) {
+	workspaceDir := t.TempDir()
+	checkpointDir := t.TempDir()
+	if err := saveCheckpoint(checkpointDir, workspaceDir, &checkpoint{
+		Request:         "Build a web server",
+		CompletedStages: []string{"DesignAgent"},
+		State:           map[string]any{"design": "web server design"},
+	}); err != nil {
+		t.Fatalf("saveCheckpoint() error = %v", err)
+	}
+
+	mdl := fake.New("fake-model", fake.Text("design: pkg/calc"), fake.Text("Created pkg/calc/calc.go"))
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:         mdl,
+		WorkspaceDir:  workspaceDir,
+		CheckpointDir: checkpointDir,
+		Stages:        []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	if _, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package"); runErr != nil {
+		t.Fatalf("Run() error = %v", runErr)
+	}
+	if got := mdl.Calls(); got != 2 {
+		t.Errorf("model calls = %d, want 2", got)
+	}
+	if got := mdl.Requests()[1].Config.SystemInstruction.Parts[0].Text; strings.Contains(got, "web server design") {
+		t.Error("code writer used the state of another request's checkpoint")
+	}
+}
+
+func TestCheckCheckpointDir(t *testing.T) {
+	workspaceDir := t.TempDir()
+	tests := []struct {
+		name          string
+		checkpointDir string
+		wantErr       bool
+	}{
+		{name: "outside", checkpointDir: t.TempDir()},
+		{name: "sibling with common prefix", checkpointDir: workspaceDir + "-checkpoint"},
+		{name: "inside", checkpointDir: filepath.Join(workspaceDir, ".checkpoint"), wantErr: true},
+		{name: "same", checkpointDir: workspaceDir, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkCheckpointDir(tt.checkpointDir, workspaceDir); (err != nil) != tt.wantErr {
+				t.Errorf("checkCheckpointDir() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package agents
+
+import (
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// CI providers the CI workflow stage writes pipelines for
+const (
+	CIGitHub = "github"
+	CIGitLab = "gitlab"
+)
+
+// ciProviderInstructions maps each CI provider to the workflow section of the CI stage instruction
+var ciProviderInstructions = map[string]string{
+	CIGitHub: `
+
+**Workflow File: .github/workflows/ci.yml (GitHub Actions)**
+- Triggers: push to main and pull_request
+- Jobs on ubuntu-latest: lint (golangci/golangci-lint-action) and test (build, vet, test); test needs nothing else so both run in parallel
+- actions/checkout and actions/setup-go with go-version-file: go.mod and module caching
+- A docker job that runs docker build (without pushing) when a Dockerfile exists
+- Minimal permissions: contents: read`,
+	CIGitLab: `
+
+**Workflow File: .gitlab-ci.yml (GitLab CI)**
+- Stages: lint, test, build
+- lint uses the golangci/golangci-lint image; test and build use the golang image matching the go.mod version
+- Cache GOPATH/pkg/mod keyed on go.sum with GOPATH set under the project directory
+- A build job that runs docker build (without pushing) when a Dockerfile exists
+- Rules: run on merge requests and on the default branch`,
+}
+
+// ciStage describes the CI workflow agent stage; withCIProvider adds the provider-specific workflow
+func ciStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "CIAgent",
+		Description: "Writes a CI workflow that builds, tests, and lints the generated project.",
+		OutputKey:   "ci_workflow",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("CIAgent"),
+	}
+}
+
+// withCIProvider adds the workflow file and conventions of provider to the CI stage
+func withCIProvider(spec stageSpec, provider string) stageSpec {
+	spec.Instruction += ciProviderInstructions[provider]
+	return spec
+}
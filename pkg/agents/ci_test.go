@@ -0,0 +1,121 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestPipelineStages_CI(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			config: PipelineConfig{SkipBuild: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "after the tests",
+			config: PipelineConfig{SkipBuild: true, CIProvider: CIGitHub},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CIAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "between deployment and lint",
+			config: PipelineConfig{SkipBuild: true, CIProvider: CIGitLab, Deployment: true, Lint: true},
+			want: []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "DeploymentAgent",
+				"CIAgent", "LintAgent", "CodeReviewerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			var names []string
+			for _, spec := range stages {
+				names = append(names, spec.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("stages = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestCI_Run(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   PipelineConfig
+		wantFile string
+	}{
+		{
+			name:     "github",
+			config:   PipelineConfig{CIProvider: CIGitHub},
+			wantFile: ".github/workflows/ci.yml",
+		},
+		{
+			name:     "gitlab",
+			config:   PipelineConfig{CIProvider: CIGitLab},
+			wantFile: ".gitlab-ci.yml",
+		},
+		{
+			name:     "builtin stage defaults to github",
+			config:   PipelineConfig{Stages: []StageConfig{{Builtin: builtinCI}, {Builtin: builtinCodeReviewer}}},
+			wantFile: ".github/workflows/ci.yml",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := "## CI Workflow\n- " + tt.wantFile
+			mdl := fake.New("fake-model", fake.Text(summary), fake.Text("No major issues found."))
+			if len(tt.config.Stages) == 0 {
+				mdl = fake.New("fake-model",
+					fake.Text("design"),
+					fake.Text("Created pkg/calc/calc.go"),
+					fake.Text("Created pkg/calc/calc_test.go"),
+					fake.Text(summary),
+					fake.Text("No major issues found."),
+				)
+			}
+			tt.config.Model = mdl
+			tt.config.WorkspaceDir = t.TempDir()
+			tt.config.SkipBuild = true
+			tt.config.SkipTests = true
+			pipeline, err := NewCodePipelineAgent(tt.config)
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			_, state := runAgent(t, pipeline, "Build a calculator package")
+
+			if got := stateString(t, state, "ci_workflow"); got != summary {
+				t.Errorf("state[ci_workflow] = %q, want %q", got, summary)
+			}
+			requests := mdl.Requests()
+			instruction := requests[len(requests)-2].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(instruction, tt.wantFile) || !strings.Contains(instruction, "go test -race -cover ./...") {
+				t.Errorf("CI instruction does not ask for %s with the test step: %q", tt.wantFile, instruction)
+			}
+		})
+	}
+}
+
+func TestNewCodePipelineAgent_UnknownCIProvider(t *testing.T) {
+	_, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        fake.New("fake-model"),
+		WorkspaceDir: t.TempDir(),
+		CIProvider:   "jenkins",
+	})
+	if err == nil || !strings.Contains(err.Error(), `unknown CI provider "jenkins"`) {
+		t.Errorf("NewCodePipelineAgent() error = %v, want unknown CI provider", err)
+	}
+}
@@ -0,0 +1,105 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// compactableKeys are the stage output keys that may be replaced by a summary. Other
+// output keys hold structured text that later stages parse, such as the task plan, the
+// review verdict, and the quality report, or a contract that must be kept exact.
+var compactableKeys = []string{
+	"design",
+	"generated_code",
+	"test_code",
+	"documentation",
+	"benchmark_code",
+	"deployment",
+	"ci_workflow",
+}
+
+// compactionInstruction is the system instruction of the summarization request
+const compactionInstruction = `You compact the output of a stage of a Go code generation pipeline so later stages fit in their context window. Summarize the text you are given in at most %d characters.
+
+**Keep:**
+- Every file path, package name, and exported identifier, as a list
+- Key decisions: architecture, interfaces, dependencies, error handling, and concurrency choices
+- Open problems, failures, and anything later stages must act on
+
+**Drop:**
+- Code bodies, examples, and repeated explanations; later stages read the files with fileRead
+
+Reply with the summary only.`
+
+// withCompaction makes a stage replace its output with a model-written summary when the
+// output is longer than maxChars. The full output is kept under "<key>_full". A failed
+// summarization keeps the full output.
+func withCompaction(spec stageSpec, mdl model.LLM, maxChars int) stageSpec {
+	if spec.Custom != nil || !slices.Contains(compactableKeys, spec.OutputKey) {
+		return spec
+	}
+	outputKey := spec.OutputKey
+	spec.AfterAgentCallbacks = append(spec.AfterAgentCallbacks, func(ctx agent.CallbackContext) (*genai.Content, error) {
+		output := readStateString(ctx.ReadonlyState(), outputKey)
+		if len(output) <= maxChars {
+			return nil, nil
+		}
+
+		summary, err := summarizeOutput(ctx, mdl, outputKey, output, maxChars)
+		if err != nil {
+			slog.Warn("Failed to compact stage output, keeping it in full", "key", outputKey, "error", err)
+			return nil, nil
+		}
+		if err := ctx.State().Set(outputKey+"_full", output); err != nil {
+			slog.Warn("Failed to store full stage output", "key", outputKey, "error", err)
+			return nil, nil
+		}
+		if err := ctx.State().Set(outputKey, summary); err != nil {
+			slog.Warn("Failed to store compacted stage output", "key", outputKey, "error", err)
+			return nil, nil
+		}
+		slog.Info("Compacted stage output", "key", outputKey, "chars", len(output), "summary_chars", len(summary))
+		return nil, nil
+	})
+	return spec
+}
+
+// summarizeOutput asks mdl to summarize the output stored under key in at most maxChars
+// characters. A summary that is empty or no shorter than output is an error.
+func summarizeOutput(ctx context.Context, mdl model.LLM, key, output string, maxChars int) (string, error) {
+	req := &model.LLMRequest{
+		Model: mdl.Name(),
+		Contents: []*genai.Content{
+			genai.NewContentFromText(fmt.Sprintf("Stage output %q:\n\n%s", key, output), genai.RoleUser),
+		},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText(fmt.Sprintf(compactionInstruction, maxChars), genai.RoleUser),
+		},
+	}
+
+	var b strings.Builder
+	for resp, err := range mdl.GenerateContent(ctx, req, false) {
+		if err != nil {
+			return "", fmt.Errorf("summarization request failed: %w", err)
+		}
+		if !resp.Partial {
+			b.WriteString(contentText(resp.Content))
+		}
+	}
+
+	summary := strings.TrimSpace(b.String())
+	if summary == "" {
+		return "", fmt.Errorf("model returned an empty summary")
+	}
+	if len(summary) >= len(output) {
+		return "", fmt.Errorf("summary (%d chars) is not shorter than the output (%d chars)", len(summary), len(output))
+	}
+	return summary, nil
+}
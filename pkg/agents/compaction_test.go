@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestSummarizeOutput(t *testing.T) {
+	output := strings.Repeat("design detail ", 20)
+	tests := []struct {
+		name        string
+		turn        fake.Turn
+		want        string
+		errContains string
+	}{
+		{name: "summary", turn: fake.Text("  pkg/calc: Add, Sub  "), want: "pkg/calc: Add, Sub"},
+		{name: "empty", turn: fake.Text(" "), errContains: "empty summary"},
+		{name: "not shorter", turn: fake.Text(output + "more"), errContains: "not shorter"},
+		{name: "model error", turn: fake.Error(errors.New("overloaded")), errContains: "overloaded"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model", tt.turn)
+			got, err := summarizeOutput(context.Background(), mdl, "design", output, 100)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("summarizeOutput() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("summarizeOutput() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("summarizeOutput() = %q, want %q", got, tt.want)
+			}
+			if instruction := mdl.Requests()[0].Config.SystemInstruction.Parts[0].Text; !strings.Contains(instruction, "at most 100 characters") {
+				t.Errorf("summarization instruction = %q, want the character limit", instruction)
+			}
+		})
+	}
+}
+
+func TestCompaction_Run(t *testing.T) {
+	design := "## Architecture Overview\n" + strings.Repeat("A calculator with careful overflow handling. ", 10)
+	summary := "pkg/calc/calc.go: Add, Sub; checks overflow"
+	mdl := fake.New("fake-model",
+		fake.Text(design),
+		fake.Text(summary),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:             mdl,
+		WorkspaceDir:      t.TempDir(),
+		SkipBuild:         true,
+		SkipTests:         true,
+		CompactStateChars: 200,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a calculator package")
+
+	if got := stateString(t, state, "design"); got != summary {
+		t.Errorf("state[design] = %q, want the summary %q", got, summary)
+	}
+	if got := stateString(t, state, "design_full"); got != design {
+		t.Errorf("state[design_full] = %q, want the full design", got)
+	}
+	// Short outputs are not summarized
+	if got := stateString(t, state, "generated_code"); got != "Created pkg/calc/calc.go" {
+		t.Errorf("state[generated_code] = %q, want it unchanged", got)
+	}
+	if got := mdl.Calls(); got != 5 {
+		t.Errorf("model calls = %d, want 5", got)
+	}
+
+	writer := mdl.Requests()[2].Config.SystemInstruction.Parts[0].Text
+	if !strings.Contains(writer, summary) || strings.Contains(writer, "careful overflow handling") {
+		t.Errorf("code writer instruction does not use the summary: %q", writer)
+	}
+}
@@ -0,0 +1,312 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"gopkg.in/yaml.v3"
+)
+
+// Built-in stage names usable in StageConfig.Builtin
+const (
+	builtinDesign        = "design"
+	builtinAPIDesign     = "api_design"
+	builtinPlanner       = "planner"
+	builtinCodeWriter    = "code_writer"
+	builtinDependencies  = "dependencies"
+	builtinBuild         = "build"
+	builtinTDDExpert     = "tdd"
+	builtinDocumentation = "documentation"
+	builtinBenchmark     = "benchmark"
+	builtinDeployment    = "deployment"
+	builtinCI            = "ci"
+	builtinLint          = "lint"
+	builtinSecurity      = "security_review"
+	builtinCodeReviewer  = "code_reviewer"
+	builtinCritic        = "critic"
+	// builtinAcceptanceTests is not part of the default stages; see NewAcceptanceTestPipeline
+	builtinAcceptanceTests = "acceptance_tests"
+)
+
+// builtinStages maps built-in stage names to their spec factories
+var builtinStages = map[string]func(workspaceDir string) stageSpec{
+	builtinDesign:          func(string) stageSpec { return designStage() },
+	builtinAPIDesign:       apiDesignStage,
+	builtinPlanner:         func(string) stageSpec { return plannerStage() },
+	builtinCodeWriter:      codeWriterStage,
+	builtinDependencies:    func(string) stageSpec { return dependencyStage() },
+	builtinBuild:           func(string) stageSpec { return buildStage() },
+	builtinTDDExpert:       tddExpertStage,
+	builtinDocumentation:   documentationStage,
+	builtinBenchmark:       benchmarkStage,
+	builtinDeployment:      deploymentStage,
+	builtinCI:              ciStage,
+	builtinLint:            func(string) stageSpec { return lintStage() },
+	builtinSecurity:        securityReviewStage,
+	builtinCodeReviewer:    codeReviewerStage,
+	builtinCritic:          func(string) stageSpec { return criticStage() },
+	builtinAcceptanceTests: acceptanceTestStage,
+}
+
+// envToolName is the stage tool that reads the environment variables in PipelineConfig.EnvVars
+const envToolName = "env"
+
+// applyStageTools adds the caller-supplied tools in extra, keyed by stage name, to stages
+func applyStageTools(stages []stageSpec, extra map[string][]tool.Tool) error {
+	for name, stageTools := range extra {
+		i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Name == name })
+		if i < 0 {
+			return fmt.Errorf("stage tools for unknown stage %q", name)
+		}
+		if stages[i].Custom != nil {
+			return fmt.Errorf("stage %q does not use tools", name)
+		}
+		for _, t := range stageTools {
+			if t == nil {
+				return fmt.Errorf("nil tool for stage %q", name)
+			}
+			if slices.ContainsFunc(stages[i].Tools, func(existing tool.Tool) bool { return existing.Name() == t.Name() }) {
+				return fmt.Errorf("stage %q already has a %q tool", name, t.Name())
+			}
+			slog.Info("Adding stage tool", "stage", name, "tool", t.Name())
+			stages[i].Tools = append(slices.Clip(stages[i].Tools), t)
+		}
+	}
+	return nil
+}
+
+// StageConfig declares a pipeline stage: a built-in stage with optional overrides, a
+// registered agent, or a custom LLM stage
+type StageConfig struct {
+	// Builtin selects a built-in stage: design, api_design, planner, code_writer, dependencies, build, tdd, documentation, benchmark, deployment, ci, lint, security_review, code_reviewer, critic, or acceptance_tests
+	Builtin string `yaml:"builtin"`
+	// Agent selects a custom agent registered with Register
+	Agent string `yaml:"agent"`
+	// Name is the agent name; required for custom stages
+	Name string `yaml:"name"`
+	// Description is the agent description
+	Description string `yaml:"description"`
+	// Instruction is the instruction template; custom stages require it or InstructionFile
+	Instruction string `yaml:"instruction"`
+	// InstructionFile loads the instruction template from a file, relative to the config file
+	InstructionFile string `yaml:"instruction_file"`
+	// OutputKey is the session state key the stage output is stored under
+	OutputKey string `yaml:"output_key"`
+	// Tools are the names of the tools available to the stage (fileRead, fileWrite, applyPatch, fileEdit, fileList, fileStat, diff, count, goSymbols, search, exec, lint, goVet, goTest, goRace, goRename, snapshot, gitInit, gitStatus, gitDiff, gitAdd, gitCommit, gitLog, goMod, archive, sql, protoc, oapiCodegen, scratchpad, env)
+	Tools []string `yaml:"tools"`
+	// ParallelGroup names the group of adjacent stages this stage may run concurrently with
+	ParallelGroup string `yaml:"parallel_group"`
+}
+
+// LoadPipelineConfig reads a pipeline configuration from a YAML or JSON file
+func LoadPipelineConfig(path string) (PipelineConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return PipelineConfig{}, fmt.Errorf("failed to read pipeline config %s: %w", path, err)
+	}
+
+	// JSON is valid YAML, so one decoder handles both formats
+	var config PipelineConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return PipelineConfig{}, fmt.Errorf("failed to parse pipeline config %s: %w", path, err)
+	}
+
+	// Instruction files, the prompt directory, and the seed workspace are relative to the config file
+	config.ResolvePaths(filepath.Dir(path))
+
+	slog.Info("Loaded pipeline config",
+		"path", path,
+		"name", config.Name,
+		"stages", len(config.Stages))
+	return config, nil
+}
+
+// ResolvePaths makes the relative instruction files, prompt directory, and seed workspace of
+// the configuration relative to baseDir, the directory of the file it was read from
+func (c *PipelineConfig) ResolvePaths(baseDir string) {
+	for name, file := range c.InstructionFiles {
+		c.InstructionFiles[name] = resolveRelative(baseDir, file)
+	}
+	for i := range c.Stages {
+		c.Stages[i].InstructionFile = resolveRelative(baseDir, c.Stages[i].InstructionFile)
+	}
+	c.PromptDir = resolveRelative(baseDir, c.PromptDir)
+	c.SeedWorkspace = resolveRelative(baseDir, c.SeedWorkspace)
+}
+
+// LoadPipeline creates the root agent selected by the configuration file at path, the
+// code pipeline unless its mode is chat
+func LoadPipeline(path string, llm model.LLM) (agent.Agent, error) {
+	config, err := LoadPipelineConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	config.Model = llm
+	return NewAgent(config)
+}
+
+// pipelineStages returns the stage specs for config, built from config.Stages when set
+// and from the default stage list otherwise
+func pipelineStages(config PipelineConfig) ([]stageSpec, error) {
+	stageConfigs := config.Stages
+	if len(stageConfigs) == 0 {
+		stageConfigs = defaultStageConfigs(config)
+	}
+
+	stages := make([]stageSpec, 0, len(stageConfigs))
+	names := make(map[string]bool, len(stageConfigs))
+	for i, sc := range stageConfigs {
+		if sc.Builtin == builtinBuild && config.SkipBuild {
+			continue
+		}
+		if sc.Builtin == builtinDependencies && (config.SkipBuild || config.SkipDependencies) {
+			continue
+		}
+		if tools.ToolConfig.ReadOnly && slices.Contains(commandStages, sc.Builtin) {
+			continue
+		}
+		spec, err := stageFromConfig(sc, config)
+		if err != nil {
+			return nil, fmt.Errorf("stage %d: %w", i, err)
+		}
+		if names[spec.Name] {
+			return nil, fmt.Errorf("stage %d: duplicate stage name %q", i, spec.Name)
+		}
+		names[spec.Name] = true
+		stages = append(stages, spec)
+	}
+	return stages, nil
+}
+
+// defaultStageConfigs returns the built-in stage list selected by config
+func defaultStageConfigs(config PipelineConfig) []StageConfig {
+	builtins := []string{builtinDesign}
+	if config.APIDesign {
+		builtins = append(builtins, builtinAPIDesign)
+	}
+	if config.Planner {
+		builtins = append(builtins, builtinPlanner)
+	}
+	builtins = append(builtins, builtinCodeWriter, builtinDependencies, builtinBuild, builtinTDDExpert)
+	if config.Documentation {
+		builtins = append(builtins, builtinDocumentation)
+	}
+	if config.Benchmarks {
+		builtins = append(builtins, builtinBenchmark)
+	}
+	if config.Deployment {
+		builtins = append(builtins, builtinDeployment)
+	}
+	if config.CIProvider != "" {
+		builtins = append(builtins, builtinCI)
+	}
+	if config.Lint {
+		builtins = append(builtins, builtinLint)
+	}
+	if config.SecurityReview {
+		builtins = append(builtins, builtinSecurity)
+	}
+	builtins = append(builtins, builtinCodeReviewer)
+	if config.MinScore > 0 {
+		builtins = append(builtins, builtinCritic)
+	}
+
+	stageConfigs := make([]StageConfig, 0, len(builtins))
+	for _, name := range builtins {
+		stageConfigs = append(stageConfigs, StageConfig{Builtin: name})
+	}
+	return stageConfigs
+}
+
+// stageFromConfig resolves a stage declaration into a stage spec. A stage without an
+// instruction takes the prompt named after it from the prompt library of config, and a
+// built-in stage falls back to the prompt of the stage it was created from.
+func stageFromConfig(sc StageConfig, config PipelineConfig) (stageSpec, error) {
+	workspaceDir := config.WorkspaceDir
+	if sc.InstructionFile != "" {
+		if sc.Instruction != "" {
+			return stageSpec{}, fmt.Errorf("instruction and instruction_file are mutually exclusive")
+		}
+		instruction, err := readInstructionFile(sc.InstructionFile)
+		if err != nil {
+			return stageSpec{}, err
+		}
+		sc.Instruction = instruction
+	}
+
+	var spec stageSpec
+	var builtinName string
+	if sc.Agent != "" {
+		if sc.Builtin != "" || sc.Name != "" {
+			return stageSpec{}, fmt.Errorf("agent cannot be combined with builtin or name")
+		}
+		var err error
+		if spec, err = registeredStage(sc.Agent); err != nil {
+			return stageSpec{}, err
+		}
+	} else if sc.Builtin != "" {
+		factory, ok := builtinStages[sc.Builtin]
+		if !ok {
+			return stageSpec{}, fmt.Errorf("unknown builtin stage %q", sc.Builtin)
+		}
+		spec = factory(workspaceDir)
+		spec.Builtin = sc.Builtin
+		builtinName = spec.Name
+	} else if sc.Name == "" || (sc.Instruction == "" && !prompts.New(config.PromptDir).Has(sc.Name)) {
+		return stageSpec{}, fmt.Errorf("custom stages require a name and an instruction")
+	}
+
+	if spec.Custom != nil && (sc.Instruction != "" || sc.OutputKey != "" || sc.Tools != nil) {
+		return stageSpec{}, fmt.Errorf("stage %q does not accept instruction, output_key, or tools", spec.Name)
+	}
+
+	if sc.Name != "" {
+		spec.Name = sc.Name
+	}
+	if sc.Description != "" {
+		spec.Description = sc.Description
+	}
+	if sc.Instruction != "" {
+		spec.Instruction = sc.Instruction
+	} else if spec.Custom == nil {
+		name := spec.Name
+		if builtinName != "" && !prompts.New(config.PromptDir).Has(name) {
+			name = builtinName
+		}
+		instruction, err := stagePrompt(config, name)
+		if err != nil {
+			return stageSpec{}, err
+		}
+		spec.Instruction = instruction
+	}
+	if sc.OutputKey != "" {
+		spec.OutputKey = sc.OutputKey
+	}
+	if sc.ParallelGroup != "" {
+		spec.ParallelGroup = sc.ParallelGroup
+	}
+	if sc.Tools != nil {
+		set := tools.NewSet(workspaceDir, tools.ToolConfig)
+		spec.Tools = make([]tool.Tool, 0, len(sc.Tools))
+		for _, name := range sc.Tools {
+			if name == envToolName {
+				spec.Tools = append(spec.Tools, tools.NewEnvTool(config.EnvVars))
+				continue
+			}
+			t, err := set.Tool(name)
+			if err != nil {
+				return stageSpec{}, err
+			}
+			spec.Tools = append(spec.Tools, t)
+		}
+	}
+	return spec, nil
+}
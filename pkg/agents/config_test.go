@@ -0,0 +1,290 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+)
+
+const testPipelineYAML = `name: LicensedPipeline
+description: Pipeline with a license stage
+skip_build: true
+skip_tests: true
+stages:
+  - builtin: design
+    instruction: "Design briefly: {design?}"
+  - name: LicenseAgent
+    description: Adds license headers
+    instruction: Add an MIT license header to every file in {design}.
+    output_key: license_report
+    tools: [fileRead, fileWrite]
+  - builtin: code_reviewer
+    name: StrictReviewer
+`
+
+func TestLoadPipelineConfig(t *testing.T) {
+	tests := []struct {
+		name      string
+		file      string
+		content   string
+		wantName  string
+		wantStage int
+		wantErr   bool
+	}{
+		{
+			name:      "yaml",
+			file:      "pipeline.yaml",
+			content:   testPipelineYAML,
+			wantName:  "LicensedPipeline",
+			wantStage: 3,
+		},
+		{
+			name:      "json",
+			file:      "pipeline.json",
+			content:   `{"name": "JSONPipeline", "min_coverage": 90, "stages": [{"builtin": "design"}, {"builtin": "code_reviewer"}]}`,
+			wantName:  "JSONPipeline",
+			wantStage: 2,
+		},
+		{
+			name:    "malformed",
+			file:    "broken.yaml",
+			content: "stages: [",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write config: %v", err)
+			}
+
+			config, err := LoadPipelineConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LoadPipelineConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if config.Name != tt.wantName {
+				t.Errorf("Name = %q, want %q", config.Name, tt.wantName)
+			}
+			if len(config.Stages) != tt.wantStage {
+				t.Errorf("got %d stages, want %d", len(config.Stages), tt.wantStage)
+			}
+		})
+	}
+
+	if _, err := LoadPipelineConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("LoadPipelineConfig() on a missing file returned no error")
+	}
+}
+
+func TestPipelineStages_Invalid(t *testing.T) {
+	tests := []struct {
+		name        string
+		stages      []StageConfig
+		errContains string
+	}{
+		{
+			name:        "unknown builtin",
+			stages:      []StageConfig{{Builtin: "deploy"}},
+			errContains: "unknown builtin stage",
+		},
+		{
+			name:        "unknown tool",
+			stages:      []StageConfig{{Name: "A", Instruction: "do it", Tools: []string{"rm"}}},
+			errContains: "unknown tool",
+		},
+		{
+			name:        "custom stage without instruction",
+			stages:      []StageConfig{{Name: "A"}},
+			errContains: "require a name and an instruction",
+		},
+		{
+			name:        "duplicate names",
+			stages:      []StageConfig{{Builtin: "design"}, {Name: "DesignAgent", Instruction: "again"}},
+			errContains: "duplicate stage name",
+		},
+		{
+			name:        "instruction on non-LLM builtin",
+			stages:      []StageConfig{{Builtin: "build", Instruction: "compile"}},
+			errContains: "does not accept",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipelineStages(PipelineConfig{WorkspaceDir: t.TempDir(), Stages: tt.stages})
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("pipelineStages() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestLoadPipeline_Run(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	content := testPipelineYAML + "workspace_dir: " + t.TempDir() + "\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mdl := fake.New("fake-model",
+		fake.Text("pkg/calc"),
+		fake.Text("Added headers"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := LoadPipeline(path, mdl)
+	if err != nil {
+		t.Fatalf("LoadPipeline() error = %v", err)
+	}
+	if got := pipeline.Name(); got != "LicensedPipeline" {
+		t.Errorf("Name() = %q, want %q", got, "LicensedPipeline")
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+
+	want := []string{"DesignAgent", "LicenseAgent", "StrictReviewer"}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, author := range want {
+		if events[i].Author != author {
+			t.Errorf("event %d author = %q, want %q", i, events[i].Author, author)
+		}
+	}
+
+	if got := stateString(t, state, "license_report"); got != "Added headers" {
+		t.Errorf("state[license_report] = %q, want %q", got, "Added headers")
+	}
+
+	// Overridden and custom instructions are used, with state placeholders resolved.
+	requests := mdl.Requests()
+	if got := requests[0].Config.SystemInstruction.Parts[0].Text; got != "Design briefly: " {
+		t.Errorf("design instruction = %q, want the override", got)
+	}
+	if got := requests[1].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "every file in pkg/calc") {
+		t.Errorf("custom stage instruction = %q, want the design substituted", got)
+	}
+	if got := len(requests[1].Tools); got != 2 {
+		t.Errorf("custom stage has %d tools, want 2", got)
+	}
+}
+
+func TestStageTools(t *testing.T) {
+	tests := []struct {
+		name        string
+		stageTools  func(dir string) map[string][]tool.Tool
+		wantTools   []string
+		errContains string
+	}{
+		{
+			name: "adds tools to a stage",
+			stageTools: func(dir string) map[string][]tool.Tool {
+				return map[string][]tool.Tool{"CodeReviewerAgent": {tools.NewExecToolWithWorkspace(dir)}}
+			},
+			wantTools: []string{"diff", "exec", "fileList", "fileRead", "goSymbols"},
+		},
+		{
+			name: "unknown stage",
+			stageTools: func(dir string) map[string][]tool.Tool {
+				return map[string][]tool.Tool{"GrepAgent": {tools.NewExecToolWithWorkspace(dir)}}
+			},
+			errContains: `stage tools for unknown stage "GrepAgent"`,
+		},
+		{
+			name: "non-LLM stage",
+			stageTools: func(dir string) map[string][]tool.Tool {
+				return map[string][]tool.Tool{"BuildAgent": {tools.NewExecToolWithWorkspace(dir)}}
+			},
+			errContains: `stage "BuildAgent" does not use tools`,
+		},
+		{
+			name: "duplicate tool",
+			stageTools: func(dir string) map[string][]tool.Tool {
+				return map[string][]tool.Tool{"CodeReviewerAgent": {tools.NewFileReadToolWithWorkspace(dir)}}
+			},
+			errContains: `stage "CodeReviewerAgent" already has a "fileRead" tool`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			mdl := fake.New("fake-model", fake.Text("No major issues found."))
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: dir,
+				Stages:       []StageConfig{{Builtin: builtinBuild}, {Builtin: builtinCodeReviewer}},
+				StageTools:   tt.stageTools(dir),
+			})
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("NewCodePipelineAgent() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			runAgent(t, pipeline, "Review the code")
+			var got []string
+			for name := range mdl.Requests()[0].Tools {
+				got = append(got, name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.wantTools) {
+				t.Errorf("reviewer tools = %v, want %v", got, tt.wantTools)
+			}
+		})
+	}
+}
+
+func TestScratchpad_AcrossStages(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.FunctionCall("scratchpad", map[string]any{"action": "set", "key": "files", "value": "calc.go"}),
+		fake.Text("Planned calc.go"),
+		fake.FunctionCall("scratchpad", map[string]any{"action": "get", "key": "files"}),
+		fake.Text("Created calc.go"),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		Stages: []StageConfig{
+			{Name: "PlanAgent", Instruction: "Plan the files", Tools: []string{"scratchpad"}},
+			{Name: "BuildAgent", Instruction: "Write the planned files: {scratchpad?}", Tools: []string{"scratchpad"}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, _ := runAgent(t, pipeline, "Build a calculator")
+
+	var got string
+	for _, event := range events {
+		if event.Content == nil || event.Author != "BuildAgent" {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			if r := part.FunctionResponse; r != nil && r.Name == "scratchpad" {
+				got, _ = r.Response["value"].(string)
+			}
+		}
+	}
+	if got != "calc.go" {
+		t.Errorf("BuildAgent read %q from the scratchpad, want calc.go", got)
+	}
+	if instruction := mdl.Requests()[2].Config.SystemInstruction.Parts[0].Text; !strings.Contains(instruction, "calc.go") {
+		t.Errorf("BuildAgent instruction = %q, want the scratchpad", instruction)
+	}
+}
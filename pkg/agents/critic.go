@@ -0,0 +1,148 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// qualityReport is the structured output of the critic stage
+type qualityReport struct {
+	// Correctness rates functional correctness and error handling from 0 to 100
+	Correctness float64 `json:"correctness"`
+	// Idioms rates idiomatic Go and code quality from 0 to 100
+	Idioms float64 `json:"idioms"`
+	// Tests rates test coverage and test quality from 0 to 100
+	Tests float64 `json:"tests"`
+	// Score is the overall score from 0 to 100
+	Score float64 `json:"score"`
+	// Issues lists the changes that would raise the score
+	Issues []string `json:"issues"`
+}
+
+// qualityReportSchema constrains the critic's response to a qualityReport
+var qualityReportSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"correctness": scoreSchema("Functional correctness and error handling"),
+		"idioms":      scoreSchema("Idiomatic Go and code quality"),
+		"tests":       scoreSchema("Test coverage and test quality"),
+		"score":       scoreSchema("Overall score"),
+		"issues": {
+			Type:        genai.TypeArray,
+			Description: "Specific changes that would raise the score, most important first",
+			Items:       &genai.Schema{Type: genai.TypeString},
+		},
+	},
+	Required: []string{"correctness", "idioms", "tests", "score", "issues"},
+}
+
+// scoreSchema returns the schema of a 0-100 score
+func scoreSchema(description string) *genai.Schema {
+	return &genai.Schema{
+		Type:        genai.TypeInteger,
+		Description: description,
+		Minimum:     genai.Ptr(0.0),
+		Maximum:     genai.Ptr(100.0),
+	}
+}
+
+// criticStage describes the critic agent stage
+func criticStage() stageSpec {
+	return stageSpec{
+		Name:         "CriticAgent",
+		Description:  "Scores the generated code from 0 to 100.",
+		OutputKey:    "quality_report",
+		OutputSchema: qualityReportSchema,
+		Instruction:  prompts.Default("CriticAgent"),
+	}
+}
+
+// qualityGateStage describes the stage that fails the run when the critic's score is
+// below minScore
+func qualityGateStage(minScore float64) stageSpec {
+	return stageSpec{
+		Name:        "QualityGateAgent",
+		Description: "Fails the run when the quality score is below the minimum.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newQualityGateAgent(minScore)
+		},
+	}
+}
+
+// withQualityGate inserts the quality gate stage after the critic stage
+func withQualityGate(stages []stageSpec, minScore float64) []stageSpec {
+	i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Builtin == builtinCritic })
+	if i < 0 {
+		return stages
+	}
+	return slices.Insert(stages, i+1, qualityGateStage(minScore))
+}
+
+// newQualityGateAgent creates an agent that stores the critic's overall score under the
+// quality_score state key and reports an error when it is below minScore
+func newQualityGateAgent(minScore float64) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "QualityGateAgent",
+		Description: "Fails the run when the quality score is below the minimum.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				report, err := parseQualityReport(readStateString(ctx.Session().State(), "quality_report"))
+				if err != nil {
+					slog.Warn("Could not parse quality report", "error", err)
+					event.LLMResponse.Content = genai.NewContentFromText("Quality gate failed: the quality report could not be read.", genai.RoleModel)
+					yield(event, fmt.Errorf("quality gate failed: %w", err))
+					return
+				}
+
+				slog.Info("Code quality scored",
+					"score", report.Score,
+					"min_score", minScore,
+					"correctness", report.Correctness,
+					"idioms", report.Idioms,
+					"tests", report.Tests)
+				event.Actions.StateDelta["quality_score"] = report.Score
+				summary := fmt.Sprintf("Quality score %.0f (correctness %.0f, idioms %.0f, tests %.0f); required %.0f.",
+					report.Score, report.Correctness, report.Idioms, report.Tests, minScore)
+				event.LLMResponse.Content = genai.NewContentFromText(summary, genai.RoleModel)
+				if report.Score < minScore {
+					yield(event, fmt.Errorf("quality score %.0f is below the required %.0f", report.Score, minScore))
+					return
+				}
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// parseQualityReport decodes a critic response, tolerating a Markdown code fence. A
+// missing overall score is the average of the category scores.
+func parseQualityReport(text string) (qualityReport, error) {
+	var report qualityReport
+	if err := json.Unmarshal([]byte(trimJSONFence(text)), &report); err != nil {
+		return qualityReport{}, fmt.Errorf("invalid quality report: %w", err)
+	}
+	if report.Score == 0 {
+		report.Score = (report.Correctness + report.Idioms + report.Tests) / 3
+	}
+	report.Score = min(max(report.Score, 0), 100)
+	return report, nil
+}
+
+// qualityApproved reports whether the latest quality report scores at least minScore;
+// a zero minScore disables the check
+func qualityApproved(state stateReader, minScore float64) bool {
+	if minScore <= 0 {
+		return true
+	}
+	report, err := parseQualityReport(readStateString(state, "quality_report"))
+	return err == nil && report.Score >= minScore
+}
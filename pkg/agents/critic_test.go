@@ -0,0 +1,189 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestParseQualityReport(t *testing.T) {
+	tests := []struct {
+		name      string
+		text      string
+		wantScore float64
+		wantErr   bool
+	}{
+		{
+			name:      "plain JSON",
+			text:      `{"correctness": 90, "idioms": 80, "tests": 70, "score": 85, "issues": []}`,
+			wantScore: 85,
+		},
+		{
+			name:      "code fence",
+			text:      "```json\n{\"correctness\": 90, \"idioms\": 80, \"tests\": 70, \"score\": 82, \"issues\": [\"add tests\"]}\n```",
+			wantScore: 82,
+		},
+		{
+			name:      "missing overall score",
+			text:      `{"correctness": 90, "idioms": 60, "tests": 60}`,
+			wantScore: 70,
+		},
+		{
+			name:      "out of range",
+			text:      `{"score": 140}`,
+			wantScore: 100,
+		},
+		{
+			name:    "not JSON",
+			text:    "The code looks great!",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := parseQualityReport(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQualityReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if report.Score != tt.wantScore {
+				t.Errorf("Score = %v, want %v", report.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestQualityApproved(t *testing.T) {
+	tests := []struct {
+		name     string
+		state    mapState
+		minScore float64
+		want     bool
+	}{
+		{name: "gate disabled", state: mapState{}, minScore: 0, want: true},
+		{name: "no report yet", state: mapState{}, minScore: 80, want: false},
+		{name: "unreadable report", state: mapState{"quality_report": "great"}, minScore: 80, want: false},
+		{name: "below", state: mapState{"quality_report": `{"score": 79}`}, minScore: 80, want: false},
+		{name: "met", state: mapState{"quality_report": `{"score": 80}`}, minScore: 80, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := qualityApproved(tt.state, tt.minScore); got != tt.want {
+				t.Errorf("qualityApproved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCritic_FailsBelowMinScore(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+		fake.Text(`{"correctness": 60, "idioms": 50, "tests": 40, "score": 55, "issues": ["handle errors"]}`),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		MinScore:     80,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+	if runErr == nil || !strings.Contains(runErr.Error(), "quality score 55 is below the required 80") {
+		t.Fatalf("Run() error = %v, want quality gate failure", runErr)
+	}
+
+	criticReq := mdl.Requests()[4]
+	if criticReq.Config.ResponseSchema == nil || criticReq.Config.ResponseMIMEType != "application/json" {
+		t.Error("critic request does not ask for structured JSON output")
+	}
+}
+
+func TestCritic_LoopRepairsUntilMinScore(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+		fake.Text(`{"correctness": 70, "idioms": 60, "tests": 50, "score": 60, "issues": ["test Div by zero"]}`),
+		fake.Text("## Fixes Applied\n- added a Div by zero test"),
+		fake.Text("No major issues found."),
+		fake.Text(`{"correctness": 95, "idioms": 90, "tests": 85, "score": 90, "issues": []}`),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		LoopPipeline: true,
+		MinScore:     80,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+	if runErr != nil {
+		t.Fatalf("Run() error = %v", runErr)
+	}
+	if got := mdl.Calls(); got != 8 {
+		t.Errorf("model calls = %d, want 8", got)
+	}
+	var authors []string
+	for _, event := range events {
+		authors = append(authors, event.Author)
+	}
+	want := "DesignAgent,CodeWriterAgent,TDDExpertAgent," +
+		"CodeReviewerAgent,CriticAgent,FixerAgent,CodeReviewerAgent,CriticAgent,ReviewGateAgent,QualityGateAgent"
+	if strings.Join(authors, ",") != want {
+		t.Errorf("authors = %v, want %s", authors, want)
+	}
+
+	// The fixer sees the critic's issues
+	if got := mdl.Requests()[5].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "test Div by zero") {
+		t.Errorf("fixer instruction does not contain the quality report: %q", got)
+	}
+}
+
+// runAgentCollectingError runs ag once with prompt and returns the emitted events and
+// the last error yielded by the run.
+func runAgentCollectingError(t *testing.T, ag agent.Agent, prompt string) ([]*session.Event, error) {
+	t.Helper()
+	ctx := context.Background()
+
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{AppName: "test-app", Agent: ag, SessionService: sessionService})
+	if err != nil {
+		t.Fatalf("runner.New() error = %v", err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "test-app", UserID: "test-user"})
+	if err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+
+	var events []*session.Event
+	var runErr error
+	msg := genai.NewContentFromText(prompt, genai.RoleUser)
+	for event, err := range r.Run(ctx, "test-user", created.Session.ID(), msg, agent.RunConfig{}) {
+		if err != nil {
+			runErr = err
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, runErr
+}
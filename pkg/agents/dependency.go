@@ -0,0 +1,77 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// dependencyStage describes the dependency management stage
+func dependencyStage() stageSpec {
+	return stageSpec{
+		Name:        "DependencyAgent",
+		Description: "Creates go.mod and resolves the dependencies of the generated code.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newDependencyAgent("DependencyAgent", config.WorkspaceDir)
+		},
+	}
+}
+
+// newDependencyAgent creates an agent named name that runs `go mod tidy` in the workspace
+// and stores the result under the dependency_status and dependency_output state keys.
+// The status uses the build status values.
+func newDependencyAgent(name, workspaceDir string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        name,
+		Description: "Creates go.mod and resolves the dependencies of the generated code.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				status, output := runDependencies(ctx, workspaceDir)
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(output, genai.RoleModel)
+				event.Actions.StateDelta["dependency_status"] = status
+				event.Actions.StateDelta["dependency_output"] = output
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// runDependencies initializes the workspace module if needed and tidies its requirements,
+// returning the status and a summary for the next stages
+func runDependencies(ctx context.Context, workspaceDir string) (string, string) {
+	if err := ensureGoModule(ctx, workspaceDir); err != nil {
+		slog.Warn("Skipping dependency resolution, workspace module could not be initialized", "error", err)
+		return buildStatusSkipped, fmt.Sprintf("Dependency resolution skipped: %v", err)
+	}
+
+	result, err := tools.RunGoMod(ctx, workspaceDir, tools.GoModInput{Operation: tools.GoModTidy})
+	if err != nil {
+		slog.Warn("Skipping dependency resolution, go mod tidy could not be run", "error", err)
+		return buildStatusSkipped, fmt.Sprintf("Dependency resolution skipped: %v", err)
+	}
+
+	if result.Success {
+		slog.Info("Dependencies resolved", "workspace", workspaceDir, "added", len(result.Added))
+		output := "Dependencies resolved: `go mod tidy` reported no errors."
+		if len(result.Added) > 0 {
+			modules := make([]string, 0, len(result.Added))
+			for _, module := range result.Added {
+				modules = append(modules, module.Path+" "+module.Version)
+			}
+			output += "\n\nAdded modules: " + strings.Join(modules, ", ")
+		}
+		return buildStatusPassed, output
+	}
+
+	slog.Warn("Dependency resolution failed", "workspace", workspaceDir, "exit_code", result.ExitCode)
+	return buildStatusFailed, fmt.Sprintf("Dependency resolution failed: `go mod tidy` exited with code %d.\n\n```\n%s\n```", result.ExitCode, result.Output)
+}
@@ -0,0 +1,107 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunDependencies(t *testing.T) {
+	// Keep module resolution offline
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+
+	tests := []struct {
+		name         string
+		files        map[string]string
+		wantStatus   string
+		wantContains string
+	}{
+		{
+			name:       "empty workspace",
+			wantStatus: buildStatusPassed,
+		},
+		{
+			name: "standard library only",
+			files: map[string]string{
+				"pkg/greet/greet.go": "package greet\n\nimport \"fmt\"\n\n// Hello greets name\nfunc Hello(name string) string { return fmt.Sprintf(\"Hello, %s\", name) }\n",
+			},
+			wantStatus: buildStatusPassed,
+		},
+		{
+			name: "local module",
+			files: map[string]string{
+				"go.mod":     "module example.com/greet\n\ngo 1.21\n\nreplace example.com/dep => ./dep\n",
+				"dep/go.mod": "module example.com/dep\n\ngo 1.21\n",
+				"dep/dep.go": "package dep\n\n// Name is the module name\nconst Name = \"dep\"\n",
+				"greet.go":   "package greet\n\nimport \"example.com/dep\"\n\nvar _ = dep.Name\n",
+			},
+			wantStatus:   buildStatusPassed,
+			wantContains: "Added modules: example.com/dep v0.0.0-",
+		},
+		{
+			name: "unresolvable import",
+			files: map[string]string{
+				"go.mod":             "module example.com/greet\n\ngo 1.21\n",
+				"pkg/greet/greet.go": "package greet\n\nimport _ \"example.invalid/missing\"\n",
+			},
+			wantStatus:   buildStatusFailed,
+			wantContains: "example.invalid/missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, tt.files)
+
+			status, output := runDependencies(context.Background(), workspaceDir)
+			if status != tt.wantStatus {
+				t.Errorf("runDependencies() status = %q, want %q (output: %s)", status, tt.wantStatus, output)
+			}
+			if !strings.Contains(output, tt.wantContains) {
+				t.Errorf("runDependencies() output = %q, want it to contain %q", output, tt.wantContains)
+			}
+			if _, err := os.Stat(filepath.Join(workspaceDir, "go.mod")); err != nil {
+				t.Errorf("go.mod missing after dependency resolution: %v", err)
+			}
+		})
+	}
+}
+
+func TestPipelineStages_Dependencies(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   bool
+	}{
+		{name: "enabled by default", config: PipelineConfig{}, want: true},
+		{name: "skip dependencies", config: PipelineConfig{SkipDependencies: true}, want: false},
+		{name: "skip build", config: PipelineConfig{SkipBuild: true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			found := false
+			for i, spec := range stages {
+				if spec.Name != "DependencyAgent" {
+					continue
+				}
+				found = true
+				if next := stages[i+1].Name; next != "BuildAgent" {
+					t.Errorf("DependencyAgent is followed by %s, want BuildAgent", next)
+				}
+			}
+			if found != tt.want {
+				t.Errorf("DependencyAgent present = %v, want %v", found, tt.want)
+			}
+		})
+	}
+}
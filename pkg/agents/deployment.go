@@ -0,0 +1,39 @@
+package agents
+
+import (
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// deploymentStage describes the deployment agent stage
+func deploymentStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "DeploymentAgent",
+		Description: "Writes a Dockerfile and docker-compose.yaml for the generated service.",
+		OutputKey:   "deployment",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("DeploymentAgent"),
+	}
+}
+
+// kubernetesInstruction is appended to the deployment stage when Kubernetes manifests are requested
+const kubernetesInstruction = `
+
+**Kubernetes Manifests (also required):**
+- deploy/k8s/deployment.yaml: a Deployment with resource requests and limits, readiness and liveness probes on the health endpoint (or a TCP probe), runAsNonRoot, readOnlyRootFilesystem, and environment from a ConfigMap
+- deploy/k8s/service.yaml: a ClusterIP Service for each exposed port
+- deploy/k8s/configmap.yaml: the non-secret environment variables
+- deploy/k8s/kustomization.yaml: listing the manifests above
+List the manifests under Deployment Files and add "kubectl apply -k deploy/k8s" to Usage.`
+
+// withKubernetes extends the deployment stage to write Kubernetes manifests
+func withKubernetes(spec stageSpec) stageSpec {
+	spec.Description = "Writes a Dockerfile, docker-compose.yaml, and Kubernetes manifests for the generated service."
+	spec.Instruction += kubernetesInstruction
+	return spec
+}
@@ -0,0 +1,99 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestPipelineStages_Deployment(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			config: PipelineConfig{SkipBuild: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "after the tests",
+			config: PipelineConfig{SkipBuild: true, Deployment: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "DeploymentAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "before lint and security review",
+			config: PipelineConfig{SkipBuild: true, Deployment: true, Lint: true, SecurityReview: true},
+			want: []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "DeploymentAgent",
+				"LintAgent", "SecurityReviewAgent", "CodeReviewerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			var names []string
+			for _, spec := range stages {
+				names = append(names, spec.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("stages = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeployment_Run(t *testing.T) {
+	tests := []struct {
+		name           string
+		kubernetes     bool
+		wantKubernetes bool
+	}{
+		{name: "docker only"},
+		{name: "with kubernetes", kubernetes: true, wantKubernetes: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summary := "## Deployment Files\n- Dockerfile multi-stage build"
+			mdl := fake.New("fake-model",
+				fake.Text("design"),
+				fake.Text("Created cmd/server/main.go"),
+				fake.Text("Created cmd/server/main_test.go"),
+				fake.Text(summary),
+				fake.Text("No major issues found."),
+			)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				SkipBuild:    true,
+				SkipTests:    true,
+				Deployment:   true,
+				Kubernetes:   tt.kubernetes,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			_, state := runAgent(t, pipeline, "Build a REST service")
+
+			if got := stateString(t, state, "deployment"); got != summary {
+				t.Errorf("state[deployment] = %q, want %q", got, summary)
+			}
+			instruction := mdl.Requests()[3].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(instruction, "docker-compose.yaml") {
+				t.Error("deployment instruction does not ask for docker-compose.yaml")
+			}
+			if got := strings.Contains(instruction, "deploy/k8s/deployment.yaml"); got != tt.wantKubernetes {
+				t.Errorf("instruction asks for Kubernetes manifests = %v, want %v", got, tt.wantKubernetes)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// drainMessage is shown to the user when a run stops because the server is shutting down
+const drainMessage = "The server is shutting down, so the run stopped before %s. Send the same request again to continue."
+
+// drainCheckpointMessage replaces drainMessage when the completed stages are checkpointed
+const drainCheckpointMessage = "The server is shutting down, so the run stopped before %s. Send the same request again to resume after the completed stages."
+
+// newDrainAgent wraps the stage agent inner so that it does not start once drain is
+// closed. It escalates instead, which ends the pipeline like the approval stage does;
+// a stage that already started runs to completion.
+func newDrainAgent(inner agent.Agent, drain <-chan struct{}, checkpointed bool) (agent.Agent, error) {
+	stage := stageName(inner)
+	message := drainMessage
+	if checkpointed {
+		message = drainCheckpointMessage
+	}
+	return agent.New(agent.Config{
+		Name:        inner.Name() + "Drain",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				select {
+				case <-drain:
+					slog.Info("Stopping run before stage, draining", "stage", stage)
+					event := session.NewEvent(ctx.InvocationID())
+					event.LLMResponse.Content = genai.NewContentFromText(fmt.Sprintf(message, stage), genai.RoleModel)
+					event.Actions.Escalate = true
+					yield(event, nil)
+					return
+				default:
+				}
+
+				for event, err := range inner.Run(ctx) {
+					if !yield(event, err) {
+						return
+					}
+				}
+			}
+		},
+	})
+}
@@ -0,0 +1,73 @@
+package agents
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+// drainingListener closes drain when stage completes
+type drainingListener struct {
+	recordingListener
+	stage string
+	drain chan struct{}
+}
+
+func (l *drainingListener) StageCompleted(stage string, err error) {
+	l.recordingListener.StageCompleted(stage, err)
+	if stage == l.stage {
+		close(l.drain)
+	}
+}
+
+func TestDrain_StopsBeforeNextStage(t *testing.T) {
+	tests := []struct {
+		name          string
+		checkpointDir string
+		want          string
+	}{
+		{name: "without checkpoints", want: "Send the same request again to continue."},
+		{name: "with checkpoints", checkpointDir: filepath.Join(t.TempDir(), "checkpoint"), want: "resume after the completed stages"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drain := make(chan struct{})
+			listener := &drainingListener{stage: "DesignAgent", drain: drain}
+			mdl := fake.New("fake-model", fake.Text("design: pkg/calc"), fake.Text("Created pkg/calc/calc.go"))
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:         mdl,
+				WorkspaceDir:  t.TempDir(),
+				CheckpointDir: tt.checkpointDir,
+				Stages:        []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+				Progress:      listener,
+				Drain:         drain,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			events, _ := runAgent(t, pipeline, "Build a calculator package")
+
+			if got := mdl.Calls(); got != 1 {
+				t.Errorf("model calls = %d, want 1 (the code writer does not start)", got)
+			}
+			last := events[len(events)-1]
+			text := contentText(last.LLMResponse.Content)
+			if !last.Actions.Escalate || !strings.Contains(text, "before CodeWriterAgent") || !strings.Contains(text, tt.want) {
+				t.Errorf("last event = %q escalate=%v, want an escalation stopping before CodeWriterAgent with %q", text, last.Actions.Escalate, tt.want)
+			}
+			if tt.checkpointDir == "" {
+				return
+			}
+			cp, err := loadCheckpoint(tt.checkpointDir)
+			if err != nil || cp == nil {
+				t.Fatalf("loadCheckpoint() = %v, %v; want the checkpoint of the completed design", cp, err)
+			}
+			if len(cp.CompletedStages) != 1 || cp.State["design"] != "design: pkg/calc" {
+				t.Errorf("checkpoint = %+v, want the completed design stage", cp)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// charsPerToken is the rough number of characters per token used for estimates
+const charsPerToken = 4
+
+// PipelinePlan describes the stages a pipeline would run, without running them
+type PipelinePlan struct {
+	// Name is the name of the pipeline agent
+	Name string `json:"name"`
+	// Stages are the agents of the pipeline in depth-first order, starting with the pipeline itself
+	Stages []PlannedStage `json:"stages"`
+	// EstimatedPromptTokens is the sum of the stage estimates, a lower bound for one pass through the pipeline
+	EstimatedPromptTokens int `json:"estimated_prompt_tokens"`
+	// MaxTotalTokens is the configured token limit of a run (0 is unlimited)
+	MaxTotalTokens int `json:"max_total_tokens,omitempty"`
+	// MaxCost is the configured cost limit of a run in US dollars (0 is unlimited)
+	MaxCost float64 `json:"max_cost,omitempty"`
+}
+
+// PlannedStage describes an agent of a planned pipeline
+type PlannedStage struct {
+	// Name is the agent name
+	Name string `json:"name"`
+	// Description is the agent description
+	Description string `json:"description,omitempty"`
+	// Depth is the nesting level of the agent below the pipeline
+	Depth int `json:"depth"`
+	// Model is the name of the model an LLM stage calls (empty for other agents)
+	Model string `json:"model,omitempty"`
+	// Tools are the names of the tools and toolsets of an LLM stage
+	Tools []string `json:"tools,omitempty"`
+	// OutputKey is the state key an LLM stage stores its response under
+	OutputKey string `json:"output_key,omitempty"`
+	// Instruction is the instruction template of an LLM stage
+	Instruction string `json:"instruction,omitempty"`
+	// EstimatedPromptTokens estimates the instruction tokens of each model call; session history and tool results add to it
+	EstimatedPromptTokens int `json:"estimated_prompt_tokens,omitempty"`
+}
+
+// stageRecorder records the LLM stages created while a pipeline is built, keyed by name
+type stageRecorder struct {
+	stages map[string]PlannedStage
+}
+
+// record records the LLM stage of spec calling the model named modelName
+func (r *stageRecorder) record(spec stageSpec, modelName string) {
+	toolNames := make([]string, 0, len(spec.Tools)+len(spec.Toolsets))
+	for _, t := range spec.Tools {
+		toolNames = append(toolNames, t.Name())
+	}
+	for _, ts := range spec.Toolsets {
+		toolNames = append(toolNames, ts.Name())
+	}
+	r.stages[spec.Name] = PlannedStage{
+		Name:                  spec.Name,
+		Model:                 modelName,
+		Tools:                 toolNames,
+		OutputKey:             spec.OutputKey,
+		Instruction:           spec.Instruction,
+		EstimatedPromptTokens: (len(spec.Instruction) + charsPerToken - 1) / charsPerToken,
+	}
+}
+
+// PlanPipeline validates config and describes the pipeline NewCodePipelineAgent would
+// create from it: its stages, their models, tools, and prompts, and an estimate of the
+// prompt tokens. No model is called, though model capabilities may be looked up.
+func PlanPipeline(config PipelineConfig) (*PipelinePlan, error) {
+	recorder := &stageRecorder{stages: make(map[string]PlannedStage)}
+	config.DryRun = false
+	config.recorder = recorder
+	pipeline, err := NewCodePipelineAgent(config)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &PipelinePlan{
+		Name:           pipeline.Name(),
+		MaxTotalTokens: config.MaxTotalTokens,
+		MaxCost:        config.MaxCost,
+	}
+	var walk func(ag agent.Agent, depth int)
+	walk = func(ag agent.Agent, depth int) {
+		stage, ok := recorder.stages[ag.Name()]
+		if !ok {
+			stage = PlannedStage{Name: ag.Name()}
+		}
+		stage.Description = ag.Description()
+		stage.Depth = depth
+		plan.Stages = append(plan.Stages, stage)
+		plan.EstimatedPromptTokens += stage.EstimatedPromptTokens
+		for _, sub := range ag.SubAgents() {
+			walk(sub, depth+1)
+		}
+	}
+	walk(pipeline, 0)
+	return plan, nil
+}
+
+// String formats the plan as Markdown: the stage tree followed by the prompt of each LLM stage
+func (p *PipelinePlan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Plan of %s (dry run, no model calls)\n\n", p.Name)
+	for _, stage := range p.Stages {
+		fmt.Fprintf(&b, "%s- %s", strings.Repeat("  ", stage.Depth), stage.Name)
+		if stage.Model != "" {
+			fmt.Fprintf(&b, " [model %s", stage.Model)
+			if len(stage.Tools) > 0 {
+				fmt.Fprintf(&b, ", tools %s", strings.Join(stage.Tools, ", "))
+			}
+			if stage.OutputKey != "" {
+				fmt.Fprintf(&b, ", output %s", stage.OutputKey)
+			}
+			fmt.Fprintf(&b, ", ~%d prompt tokens]", stage.EstimatedPromptTokens)
+		}
+		b.WriteString("\n")
+	}
+
+	fmt.Fprintf(&b, "\nEstimated prompt tokens for one pass: ~%d", p.EstimatedPromptTokens)
+	var limits []string
+	if p.MaxTotalTokens > 0 {
+		limits = append(limits, fmt.Sprintf("%d tokens", p.MaxTotalTokens))
+	}
+	if p.MaxCost > 0 {
+		limits = append(limits, fmt.Sprintf("$%.2f", p.MaxCost))
+	}
+	if len(limits) > 0 {
+		fmt.Fprintf(&b, " (run limits: %s)", strings.Join(limits, ", "))
+	}
+	b.WriteString("\n")
+
+	for _, stage := range p.Stages {
+		if stage.Model == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s prompt\n\n%s\n", stage.Name, stage.Instruction)
+	}
+	return b.String()
+}
+
+// newDryRunAgent plans the pipeline of config and returns an agent that answers every
+// request with the plan, stored under the pipeline_plan state key, without calling a model
+func newDryRunAgent(config PipelineConfig) (agent.Agent, error) {
+	plan, err := PlanPipeline(config)
+	if err != nil {
+		return nil, err
+	}
+	text := plan.String()
+	slog.Info("Created dry-run pipeline", "name", plan.Name, "stages", len(plan.Stages),
+		"estimated_prompt_tokens", plan.EstimatedPromptTokens)
+	return agent.New(agent.Config{
+		Name:        plan.Name,
+		Description: "Describes the stages the pipeline would run, without running them.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(text, genai.RoleModel)
+				event.Actions.StateDelta["pipeline_plan"] = text
+				yield(event, nil)
+			}
+		},
+	})
+}
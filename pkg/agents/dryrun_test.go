@@ -0,0 +1,172 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+)
+
+func TestPlanPipeline(t *testing.T) {
+	mdl := fake.New("fake-model")
+	large := fake.New("large-model")
+	plan, err := PlanPipeline(PipelineConfig{
+		Model:          mdl,
+		Models:         map[string]model.LLM{"large": large},
+		ModelRouting:   ModelRouting{Critical: "large"},
+		WorkspaceDir:   t.TempDir(),
+		LoopPipeline:   true,
+		MaxTotalTokens: 50000,
+	})
+	if err != nil {
+		t.Fatalf("PlanPipeline() error = %v", err)
+	}
+	if mdl.Calls() != 0 || large.Calls() != 0 {
+		t.Errorf("planning called the models %d and %d times, want 0", mdl.Calls(), large.Calls())
+	}
+
+	if plan.Name != "CodePipelineAgent" || plan.Stages[0].Name != plan.Name || plan.Stages[0].Depth != 0 {
+		t.Errorf("plan starts with %+v, want the CodePipelineAgent root", plan.Stages[0])
+	}
+	stages := make(map[string]PlannedStage, len(plan.Stages))
+	total := 0
+	for _, stage := range plan.Stages {
+		stages[stage.Name] = stage
+		total += stage.EstimatedPromptTokens
+	}
+	if plan.EstimatedPromptTokens != total || total == 0 {
+		t.Errorf("EstimatedPromptTokens = %d, want the stage sum %d", plan.EstimatedPromptTokens, total)
+	}
+	if plan.MaxTotalTokens != 50000 {
+		t.Errorf("MaxTotalTokens = %d, want 50000", plan.MaxTotalTokens)
+	}
+
+	tests := []struct {
+		stage     string
+		model     string
+		tools     []string
+		outputKey string
+	}{
+		{stage: "DesignAgent", model: "large-model", outputKey: "design"},
+		{stage: "CodeWriterAgent", model: "fake-model", tools: []string{"fileRead", "fileWrite"}, outputKey: "generated_code"},
+		{stage: "TDDExpertAgent", model: "fake-model", tools: []string{"fileRead", "fileWrite", "goSymbols", "goRace"}, outputKey: "test_code"},
+		{stage: "CodeReviewerAgent", model: "large-model", tools: []string{"fileRead", "fileList", "diff", "goSymbols"}, outputKey: "review_comments"},
+		{stage: "FixerAgent", model: "fake-model"},
+		{stage: "BuildAgent"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.stage, func(t *testing.T) {
+			stage, ok := stages[tt.stage]
+			if !ok {
+				t.Fatalf("plan has no %s", tt.stage)
+			}
+			if stage.Model != tt.model {
+				t.Errorf("Model = %q, want %q", stage.Model, tt.model)
+			}
+			if tt.tools != nil && !slices.Equal(stage.Tools, tt.tools) {
+				t.Errorf("Tools = %v, want %v", stage.Tools, tt.tools)
+			}
+			if tt.outputKey != "" && stage.OutputKey != tt.outputKey {
+				t.Errorf("OutputKey = %q, want %q", stage.OutputKey, tt.outputKey)
+			}
+			if (stage.Instruction != "") != (tt.model != "") {
+				t.Errorf("Instruction = %q, want one only for LLM stages", stage.Instruction)
+			}
+			if stage.Depth == 0 {
+				t.Errorf("Depth = 0, want the stage nested under the pipeline")
+			}
+		})
+	}
+}
+
+func TestPlanPipeline_Invalid(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+	}{
+		{name: "no model", config: PipelineConfig{}},
+		{name: "conflicting modes", config: PipelineConfig{Model: fake.New("fake-model"), LoopPipeline: true, FixOnReview: true}},
+		{name: "unknown routed model", config: PipelineConfig{Model: fake.New("fake-model"), ModelRouting: ModelRouting{Bulk: "small"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := PlanPipeline(tt.config); err == nil {
+				t.Errorf("PlanPipeline() error = nil, want an error")
+			}
+			tt.config.DryRun = true
+			if _, err := NewCodePipelineAgent(tt.config); err == nil {
+				t.Errorf("NewCodePipelineAgent() with DryRun error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestNewCodePipelineAgent_DryRun(t *testing.T) {
+	mdl := fake.New("fake-model")
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{Model: mdl, WorkspaceDir: t.TempDir(), SkipBuild: true, DryRun: true})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	if pipeline.Name() != "CodePipelineAgent" {
+		t.Errorf("Name() = %q, want CodePipelineAgent", pipeline.Name())
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if mdl.Calls() != 0 {
+		t.Errorf("dry run called the model %d times, want 0", mdl.Calls())
+	}
+
+	text := events[0].Content.Parts[0].Text
+	for _, want := range []string{
+		"# Plan of CodePipelineAgent (dry run, no model calls)",
+		"  - DesignAgent [model fake-model, output design, ~",
+		"  - CodeWriterAgentSyntaxCheck\n    - CodeWriterAgent [model fake-model, tools fileRead, fileWrite, output generated_code, ~",
+		"Estimated prompt tokens for one pass: ~",
+		"## CodeReviewerAgent prompt\n\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("plan = %q, want it to contain %q", text, want)
+		}
+	}
+	if strings.Contains(text, "BuildAgent") {
+		t.Errorf("plan lists the skipped BuildAgent: %q", text)
+	}
+	if got := stateString(t, state, "pipeline_plan"); got != text {
+		t.Errorf("state[pipeline_plan] = %q, want the plan", got)
+	}
+}
+
+func TestPipelinePlan_String(t *testing.T) {
+	plan := &PipelinePlan{
+		Name: "Pipeline",
+		Stages: []PlannedStage{
+			{Name: "Pipeline"},
+			{Name: "DesignAgent", Depth: 1, Model: "m", Instruction: "Design it.", EstimatedPromptTokens: 3},
+			{Name: "BuildAgent", Depth: 1},
+		},
+		EstimatedPromptTokens: 3,
+		MaxTotalTokens:        1000,
+		MaxCost:               2.5,
+	}
+	want := `# Plan of Pipeline (dry run, no model calls)
+
+- Pipeline
+  - DesignAgent [model m, ~3 prompt tokens]
+  - BuildAgent
+
+Estimated prompt tokens for one pass: ~3 (run limits: 1000 tokens, $2.50)
+
+## DesignAgent prompt
+
+Design it.
+`
+	if got := plan.String(); got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,213 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// designEnsembleName is the name of the stage that replaces the design stage when the
+// pipeline generates several candidate designs
+const designEnsembleName = "DesignEnsembleAgent"
+
+// designCandidate is the result of one candidate design run
+type designCandidate struct {
+	design string
+	usage  genai.GenerateContentResponseUsageMetadata
+	err    error
+}
+
+// withDesignEnsemble replaces the design stage with one that generates n candidate
+// designs concurrently, cycling through models (the design model when empty), and has
+// the design stage judge them.
+// Only the judged design reaches later stages: the candidates are stored under the
+// design_candidate_<n> state keys and their responses are kept out of the session
+// history.
+func withDesignEnsemble(design stageSpec, n int, models []model.LLM) stageSpec {
+	candidates := make([]stageSpec, n)
+	for i := range candidates {
+		candidates[i] = stageSpec{
+			Name:        fmt.Sprintf("DesignCandidate%dAgent", i+1),
+			Description: fmt.Sprintf("Drafts candidate design %d.", i+1),
+			Instruction: design.Instruction,
+			OutputKey:   fmt.Sprintf("design_candidate_%d", i+1),
+			Model:       design.Model,
+			// Budget limits apply to every model call, but the approval, commit, and
+			// compaction callbacks belong to the judged design
+			BeforeModelCallbacks: design.BeforeModelCallbacks,
+			AfterModelCallbacks:  design.AfterModelCallbacks,
+		}
+		if len(models) > 0 {
+			candidates[i].Model = models[i%len(models)]
+		}
+	}
+
+	judge := design
+	judge.Instruction = designJudgeInstruction(n)
+	if strings.Contains(design.Instruction, "{design_feedback?}") {
+		judge.Instruction += `
+
+**Reviewer Feedback on the Previous Design (address all of it if present):**
+{design_feedback?}`
+	}
+
+	return stageSpec{
+		Name:          designEnsembleName,
+		Description:   fmt.Sprintf("Drafts %d candidate designs and judges them into one.", n),
+		OutputKey:     design.OutputKey,
+		ParallelGroup: design.ParallelGroup,
+		Builtin:       design.Builtin,
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			candidateAgents := make([]agent.Agent, 0, len(candidates))
+			for _, spec := range candidates {
+				ag, err := newStage(config, spec)
+				if err != nil {
+					return nil, fmt.Errorf("failed to create %s: %w", spec.Name, err)
+				}
+				candidateAgents = append(candidateAgents, ag)
+			}
+			judgeAgent, err := newLLMStage(config, judge)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", judge.Name, err)
+			}
+			return newDesignEnsembleAgent(candidateAgents, candidates, judgeAgent)
+		},
+	}
+}
+
+// newDesignEnsembleAgent creates an agent that runs the candidate agents concurrently,
+// records their designs in state, and then runs judge. specs describe the candidates.
+func newDesignEnsembleAgent(candidates []agent.Agent, specs []stageSpec, judge agent.Agent) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        designEnsembleName,
+		Description: fmt.Sprintf("Drafts %d candidate designs and judges them into one.", len(candidates)),
+		SubAgents:   append(append([]agent.Agent{}, candidates...), judge),
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				state := ctx.Session().State()
+				if readStateString(state, "design_approval") == approvalPending {
+					reply := contentText(ctx.UserContent())
+					if isApproval(reply) {
+						// The judge reuses the pending design
+						for event, err := range judge.Run(ctx) {
+							if !yield(event, err) || err != nil {
+								return
+							}
+						}
+						return
+					}
+					// The candidates revise their designs with the feedback
+					event := session.NewEvent(ctx.InvocationID())
+					event.Actions.StateDelta["design_feedback"] = reply
+					if !yield(event, nil) {
+						return
+					}
+				}
+
+				slog.Info("Drafting candidate designs", "candidates", len(candidates))
+				results := runDesignCandidates(ctx, candidates, specs)
+
+				var errs []error
+				for i, result := range results {
+					if result.err != nil {
+						slog.Warn("Candidate design failed", "candidate", candidates[i].Name(), "error", result.err)
+						errs = append(errs, fmt.Errorf("%s: %w", candidates[i].Name(), result.err))
+					}
+					// Only the design is recorded, so later stages never see the candidates
+					event := session.NewEvent(ctx.InvocationID())
+					event.Author = candidates[i].Name()
+					event.Actions.StateDelta[specs[i].OutputKey] = result.design
+					if result.usage.TotalTokenCount > 0 {
+						usage := result.usage
+						event.UsageMetadata = &usage
+					}
+					if !yield(event, nil) {
+						return
+					}
+				}
+				if len(errs) == len(candidates) {
+					err := fmt.Errorf("every candidate design failed: %w", errors.Join(errs...))
+					event := session.NewEvent(ctx.InvocationID())
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+
+				for event, err := range judge.Run(ctx) {
+					if !yield(event, err) || err != nil {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// runDesignCandidates runs the candidate agents concurrently and returns the design
+// each stored under the output key of its spec. Their events are not yielded, so the
+// candidates neither see each other nor reach the session history.
+func runDesignCandidates(ctx agent.InvocationContext, candidates []agent.Agent, specs []stageSpec) []designCandidate {
+	results := make([]designCandidate, len(candidates))
+	var wg sync.WaitGroup
+	for i, candidate := range candidates {
+		wg.Go(func() {
+			result := &results[i]
+			for event, err := range candidate.Run(ctx) {
+				if err != nil {
+					result.err = err
+					return
+				}
+				if event == nil || event.Partial {
+					continue
+				}
+				if design, ok := event.Actions.StateDelta[specs[i].OutputKey].(string); ok {
+					result.design = design
+				}
+				if usage := event.UsageMetadata; usage != nil {
+					result.usage.PromptTokenCount += usage.PromptTokenCount
+					result.usage.CandidatesTokenCount += usage.CandidatesTokenCount
+					result.usage.TotalTokenCount += usage.TotalTokenCount
+				}
+			}
+			if strings.TrimSpace(result.design) == "" {
+				result.err = errEmptyOutput
+			}
+		})
+	}
+	wg.Wait()
+	return results
+}
+
+// designJudgeInstruction is the instruction of the design stage when it judges n
+// candidate designs
+func designJudgeInstruction(n int) string {
+	var b strings.Builder
+	b.WriteString(`You are a Principal Go Software Architect judging competing designs for the same request. Pick the strongest candidate, or merge the best parts of several into one coherent design. Work completely autonomously without asking for clarification or user input.
+
+**Candidate Designs:**`)
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&b, "\n\n### Candidate %d\n{design_candidate_%d?}", i, i)
+	}
+	b.WriteString(`
+
+**Judging Criteria:**
+1. Covers everything the request asks for, with no invented requirements
+2. Simplest package structure and fewest dependencies that do the job
+3. Clear interfaces that make the code testable
+4. Idiomatic Go error handling and concurrency
+Ignore empty candidates.
+
+**Output Format:**
+Output only the final design document, with the sections Architecture Overview, Package Structure, Design Patterns, Key Interfaces, Dependencies, and Error Handling & Concurrency. Do not mention the candidates or the judging.
+
+**IMPORTANT: Output the complete final design now. Do not ask for clarification.**`)
+	return b.String()
+}
@@ -0,0 +1,142 @@
+package agents
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+)
+
+func TestDesignEnsemble_Run(t *testing.T) {
+	tests := []struct {
+		name        string
+		failing     bool
+		wantAuthors []string
+		wantErr     bool
+	}{
+		{
+			name:        "judge picks from candidates",
+			wantAuthors: []string{"DesignCandidate1Agent", "DesignCandidate2Agent", "DesignCandidate3Agent", "DesignAgent", "CodeWriterAgent"},
+		},
+		{
+			name:    "every candidate fails",
+			failing: true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first := fake.New("first-model", fake.Text("layered design"), fake.Text("layered design, revised"))
+			second := fake.New("second-model", fake.Text("hexagonal design"))
+			if tt.failing {
+				first = fake.New("first-model", fake.Error(errors.New("model unavailable")), fake.Error(errors.New("model unavailable")))
+				second = fake.New("second-model", fake.Error(errors.New("model unavailable")))
+			}
+			judge := fake.New("judge-model", fake.Text("merged design"), fake.Text("Created pkg/calc/calc.go"))
+
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:            judge,
+				DesignCandidates: 3,
+				DesignModels:     []model.LLM{first, second},
+				WorkspaceDir:     t.TempDir(),
+				Stages:           []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			if tt.wantErr {
+				if _, err := runAgentCollectingError(t, pipeline, "Build a calculator package"); err == nil || !strings.Contains(err.Error(), "every candidate design failed") {
+					t.Fatalf("Run() error = %v, want every candidate design failed", err)
+				}
+				for _, req := range judge.Requests() {
+					if strings.Contains(req.Config.SystemInstruction.Parts[0].Text, "judging competing designs") {
+						t.Error("judge ran without candidate designs")
+					}
+				}
+				return
+			}
+
+			events, state := runAgent(t, pipeline, "Build a calculator package")
+
+			var authors []string
+			for _, event := range events {
+				authors = append(authors, event.Author)
+			}
+			if !slices.Equal(authors, tt.wantAuthors) {
+				t.Errorf("authors = %v, want %v", authors, tt.wantAuthors)
+			}
+
+			// Candidates cycle through the design models
+			if first.Calls() != 2 || second.Calls() != 1 {
+				t.Errorf("candidate model calls = %d and %d, want 2 and 1", first.Calls(), second.Calls())
+			}
+			candidates := []string{
+				stateString(t, state, "design_candidate_1"),
+				stateString(t, state, "design_candidate_2"),
+				stateString(t, state, "design_candidate_3"),
+			}
+			slices.Sort(candidates)
+			if want := []string{"hexagonal design", "layered design", "layered design, revised"}; !slices.Equal(candidates, want) {
+				t.Errorf("candidate designs = %v, want %v", candidates, want)
+			}
+			if got := stateString(t, state, "design"); got != "merged design" {
+				t.Errorf("state[design] = %q, want the judged design", got)
+			}
+
+			requests := judge.Requests()
+			instruction := requests[0].Config.SystemInstruction.Parts[0].Text
+			for _, candidate := range candidates {
+				if !strings.Contains(instruction, candidate) {
+					t.Errorf("judge instruction does not contain candidate %q", candidate)
+				}
+			}
+
+			// Only the judged design reaches the code writer
+			var history []string
+			for _, content := range requests[1].Contents {
+				history = append(history, contentText(content))
+			}
+			writerContext := strings.Join(history, "\n") + requests[1].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(writerContext, "merged design") {
+				t.Errorf("code writer context = %q, want the judged design", writerContext)
+			}
+			if strings.Contains(writerContext, "hexagonal") || strings.Contains(writerContext, "layered") {
+				t.Errorf("code writer context = %q, want no candidate designs", writerContext)
+			}
+		})
+	}
+}
+
+func TestDesignEnsemble_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  PipelineConfig
+		wantErr string
+	}{
+		{
+			name:    "no design stage",
+			config:  PipelineConfig{DesignCandidates: 2, Stages: []StageConfig{{Builtin: builtinCodeWriter}}},
+			wantErr: "design candidates require a design stage",
+		},
+		{
+			name:    "nil design model",
+			config:  PipelineConfig{DesignCandidates: 2, DesignModels: []model.LLM{nil}},
+			wantErr: "design models cannot contain nil",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.Model = fake.New("fake-model")
+			tt.config.WorkspaceDir = t.TempDir()
+			if _, err := NewCodePipelineAgent(tt.config); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("NewCodePipelineAgent() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
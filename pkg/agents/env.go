@@ -0,0 +1,18 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+// withEnvTool gives a stage the env tool, which reads only the environment variables in names
+func withEnvTool(spec stageSpec, names []string) stageSpec {
+	spec.Tools = append(slices.Clip(spec.Tools), tools.NewEnvTool(names))
+	spec.Instruction += `
+
+**Environment:**
+The operator set ` + strings.Join(names, ", ") + ` for this project. Read them with the env tool before choosing versions, ports, or other settings they cover, and use their values instead of defaults.`
+	return spec
+}
@@ -0,0 +1,69 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestEnvVars(t *testing.T) {
+	t.Setenv("TARGET_GO_VERSION", "1.24")
+	mdl := fake.New("fake-model",
+		fake.Text("design: a calculator"),
+		fake.FunctionCall("env", map[string]any{}),
+		fake.Text("Created calc.go for Go 1.24"),
+		fake.Text("Created calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		EnvVars:      []string{"TARGET_GO_VERSION"},
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	events, _ := runAgent(t, pipeline, "Build a calculator")
+
+	requests := mdl.Requests()
+	writer := requests[1]
+	if _, ok := writer.Tools["env"]; !ok {
+		t.Error("code writer has no env tool")
+	}
+	if got := writer.Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "The operator set TARGET_GO_VERSION") {
+		t.Errorf("code writer instruction = %q, want the environment note", got)
+	}
+	// The tool response carries the allowed value
+	read := false
+	for _, event := range events {
+		if event.Content == nil {
+			continue
+		}
+		for _, part := range event.Content.Parts {
+			if r := part.FunctionResponse; r != nil && r.Name == "env" {
+				read = strings.Contains(fmt.Sprint(r.Response["variables"]), "TARGET_GO_VERSION:1.24")
+			}
+		}
+	}
+	if !read {
+		t.Error("code writer did not read TARGET_GO_VERSION")
+	}
+	if _, ok := requests[len(requests)-1].Tools["env"]; ok {
+		t.Error("reviewer has the env tool, want it only for the code writer")
+	}
+}
+
+func TestStageFromConfig_EnvTool(t *testing.T) {
+	config := PipelineConfig{WorkspaceDir: t.TempDir(), EnvVars: []string{"SERVICE_PORT"}}
+	spec, err := stageFromConfig(StageConfig{Name: "PortAgent", Instruction: "Pick the port", Tools: []string{"env"}}, config)
+	if err != nil {
+		t.Fatalf("stageFromConfig() error = %v", err)
+	}
+	if len(spec.Tools) != 1 || !strings.Contains(spec.Tools[0].Description(), "SERVICE_PORT") {
+		t.Errorf("tools = %v, want the env tool for SERVICE_PORT", spec.Tools)
+	}
+}
@@ -0,0 +1,164 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// maxCommitBodyLines caps the lines of stage output used as a commit message body
+const maxCommitBodyLines = 20
+
+// gitStage describes the stage that initializes the workspace repository
+func gitStage() stageSpec {
+	return stageSpec{
+		Name:        "GitAgent",
+		Description: "Initializes a git repository in the workspace.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newGitAgent(config.WorkspaceDir)
+		},
+	}
+}
+
+// gitReviewNote tells the code reviewer how to use the history of the workspace
+const gitReviewNote = "\n\n**Git History:** The workspace is a git repository with a commit per stage. Use gitLog to list them and gitDiff with a ref (such as HEAD~1) to see what changed, instead of re-reading files that did not change."
+
+// Session state keys set by the git stage. gitHistoryKey is true when the workspace
+// repository was initialized; gitReviewNoteKey holds gitReviewNote then, and is empty
+// otherwise.
+const (
+	gitHistoryKey    = "git_history"
+	gitReviewNoteKey = "git_review_note"
+)
+
+// withGitCommits prepends the git stage and makes every LLM stage commit its
+// workspace changes when it finishes. The code reviewer also gets the gitDiff and
+// gitLog tools to review the changes instead of every file. Stages neither commit
+// nor get the git tools when the git stage could not initialize the repository.
+func withGitCommits(stages []stageSpec, workspaceDir string) []stageSpec {
+	for i := range stages {
+		stages[i] = withGitCommit(stages[i], workspaceDir)
+		if stages[i].Builtin == builtinCodeReviewer {
+			stages[i].Toolsets = append(slices.Clip(stages[i].Toolsets), &gitHistoryToolset{tools: []tool.Tool{
+				tools.NewGitDiffToolWithWorkspace(workspaceDir),
+				tools.NewGitLogToolWithWorkspace(workspaceDir),
+			}})
+			stages[i].Instruction += "{" + gitReviewNoteKey + "?}"
+		}
+	}
+	return append([]stageSpec{gitStage()}, stages...)
+}
+
+// withGitCommit makes an LLM stage commit the workspace changes it made, using the
+// stage description as the subject and the stage output as the body
+func withGitCommit(spec stageSpec, workspaceDir string) stageSpec {
+	if spec.Custom != nil {
+		return spec
+	}
+	subject := fmt.Sprintf("%s: %s", spec.Name, strings.TrimSuffix(spec.Description, "."))
+	outputKey := spec.OutputKey
+	spec.AfterAgentCallbacks = append(spec.AfterAgentCallbacks, func(ctx agent.CallbackContext) (*genai.Content, error) {
+		if !gitHistoryEnabled(ctx.ReadonlyState()) {
+			return nil, nil
+		}
+		var body string
+		if outputKey != "" {
+			body = commitBody(readStateString(ctx.ReadonlyState(), outputKey))
+		}
+		// A failed commit must not fail the pipeline; the files are still in the workspace
+		if _, err := tools.CommitWorkspace(ctx, workspaceDir, subject, body); err != nil {
+			slog.Warn("Failed to commit stage changes", "stage", ctx.AgentName(), "error", err)
+		}
+		return nil, nil
+	})
+	return spec
+}
+
+// gitHistoryEnabled reports whether the git stage initialized the workspace repository
+func gitHistoryEnabled(state stateReader) bool {
+	v, err := state.Get(gitHistoryKey)
+	enabled, _ := v.(bool)
+	return err == nil && enabled
+}
+
+// gitHistoryToolset provides its tools only when the git stage initialized the
+// workspace repository
+type gitHistoryToolset struct {
+	tools []tool.Tool
+}
+
+func (s *gitHistoryToolset) Name() string {
+	return "gitHistory"
+}
+
+func (s *gitHistoryToolset) Tools(ctx agent.ReadonlyContext) ([]tool.Tool, error) {
+	if !gitHistoryEnabled(ctx.ReadonlyState()) {
+		return nil, nil
+	}
+	return s.tools, nil
+}
+
+// newGitAgent creates an agent that initializes a git repository in the workspace
+// and commits any files already present
+func newGitAgent(workspaceDir string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "GitAgent",
+		Description: "Initializes a git repository in the workspace.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				output := "Git repository ready; each stage commits its changes."
+				event := session.NewEvent(ctx.InvocationID())
+				event.Actions.StateDelta[gitHistoryKey] = true
+				event.Actions.StateDelta[gitReviewNoteKey] = gitReviewNote
+				if err := initRepository(ctx, workspaceDir); err != nil {
+					slog.Warn("Git history disabled, repository could not be initialized", "error", err)
+					output = fmt.Sprintf("Git history disabled: %v", err)
+					event.Actions.StateDelta[gitHistoryKey] = false
+					event.Actions.StateDelta[gitReviewNoteKey] = ""
+				}
+				event.LLMResponse.Content = genai.NewContentFromText(output, genai.RoleModel)
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// initRepository initializes a repository in workspaceDir if it has none and commits
+// the existing files
+func initRepository(ctx context.Context, workspaceDir string) error {
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return fmt.Errorf("failed to create workspace: %w", err)
+	}
+	_, err := os.Stat(filepath.Join(workspaceDir, ".git"))
+	if errors.Is(err, os.ErrNotExist) {
+		slog.Info("Initializing git repository in workspace", "workspace", workspaceDir)
+		if _, err := tools.RunGit(ctx, workspaceDir, "init", "--quiet"); err != nil {
+			return err
+		}
+	} else if err != nil {
+		return fmt.Errorf("failed to stat .git: %w", err)
+	}
+	_, err = tools.CommitWorkspace(ctx, workspaceDir, "Initial workspace", "")
+	return err
+}
+
+// commitBody returns the first maxCommitBodyLines lines of output
+func commitBody(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > maxCommitBodyLines {
+		lines = append(lines[:maxCommitBodyLines], "...")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
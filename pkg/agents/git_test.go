@@ -0,0 +1,180 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+func TestCommitBody(t *testing.T) {
+	long := strings.Repeat("line\n", maxCommitBodyLines+5)
+
+	tests := []struct {
+		name      string
+		output    string
+		wantLines int
+	}{
+		{name: "empty", output: "", wantLines: 1},
+		{name: "short", output: "\nCreated pkg/calc/calc.go\n", wantLines: 1},
+		{name: "truncated", output: long, wantLines: maxCommitBodyLines + 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := commitBody(tt.output)
+			if lines := strings.Count(got, "\n") + 1; lines != tt.wantLines {
+				t.Errorf("commitBody() has %d lines, want %d: %q", lines, tt.wantLines, got)
+			}
+		})
+	}
+}
+
+func TestCommitWorkspace(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+	writeWorkspace(t, workspaceDir, map[string]string{"README.md": "# calc\n"})
+
+	if err := initRepository(ctx, workspaceDir); err != nil {
+		t.Fatalf("initRepository() error = %v", err)
+	}
+	committed, err := tools.CommitWorkspace(ctx, workspaceDir, "nothing changed", "")
+	if err != nil {
+		t.Fatalf("tools.CommitWorkspace() error = %v", err)
+	}
+	if committed {
+		t.Error("tools.CommitWorkspace() on a clean workspace created a commit")
+	}
+
+	// Re-initializing an existing repository keeps its history
+	if err := initRepository(ctx, workspaceDir); err != nil {
+		t.Fatalf("initRepository() on an existing repository error = %v", err)
+	}
+	if got := gitLog(t, workspaceDir); !slices.Equal(got, []string{"Initial workspace"}) {
+		t.Errorf("git log = %v, want the initial commit only", got)
+	}
+}
+
+func TestGitCommits_Run(t *testing.T) {
+	workspaceDir := t.TempDir()
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.FunctionCall("fileWrite", map[string]any{
+			"path":    "pkg/calc/calc.go",
+			"content": "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a + b }\n",
+		}),
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.FunctionCall("fileWrite", map[string]any{
+			"path":    "pkg/calc/calc_test.go",
+			"content": "package calc\n",
+		}),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: workspaceDir,
+		SkipBuild:    true,
+		SkipTests:    true,
+		GitCommits:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, _ := runAgent(t, pipeline, "Build a calculator package")
+	if events[0].Author != "GitAgent" {
+		t.Errorf("first event author = %q, want GitAgent", events[0].Author)
+	}
+
+	// Stages that change no files create no commits
+	want := []string{
+		"TDDExpertAgent: Writes comprehensive Go tests following TDD best practices",
+		"CodeWriterAgent: Writes initial Go code based on a specification",
+	}
+	if got := gitLog(t, workspaceDir); !slices.Equal(got, want) {
+		t.Errorf("git log = %v, want %v", got, want)
+	}
+
+	body, err := tools.RunGit(context.Background(), workspaceDir, "log", "-1", "--format=%b", "HEAD~1")
+	if err != nil {
+		t.Fatalf("git log error = %v", err)
+	}
+	if !strings.Contains(body, "Created pkg/calc/calc.go") {
+		t.Errorf("commit body = %q, want the stage output", body)
+	}
+
+	// The reviewer can diff the history instead of re-reading every file
+	requests := mdl.Requests()
+	reviewer := requests[len(requests)-1]
+	for _, name := range []string{"gitDiff", "gitLog"} {
+		if _, ok := reviewer.Tools[name]; !ok {
+			t.Errorf("reviewer has no %s tool", name)
+		}
+	}
+	if got := reviewer.Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "gitDiff with a ref") {
+		t.Errorf("reviewer instruction = %q, want the git history note", got)
+	}
+}
+
+func TestGitCommits_InitFailure(t *testing.T) {
+	// An empty .git directory is not a repository and git init is skipped for it
+	workspaceDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(workspaceDir, ".git"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("tests"),
+		fake.Text("code"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: workspaceDir,
+		SkipBuild:    true,
+		SkipTests:    true,
+		GitCommits:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+	if got := events[0].Content.Parts[0].Text; !strings.HasPrefix(got, "Git history disabled") {
+		t.Errorf("GitAgent output = %q, want git history disabled", got)
+	}
+	if gitHistoryEnabled(state) {
+		t.Error("git history enabled after the repository failed to initialize")
+	}
+	if entries, err := os.ReadDir(filepath.Join(workspaceDir, ".git")); err != nil || len(entries) > 0 {
+		t.Errorf(".git entries = %v, %v, want none", entries, err)
+	}
+
+	// The reviewer gets neither the git tools nor the note about the history
+	requests := mdl.Requests()
+	reviewer := requests[len(requests)-1]
+	for _, name := range []string{"gitDiff", "gitLog"} {
+		if _, ok := reviewer.Tools[name]; ok {
+			t.Errorf("reviewer has the %s tool", name)
+		}
+	}
+	if got := reviewer.Config.SystemInstruction.Parts[0].Text; strings.Contains(got, "Git History") {
+		t.Errorf("reviewer instruction = %q, want no git history note", got)
+	}
+}
+
+// gitLog returns the commit subjects in workspaceDir, newest first
+func gitLog(t *testing.T, workspaceDir string) []string {
+	t.Helper()
+	out, err := tools.RunGit(context.Background(), workspaceDir, "log", "--format=%s")
+	if err != nil {
+		t.Fatalf("git log error = %v", err)
+	}
+	return strings.Split(strings.TrimSpace(out), "\n")
+}
@@ -0,0 +1,181 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/parallelagent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+)
+
+// GraphNode is a stage of a Graph
+type GraphNode struct {
+	// Agent runs the stage
+	Agent agent.Agent
+	// Needs lists the state keys the stage reads, each output by another node
+	Needs []string
+	// Outputs lists the state keys the stage writes
+	Outputs []string
+}
+
+// Graph builds a pipeline from stages that declare their dependencies on output keys
+// instead of a fixed order. Stages run in waves: each wave holds the stages whose needs
+// are all output by earlier waves, and the stages of a wave run concurrently. Stages of
+// the same wave share the session state, so they should write distinct files.
+type Graph struct {
+	name        string
+	description string
+	nodes       []GraphNode
+}
+
+// NewGraph creates an empty graph whose built agent has the given name and description
+func NewGraph(name, description string) *Graph {
+	return &Graph{name: name, description: description}
+}
+
+// Add adds node to the graph and returns the graph for chaining
+func (g *Graph) Add(node GraphNode) *Graph {
+	g.nodes = append(g.nodes, node)
+	return g
+}
+
+// Build validates the graph and returns an agent that runs its stages in dependency
+// order, running independent stages concurrently
+func (g *Graph) Build() (agent.Agent, error) {
+	if g.name == "" {
+		return nil, fmt.Errorf("graph name cannot be empty")
+	}
+	if len(g.nodes) == 0 {
+		return nil, fmt.Errorf("graph %s has no nodes", g.name)
+	}
+
+	names := make(map[string]bool, len(g.nodes))
+	producers := make(map[string]int)
+	for i, node := range g.nodes {
+		if node.Agent == nil {
+			return nil, fmt.Errorf("graph %s node %d has no agent", g.name, i)
+		}
+		name := node.Agent.Name()
+		if names[name] {
+			return nil, fmt.Errorf("graph %s has more than one node named %q", g.name, name)
+		}
+		names[name] = true
+		for _, key := range node.Outputs {
+			if other, ok := producers[key]; ok {
+				return nil, fmt.Errorf("nodes %q and %q both output %q", g.nodes[other].Agent.Name(), name, key)
+			}
+			producers[key] = i
+		}
+	}
+	for _, node := range g.nodes {
+		for _, key := range node.Needs {
+			if _, ok := producers[key]; !ok {
+				return nil, fmt.Errorf("node %q needs %q, which no node outputs", node.Agent.Name(), key)
+			}
+		}
+	}
+
+	waves, err := g.waves(producers)
+	if err != nil {
+		return nil, err
+	}
+
+	stages := make([]agent.Agent, 0, len(waves))
+	for i, wave := range waves {
+		if len(wave) == 1 {
+			stages = append(stages, wave[0])
+			continue
+		}
+
+		waveNames := make([]string, 0, len(wave))
+		for _, ag := range wave {
+			waveNames = append(waveNames, ag.Name())
+		}
+		name := fmt.Sprintf("%sWave%d", g.name, i+1)
+		slog.Info("Creating graph wave", "graph", g.name, "wave", name, "stages", waveNames)
+
+		parallel, err := parallelagent.New(parallelagent.Config{
+			AgentConfig: agent.Config{
+				Name:        name,
+				Description: fmt.Sprintf("Runs %s concurrently.", strings.Join(waveNames, ", ")),
+				SubAgents:   wave,
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("parallel agent %s creation failed: %w", name, err)
+		}
+		stages = append(stages, parallel)
+	}
+
+	return sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{
+			Name:        g.name,
+			Description: g.description,
+			SubAgents:   stages,
+		},
+	})
+}
+
+// waves orders the nodes into waves with Kahn's algorithm, keeping the order the nodes
+// were added in within each wave. producers maps each output key to its node index.
+func (g *Graph) waves(producers map[string]int) ([][]agent.Agent, error) {
+	pending := make([]int, len(g.nodes))
+	dependents := make([][]int, len(g.nodes))
+	for i, node := range g.nodes {
+		seen := make(map[int]bool)
+		for _, key := range node.Needs {
+			producer := producers[key]
+			if producer == i {
+				return nil, fmt.Errorf("node %q needs its own output %q", node.Agent.Name(), key)
+			}
+			if seen[producer] {
+				continue
+			}
+			seen[producer] = true
+			pending[i]++
+			dependents[producer] = append(dependents[producer], i)
+		}
+	}
+
+	var ready []int
+	for i := range g.nodes {
+		if pending[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	var waves [][]agent.Agent
+	placed := 0
+	for len(ready) > 0 {
+		wave := make([]agent.Agent, 0, len(ready))
+		var next []int
+		for _, i := range ready {
+			wave = append(wave, g.nodes[i].Agent)
+			for _, dependent := range dependents[i] {
+				pending[dependent]--
+				if pending[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		placed += len(ready)
+		waves = append(waves, wave)
+		// Keep the order the nodes were added in
+		slices.Sort(next)
+		ready = next
+	}
+
+	if placed < len(g.nodes) {
+		var cycle []string
+		for i, node := range g.nodes {
+			if pending[i] > 0 {
+				cycle = append(cycle, node.Agent.Name())
+			}
+		}
+		return nil, fmt.Errorf("graph %s has a dependency cycle between %s", g.name, strings.Join(cycle, ", "))
+	}
+	return waves, nil
+}
@@ -0,0 +1,185 @@
+package agents
+
+import (
+	"slices"
+	"sort"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+)
+
+// graphStage creates an LLM stage named name that writes its reply to outputKey
+func graphStage(t *testing.T, mdl model.LLM, name, outputKey string) agent.Agent {
+	t.Helper()
+	ag, err := newLLMStage(PipelineConfig{Model: mdl}, stageSpec{
+		Name:        name,
+		Description: "Graph test stage.",
+		Instruction: "stage " + name,
+		OutputKey:   outputKey,
+	})
+	if err != nil {
+		t.Fatalf("newLLMStage(%s) error = %v", name, err)
+	}
+	return ag
+}
+
+func TestGraph_Build(t *testing.T) {
+	mdl := fake.New("fake-model")
+	design := graphStage(t, mdl, "DesignAgent", "design")
+	api := graphStage(t, mdl, "APIAgent", "api")
+	store := graphStage(t, mdl, "StoreAgent", "store")
+	wire := graphStage(t, mdl, "WireAgent", "wiring")
+
+	tests := []struct {
+		name      string
+		nodes     []GraphNode
+		wantWaves []string
+		wantErr   string
+	}{
+		{
+			name: "diamond",
+			nodes: []GraphNode{
+				{Agent: design, Outputs: []string{"design"}},
+				{Agent: api, Needs: []string{"design"}, Outputs: []string{"api"}},
+				{Agent: store, Needs: []string{"design"}, Outputs: []string{"store"}},
+				{Agent: wire, Needs: []string{"api", "store"}, Outputs: []string{"wiring"}},
+			},
+			wantWaves: []string{"DesignAgent", "GraphWave2[APIAgent StoreAgent]", "WireAgent"},
+		},
+		{
+			name: "chain keeps declared dependencies over insertion order",
+			nodes: []GraphNode{
+				{Agent: wire, Needs: []string{"api"}},
+				{Agent: api, Needs: []string{"design", "design"}, Outputs: []string{"api"}},
+				{Agent: design, Outputs: []string{"design"}},
+			},
+			wantWaves: []string{"DesignAgent", "APIAgent", "WireAgent"},
+		},
+		{
+			name:    "no nodes",
+			wantErr: "graph Graph has no nodes",
+		},
+		{
+			name:    "nil agent",
+			nodes:   []GraphNode{{Agent: design}, {}},
+			wantErr: "graph Graph node 1 has no agent",
+		},
+		{
+			name:    "duplicate name",
+			nodes:   []GraphNode{{Agent: design}, {Agent: design}},
+			wantErr: `more than one node named "DesignAgent"`,
+		},
+		{
+			name: "duplicate output",
+			nodes: []GraphNode{
+				{Agent: api, Outputs: []string{"code"}},
+				{Agent: store, Outputs: []string{"code"}},
+			},
+			wantErr: `nodes "APIAgent" and "StoreAgent" both output "code"`,
+		},
+		{
+			name:    "missing producer",
+			nodes:   []GraphNode{{Agent: api, Needs: []string{"design"}}},
+			wantErr: `node "APIAgent" needs "design", which no node outputs`,
+		},
+		{
+			name:    "self dependency",
+			nodes:   []GraphNode{{Agent: api, Needs: []string{"api"}, Outputs: []string{"api"}}},
+			wantErr: `node "APIAgent" needs its own output "api"`,
+		},
+		{
+			name: "cycle",
+			nodes: []GraphNode{
+				{Agent: design, Outputs: []string{"design"}},
+				{Agent: api, Needs: []string{"design", "store"}, Outputs: []string{"api"}},
+				{Agent: store, Needs: []string{"api"}, Outputs: []string{"store"}},
+			},
+			wantErr: "dependency cycle between APIAgent, StoreAgent",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := NewGraph("Graph", "Test graph.")
+			for _, node := range tt.nodes {
+				g.Add(node)
+			}
+			built, err := g.Build()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Build() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			var waves []string
+			for _, stage := range built.SubAgents() {
+				if len(stage.SubAgents()) == 0 {
+					waves = append(waves, stage.Name())
+					continue
+				}
+				var names []string
+				for _, sub := range stage.SubAgents() {
+					names = append(names, sub.Name())
+				}
+				waves = append(waves, stage.Name()+"["+strings.Join(names, " ")+"]")
+			}
+			if !slices.Equal(waves, tt.wantWaves) {
+				t.Errorf("waves = %v, want %v", waves, tt.wantWaves)
+			}
+		})
+	}
+}
+
+func TestGraph_Run(t *testing.T) {
+	// Each stage replies with its name and the state it was given
+	mdl := fake.NewWithResponder("fake-model", func(req *model.LLMRequest) fake.Turn {
+		return fake.Text(req.Config.SystemInstruction.Parts[0].Text)
+	})
+	stage := func(name, instruction, outputKey string) agent.Agent {
+		ag, err := newLLMStage(PipelineConfig{Model: mdl}, stageSpec{
+			Name:        name,
+			Description: "Graph test stage.",
+			Instruction: instruction,
+			OutputKey:   outputKey,
+		})
+		if err != nil {
+			t.Fatalf("newLLMStage(%s) error = %v", name, err)
+		}
+		return ag
+	}
+
+	graph, err := NewGraph("ServiceGraph", "Builds a service.").
+		Add(GraphNode{Agent: stage("DesignAgent", "design", "design"), Outputs: []string{"design"}}).
+		Add(GraphNode{Agent: stage("APIAgent", "api for {design}", "api"), Needs: []string{"design"}, Outputs: []string{"api"}}).
+		Add(GraphNode{Agent: stage("StoreAgent", "store for {design}", "store"), Needs: []string{"design"}, Outputs: []string{"store"}}).
+		Add(GraphNode{Agent: stage("WireAgent", "wire {api} and {store}", "wiring"), Needs: []string{"api", "store"}, Outputs: []string{"wiring"}}).
+		Build()
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	events, state := runAgent(t, graph, "Build a key-value service")
+
+	var authors []string
+	for _, event := range events {
+		authors = append(authors, event.Author)
+	}
+	if len(authors) != 4 {
+		t.Fatalf("got authors %v, want 4 events", authors)
+	}
+	concurrent := []string{authors[1], authors[2]}
+	sort.Strings(concurrent)
+	if authors[0] != "DesignAgent" || authors[3] != "WireAgent" || concurrent[0] != "APIAgent" || concurrent[1] != "StoreAgent" {
+		t.Errorf("unexpected stage order: %v", authors)
+	}
+	if got, want := stateString(t, state, "wiring"), "wire api for design and store for design"; got != want {
+		t.Errorf("state[wiring] = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,150 @@
+package agents
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Hooks are called around each stage invocation of a pipeline run, for metrics, audit, and
+// progress integrations. Any function may be nil. They are called synchronously from the
+// goroutine running the stage, concurrently for stages in the same parallel group, and
+// once per iteration for stages inside loops.
+type Hooks struct {
+	// BeforeStage is called before a stage runs
+	BeforeStage func(ctx context.Context, stage StageInfo)
+	// StageEvent is called with each event of a stage, including the partial events of
+	// streamed responses and the events of nested agents
+	StageEvent func(ctx context.Context, stage StageInfo, event *session.Event)
+	// AfterStage is called after a stage finishes
+	AfterStage func(ctx context.Context, result StageResult)
+}
+
+// StageInfo identifies a stage invocation
+type StageInfo struct {
+	// Stage is the stage name
+	Stage string
+	// AppName is the app of the session
+	AppName string
+	// UserID is the user of the session
+	UserID string
+	// SessionID is the session of the run
+	SessionID string
+	// InvocationID is the invocation the stage runs in
+	InvocationID string
+	// UserContent is the user message that started the invocation
+	UserContent *genai.Content
+}
+
+// StageResult describes a finished stage invocation
+type StageResult struct {
+	StageInfo
+	// Duration is the wall-clock time the stage took
+	Duration time.Duration
+	// Usage is the token usage of the model calls of the stage, including nested agents
+	Usage TokenUsage
+	// OutputKey is the state key the stage output is stored under, empty for stages without one
+	OutputKey string
+	// OutputSize is the length in bytes of the output stored under OutputKey
+	OutputSize int
+	// Err is the first error the stage reported
+	Err error
+}
+
+// enabled reports whether any hook is set
+func (h Hooks) enabled() bool {
+	return h.BeforeStage != nil || h.StageEvent != nil || h.AfterStage != nil
+}
+
+// CombineHooks returns the hooks that call each of hooks in turn
+func CombineHooks(hooks ...Hooks) Hooks {
+	var combined Hooks
+	for _, h := range hooks {
+		if before, next := combined.BeforeStage, h.BeforeStage; next != nil {
+			combined.BeforeStage = func(ctx context.Context, stage StageInfo) {
+				if before != nil {
+					before(ctx, stage)
+				}
+				next(ctx, stage)
+			}
+		}
+		if onEvent, next := combined.StageEvent, h.StageEvent; next != nil {
+			combined.StageEvent = func(ctx context.Context, stage StageInfo, event *session.Event) {
+				if onEvent != nil {
+					onEvent(ctx, stage, event)
+				}
+				next(ctx, stage, event)
+			}
+		}
+		if after, next := combined.AfterStage, h.AfterStage; next != nil {
+			combined.AfterStage = func(ctx context.Context, result StageResult) {
+				if after != nil {
+					after(ctx, result)
+				}
+				next(ctx, result)
+			}
+		}
+	}
+	return combined
+}
+
+// newHooksAgent wraps the stage agent inner, whose output is stored under outputKey, so
+// that hooks are called before and after each of its runs
+func newHooksAgent(inner agent.Agent, outputKey string, hooks Hooks) (agent.Agent, error) {
+	stage := stageName(inner)
+	return agent.New(agent.Config{
+		Name:        inner.Name() + "Hooks",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				sess := ctx.Session()
+				info := StageInfo{
+					Stage:        stage,
+					AppName:      sess.AppName(),
+					UserID:       sess.UserID(),
+					SessionID:    sess.ID(),
+					InvocationID: ctx.InvocationID(),
+					UserContent:  ctx.UserContent(),
+				}
+				if hooks.BeforeStage != nil {
+					hooks.BeforeStage(ctx, info)
+				}
+				result := StageResult{StageInfo: info, OutputKey: outputKey}
+				start := time.Now()
+				defer func() {
+					result.Duration = time.Since(start)
+					if hooks.AfterStage != nil {
+						hooks.AfterStage(ctx, result)
+					}
+				}()
+
+				for event, err := range inner.Run(ctx) {
+					if err != nil && result.Err == nil {
+						result.Err = err
+					}
+					if event != nil {
+						if usage := event.UsageMetadata; usage != nil && !event.Partial {
+							result.Usage.PromptTokens += int(usage.PromptTokenCount)
+							result.Usage.CompletionTokens += int(usage.CandidatesTokenCount)
+							result.Usage.TotalTokens += int(usage.TotalTokenCount)
+						}
+						if output, ok := event.Actions.StateDelta[outputKey].(string); ok && outputKey != "" {
+							result.OutputSize = len(output)
+						}
+						if hooks.StageEvent != nil {
+							hooks.StageEvent(ctx, info, event)
+						}
+					}
+					if !yield(event, err) {
+						return
+					}
+				}
+			}
+		},
+	})
+}
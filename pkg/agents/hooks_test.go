@@ -0,0 +1,148 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestHooks(t *testing.T) {
+	var mu sync.Mutex
+	var calls []string
+	var results []StageResult
+	eventTexts := make(map[string][]string)
+	hooks := Hooks{
+		BeforeStage: func(ctx context.Context, stage StageInfo) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "before "+stage.Stage)
+		},
+		StageEvent: func(ctx context.Context, stage StageInfo, event *session.Event) {
+			mu.Lock()
+			defer mu.Unlock()
+			if stage.AppName != "test-app" || stage.UserID != "test-user" {
+				t.Errorf("%s event of app %q, user %q; want test-app, test-user", stage.Stage, stage.AppName, stage.UserID)
+			}
+			if c := stage.UserContent; c == nil || c.Parts[0].Text != "Build a calculator package" {
+				t.Errorf("%s event of user content %v, want the request", stage.Stage, c)
+			}
+			if event.Content != nil && len(event.Content.Parts) > 0 {
+				eventTexts[stage.Stage] = append(eventTexts[stage.Stage], event.Content.Parts[0].Text)
+			}
+		},
+		AfterStage: func(ctx context.Context, result StageResult) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls = append(calls, "after "+result.Stage)
+			results = append(results, result)
+		},
+	}
+	audit, err := agent.New(agent.Config{
+		Name:        "AuditAgent",
+		Description: "Fails the run.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText("audit failed", genai.RoleModel)
+				yield(event, errors.New("audit failed"))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+
+	mdl := fake.New("fake-model",
+		usageTurn("design", 100, 20),
+		usageTurn("Created pkg/calc/calc.go", 150, 30),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		Stages:       []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+		PostStages:   []agent.Agent{audit},
+		StageRetries: 1,
+		Hooks:        hooks,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+	if runErr == nil {
+		t.Fatal("Run() error = nil, want the audit failure")
+	}
+
+	want := []string{
+		"before DesignAgent", "after DesignAgent",
+		"before CodeWriterAgent", "after CodeWriterAgent",
+		"before AuditAgent", "after AuditAgent",
+	}
+	if !slices.Equal(calls, want) {
+		t.Fatalf("hook calls = %v, want %v", calls, want)
+	}
+
+	if got := eventTexts["AuditAgent"]; !slices.Equal(got, []string{"audit failed"}) {
+		t.Errorf("AuditAgent events = %q, want the audit failure", got)
+	}
+	if len(eventTexts["DesignAgent"]) == 0 || len(eventTexts["CodeWriterAgent"]) == 0 {
+		t.Errorf("stage events = %q, want events of each stage", eventTexts)
+	}
+
+	var got []string
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s tokens=%d key=%s size=%d err=%v",
+			r.Stage, r.Usage.TotalTokens, r.OutputKey, r.OutputSize, r.Err))
+		if r.SessionID == "" || r.InvocationID == "" || r.Duration <= 0 {
+			t.Errorf("%s result = %+v, want session, invocation, and duration set", r.Stage, r)
+		}
+	}
+	wantResults := []string{
+		"DesignAgent tokens=120 key=design size=6 err=<nil>",
+		"CodeWriterAgent tokens=180 key=generated_code size=24 err=<nil>",
+		"AuditAgent tokens=0 key= size=0 err=audit failed",
+	}
+	if strings.Join(got, "\n") != strings.Join(wantResults, "\n") {
+		t.Errorf("stage results =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(wantResults, "\n"))
+	}
+}
+
+func TestCombineHooks(t *testing.T) {
+	var calls []string
+	record := func(name string) Hooks {
+		return Hooks{
+			BeforeStage: func(ctx context.Context, stage StageInfo) { calls = append(calls, name+" before") },
+			StageEvent: func(ctx context.Context, stage StageInfo, event *session.Event) {
+				calls = append(calls, name+" event")
+			},
+			AfterStage: func(ctx context.Context, result StageResult) { calls = append(calls, name+" after") },
+		}
+	}
+	afterOnly := Hooks{AfterStage: func(ctx context.Context, result StageResult) { calls = append(calls, "metrics after") }}
+
+	hooks := CombineHooks(record("audit"), Hooks{}, afterOnly, record("progress"))
+	hooks.BeforeStage(context.Background(), StageInfo{})
+	hooks.StageEvent(context.Background(), StageInfo{}, session.NewEvent("inv"))
+	hooks.AfterStage(context.Background(), StageResult{})
+	want := []string{
+		"audit before", "progress before",
+		"audit event", "progress event",
+		"audit after", "metrics after", "progress after",
+	}
+	if !slices.Equal(calls, want) {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if CombineHooks(Hooks{}, Hooks{}).enabled() {
+		t.Error("CombineHooks() of no hooks is enabled")
+	}
+}
@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// promptVars returns the prompt template variables for config
+func promptVars(config PipelineConfig) prompts.Vars {
+	vars := prompts.DefaultVars()
+	if config.MinCoverage > 0 {
+		vars.CoverageTarget = config.MinCoverage
+	}
+	return vars
+}
+
+// stagePrompt renders the instruction of the named stage from the prompt library of
+// config: a template in PromptDir, or the embedded default
+func stagePrompt(config PipelineConfig, name string) (string, error) {
+	instruction, err := prompts.New(config.PromptDir).Render(name, promptVars(config))
+	if err != nil {
+		return "", fmt.Errorf("stage %q: %w", name, err)
+	}
+	return instruction, nil
+}
+
+// instructionOverrides merges InstructionOverrides with the templates loaded from
+// InstructionFiles, keyed by stage name
+func instructionOverrides(config PipelineConfig) (map[string]string, error) {
+	overrides := make(map[string]string, len(config.InstructionOverrides)+len(config.InstructionFiles))
+	for name, instruction := range config.InstructionOverrides {
+		overrides[name] = instruction
+	}
+
+	for name, path := range config.InstructionFiles {
+		if _, ok := overrides[name]; ok {
+			return nil, fmt.Errorf("stage %q has both an instruction override and an instruction file", name)
+		}
+		instruction, err := readInstructionFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("stage %q: %w", name, err)
+		}
+		overrides[name] = instruction
+	}
+	return overrides, nil
+}
+
+// applyInstructionOverrides replaces the instructions of the named stages. Every
+// override must match a stage so that misspelled stage names are reported.
+func applyInstructionOverrides(stages []stageSpec, overrides map[string]string) error {
+	for name, instruction := range overrides {
+		i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Name == name })
+		if i < 0 {
+			return fmt.Errorf("instruction override for unknown stage %q", name)
+		}
+		if stages[i].Custom != nil {
+			return fmt.Errorf("stage %q does not use an instruction", name)
+		}
+		slog.Info("Overriding stage instruction", "stage", name)
+		stages[i].Instruction = instruction
+	}
+	return nil
+}
+
+// readInstructionFile reads an instruction template from path
+func readInstructionFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read instruction file %s: %w", path, err)
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("instruction file %s is empty", path)
+	}
+	return string(data), nil
+}
+
+// resolveRelative returns path relative to baseDir unless it is absolute or empty
+func resolveRelative(baseDir, path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
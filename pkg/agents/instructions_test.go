@@ -0,0 +1,226 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestInstructionOverrides(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{
+		"reviewer.md": "Review for security only.",
+		"empty.md":    "",
+	})
+
+	tests := []struct {
+		name        string
+		config      PipelineConfig
+		wantStage   string
+		want        string
+		errContains string
+	}{
+		{
+			name:      "inline override",
+			config:    PipelineConfig{InstructionOverrides: map[string]string{"DesignAgent": "Design tersely."}},
+			wantStage: "DesignAgent",
+			want:      "Design tersely.",
+		},
+		{
+			name:      "instruction file",
+			config:    PipelineConfig{InstructionFiles: map[string]string{"CodeReviewerAgent": filepath.Join(dir, "reviewer.md")}},
+			wantStage: "CodeReviewerAgent",
+			want:      "Review for security only.",
+		},
+		{
+			name:      "fixer stage",
+			config:    PipelineConfig{InstructionOverrides: map[string]string{"FixerAgent": "Fix everything."}},
+			wantStage: "FixerAgent",
+			want:      "Fix everything.",
+		},
+		{
+			name: "override and file for the same stage",
+			config: PipelineConfig{
+				InstructionOverrides: map[string]string{"DesignAgent": "Design tersely."},
+				InstructionFiles:     map[string]string{"DesignAgent": filepath.Join(dir, "reviewer.md")},
+			},
+			errContains: "both an instruction override and an instruction file",
+		},
+		{
+			name:        "missing file",
+			config:      PipelineConfig{InstructionFiles: map[string]string{"DesignAgent": filepath.Join(dir, "missing.md")}},
+			errContains: "failed to read instruction file",
+		},
+		{
+			name:        "empty file",
+			config:      PipelineConfig{InstructionFiles: map[string]string{"DesignAgent": filepath.Join(dir, "empty.md")}},
+			errContains: "is empty",
+		},
+		{
+			name:        "unknown stage",
+			config:      PipelineConfig{InstructionOverrides: map[string]string{"DesignAgnet": "typo"}},
+			errContains: "unknown stage",
+		},
+		{
+			name:        "non-LLM stage",
+			config:      PipelineConfig{InstructionOverrides: map[string]string{"BuildAgent": "compile"}},
+			errContains: "does not use an instruction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stages, err := pipelineStages(PipelineConfig{WorkspaceDir: t.TempDir()})
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			stages = append(stages, fixerStage(t.TempDir()))
+
+			overrides, err := instructionOverrides(tt.config)
+			if err == nil {
+				err = applyInstructionOverrides(stages, overrides)
+			}
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error = %v", err)
+			}
+
+			for _, spec := range stages {
+				if spec.Name == tt.wantStage && spec.Instruction != tt.want {
+					t.Errorf("%s instruction = %q, want %q", spec.Name, spec.Instruction, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadPipeline_InstructionFiles(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{
+		"prompts/design.md":  "Design from prompts/design.md",
+		"prompts/license.md": "Add license headers from prompts/license.md",
+	})
+	content := `skip_build: true
+skip_tests: true
+workspace_dir: ` + t.TempDir() + `
+instruction_files:
+  DesignAgent: prompts/design.md
+instruction_overrides:
+  CodeReviewerAgent: Review from the inline override
+stages:
+  - builtin: design
+  - name: LicenseAgent
+    instruction_file: prompts/license.md
+  - builtin: code_reviewer
+`
+	path := filepath.Join(dir, "pipeline.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mdl := fake.New("fake-model",
+		fake.Text("pkg/calc"),
+		fake.Text("Added headers"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := LoadPipeline(path, mdl)
+	if err != nil {
+		t.Fatalf("LoadPipeline() error = %v", err)
+	}
+	runAgent(t, pipeline, "Build a calculator package")
+
+	requests := mdl.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d model requests, want 3", len(requests))
+	}
+	for i, want := range []string{
+		"Design from prompts/design.md",
+		"Add license headers from prompts/license.md",
+		"Review from the inline override",
+	} {
+		if got := requests[i].Config.SystemInstruction.Parts[0].Text; got != want {
+			t.Errorf("request %d instruction = %q, want %q", i, got, want)
+		}
+	}
+}
+
+func TestStageFromConfig_InstructionFile(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{"stage.md": "Do the thing."})
+
+	if _, err := stageFromConfig(StageConfig{
+		Name:            "A",
+		Instruction:     "inline",
+		InstructionFile: filepath.Join(dir, "stage.md"),
+	}, PipelineConfig{WorkspaceDir: dir}); err == nil || !strings.Contains(err.Error(), "mutually exclusive") {
+		t.Errorf("stageFromConfig() error = %v, want mutually exclusive error", err)
+	}
+
+	spec, err := stageFromConfig(StageConfig{Name: "A", InstructionFile: filepath.Join(dir, "stage.md")}, PipelineConfig{WorkspaceDir: dir})
+	if err != nil {
+		t.Fatalf("stageFromConfig() error = %v", err)
+	}
+	if spec.Instruction != "Do the thing." {
+		t.Errorf("Instruction = %q, want the file contents", spec.Instruction)
+	}
+}
+
+func TestPromptDir(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{
+		"prompts/DesignAgent.md":  "Design in {{.Language}} for {{.CoverageTarget}}% coverage.",
+		"prompts/LicenseAgent.md": "Add license headers.",
+	})
+	content := `skip_build: true
+skip_tests: true
+min_coverage: 90
+prompt_dir: prompts
+workspace_dir: ` + t.TempDir() + `
+stages:
+  - builtin: design
+  - name: LicenseAgent
+  - builtin: tdd
+`
+	path := filepath.Join(dir, "pipeline.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mdl := fake.New("fake-model",
+		fake.Text("pkg/calc"),
+		fake.Text("Added headers"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+	)
+	pipeline, err := LoadPipeline(path, mdl)
+	if err != nil {
+		t.Fatalf("LoadPipeline() error = %v", err)
+	}
+	runAgent(t, pipeline, "Build a calculator package")
+
+	requests := mdl.Requests()
+	if len(requests) != 3 {
+		t.Fatalf("got %d model requests, want 3", len(requests))
+	}
+	for i, want := range []string{
+		"Design in Go for 90% coverage.",
+		"Add license headers.",
+		"You are a Go Testing Expert. Write tests for code files. Target >90% coverage.",
+	} {
+		if got := requests[i].Config.SystemInstruction.Parts[0].Text; !strings.HasPrefix(got, want) {
+			t.Errorf("request %d instruction = %q, want prefix %q", i, got, want)
+		}
+	}
+
+	// A custom stage needs an instruction when the library has no prompt for it
+	if _, err := stageFromConfig(StageConfig{Name: "MissingAgent"}, PipelineConfig{PromptDir: filepath.Join(dir, "prompts")}); err == nil {
+		t.Error("stageFromConfig() error = nil, want an error for a stage without an instruction or prompt")
+	}
+}
@@ -0,0 +1,88 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// maxLintIssuesShown caps the issues listed in lint_output; lint_issues keeps all of them
+const maxLintIssuesShown = 50
+
+// lintStage describes the lint stage
+func lintStage() stageSpec {
+	return stageSpec{
+		Name:        "LintAgent",
+		Description: "Runs golangci-lint, or go vet without it, over the generated code and records its findings.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newLintAgent("LintAgent", config.WorkspaceDir)
+		},
+	}
+}
+
+// newLintAgent creates an agent named name that runs golangci-lint in the workspace, or go
+// vet when golangci-lint cannot be run, and stores the result under the lint_status, lint_issues, and lint_output state keys. The
+// status uses the build status values; lint_issues is a JSON array of tools.LintIssue.
+func newLintAgent(name, workspaceDir string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        name,
+		Description: "Runs golangci-lint, or go vet without it, over the generated code and records its findings.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				status, issues, output := runLint(ctx, workspaceDir)
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(output, genai.RoleModel)
+				event.Actions.StateDelta["lint_status"] = status
+				event.Actions.StateDelta["lint_issues"] = issues
+				event.Actions.StateDelta["lint_output"] = output
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// runLint lints the workspace and returns the lint status, the issues as a JSON array,
+// and a summary for the next stages
+func runLint(ctx context.Context, workspaceDir string) (string, string, string) {
+	linter := tools.LintCommand
+	result, err := tools.RunLint(ctx, workspaceDir, tools.LintInput{})
+	if err != nil {
+		slog.Warn("golangci-lint could not be run, falling back to go vet", "error", err)
+		linter = "go vet"
+		var vetErr error
+		if result, vetErr = tools.RunGoVet(ctx, workspaceDir, tools.GoVetInput{}); vetErr != nil {
+			slog.Warn("Skipping lint, go vet could not be run", "error", vetErr)
+			return buildStatusSkipped, "[]", fmt.Sprintf("Lint skipped: %v; %v", err, vetErr)
+		}
+	}
+
+	issues, err := json.Marshal(result.Issues)
+	if err != nil {
+		return buildStatusSkipped, "[]", fmt.Sprintf("Lint skipped: failed to encode issues: %v", err)
+	}
+	if len(result.Issues) == 0 {
+		slog.Info("Lint passed", "workspace", workspaceDir, "linter", linter)
+		return buildStatusPassed, string(issues), fmt.Sprintf("Lint passed: %s reported no issues.", linter)
+	}
+
+	slog.Warn("Lint reported issues", "workspace", workspaceDir, "linter", linter, "issues", len(result.Issues))
+	var b strings.Builder
+	fmt.Fprintf(&b, "Lint failed: %s reported %d issues.\n\n", linter, len(result.Issues))
+	for i, issue := range result.Issues {
+		if i == maxLintIssuesShown {
+			fmt.Fprintf(&b, "- ... %d more\n", len(result.Issues)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s\n", issue)
+	}
+	return buildStatusFailed, string(issues), strings.TrimSpace(b.String())
+}
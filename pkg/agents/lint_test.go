@@ -0,0 +1,153 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+func TestRunLint(t *testing.T) {
+	tests := []struct {
+		name       string
+		script     string
+		wantStatus string
+		wantIssues int
+		wantOutput string
+	}{
+		{
+			name:       "passed",
+			script:     `echo '{"Issues":[]}'`,
+			wantStatus: buildStatusPassed,
+			wantOutput: "Lint passed",
+		},
+		{
+			name:       "issues found",
+			script:     `echo '{"Issues":[{"FromLinter":"errcheck","Text":"unchecked error","Pos":{"Filename":"calc.go","Line":7,"Column":2}}]}'`,
+			wantStatus: buildStatusFailed,
+			wantIssues: 1,
+			wantOutput: "- calc.go:7:2: unchecked error (errcheck)",
+		},
+		{
+			name:       "linter unavailable",
+			wantStatus: buildStatusSkipped,
+			wantOutput: "Lint skipped",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.script != "" {
+				fakeLinter(t, tt.script)
+			} else {
+				t.Setenv("PATH", t.TempDir())
+			}
+
+			status, issuesJSON, output := runLint(context.Background(), t.TempDir())
+			if status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", status, tt.wantStatus)
+			}
+			var issues []tools.LintIssue
+			if err := json.Unmarshal([]byte(issuesJSON), &issues); err != nil {
+				t.Fatalf("lint issues are not a JSON array: %v", err)
+			}
+			if len(issues) != tt.wantIssues {
+				t.Errorf("issues = %d, want %d", len(issues), tt.wantIssues)
+			}
+			if !strings.Contains(output, tt.wantOutput) {
+				t.Errorf("output = %q, want it to contain %q", output, tt.wantOutput)
+			}
+		})
+	}
+}
+
+func TestRunLint_FallsBackToGoVet(t *testing.T) {
+	fakeLinter(t, `echo "golangci-lint: broken install" >&2; exit 3`)
+	workspaceDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":  "module example.com/calc\n\ngo 1.22\n",
+		"calc.go": "package calc\n\nimport \"fmt\"\n\nfunc Format() string { return fmt.Sprintf(\"%d\", \"x\") }\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workspaceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", name, err)
+		}
+	}
+
+	status, issuesJSON, output := runLint(context.Background(), workspaceDir)
+	if status != buildStatusFailed {
+		t.Errorf("status = %q, want %q", status, buildStatusFailed)
+	}
+	var issues []tools.LintIssue
+	if err := json.Unmarshal([]byte(issuesJSON), &issues); err != nil {
+		t.Fatalf("lint issues are not a JSON array: %v", err)
+	}
+	if len(issues) != 1 || issues[0].Rule != "printf" {
+		t.Errorf("issues = %+v, want one printf issue", issues)
+	}
+	for _, want := range []string{"Lint failed: go vet reported 1 issues.", "- calc.go:5:"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output = %q, want it to contain %q", output, want)
+		}
+	}
+}
+
+func TestLint_FeedsReviewerAndFixer(t *testing.T) {
+	fakeLinter(t, `echo '{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Pos":{"Filename":"calc.go","Line":7}}]}'`)
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("Created calc.go"),
+		fake.Text("Created calc_test.go"),
+		fake.Text("## Critical Issues (Must Fix)\n- calc.go: check the error"),
+		fake.Text("## Fixes Applied\n- calc.go: checked the error"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		LoopPipeline: true,
+		Lint:         true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+
+	var authors []string
+	for _, event := range events {
+		authors = append(authors, event.Author)
+	}
+	want := "DesignAgent,CodeWriterAgent,TDDExpertAgent,LintAgent," +
+		"CodeReviewerAgent,FixerAgent,FixLintAgent,CodeReviewerAgent,ReviewGateAgent"
+	if strings.Join(authors, ",") != want {
+		t.Errorf("authors = %v, want %s", authors, want)
+	}
+	if got := stateString(t, state, "lint_status"); got != buildStatusFailed {
+		t.Errorf("state[lint_status] = %q, want %q", got, buildStatusFailed)
+	}
+
+	requests := mdl.Requests()
+	for i, stage := range map[int]string{3: "reviewer", 4: "fixer"} {
+		if got := requests[i].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "calc.go:7: Error return value is not checked (errcheck)") {
+			t.Errorf("%s instruction does not contain the lint issue: %q", stage, got)
+		}
+	}
+}
+
+// fakeLinter puts a golangci-lint shell script running script first on PATH
+func fakeLinter(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, tools.LintCommand), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake linter: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
@@ -0,0 +1,238 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// defaultMaxFixIterations is the default number of review rounds in loop mode
+const defaultMaxFixIterations = 3
+
+// reviewApprovalPhrases mark a review that found nothing that must be fixed
+var reviewApprovalPhrases = []string{
+	"no critical issues",
+	"no major issues found",
+}
+
+// newReviewFixLoop wraps reviewer in a loop that feeds its review to a fixer stage
+// until the review reports no critical issues or MaxFixIterations rounds have run
+func newReviewFixLoop(config PipelineConfig, reviewer, critic agent.Agent, fixer stageSpec) (agent.Agent, error) {
+	maxIterations := config.MaxFixIterations
+	if maxIterations <= 0 {
+		maxIterations = defaultMaxFixIterations
+	}
+
+	fixStages, err := newFixStages(config, fixer)
+	if err != nil {
+		return nil, err
+	}
+
+	gate, err := newReviewGateAgent(config.MinScore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create review gate: %w", err)
+	}
+
+	subAgents := []agent.Agent{reviewer}
+	if critic != nil {
+		subAgents = append(subAgents, critic)
+	}
+	subAgents = append(subAgents, gate)
+	subAgents = append(subAgents, fixStages...)
+
+	slog.Info("Creating review-and-fix loop", "max_iterations", maxIterations)
+
+	loop, err := newBoundedLoopAgent(agent.Config{
+		Name:        "ReviewFixLoopAgent",
+		Description: "Reviews the code and applies fixes until no critical issues remain.",
+		SubAgents:   subAgents,
+		AfterAgentCallbacks: []agent.AfterAgentCallback{
+			func(ctx agent.CallbackContext) (*genai.Content, error) {
+				if !loopApproved(ctx.ReadonlyState(), config.MinScore) {
+					slog.Warn("Review-and-fix loop reached its iteration cap with critical issues remaining",
+						"max_iterations", maxIterations)
+				}
+				return nil, nil
+			},
+		},
+	}, maxIterations)
+	if err != nil {
+		return nil, fmt.Errorf("loop agent creation failed: %w", err)
+	}
+	return loop, nil
+}
+
+// newFixStages creates the fixer stage followed by the dependency, build, and lint
+// checks of its changes that config enables
+func newFixStages(config PipelineConfig, fixer stageSpec) ([]agent.Agent, error) {
+	fixerAgent, err := newLLMStage(config, fixer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", fixer.Name, err)
+	}
+
+	subAgents := []agent.Agent{fixerAgent}
+	if !config.SkipBuild && !config.SkipDependencies {
+		deps, err := newDependencyAgent("FixDependencyAgent", config.WorkspaceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create dependency agent: %w", err)
+		}
+		subAgents = append(subAgents, deps)
+	}
+	if !config.SkipBuild {
+		build, err := newBuildAgent("FixBuildAgent", config.WorkspaceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create build agent: %w", err)
+		}
+		subAgents = append(subAgents, build)
+	}
+	if config.Lint {
+		lint, err := newLintAgent("FixLintAgent", config.WorkspaceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create lint agent: %w", err)
+		}
+		subAgents = append(subAgents, lint)
+	}
+	return subAgents, nil
+}
+
+// newBoundedLoopAgent creates an agent that runs the sub-agents of cfg in order up to
+// maxIterations times, stopping early when a sub-agent escalates. Unlike loopagent, the
+// escalation ends only this loop and is not propagated to the enclosing pipeline.
+func newBoundedLoopAgent(cfg agent.Config, maxIterations int) (agent.Agent, error) {
+	cfg.Run = func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+		return func(yield func(*session.Event, error) bool) {
+			for i := 0; i < maxIterations; i++ {
+				for _, subAgent := range ctx.Agent().SubAgents() {
+					escalated := false
+					for event, err := range subAgent.Run(ctx) {
+						if event != nil && event.Actions.Escalate {
+							escalated = true
+							contained := *event
+							contained.Actions.Escalate = false
+							event = &contained
+						}
+						if !yield(event, err) || err != nil {
+							return
+						}
+					}
+					if escalated {
+						return
+					}
+				}
+			}
+		}
+	}
+	return agent.New(cfg)
+}
+
+// newReviewGateAgent creates an agent that ends the enclosing loop once the
+// latest review reports no critical issues, the code builds, and the quality
+// score meets minScore
+func newReviewGateAgent(minScore float64) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "ReviewGateAgent",
+		Description: "Stops the review-and-fix loop when the review reports no critical issues.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				if !loopApproved(ctx.Session().State(), minScore) {
+					slog.Info("Review reported critical issues, running fixer")
+					return
+				}
+
+				slog.Info("Review reported no critical issues, exiting loop")
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText("Review passed with no critical issues.", genai.RoleModel)
+				event.Actions.Escalate = true
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// stateReader is the read side shared by session.State and session.ReadonlyState
+type stateReader interface {
+	Get(string) (any, error)
+}
+
+// readStateString returns the string value of key in state, or "" if it is missing
+func readStateString(state stateReader, key string) string {
+	v, err := state.Get(key)
+	if err != nil {
+		return ""
+	}
+	s, _ := v.(string)
+	return s
+}
+
+// loopApproved reports whether the latest review approves the code, the latest build
+// did not fail, and the latest quality score meets minScore
+func loopApproved(state stateReader, minScore float64) bool {
+	return reviewApproved(readStateString(state, "review_comments")) &&
+		readStateString(state, "build_status") != buildStatusFailed &&
+		qualityApproved(state, minScore)
+}
+
+// reviewApproved reports whether review contains no critical issues. A review
+// with a critical issues section approves the code when the section lists no
+// items; a review without one approves it only when it says so explicitly.
+func reviewApproved(review string) bool {
+	if strings.TrimSpace(review) == "" {
+		return false
+	}
+
+	lower := strings.ToLower(review)
+	inCritical := false
+	foundSection := false
+	for _, line := range strings.Split(lower, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			inCritical = strings.Contains(line, "critical issues")
+			foundSection = foundSection || inCritical
+			continue
+		}
+		if !inCritical {
+			continue
+		}
+		if strings.HasPrefix(line, "-") || strings.HasPrefix(line, "*") {
+			item := strings.TrimSpace(strings.TrimLeft(line, "-* "))
+			if item != "" && item != "none" && item != "none." {
+				return false
+			}
+		}
+	}
+	if foundSection {
+		return true
+	}
+
+	for _, phrase := range reviewApprovalPhrases {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// fixerStage describes the fixer agent stage
+func fixerStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "FixerAgent",
+		Description: "Fixes the critical issues reported by the code review.",
+		OutputKey:   "fix_summary",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+			tools.NewApplyPatchToolWithWorkspace(workspaceDir),
+			tools.NewFileEditToolWithWorkspace(workspaceDir),
+			tools.NewSnapshotToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("FixerAgent"),
+	}
+}
@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestReviewApproved(t *testing.T) {
+	tests := []struct {
+		name   string
+		review string
+		want   bool
+	}{
+		{
+			name:   "empty review",
+			review: "",
+			want:   false,
+		},
+		{
+			name:   "explicit approval",
+			review: "No major issues found. Code follows Go best practices.",
+			want:   true,
+		},
+		{
+			name:   "no critical issues phrase",
+			review: "There are no critical issues, only minor suggestions.",
+			want:   true,
+		},
+		{
+			name:   "critical issues listed",
+			review: "## Critical Issues (Must Fix)\n- [calc.go:Div] division by zero is not handled\n\n## Suggestions\n- add docs",
+			want:   false,
+		},
+		{
+			name:   "empty critical section",
+			review: "## Critical Issues (Must Fix)\n\n## Suggestions (Should Consider)\n- [calc.go] add godoc",
+			want:   true,
+		},
+		{
+			name:   "critical section says none",
+			review: "## Critical Issues (Must Fix)\n- None\n\n## Positive Observations\n- clean code",
+			want:   true,
+		},
+		{
+			name:   "approval phrase with critical issues listed",
+			review: "## Critical Issues (Must Fix)\n- [calc.go:Div] division by zero is not handled\n\n## Positive Observations\n- no critical issues in the parser",
+			want:   false,
+		},
+		{
+			name:   "free-form review without verdict",
+			review: "The code looks mostly fine but Div panics on zero.",
+			want:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reviewApproved(tt.review); got != tt.want {
+				t.Errorf("reviewApproved() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoopPipeline_Run(t *testing.T) {
+	critical := "## Critical Issues (Must Fix)\n- [calc.go:Div] division by zero is not handled"
+
+	tests := []struct {
+		name          string
+		maxIterations int
+		turns         []fake.Turn
+		wantAuthors   []string
+		wantReview    string
+	}{
+		{
+			name: "fixes until review passes",
+			turns: []fake.Turn{
+				fake.Text("design"),
+				fake.Text("Created pkg/calc/calc.go"),
+				fake.Text("Created pkg/calc/calc_test.go"),
+				fake.Text(critical),
+				fake.Text("## Fixes Applied\n- [calc.go:Div] return an error on zero"),
+				fake.Text("No critical issues found."),
+			},
+			wantAuthors: []string{
+				"DesignAgent", "CodeWriterAgent", "DependencyAgent", "BuildAgent", "TDDExpertAgent",
+				"CodeReviewerAgent", "FixerAgent", "FixDependencyAgent", "FixBuildAgent",
+				"CodeReviewerAgent", "ReviewGateAgent",
+			},
+			wantReview: "No critical issues found.",
+		},
+		{
+			name:          "stops at iteration cap",
+			maxIterations: 1,
+			turns: []fake.Turn{
+				fake.Text("design"),
+				fake.Text("Created pkg/calc/calc.go"),
+				fake.Text("Created pkg/calc/calc_test.go"),
+				fake.Text(critical),
+				fake.Text("## Fixes Applied\n- none"),
+			},
+			wantAuthors: []string{
+				"DesignAgent", "CodeWriterAgent", "DependencyAgent", "BuildAgent", "TDDExpertAgent",
+				"CodeReviewerAgent", "FixerAgent", "FixDependencyAgent", "FixBuildAgent",
+			},
+			wantReview: critical,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model", tt.turns...)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:            mdl,
+				LoopPipeline:     true,
+				MaxFixIterations: tt.maxIterations,
+				WorkspaceDir:     t.TempDir(),
+				SkipTests:        true,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			events, state := runAgent(t, pipeline, "Build a calculator package")
+
+			if len(events) != len(tt.wantAuthors) {
+				t.Fatalf("got %d events, want %d", len(events), len(tt.wantAuthors))
+			}
+			for i, want := range tt.wantAuthors {
+				if events[i].Author != want {
+					t.Errorf("event %d author = %q, want %q", i, events[i].Author, want)
+				}
+			}
+			if got := stateString(t, state, "review_comments"); got != tt.wantReview {
+				t.Errorf("state[review_comments] = %q, want %q", got, tt.wantReview)
+			}
+			if got := mdl.Calls(); got != len(tt.turns) {
+				t.Errorf("model calls = %d, want %d", got, len(tt.turns))
+			}
+		})
+	}
+}
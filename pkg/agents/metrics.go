@@ -0,0 +1,54 @@
+package agents
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus metrics of the stage invocations, labeled by stage name. They are collected
+// by the hooks returned by MetricsHooks and exported once registered with RegisterMetrics.
+var (
+	stageRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_stage_runs_total",
+		Help: "Number of pipeline stage invocations, by status: ok or error.",
+	}, []string{"stage", "status"})
+	stageDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agi_stage_duration_seconds",
+		Help:    "Duration of pipeline stage invocations in seconds.",
+		Buckets: []float64{1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"stage"})
+	stageTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_stage_tokens_total",
+		Help: "Number of tokens used by pipeline stages, by type: prompt or completion.",
+	}, []string{"stage", "type"})
+)
+
+// RegisterMetrics registers the stage metrics with registerer, such as
+// prometheus.DefaultRegisterer or the registry of a metrics endpoint
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{stageRuns, stageDuration, stageTokens} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MetricsHooks returns the hooks that record the metrics of each stage invocation, for
+// PipelineConfig.Hooks
+func MetricsHooks() Hooks {
+	return Hooks{AfterStage: recordStageMetrics}
+}
+
+// recordStageMetrics records the metrics of the finished stage invocation result
+func recordStageMetrics(_ context.Context, result StageResult) {
+	status := "ok"
+	if result.Err != nil {
+		status = "error"
+	}
+	stageRuns.WithLabelValues(result.Stage, status).Inc()
+	stageDuration.WithLabelValues(result.Stage).Observe(result.Duration.Seconds())
+	stageTokens.WithLabelValues(result.Stage, "prompt").Add(float64(result.Usage.PromptTokens))
+	stageTokens.WithLabelValues(result.Stage, "completion").Add(float64(result.Usage.CompletionTokens))
+}
@@ -0,0 +1,53 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetricsHooks(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if err := RegisterMetrics(registry); err != nil {
+		t.Fatalf("RegisterMetrics() error = %v", err)
+	}
+	const stage = "MetricsTestAgent"
+	counter := func(c *prometheus.CounterVec, labels ...string) float64 {
+		return testutil.ToFloat64(c.WithLabelValues(labels...))
+	}
+	ok, failed := counter(stageRuns, stage, "ok"), counter(stageRuns, stage, "error")
+	prompt, completion := counter(stageTokens, stage, "prompt"), counter(stageTokens, stage, "completion")
+
+	hooks := MetricsHooks()
+	if hooks.BeforeStage != nil || hooks.AfterStage == nil {
+		t.Fatalf("MetricsHooks() = %+v, want only AfterStage", hooks)
+	}
+	info := StageInfo{Stage: stage}
+	hooks.AfterStage(context.Background(), StageResult{StageInfo: info, Duration: 2 * time.Second, Usage: TokenUsage{PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120}})
+	hooks.AfterStage(context.Background(), StageResult{StageInfo: info, Duration: time.Second, Usage: TokenUsage{PromptTokens: 50, CompletionTokens: 5, TotalTokens: 55}, Err: errors.New("timed out")})
+
+	if got := counter(stageRuns, stage, "ok") - ok; got != 1 {
+		t.Errorf("ok runs = %v, want 1", got)
+	}
+	if got := counter(stageRuns, stage, "error") - failed; got != 1 {
+		t.Errorf("failed runs = %v, want 1", got)
+	}
+	if got := counter(stageTokens, stage, "prompt") - prompt; got != 150 {
+		t.Errorf("prompt tokens = %v, want 150", got)
+	}
+	if got := counter(stageTokens, stage, "completion") - completion; got != 25 {
+		t.Errorf("completion tokens = %v, want 25", got)
+	}
+	if got := testutil.CollectAndCount(stageDuration, "agi_stage_duration_seconds"); got == 0 {
+		t.Error("no stage durations recorded")
+	}
+
+	// The metrics can be registered only once per registry
+	if err := RegisterMetrics(registry); err == nil {
+		t.Error("RegisterMetrics() twice error = nil, want an already registered error")
+	}
+}
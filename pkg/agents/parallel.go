@@ -0,0 +1,69 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/parallelagent"
+	"google.golang.org/adk/tool"
+)
+
+// testsAndDocsGroup is the parallel group of stages that only depend on the generated code
+const testsAndDocsGroup = "TestsAndDocsAgent"
+
+// groupParallelStages replaces each run of consecutive agents whose specs share a
+// non-empty ParallelGroup with a single parallel agent named after the group
+func groupParallelStages(specs []stageSpec, agents []agent.Agent) ([]agent.Agent, error) {
+	grouped := make([]agent.Agent, 0, len(agents))
+	for i := 0; i < len(agents); {
+		group := specs[i].ParallelGroup
+		j := i + 1
+		for group != "" && j < len(agents) && specs[j].ParallelGroup == group {
+			j++
+		}
+		if j-i < 2 {
+			grouped = append(grouped, agents[i])
+			i = j
+			continue
+		}
+
+		names := make([]string, 0, j-i)
+		for _, ag := range agents[i:j] {
+			names = append(names, ag.Name())
+		}
+		slog.Info("Creating parallel stage", "group", group, "stages", names)
+
+		parallel, err := parallelagent.New(parallelagent.Config{
+			AgentConfig: agent.Config{
+				Name:        group,
+				Description: fmt.Sprintf("Runs %s concurrently.", strings.Join(names, ", ")),
+				SubAgents:   agents[i:j],
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("parallel agent %s creation failed: %w", group, err)
+		}
+		grouped = append(grouped, parallel)
+		i = j
+	}
+	return grouped, nil
+}
+
+// documentationStage describes the documentation agent stage
+func documentationStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:          "DocumentationAgent",
+		Description:   "Writes package documentation and a README for the generated code.",
+		OutputKey:     "documentation",
+		ParallelGroup: testsAndDocsGroup,
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("DocumentationAgent"),
+	}
+}
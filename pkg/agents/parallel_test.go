@@ -0,0 +1,115 @@
+package agents
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+)
+
+// stageResponder replies with the name of the stage whose instruction is in the request
+func stageResponder(req *model.LLMRequest) fake.Turn {
+	instruction := ""
+	if req.Config != nil && req.Config.SystemInstruction != nil {
+		for _, part := range req.Config.SystemInstruction.Parts {
+			instruction += part.Text
+		}
+	}
+
+	roles := map[string]string{
+		"Go Software Architect": "design",
+		"Go Developer":          "code",
+		"Go Testing Expert":     "tests",
+		"Go Technical Writer":   "docs",
+		"Go Code Reviewer":      "No major issues found.",
+	}
+	for role, reply := range roles {
+		if strings.Contains(instruction, role) {
+			return fake.Text(reply)
+		}
+	}
+	return fake.Text("unknown stage")
+}
+
+func TestGroupParallelStages(t *testing.T) {
+	mdl := fake.New("fake-model")
+	specs := []stageSpec{
+		{Name: "A"},
+		{Name: "B", ParallelGroup: "G"},
+		{Name: "C", ParallelGroup: "G"},
+		{Name: "D", ParallelGroup: "H"},
+		{Name: "E"},
+	}
+
+	agents := make([]agent.Agent, 0, len(specs))
+	for _, spec := range specs {
+		ag, err := newLLMStage(PipelineConfig{Model: mdl}, spec)
+		if err != nil {
+			t.Fatalf("newLLMStage(%s) error = %v", spec.Name, err)
+		}
+		agents = append(agents, ag)
+	}
+
+	grouped, err := groupParallelStages(specs, agents)
+	if err != nil {
+		t.Fatalf("groupParallelStages() error = %v", err)
+	}
+
+	var names []string
+	for _, ag := range grouped {
+		names = append(names, ag.Name())
+	}
+	want := []string{"A", "G", "D", "E"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("grouped agents = %v, want %v", names, want)
+	}
+	if got := len(grouped[1].SubAgents()); got != 2 {
+		t.Errorf("parallel group has %d sub-agents, want 2", got)
+	}
+}
+
+func TestParallelStages_Run(t *testing.T) {
+	mdl := fake.NewWithResponder("fake-model", stageResponder)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:          mdl,
+		Documentation:  true,
+		ParallelStages: true,
+		SkipBuild:      true,
+		SkipTests:      true,
+		WorkspaceDir:   t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	if got := pipeline.SubAgents()[2].Name(); got != testsAndDocsGroup {
+		t.Fatalf("third stage = %q, want %q", got, testsAndDocsGroup)
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+
+	var authors []string
+	for _, event := range events {
+		authors = append(authors, event.Author)
+	}
+	if len(authors) != 5 {
+		t.Fatalf("got authors %v, want 5 events", authors)
+	}
+	if authors[0] != "DesignAgent" || authors[1] != "CodeWriterAgent" || authors[4] != "CodeReviewerAgent" {
+		t.Errorf("unexpected stage order: %v", authors)
+	}
+	concurrent := []string{authors[2], authors[3]}
+	sort.Strings(concurrent)
+	if concurrent[0] != "DocumentationAgent" || concurrent[1] != "TDDExpertAgent" {
+		t.Errorf("parallel stages = %v, want DocumentationAgent and TDDExpertAgent", concurrent)
+	}
+
+	for key, want := range map[string]string{"test_code": "tests", "documentation": "docs"} {
+		if got := stateString(t, state, key); got != want {
+			t.Errorf("state[%q] = %q, want %q", key, got, want)
+		}
+	}
+}
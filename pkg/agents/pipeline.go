@@ -2,33 +2,171 @@
 package agents
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
+	"slices"
+	"time"
 
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
 	"com.github.dimetron.adk-go-agi/pkg/tools"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/agent/workflowagents/sequentialagent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
 )
 
 // PipelineConfig holds configuration for creating a code pipeline agent
 type PipelineConfig struct {
 	// Model is the LLM model to use for all agents in the pipeline
-	Model model.LLM
+	Model model.LLM `yaml:"-"`
 	// Name is the name of the pipeline agent (defaults to "CodePipelineAgent")
-	Name string
+	Name string `yaml:"name"`
 	// Description is the description of the pipeline agent
-	Description string
+	Description string `yaml:"description"`
+	// Mode selects the root agent created by NewAgent and LoadPipeline: pipeline (the default) or chat
+	Mode string `yaml:"mode"`
+	// LoopPipeline repeats review and fix rounds until the review reports no critical issues
+	LoopPipeline bool `yaml:"loop_pipeline"`
+	// MaxFixIterations caps the review-and-fix rounds in loop mode (defaults to 3)
+	MaxFixIterations int `yaml:"max_fix_iterations"`
+	// FixOnReview runs a single fixer pass after the review when it reports critical issues, and completes the run otherwise
+	FixOnReview bool `yaml:"fix_on_review"`
+	// DesignCandidates drafts this many designs concurrently and has the DesignAgent judge them into one (0 or 1 disables)
+	DesignCandidates int `yaml:"design_candidates"`
+	// Models are the models ModelRouting can send stages to, keyed by the names the policy uses
+	Models map[string]model.LLM `yaml:"-"`
+	// ModelRouting sends bulky stages to one model and critical stages to another (empty uses Model for every stage)
+	ModelRouting ModelRouting `yaml:"model_routing"`
+	// DesignModels are the models the candidate designs are drafted with, in turn (defaults to Model)
+	DesignModels []model.LLM `yaml:"-"`
+	// APIDesign adds an APIDesignAgent that writes openapi.yaml for web services before code generation
+	APIDesign bool `yaml:"api_design"`
+	// APICodegen gives the CodeWriterAgent the oapiCodegen tool to generate types and server stubs from openapi.yaml; requires APIDesign and oapi-codegen
+	APICodegen bool `yaml:"api_codegen"`
+	// EnvVars are the environment variables, such as TARGET_GO_VERSION, that the code writer and chat agents may read with the env tool
+	EnvVars []string `yaml:"env_vars"`
+	// Planner adds a PlannerAgent that breaks the design into a task list the code writer works through
+	Planner bool `yaml:"planner"`
+	// MaxPlanIterations caps the code writing rounds while plan tasks remain (defaults to 3)
+	MaxPlanIterations int `yaml:"max_plan_iterations"`
+	// Documentation adds a DocumentationAgent stage after the TDD stage
+	Documentation bool `yaml:"documentation"`
+	// Benchmarks adds a BenchmarkAgent stage that writes benchmarks for performance-sensitive functions
+	Benchmarks bool `yaml:"benchmarks"`
+	// Deployment adds a DeploymentAgent stage that writes a Dockerfile and docker-compose.yaml for the service
+	Deployment bool `yaml:"deployment"`
+	// Kubernetes makes the DeploymentAgent write Kubernetes manifests as well
+	Kubernetes bool `yaml:"kubernetes"`
+	// CIProvider adds a CIAgent stage that writes a CI workflow for the project: github or gitlab
+	CIProvider string `yaml:"ci_provider"`
+	// AcceptanceStyle is the style of acceptance_tests stages: table (the default) or ginkgo
+	AcceptanceStyle string `yaml:"acceptance_style"`
+	// Lint adds a LintAgent stage that runs golangci-lint and feeds its findings to the reviewer
+	Lint bool `yaml:"lint"`
+	// SecurityReview adds a SecurityReviewAgent stage before the code review
+	SecurityReview bool `yaml:"security_review"`
+	// ParallelStages runs independent stages, such as tests and documentation, concurrently
+	ParallelStages bool `yaml:"parallel_stages"`
+	// WorkspaceDir is the directory stages read, write, and build files in (defaults to tools.DefaultWorkspaceDir)
+	WorkspaceDir string `yaml:"workspace_dir"`
+	// Sandbox runs builds, tests, and other workspace commands in Docker containers instead of on the host
+	Sandbox SandboxConfig `yaml:"sandbox"`
+	// SkipBuild disables the BuildAgent that compiles the generated code, and the DependencyAgent with it
+	SkipBuild bool `yaml:"skip_build"`
+	// SkipDependencies disables the DependencyAgent that runs go mod tidy before the build
+	SkipDependencies bool `yaml:"skip_dependencies"`
+	// SkipTests disables the TestRunnerAgent that runs the generated tests
+	SkipTests bool `yaml:"skip_tests"`
+	// MinCoverage is the minimum total statement coverage in percent (0 disables the coverage gate)
+	MinCoverage float64 `yaml:"min_coverage"`
+	// TestConcurrency generates the tests of each workspace Go file in a separate TDD run, this many files at a time (0 writes all tests in one run)
+	TestConcurrency int `yaml:"test_concurrency"`
+	// MaxTestIterations caps the test-writing rounds before the coverage gate fails (defaults to 3)
+	MaxTestIterations int `yaml:"max_test_iterations"`
+	// MinScore is the minimum critic score from 0 to 100; it adds a CriticAgent after the review
+	// whose score gates the review-and-fix loop in loop mode, and fails the run when not met
+	MinScore float64 `yaml:"min_score"`
+	// RequireDesignApproval pauses the pipeline after the design until a user approves it
+	RequireDesignApproval bool `yaml:"require_design_approval"`
+	// GitCommits initializes a git repository in the workspace and commits the changes of each stage
+	GitCommits bool `yaml:"git_commits"`
+	// MaxTotalTokens stops a run gracefully once its model calls have used this many tokens (0 disables)
+	MaxTotalTokens int `yaml:"max_total_tokens"`
+	// MaxCost stops a run gracefully once its model calls have cost this many US dollars, priced from Prices (0 disables)
+	MaxCost float64 `yaml:"max_cost"`
+	// Prices maps model names to their token prices for MaxCost
+	Prices map[string]ModelPrice `yaml:"prices"`
+	// CheckpointDir saves the state and a workspace snapshot there after each stage, so a run repeated with the same request resumes after the last completed stage (empty disables)
+	CheckpointDir string `yaml:"checkpoint_dir"`
+	// Rollback restores the workspace to its state before the run when a stage fails, so failed runs leave no half-written files
+	Rollback bool `yaml:"rollback"`
+	// QuarantineDir keeps the output of failed runs there, in a timestamped directory, before it is rolled back; setting it enables Rollback
+	QuarantineDir string `yaml:"quarantine_dir"`
+	// StageTimeout cancels an LLM stage attempt that runs longer than this and reports it as failed (0 disables)
+	StageTimeout time.Duration `yaml:"stage_timeout"`
+	// StageRetries retries an LLM stage that fails or returns no output this many times, with fresh context, before failing the run
+	StageRetries int `yaml:"stage_retries"`
+	// CompactStateChars summarizes stage outputs longer than this many characters before later stages see them (0 disables)
+	CompactStateChars int `yaml:"compact_state_chars"`
+	// SeedWorkspace is an existing project copied into an empty WorkspaceDir before the first stage, so the pipeline changes it instead of starting from scratch
+	SeedWorkspace string `yaml:"seed_workspace"`
+	// ContextFiles are workspace-relative paths or globs of key files whose contents the design stage sees
+	ContextFiles []string `yaml:"context_files"`
+	// PromptDir holds <stage name>.md prompt templates that replace the embedded defaults, read when the pipeline is created
+	PromptDir string `yaml:"prompt_dir"`
+	// InstructionOverrides replaces stage instruction templates, keyed by stage name
+	InstructionOverrides map[string]string `yaml:"instruction_overrides"`
+	// InstructionFiles loads stage instruction templates from files, keyed by stage name
+	InstructionFiles map[string]string `yaml:"instruction_files"`
+	// StageTools adds tools to LLM stages, keyed by stage name, on top of their built-in tools
+	StageTools map[string][]tool.Tool `yaml:"-"`
+	// PreStages are custom agents run before the built-in stages
+	PreStages []agent.Agent `yaml:"-"`
+	// PostStages are custom agents run after the built-in stages
+	PostStages []agent.Agent `yaml:"-"`
+	// Progress is notified as stages start and complete, use tokens, and write files
+	Progress ProgressListener `yaml:"-"`
+	// Hooks are called before and after each stage invocation with its duration, token usage, and output size, and with each of its events
+	Hooks Hooks `yaml:"-"`
+	// Drain, once closed, stops runs before their next top-level stage, so a server can shut down after the stages in progress complete
+	Drain <-chan struct{} `yaml:"-"`
+	// DryRun makes the pipeline answer with the stages, models, tools, and prompts it would run, and an estimate of their prompt tokens, without calling a model
+	DryRun bool `yaml:"dry_run"`
+	// Stages replaces the default stage list when set
+	Stages []StageConfig `yaml:"stages"`
+
+	// recorder records the LLM stages created for PlanPipeline
+	recorder *stageRecorder
 }
 
 // NewCodePipelineAgent creates a sequential agent pipeline for code generation, testing, and review
 func NewCodePipelineAgent(config PipelineConfig) (agent.Agent, error) {
+	if config.DryRun {
+		return newDryRunAgent(config)
+	}
+
 	// Validate config
 	if config.Model == nil {
 		return nil, fmt.Errorf("model cannot be nil")
 	}
+	if slices.Contains(config.DesignModels, nil) {
+		return nil, fmt.Errorf("design models cannot contain nil")
+	}
+	if config.LoopPipeline && config.FixOnReview {
+		return nil, fmt.Errorf("loop_pipeline and fix_on_review cannot both be set")
+	}
+	if _, ok := ciProviderInstructions[config.CIProvider]; config.CIProvider != "" && !ok {
+		return nil, fmt.Errorf("unknown CI provider %q (want %s or %s)", config.CIProvider, CIGitHub, CIGitLab)
+	}
+	if _, ok := acceptanceStyleInstructions[config.AcceptanceStyle]; config.AcceptanceStyle != "" && !ok {
+		return nil, fmt.Errorf("unknown acceptance test style %q (want %s or %s)", config.AcceptanceStyle, AcceptanceTable, AcceptanceGinkgo)
+	}
+	if err := checkSeedConfig(config); err != nil {
+		return nil, err
+	}
 
 	slog.Info("Creating code pipeline agent",
 		"name", config.Name,
@@ -43,63 +181,280 @@ func NewCodePipelineAgent(config PipelineConfig) (agent.Agent, error) {
 		config.Description = "Executes a sequence of code writing, test generation, and reviewing."
 	}
 
-	// Create sub-agents
-	slog.Info("Creating design agent")
-	designAgent, err := newDesignAgent(config.Model)
-	if err != nil {
-		slog.Error("Failed to create design agent", "error", err)
-		return nil, err
+	if config.WorkspaceDir == "" {
+		config.WorkspaceDir = tools.DefaultWorkspaceDir
 	}
-	if designAgent == nil {
-		slog.Error("Design agent is nil despite no error")
-		return nil, fmt.Errorf("design agent creation returned nil")
+	if err := applySandbox(config); err != nil {
+		return nil, err
 	}
-	slog.Info("Design agent created successfully")
+	config = withReadOnlyChecks(config)
 
-	slog.Info("Creating code writer agent")
-	codeWriterAgent, err := newCodeWriterAgent(config.Model)
+	// Build the stage list and adapt it to the model's capabilities
+	stages, err := pipelineStages(config)
 	if err != nil {
-		slog.Error("Failed to create code writer agent", "error", err)
+		slog.Error("Invalid pipeline stages", "error", err)
 		return nil, err
 	}
-	if codeWriterAgent == nil {
-		slog.Error("Code writer agent is nil despite no error")
-		return nil, fmt.Errorf("code writer agent creation returned nil")
+	fixer := fixerStage(config.WorkspaceDir)
+	if fixer.Instruction, err = stagePrompt(config, fixer.Name); err != nil {
+		return nil, err
 	}
-	slog.Info("Code writer agent created successfully")
-
-	slog.Info("Creating TDD expert agent")
-	tddExpertAgent, err := newTDDExpertAgent(config.Model)
+	overrides, err := instructionOverrides(config)
 	if err != nil {
-		slog.Error("Failed to create TDD expert agent", "error", err)
 		return nil, err
 	}
-	if tddExpertAgent == nil {
-		slog.Error("TDD expert agent is nil despite no error")
-		return nil, fmt.Errorf("TDD expert agent creation returned nil")
+	withFixer := append(slices.Clone(stages), fixer)
+	if err := applyInstructionOverrides(withFixer, overrides); err != nil {
+		return nil, err
 	}
-	slog.Info("TDD expert agent created successfully")
-
-	slog.Info("Creating code reviewer agent")
-	codeReviewerAgent, err := newCodeReviewerAgent(config.Model)
+	if err := applyStageTools(withFixer, config.StageTools); err != nil {
+		return nil, err
+	}
+	if err := applyModelRouting(withFixer, config); err != nil {
+		return nil, err
+	}
+	stages, fixer = withFixer[:len(stages)], withFixer[len(stages)]
+	if config.RequireDesignApproval {
+		if stages, err = withDesignApproval(stages); err != nil {
+			return nil, err
+		}
+	}
+	preStages, err := agentStages(config.PreStages)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pre-stages: %w", err)
+	}
+	postStages, err := agentStages(config.PostStages)
+	if err != nil {
+		return nil, fmt.Errorf("invalid post-stages: %w", err)
+	}
+	stages = slices.Concat(preStages, stages, postStages)
+	if config.GitCommits {
+		stages = withGitCommits(stages, config.WorkspaceDir)
+		fixer = withGitCommit(fixer, config.WorkspaceDir)
+	}
+	if seedsWorkspace(config) {
+		stages = append([]stageSpec{repoMapStage()}, stages...)
+	}
+	if config.CompactStateChars > 0 {
+		for i := range stages {
+			stages[i] = withCompaction(stages[i], config.Model, config.CompactStateChars)
+		}
+	}
+	seen := make(map[string]bool, len(stages))
+	for _, spec := range stages {
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("duplicate stage name %q", spec.Name)
+		}
+		seen[spec.Name] = true
+	}
+	budget, err := newRunBudget(config)
 	if err != nil {
-		slog.Error("Failed to create code reviewer agent", "error", err)
 		return nil, err
 	}
-	if codeReviewerAgent == nil {
-		slog.Error("Code reviewer agent is nil despite no error")
-		return nil, fmt.Errorf("code reviewer agent creation returned nil")
+	if budget != nil {
+		for i := range stages {
+			stages[i] = withBudget(stages[i], budget)
+		}
+		fixer = withBudget(fixer, budget)
+	}
+	toolSupport := make(map[string]bool)
+	supportsTools := func(spec stageSpec) bool {
+		llm := cmp.Or(spec.Model, config.Model)
+		supported, ok := toolSupport[llm.Name()]
+		if !ok {
+			supported = detectToolSupport(llm)
+			toolSupport[llm.Name()] = supported
+			if !supported {
+				slog.Warn("Model does not support tool calling; stages will return file contents inline",
+					"model", llm.Name())
+			}
+		}
+		return supported
+	}
+	for i := range stages {
+		if !supportsTools(stages[i]) {
+			stages[i] = withoutTools(stages[i])
+		}
+	}
+	if !supportsTools(fixer) {
+		fixer = withoutTools(fixer)
+	}
+	ciProvider := config.CIProvider
+	if ciProvider == "" {
+		ciProvider = CIGitHub
+	}
+	for i := range stages {
+		if stages[i].Builtin == builtinCI {
+			stages[i] = withCIProvider(stages[i], ciProvider)
+		}
+	}
+	acceptanceStyle := config.AcceptanceStyle
+	if acceptanceStyle == "" {
+		acceptanceStyle = AcceptanceTable
+	}
+	for i := range stages {
+		if stages[i].Builtin == builtinAcceptanceTests {
+			stages[i] = withAcceptanceStyle(stages[i], acceptanceStyle)
+		}
+	}
+	if config.Kubernetes {
+		for i := range stages {
+			if stages[i].Builtin == builtinDeployment {
+				stages[i] = withKubernetes(stages[i])
+			}
+		}
+	}
+	if slices.ContainsFunc(stages, func(spec stageSpec) bool { return spec.Builtin == builtinAPIDesign }) {
+		for i := range stages {
+			if stages[i].Builtin == builtinCodeWriter {
+				stages[i] = withAPISpec(stages[i])
+				if config.APICodegen && len(stages[i].Tools) > 0 {
+					stages[i] = withAPICodegen(stages[i], config.WorkspaceDir)
+				}
+			}
+		}
+	}
+	if len(config.EnvVars) > 0 {
+		for i := range stages {
+			if stages[i].Builtin == builtinCodeWriter && len(stages[i].Tools) > 0 {
+				stages[i] = withEnvTool(stages[i], config.EnvVars)
+			}
+		}
+	}
+	if seedsWorkspace(config) {
+		for i := range stages {
+			stages[i] = withExistingCode(stages[i])
+		}
+	}
+	for i := range stages {
+		writer := slices.Contains([]string{builtinCodeWriter, builtinTDDExpert, builtinAcceptanceTests}, stages[i].Builtin)
+		if writer && len(stages[i].Tools) > 0 {
+			stages[i] = withSyntaxCheck(stages[i])
+		}
+	}
+	if slices.ContainsFunc(stages, func(spec stageSpec) bool { return spec.Builtin == builtinPlanner }) {
+		for i := range stages {
+			if stages[i].Builtin == builtinCodeWriter {
+				stages[i] = withPlan(stages[i])
+			}
+		}
+	}
+	if config.DesignCandidates > 1 {
+		i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Builtin == builtinDesign })
+		if i < 0 {
+			return nil, fmt.Errorf("design candidates require a %s stage", builtinDesign)
+		}
+		stages[i] = withDesignEnsemble(stages[i], config.DesignCandidates, config.DesignModels)
+	}
+	if config.TestConcurrency > 0 {
+		for i := range stages {
+			if stages[i].Builtin == builtinTDDExpert {
+				stages[i] = withTestFanOut(stages[i], config.TestConcurrency)
+			}
+		}
+	}
+	if config.MinScore > 0 {
+		stages = withQualityGate(stages, config.MinScore)
+	}
+	if !config.SkipTests {
+		for i := range stages {
+			if stages[i].Builtin == builtinTDDExpert {
+				stages[i] = withTestRunner(stages[i])
+			}
+		}
 	}
-	slog.Info("Code reviewer agent created successfully")
 
-	// Validate all agents are non-nil before assembling pipeline
-	subAgents := []agent.Agent{
-		designAgent,
-		codeWriterAgent,
-		tddExpertAgent,
-		codeReviewerAgent,
+	// Create sub-agents
+	subAgents := make([]agent.Agent, 0, len(stages))
+	for _, spec := range stages {
+		slog.Info("Creating stage agent", "stage", spec.Name)
+		ag, err := newStage(config, spec)
+		if err != nil {
+			slog.Error("Failed to create stage agent", "stage", spec.Name, "error", err)
+			return nil, err
+		}
+		if ag == nil {
+			slog.Error("Stage agent is nil despite no error", "stage", spec.Name)
+			return nil, fmt.Errorf("%s creation returned nil", spec.Name)
+		}
+		if config.Hooks.enabled() {
+			if ag, err = newHooksAgent(ag, spec.OutputKey, config.Hooks); err != nil {
+				return nil, fmt.Errorf("failed to create hooks agent for %s: %w", spec.Name, err)
+			}
+		}
+		slog.Info("Stage agent created successfully", "stage", spec.Name)
+		subAgents = append(subAgents, ag)
+	}
+
+	// In loop mode the reviewer runs inside a review-and-fix loop
+	if config.LoopPipeline {
+		// The critic scores each round inside the loop
+		var critic agent.Agent
+		if i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Builtin == builtinCritic }); i >= 0 {
+			critic = subAgents[i]
+			stages = slices.Delete(stages, i, i+1)
+			subAgents = slices.Delete(subAgents, i, i+1)
+		}
+		reviewIndex := slices.IndexFunc(stages, func(spec stageSpec) bool {
+			return spec.Builtin == builtinCodeReviewer
+		})
+		if reviewIndex < 0 {
+			return nil, fmt.Errorf("loop pipeline requires a %s stage", builtinCodeReviewer)
+		}
+		loop, err := newReviewFixLoop(config, subAgents[reviewIndex], critic, fixer)
+		if err != nil {
+			slog.Error("Failed to create review-and-fix loop", "error", err)
+			return nil, err
+		}
+		subAgents[reviewIndex] = loop
+	}
+
+	// Otherwise the review outcome decides whether the fixer runs once
+	if config.FixOnReview {
+		reviewIndex := slices.IndexFunc(stages, func(spec stageSpec) bool {
+			return spec.Builtin == builtinCodeReviewer
+		})
+		if reviewIndex < 0 {
+			return nil, fmt.Errorf("fix on review requires a %s stage", builtinCodeReviewer)
+		}
+		branch, err := newReviewBranch(config, fixer)
+		if err != nil {
+			slog.Error("Failed to create review branch", "error", err)
+			return nil, err
+		}
+		stages = slices.Insert(stages, reviewIndex+1, stageSpec{Name: branch.Name()})
+		subAgents = slices.Insert(subAgents, reviewIndex+1, branch)
+	}
+
+	// Run independent stages concurrently
+	if config.ParallelStages {
+		grouped, err := groupParallelStages(stages, subAgents)
+		if err != nil {
+			slog.Error("Failed to create parallel stages", "error", err)
+			return nil, err
+		}
+		subAgents = grouped
+	}
+
+	// Report the progress of each stage
+	if config.Progress != nil {
+		for i, ag := range subAgents {
+			if subAgents[i], err = newProgressAgent(ag, config.Progress); err != nil {
+				return nil, fmt.Errorf("failed to create progress agent for %s: %w", ag.Name(), err)
+			}
+		}
 	}
 
+	// Stop before the next stage once the server drains
+	if config.Drain != nil {
+		for i, ag := range subAgents {
+			if subAgents[i], err = newDrainAgent(ag, config.Drain, config.CheckpointDir != ""); err != nil {
+				return nil, fmt.Errorf("failed to create drain agent for %s: %w", ag.Name(), err)
+			}
+		}
+	}
+
+	// Validate all agents are non-nil before assembling pipeline
 	for i, ag := range subAgents {
 		if ag == nil {
 			err := fmt.Errorf("sub-agent at index %d is nil", i)
@@ -120,14 +475,22 @@ func NewCodePipelineAgent(config PipelineConfig) (agent.Agent, error) {
 			"description", ag.Description())
 	}
 
-	// Create the sequential pipeline agent
-	pipelineAgent, err := sequentialagent.New(sequentialagent.Config{
-		AgentConfig: agent.Config{
-			Name:        config.Name,
-			SubAgents:   subAgents,
-			Description: config.Description,
-		},
-	})
+	// Create the sequential pipeline agent, checkpointing after each stage if configured
+	pipelineConfig := agent.Config{
+		Name:        config.Name,
+		SubAgents:   subAgents,
+		Description: config.Description,
+	}
+	if budget != nil {
+		pipelineConfig.BeforeAgentCallbacks = []agent.BeforeAgentCallback{resetBudget(budget)}
+		pipelineConfig.AfterAgentCallbacks = []agent.AfterAgentCallback{releaseBudget(budget)}
+	}
+	var pipelineAgent agent.Agent
+	if config.CheckpointDir != "" {
+		pipelineAgent, err = newCheckpointPipeline(pipelineConfig, config.CheckpointDir, config.WorkspaceDir)
+	} else {
+		pipelineAgent, err = sequentialagent.New(sequentialagent.Config{AgentConfig: pipelineConfig})
+	}
 	if err != nil {
 		slog.Error("Failed to create sequential pipeline agent", "error", err)
 		return nil, fmt.Errorf("sequential agent creation failed: %w", err)
@@ -137,6 +500,12 @@ func NewCodePipelineAgent(config PipelineConfig) (agent.Agent, error) {
 		return nil, fmt.Errorf("sequential pipeline agent creation returned nil")
 	}
 
+	if config.Rollback || config.QuarantineDir != "" {
+		if pipelineAgent, err = newRollbackAgent(pipelineAgent, config.WorkspaceDir, config.QuarantineDir); err != nil {
+			return nil, fmt.Errorf("failed to create rollback agent: %w", err)
+		}
+	}
+
 	slog.Info("Sequential pipeline agent created successfully",
 		"name", pipelineAgent.Name(),
 		"description", pipelineAgent.Description())
@@ -144,208 +513,178 @@ func NewCodePipelineAgent(config PipelineConfig) (agent.Agent, error) {
 	return pipelineAgent, nil
 }
 
+// stageSpec describes a stage of the code pipeline
+type stageSpec struct {
+	// Name is the agent name, unique within the pipeline
+	Name string
+	// Description is the agent description
+	Description string
+	// Instruction is the instruction template; {key} placeholders are resolved from session state
+	Instruction string
+	// OutputKey is the session state key the stage output is stored under
+	OutputKey string
+	// Tools are the tools available to the stage
+	Tools []tool.Tool
+	// Toolsets provide tools resolved for each model call, on top of Tools
+	Toolsets []tool.Toolset
+	// ParallelGroup names the group of adjacent independent stages this stage may run concurrently with
+	ParallelGroup string
+	// Custom builds a non-LLM stage; when set, Instruction, OutputKey, Tools, and Toolsets are ignored
+	Custom func(config PipelineConfig) (agent.Agent, error)
+	// Builtin is the built-in stage this spec was created from, if any
+	Builtin string
+	// BeforeAgentCallbacks run before the stage; returning content skips the stage
+	BeforeAgentCallbacks []agent.BeforeAgentCallback
+	// AfterAgentCallbacks run after the stage completes
+	AfterAgentCallbacks []agent.AfterAgentCallback
+	// OutputSchema constrains the stage response to JSON matching the schema
+	OutputSchema *genai.Schema
+	// BeforeModelCallbacks run before each model call; returning a response skips the call
+	BeforeModelCallbacks []llmagent.BeforeModelCallback
+	// AfterModelCallbacks run after each model response
+	AfterModelCallbacks []llmagent.AfterModelCallback
+	// Model overrides the pipeline model for the stage when set
+	Model model.LLM
+	// SyntaxCheck parses the Go files the stage writes and sends files with syntax errors back to it
+	SyntaxCheck bool
+}
+
+// newStage creates the agent for spec using the pipeline configuration
+func newStage(config PipelineConfig, spec stageSpec) (agent.Agent, error) {
+	if spec.Custom != nil {
+		return spec.Custom(config)
+	}
+	return newLLMStage(config, spec)
+}
+
+// agentStages wraps user-provided agents as pipeline stages
+func agentStages(agents []agent.Agent) ([]stageSpec, error) {
+	stages := make([]stageSpec, 0, len(agents))
+	for i, ag := range agents {
+		if ag == nil {
+			return nil, fmt.Errorf("agent at index %d is nil", i)
+		}
+		stages = append(stages, stageSpec{
+			Name:        ag.Name(),
+			Description: ag.Description(),
+			Custom: func(PipelineConfig) (agent.Agent, error) {
+				return ag, nil
+			},
+		})
+	}
+	return stages, nil
+}
+
+// newLLMStage creates an LLM agent for spec using the pipeline configuration, wrapped
+// in a timeout agent when StageTimeout is set, a retry agent when StageRetries is set, and
+// a syntax check agent when the stage has SyntaxCheck set. In read-only mode
+// (tools.ToolConfig.ReadOnly) the stage loses its mutating tools.
+func newLLMStage(config PipelineConfig, spec stageSpec) (agent.Agent, error) {
+	if tools.ToolConfig.ReadOnly {
+		spec = withReadOnlyTools(spec)
+	}
+	ag, err := llmagent.New(llmagent.Config{
+		Name:                 spec.Name,
+		Model:                cmp.Or(spec.Model, config.Model),
+		Tools:                spec.Tools,
+		Toolsets:             spec.Toolsets,
+		Instruction:          spec.Instruction,
+		Description:          spec.Description,
+		OutputKey:            spec.OutputKey,
+		OutputSchema:         spec.OutputSchema,
+		BeforeAgentCallbacks: spec.BeforeAgentCallbacks,
+		AfterAgentCallbacks:  spec.AfterAgentCallbacks,
+		BeforeModelCallbacks: spec.BeforeModelCallbacks,
+		AfterModelCallbacks:  spec.AfterModelCallbacks,
+	})
+	if err == nil && config.recorder != nil {
+		config.recorder.record(spec, cmp.Or(spec.Model, config.Model).Name())
+	}
+	if err == nil && config.StageTimeout > 0 {
+		ag, err = newTimeoutAgent(ag, config.StageTimeout)
+	}
+	if err == nil && config.StageRetries > 0 {
+		ag, err = newRetryAgent(ag, config.StageRetries)
+	}
+	if err != nil || !spec.SyntaxCheck {
+		return ag, err
+	}
+	return newSyntaxCheckAgent(ag, config.WorkspaceDir, defaultMaxSyntaxAttempts)
+}
+
 // newDesignAgent creates a design agent that creates a new design for the code
 func newDesignAgent(model model.LLM) (agent.Agent, error) {
-	return llmagent.New(llmagent.Config{
-		Name:  "DesignAgent",
-		Model: model,
-		Instruction: `You are a Go Software Architect. Create a high-level design for a Go application. Work completely autonomously without asking for clarification or user input.
-
-**Required Sections:**
-1. Architecture Overview - brief description
-2. Package Structure - list packages and key files (pkg/, internal/, cmd/)
-3. Design Patterns - which patterns to use and where
-4. Key Interfaces - main abstractions for testability
-5. Dependencies - only essential external packages with justification
-6. Error Handling & Concurrency - strategies
-
-**Format Example:**
-## Architecture Overview
-[description]
-
-## Package Structure
-- pkg/user/
-  - user.go - domain model
-  - repository.go - data access interface
-
-## Design Patterns
-- Repository: abstract data access
-
-## Key Interfaces
-- UserRepository: CRUD operations
-
-## Dependencies
-- none (use stdlib)
-
-**Constraints:**
-- Follow Go standard layout
-- Minimize dependencies
-- Target >85% test coverage
-- Include concurrency where beneficial
-
-**IMPORTANT: Complete the entire design now. Do not ask for clarification. Provide a complete, detailed design document covering all required sections.**`,
+	return newLLMStage(PipelineConfig{Model: model}, designStage())
+}
+
+// designStage describes the design agent stage
+func designStage() stageSpec {
+	return stageSpec{
+		Name:        "DesignAgent",
 		Description: "Creates a new design for the code.",
 		OutputKey:   "design",
-	})
+		Instruction: prompts.Default("DesignAgent"),
+	}
 }
 
 // newCodeWriterAgent creates a code writer agent that generates Go code from specifications
 func newCodeWriterAgent(model model.LLM) (agent.Agent, error) {
-	return llmagent.New(llmagent.Config{
-		Name:  "CodeWriterAgent",
-		Model: model,
-		Tools: []tool.Tool{
-			tools.FileReadTool(),
-			tools.FileWriteTool(),
-		},
-		Instruction: `You are a Go Developer. Implement code from the design below. Use fileWrite to save files. Work completely autonomously without asking questions or waiting for approval.
-
-**Design:**
-{design}
-
-**Tools:**
-- fileRead: Read existing files
-- fileWrite: Save code files (use this for ALL code)
-
-**Process:**
-1. Read design to identify files
-2. For each file, generate complete Go code
-3. Use fileWrite with path and content
-4. List all files created at the end
-
-**File Paths:**
-- pkg/packagename/file.go - public packages
-- internal/packagename/file.go - private packages
-- cmd/appname/main.go - main executables
-
-**Code Standards:**
-- Add godoc comments for exported items
-- Return errors as last value, wrap with %w
-- Use interfaces for abstraction
-- Prefer composition over inheritance
-- Use defer for cleanup
-- Keep functions <50 lines
-- Validate inputs
-
-**Example fileWrite:**
-path: "pkg/user/user.go"
-content: "package user\n\n// User represents...\ntype User struct {...}"
-
-**CRITICAL: You MUST generate and save ALL files now. Do not stop until every file from the design is created. Do not ask for confirmation. Complete the entire implementation.**`,
+	return newLLMStage(PipelineConfig{Model: model}, codeWriterStage(tools.DefaultWorkspaceDir))
+}
+
+// codeWriterStage describes the code writer agent stage
+func codeWriterStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "CodeWriterAgent",
 		Description: "Writes initial Go code based on a specification.",
 		OutputKey:   "generated_code",
-	})
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("CodeWriterAgent"),
+	}
 }
 
 // newTDDExpertAgent creates a TDD expert agent that writes comprehensive tests
 func newTDDExpertAgent(model model.LLM) (agent.Agent, error) {
-	return llmagent.New(llmagent.Config{
-		Name:  "TDDExpertAgent",
-		Model: model,
-		Tools: []tool.Tool{
-			tools.FileReadTool(),
-			tools.FileWriteTool(),
-		},
-		Instruction: `You are a Go Testing Expert. Write tests for code files. Target >85% coverage. Use fileRead to read code, fileWrite to save tests. Work completely autonomously without requesting input.
-
-**Code Reference:**
-{generated_code}
-
-**Tools:**
-- fileRead: Read .go files
-- fileWrite: Save test files
-
-**Process:**
-1. Use fileRead on each .go file (skip _test.go)
-2. Write tests for each file
-3. Use fileWrite to save as filename_test.go in same directory
-4. List all test files created
-
-**Test Requirements:**
-- Package: use package_test for black-box tests
-- Naming: TestFunction_Scenario
-- Structure: table-driven tests with t.Run()
-- Coverage: all exported items, success/error paths, edge cases
-- Format: Arrange-Act-Assert (AAA)
-
-**Table-Driven Test Template:**
-tests := []struct {
-    name    string
-    input   Type
-    want    Type
-    wantErr bool
-}{
-    {"valid", validInput, expected, false},
-    {"invalid", badInput, nil, true},
-}
-for _, tt := range tests {
-    t.Run(tt.name, func(t *testing.T) {...})
+	return newLLMStage(PipelineConfig{Model: model}, tddExpertStage(tools.DefaultWorkspaceDir))
 }
 
-**Test Cases:**
-- Happy path and errors
-- Nil/empty/zero values
-- Boundary conditions
-- Use errors.Is() for error checks
-
-**Example fileWrite:**
-path: "pkg/user/user_test.go"
-content: "package user_test\n\nimport \"testing\"\n\nfunc TestUser_Valid(t *testing.T) {...}"
-
-**MANDATORY: Create ALL test files now. Do not stop until every code file has corresponding tests. Do not ask for permission. Complete all test generation immediately.**`,
-		Description: "Writes comprehensive Go tests following TDD best practices.",
-		OutputKey:   "test_code",
-	})
+// tddExpertStage describes the TDD expert agent stage
+func tddExpertStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:          "TDDExpertAgent",
+		Description:   "Writes comprehensive Go tests following TDD best practices.",
+		OutputKey:     "test_code",
+		ParallelGroup: testsAndDocsGroup,
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileWriteToolWithWorkspace(workspaceDir),
+			tools.NewGoSymbolsToolWithWorkspace(workspaceDir),
+			tools.NewGoRaceToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("TDDExpertAgent"),
+	}
 }
 
 // newCodeReviewerAgent creates a code reviewer agent that provides feedback
 func newCodeReviewerAgent(model model.LLM) (agent.Agent, error) {
-	return llmagent.New(llmagent.Config{
-		Name:  "CodeReviewerAgent",
-		Model: model,
-		Tools: []tool.Tool{
-			tools.FileReadTool(),
-		},
-		Instruction: `You are a Senior Go Code Reviewer. Review all code files for correctness, quality, and best practices. Use fileRead to examine files. Work completely autonomously without asking questions.
-
-**Tools:**
-- fileRead: Read code files for review
-
-**Process:**
-1. Use fileRead on all .go files (code and tests)
-2. Check each file against review criteria
-3. Provide structured feedback
-
-**Code Reference:**
-{generated_code}
-
-**Review Criteria:**
-- Correctness: logic errors, bugs, proper error handling
-- Go Idioms: interfaces, composition, error wrapping (%w), defer usage
-- Quality: readable code, descriptive names, functions <50 lines, no duplication
-- Documentation: godoc comments for all exported items
-- Edge Cases: nil/empty/zero values, input validation
-- Performance: unnecessary allocations, efficient data structures
-- Concurrency: proper goroutine/channel usage, race condition checks
-- Security: input validation, injection prevention
-- Testability: dependency injection, minimal side effects
-
-**Output Format:**
-## Critical Issues (Must Fix)
-- [file:function] [specific issue and fix]
-
-## Suggestions (Should Consider)
-- [file] [improvement with rationale]
-
-## Positive Observations
-- [what works well]
-
-If no issues: "No major issues found. Code follows Go best practices."
-
-Be specific, constructive, and actionable.
+	return newLLMStage(PipelineConfig{Model: model}, codeReviewerStage(tools.DefaultWorkspaceDir))
+}
 
-**REQUIRED: Complete the full review now. Read ALL files and provide comprehensive feedback. Do not ask for clarification. Finish the entire code review process immediately.**`,
+// codeReviewerStage describes the code reviewer agent stage
+func codeReviewerStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "CodeReviewerAgent",
 		Description: "Reviews code and provides feedback.",
 		OutputKey:   "review_comments",
-	})
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileListToolWithWorkspace(workspaceDir),
+			tools.NewDiffToolWithWorkspace(workspaceDir),
+			tools.NewGoSymbolsToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("CodeReviewerAgent"),
+	}
 }
@@ -0,0 +1,227 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+	"google.golang.org/genai"
+)
+
+// defaultMaxPlanIterations is the default cap on code writing rounds while plan tasks remain
+const defaultMaxPlanIterations = 3
+
+// planLinePattern matches a numbered plan task, optionally with a checkbox: "3. [x] Write pkg/calc/calc.go"
+var planLinePattern = regexp.MustCompile(`^\s*(\d+)[.)]\s+(?:\[([ xX])\]\s*)?(.+?)\s*$`)
+
+// planTask is a task of the plan stored under the task_plan state key
+type planTask struct {
+	// Number is the task number shown to the agents
+	Number int
+	// Title describes the task
+	Title string
+	// Done reports whether an agent marked the task complete
+	Done bool
+}
+
+// parsePlan extracts the numbered tasks from plan text, ignoring other lines
+func parsePlan(text string) []planTask {
+	var tasks []planTask
+	for _, line := range strings.Split(text, "\n") {
+		m := planLinePattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		number, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+		tasks = append(tasks, planTask{
+			Number: number,
+			Title:  m[3],
+			Done:   strings.EqualFold(m[2], "x"),
+		})
+	}
+	return tasks
+}
+
+// formatPlan renders tasks as a numbered checklist that parsePlan reads back
+func formatPlan(tasks []planTask) string {
+	var b strings.Builder
+	for _, task := range tasks {
+		mark := " "
+		if task.Done {
+			mark = "x"
+		}
+		fmt.Fprintf(&b, "%d. [%s] %s\n", task.Number, mark, task.Title)
+	}
+	return b.String()
+}
+
+// remainingTasks returns the titles of the tasks not yet done
+func remainingTasks(tasks []planTask) []string {
+	var remaining []string
+	for _, task := range tasks {
+		if !task.Done {
+			remaining = append(remaining, fmt.Sprintf("%d. %s", task.Number, task.Title))
+		}
+	}
+	return remaining
+}
+
+// plannerStage describes the planner agent stage
+func plannerStage() stageSpec {
+	return stageSpec{
+		Name:        "PlannerAgent",
+		Description: "Breaks the request into a numbered task list.",
+		OutputKey:   "task_plan",
+		Instruction: prompts.Default("PlannerAgent"),
+	}
+}
+
+// planInstruction is appended to stages that work through the task plan
+const planInstruction = `
+
+**Task Plan (complete the unchecked tasks in order):**
+{task_plan?}`
+
+// planTrackingInstruction is appended to stages that mark plan tasks complete
+const planTrackingInstruction = `
+
+After finishing each task, call markTaskComplete with its number. Tasks marked [x] are already done; do not redo them.`
+
+// withPlan gives a stage the task plan. Stages with tools also get the
+// markTaskComplete tool and run in a loop until every task is complete.
+func withPlan(spec stageSpec) stageSpec {
+	spec.Instruction += planInstruction
+	if len(spec.Tools) == 0 {
+		return spec
+	}
+	spec.Tools = append(spec.Tools, newMarkTaskCompleteTool())
+	spec.Instruction += planTrackingInstruction
+	return withPlanLoop(spec)
+}
+
+// withPlanLoop wraps the code writer stage in a loop that runs it again while plan
+// tasks remain, so a large plan is not lost to a truncated response
+func withPlanLoop(writer stageSpec) stageSpec {
+	return stageSpec{
+		Name:          "PlanLoopAgent",
+		Description:   "Writes code until every task of the plan is complete.",
+		ParallelGroup: writer.ParallelGroup,
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			writerAgent, err := newLLMStage(config, writer)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", writer.Name, err)
+			}
+			gate, err := newPlanGateAgent()
+			if err != nil {
+				return nil, fmt.Errorf("failed to create plan gate: %w", err)
+			}
+
+			maxIterations := config.MaxPlanIterations
+			if maxIterations <= 0 {
+				maxIterations = defaultMaxPlanIterations
+			}
+			return newBoundedLoopAgent(agent.Config{
+				Name:        "PlanLoopAgent",
+				Description: "Writes code until every task of the plan is complete.",
+				SubAgents:   []agent.Agent{writerAgent, gate},
+			}, maxIterations)
+		},
+	}
+}
+
+// newPlanGateAgent creates an agent that ends the enclosing loop once every task of
+// the plan is complete, or when there is no plan to track
+func newPlanGateAgent() (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "PlanGateAgent",
+		Description: "Stops the code writing loop when every plan task is complete.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				remaining := remainingTasks(parsePlan(readStateString(ctx.Session().State(), "task_plan")))
+				if len(remaining) > 0 {
+					slog.Info("Plan tasks remaining, running code writer again", "remaining", len(remaining))
+					return
+				}
+
+				slog.Info("All plan tasks complete, exiting loop")
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText("All plan tasks are complete.", genai.RoleModel)
+				event.Actions.Escalate = true
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// markTaskCompleteInput defines the input parameters for the markTaskComplete tool
+type markTaskCompleteInput struct {
+	// TaskNumber is the number of the completed task
+	TaskNumber int `json:"taskNumber"`
+}
+
+// markTaskCompleteOutput defines the output structure for the markTaskComplete tool
+type markTaskCompleteOutput struct {
+	// Success indicates whether the task was marked complete
+	Success bool `json:"success"`
+	// Remaining lists the tasks not yet complete
+	Remaining []string `json:"remaining,omitempty"`
+	// Error contains the error message if the task could not be marked
+	Error string `json:"error,omitempty"`
+}
+
+// markTaskComplete marks a task of the plan in state complete
+func markTaskComplete(state session.State, input markTaskCompleteInput) (*markTaskCompleteOutput, error) {
+	tasks := parsePlan(readStateString(state, "task_plan"))
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("there is no task plan")
+	}
+
+	found := false
+	for i := range tasks {
+		if tasks[i].Number == input.TaskNumber {
+			tasks[i].Done = true
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("task %d is not in the plan", input.TaskNumber)
+	}
+
+	if err := state.Set("task_plan", formatPlan(tasks)); err != nil {
+		return nil, fmt.Errorf("failed to update task plan: %w", err)
+	}
+	slog.Info("Plan task completed", "task", input.TaskNumber)
+	return &markTaskCompleteOutput{Success: true, Remaining: remainingTasks(tasks)}, nil
+}
+
+// newMarkTaskCompleteTool creates the tool agents use to mark plan tasks complete
+func newMarkTaskCompleteTool() tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "markTaskComplete",
+			Description: "Mark a task of the task plan complete by its number. Returns the tasks that remain.",
+		},
+		func(ctx tool.Context, input markTaskCompleteInput) *markTaskCompleteOutput {
+			output, err := markTaskComplete(ctx.State(), input)
+			if err != nil {
+				return &markTaskCompleteOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create markTaskComplete tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,107 @@
+package agents
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestParsePlan(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []planTask
+	}{
+		{
+			name: "checklist",
+			text: "1. [ ] Create go.mod\n2. [x] Create pkg/calc/calc.go\n",
+			want: []planTask{
+				{Number: 1, Title: "Create go.mod"},
+				{Number: 2, Title: "Create pkg/calc/calc.go", Done: true},
+			},
+		},
+		{
+			name: "plain numbered list with prose",
+			text: "Here is the plan:\n\n1) Create go.mod\n 2. Create pkg/calc/calc.go  \nGood luck!",
+			want: []planTask{
+				{Number: 1, Title: "Create go.mod"},
+				{Number: 2, Title: "Create pkg/calc/calc.go"},
+			},
+		},
+		{
+			name: "no tasks",
+			text: "I could not plan this.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parsePlan(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePlan() = %+v, want %+v", got, tt.want)
+			}
+			if len(got) > 0 && !reflect.DeepEqual(parsePlan(formatPlan(got)), got) {
+				t.Errorf("parsePlan(formatPlan()) does not round-trip: %q", formatPlan(got))
+			}
+		})
+	}
+}
+
+func TestPlanner_Run(t *testing.T) {
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("1. [ ] Create pkg/calc/add.go\n2. [ ] Create pkg/calc/sub.go"),
+		fake.FunctionCall("markTaskComplete", map[string]any{"taskNumber": 1}),
+		fake.Text("Created pkg/calc/add.go"),
+		fake.FunctionCall("markTaskComplete", map[string]any{"taskNumber": 2}),
+		fake.Text("Created pkg/calc/sub.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+		Planner:      true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a calculator package")
+
+	want := "1. [x] Create pkg/calc/add.go\n2. [x] Create pkg/calc/sub.go\n"
+	if got := stateString(t, state, "task_plan"); got != want {
+		t.Errorf("state[task_plan] = %q, want %q", got, want)
+	}
+	if got := mdl.Calls(); got != 8 {
+		t.Errorf("model calls = %d, want 8", got)
+	}
+
+	// The second code writing round sees the first task as done
+	requests := mdl.Requests()
+	if got := requests[4].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "1. [x] Create pkg/calc/add.go") {
+		t.Errorf("second round instruction does not show the completed task: %q", got)
+	}
+}
+
+func TestPlanner_WithoutTools(t *testing.T) {
+	stages, err := pipelineStages(PipelineConfig{WorkspaceDir: t.TempDir(), Planner: true, SkipBuild: true})
+	if err != nil {
+		t.Fatalf("pipelineStages() error = %v", err)
+	}
+	if stages[1].Name != "PlannerAgent" {
+		t.Fatalf("stage 1 = %s, want PlannerAgent", stages[1].Name)
+	}
+
+	writer := withPlan(withoutTools(stages[2]))
+	if writer.Custom != nil {
+		t.Error("withPlan() looped a stage without tools")
+	}
+	if !strings.Contains(writer.Instruction, "{task_plan?}") || strings.Contains(writer.Instruction, "markTaskComplete") {
+		t.Errorf("instruction without tools = %q, want the plan without markTaskComplete", writer.Instruction)
+	}
+}
@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"iter"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+)
+
+// ProgressListener receives progress notifications from a pipeline run. Methods are
+// called synchronously from the goroutine running the pipeline and should return quickly.
+type ProgressListener interface {
+	// StageStarted is called when a top-level stage starts
+	StageStarted(stage string)
+	// StageCompleted is called when a top-level stage finishes; err is the first error it reported
+	StageCompleted(stage string, err error)
+	// TokensUsed is called for each model response that reports token usage; agent is the
+	// agent that made the request, which may be nested within stage
+	TokensUsed(stage, agent string, usage TokenUsage)
+	// FileWritten is called when an agent of stage writes a workspace file with fileWrite
+	FileWritten(stage, agent, path string)
+}
+
+// TokenUsage is the token usage of a model response
+type TokenUsage struct {
+	// PromptTokens is the number of tokens in the request
+	PromptTokens int `json:"promptTokens"`
+	// CompletionTokens is the number of tokens in the response
+	CompletionTokens int `json:"completionTokens"`
+	// TotalTokens is the total number of tokens, including any the model reports beyond the two above
+	TotalTokens int `json:"totalTokens"`
+}
+
+// newProgressAgent wraps the stage agent inner so that listener is notified of its
+// progress: start and completion, and the token usage and file writes found in its events
+func newProgressAgent(inner agent.Agent, listener ProgressListener) (agent.Agent, error) {
+	stage := stageName(inner)
+	return agent.New(agent.Config{
+		Name:        stage + "Progress",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				listener.StageStarted(stage)
+				var stageErr error
+				defer func() { listener.StageCompleted(stage, stageErr) }()
+
+				for event, err := range inner.Run(ctx) {
+					if err != nil && stageErr == nil {
+						stageErr = err
+					}
+					if event != nil {
+						reportEventProgress(listener, stage, event)
+					}
+					if !yield(event, err) {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// stageName returns the name of the stage agent ag, looking through wrappers such as
+// retry agents that are named after the single agent they wrap
+func stageName(ag agent.Agent) string {
+	for len(ag.SubAgents()) == 1 && strings.HasPrefix(ag.Name(), ag.SubAgents()[0].Name()) {
+		ag = ag.SubAgents()[0]
+	}
+	return ag.Name()
+}
+
+// reportEventProgress notifies listener of the token usage and file writes in event
+func reportEventProgress(listener ProgressListener, stage string, event *session.Event) {
+	if usage := event.UsageMetadata; usage != nil && !event.Partial {
+		listener.TokensUsed(stage, event.Author, TokenUsage{
+			PromptTokens:     int(usage.PromptTokenCount),
+			CompletionTokens: int(usage.CandidatesTokenCount),
+			TotalTokens:      int(usage.TotalTokenCount),
+		})
+	}
+	if event.Content == nil {
+		return
+	}
+	for _, part := range event.Content.Parts {
+		response := part.FunctionResponse
+		if response == nil || response.Name != "fileWrite" {
+			continue
+		}
+		path, _ := response.Response["path"].(string)
+		if success, _ := response.Response["success"].(bool); success && path != "" {
+			listener.FileWritten(stage, event.Author, path)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package agents
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/genai"
+)
+
+// recordingListener records progress notifications as strings
+type recordingListener struct {
+	calls []string
+}
+
+func (l *recordingListener) StageStarted(stage string) {
+	l.calls = append(l.calls, "started "+stage)
+}
+
+func (l *recordingListener) StageCompleted(stage string, err error) {
+	l.calls = append(l.calls, fmt.Sprintf("completed %s err=%v", stage, err))
+}
+
+func (l *recordingListener) TokensUsed(stage, agent string, usage TokenUsage) {
+	l.calls = append(l.calls, fmt.Sprintf("tokens %s/%s %d+%d=%d", stage, agent, usage.PromptTokens, usage.CompletionTokens, usage.TotalTokens))
+}
+
+func (l *recordingListener) FileWritten(stage, agent, path string) {
+	l.calls = append(l.calls, fmt.Sprintf("wrote %s/%s %s", stage, agent, path))
+}
+
+func TestProgressListener(t *testing.T) {
+	usage := func(prompt, completion int32) *genai.GenerateContentResponseUsageMetadata {
+		return &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:     prompt,
+			CandidatesTokenCount: completion,
+			TotalTokenCount:      prompt + completion,
+		}
+	}
+	designTurn := fake.Text("design")
+	designTurn.Usage = usage(100, 20)
+	writeTurn := fake.FunctionCall("fileWrite", map[string]any{"path": "pkg/calc/calc.go", "content": "package calc\n"})
+	writeTurn.Usage = usage(150, 30)
+	mdl := fake.New("fake-model",
+		designTurn,
+		writeTurn,
+		fake.Text("Created pkg/calc/calc.go"),
+	)
+	listener := &recordingListener{}
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: t.TempDir(),
+		Stages:       []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+		Progress:     listener,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	events, _ := runAgent(t, pipeline, "Build a calculator package")
+
+	want := []string{
+		"started DesignAgent",
+		"tokens DesignAgent/DesignAgent 100+20=120",
+		"completed DesignAgent err=<nil>",
+		"started CodeWriterAgent",
+		"tokens CodeWriterAgent/CodeWriterAgent 150+30=180",
+		"wrote CodeWriterAgent/CodeWriterAgent pkg/calc/calc.go",
+		"completed CodeWriterAgent err=<nil>",
+	}
+	if !slices.Equal(listener.calls, want) {
+		t.Errorf("progress calls =\n%s\nwant\n%s", strings.Join(listener.calls, "\n"), strings.Join(want, "\n"))
+	}
+	// The listener does not change the events of the run
+	for _, event := range events {
+		if strings.HasSuffix(event.Author, "Progress") {
+			t.Errorf("event authored by the progress wrapper: %+v", event)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package agents
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/workflowagents/sequentialagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// Verdicts of a pull-request review
+const (
+	PRVerdictApprove        = "approve"
+	PRVerdictRequestChanges = "request_changes"
+	PRVerdictComment        = "comment"
+)
+
+// PRReviewConfig holds configuration for creating a pull-request review agent
+type PRReviewConfig struct {
+	// Model is the LLM model used to review the change
+	Model model.LLM
+	// Name is the name of the review agent (defaults to "PRReviewAgent")
+	Name string
+	// Description is the description of the review agent
+	Description string
+	// RepoDir is the checkout of the existing repository the diff applies to; it is only read
+	RepoDir string
+}
+
+// PRReview is the structured review stored under the pr_review state key
+type PRReview struct {
+	// Summary describes the change and the overall assessment
+	Summary string `json:"summary"`
+	// Verdict is one of the PRVerdict values
+	Verdict string `json:"verdict"`
+	// Comments are the findings, most important first
+	Comments []PRReviewComment `json:"comments"`
+}
+
+// PRReviewComment is a single review finding
+type PRReviewComment struct {
+	// File is the repository path of the file the comment refers to
+	File string `json:"file"`
+	// Line is the line in the new version of the file, or 0 for a file-level comment
+	Line int `json:"line"`
+	// Severity is critical, major, minor, or nit
+	Severity string `json:"severity"`
+	// Message explains the issue and the suggested change
+	Message string `json:"message"`
+}
+
+// prReviewSchema constrains the review report to a PRReview
+var prReviewSchema = &genai.Schema{
+	Type: genai.TypeObject,
+	Properties: map[string]*genai.Schema{
+		"summary": {Type: genai.TypeString, Description: "What the change does and the overall assessment"},
+		"verdict": {
+			Type: genai.TypeString,
+			Enum: []string{PRVerdictApprove, PRVerdictRequestChanges, PRVerdictComment},
+		},
+		"comments": {
+			Type:        genai.TypeArray,
+			Description: "Review findings, most important first",
+			Items: &genai.Schema{
+				Type: genai.TypeObject,
+				Properties: map[string]*genai.Schema{
+					"file":     {Type: genai.TypeString},
+					"line":     {Type: genai.TypeInteger, Description: "Line in the new version of the file, 0 for the whole file"},
+					"severity": {Type: genai.TypeString, Enum: []string{"critical", "major", "minor", "nit"}},
+					"message":  {Type: genai.TypeString},
+				},
+				Required: []string{"file", "line", "severity", "message"},
+			},
+		},
+	},
+	Required: []string{"summary", "verdict", "comments"},
+}
+
+// noRepoToolsInstruction replaces repository reads for models without tool calling support
+const noRepoToolsInstruction = `
+
+**Tool calling is NOT available for this model.** Ignore any instructions to call fileRead and review the diff on its own.`
+
+// NewPRReviewAgent creates an agent that reviews the diff or patch given as the user
+// message against an existing repository. A PRDiffReviewerAgent reads the changed files
+// and their callers from the read-only RepoDir and records its findings under
+// pr_findings, and a PRReviewReportAgent turns them into a PRReview stored as JSON under
+// pr_review; use ParsePRReview to decode it.
+func NewPRReviewAgent(config PRReviewConfig) (agent.Agent, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+	if config.RepoDir == "" {
+		return nil, fmt.Errorf("repository directory cannot be empty")
+	}
+	if info, err := os.Stat(config.RepoDir); err != nil {
+		return nil, fmt.Errorf("invalid repository directory: %w", err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("repository path %s is not a directory", config.RepoDir)
+	}
+	if config.Name == "" {
+		config.Name = "PRReviewAgent"
+	}
+	if config.Description == "" {
+		config.Description = "Reviews a pull-request diff against an existing repository."
+	}
+
+	slog.Info("Creating pull-request review agent",
+		"name", config.Name,
+		"model", config.Model.Name(),
+		"repo", config.RepoDir)
+
+	reviewer := prDiffReviewerStage(config.RepoDir)
+	if !detectToolSupport(config.Model) {
+		slog.Warn("Model does not support tool calling; reviewing the diff without repository context",
+			"model", config.Model.Name())
+		reviewer.Tools = nil
+		reviewer.Instruction += noRepoToolsInstruction
+	}
+
+	stageConfig := PipelineConfig{Model: config.Model, WorkspaceDir: config.RepoDir}
+	subAgents := make([]agent.Agent, 0, 2)
+	for _, spec := range []stageSpec{reviewer, prReviewReportStage()} {
+		ag, err := newLLMStage(stageConfig, spec)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", spec.Name, err)
+		}
+		subAgents = append(subAgents, ag)
+	}
+
+	return sequentialagent.New(sequentialagent.Config{
+		AgentConfig: agent.Config{
+			Name:        config.Name,
+			Description: config.Description,
+			SubAgents:   subAgents,
+		},
+	})
+}
+
+// prDiffReviewerStage describes the stage that reviews the diff with read-only access to the repository
+func prDiffReviewerStage(repoDir string) stageSpec {
+	return stageSpec{
+		Name:        "PRDiffReviewerAgent",
+		Description: "Reviews a diff in the context of the repository it applies to.",
+		OutputKey:   "pr_findings",
+		Tools:       []tool.Tool{tools.NewFileReadToolWithWorkspace(repoDir)},
+		Instruction: prompts.Default("PRDiffReviewerAgent"),
+	}
+}
+
+// prReviewReportStage describes the stage that turns the review findings into a PRReview
+func prReviewReportStage() stageSpec {
+	return stageSpec{
+		Name:         "PRReviewReportAgent",
+		Description:  "Turns the review findings into a structured review.",
+		OutputKey:    "pr_review",
+		OutputSchema: prReviewSchema,
+		Instruction:  prompts.Default("PRReviewReportAgent"),
+	}
+}
+
+// ParsePRReview decodes the JSON review stored under the pr_review state key, tolerating a
+// Markdown code fence
+func ParsePRReview(text string) (PRReview, error) {
+	var review PRReview
+	if err := json.Unmarshal([]byte(trimJSONFence(text)), &review); err != nil {
+		return PRReview{}, fmt.Errorf("invalid pull-request review: %w", err)
+	}
+	if !slices.Contains([]string{PRVerdictApprove, PRVerdictRequestChanges, PRVerdictComment}, review.Verdict) {
+		return PRReview{}, fmt.Errorf("invalid pull-request review verdict %q", review.Verdict)
+	}
+	return review, nil
+}
+
+// trimJSONFence removes surrounding whitespace and a Markdown code fence from a JSON response
+func trimJSONFence(text string) string {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, "```json")
+	text = strings.TrimPrefix(text, "```")
+	text = strings.TrimSuffix(text, "```")
+	return strings.TrimSpace(text)
+}
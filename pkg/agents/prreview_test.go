@@ -0,0 +1,180 @@
+package agents
+
+import (
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestParsePRReview(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantVerdict  string
+		wantComments int
+		wantErr      bool
+	}{
+		{
+			name:         "plain JSON",
+			text:         `{"summary":"Adds Sub","verdict":"comment","comments":[{"file":"calc.go","line":7,"severity":"minor","message":"Document Sub"}]}`,
+			wantVerdict:  PRVerdictComment,
+			wantComments: 1,
+		},
+		{
+			name:        "fenced JSON",
+			text:        "```json\n{\"summary\":\"Looks good\",\"verdict\":\"approve\",\"comments\":[]}\n```",
+			wantVerdict: PRVerdictApprove,
+		},
+		{
+			name:    "unknown verdict",
+			text:    `{"summary":"x","verdict":"lgtm","comments":[]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not JSON",
+			text:    "The change looks fine.",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePRReview(tt.text)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePRReview() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Verdict != tt.wantVerdict {
+				t.Errorf("Verdict = %q, want %q", got.Verdict, tt.wantVerdict)
+			}
+			if len(got.Comments) != tt.wantComments {
+				t.Errorf("got %d comments, want %d", len(got.Comments), tt.wantComments)
+			}
+		})
+	}
+}
+
+func TestNewPRReviewAgent_Invalid(t *testing.T) {
+	mdl := fake.New("fake-model")
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{"calc.go": "package calc\n"})
+	file := filepath.Join(dir, "calc.go")
+
+	tests := []struct {
+		name   string
+		config PRReviewConfig
+	}{
+		{name: "nil model", config: PRReviewConfig{RepoDir: t.TempDir()}},
+		{name: "empty repository", config: PRReviewConfig{Model: mdl}},
+		{name: "missing repository", config: PRReviewConfig{Model: mdl, RepoDir: filepath.Join(t.TempDir(), "missing")}},
+		{name: "repository is a file", config: PRReviewConfig{Model: mdl, RepoDir: file}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewPRReviewAgent(tt.config); err == nil {
+				t.Error("NewPRReviewAgent() error = nil, want an error")
+			}
+		})
+	}
+}
+
+func TestPRReview_Run(t *testing.T) {
+	const diff = `--- a/calc.go
++++ b/calc.go
+@@ -3,3 +3,7 @@ package calc
+ func Add(a, b int) int { return a + b }
++
++func Div(a, b int) int { return a / b }
+`
+	const report = `{"summary":"Adds Div","verdict":"request_changes","comments":[{"file":"calc.go","line":5,"severity":"major","message":"Div panics when b is 0"}]}`
+
+	tests := []struct {
+		name          string
+		supportsTools bool
+		turns         []fake.Turn
+	}{
+		{
+			name:          "reads the repository",
+			supportsTools: true,
+			turns: []fake.Turn{
+				fake.FunctionCall("fileRead", map[string]any{"path": "calc.go"}),
+				fake.Text("calc.go:5 major: Div panics when b is 0"),
+				fake.Text(report),
+			},
+		},
+		{
+			name: "model without tool support",
+			turns: []fake.Turn{
+				fake.Text("calc.go:5 major: Div panics when b is 0"),
+				fake.Text(report),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := t.TempDir()
+			writeWorkspace(t, repo, map[string]string{
+				"calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Div(a, b int) int { return a / b }\n",
+			})
+			mdl := fake.New("fake-model", tt.turns...)
+			mdl.SetCapabilities(capability.Capabilities{SupportsTools: tt.supportsTools})
+
+			reviewer, err := NewPRReviewAgent(PRReviewConfig{Model: mdl, RepoDir: repo})
+			if err != nil {
+				t.Fatalf("NewPRReviewAgent() error = %v", err)
+			}
+			events, state := runAgent(t, reviewer, diff)
+
+			review, err := ParsePRReview(stateString(t, state, "pr_review"))
+			if err != nil {
+				t.Fatalf("ParsePRReview() error = %v", err)
+			}
+			if review.Verdict != PRVerdictRequestChanges || len(review.Comments) != 1 || review.Comments[0].Line != 5 {
+				t.Errorf("review = %+v, want one major comment on calc.go:5", review)
+			}
+
+			// The reviewer may only read the repository
+			requests := mdl.Requests()
+			gotTools := make([]string, 0, len(requests[0].Tools))
+			for name := range requests[0].Tools {
+				gotTools = append(gotTools, name)
+			}
+			wantTools := []string{"fileRead"}
+			if !tt.supportsTools {
+				wantTools = []string{}
+			}
+			if !slices.Equal(gotTools, wantTools) {
+				t.Errorf("reviewer tools = %v, want %v", gotTools, wantTools)
+			}
+			if tt.supportsTools {
+				read := false
+				for _, event := range events {
+					if event.Content == nil {
+						continue
+					}
+					for _, part := range event.Content.Parts {
+						if r := part.FunctionResponse; r != nil && r.Name == "fileRead" {
+							content, _ := r.Response["content"].(string)
+							read = strings.Contains(content, "func Div")
+						}
+					}
+				}
+				if !read {
+					t.Error("reviewer did not read calc.go from the repository")
+				}
+			}
+			instruction := requests[len(requests)-1].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(instruction, "Div panics when b is 0") {
+				t.Errorf("report instruction does not contain the findings: %q", instruction)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package agents
+
+import (
+	"slices"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+)
+
+// commandStages are the built-in stages that run commands in the workspace
+var commandStages = []string{builtinBuild, builtinDependencies, builtinLint}
+
+// withReadOnlyChecks turns off the stages of config that run commands in the workspace in
+// read-only mode (tools.ToolConfig.ReadOnly): the build, dependency, test runner, lint, and
+// git stages, since the commands could change the workspace
+func withReadOnlyChecks(config PipelineConfig) PipelineConfig {
+	if tools.ToolConfig.ReadOnly {
+		config.SkipBuild, config.SkipDependencies, config.SkipTests = true, true, true
+		config.Lint, config.GitCommits = false, false
+	}
+	return config
+}
+
+// withReadOnlyTools removes the tools that change the workspace or run commands from a
+// stage, so inspection stages such as the reviewer keep working in read-only mode
+func withReadOnlyTools(spec stageSpec) stageSpec {
+	readOnly := slices.DeleteFunc(slices.Clone(spec.Tools), func(t tool.Tool) bool { return tools.IsMutating(t.Name()) })
+	if len(readOnly) == len(spec.Tools) {
+		return spec
+	}
+	spec.Tools = readOnly
+	spec.Instruction += `
+
+**Read-only mode:**
+The workspace cannot be changed and commands cannot be run. Ignore instructions to write files or run commands; inspect the workspace with the remaining tools and describe the changes you would make instead.`
+	return spec
+}
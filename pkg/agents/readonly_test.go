@@ -0,0 +1,67 @@
+package agents
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+func TestReadOnly(t *testing.T) {
+	previous := tools.ToolConfig
+	tools.ToolConfig.ReadOnly = true
+	t.Cleanup(func() { tools.ToolConfig = previous })
+
+	mdl := fake.New("fake-model",
+		fake.Text("design: a calculator"),
+		fake.Text("calc.go would add Add and Div"),
+		fake.Text("calc_test.go would test Add and Div"),
+		fake.Text("No major issues found."),
+	)
+	// The stages that run commands are left out, however they are enabled
+	workspaceDir := t.TempDir()
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        mdl,
+		WorkspaceDir: workspaceDir,
+		Lint:         true,
+		GitCommits:   true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	events, _ := runAgent(t, pipeline, "Build a calculator")
+	for _, event := range events {
+		if slices.Contains([]string{"GitAgent", "DependencyAgent", "BuildAgent", "TestRunnerAgent", "LintAgent"}, event.Author) {
+			t.Errorf("%s ran in read-only mode", event.Author)
+		}
+	}
+	if entries, _ := os.ReadDir(workspaceDir); len(entries) != 0 {
+		t.Errorf("workspace has %d entries after a read-only run, want none", len(entries))
+	}
+
+	requests := mdl.Requests()
+	for i, request := range requests {
+		for name := range request.Tools {
+			if tools.IsMutating(name) {
+				t.Errorf("request %d has mutating tool %q", i, name)
+			}
+		}
+	}
+	writer := requests[1]
+	if _, ok := writer.Tools["fileRead"]; !ok {
+		t.Error("code writer has no fileRead tool, want the inspection tools kept")
+	}
+	if got := writer.Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "Read-only mode") {
+		t.Errorf("code writer instruction = %q, want the read-only note", got)
+	}
+	reviewer := requests[len(requests)-1]
+	if _, ok := reviewer.Tools["fileRead"]; !ok {
+		t.Error("reviewer has no fileRead tool")
+	}
+	if got := reviewer.Config.SystemInstruction.Parts[0].Text; strings.Contains(got, "Read-only mode") {
+		t.Error("reviewer instruction has the read-only note, want it only for stages that lost tools")
+	}
+}
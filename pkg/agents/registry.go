@@ -0,0 +1,85 @@
+package agents
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"google.golang.org/adk/agent"
+)
+
+// Factory creates a custom agent for a pipeline from its configuration
+type Factory func(config PipelineConfig) (agent.Agent, error)
+
+var (
+	registryMu sync.RWMutex
+	// registry maps registered agent names to their factories
+	registry = make(map[string]Factory)
+)
+
+// Register makes factory available under name to New and to the agent key of stage
+// declarations, so pipeline config files can use custom agents. It is meant to be called
+// from init functions and panics if name is empty or already registered, or factory is nil.
+func Register(name string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if name == "" {
+		panic("agents: Register name is empty")
+	}
+	if factory == nil {
+		panic(fmt.Sprintf("agents: Register factory for %q is nil", name))
+	}
+	if _, ok := registry[name]; ok {
+		panic(fmt.Sprintf("agents: Register called twice for %q", name))
+	}
+	registry[name] = factory
+}
+
+// New creates the agent registered under name with config
+func New(name string, config PipelineConfig) (agent.Agent, error) {
+	factory, ok := registeredFactory(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown agent %q (registered: %v)", name, Registered())
+	}
+	ag, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create agent %q: %w", name, err)
+	}
+	if ag == nil {
+		return nil, fmt.Errorf("agent %q factory returned nil", name)
+	}
+	return ag, nil
+}
+
+// Registered returns the registered agent names in sorted order
+func Registered() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// registeredFactory returns the factory registered under name
+func registeredFactory(name string) (Factory, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	factory, ok := registry[name]
+	return factory, ok
+}
+
+// registeredStage describes a stage that runs the agent registered under name
+func registeredStage(name string) (stageSpec, error) {
+	if _, ok := registeredFactory(name); !ok {
+		return stageSpec{}, fmt.Errorf("unknown agent %q (registered: %v)", name, Registered())
+	}
+	return stageSpec{
+		Name: name,
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return New(name, config)
+		},
+	}, nil
+}
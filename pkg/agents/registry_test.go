@@ -0,0 +1,159 @@
+package agents
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+)
+
+// registerForTest registers factory under name for the duration of the test
+func registerForTest(t *testing.T, name string, factory Factory) {
+	t.Helper()
+	Register(name, factory)
+	t.Cleanup(func() {
+		registryMu.Lock()
+		defer registryMu.Unlock()
+		delete(registry, name)
+	})
+}
+
+func TestRegister_Panics(t *testing.T) {
+	registerForTest(t, "registered", func(PipelineConfig) (agent.Agent, error) { return nil, nil })
+
+	tests := []struct {
+		name     string
+		register func()
+	}{
+		{name: "empty name", register: func() { Register("", func(PipelineConfig) (agent.Agent, error) { return nil, nil }) }},
+		{name: "nil factory", register: func() { Register("nil-factory", nil) }},
+		{name: "duplicate name", register: func() { Register("registered", func(PipelineConfig) (agent.Agent, error) { return nil, nil }) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register() did not panic")
+				}
+			}()
+			tt.register()
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	registerForTest(t, "note", func(config PipelineConfig) (agent.Agent, error) {
+		return newNoteAgent(t, "NoteAgent", "workspace "+config.WorkspaceDir), nil
+	})
+	registerForTest(t, "broken", func(PipelineConfig) (agent.Agent, error) {
+		return nil, errors.New("missing credentials")
+	})
+	registerForTest(t, "empty", func(PipelineConfig) (agent.Agent, error) { return nil, nil })
+
+	if got := Registered(); !slices.Equal(got, []string{"broken", "empty", "note"}) {
+		t.Errorf("Registered() = %v, want [broken empty note]", got)
+	}
+
+	tests := []struct {
+		name     string
+		agent    string
+		wantName string
+		wantErr  string
+	}{
+		{name: "registered", agent: "note", wantName: "NoteAgent"},
+		{name: "unknown", agent: "missing", wantErr: `unknown agent "missing" (registered: [broken empty note])`},
+		{name: "factory error", agent: "broken", wantErr: "missing credentials"},
+		{name: "nil agent", agent: "empty", wantErr: "returned nil"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ag, err := New(tt.agent, PipelineConfig{WorkspaceDir: "/work"})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if ag.Name() != tt.wantName {
+				t.Errorf("Name() = %q, want %q", ag.Name(), tt.wantName)
+			}
+		})
+	}
+}
+
+func TestLoadPipeline_RegisteredAgent(t *testing.T) {
+	registerForTest(t, "license", func(config PipelineConfig) (agent.Agent, error) {
+		return newNoteAgent(t, "LicenseAgent", "licensed "+config.Name), nil
+	})
+
+	path := filepath.Join(t.TempDir(), "pipeline.yaml")
+	content := `name: LicensedPipeline
+skip_build: true
+skip_tests: true
+workspace_dir: ` + t.TempDir() + `
+stages:
+  - builtin: design
+  - agent: license
+  - builtin: code_reviewer
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	mdl := fake.New("fake-model", fake.Text("pkg/calc"), fake.Text("No major issues found."))
+	pipeline, err := LoadPipeline(path, mdl)
+	if err != nil {
+		t.Fatalf("LoadPipeline() error = %v", err)
+	}
+
+	events, state := runAgent(t, pipeline, "Build a calculator package")
+
+	want := []string{"DesignAgent", "LicenseAgent", "CodeReviewerAgent"}
+	if len(events) != len(want) {
+		t.Fatalf("got %d events, want %d", len(events), len(want))
+	}
+	for i, author := range want {
+		if events[i].Author != author {
+			t.Errorf("event %d author = %q, want %q", i, events[i].Author, author)
+		}
+	}
+	if got := stateString(t, state, "LicenseAgent"); got != "licensed LicensedPipeline" {
+		t.Errorf("state[LicenseAgent] = %q, want the factory to see the pipeline config", got)
+	}
+}
+
+func TestPipelineStages_RegisteredAgentInvalid(t *testing.T) {
+	registerForTest(t, "license", func(PipelineConfig) (agent.Agent, error) {
+		return newNoteAgent(t, "LicenseAgent", "licensed"), nil
+	})
+
+	tests := []struct {
+		name  string
+		stage StageConfig
+	}{
+		{name: "unknown agent", stage: StageConfig{Agent: "missing"}},
+		{name: "with builtin", stage: StageConfig{Agent: "license", Builtin: builtinDesign}},
+		{name: "with name", stage: StageConfig{Agent: "license", Name: "OtherAgent"}},
+		{name: "with instruction", stage: StageConfig{Agent: "license", Instruction: "Add headers."}},
+		{name: "with tools", stage: StageConfig{Agent: "license", Tools: []string{"fileRead"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := pipelineStages(PipelineConfig{Stages: []StageConfig{tt.stage}})
+			if err == nil {
+				t.Errorf("pipelineStages() error = nil, want an error")
+			}
+		})
+	}
+}
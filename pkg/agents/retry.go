@@ -0,0 +1,134 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// errEmptyOutput reports a stage attempt that ended without any text response
+var errEmptyOutput = errors.New("stage produced no output")
+
+// newRetryAgent wraps inner so that a failed run, one that yields an error or ends without
+// a text response, is retried up to retries times. Each retry starts inner from its
+// instruction with the events of the failed attempts hidden from its context; the error
+// of the last attempt is reported if every attempt fails.
+func newRetryAgent(inner agent.Agent, retries int) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        inner.Name() + "Retry",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				failed := make(map[string]bool)
+				for attempt := 0; ; attempt++ {
+					attemptCtx := ctx
+					if len(failed) > 0 {
+						attemptCtx = &retryContext{
+							InvocationContext: ctx,
+							session:           &filteredSession{Session: ctx.Session(), hidden: failed},
+						}
+					}
+
+					var events []string
+					var runErr error
+					answered := false
+					for event, err := range inner.Run(attemptCtx) {
+						if event != nil {
+							events = append(events, event.ID)
+							answered = answered || (!event.Partial && strings.TrimSpace(contentText(event.Content)) != "")
+						}
+						if err != nil {
+							runErr = err
+							break
+						}
+						if !yield(event, nil) {
+							return
+						}
+					}
+					if runErr == nil && !answered {
+						runErr = errEmptyOutput
+					}
+					if runErr == nil {
+						return
+					}
+					if attempt == retries {
+						// Enclosing loop agents dereference every event, so the error carries one
+						err := fmt.Errorf("%s failed after %d attempts: %w", inner.Name(), attempt+1, runErr)
+						event := session.NewEvent(ctx.InvocationID())
+						event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+						yield(event, err)
+						return
+					}
+
+					slog.Warn("Stage failed, retrying with fresh context",
+						"stage", inner.Name(),
+						"attempt", attempt+1,
+						"retries", retries,
+						"error", runErr)
+					for _, id := range events {
+						failed[id] = true
+					}
+				}
+			}
+		},
+	})
+}
+
+// retryContext is an invocation context with a filtered view of the session
+type retryContext struct {
+	agent.InvocationContext
+	session session.Session
+}
+
+// Session implements agent.InvocationContext.
+func (c *retryContext) Session() session.Session {
+	return c.session
+}
+
+// filteredSession is a session whose events exclude the hidden event IDs
+type filteredSession struct {
+	session.Session
+	hidden map[string]bool
+}
+
+// Events implements session.Session.
+func (s *filteredSession) Events() session.Events {
+	var events eventList
+	for event := range s.Session.Events().All() {
+		if !s.hidden[event.ID] {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// eventList implements session.Events over a slice
+type eventList []*session.Event
+
+// All implements session.Events.
+func (l eventList) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, event := range l {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// Len implements session.Events.
+func (l eventList) Len() int {
+	return len(l)
+}
+
+// At implements session.Events.
+func (l eventList) At(i int) *session.Event {
+	return l[i]
+}
@@ -0,0 +1,96 @@
+package agents
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestStageRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		turns       []fake.Turn
+		wantCalls   int
+		wantDesign  string
+		errContains string
+	}{
+		{
+			name:    "model error is retried",
+			retries: 1,
+			turns: []fake.Turn{
+				fake.Error(errors.New("model overloaded")),
+				fake.Text("design"),
+				fake.Text("Created pkg/calc/calc.go"),
+			},
+			wantCalls:  3,
+			wantDesign: "design",
+		},
+		{
+			name:    "empty output is retried",
+			retries: 2,
+			turns: []fake.Turn{
+				fake.Text(""),
+				fake.Text("  "),
+				fake.Text("design"),
+				fake.Text("Created pkg/calc/calc.go"),
+			},
+			wantCalls:  4,
+			wantDesign: "design",
+		},
+		{
+			name:    "retries exhausted",
+			retries: 1,
+			turns: []fake.Turn{
+				fake.Error(errors.New("model overloaded")),
+				fake.Error(errors.New("model overloaded")),
+				fake.Text("Created pkg/calc/calc.go"),
+			},
+			wantCalls:   3,
+			errContains: "DesignAgent failed after 2 attempts: model overloaded",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model", tt.turns...)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				Stages:       []StageConfig{{Builtin: builtinDesign}, {Builtin: builtinCodeWriter}},
+				StageRetries: tt.retries,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			_, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+			if tt.errContains != "" {
+				if runErr == nil || !strings.Contains(runErr.Error(), tt.errContains) {
+					t.Fatalf("Run() error = %v, want it to contain %q", runErr, tt.errContains)
+				}
+			} else if runErr != nil {
+				t.Fatalf("Run() error = %v", runErr)
+			}
+			if got := mdl.Calls(); got != tt.wantCalls {
+				t.Errorf("model calls = %d, want %d", got, tt.wantCalls)
+			}
+			if tt.wantDesign == "" {
+				return
+			}
+
+			// Retries see the same context as the first attempt
+			requests := mdl.Requests()
+			retried := requests[tt.wantCalls-2]
+			if got, want := len(retried.Contents), len(requests[0].Contents); got != want {
+				t.Errorf("retry request has %d contents, want %d as in the first attempt", got, want)
+			}
+			writer := requests[tt.wantCalls-1].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(writer, tt.wantDesign) {
+				t.Errorf("code writer instruction does not contain the design: %q", writer)
+			}
+		})
+	}
+}
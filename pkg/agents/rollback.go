@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// newRollbackAgent wraps pipeline so that a run that fails leaves the workspace as it
+// was before the run. The workspace is snapshotted before the run starts; when any stage
+// reports an error, the failed output is copied to a timestamped directory under
+// quarantineDir, if set, and the snapshot is restored. Git metadata is left untouched.
+func newRollbackAgent(pipeline agent.Agent, workspaceDir, quarantineDir string) (agent.Agent, error) {
+	if quarantineDir != "" {
+		inside, err := insideWorkspace(quarantineDir, workspaceDir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve quarantine directory: %w", err)
+		}
+		if inside {
+			return nil, fmt.Errorf("quarantine directory %s must be outside the workspace %s", quarantineDir, workspaceDir)
+		}
+	}
+
+	return agent.New(agent.Config{
+		Name:        pipeline.Name() + "Rollback",
+		Description: pipeline.Description(),
+		SubAgents:   []agent.Agent{pipeline},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				snapshot, err := tools.NewSnapshot(workspaceDir, "")
+				if err != nil {
+					err = fmt.Errorf("failed to snapshot workspace: %w", err)
+					event := session.NewEvent(ctx.InvocationID())
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+				defer snapshot.Remove()
+
+				failed := false
+				for event, err := range pipeline.Run(ctx) {
+					failed = failed || err != nil
+					if !yield(event, err) {
+						return
+					}
+				}
+				if !failed {
+					return
+				}
+
+				event := session.NewEvent(ctx.InvocationID())
+				summary, err := rollbackWorkspace(snapshot, quarantineDir)
+				if err != nil {
+					err = fmt.Errorf("failed to roll back workspace: %w", err)
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+				event.LLMResponse.Content = genai.NewContentFromText(summary, genai.RoleModel)
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// rollbackWorkspace moves the failed output of the workspace to quarantineDir, if set,
+// then restores the workspace from snapshot, returning a summary of what it did
+func rollbackWorkspace(snapshot *tools.Snapshot, quarantineDir string) (string, error) {
+	summary := "The run failed, so the workspace was restored to its state before the run."
+	if quarantineDir != "" {
+		target := filepath.Join(quarantineDir, time.Now().UTC().Format("20060102T150405.000Z"))
+		if err := tools.CopyTree(snapshot.WorkspaceDir, target); err != nil {
+			return "", fmt.Errorf("failed to quarantine output: %w", err)
+		}
+		slog.Warn("Quarantined output of the failed run", "dir", target)
+		summary += fmt.Sprintf(" The failed output was moved to %s.", target)
+	}
+	if err := snapshot.Restore(); err != nil {
+		return "", err
+	}
+	slog.Warn("Rolled back workspace after a failed run", "workspace", snapshot.WorkspaceDir)
+	return summary, nil
+}
@@ -0,0 +1,157 @@
+package agents
+
+import (
+	"errors"
+	"iter"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// newFileAgent creates an agent that writes content to path in workspaceDir
+func newFileAgent(t *testing.T, name, workspaceDir, path, content string) agent.Agent {
+	t.Helper()
+	ag, err := agent.New(agent.Config{
+		Name:        name,
+		Description: "Writes a file to the workspace.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				if err := os.WriteFile(filepath.Join(workspaceDir, path), []byte(content), 0644); err != nil {
+					yield(event, err)
+					return
+				}
+				event.LLMResponse.Content = genai.NewContentFromText("Wrote "+path, genai.RoleModel)
+				yield(event, nil)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+	return ag
+}
+
+// newFailingAgent creates an agent that reports an error
+func newFailingAgent(t *testing.T) agent.Agent {
+	t.Helper()
+	ag, err := agent.New(agent.Config{
+		Name:        "FailingAgent",
+		Description: "Reports an error.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				yield(session.NewEvent(ctx.InvocationID()), errors.New("stage failed"))
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("agent.New() error = %v", err)
+	}
+	return ag
+}
+
+func TestRollback_Run(t *testing.T) {
+	tests := []struct {
+		name       string
+		fail       bool
+		quarantine bool
+		wantFiles  []string
+	}{
+		{name: "successful run keeps changes", wantFiles: []string{".git", "half.go", "main.go"}},
+		{name: "failed run is rolled back", fail: true, wantFiles: []string{".git", "main.go"}},
+		{name: "failed run is quarantined", fail: true, quarantine: true, wantFiles: []string{".git", "main.go"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, map[string]string{
+				"main.go":   "package main\n",
+				".git/HEAD": "ref: refs/heads/main\n",
+			})
+			config := PipelineConfig{
+				Model: fake.New("fake-model",
+					fake.Text("design"),
+					fake.Text("code"),
+					fake.Text("tests"),
+					fake.Text("No major issues found."),
+				),
+				WorkspaceDir: workspaceDir,
+				SkipBuild:    true,
+				SkipTests:    true,
+				Rollback:     true,
+				PreStages:    []agent.Agent{newFileAgent(t, "MainFileAgent", workspaceDir, "main.go", "package main // half written\n")},
+				PostStages:   []agent.Agent{newFileAgent(t, "HalfFileAgent", workspaceDir, "half.go", "package main\n")},
+			}
+			if tt.fail {
+				config.PostStages = append(config.PostStages, newFailingAgent(t))
+			}
+			quarantineDir := filepath.Join(t.TempDir(), "quarantine")
+			if tt.quarantine {
+				config.Rollback = false
+				config.QuarantineDir = quarantineDir
+			}
+			pipeline, err := NewCodePipelineAgent(config)
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+			if pipeline.Name() != "CodePipelineAgentRollback" {
+				t.Errorf("Name() = %q, want CodePipelineAgentRollback", pipeline.Name())
+			}
+
+			events, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+			if (runErr != nil) != tt.fail {
+				t.Fatalf("run error = %v, want error %v", runErr, tt.fail)
+			}
+
+			entries, err := os.ReadDir(workspaceDir)
+			if err != nil {
+				t.Fatalf("ReadDir() error = %v", err)
+			}
+			var files []string
+			for _, entry := range entries {
+				files = append(files, entry.Name())
+			}
+			if strings.Join(files, ",") != strings.Join(tt.wantFiles, ",") {
+				t.Errorf("workspace files = %v, want %v", files, tt.wantFiles)
+			}
+			if data, _ := os.ReadFile(filepath.Join(workspaceDir, ".git/HEAD")); string(data) != "ref: refs/heads/main\n" {
+				t.Errorf(".git/HEAD = %q, want it untouched", data)
+			}
+
+			if !tt.fail {
+				return
+			}
+			if data, _ := os.ReadFile(filepath.Join(workspaceDir, "main.go")); string(data) != "package main\n" {
+				t.Errorf("main.go = %q, want the content before the run", data)
+			}
+			last := contentText(events[len(events)-1].Content)
+			if !strings.Contains(last, "workspace was restored") {
+				t.Errorf("last event = %q, want the rollback summary", last)
+			}
+
+			quarantined, _ := filepath.Glob(filepath.Join(quarantineDir, "*", "half.go"))
+			if tt.quarantine != (len(quarantined) == 1) {
+				t.Errorf("quarantined files = %v, want half.go quarantined %v", quarantined, tt.quarantine)
+			}
+		})
+	}
+}
+
+func TestNewRollbackAgent_QuarantineInsideWorkspace(t *testing.T) {
+	workspaceDir := t.TempDir()
+	_, err := NewCodePipelineAgent(PipelineConfig{
+		Model:         fake.New("fake-model"),
+		WorkspaceDir:  workspaceDir,
+		QuarantineDir: filepath.Join(workspaceDir, "failed"),
+	})
+	if err == nil || !strings.Contains(err.Error(), "must be outside the workspace") {
+		t.Errorf("NewCodePipelineAgent() error = %v, want quarantine directory error", err)
+	}
+}
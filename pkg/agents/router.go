@@ -0,0 +1,255 @@
+package agents
+
+import (
+	"fmt"
+	"iter"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// TaskType classifies a user request for routing
+type TaskType string
+
+// Task types recognized by the task router
+const (
+	TaskNewProject TaskType = "new_project"
+	TaskBugFix     TaskType = "bug_fix"
+	TaskRefactor   TaskType = "refactor"
+	TaskDocs       TaskType = "docs"
+	TaskQuestion   TaskType = "question"
+)
+
+// taskDescriptions explains each task type to the classifier
+var taskDescriptions = map[TaskType]string{
+	TaskNewProject: "build a new application, package, or feature from scratch",
+	TaskBugFix:     "fix a bug, failing test, crash, or incorrect behavior in existing code",
+	TaskRefactor:   "restructure or clean up existing code without changing its behavior",
+	TaskDocs:       "write or update documentation only",
+	TaskQuestion:   "answer a question about code or Go without changing any files",
+}
+
+// RouterConfig holds configuration for creating a task router agent
+type RouterConfig struct {
+	// Model is the LLM model used to classify requests
+	Model model.LLM
+	// Name is the name of the router agent (defaults to "TaskRouterAgent")
+	Name string
+	// Description is the description of the router agent
+	Description string
+	// Routes maps each task type to the agent that handles it
+	Routes map[TaskType]agent.Agent
+	// Default is the route for requests that cannot be classified (defaults to TaskNewProject)
+	Default TaskType
+}
+
+// NewRouterAgent creates an agent that classifies each request and runs the agent routed
+// to its task type. Route agents run outside the router's agent tree, so they may reuse
+// stage names.
+func NewRouterAgent(config RouterConfig) (agent.Agent, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+	if len(config.Routes) == 0 {
+		return nil, fmt.Errorf("router requires at least one route")
+	}
+	for taskType, route := range config.Routes {
+		if route == nil {
+			return nil, fmt.Errorf("route %q is nil", taskType)
+		}
+	}
+	if config.Name == "" {
+		config.Name = "TaskRouterAgent"
+	}
+	if config.Description == "" {
+		config.Description = "Classifies the request and dispatches it to the matching pipeline."
+	}
+	if config.Default == "" {
+		config.Default = TaskNewProject
+	}
+	if _, ok := config.Routes[config.Default]; !ok {
+		return nil, fmt.Errorf("default route %q is not configured", config.Default)
+	}
+
+	taskTypes := make([]TaskType, 0, len(config.Routes))
+	for taskType := range config.Routes {
+		taskTypes = append(taskTypes, taskType)
+	}
+	slices.Sort(taskTypes)
+
+	classifier, err := llmagent.New(llmagent.Config{
+		Name:        "TaskClassifierAgent",
+		Model:       config.Model,
+		Description: "Classifies the user request by task type.",
+		Instruction: classifierInstruction(taskTypes),
+		OutputKey:   "task_type",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create task classifier: %w", err)
+	}
+
+	slog.Info("Creating task router agent", "name", config.Name, "routes", taskTypes)
+
+	return agent.New(agent.Config{
+		Name:        config.Name,
+		Description: config.Description,
+		SubAgents:   []agent.Agent{classifier},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				// A reply to a pending design approval continues the route that asked for it
+				if readStateString(ctx.Session().State(), "design_approval") != approvalPending {
+					for event, err := range classifier.Run(ctx) {
+						if !yield(event, err) || err != nil {
+							return
+						}
+					}
+				}
+
+				taskType, ok := parseTaskType(readStateString(ctx.Session().State(), "task_type"), taskTypes)
+				if !ok {
+					slog.Warn("Could not classify request, using default route", "default", config.Default)
+					taskType = config.Default
+				}
+				route := config.Routes[taskType]
+				slog.Info("Routing request", "task_type", taskType, "agent", route.Name())
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(
+					fmt.Sprintf("Routing %s request to %s.", taskType, route.Name()), genai.RoleModel)
+				event.Actions.StateDelta["task_type"] = string(taskType)
+				if !yield(event, nil) {
+					return
+				}
+
+				for event, err := range route.Run(ctx) {
+					if !yield(event, err) {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// NewTaskRouterAgent creates a router over pipelines derived from config: the full code
+// pipeline for new projects, and shorter pipelines for bug fixes, refactoring,
+// documentation, and questions
+func NewTaskRouterAgent(config PipelineConfig) (agent.Agent, error) {
+	if config.Model == nil {
+		return nil, fmt.Errorf("model cannot be nil")
+	}
+
+	routes := make(map[TaskType]agent.Agent, len(taskDescriptions))
+	newProject, err := NewCodePipelineAgent(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s pipeline: %w", TaskNewProject, err)
+	}
+	routes[TaskNewProject] = newProject
+
+	for taskType, route := range routePipelines(config) {
+		pipeline, err := NewCodePipelineAgent(route)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s pipeline: %w", taskType, err)
+		}
+		routes[taskType] = pipeline
+	}
+
+	return NewRouterAgent(RouterConfig{
+		Model:  config.Model,
+		Routes: routes,
+	})
+}
+
+// routePipelines returns the pipeline configurations of the routes other than
+// TaskNewProject. They keep the model and workspace settings of config, including the
+// bulk and critical model routing, but not its stage customizations, which refer to the
+// full pipeline.
+func routePipelines(config PipelineConfig) map[TaskType]PipelineConfig {
+	base := config
+	base.Stages = nil
+	base.PreStages = nil
+	base.PostStages = nil
+	base.InstructionOverrides = nil
+	base.InstructionFiles = nil
+	base.StageTools = nil
+	base.ModelRouting.Stages = nil
+	base.RequireDesignApproval = false
+
+	// route returns base with the given name and stages
+	route := func(name, description string, stages ...StageConfig) PipelineConfig {
+		c := base
+		c.Name = name
+		c.Description = description
+		c.Stages = stages
+		// Each route checkpoints separately, as its stages differ
+		if c.CheckpointDir != "" {
+			c.CheckpointDir = filepath.Join(c.CheckpointDir, name)
+		}
+		return c
+	}
+	verify := []StageConfig{{Builtin: builtinDependencies}, {Builtin: builtinBuild}, {Builtin: builtinTDDExpert}, {Builtin: builtinCodeReviewer}}
+
+	docs := route("DocsPipeline", "Writes documentation for existing code.", StageConfig{Builtin: builtinDocumentation})
+	docs.LoopPipeline = false
+	docs.FixOnReview = false
+	question := route("QuestionPipeline", "Answers questions about the code.", StageConfig{
+		Name:        "AnswerAgent",
+		Description: "Answers questions about the code without changing it.",
+		OutputKey:   "answer",
+		Tools:       []string{"fileRead"},
+	})
+	question.LoopPipeline = false
+	question.FixOnReview = false
+
+	return map[TaskType]PipelineConfig{
+		TaskBugFix: route("BugFixPipeline", "Fixes a bug in existing code and verifies the fix.", slices.Concat([]StageConfig{{
+			Builtin:     builtinCodeWriter,
+			Name:        "BugFixAgent",
+			Description: "Diagnoses and fixes a bug in existing code.",
+		}}, verify)...),
+		TaskRefactor: route("RefactorPipeline", "Refactors existing code and verifies its behavior.", slices.Concat([]StageConfig{{
+			Builtin:     builtinCodeWriter,
+			Name:        "RefactorAgent",
+			Description: "Restructures existing code without changing its behavior.",
+			Tools:       []string{"fileRead", "fileWrite", "diff", "goRename"},
+		}}, verify)...),
+		TaskDocs:     docs,
+		TaskQuestion: question,
+	}
+}
+
+// classifierInstruction returns the classifier instruction for taskTypes
+func classifierInstruction(taskTypes []TaskType) string {
+	var b strings.Builder
+	b.WriteString("You are a request classifier for a Go coding assistant. Classify the user's request into exactly one task type.\n\n**Task Types:**\n")
+	for _, taskType := range taskTypes {
+		description := taskDescriptions[taskType]
+		if description == "" {
+			description = strings.ReplaceAll(string(taskType), "_", " ")
+		}
+		fmt.Fprintf(&b, "- %s: %s\n", taskType, description)
+	}
+	b.WriteString("\nReply with the task type only, for example: ")
+	b.WriteString(string(taskTypes[0]))
+	return b.String()
+}
+
+// parseTaskType returns the first of taskTypes mentioned in the classifier output
+func parseTaskType(output string, taskTypes []TaskType) (TaskType, bool) {
+	normalized := strings.NewReplacer("-", "_", " ", "_").Replace(strings.ToLower(output))
+	best, bestIndex := TaskType(""), -1
+	for _, taskType := range taskTypes {
+		i := strings.Index(normalized, string(taskType))
+		if i >= 0 && (bestIndex < 0 || i < bestIndex) {
+			best, bestIndex = taskType, i
+		}
+	}
+	return best, bestIndex >= 0
+}
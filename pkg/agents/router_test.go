@@ -0,0 +1,149 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+)
+
+func TestParseTaskType(t *testing.T) {
+	taskTypes := []TaskType{TaskBugFix, TaskDocs, TaskNewProject, TaskQuestion, TaskRefactor}
+
+	tests := []struct {
+		output string
+		want   TaskType
+		wantOK bool
+	}{
+		{output: "bug_fix", want: TaskBugFix, wantOK: true},
+		{output: "Bug fix", want: TaskBugFix, wantOK: true},
+		{output: "new-project", want: TaskNewProject, wantOK: true},
+		{output: "Task type: refactor", want: TaskRefactor, wantOK: true},
+		{output: "docs, not a question", want: TaskDocs, wantOK: true},
+		{output: "I am not sure", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			got, ok := parseTaskType(tt.output, taskTypes)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseTaskType(%q) = %q, %v, want %q, %v", tt.output, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestNewRouterAgent_Invalid(t *testing.T) {
+	mdl := fake.New("fake-model")
+	route := newNoteAgent(t, "RouteAgent", "handled")
+
+	tests := []struct {
+		name        string
+		config      RouterConfig
+		errContains string
+	}{
+		{
+			name:        "nil model",
+			config:      RouterConfig{Routes: map[TaskType]agent.Agent{TaskNewProject: route}},
+			errContains: "model cannot be nil",
+		},
+		{
+			name:        "no routes",
+			config:      RouterConfig{Model: mdl},
+			errContains: "at least one route",
+		},
+		{
+			name:        "nil route",
+			config:      RouterConfig{Model: mdl, Routes: map[TaskType]agent.Agent{TaskNewProject: nil}},
+			errContains: "is nil",
+		},
+		{
+			name:        "missing default route",
+			config:      RouterConfig{Model: mdl, Routes: map[TaskType]agent.Agent{TaskQuestion: route}},
+			errContains: "default route",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewRouterAgent(tt.config)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("NewRouterAgent() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestTaskRouter_Run(t *testing.T) {
+	tests := []struct {
+		name         string
+		turns        []fake.Turn
+		wantAuthors  []string
+		wantTaskType TaskType
+	}{
+		{
+			name: "bug fix",
+			turns: []fake.Turn{
+				fake.Text("bug_fix"),
+				fake.Text("## Root Cause\noff by one"),
+				fake.Text("Created pkg/calc/calc_test.go"),
+				fake.Text("No major issues found."),
+			},
+			wantAuthors:  []string{"TaskClassifierAgent", "TaskRouterAgent", "BugFixAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+			wantTaskType: TaskBugFix,
+		},
+		{
+			name: "question",
+			turns: []fake.Turn{
+				fake.Text("question"),
+				fake.Text("Add is defined in pkg/calc/calc.go:3"),
+			},
+			wantAuthors:  []string{"TaskClassifierAgent", "TaskRouterAgent", "AnswerAgent"},
+			wantTaskType: TaskQuestion,
+		},
+		{
+			name: "unclassified request uses the full pipeline",
+			turns: []fake.Turn{
+				fake.Text("not sure"),
+				fake.Text("design"),
+				fake.Text("Created pkg/calc/calc.go"),
+				fake.Text("Created pkg/calc/calc_test.go"),
+				fake.Text("No major issues found."),
+			},
+			wantAuthors:  []string{"TaskClassifierAgent", "TaskRouterAgent", "DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+			wantTaskType: TaskNewProject,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model", tt.turns...)
+			router, err := NewTaskRouterAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				SkipBuild:    true,
+				SkipTests:    true,
+			})
+			if err != nil {
+				t.Fatalf("NewTaskRouterAgent() error = %v", err)
+			}
+
+			events, state := runAgent(t, router, "Please handle this")
+
+			var authors []string
+			for _, event := range events {
+				authors = append(authors, event.Author)
+			}
+			if strings.Join(authors, ",") != strings.Join(tt.wantAuthors, ",") {
+				t.Errorf("authors = %v, want %v", authors, tt.wantAuthors)
+			}
+			if got := stateString(t, state, "task_type"); got != string(tt.wantTaskType) {
+				t.Errorf("state[task_type] = %q, want %q", got, tt.wantTaskType)
+			}
+			if got := mdl.Calls(); got != len(tt.turns) {
+				t.Errorf("model calls = %d, want %d", got, len(tt.turns))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"google.golang.org/adk/model"
+)
+
+// Classes of work that ModelRouting assigns models to
+const (
+	// ModelClassBulk is bulky, boilerplate-heavy work: code, tests, docs, and configuration files
+	ModelClassBulk = "bulk"
+	// ModelClassCritical is work whose quality decides the run: design, planning, and review
+	ModelClassCritical = "critical"
+)
+
+// stageModelClasses maps built-in stages to the class of work they do
+var stageModelClasses = map[string]string{
+	builtinDesign:          ModelClassCritical,
+	builtinAPIDesign:       ModelClassCritical,
+	builtinPlanner:         ModelClassCritical,
+	builtinSecurity:        ModelClassCritical,
+	builtinCodeReviewer:    ModelClassCritical,
+	builtinCritic:          ModelClassCritical,
+	builtinCodeWriter:      ModelClassBulk,
+	builtinTDDExpert:       ModelClassBulk,
+	builtinDocumentation:   ModelClassBulk,
+	builtinBenchmark:       ModelClassBulk,
+	builtinDeployment:      ModelClassBulk,
+	builtinCI:              ModelClassBulk,
+	builtinAcceptanceTests: ModelClassBulk,
+}
+
+// ModelRouting is a policy that sends each LLM stage to one of PipelineConfig.Models by
+// the kind of work it does, so cheap models handle bulky work and larger models the
+// critical work. Stages the policy does not cover use PipelineConfig.Model.
+type ModelRouting struct {
+	// Bulk names the model for code, tests, documentation, benchmarks, and deployment and CI files
+	Bulk string `yaml:"bulk"`
+	// Critical names the model for design, API design, planning, security review, code review, and critique
+	Critical string `yaml:"critical"`
+	// Stages names the model for individual stages by stage name, taking precedence over Bulk and Critical
+	Stages map[string]string `yaml:"stages"`
+}
+
+// modelName returns the name of the model the policy assigns to spec, or "" for the pipeline model
+func (r ModelRouting) modelName(spec stageSpec) string {
+	if name, ok := r.Stages[spec.Name]; ok {
+		return name
+	}
+	switch stageModelClasses[spec.Builtin] {
+	case ModelClassBulk:
+		return r.Bulk
+	case ModelClassCritical:
+		return r.Critical
+	default:
+		return ""
+	}
+}
+
+// ModelNames returns the distinct model names the policy uses, sorted
+func (r ModelRouting) ModelNames() []string {
+	names := []string{r.Bulk, r.Critical}
+	names = slices.AppendSeq(names, maps.Values(r.Stages))
+	slices.Sort(names)
+	names = slices.Compact(names)
+	return slices.DeleteFunc(names, func(name string) bool { return name == "" })
+}
+
+// applyModelRouting sets the model of each LLM stage in stages from the routing policy of config
+func applyModelRouting(stages []stageSpec, config PipelineConfig) error {
+	routing := config.ModelRouting
+	for name := range routing.Stages {
+		i := slices.IndexFunc(stages, func(spec stageSpec) bool { return spec.Name == name })
+		if i < 0 {
+			return fmt.Errorf("model routing for unknown stage %q", name)
+		}
+		if stages[i].Custom != nil {
+			return fmt.Errorf("stage %q does not use a model", name)
+		}
+	}
+
+	for i := range stages {
+		if stages[i].Custom != nil {
+			continue
+		}
+		name := routing.modelName(stages[i])
+		if name == "" {
+			continue
+		}
+		llm, ok := config.Models[name]
+		if !ok || llm == nil {
+			return fmt.Errorf("model routing for stage %q: unknown model %q", stages[i].Name, name)
+		}
+		slog.Info("Routing stage to model", "stage", stages[i].Name, "model", llm.Name())
+		stages[i].Model = llm
+	}
+	return nil
+}
+
+// routedModels returns the distinct models the routing policy of config assigns to stages
+func routedModels(config PipelineConfig) []model.LLM {
+	var models []model.LLM
+	for _, name := range config.ModelRouting.ModelNames() {
+		llm := config.Models[name]
+		if llm != nil && !slices.ContainsFunc(models, func(m model.LLM) bool { return m.Name() == llm.Name() }) {
+			models = append(models, llm)
+		}
+	}
+	return models
+}
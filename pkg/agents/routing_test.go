@@ -0,0 +1,175 @@
+package agents
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+)
+
+func TestApplyModelRouting(t *testing.T) {
+	small := fake.New("small-model")
+	large := fake.New("large-model")
+	models := map[string]model.LLM{"small": small, "large": large}
+
+	tests := []struct {
+		name        string
+		routing     ModelRouting
+		want        map[string]string
+		errContains string
+	}{
+		{
+			name:    "no policy",
+			routing: ModelRouting{},
+			want:    map[string]string{"DesignAgent": "", "CodeWriterAgent": "", "CodeReviewerAgent": ""},
+		},
+		{
+			name:    "bulk and critical",
+			routing: ModelRouting{Bulk: "small", Critical: "large"},
+			want: map[string]string{
+				"DesignAgent":       "large-model",
+				"CodeWriterAgent":   "small-model",
+				"TDDExpertAgent":    "small-model",
+				"CodeReviewerAgent": "large-model",
+				"FixerAgent":        "",
+			},
+		},
+		{
+			name:    "stage takes precedence",
+			routing: ModelRouting{Bulk: "small", Stages: map[string]string{"CodeWriterAgent": "large", "FixerAgent": "small"}},
+			want:    map[string]string{"CodeWriterAgent": "large-model", "TDDExpertAgent": "small-model", "DesignAgent": "", "FixerAgent": "small-model"},
+		},
+		{
+			name:        "unknown model",
+			routing:     ModelRouting{Critical: "huge"},
+			errContains: `unknown model "huge"`,
+		},
+		{
+			name:        "unknown stage",
+			routing:     ModelRouting{Stages: map[string]string{"DesignAgnet": "large"}},
+			errContains: "unknown stage",
+		},
+		{
+			name:        "non-LLM stage",
+			routing:     ModelRouting{Stages: map[string]string{"BuildAgent": "small"}},
+			errContains: "does not use a model",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := PipelineConfig{WorkspaceDir: t.TempDir(), Models: models, ModelRouting: tt.routing}
+			stages, err := pipelineStages(config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			stages = append(stages, fixerStage(config.WorkspaceDir))
+
+			err = applyModelRouting(stages, config)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("applyModelRouting() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("applyModelRouting() error = %v", err)
+			}
+			for _, spec := range stages {
+				want, ok := tt.want[spec.Name]
+				if !ok {
+					continue
+				}
+				got := ""
+				if spec.Model != nil {
+					got = spec.Model.Name()
+				}
+				if got != want {
+					t.Errorf("%s model = %q, want %q", spec.Name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestModelRouting_ModelNames(t *testing.T) {
+	routing := ModelRouting{
+		Bulk:     "small",
+		Critical: "large",
+		Stages:   map[string]string{"CodeWriterAgent": "large", "FixerAgent": "medium"},
+	}
+	if got, want := routing.ModelNames(), []string{"large", "medium", "small"}; !slices.Equal(got, want) {
+		t.Errorf("ModelNames() = %v, want %v", got, want)
+	}
+	if got := (ModelRouting{}).ModelNames(); len(got) != 0 {
+		t.Errorf("ModelNames() of an empty policy = %v, want none", got)
+	}
+}
+
+func TestModelRouting_Run(t *testing.T) {
+	base := fake.New("base-model")
+	small := fake.New("small-model",
+		fake.Text("Created pkg/calc/calc.go"),
+		fake.Text("Created pkg/calc/calc_test.go"),
+	)
+	large := fake.New("large-model",
+		fake.Text("design: pkg/calc"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        base,
+		Models:       map[string]model.LLM{"small": small, "large": large},
+		ModelRouting: ModelRouting{Bulk: "small", Critical: "large"},
+		WorkspaceDir: t.TempDir(),
+		SkipBuild:    true,
+		SkipTests:    true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a calculator package")
+
+	if got := stateString(t, state, "review_comments"); got != "No major issues found." {
+		t.Errorf("state[review_comments] = %q, want the large model's review", got)
+	}
+	if calls := len(base.Requests()); calls != 0 {
+		t.Errorf("base model got %d requests, want none", calls)
+	}
+	if calls := len(small.Requests()); calls != 2 {
+		t.Errorf("small model got %d requests, want 2", calls)
+	}
+	if calls := len(large.Requests()); calls != 2 {
+		t.Errorf("large model got %d requests, want 2", calls)
+	}
+}
+
+func TestModelRouting_Budget(t *testing.T) {
+	config := PipelineConfig{
+		Model:        fake.New("base-model"),
+		Models:       map[string]model.LLM{"small": fake.New("small-model"), "large": fake.New("large-model")},
+		ModelRouting: ModelRouting{Bulk: "small", Critical: "large"},
+		MaxCost:      1,
+		Prices: map[string]ModelPrice{
+			"base-model":  {InputPerMillion: 1, OutputPerMillion: 1},
+			"small-model": {InputPerMillion: 0.1, OutputPerMillion: 0.2},
+		},
+	}
+	if _, err := newRunBudget(config); err == nil || !strings.Contains(err.Error(), `"large-model"`) {
+		t.Fatalf("newRunBudget() error = %v, want a missing price for the large model", err)
+	}
+
+	config.Prices["large-model"] = ModelPrice{InputPerMillion: 10, OutputPerMillion: 30}
+	b, err := newRunBudget(config)
+	if err != nil {
+		t.Fatalf("newRunBudget() error = %v", err)
+	}
+	b.add("session-1", "small-model", usageTurn("", 1_000_000, 0).Usage)
+	b.add("session-1", "large-model", usageTurn("", 0, 1_000_000).Usage)
+	if usage, _ := b.exceeded("session-1"); math.Abs(usage.cost-30.1) > 1e-9 {
+		t.Errorf("cost = %v, want each model priced separately (30.1)", usage.cost)
+	}
+}
@@ -0,0 +1,55 @@
+package agents
+
+import (
+	"fmt"
+	"log/slog"
+	"path/filepath"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+// SandboxConfig runs the commands of the pipeline, such as builds, tests, and the exec
+// tool, in throwaway Docker containers that see only the workspace
+type SandboxConfig struct {
+	// Image is the container image, which must provide go and the other commands run (empty runs commands on the host)
+	Image string `yaml:"image"`
+	// Network is the container network (defaults to none, which blocks module downloads; use bridge to allow them)
+	Network string `yaml:"network"`
+	// Memory limits the memory of each container, such as 2g
+	Memory string `yaml:"memory"`
+	// CPUs limits the CPUs of each container, such as 2
+	CPUs string `yaml:"cpus"`
+	// PidsLimit limits the number of processes in each container
+	PidsLimit int `yaml:"pids_limit"`
+	// ModCacheDir is a host Go module cache mounted into the containers to avoid repeated downloads
+	ModCacheDir string `yaml:"mod_cache_dir"`
+}
+
+// applySandbox makes the commands run in the workspace of config use a DockerExecutor
+// when config.Sandbox has an image
+func applySandbox(config PipelineConfig) error {
+	sandbox := config.Sandbox
+	if sandbox.Image == "" {
+		return nil
+	}
+	if sandbox.ModCacheDir != "" {
+		dir, err := filepath.Abs(sandbox.ModCacheDir)
+		if err != nil {
+			return fmt.Errorf("failed to resolve sandbox module cache: %w", err)
+		}
+		sandbox.ModCacheDir = dir
+	}
+
+	slog.Info("Running workspace commands in containers",
+		"image", sandbox.Image,
+		"network", sandbox.Network,
+		"workspace", config.WorkspaceDir)
+	return tools.SetExecutor(config.WorkspaceDir, &tools.DockerExecutor{
+		Image:       sandbox.Image,
+		Network:     sandbox.Network,
+		Memory:      sandbox.Memory,
+		CPUs:        sandbox.CPUs,
+		PidsLimit:   sandbox.PidsLimit,
+		ModCacheDir: sandbox.ModCacheDir,
+	})
+}
@@ -0,0 +1,39 @@
+package agents
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+func TestSandbox(t *testing.T) {
+	workspaceDir := t.TempDir()
+	defer tools.SetExecutor(workspaceDir, nil)
+
+	// Without an image, commands run on the host
+	if _, err := NewCodePipelineAgent(PipelineConfig{Model: fake.New("fake-model"), WorkspaceDir: workspaceDir}); err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	if _, err := tools.RunCommand(context.Background(), workspaceDir, tools.ExecInput{Command: "go", Args: []string{"env", "GOOS"}}); err != nil {
+		t.Fatalf("RunCommand() on the host error = %v", err)
+	}
+
+	_, err := NewCodePipelineAgent(PipelineConfig{
+		Model:        fake.New("fake-model"),
+		WorkspaceDir: workspaceDir,
+		Sandbox:      SandboxConfig{Image: "golang:1.25", Memory: "1g"},
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	// An empty PATH has no docker, which shows that the command goes through it
+	t.Setenv("PATH", t.TempDir())
+	_, err = tools.RunCommand(context.Background(), workspaceDir, tools.ExecInput{Command: "go", Args: []string{"env", "GOOS"}})
+	if err == nil || !strings.Contains(err.Error(), `"docker"`) {
+		t.Errorf("RunCommand() in the sandbox error = %v, want docker to be run", err)
+	}
+}
@@ -0,0 +1,23 @@
+package agents
+
+import (
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/tool"
+
+	"com.github.dimetron.adk-go-agi/pkg/prompts"
+)
+
+// securityReviewStage describes the security review agent stage
+func securityReviewStage(workspaceDir string) stageSpec {
+	return stageSpec{
+		Name:        "SecurityReviewAgent",
+		Description: "Audits the generated code for security vulnerabilities.",
+		OutputKey:   "security_findings",
+		Tools: []tool.Tool{
+			tools.NewFileReadToolWithWorkspace(workspaceDir),
+			tools.NewFileListToolWithWorkspace(workspaceDir),
+			tools.NewExecToolWithWorkspace(workspaceDir),
+		},
+		Instruction: prompts.Default("SecurityReviewAgent"),
+	}
+}
@@ -0,0 +1,85 @@
+package agents
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestPipelineStages_SecurityReview(t *testing.T) {
+	tests := []struct {
+		name   string
+		config PipelineConfig
+		want   []string
+	}{
+		{
+			name:   "disabled",
+			config: PipelineConfig{SkipBuild: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "before the code review",
+			config: PipelineConfig{SkipBuild: true, SecurityReview: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "SecurityReviewAgent", "CodeReviewerAgent"},
+		},
+		{
+			name:   "after the documentation",
+			config: PipelineConfig{SkipBuild: true, SecurityReview: true, Documentation: true},
+			want:   []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "DocumentationAgent", "SecurityReviewAgent", "CodeReviewerAgent"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.config.WorkspaceDir = t.TempDir()
+			stages, err := pipelineStages(tt.config)
+			if err != nil {
+				t.Fatalf("pipelineStages() error = %v", err)
+			}
+			var names []string
+			for _, spec := range stages {
+				names = append(names, spec.Name)
+			}
+			if !slices.Equal(names, tt.want) {
+				t.Errorf("stages = %v, want %v", names, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityReview_Run(t *testing.T) {
+	findings := "## Findings\n### [HIGH] Path Traversal - pkg/files/files.go:12"
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("Created pkg/files/files.go"),
+		fake.Text("Created pkg/files/files_test.go"),
+		fake.Text(findings),
+		fake.Text("## Critical Issues (Must Fix)\n- path traversal"),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:          mdl,
+		WorkspaceDir:   t.TempDir(),
+		SkipBuild:      true,
+		SkipTests:      true,
+		SecurityReview: true,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Build a file server")
+
+	if got := stateString(t, state, "security_findings"); got != findings {
+		t.Errorf("state[security_findings] = %q, want %q", got, findings)
+	}
+
+	requests := mdl.Requests()
+	if got := len(requests[3].Tools); got != 3 {
+		t.Errorf("security review has %d tools, want 3", got)
+	}
+	if got := requests[4].Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, findings) {
+		t.Errorf("reviewer instruction does not contain the security findings: %q", got)
+	}
+}
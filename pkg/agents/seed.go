@@ -0,0 +1,318 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Limits of the existing code shown to the design and code writer stages
+const (
+	maxRepoMapFiles     = 200
+	maxRepoMapSymbols   = 15
+	maxContextFileBytes = 8 << 10
+)
+
+// repoMapSkipDirs are directories left out of the repository map
+var repoMapSkipDirs = map[string]bool{
+	".git":         true,
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+}
+
+// existingCodeDesignInstruction is appended to the design stage when the pipeline works
+// on an existing codebase
+const existingCodeDesignInstruction = `
+
+**Existing Codebase (extend it; do not redesign what already exists):**
+{repo_map?}
+
+**Key Files:**
+{context_files?}
+
+Base the design on the packages, types, and conventions above. List only the packages and files to add or change, and mark each as new or modified.`
+
+// existingCodeWriterInstruction is appended to the code writer stage when the pipeline
+// works on an existing codebase
+const existingCodeWriterInstruction = `
+
+**Existing Codebase:**
+{repo_map?}
+
+The workspace already contains the project above. Use fileRead on every file before changing it, modify existing files in place instead of recreating them, keep unrelated code and the existing style intact, and create new files only where the design asks for them.`
+
+// seedsWorkspace reports whether config starts the pipeline from an existing codebase
+func seedsWorkspace(config PipelineConfig) bool {
+	return config.SeedWorkspace != "" || len(config.ContextFiles) > 0
+}
+
+// checkSeedConfig validates the seed workspace and context file patterns of config
+func checkSeedConfig(config PipelineConfig) error {
+	if config.SeedWorkspace != "" {
+		info, err := os.Stat(config.SeedWorkspace)
+		if err != nil {
+			return fmt.Errorf("invalid seed workspace: %w", err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("seed workspace %s is not a directory", config.SeedWorkspace)
+		}
+	}
+	for _, pattern := range config.ContextFiles {
+		if !filepath.IsLocal(pattern) {
+			return fmt.Errorf("context file %q must be a path inside the workspace", pattern)
+		}
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid context file pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// withExistingCode gives the design and code writer stages the map of the existing code
+func withExistingCode(spec stageSpec) stageSpec {
+	switch spec.Builtin {
+	case builtinDesign:
+		spec.Instruction += existingCodeDesignInstruction
+	case builtinCodeWriter:
+		spec.Instruction += existingCodeWriterInstruction
+	}
+	return spec
+}
+
+// repoMapStage describes the stage that seeds the workspace and maps its existing code
+func repoMapStage() stageSpec {
+	return stageSpec{
+		Name:        "RepoMapAgent",
+		Description: "Seeds the workspace with the existing project and maps its code.",
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			return newRepoMapAgent(config.SeedWorkspace, config.WorkspaceDir, config.ContextFiles)
+		},
+	}
+}
+
+// newRepoMapAgent creates an agent that copies seedDir into an empty workspace, then
+// stores a map of the workspace under the repo_map state key and excerpts of the files
+// matching contextFiles under the context_files state key. A workspace that already has
+// files, such as one resumed from a checkpoint, is not seeded again.
+func newRepoMapAgent(seedDir, workspaceDir string, contextFiles []string) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "RepoMapAgent",
+		Description: "Seeds the workspace with the existing project and maps its code.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				event := session.NewEvent(ctx.InvocationID())
+				if err := seedWorkspace(seedDir, workspaceDir); err != nil {
+					err = fmt.Errorf("failed to seed workspace: %w", err)
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+
+				repoMap, files, err := buildRepoMap(workspaceDir)
+				if err != nil {
+					err = fmt.Errorf("failed to map workspace: %w", err)
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+				excerpts := contextExcerpts(workspaceDir, contextFiles)
+
+				slog.Info("Mapped existing workspace", "files", files, "context_files", len(contextFiles))
+				event.LLMResponse.Content = genai.NewContentFromText(
+					fmt.Sprintf("Mapped %d files of the existing project.", files), genai.RoleModel)
+				event.Actions.StateDelta["repo_map"] = repoMap
+				event.Actions.StateDelta["context_files"] = excerpts
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// seedWorkspace copies seedDir into workspaceDir unless seedDir is empty, is the
+// workspace itself, or the workspace already has files
+func seedWorkspace(seedDir, workspaceDir string) error {
+	if seedDir == "" {
+		return nil
+	}
+	same, err := samePath(seedDir, workspaceDir)
+	if err != nil || same {
+		return err
+	}
+	entries, err := os.ReadDir(workspaceDir)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if len(entries) > 0 {
+		slog.Info("Workspace already has files, not seeding it", "workspace", workspaceDir)
+		return nil
+	}
+	slog.Info("Seeding workspace", "seed", seedDir, "workspace", workspaceDir)
+	return tools.CopyTree(seedDir, workspaceDir)
+}
+
+// samePath reports whether a and b name the same directory
+func samePath(a, b string) (bool, error) {
+	absA, err := filepath.Abs(a)
+	if err != nil {
+		return false, err
+	}
+	absB, err := filepath.Abs(b)
+	if err != nil {
+		return false, err
+	}
+	return absA == absB, nil
+}
+
+// buildRepoMap lists the files of workspaceDir, one per line, with the package and
+// exported identifiers of each Go file, and returns the list and the number of files
+func buildRepoMap(workspaceDir string) (string, int, error) {
+	var b strings.Builder
+	files := 0
+	err := filepath.WalkDir(workspaceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != workspaceDir && (repoMapSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		files++
+		if files > maxRepoMapFiles {
+			return nil
+		}
+		rel, err := filepath.Rel(workspaceDir, path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&b, "- %s%s\n", filepath.ToSlash(rel), goFileSummary(path))
+		return nil
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	if files > maxRepoMapFiles {
+		fmt.Fprintf(&b, "- ... and %d more files\n", files-maxRepoMapFiles)
+	}
+	if files == 0 {
+		return "The workspace is empty.", 0, nil
+	}
+	return b.String(), files, nil
+}
+
+// goFileSummary returns the package and exported identifiers of the Go file at path,
+// or an empty string for other files and files that do not parse
+func goFileSummary(path string) string {
+	if !strings.HasSuffix(path, ".go") {
+		return ""
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, parser.SkipObjectResolution)
+	if err != nil {
+		return ""
+	}
+
+	var symbols []string
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			name := decl.Name.Name
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				name = receiverType(decl.Recv.List[0].Type) + "." + name
+			}
+			symbols = append(symbols, name)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				switch spec := spec.(type) {
+				case *ast.TypeSpec:
+					if spec.Name.IsExported() {
+						symbols = append(symbols, spec.Name.Name)
+					}
+				case *ast.ValueSpec:
+					for _, name := range spec.Names {
+						if name.IsExported() {
+							symbols = append(symbols, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	summary := " (package " + file.Name.Name + ")"
+	if len(symbols) > maxRepoMapSymbols {
+		symbols = append(symbols[:maxRepoMapSymbols], "...")
+	}
+	if len(symbols) > 0 {
+		summary += ": " + strings.Join(symbols, ", ")
+	}
+	return summary
+}
+
+// receiverType returns the type name of a method receiver expression
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	default:
+		return ""
+	}
+}
+
+// contextExcerpts returns the contents of the workspace files matching patterns as
+// Markdown sections, each truncated to maxContextFileBytes
+func contextExcerpts(workspaceDir string, patterns []string) string {
+	var b strings.Builder
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(filepath.Join(workspaceDir, pattern))
+		if err != nil || len(matches) == 0 {
+			slog.Warn("Context file pattern matches no files", "pattern", pattern)
+			continue
+		}
+		for _, path := range matches {
+			rel, err := filepath.Rel(workspaceDir, path)
+			if err != nil || seen[rel] {
+				continue
+			}
+			seen[rel] = true
+			data, err := os.ReadFile(path)
+			if err != nil {
+				// Directories and unreadable files are skipped
+				continue
+			}
+			truncated := ""
+			if len(data) > maxContextFileBytes {
+				data, truncated = data[:maxContextFileBytes], "\n... (truncated)"
+			}
+			fmt.Fprintf(&b, "### %s\n```\n%s%s\n```\n\n", filepath.ToSlash(rel), strings.TrimRight(string(data), "\n"), truncated)
+		}
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,171 @@
+package agents
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestSeedWorkspace_Run(t *testing.T) {
+	seedDir := t.TempDir()
+	writeWorkspace(t, seedDir, map[string]string{
+		"go.mod":                "module example.com/calc\n\ngo 1.25\n",
+		"pkg/calc/calc.go":      "package calc\n\n// Calculator adds numbers\ntype Calculator struct{}\n\nfunc (c *Calculator) Add(a, b int) int { return a + b }\n\nfunc helper() {}\n",
+		"pkg/calc/calc_test.go": "package calc\n",
+		".git/HEAD":             "ref: refs/heads/main\n",
+	})
+
+	mdl := fake.New("fake-model",
+		fake.Text("Add a Sub method to Calculator"),
+		fake.Text("Modified pkg/calc/calc.go"),
+		fake.Text("Added TestCalculator_Sub"),
+		fake.Text("No major issues found."),
+	)
+	workspaceDir := filepath.Join(t.TempDir(), "workspace")
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:         mdl,
+		WorkspaceDir:  workspaceDir,
+		SkipBuild:     true,
+		SkipTests:     true,
+		SeedWorkspace: seedDir,
+		ContextFiles:  []string{"go.mod", "pkg/calc/calc.go", "missing/*.go"},
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	_, state := runAgent(t, pipeline, "Add subtraction to the calculator")
+
+	if _, err := os.Stat(filepath.Join(workspaceDir, "pkg/calc/calc.go")); err != nil {
+		t.Errorf("seeded file missing from workspace: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, ".git")); err == nil {
+		t.Error("workspace contains .git, want it skipped when seeding")
+	}
+
+	repoMap := stateString(t, state, "repo_map")
+	for _, want := range []string{
+		"- go.mod\n",
+		"- pkg/calc/calc.go (package calc): Calculator, Calculator.Add\n",
+		"- pkg/calc/calc_test.go (package calc)\n",
+	} {
+		if !strings.Contains(repoMap, want) {
+			t.Errorf("state[repo_map] = %q, want it to contain %q", repoMap, want)
+		}
+	}
+	if strings.Contains(repoMap, "helper") {
+		t.Errorf("state[repo_map] = %q, want unexported identifiers left out", repoMap)
+	}
+	if got := stateString(t, state, "context_files"); !strings.Contains(got, "### go.mod\n```\nmodule example.com/calc") ||
+		!strings.Contains(got, "### pkg/calc/calc.go\n") {
+		t.Errorf("state[context_files] = %q, want excerpts of go.mod and calc.go", got)
+	}
+
+	requests := mdl.Requests()
+	if len(requests) != 4 {
+		t.Fatalf("got %d model requests, want 4", len(requests))
+	}
+	design := requests[0].Config.SystemInstruction.Parts[0].Text
+	if !strings.Contains(design, "extend it; do not redesign") || !strings.Contains(design, "Calculator.Add") ||
+		!strings.Contains(design, "### go.mod") {
+		t.Errorf("design instruction = %q, want the repo map and context files", design)
+	}
+	writer := requests[1].Config.SystemInstruction.Parts[0].Text
+	if !strings.Contains(writer, "modify existing files in place") || !strings.Contains(writer, "Calculator.Add") {
+		t.Errorf("code writer instruction = %q, want the repo map and modify guidance", writer)
+	}
+	if review := requests[3].Config.SystemInstruction.Parts[0].Text; strings.Contains(review, "Calculator.Add") {
+		t.Errorf("reviewer instruction = %q, want no repo map", review)
+	}
+}
+
+func TestSeedWorkspace(t *testing.T) {
+	seedDir := t.TempDir()
+	writeWorkspace(t, seedDir, map[string]string{"main.go": "package main\n"})
+
+	tests := []struct {
+		name     string
+		existing map[string]string
+		seedDir  string
+		want     []string
+	}{
+		{name: "empty workspace", seedDir: seedDir, want: []string{"main.go"}},
+		{name: "workspace with files", seedDir: seedDir, existing: map[string]string{"keep.go": "package keep\n"}, want: []string{"keep.go"}},
+		{name: "no seed", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, tt.existing)
+			if err := seedWorkspace(tt.seedDir, workspaceDir); err != nil {
+				t.Fatalf("seedWorkspace() error = %v", err)
+			}
+			entries, err := os.ReadDir(workspaceDir)
+			if err != nil {
+				t.Fatalf("ReadDir() error = %v", err)
+			}
+			var got []string
+			for _, entry := range entries {
+				got = append(got, entry.Name())
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("workspace = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Seeding a workspace from itself is a no-op
+	if err := seedWorkspace(seedDir, seedDir); err != nil {
+		t.Errorf("seedWorkspace() from itself error = %v", err)
+	}
+}
+
+func TestCheckSeedConfig(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{"file.txt": "not a directory"})
+
+	tests := []struct {
+		name        string
+		config      PipelineConfig
+		errContains string
+	}{
+		{name: "valid", config: PipelineConfig{SeedWorkspace: dir, ContextFiles: []string{"go.mod", "pkg/*/*.go"}}},
+		{name: "missing seed", config: PipelineConfig{SeedWorkspace: filepath.Join(dir, "missing")}, errContains: "invalid seed workspace"},
+		{name: "seed is a file", config: PipelineConfig{SeedWorkspace: filepath.Join(dir, "file.txt")}, errContains: "is not a directory"},
+		{name: "absolute context file", config: PipelineConfig{ContextFiles: []string{"/etc/passwd"}}, errContains: "inside the workspace"},
+		{name: "escaping context file", config: PipelineConfig{ContextFiles: []string{"../secret.go"}}, errContains: "inside the workspace"},
+		{name: "bad pattern", config: PipelineConfig{ContextFiles: []string{"pkg/[.go"}}, errContains: "invalid context file pattern"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkSeedConfig(tt.config)
+			if tt.errContains == "" {
+				if err != nil {
+					t.Fatalf("checkSeedConfig() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Fatalf("checkSeedConfig() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestContextExcerpts_Truncates(t *testing.T) {
+	dir := t.TempDir()
+	writeWorkspace(t, dir, map[string]string{"big.txt": strings.Repeat("x", maxContextFileBytes+100)})
+
+	got := contextExcerpts(dir, []string{"big.txt", "big.txt"})
+	if strings.Count(got, "### big.txt") != 1 {
+		t.Errorf("contextExcerpts() repeated a file matched twice: %q", got[:40])
+	}
+	if !strings.Contains(got, "... (truncated)") || len(got) > maxContextFileBytes+100 {
+		t.Errorf("contextExcerpts() length = %d, want the file truncated to %d bytes", len(got), maxContextFileBytes)
+	}
+}
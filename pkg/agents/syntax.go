@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"errors"
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"iter"
+	"log/slog"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// defaultMaxSyntaxAttempts is the number of times a stage may write files before syntax errors fail the run
+const defaultMaxSyntaxAttempts = 3
+
+// maxSyntaxErrorsShown caps the syntax errors listed per file in syntax_errors
+const maxSyntaxErrorsShown = 10
+
+// syntaxCheckInstruction is appended to stages whose Go files are syntax checked
+const syntaxCheckInstruction = `
+
+**Syntax Errors (rewrite these files so they parse):**
+{syntax_errors?}`
+
+// withSyntaxCheck parses the Go files spec writes after each run and sends files with
+// syntax errors back to it
+func withSyntaxCheck(spec stageSpec) stageSpec {
+	spec.SyntaxCheck = true
+	spec.Instruction += syntaxCheckInstruction
+	return spec
+}
+
+// newSyntaxCheckAgent wraps the writing agent inner so that every .go file it writes
+// with fileWrite is parsed with go/parser. Files with syntax errors are listed under the
+// syntax_errors state key and inner runs again, up to maxAttempts times in total, after
+// which the errors fail the stage; reported errors are cleared once every file parses.
+func newSyntaxCheckAgent(inner agent.Agent, workspaceDir string, maxAttempts int) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        inner.Name() + "SyntaxCheck",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				var written []string
+				for attempt := 1; ; attempt++ {
+					for event, err := range inner.Run(ctx) {
+						if event != nil {
+							for _, path := range writtenGoFiles(event) {
+								if !slices.Contains(written, path) {
+									written = append(written, path)
+								}
+							}
+						}
+						if !yield(event, err) || err != nil {
+							return
+						}
+					}
+
+					report := checkGoSyntax(workspaceDir, written)
+					event := session.NewEvent(ctx.InvocationID())
+					event.Actions.StateDelta["syntax_errors"] = report
+					if report == "" {
+						slog.Info("Syntax check passed", "stage", inner.Name(), "files", len(written))
+						// Only errors reported earlier need clearing
+						if readStateString(ctx.Session().State(), "syntax_errors") != "" {
+							event.LLMResponse.Content = genai.NewContentFromText(
+								fmt.Sprintf("Syntax check passed: %d Go files parse.", len(written)), genai.RoleModel)
+							yield(event, nil)
+						}
+						return
+					}
+
+					event.LLMResponse.Content = genai.NewContentFromText("Syntax check failed:\n"+report, genai.RoleModel)
+					if attempt >= maxAttempts {
+						slog.Error("Syntax check failed", "stage", inner.Name(), "attempts", attempt)
+						yield(event, fmt.Errorf("%s wrote Go files with syntax errors after %d attempts:\n%s",
+							inner.Name(), attempt, report))
+						return
+					}
+					slog.Warn("Syntax check failed, returning files to the writer",
+						"stage", inner.Name(),
+						"attempt", attempt)
+					if !yield(event, nil) {
+						return
+					}
+				}
+			}
+		},
+	})
+}
+
+// writtenGoFiles returns the workspace paths of the .go files successfully written with fileWrite in event
+func writtenGoFiles(event *session.Event) []string {
+	if event.Content == nil {
+		return nil
+	}
+	var paths []string
+	for _, part := range event.Content.Parts {
+		response := part.FunctionResponse
+		if response == nil || response.Name != "fileWrite" {
+			continue
+		}
+		path, _ := response.Response["path"].(string)
+		if success, _ := response.Response["success"].(bool); success && strings.HasSuffix(path, ".go") {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// checkGoSyntax parses the workspace files at paths and returns their syntax errors,
+// or an empty string if every file parses. Files that no longer exist are skipped.
+func checkGoSyntax(workspaceDir string, paths []string) string {
+	var report strings.Builder
+	fset := token.NewFileSet()
+	for _, path := range paths {
+		_, err := parser.ParseFile(fset, filepath.Join(workspaceDir, path), nil, parser.AllErrors|parser.SkipObjectResolution)
+		if err == nil {
+			continue
+		}
+		var list scanner.ErrorList
+		if !errors.As(err, &list) {
+			slog.Warn("Skipping syntax check of unreadable file", "path", path, "error", err)
+			continue
+		}
+		fmt.Fprintf(&report, "- %s:\n", path)
+		for i, e := range list {
+			if i == maxSyntaxErrorsShown {
+				fmt.Fprintf(&report, "  - ... and %d more\n", len(list)-i)
+				break
+			}
+			fmt.Fprintf(&report, "  - line %d:%d: %s\n", e.Pos.Line, e.Pos.Column, e.Msg)
+		}
+	}
+	return report.String()
+}
@@ -0,0 +1,123 @@
+package agents
+
+import (
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+)
+
+func TestCheckGoSyntax(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		paths        []string
+		wantContains []string
+	}{
+		{
+			name:  "valid files",
+			files: map[string]string{"calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n"},
+			paths: []string{"calc.go"},
+		},
+		{
+			name:         "syntax error",
+			files:        map[string]string{"pkg/calc/calc.go": "package calc\n\nfunc Add(a, b int) int { return a + }\n"},
+			paths:        []string{"pkg/calc/calc.go"},
+			wantContains: []string{"- pkg/calc/calc.go:", "line 3:"},
+		},
+		{
+			name:  "missing file is skipped",
+			paths: []string{"deleted.go"},
+		},
+		{
+			name: "only listed files are checked",
+			files: map[string]string{
+				"calc.go":   "package calc\n",
+				"broken.go": "package calc\nfunc {\n",
+			},
+			paths: []string{"calc.go"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeWorkspace(t, dir, tt.files)
+
+			got := checkGoSyntax(dir, tt.paths)
+			if len(tt.wantContains) == 0 && got != "" {
+				t.Errorf("checkGoSyntax() = %q, want no errors", got)
+			}
+			for _, want := range tt.wantContains {
+				if !strings.Contains(got, want) {
+					t.Errorf("checkGoSyntax() = %q, want it to contain %q", got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestSyntaxCheck_ReturnsFilesToWriter(t *testing.T) {
+	broken := "package calc\n\nfunc Add(a, b int) int { return a + }\n"
+	fixed := "package calc\n\nfunc Add(a, b int) int { return a + b }\n"
+	tests := []struct {
+		name        string
+		turns       []fake.Turn
+		wantCalls   int
+		errContains string
+	}{
+		{
+			name: "fixed on the second attempt",
+			turns: []fake.Turn{
+				fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": broken}),
+				fake.Text("Created calc.go"),
+				fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": fixed}),
+				fake.Text("Fixed calc.go"),
+			},
+			wantCalls: 4,
+		},
+		{
+			name: "attempts exhausted",
+			turns: []fake.Turn{
+				fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": broken}),
+				fake.Text("Created calc.go"),
+				fake.Text("Done"),
+				fake.Text("Done"),
+			},
+			wantCalls:   4,
+			errContains: "CodeWriterAgent wrote Go files with syntax errors after 3 attempts",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mdl := fake.New("fake-model", tt.turns...)
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				Stages:       []StageConfig{{Builtin: builtinCodeWriter}},
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			_, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+			if tt.errContains != "" {
+				if runErr == nil || !strings.Contains(runErr.Error(), tt.errContains) {
+					t.Fatalf("Run() error = %v, want it to contain %q", runErr, tt.errContains)
+				}
+			} else if runErr != nil {
+				t.Fatalf("Run() error = %v", runErr)
+			}
+			if got := mdl.Calls(); got != tt.wantCalls {
+				t.Fatalf("model calls = %d, want %d", got, tt.wantCalls)
+			}
+
+			// The writer sees the syntax errors of the files it wrote
+			retry := mdl.Requests()[2].Config.SystemInstruction.Parts[0].Text
+			if !strings.Contains(retry, "- calc.go:") {
+				t.Errorf("retry instruction does not list the syntax errors: %q", retry)
+			}
+		})
+	}
+}
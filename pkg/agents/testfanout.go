@@ -0,0 +1,248 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// testFanOutName is the name of the stage that replaces the TDD stage when tests are
+// generated per file
+const testFanOutName = "TDDFanOutAgent"
+
+// testFileInstruction is appended to the TDD stage instruction in each per-file run
+const testFileInstruction = `
+
+**Target File (separate runs cover the other files):**
+Write tests for %s only and save them as %s.`
+
+// fanOutEvent is an event of a per-file run, sent to the goroutine that yields events
+type fanOutEvent struct {
+	file  int
+	event *session.Event
+	err   error
+}
+
+// withTestFanOut replaces the TDD stage with one that lists the workspace Go files and
+// generates the tests of each file in a separate run of the TDD stage, running up to
+// concurrency files at a time. Each run sees only its own history, so large projects
+// never overflow a single context.
+func withTestFanOut(tdd stageSpec, concurrency int) stageSpec {
+	return stageSpec{
+		Name:          testFanOutName,
+		Description:   "Writes the tests of each Go file in a separate concurrent run.",
+		OutputKey:     tdd.OutputKey,
+		ParallelGroup: tdd.ParallelGroup,
+		Builtin:       tdd.Builtin,
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			// The whole-workspace TDD stage still runs when there are no files to fan out to
+			whole, err := newLLMStage(config, tdd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", tdd.Name, err)
+			}
+			return newTestFanOutAgent(config, tdd, whole, concurrency)
+		},
+	}
+}
+
+// newTestFanOutAgent creates an agent that runs a copy of the tdd stage per workspace Go
+// file, concurrency at a time, and stores their combined responses under the stage's
+// output key. Each copy runs on its own branch, so it does not see the others' events.
+// The run fails only if every file fails; whole runs instead when there are no Go files.
+func newTestFanOutAgent(config PipelineConfig, tdd stageSpec, whole agent.Agent, concurrency int) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        testFanOutName,
+		Description: "Writes the tests of each Go file in a separate concurrent run.",
+		SubAgents:   []agent.Agent{whole},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				files, err := testTargets(config.WorkspaceDir)
+				if err != nil {
+					err = fmt.Errorf("failed to list Go files: %w", err)
+					event := session.NewEvent(ctx.InvocationID())
+					event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+					yield(event, err)
+					return
+				}
+				if len(files) == 0 {
+					slog.Info("No Go files to fan out test generation to, testing the whole workspace")
+					for event, err := range whole.Run(ctx) {
+						if !yield(event, err) || err != nil {
+							return
+						}
+					}
+					return
+				}
+
+				slog.Info("Generating tests per file", "files", len(files), "concurrency", concurrency)
+				runCtx, cancel := context.WithCancel(ctx)
+				defer cancel()
+				events := runTestFiles(ctx, runCtx, config, tdd, files, concurrency)
+
+				names := make([]string, len(files))
+				for i := range files {
+					names[i] = testFileAgentName(tdd, i)
+				}
+				outputs := make([]string, len(files))
+				errs := make([]error, len(files))
+				for e := range events {
+					if e.err != nil {
+						errs[e.file] = e.err
+					}
+					if e.event == nil {
+						continue
+					}
+					if e.event.Author == names[e.file] && !e.event.Partial {
+						if text := strings.TrimSpace(contentText(e.event.Content)); text != "" {
+							outputs[e.file] = text
+						}
+					}
+					// Failures are reported once every file has finished
+					if !yield(e.event, nil) {
+						return
+					}
+				}
+
+				var b strings.Builder
+				var failed []error
+				for i, path := range files {
+					if errs[i] == nil && outputs[i] == "" {
+						errs[i] = errEmptyOutput
+					}
+					fmt.Fprintf(&b, "### %s\n\n", path)
+					if errs[i] != nil {
+						slog.Warn("Test generation failed", "file", path, "error", errs[i])
+						failed = append(failed, fmt.Errorf("%s: %w", path, errs[i]))
+						fmt.Fprintf(&b, "Test generation failed: %v\n\n", errs[i])
+						continue
+					}
+					b.WriteString(outputs[i] + "\n\n")
+				}
+				output := strings.TrimSpace(b.String())
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(output, genai.RoleModel)
+				event.Actions.StateDelta[tdd.OutputKey] = output
+				if len(failed) == len(files) {
+					yield(event, fmt.Errorf("test generation failed for every file: %w", errors.Join(failed...)))
+					return
+				}
+				yield(event, nil)
+			}
+		},
+	})
+}
+
+// runTestFiles runs a copy of the tdd stage per file, up to concurrency at a time, and
+// returns their events in arrival order. The channel is closed once every run has
+// finished or runCtx is cancelled.
+func runTestFiles(ctx agent.InvocationContext, runCtx context.Context, config PipelineConfig, tdd stageSpec,
+	files []string, concurrency int) <-chan fanOutEvent {
+	events := make(chan fanOutEvent)
+	send := func(e fanOutEvent) bool {
+		select {
+		case events <- e:
+			return true
+		case <-runCtx.Done():
+			return false
+		}
+	}
+
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, path := range files {
+		wg.Go(func() {
+			select {
+			case slots <- struct{}{}:
+			case <-runCtx.Done():
+				return
+			}
+			defer func() { <-slots }()
+
+			spec := tdd
+			spec.Name = testFileAgentName(tdd, i)
+			spec.Instruction += fmt.Sprintf(testFileInstruction, path, strings.TrimSuffix(path, ".go")+"_test.go")
+			ag, err := newLLMStage(config, spec)
+			if err != nil {
+				send(fanOutEvent{file: i, err: fmt.Errorf("failed to create %s: %w", spec.Name, err)})
+				return
+			}
+
+			branch := testFanOutName + "." + spec.Name
+			if ctx.Branch() != "" {
+				branch = ctx.Branch() + "." + branch
+			}
+			fileCtx := &branchContext{
+				timeoutContext: &timeoutContext{InvocationContext: ctx, ctx: runCtx},
+				branch:         branch,
+			}
+			for event, err := range ag.Run(fileCtx) {
+				if !send(fanOutEvent{file: i, event: event, err: err}) || err != nil {
+					return
+				}
+			}
+		})
+	}
+	go func() {
+		wg.Wait()
+		close(events)
+	}()
+	return events
+}
+
+// testFileAgentName is the name of the tdd stage copy that tests the file at index i
+func testFileAgentName(tdd stageSpec, i int) string {
+	return fmt.Sprintf("%sFile%dAgent", strings.TrimSuffix(tdd.Name, "Agent"), i+1)
+}
+
+// testTargets returns the workspace-relative paths of the non-test Go files in
+// workspaceDir, skipping the directories the repository map skips
+func testTargets(workspaceDir string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(workspaceDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != workspaceDir && (repoMapSkipDirs[d.Name()] || strings.HasPrefix(d.Name(), ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+			return nil
+		}
+		rel, err := filepath.Rel(workspaceDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return files, err
+}
+
+// branchContext is an invocation context on its own branch, so that the agents it runs
+// see only the events of that branch
+type branchContext struct {
+	*timeoutContext
+	branch string
+}
+
+// Branch implements agent.InvocationContext.
+func (c *branchContext) Branch() string {
+	return c.branch
+}
@@ -0,0 +1,206 @@
+package agents
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+)
+
+// targetFile matches the file a per-file TDD run is asked to test
+var targetFile = regexp.MustCompile(`Write tests for (\S+) only`)
+
+func TestTestFanOut(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		failFiles   []string
+		wantTargets []string
+		wantOutput  []string
+		wantErr     bool
+	}{
+		{
+			name: "one run per Go file",
+			files: map[string]string{
+				"main.go":               "package main\n",
+				"pkg/calc/calc.go":      "package calc\n",
+				"pkg/calc/calc_test.go": "package calc\n",
+				"pkg/calc/ops.go":       "package calc\n",
+				"vendor/dep/dep.go":     "package dep\n",
+				"README.md":             "# calc\n",
+			},
+			wantTargets: []string{"main.go", "pkg/calc/calc.go", "pkg/calc/ops.go"},
+			wantOutput:  []string{"### main.go\n\ntests for main.go", "### pkg/calc/ops.go\n\ntests for pkg/calc/ops.go"},
+		},
+		{
+			name:        "no Go files runs the whole-workspace stage",
+			files:       map[string]string{"README.md": "# empty\n"},
+			wantTargets: []string{""},
+			wantOutput:  []string{"tests for the workspace"},
+		},
+		{
+			name:        "a failed file is reported in the output",
+			files:       map[string]string{"a.go": "package a\n", "b.go": "package a\n"},
+			failFiles:   []string{"b.go"},
+			wantTargets: []string{"a.go", "b.go"},
+			wantOutput:  []string{"### a.go\n\ntests for a.go", "### b.go\n\nTest generation failed"},
+		},
+		{
+			name:        "every file failing fails the stage",
+			files:       map[string]string{"a.go": "package a\n"},
+			failFiles:   []string{"a.go"},
+			wantTargets: []string{"a.go"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, tt.files)
+
+			var mu sync.Mutex
+			var targets []string
+			inFlight, maxInFlight := 0, 0
+			mdl := fake.NewWithResponder("fake-model", func(req *model.LLMRequest) fake.Turn {
+				target := ""
+				if m := targetFile.FindStringSubmatch(req.Config.SystemInstruction.Parts[0].Text); m != nil {
+					target = m[1]
+				}
+				mu.Lock()
+				targets = append(targets, target)
+				inFlight++
+				maxInFlight = max(maxInFlight, inFlight)
+				mu.Unlock()
+
+				time.Sleep(20 * time.Millisecond)
+
+				mu.Lock()
+				inFlight--
+				mu.Unlock()
+				switch {
+				case slices.Contains(tt.failFiles, target):
+					return fake.Error(errors.New("model unavailable"))
+				case target == "":
+					return fake.Text("tests for the workspace")
+				default:
+					return fake.Text("tests for " + target)
+				}
+			})
+
+			config := PipelineConfig{Model: mdl, WorkspaceDir: workspaceDir}
+			ag, err := withTestFanOut(tddExpertStage(workspaceDir), 2).Custom(config)
+			if err != nil {
+				t.Fatalf("Custom() error = %v", err)
+			}
+			events, err := runAgentCollectingError(t, ag, "write the tests")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("run error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			slices.Sort(targets)
+			if !slices.Equal(targets, tt.wantTargets) {
+				t.Errorf("tested files = %v, want %v", targets, tt.wantTargets)
+			}
+			if maxInFlight > 2 {
+				t.Errorf("concurrent model calls = %d, want at most 2", maxInFlight)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			output := ""
+			for _, event := range events {
+				if v, ok := event.Actions.StateDelta["test_code"].(string); ok {
+					output = v
+				}
+			}
+			for _, want := range tt.wantOutput {
+				if !strings.Contains(output, want) {
+					t.Errorf("test_code = %q, want it to contain %q", output, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTestFanOut_Branches(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeWorkspace(t, workspaceDir, map[string]string{"a.go": "package a\n", "b.go": "package a\n"})
+	mdl := fake.NewWithResponder("fake-model", func(req *model.LLMRequest) fake.Turn {
+		return fake.Text("tests")
+	})
+
+	ag, err := withTestFanOut(tddExpertStage(workspaceDir), 1).Custom(PipelineConfig{Model: mdl, WorkspaceDir: workspaceDir})
+	if err != nil {
+		t.Fatalf("Custom() error = %v", err)
+	}
+	events, err := runAgentCollectingError(t, ag, "write the tests")
+	if err != nil {
+		t.Fatalf("run error = %v", err)
+	}
+
+	branches := make(map[string]string)
+	for _, event := range events {
+		if strings.HasPrefix(event.Author, "TDDExpertFile") {
+			branches[event.Author] = event.Branch
+		}
+	}
+	want := map[string]string{
+		"TDDExpertFile1Agent": "TDDFanOutAgent.TDDExpertFile1Agent",
+		"TDDExpertFile2Agent": "TDDFanOutAgent.TDDExpertFile2Agent",
+	}
+	if len(branches) != len(want) {
+		t.Fatalf("branches = %v, want %v", branches, want)
+	}
+	for author, branch := range want {
+		if branches[author] != branch {
+			t.Errorf("branch of %s = %q, want %q", author, branches[author], branch)
+		}
+	}
+	// The second file's run does not see the first file's response
+	for _, req := range mdl.Requests() {
+		for _, content := range req.Contents {
+			if content.Role == "model" {
+				t.Errorf("request contains another run's response: %v", content.Parts[0].Text)
+			}
+		}
+	}
+}
+
+// findSubAgent returns the agent named name in the tree of ag, or nil
+func findSubAgent(ag agent.Agent, name string) agent.Agent {
+	if ag.Name() == name {
+		return ag
+	}
+	for _, sub := range ag.SubAgents() {
+		if found := findSubAgent(sub, name); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func TestNewCodePipelineAgent_TestConcurrency(t *testing.T) {
+	ag, err := NewCodePipelineAgent(PipelineConfig{
+		Model:           fake.New("fake-model"),
+		WorkspaceDir:    t.TempDir(),
+		TestConcurrency: 4,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+	if findSubAgent(ag, testFanOutName) == nil {
+		t.Errorf("pipeline has no %s", testFanOutName)
+	}
+	if findSubAgent(ag, "TDDExpertAgent") == nil {
+		t.Errorf("pipeline has no whole-workspace TDDExpertAgent")
+	}
+}
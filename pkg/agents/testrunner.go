@@ -0,0 +1,228 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// Test result values stored under the test_status state key
+const (
+	testStatusPassed      = "passed"
+	testStatusFailed      = "failed"
+	testStatusLowCoverage = "low_coverage"
+	testStatusSkipped     = "skipped"
+)
+
+// defaultMaxTestIterations is the default number of test-writing rounds before the coverage gate fails
+const defaultMaxTestIterations = 3
+
+// testResult is the outcome of running the workspace tests
+type testResult struct {
+	// Status is one of the testStatus values
+	Status string
+	// Coverage is the total statement coverage in percent
+	Coverage float64
+	// Summary describes the result for the next stages
+	Summary string
+}
+
+// withTestRunner wraps the TDD stage in a loop with a TestRunnerAgent so that tests are
+// rewritten until they pass and meet the coverage threshold
+func withTestRunner(tdd stageSpec) stageSpec {
+	return stageSpec{
+		Name:          "TDDLoopAgent",
+		Description:   "Writes tests and runs them until they pass and meet the coverage threshold.",
+		ParallelGroup: tdd.ParallelGroup,
+		Custom: func(config PipelineConfig) (agent.Agent, error) {
+			tddAgent, err := newStage(config, tdd)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create %s: %w", tdd.Name, err)
+			}
+
+			maxIterations := config.MaxTestIterations
+			if maxIterations <= 0 {
+				maxIterations = defaultMaxTestIterations
+			}
+			runner, err := newTestRunnerAgent(config.WorkspaceDir, config.MinCoverage, maxIterations)
+			if err != nil {
+				return nil, fmt.Errorf("failed to create test runner: %w", err)
+			}
+
+			return newBoundedLoopAgent(agent.Config{
+				Name:        "TDDLoopAgent",
+				Description: "Writes tests and runs them until they pass and meet the coverage threshold.",
+				SubAgents:   []agent.Agent{tddAgent, runner},
+			}, maxIterations)
+		},
+	}
+}
+
+// newTestRunnerAgent creates an agent that runs `go test -cover ./...` in the workspace,
+// stores the result under the test_status, test_coverage, and test_output state keys,
+// and escalates once the tests pass and meet minCoverage. If coverage is still below
+// minCoverage after maxIterations runs in one invocation, it reports an error.
+func newTestRunnerAgent(workspaceDir string, minCoverage float64, maxIterations int) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        "TestRunnerAgent",
+		Description: "Runs the tests and enforces the coverage threshold.",
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				state := ctx.Session().State()
+				runs := 1
+				if readStateString(state, "test_runs_invocation") == ctx.InvocationID() {
+					// Persistent session services may decode numbers as float64
+					switch n := readState(state, "test_runs").(type) {
+					case int:
+						runs = n + 1
+					case float64:
+						runs = int(n) + 1
+					}
+				}
+
+				result := runTests(ctx, workspaceDir, minCoverage)
+
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(result.Summary, genai.RoleModel)
+				event.Actions.StateDelta["test_status"] = result.Status
+				event.Actions.StateDelta["test_coverage"] = result.Coverage
+				event.Actions.StateDelta["test_output"] = result.Summary
+				event.Actions.StateDelta["test_runs"] = runs
+				event.Actions.StateDelta["test_runs_invocation"] = ctx.InvocationID()
+
+				switch {
+				case result.Status == testStatusPassed || result.Status == testStatusSkipped:
+					event.Actions.Escalate = true
+					yield(event, nil)
+				case result.Status == testStatusLowCoverage && runs >= maxIterations:
+					slog.Error("Coverage gate failed",
+						"coverage", result.Coverage,
+						"min_coverage", minCoverage,
+						"runs", runs)
+					yield(event, fmt.Errorf("coverage %.1f%% is below the required %.1f%% after %d test runs",
+						result.Coverage, minCoverage, runs))
+				default:
+					slog.Info("Tests did not pass the gate, returning to the TDD stage",
+						"status", result.Status,
+						"coverage", result.Coverage,
+						"runs", runs)
+					yield(event, nil)
+				}
+			}
+		},
+	})
+}
+
+// readState returns the value of key in state, or nil if it is missing
+func readState(state stateReader, key string) any {
+	v, err := state.Get(key)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+// runTests runs the workspace tests with coverage and evaluates them against minCoverage
+func runTests(ctx context.Context, workspaceDir string, minCoverage float64) testResult {
+	if err := ensureGoModule(ctx, workspaceDir); err != nil {
+		slog.Warn("Skipping tests, workspace module could not be initialized", "error", err)
+		return testResult{Status: testStatusSkipped, Summary: fmt.Sprintf("Tests skipped: %v", err)}
+	}
+
+	profileDir, err := tools.CoverProfileDir(workspaceDir)
+	if err != nil {
+		return testResult{Status: testStatusSkipped, Summary: fmt.Sprintf("Tests skipped: %v", err)}
+	}
+	defer os.RemoveAll(profileDir)
+	// The commands run in the workspace, possibly in a sandbox, so they get the profile relative to it
+	profile := filepath.Join(filepath.Base(profileDir), "cover.out")
+
+	result, err := tools.RunCommand(ctx, workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"test", "-cover", "-coverprofile=" + profile, "./..."},
+	})
+	if err != nil {
+		slog.Warn("Skipping tests, go test could not be run", "error", err)
+		return testResult{Status: testStatusSkipped, Summary: fmt.Sprintf("Tests skipped: %v", err)}
+	}
+
+	if strings.Contains(result.Stderr, "no packages to test") {
+		slog.Warn("No tests found", "workspace", workspaceDir)
+		return testResult{
+			Status:  testStatusFailed,
+			Summary: "Tests failed: no packages to test. Write _test.go files next to the code.",
+		}
+	}
+
+	if !result.Success {
+		slog.Warn("Tests failed", "workspace", workspaceDir, "exit_code", result.ExitCode)
+		output := strings.TrimSpace(result.Stdout + "\n" + result.Stderr)
+		return testResult{
+			Status:  testStatusFailed,
+			Summary: fmt.Sprintf("Tests failed: `go test -cover ./...` exited with code %d.\n\n```\n%s\n```", result.ExitCode, output),
+		}
+	}
+
+	coverage := totalCoverage(ctx, workspaceDir, profile)
+	if coverage < minCoverage {
+		slog.Warn("Coverage below threshold", "coverage", coverage, "min_coverage", minCoverage)
+		return testResult{
+			Status:   testStatusLowCoverage,
+			Coverage: coverage,
+			Summary: fmt.Sprintf("Tests passed but coverage is %.1f%%, below the required %.1f%%. Add tests for the uncovered code.\n\n```\n%s\n```",
+				coverage, minCoverage, strings.TrimSpace(result.Stdout)),
+		}
+	}
+
+	slog.Info("Tests passed", "workspace", workspaceDir, "coverage", coverage)
+	return testResult{
+		Status:   testStatusPassed,
+		Coverage: coverage,
+		Summary:  fmt.Sprintf("Tests passed with %.1f%% coverage.", coverage),
+	}
+}
+
+// totalCoverage returns the total statement coverage recorded in profile, a path relative to
+// workspaceDir, or 0 if it is unavailable
+func totalCoverage(ctx context.Context, workspaceDir, profile string) float64 {
+	if _, err := os.Stat(filepath.Join(workspaceDir, profile)); err != nil {
+		return 0
+	}
+	result, err := tools.RunCommand(ctx, workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"tool", "cover", "-func=" + profile},
+	})
+	if err != nil || !result.Success {
+		slog.Warn("Failed to compute total coverage", "error", err)
+		return 0
+	}
+	coverage, _ := parseTotalCoverage(result.Stdout)
+	return coverage
+}
+
+// parseTotalCoverage extracts the total percentage from `go tool cover -func` output
+func parseTotalCoverage(output string) (float64, bool) {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "total:" {
+			continue
+		}
+		pct := strings.TrimSuffix(fields[len(fields)-1], "%")
+		coverage, err := strconv.ParseFloat(pct, 64)
+		if err != nil {
+			return 0, false
+		}
+		return coverage, true
+	}
+	return 0, false
+}
@@ -0,0 +1,226 @@
+package agents
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// halfCoveredWorkspace is a small module whose tests cover half of the statements
+var halfCoveredWorkspace = map[string]string{
+	"go.mod":                "module example.com/calc\n\ngo 1.21\n",
+	"pkg/calc/calc.go":      "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int { return a + b }\n\n// Sub returns a - b\nfunc Sub(a, b int) int { return a - b }\n",
+	"pkg/calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"Add(1, 2) != 3\")\n\t}\n}\n",
+}
+
+func TestParseTotalCoverage(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   float64
+		wantOK bool
+	}{
+		{
+			name:   "cover func output",
+			output: "example.com/calc/pkg/calc/calc.go:4:\tAdd\t\t100.0%\nexample.com/calc/pkg/calc/calc.go:7:\tSub\t\t0.0%\ntotal:\t\t\t\t(statements)\t50.0%\n",
+			want:   50,
+			wantOK: true,
+		},
+		{
+			name:   "missing total",
+			output: "example.com/calc/pkg/calc/calc.go:4:\tAdd\t\t100.0%\n",
+			wantOK: false,
+		},
+		{
+			name:   "malformed percentage",
+			output: "total:\t(statements)\tabc%\n",
+			wantOK: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseTotalCoverage(tt.output)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("parseTotalCoverage() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRunTests(t *testing.T) {
+	tests := []struct {
+		name         string
+		files        map[string]string
+		minCoverage  float64
+		wantStatus   string
+		wantCoverage float64
+		wantContains string
+	}{
+		{
+			name:         "no tests",
+			wantStatus:   testStatusFailed,
+			wantContains: "no packages to test",
+		},
+		{
+			name:         "full coverage",
+			files:        calcWorkspace,
+			minCoverage:  85,
+			wantStatus:   testStatusPassed,
+			wantCoverage: 100,
+		},
+		{
+			name:         "coverage below threshold",
+			files:        halfCoveredWorkspace,
+			minCoverage:  85,
+			wantStatus:   testStatusLowCoverage,
+			wantCoverage: 50,
+			wantContains: "below the required 85.0%",
+		},
+		{
+			name: "failing test",
+			files: map[string]string{
+				"go.mod":                "module example.com/calc\n\ngo 1.21\n",
+				"pkg/calc/calc.go":      "package calc\n\nfunc Add(a, b int) int { return a - b }\n",
+				"pkg/calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"Add(1, 2) != 3\")\n\t}\n}\n",
+			},
+			wantStatus:   testStatusFailed,
+			wantContains: "Add(1, 2) != 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			writeWorkspace(t, workspaceDir, tt.files)
+
+			got := runTests(context.Background(), workspaceDir, tt.minCoverage)
+			if got.Status != tt.wantStatus {
+				t.Errorf("runTests() status = %q, want %q (summary: %s)", got.Status, tt.wantStatus, got.Summary)
+			}
+			if got.Coverage != tt.wantCoverage {
+				t.Errorf("runTests() coverage = %v, want %v", got.Coverage, tt.wantCoverage)
+			}
+			if !strings.Contains(got.Summary, tt.wantContains) {
+				t.Errorf("runTests() summary = %q, want it to contain %q", got.Summary, tt.wantContains)
+			}
+		})
+	}
+}
+
+// profileExecutor runs commands on the host and records the coverage profiles they
+// write or read, resolved against their directory, as a sandbox sees only the workspace
+type profileExecutor struct {
+	profiles []string
+}
+
+func (e *profileExecutor) Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd {
+	for _, arg := range args {
+		for _, flag := range []string{"-coverprofile=", "-func="} {
+			if profile, ok := strings.CutPrefix(arg, flag); ok {
+				if !filepath.IsAbs(profile) {
+					profile = filepath.Join(dir, profile)
+				}
+				e.profiles = append(e.profiles, profile)
+			}
+		}
+	}
+	return tools.HostExecutor{}.Command(ctx, workspaceDir, dir, name, args...)
+}
+
+func TestRunTests_CoverProfileInWorkspace(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeWorkspace(t, workspaceDir, halfCoveredWorkspace)
+	executor := &profileExecutor{}
+	if err := tools.SetExecutor(workspaceDir, executor); err != nil {
+		t.Fatalf("tools.SetExecutor() error = %v", err)
+	}
+	defer tools.SetExecutor(workspaceDir, nil)
+
+	got := runTests(context.Background(), workspaceDir, 0)
+	if got.Coverage != 50 {
+		t.Errorf("runTests() coverage = %v, want 50 (summary: %s)", got.Coverage, got.Summary)
+	}
+	if len(executor.profiles) != 2 {
+		t.Fatalf("coverage profiles = %v, want one written and read", executor.profiles)
+	}
+	for _, profile := range executor.profiles {
+		if rel, err := filepath.Rel(workspaceDir, profile); err != nil || !filepath.IsLocal(rel) {
+			t.Errorf("coverage profile %s is outside the workspace %s", profile, workspaceDir)
+		}
+	}
+	if dirs, _ := filepath.Glob(filepath.Join(workspaceDir, ".agi-cover-*")); len(dirs) > 0 {
+		t.Errorf("coverage profile directories left in the workspace: %v", dirs)
+	}
+}
+
+func TestCoverageGate_FailsAfterMaxIterations(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeWorkspace(t, workspaceDir, halfCoveredWorkspace)
+
+	mdl := fake.New("fake-model",
+		fake.Text("design"),
+		fake.Text("code"),
+		fake.Text("tests, round 1"),
+		fake.Text("tests, round 2"),
+		fake.Text("No major issues found."),
+	)
+	pipeline, err := NewCodePipelineAgent(PipelineConfig{
+		Model:             mdl,
+		WorkspaceDir:      workspaceDir,
+		SkipBuild:         true,
+		MinCoverage:       85,
+		MaxTestIterations: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewCodePipelineAgent() error = %v", err)
+	}
+
+	ctx := context.Background()
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{AppName: "test-app", Agent: pipeline, SessionService: sessionService})
+	if err != nil {
+		t.Fatalf("runner.New() error = %v", err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "test-app", UserID: "test-user"})
+	if err != nil {
+		t.Fatalf("session Create() error = %v", err)
+	}
+
+	var authors []string
+	var runErr error
+	msg := genai.NewContentFromText("Build a calculator package", genai.RoleUser)
+	for event, err := range r.Run(ctx, "test-user", created.Session.ID(), msg, agent.RunConfig{}) {
+		if err != nil {
+			runErr = err
+			continue
+		}
+		authors = append(authors, event.Author)
+	}
+
+	if runErr == nil || !strings.Contains(runErr.Error(), "below the required 85.0%") {
+		t.Fatalf("Run() error = %v, want coverage gate failure", runErr)
+	}
+
+	// The TDD stage is retried once after the first low-coverage run.
+	want := []string{"DesignAgent", "CodeWriterAgent", "TDDExpertAgent", "TestRunnerAgent", "TDDExpertAgent"}
+	if strings.Join(authors[:len(want)], ",") != strings.Join(want, ",") {
+		t.Errorf("authors = %v, want prefix %v", authors, want)
+	}
+
+	// The second TDD round sees the coverage feedback.
+	tddReq := mdl.Requests()[3]
+	if got := tddReq.Config.SystemInstruction.Parts[0].Text; !strings.Contains(got, "coverage is 50.0%") {
+		t.Errorf("second TDD instruction does not contain the coverage feedback: %q", got)
+	}
+}
@@ -0,0 +1,83 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"time"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// errStageTimeout reports a stage cancelled because it ran longer than StageTimeout
+var errStageTimeout = errors.New("stage timed out")
+
+// newTimeoutAgent wraps inner so that a run taking longer than timeout is cancelled and
+// reported as an error, while cancellation by the caller is passed through unchanged
+func newTimeoutAgent(inner agent.Agent, timeout time.Duration) (agent.Agent, error) {
+	return agent.New(agent.Config{
+		Name:        inner.Name() + "Timeout",
+		Description: inner.Description(),
+		SubAgents:   []agent.Agent{inner},
+		Run: func(ctx agent.InvocationContext) iter.Seq2[*session.Event, error] {
+			return func(yield func(*session.Event, error) bool) {
+				stageCtx, cancel := context.WithTimeout(ctx, timeout)
+				defer cancel()
+
+				for event, err := range inner.Run(&timeoutContext{InvocationContext: ctx, ctx: stageCtx}) {
+					if timedOut(ctx, stageCtx) {
+						break
+					}
+					if !yield(event, err) {
+						return
+					}
+				}
+				if !timedOut(ctx, stageCtx) {
+					return
+				}
+
+				slog.Error("Stage timed out", "stage", inner.Name(), "timeout", timeout)
+				// Enclosing loop agents dereference every event, so the error carries one
+				err := fmt.Errorf("%s: %w after %s", inner.Name(), errStageTimeout, timeout)
+				event := session.NewEvent(ctx.InvocationID())
+				event.LLMResponse.Content = genai.NewContentFromText(err.Error(), genai.RoleModel)
+				yield(event, err)
+			}
+		},
+	})
+}
+
+// timedOut reports whether stageCtx hit its deadline while the caller's ctx is still live
+func timedOut(ctx, stageCtx context.Context) bool {
+	return ctx.Err() == nil && errors.Is(stageCtx.Err(), context.DeadlineExceeded)
+}
+
+// timeoutContext is an invocation context whose cancellation follows ctx
+type timeoutContext struct {
+	agent.InvocationContext
+	ctx context.Context
+}
+
+// Deadline implements context.Context.
+func (c *timeoutContext) Deadline() (time.Time, bool) {
+	return c.ctx.Deadline()
+}
+
+// Done implements context.Context.
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.ctx.Done()
+}
+
+// Err implements context.Context.
+func (c *timeoutContext) Err() error {
+	return c.ctx.Err()
+}
+
+// Value implements context.Context.
+func (c *timeoutContext) Value(key any) any {
+	return c.ctx.Value(key)
+}
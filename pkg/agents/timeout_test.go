@@ -0,0 +1,73 @@
+package agents
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+)
+
+func TestStageTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		retries     int
+		stuckFor    time.Duration
+		errContains string
+	}{
+		{
+			name:        "stuck stage is cancelled",
+			stuckFor:    time.Hour,
+			errContains: "CodeWriterAgent: stage timed out after 200ms",
+		},
+		{
+			name:     "stuck attempt is retried",
+			retries:  1,
+			stuckFor: 300 * time.Millisecond,
+		},
+		{
+			name:     "stage within the timeout",
+			stuckFor: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The model keeps calling tools until stuckFor has passed, then answers
+			start := time.Now()
+			mdl := fake.NewWithResponder("fake-model", func(req *model.LLMRequest) fake.Turn {
+				if time.Since(start) < tt.stuckFor {
+					time.Sleep(5 * time.Millisecond)
+					return fake.FunctionCall("fileRead", map[string]any{"path": "missing.go"})
+				}
+				return fake.Text("Created pkg/calc/calc.go")
+			})
+			pipeline, err := NewCodePipelineAgent(PipelineConfig{
+				Model:        mdl,
+				WorkspaceDir: t.TempDir(),
+				Stages:       []StageConfig{{Builtin: builtinCodeWriter}},
+				StageTimeout: 200 * time.Millisecond,
+				StageRetries: tt.retries,
+			})
+			if err != nil {
+				t.Fatalf("NewCodePipelineAgent() error = %v", err)
+			}
+
+			_, runErr := runAgentCollectingError(t, pipeline, "Build a calculator package")
+			if tt.errContains == "" {
+				if runErr != nil {
+					t.Fatalf("Run() error = %v", runErr)
+				}
+				return
+			}
+			if runErr == nil || !strings.Contains(runErr.Error(), tt.errContains) {
+				t.Fatalf("Run() error = %v, want it to contain %q", runErr, tt.errContains)
+			}
+			if !errors.Is(runErr, errStageTimeout) {
+				t.Errorf("Run() error = %v, want errStageTimeout", runErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"com.github.dimetron.adk-go-agi/pkg/tools"
+)
+
+// AssertionResult is the outcome of an assertion
+type AssertionResult struct {
+	// Assertion is the checked assertion
+	Assertion Assertion `json:"assertion"`
+	// Passed reports whether the workspace satisfied it
+	Passed bool `json:"passed"`
+	// Detail explains the outcome, such as the measured coverage or the failing output
+	Detail string `json:"detail,omitempty"`
+}
+
+// maxDetailBytes caps the command output kept in an assertion result
+const maxDetailBytes = 2000
+
+// checker checks assertions against a workspace, running the tests at most once
+type checker struct {
+	ctx          context.Context
+	workspaceDir string
+	tests        *testRun
+}
+
+// testRun is the outcome of `go test` with coverage in the workspace
+type testRun struct {
+	passed   bool
+	coverage float64
+	detail   string
+}
+
+// Check checks each assertion against workspaceDir
+func Check(ctx context.Context, workspaceDir string, assertions []Assertion) []AssertionResult {
+	c := &checker{ctx: ctx, workspaceDir: workspaceDir}
+	results := make([]AssertionResult, 0, len(assertions))
+	for _, a := range assertions {
+		passed, detail := c.check(a)
+		results = append(results, AssertionResult{Assertion: a, Passed: passed, Detail: detail})
+	}
+	return results
+}
+
+// check checks a single assertion
+func (c *checker) check(a Assertion) (bool, string) {
+	switch a.Type {
+	case AssertCompiles:
+		return c.goCommand("build", "./...")
+	case AssertVet:
+		return c.goCommand("vet", "./...")
+	case AssertTestsPass:
+		run := c.runTests()
+		return run.passed, run.detail
+	case AssertCoverage:
+		run := c.runTests()
+		if !run.passed {
+			return false, "tests failed: " + run.detail
+		}
+		return run.coverage >= a.Min, fmt.Sprintf("coverage %.1f%%", run.coverage)
+	case AssertFileExists:
+		if _, err := os.Stat(filepath.Join(c.workspaceDir, a.Path)); err != nil {
+			return false, fmt.Sprintf("%s is missing", a.Path)
+		}
+		return true, ""
+	default:
+		return false, fmt.Sprintf("unknown assertion type %q", a.Type)
+	}
+}
+
+// goCommand runs a go subcommand in the workspace and reports whether it succeeded
+func (c *checker) goCommand(args ...string) (bool, string) {
+	result, err := tools.RunCommand(c.ctx, c.workspaceDir, tools.ExecInput{Command: "go", Args: args})
+	if err != nil {
+		return false, err.Error()
+	}
+	if !result.Success {
+		return false, truncate(strings.TrimSpace(result.Stdout + "\n" + result.Stderr))
+	}
+	return true, ""
+}
+
+// runTests runs the workspace tests with coverage once and caches the outcome
+func (c *checker) runTests() *testRun {
+	if c.tests != nil {
+		return c.tests
+	}
+	c.tests = &testRun{}
+
+	profileDir, err := tools.CoverProfileDir(c.workspaceDir)
+	if err != nil {
+		c.tests.detail = err.Error()
+		return c.tests
+	}
+	defer os.RemoveAll(profileDir)
+	// The commands run in the workspace, possibly in a sandbox, so they get the profile relative to it
+	profile := filepath.Join(filepath.Base(profileDir), "cover.out")
+
+	result, err := tools.RunCommand(c.ctx, c.workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"test", "-coverprofile=" + profile, "./..."},
+	})
+	switch {
+	case err != nil:
+		c.tests.detail = err.Error()
+		return c.tests
+	case !result.Success:
+		c.tests.detail = truncate(strings.TrimSpace(result.Stdout + "\n" + result.Stderr))
+		return c.tests
+	case !hasTestedPackage(result.Stdout):
+		c.tests.detail = "no tests found"
+		return c.tests
+	}
+	c.tests.passed = true
+
+	cover, err := tools.RunCommand(c.ctx, c.workspaceDir, tools.ExecInput{
+		Command: "go",
+		Args:    []string{"tool", "cover", "-func=" + profile},
+	})
+	if err == nil && cover.Success {
+		c.tests.coverage = parseTotalCoverage(cover.Stdout)
+	}
+	return c.tests
+}
+
+// hasTestedPackage reports whether `go test` output shows a package whose tests ran
+func hasTestedPackage(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "ok ") && !strings.Contains(line, "[no test files]") {
+			return true
+		}
+	}
+	return false
+}
+
+// parseTotalCoverage extracts the total percentage from `go tool cover -func` output
+func parseTotalCoverage(output string) float64 {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 || fields[0] != "total:" {
+			continue
+		}
+		coverage, _ := strconv.ParseFloat(strings.TrimSuffix(fields[len(fields)-1], "%"), 64)
+		return coverage
+	}
+	return 0
+}
+
+// truncate shortens command output to maxDetailBytes
+func truncate(s string) string {
+	if len(s) <= maxDetailBytes {
+		return s
+	}
+	return s[:maxDetailBytes] + "\n... (truncated)"
+}
@@ -0,0 +1,80 @@
+package eval
+
+import (
+	"context"
+	"testing"
+)
+
+// writeFiles writes files, keyed by relative path, under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	if err := seedWorkspace(dir, files); err != nil {
+		t.Fatalf("seedWorkspace() error = %v", err)
+	}
+}
+
+const calcSource = `package calc
+
+// Add returns a + b
+func Add(a, b int) int { return a + b }
+
+// Sub returns a - b
+func Sub(a, b int) int { return a - b }
+`
+
+func TestCheck(t *testing.T) {
+	assertions := []Assertion{
+		{Type: AssertCompiles},
+		{Type: AssertVet},
+		{Type: AssertTestsPass},
+		{Type: AssertCoverage, Min: 40},
+		{Type: AssertCoverage, Min: 90},
+		{Type: AssertFileExists, Path: "calc.go"},
+		{Type: AssertFileExists, Path: "missing.go"},
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []bool
+	}{
+		{
+			name: "half covered",
+			files: map[string]string{
+				"go.mod":       "module calc\n\ngo 1.21\n",
+				"calc.go":      calcSource,
+				"calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong\")\n\t}\n}\n",
+			},
+			want: []bool{true, true, true, true, false, true, false},
+		},
+		{
+			name: "no tests",
+			files: map[string]string{
+				"go.mod":  "module calc\n\ngo 1.21\n",
+				"calc.go": calcSource,
+			},
+			want: []bool{true, true, false, false, false, true, false},
+		},
+		{
+			name: "does not compile",
+			files: map[string]string{
+				"go.mod":  "module calc\n\ngo 1.21\n",
+				"calc.go": "package calc\n\nfunc Add(a, b int) int { return \"x\" }\n",
+			},
+			want: []bool{false, false, false, false, false, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			writeFiles(t, dir, tt.files)
+			results := Check(context.Background(), dir, assertions)
+			for i, result := range results {
+				if result.Passed != tt.want[i] {
+					t.Errorf("%s passed = %v, want %v (detail %q)", result.Assertion, result.Passed, tt.want[i], result.Detail)
+				}
+			}
+		})
+	}
+}
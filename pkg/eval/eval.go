@@ -0,0 +1,167 @@
+// Package eval runs the code pipeline over a corpus of tasks with several model
+// configurations and compares the results. Each task is a prompt with assertions on the
+// generated workspace, such as "compiles", "tests_pass", or a minimum test coverage,
+// so models can be chosen by how often their output actually works.
+package eval
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Assertion types
+const (
+	// AssertCompiles passes when `go build ./...` succeeds in the workspace
+	AssertCompiles = "compiles"
+	// AssertVet passes when `go vet ./...` reports no problems
+	AssertVet = "vet"
+	// AssertTestsPass passes when `go test ./...` runs at least one test package and succeeds
+	AssertTestsPass = "tests_pass"
+	// AssertCoverage passes when the tests pass with at least Min percent statement coverage
+	AssertCoverage = "coverage"
+	// AssertFileExists passes when the workspace contains Path
+	AssertFileExists = "file_exists"
+)
+
+//go:embed corpus.yaml
+var defaultCorpus []byte
+
+// Task is a request for the pipeline and the assertions its output must satisfy
+type Task struct {
+	// Name identifies the task in reports and names its workspace directory
+	Name string `yaml:"name"`
+	// Prompt is the request sent to the pipeline
+	Prompt string `yaml:"prompt"`
+	// Files seed the workspace before the run, keyed by workspace-relative path
+	Files map[string]string `yaml:"files"`
+	// Assertions are checked against the workspace after the run
+	Assertions []Assertion `yaml:"assertions"`
+}
+
+// Assertion is a check of the workspace a task produced. In YAML, assertions without
+// parameters can be written as their type alone, such as "- compiles".
+type Assertion struct {
+	// Type is the check: compiles, vet, tests_pass, coverage, or file_exists
+	Type string `yaml:"type"`
+	// Min is the minimum coverage in percent for coverage assertions
+	Min float64 `yaml:"min,omitempty"`
+	// Path is the workspace-relative file for file_exists assertions
+	Path string `yaml:"path,omitempty"`
+}
+
+// UnmarshalYAML accepts an assertion as a mapping or as a bare type name
+func (a *Assertion) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		a.Type = value.Value
+		return nil
+	}
+	type plain Assertion
+	return value.Decode((*plain)(a))
+}
+
+// String describes the assertion for reports
+func (a Assertion) String() string {
+	switch a.Type {
+	case AssertCoverage:
+		return fmt.Sprintf("coverage>=%g%%", a.Min)
+	case AssertFileExists:
+		return "file_exists " + a.Path
+	default:
+		return a.Type
+	}
+}
+
+// validate reports whether the assertion is well formed
+func (a Assertion) validate() error {
+	switch a.Type {
+	case AssertCompiles, AssertVet, AssertTestsPass:
+		return nil
+	case AssertCoverage:
+		if a.Min <= 0 || a.Min > 100 {
+			return fmt.Errorf("coverage assertion needs a min between 0 and 100, got %g", a.Min)
+		}
+		return nil
+	case AssertFileExists:
+		if !filepath.IsLocal(a.Path) {
+			return fmt.Errorf("file_exists assertion needs a workspace-relative path, got %q", a.Path)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown assertion type %q", a.Type)
+	}
+}
+
+// corpus is the file format of a task corpus
+type corpus struct {
+	Tasks []Task `yaml:"tasks"`
+}
+
+// LoadCorpus reads the tasks of a YAML corpus file
+func LoadCorpus(path string) ([]Task, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read corpus %s: %w", path, err)
+	}
+	tasks, err := parseCorpus(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid corpus %s: %w", path, err)
+	}
+	return tasks, nil
+}
+
+// DefaultCorpus returns the built-in tasks: small Go libraries that every model should
+// be able to write, build, and test
+func DefaultCorpus() []Task {
+	tasks, err := parseCorpus(defaultCorpus)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in corpus: %v", err))
+	}
+	return tasks
+}
+
+// parseCorpus decodes and validates a YAML corpus
+func parseCorpus(data []byte) ([]Task, error) {
+	var c corpus
+	if err := yaml.Unmarshal(data, &c); err != nil {
+		return nil, err
+	}
+	if err := validateTasks(c.Tasks); err != nil {
+		return nil, err
+	}
+	return c.Tasks, nil
+}
+
+// validateTasks checks that tasks are named uniquely and have a prompt and valid assertions
+func validateTasks(tasks []Task) error {
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks")
+	}
+	seen := make(map[string]bool, len(tasks))
+	for i, task := range tasks {
+		if task.Name == "" || task.Name != filepath.Base(task.Name) || task.Name == "." || task.Name == ".." {
+			return fmt.Errorf("task %d: invalid name %q", i, task.Name)
+		}
+		if seen[task.Name] {
+			return fmt.Errorf("duplicate task name %q", task.Name)
+		}
+		seen[task.Name] = true
+		if task.Prompt == "" {
+			return fmt.Errorf("task %q: prompt is required", task.Name)
+		}
+		for path := range task.Files {
+			if !filepath.IsLocal(path) {
+				return fmt.Errorf("task %q: file %q must be a workspace-relative path", task.Name, path)
+			}
+		}
+		for _, a := range task.Assertions {
+			if err := a.validate(); err != nil {
+				return fmt.Errorf("task %q: %w", task.Name, err)
+			}
+		}
+	}
+	return nil
+}
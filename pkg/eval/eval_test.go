@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadCorpus(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		want        []Assertion
+		errContains string
+	}{
+		{
+			name: "bare and mapped assertions",
+			content: `tasks:
+  - name: calc
+    prompt: Build a calculator
+    files:
+      go.mod: "module calc\n"
+    assertions:
+      - compiles
+      - type: coverage
+        min: 80
+      - type: file_exists
+        path: calc.go
+`,
+			want: []Assertion{{Type: AssertCompiles}, {Type: AssertCoverage, Min: 80}, {Type: AssertFileExists, Path: "calc.go"}},
+		},
+		{name: "no tasks", content: "tasks: []\n", errContains: "no tasks"},
+		{name: "missing prompt", content: "tasks:\n  - name: calc\n", errContains: "prompt is required"},
+		{name: "duplicate task", content: "tasks:\n  - {name: a, prompt: x}\n  - {name: a, prompt: y}\n", errContains: "duplicate task name"},
+		{name: "invalid task name", content: "tasks:\n  - {name: ../a, prompt: x}\n", errContains: "invalid name"},
+		{name: "unknown assertion", content: "tasks:\n  - {name: a, prompt: x, assertions: [fast]}\n", errContains: `unknown assertion type "fast"`},
+		{name: "coverage without min", content: "tasks:\n  - {name: a, prompt: x, assertions: [coverage]}\n", errContains: "needs a min"},
+		{name: "escaping file", content: "tasks:\n  - {name: a, prompt: x, files: {../go.mod: x}}\n", errContains: "workspace-relative path"},
+		{name: "invalid YAML", content: "tasks: [", errContains: "invalid corpus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "corpus.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+			tasks, err := LoadCorpus(path)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("LoadCorpus() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadCorpus() error = %v", err)
+			}
+			if len(tasks) != 1 || len(tasks[0].Assertions) != len(tt.want) {
+				t.Fatalf("LoadCorpus() = %+v, want one task with %d assertions", tasks, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if got := tasks[0].Assertions[i]; got != want {
+					t.Errorf("assertion %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestDefaultCorpus(t *testing.T) {
+	tasks := DefaultCorpus()
+	if len(tasks) == 0 {
+		t.Fatal("DefaultCorpus() is empty")
+	}
+	for _, task := range tasks {
+		if len(task.Assertions) == 0 {
+			t.Errorf("task %q has no assertions", task.Name)
+		}
+	}
+}
+
+func TestAssertion_String(t *testing.T) {
+	for a, want := range map[Assertion]string{
+		{Type: AssertCompiles}:                     "compiles",
+		{Type: AssertCoverage, Min: 72.5}:          "coverage>=72.5%",
+		{Type: AssertFileExists, Path: "pkg/a.go"}: "file_exists pkg/a.go",
+	} {
+		if got := a.String(); got != want {
+			t.Errorf("String() = %q, want %q", got, want)
+		}
+	}
+}
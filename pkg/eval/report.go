@@ -0,0 +1,172 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Report is the outcome of an evaluation: one result per candidate and task
+type Report struct {
+	// Candidates are the candidate names, in the order they ran
+	Candidates []string `json:"candidates"`
+	// Tasks are the task names, in the order they ran
+	Tasks []string `json:"tasks"`
+	// Results are the results of every candidate and task
+	Results []Result `json:"results"`
+	// WorkDir holds the workspaces of the runs
+	WorkDir string `json:"work_dir"`
+}
+
+// Result is the outcome of running a task with a candidate
+type Result struct {
+	// Candidate is the candidate name
+	Candidate string `json:"candidate"`
+	// Task is the task name
+	Task string `json:"task"`
+	// Passed reports whether the run completed without errors and every assertion passed
+	Passed bool `json:"passed"`
+	// Assertions are the outcomes of the task assertions
+	Assertions []AssertionResult `json:"assertions"`
+	// Tokens is the number of tokens the run's model calls used
+	Tokens int `json:"tokens"`
+	// Duration is how long the run and its checks took
+	Duration time.Duration `json:"duration"`
+	// Error is the last error the run reported, if any
+	Error string `json:"error,omitempty"`
+}
+
+// Summary aggregates the results of a candidate
+type Summary struct {
+	// Candidate is the candidate name
+	Candidate string `json:"candidate"`
+	// PassedTasks is the number of tasks whose assertions all passed
+	PassedTasks int `json:"passed_tasks"`
+	// Tasks is the number of tasks run
+	Tasks int `json:"tasks"`
+	// PassedAssertions is the number of assertions that passed across all tasks
+	PassedAssertions int `json:"passed_assertions"`
+	// Assertions is the number of assertions checked across all tasks
+	Assertions int `json:"assertions"`
+	// Tokens is the number of tokens used across all tasks
+	Tokens int `json:"tokens"`
+	// Duration is the total time across all tasks
+	Duration time.Duration `json:"duration"`
+}
+
+// Result returns the result of candidate on task
+func (r *Report) Result(candidate, task string) (Result, bool) {
+	for _, result := range r.Results {
+		if result.Candidate == candidate && result.Task == task {
+			return result, true
+		}
+	}
+	return Result{}, false
+}
+
+// Summaries returns the summary of each candidate, in candidate order
+func (r *Report) Summaries() []Summary {
+	summaries := make([]Summary, 0, len(r.Candidates))
+	for _, candidate := range r.Candidates {
+		s := Summary{Candidate: candidate}
+		for _, result := range r.Results {
+			if result.Candidate != candidate {
+				continue
+			}
+			s.Tasks++
+			if result.Passed {
+				s.PassedTasks++
+			}
+			s.PassedAssertions += passedAssertions(result)
+			s.Assertions += len(result.Assertions)
+			s.Tokens += result.Tokens
+			s.Duration += result.Duration
+		}
+		summaries = append(summaries, s)
+	}
+	return summaries
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// WriteMarkdown writes a summary table with a row per candidate, a table of the passed
+// assertions per task and candidate, and the failures
+func (r *Report) WriteMarkdown(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| Candidate | Tasks passed | Assertions passed | Tokens | Duration |\n")
+	b.WriteString("|---|---|---|---|---|\n")
+	for _, s := range r.Summaries() {
+		fmt.Fprintf(&b, "| %s | %d/%d | %d/%d | %d | %s |\n", s.Candidate, s.PassedTasks, s.Tasks,
+			s.PassedAssertions, s.Assertions, s.Tokens, s.Duration.Round(time.Second))
+	}
+
+	b.WriteString("\n## Tasks\n\n| Task |")
+	for _, candidate := range r.Candidates {
+		fmt.Fprintf(&b, " %s |", candidate)
+	}
+	b.WriteString("\n|---|" + strings.Repeat("---|", len(r.Candidates)) + "\n")
+	for _, task := range r.Tasks {
+		fmt.Fprintf(&b, "| %s |", task)
+		for _, candidate := range r.Candidates {
+			result, ok := r.Result(candidate, task)
+			if !ok {
+				b.WriteString(" - |")
+				continue
+			}
+			mark := "FAIL"
+			if result.Passed {
+				mark = "PASS"
+			}
+			fmt.Fprintf(&b, " %s %d/%d |", mark, passedAssertions(result), len(result.Assertions))
+		}
+		b.WriteString("\n")
+	}
+
+	var failures strings.Builder
+	for _, result := range r.Results {
+		if result.Passed {
+			continue
+		}
+		fmt.Fprintf(&failures, "\n### %s / %s\n\n", result.Candidate, result.Task)
+		if result.Error != "" {
+			fmt.Fprintf(&failures, "- run error: %s\n", result.Error)
+		}
+		for _, a := range result.Assertions {
+			if a.Passed {
+				continue
+			}
+			fmt.Fprintf(&failures, "- %s failed", a.Assertion)
+			if a.Detail != "" {
+				fmt.Fprintf(&failures, ":\n\n```\n%s\n```\n", a.Detail)
+			} else {
+				failures.WriteString("\n")
+			}
+		}
+	}
+	if failures.Len() > 0 {
+		b.WriteString("\n## Failures\n")
+		b.WriteString(failures.String())
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// passedAssertions counts the passed assertions of result
+func passedAssertions(result Result) int {
+	n := 0
+	for _, a := range result.Assertions {
+		if a.Passed {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,82 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func testReport() *Report {
+	compiles := Assertion{Type: AssertCompiles}
+	coverage := Assertion{Type: AssertCoverage, Min: 80}
+	return &Report{
+		Candidates: []string{"small", "large"},
+		Tasks:      []string{"stack", "lru"},
+		Results: []Result{
+			{Candidate: "small", Task: "stack", Passed: true, Tokens: 100, Duration: time.Second,
+				Assertions: []AssertionResult{{Assertion: compiles, Passed: true}, {Assertion: coverage, Passed: true, Detail: "coverage 91.0%"}}},
+			{Candidate: "small", Task: "lru", Tokens: 200, Duration: 2 * time.Second, Error: "model timed out",
+				Assertions: []AssertionResult{{Assertion: compiles, Passed: false, Detail: "undefined: Cache"}}},
+			{Candidate: "large", Task: "stack", Passed: true, Tokens: 300, Duration: 3 * time.Second,
+				Assertions: []AssertionResult{{Assertion: compiles, Passed: true}, {Assertion: coverage, Passed: true}}},
+			{Candidate: "large", Task: "lru", Passed: true, Tokens: 400, Duration: 4 * time.Second,
+				Assertions: []AssertionResult{{Assertion: compiles, Passed: true}}},
+		},
+	}
+}
+
+func TestReport_Summaries(t *testing.T) {
+	summaries := testReport().Summaries()
+	want := []Summary{
+		{Candidate: "small", PassedTasks: 1, Tasks: 2, PassedAssertions: 2, Assertions: 3, Tokens: 300, Duration: 3 * time.Second},
+		{Candidate: "large", PassedTasks: 2, Tasks: 2, PassedAssertions: 3, Assertions: 3, Tokens: 700, Duration: 7 * time.Second},
+	}
+	if len(summaries) != len(want) {
+		t.Fatalf("Summaries() = %+v, want %+v", summaries, want)
+	}
+	for i := range want {
+		if summaries[i] != want[i] {
+			t.Errorf("summary %d = %+v, want %+v", i, summaries[i], want[i])
+		}
+	}
+}
+
+func TestReport_WriteMarkdown(t *testing.T) {
+	var b bytes.Buffer
+	if err := testReport().WriteMarkdown(&b); err != nil {
+		t.Fatalf("WriteMarkdown() error = %v", err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"| small | 1/2 | 2/3 | 300 | 3s |",
+		"| large | 2/2 | 3/3 | 700 | 7s |",
+		"| Task | small | large |",
+		"| lru | FAIL 0/1 | PASS 1/1 |",
+		"### small / lru",
+		"- run error: model timed out",
+		"- compiles failed:\n\n```\nundefined: Cache\n```",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WriteMarkdown() = %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "### large") {
+		t.Errorf("WriteMarkdown() lists passed results as failures: %q", got)
+	}
+}
+
+func TestReport_WriteJSON(t *testing.T) {
+	var b bytes.Buffer
+	if err := testReport().WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if result, ok := decoded.Result("small", "lru"); !ok || result.Error != "model timed out" || result.Assertions[0].Assertion.Type != AssertCompiles {
+		t.Errorf("decoded result = %+v, want the small/lru result", result)
+	}
+}
@@ -0,0 +1,176 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/session/inmemory"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// unsafePathChars are replaced in candidate names to form directory names
+var unsafePathChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// Candidate is a pipeline configuration under evaluation, usually differing from the
+// others only in its model
+type Candidate struct {
+	// Name identifies the candidate in reports, such as the model name
+	Name string
+	// Config is the pipeline configuration; its WorkspaceDir is replaced per task
+	Config agents.PipelineConfig
+}
+
+// Runner runs every task against every candidate
+type Runner struct {
+	// Tasks are the tasks to run
+	Tasks []Task
+	// Candidates are the pipeline configurations to compare
+	Candidates []Candidate
+	// WorkDir holds a workspace per candidate and task, kept for inspection (defaults to a new temporary directory)
+	WorkDir string
+	// Timeout bounds each pipeline run (0 disables)
+	Timeout time.Duration
+}
+
+// Run runs the tasks against the candidates and returns the comparison report. Failed
+// pipeline runs are recorded in the report; Run fails only on invalid input.
+func (r *Runner) Run(ctx context.Context) (*Report, error) {
+	if err := validateTasks(r.Tasks); err != nil {
+		return nil, fmt.Errorf("invalid tasks: %w", err)
+	}
+	if len(r.Candidates) == 0 {
+		return nil, fmt.Errorf("no candidates")
+	}
+	report := &Report{}
+	seen := make(map[string]bool, len(r.Candidates))
+	for _, c := range r.Candidates {
+		if c.Name == "" || seen[c.Name] {
+			return nil, fmt.Errorf("candidate names must be unique and non-empty, got %q", c.Name)
+		}
+		if c.Config.Model == nil {
+			return nil, fmt.Errorf("candidate %q has no model", c.Name)
+		}
+		seen[c.Name] = true
+		report.Candidates = append(report.Candidates, c.Name)
+	}
+	for _, task := range r.Tasks {
+		report.Tasks = append(report.Tasks, task.Name)
+	}
+
+	workDir := r.WorkDir
+	if workDir == "" {
+		dir, err := os.MkdirTemp("", "agi-eval-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create work directory: %w", err)
+		}
+		workDir = dir
+	}
+	report.WorkDir = workDir
+
+	for _, c := range r.Candidates {
+		for _, task := range r.Tasks {
+			workspaceDir := filepath.Join(workDir, unsafePathChars.ReplaceAllString(c.Name, "_"), task.Name)
+			slog.Info("Running evaluation task", "candidate", c.Name, "task", task.Name, "workspace", workspaceDir)
+			result := r.runTask(ctx, c, task, workspaceDir)
+			slog.Info("Evaluation task finished", "candidate", c.Name, "task", task.Name,
+				"passed", result.Passed, "duration", result.Duration)
+			report.Results = append(report.Results, result)
+		}
+	}
+	return report, nil
+}
+
+// runTask runs task with candidate c in workspaceDir and checks its assertions
+func (r *Runner) runTask(ctx context.Context, c Candidate, task Task, workspaceDir string) Result {
+	result := Result{Candidate: c.Name, Task: task.Name}
+	start := time.Now()
+
+	if err := seedWorkspace(workspaceDir, task.Files); err != nil {
+		result.Error = fmt.Sprintf("failed to prepare workspace: %v", err)
+		result.Duration = time.Since(start)
+		return result
+	}
+
+	config := c.Config
+	config.WorkspaceDir = workspaceDir
+	tokens, err := runPipeline(ctx, config, task.Prompt, r.Timeout)
+	result.Tokens = tokens
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	result.Assertions = Check(ctx, workspaceDir, task.Assertions)
+	result.Passed = err == nil
+	for _, a := range result.Assertions {
+		result.Passed = result.Passed && a.Passed
+	}
+	result.Duration = time.Since(start)
+	return result
+}
+
+// seedWorkspace creates a fresh workspaceDir containing files
+func seedWorkspace(workspaceDir string, files map[string]string) error {
+	if err := os.RemoveAll(workspaceDir); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return err
+	}
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runPipeline runs the pipeline of config once with prompt and returns the tokens its
+// model calls used and the last error the run reported
+func runPipeline(ctx context.Context, config agents.PipelineConfig, prompt string, timeout time.Duration) (int, error) {
+	ag, err := agents.NewAgent(config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create pipeline: %w", err)
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	sessionService := inmemory.New()
+	r, err := runner.New(runner.Config{AppName: "agi-eval", Agent: ag, SessionService: sessionService})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create runner: %w", err)
+	}
+	created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "agi-eval", UserID: "eval"})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	tokens := 0
+	var runErr error
+	msg := genai.NewContentFromText(prompt, genai.RoleUser)
+	for event, err := range r.Run(ctx, "eval", created.Session.ID(), msg, agent.RunConfig{}) {
+		if err != nil {
+			runErr = err
+			continue
+		}
+		if event != nil && event.UsageMetadata != nil && !event.Partial {
+			tokens += int(event.UsageMetadata.TotalTokenCount)
+		}
+	}
+	return tokens, runErr
+}
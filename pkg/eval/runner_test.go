@@ -0,0 +1,98 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"google.golang.org/adk/model"
+)
+
+const calcTest = "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong\")\n\t}\n}\n"
+
+// calcModel returns a fake model that writes a calc module with calcSource as its code
+func calcModel(name, calcSource string, turns ...fake.Turn) *fake.Model {
+	return fake.New(name, append([]fake.Turn{
+		fake.Text("design: package calc with Add and Sub"),
+		fake.FunctionCall("fileWrite", map[string]any{"path": "go.mod", "content": "module calc\n\ngo 1.21\n"}),
+		fake.FunctionCall("fileWrite", map[string]any{"path": "calc.go", "content": calcSource}),
+		fake.Text("Created calc.go"),
+	}, turns...)...)
+}
+
+func TestRunner_Run(t *testing.T) {
+	good := calcModel("good-model", calcSource,
+		fake.FunctionCall("fileWrite", map[string]any{"path": "calc_test.go", "content": calcTest}),
+		fake.Text("Created calc_test.go"),
+		fake.Text("No major issues found."),
+	)
+	bad := calcModel("bad-model", "package calc\n\nfunc Add(a, b int) int { return \"x\" }\n",
+		fake.Text("No tests written"),
+		fake.Text("No major issues found."),
+	)
+	config := func(llm model.LLM) agents.PipelineConfig {
+		return agents.PipelineConfig{Model: llm, SkipBuild: true, SkipTests: true}
+	}
+
+	r := &Runner{
+		Tasks: []Task{{
+			Name:       "calc",
+			Prompt:     "Create a calc package with Add and Sub",
+			Assertions: []Assertion{{Type: AssertCompiles}, {Type: AssertTestsPass}, {Type: AssertFileExists, Path: "calc.go"}},
+		}},
+		Candidates: []Candidate{{Name: "good:1b", Config: config(good)}, {Name: "bad", Config: config(bad)}},
+		WorkDir:    t.TempDir(),
+	}
+	report, err := r.Run(context.Background())
+	if err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	goodResult, ok := report.Result("good:1b", "calc")
+	if !ok || !goodResult.Passed || goodResult.Error != "" {
+		t.Errorf("good result = %+v, want passed", goodResult)
+	}
+	badResult, ok := report.Result("bad", "calc")
+	if !ok || badResult.Passed {
+		t.Fatalf("bad result = %+v, want failed", badResult)
+	}
+	if got := passedAssertions(badResult); got != 1 {
+		t.Errorf("bad result passed %d assertions, want only file_exists", got)
+	}
+
+	summaries := report.Summaries()
+	if len(summaries) != 2 || summaries[0].PassedTasks != 1 || summaries[1].PassedTasks != 0 || summaries[0].Assertions != 3 {
+		t.Errorf("Summaries() = %+v, want the good model to pass its task", summaries)
+	}
+}
+
+func TestRunner_RunInvalid(t *testing.T) {
+	task := Task{Name: "calc", Prompt: "Build a calculator"}
+	tests := []struct {
+		name        string
+		runner      Runner
+		errContains string
+	}{
+		{name: "no tasks", runner: Runner{Candidates: []Candidate{{Name: "a", Config: agents.PipelineConfig{Model: fake.New("a")}}}}, errContains: "no tasks"},
+		{name: "no candidates", runner: Runner{Tasks: []Task{task}}, errContains: "no candidates"},
+		{
+			name: "duplicate candidates",
+			runner: Runner{Tasks: []Task{task}, Candidates: []Candidate{
+				{Name: "a", Config: agents.PipelineConfig{Model: fake.New("a")}},
+				{Name: "a", Config: agents.PipelineConfig{Model: fake.New("a")}},
+			}},
+			errContains: "must be unique",
+		},
+		{name: "no model", runner: Runner{Tasks: []Task{task}, Candidates: []Candidate{{Name: "a"}}}, errContains: "has no model"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.runner.Run(context.Background()); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("Run() error = %v, want error containing %q", err, tt.errContains)
+			}
+		})
+	}
+}
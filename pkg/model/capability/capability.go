@@ -0,0 +1,22 @@
+// Package capability describes model features that agents can adapt to at runtime.
+package capability
+
+import "context"
+
+// Capabilities describes what a model supports.
+type Capabilities struct {
+	// SupportsTools reports whether the model can emit native tool/function calls
+	SupportsTools bool `json:"supports_tools"`
+	// SupportsVision reports whether the model accepts image inputs
+	SupportsVision bool `json:"supports_vision"`
+	// SupportsJSONMode reports whether the backend can constrain output to JSON
+	SupportsJSONMode bool `json:"supports_json_mode"`
+	// ContextWindow is the maximum context length in tokens (0 if unknown)
+	ContextWindow int `json:"context_window"`
+}
+
+// Provider is implemented by models that can report their capabilities.
+type Provider interface {
+	// Capabilities queries the backend for the model's capabilities.
+	Capabilities(ctx context.Context) (Capabilities, error)
+}
@@ -0,0 +1,197 @@
+// Package fake implements a scripted model.LLM for deterministic tests of agents and pipelines.
+package fake
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// Turn is the scripted outcome of a single GenerateContent call.
+type Turn struct {
+	// Chunks are the streamed text fragments returned when streaming is requested.
+	// In non-streaming mode they are concatenated into a single response.
+	Chunks []string
+	// FunctionCalls are tool calls the model requests in this turn.
+	FunctionCalls []*genai.FunctionCall
+	// Usage is attached to the final response of the turn
+	Usage *genai.GenerateContentResponseUsageMetadata
+	// Err is returned instead of a response when set
+	Err error
+}
+
+// defaultCapabilities are reported until SetCapabilities is called.
+var defaultCapabilities = capability.Capabilities{
+	SupportsTools:    true,
+	SupportsJSONMode: true,
+	ContextWindow:    128000,
+}
+
+// Responder computes the turn for a request, used when responses depend on the prompt.
+type Responder func(req *model.LLMRequest) Turn
+
+// Model is a scripted model.LLM. Each GenerateContent call consumes the next scripted turn.
+// It is safe for concurrent use.
+type Model struct {
+	mu        sync.Mutex
+	name      string
+	turns     []Turn
+	next      int
+	responder Responder
+	requests  []*model.LLMRequest
+	caps      capability.Capabilities
+}
+
+// Text returns a turn that replies with the given text.
+func Text(text string) Turn {
+	return Turn{Chunks: []string{text}}
+}
+
+// Stream returns a turn that replies with the given chunks when streaming.
+func Stream(chunks ...string) Turn {
+	return Turn{Chunks: chunks}
+}
+
+// FunctionCall returns a turn that requests a single tool call.
+func FunctionCall(name string, args map[string]any) Turn {
+	return Turn{FunctionCalls: []*genai.FunctionCall{{Name: name, Args: args}}}
+}
+
+// Error returns a turn that fails with err.
+func Error(err error) Turn {
+	return Turn{Err: err}
+}
+
+// New creates a fake model that replays turns in order.
+func New(name string, turns ...Turn) *Model {
+	return &Model{
+		name:  name,
+		turns: turns,
+		caps:  defaultCapabilities,
+	}
+}
+
+// NewWithResponder creates a fake model that computes every turn with responder.
+// Scripted turns, if any are added with Append, take precedence.
+func NewWithResponder(name string, responder Responder) *Model {
+	return &Model{
+		name:      name,
+		responder: responder,
+		caps:      defaultCapabilities,
+	}
+}
+
+// Append adds turns to the end of the script.
+func (m *Model) Append(turns ...Turn) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.turns = append(m.turns, turns...)
+}
+
+// SetCapabilities changes the capabilities reported by Capabilities.
+func (m *Model) SetCapabilities(caps capability.Capabilities) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.caps = caps
+}
+
+// Capabilities implements capability.Provider.
+func (m *Model) Capabilities(ctx context.Context) (capability.Capabilities, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.caps, nil
+}
+
+// Name returns the model name.
+func (m *Model) Name() string {
+	return m.name
+}
+
+// Calls returns the number of GenerateContent calls made so far.
+func (m *Model) Calls() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.requests)
+}
+
+// Requests returns the requests received so far, in call order.
+func (m *Model) Requests() []*model.LLMRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*model.LLMRequest(nil), m.requests...)
+}
+
+// GenerateContent implements the model.LLM interface by replaying the next scripted turn.
+func (m *Model) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	turn, err := m.nextTurn(req)
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if err := ctx.Err(); err != nil {
+			yield(nil, err)
+			return
+		}
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		if turn.Err != nil {
+			yield(nil, turn.Err)
+			return
+		}
+
+		if stream && len(turn.Chunks) > 1 {
+			for _, chunk := range turn.Chunks {
+				partial := &model.LLMResponse{
+					Content: genai.NewContentFromText(chunk, genai.RoleModel),
+					Partial: true,
+				}
+				if !yield(partial, nil) {
+					return
+				}
+			}
+		}
+
+		yield(finalResponse(turn), nil)
+	}
+}
+
+// nextTurn picks the turn for the current call and records the request.
+func (m *Model) nextTurn(req *model.LLMRequest) (Turn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests = append(m.requests, req)
+	if m.next < len(m.turns) {
+		turn := m.turns[m.next]
+		m.next++
+		return turn, nil
+	}
+	if m.responder != nil {
+		return m.responder(req), nil
+	}
+	return Turn{}, fmt.Errorf("fake model %q: no scripted response for call %d", m.name, len(m.requests))
+}
+
+// finalResponse builds the complete response for a turn.
+func finalResponse(turn Turn) *model.LLMResponse {
+	content := &genai.Content{Role: genai.RoleModel}
+	if text := strings.Join(turn.Chunks, ""); text != "" || len(turn.FunctionCalls) == 0 {
+		content.Parts = append(content.Parts, genai.NewPartFromText(text))
+	}
+	for _, call := range turn.FunctionCalls {
+		content.Parts = append(content.Parts, &genai.Part{FunctionCall: call})
+	}
+
+	return &model.LLMResponse{
+		Content:       content,
+		UsageMetadata: turn.Usage,
+		FinishReason:  genai.FinishReasonStop,
+		TurnComplete:  true,
+	}
+}
@@ -0,0 +1,138 @@
+package fake
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func collect(t *testing.T, m *Model, stream bool) ([]*model.LLMResponse, error) {
+	t.Helper()
+	var responses []*model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), &model.LLMRequest{}, stream) {
+		if err != nil {
+			return responses, err
+		}
+		responses = append(responses, resp)
+	}
+	return responses, nil
+}
+
+func TestModel_ScriptedTurns(t *testing.T) {
+	errBoom := errors.New("boom")
+	m := New("fake-model",
+		Text("hello"),
+		FunctionCall("fileRead", map[string]any{"path": "main.go"}),
+		Error(errBoom),
+	)
+
+	if m.Name() != "fake-model" {
+		t.Errorf("Name() = %q, want %q", m.Name(), "fake-model")
+	}
+
+	responses, err := collect(t, m, false)
+	if err != nil {
+		t.Fatalf("call 1: unexpected error: %v", err)
+	}
+	if len(responses) != 1 || responses[0].Content.Parts[0].Text != "hello" {
+		t.Errorf("call 1: got %+v, want single text response", responses)
+	}
+	if !responses[0].TurnComplete {
+		t.Error("call 1: TurnComplete = false, want true")
+	}
+
+	responses, err = collect(t, m, false)
+	if err != nil {
+		t.Fatalf("call 2: unexpected error: %v", err)
+	}
+	call := responses[0].Content.Parts[0].FunctionCall
+	if call == nil || call.Name != "fileRead" || call.Args["path"] != "main.go" {
+		t.Errorf("call 2: got %+v, want fileRead function call", responses[0].Content.Parts)
+	}
+
+	if _, err := collect(t, m, false); !errors.Is(err, errBoom) {
+		t.Errorf("call 3: error = %v, want %v", err, errBoom)
+	}
+
+	if _, err := collect(t, m, false); err == nil {
+		t.Error("call 4: expected error when script is exhausted")
+	}
+
+	if m.Calls() != 4 {
+		t.Errorf("Calls() = %d, want 4", m.Calls())
+	}
+}
+
+func TestModel_Stream(t *testing.T) {
+	tests := []struct {
+		name      string
+		stream    bool
+		wantCount int
+	}{
+		{name: "streaming yields chunks and final", stream: true, wantCount: 3},
+		{name: "non-streaming yields aggregate", stream: false, wantCount: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("fake-model", Stream("Hello", " world"))
+
+			responses, err := collect(t, m, tt.stream)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(responses) != tt.wantCount {
+				t.Fatalf("got %d responses, want %d", len(responses), tt.wantCount)
+			}
+
+			last := responses[len(responses)-1]
+			if last.Partial {
+				t.Error("final response is marked partial")
+			}
+			if got := last.Content.Parts[0].Text; got != "Hello world" {
+				t.Errorf("final text = %q, want %q", got, "Hello world")
+			}
+		})
+	}
+}
+
+func TestModel_Responder(t *testing.T) {
+	m := NewWithResponder("fake-model", func(req *model.LLMRequest) Turn {
+		return Text(req.Contents[0].Parts[0].Text + "!")
+	})
+	m.Append(Text("scripted"))
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("echo", genai.RoleUser)}}
+
+	var got []string
+	for range 2 {
+		for resp, err := range m.GenerateContent(context.Background(), req, false) {
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got = append(got, resp.Content.Parts[0].Text)
+		}
+	}
+
+	if got[0] != "scripted" || got[1] != "echo!" {
+		t.Errorf("responses = %v, want [scripted echo!]", got)
+	}
+	if len(m.Requests()) != 2 {
+		t.Errorf("Requests() len = %d, want 2", len(m.Requests()))
+	}
+}
+
+func TestModel_CanceledContext(t *testing.T) {
+	m := New("fake-model", Text("unused"))
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, err := range m.GenerateContent(ctx, &model.LLMRequest{}, false) {
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("error = %v, want context.Canceled", err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Package metrics records Prometheus metrics of the requests to a model.LLM.
+package metrics
+
+import (
+	"context"
+	"iter"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/adk/model"
+)
+
+// Prometheus metrics of the model requests, labeled by model name. They are collected by
+// the models returned by Wrap and exported once registered with Register.
+var (
+	modelRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_model_requests_total",
+		Help: "Number of model requests, by status: ok or error.",
+	}, []string{"model", "status"})
+	modelDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agi_model_request_duration_seconds",
+		Help:    "Duration of model requests in seconds, including streaming.",
+		Buckets: []float64{0.1, 0.5, 1, 2, 5, 10, 30, 60, 120, 300},
+	}, []string{"model"})
+	modelTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_model_tokens_total",
+		Help: "Number of tokens reported by the model, by type: prompt or completion.",
+	}, []string{"model", "type"})
+)
+
+// Register registers the model metrics with registerer, such as
+// prometheus.DefaultRegisterer or the registry of a metrics endpoint
+func Register(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{modelRequests, modelDuration, modelTokens} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Model records the metrics of the requests to the model it wraps
+type Model struct {
+	model.LLM
+}
+
+// capabilityModel is a Model whose wrapped model reports its capabilities
+type capabilityModel struct {
+	*Model
+	provider capability.Provider
+}
+
+// Capabilities implements capability.Provider
+func (m *capabilityModel) Capabilities(ctx context.Context) (capability.Capabilities, error) {
+	return m.provider.Capabilities(ctx)
+}
+
+// Wrap returns llm with the metrics of its requests recorded. The result implements
+// capability.Provider when llm does.
+func Wrap(llm model.LLM) model.LLM {
+	m := &Model{LLM: llm}
+	if provider, ok := llm.(capability.Provider); ok {
+		return &capabilityModel{Model: m, provider: provider}
+	}
+	return m
+}
+
+// GenerateContent implements model.LLM
+func (m *Model) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		name := m.Name()
+		start := time.Now()
+		status := "ok"
+		defer func() {
+			modelRequests.WithLabelValues(name, status).Inc()
+			modelDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+		}()
+
+		for resp, err := range m.LLM.GenerateContent(ctx, req, stream) {
+			if err != nil {
+				status = "error"
+			}
+			// Partial responses carry running counts at most; the final one has the totals
+			if resp != nil && resp.UsageMetadata != nil && !resp.Partial {
+				modelTokens.WithLabelValues(name, "prompt").Add(float64(resp.UsageMetadata.PromptTokenCount))
+				modelTokens.WithLabelValues(name, "completion").Add(float64(resp.UsageMetadata.CandidatesTokenCount))
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// plainModel hides the capabilities of the model it wraps
+type plainModel struct {
+	model.LLM
+}
+
+func TestModel(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if err := Register(registry); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	const name = "metrics-test-model"
+	counter := func(c *prometheus.CounterVec, labels ...string) float64 {
+		return testutil.ToFloat64(c.WithLabelValues(labels...))
+	}
+	ok, failed := counter(modelRequests, name, "ok"), counter(modelRequests, name, "error")
+	prompt, completion := counter(modelTokens, name, "prompt"), counter(modelTokens, name, "completion")
+
+	usage := &genai.GenerateContentResponseUsageMetadata{PromptTokenCount: 100, CandidatesTokenCount: 20, TotalTokenCount: 120}
+	llm := Wrap(fake.New(name,
+		fake.Turn{Chunks: []string{"Hel", "lo"}, Usage: usage},
+		fake.Text("no usage"),
+		fake.Error(errors.New("model unavailable")),
+	))
+	if _, ok := llm.(capability.Provider); !ok {
+		t.Error("Wrap() of a capability provider does not implement capability.Provider")
+	}
+	for _, stream := range []bool{true, false, false} {
+		for range llm.GenerateContent(context.Background(), &model.LLMRequest{}, stream) {
+		}
+	}
+
+	if got := counter(modelRequests, name, "ok") - ok; got != 2 {
+		t.Errorf("ok requests = %v, want 2", got)
+	}
+	if got := counter(modelRequests, name, "error") - failed; got != 1 {
+		t.Errorf("failed requests = %v, want 1", got)
+	}
+	if got := counter(modelTokens, name, "prompt") - prompt; got != 100 {
+		t.Errorf("prompt tokens = %v, want 100", got)
+	}
+	if got := counter(modelTokens, name, "completion") - completion; got != 20 {
+		t.Errorf("completion tokens = %v, want 20", got)
+	}
+	if got := testutil.CollectAndCount(modelDuration, "agi_model_request_duration_seconds"); got == 0 {
+		t.Error("no request durations recorded")
+	}
+
+	if _, ok := Wrap(plainModel{fake.New(name)}).(capability.Provider); ok {
+		t.Error("Wrap() of a model without capabilities implements capability.Provider")
+	}
+	// The metrics can be registered only once per registry
+	if err := Register(registry); err == nil {
+		t.Error("Register() twice error = nil, want an already registered error")
+	}
+}
@@ -3,6 +3,7 @@ package ollama
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"iter"
 	"log/slog"
@@ -11,7 +12,10 @@ import (
 	"net/url"
 	"time"
 
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"com.github.dimetron.adk-go-agi/pkg/model/schema"
 	"github.com/ollama/ollama/api"
+	ollamatypes "github.com/ollama/ollama/types/model"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
 )
@@ -21,12 +25,24 @@ type chatClient interface {
 	Chat(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error
 }
 
+// showClient defines the interface for model metadata lookups, allowing for testing with mocks.
+type showClient interface {
+	Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error)
+}
+
 // baseModel holds shared configuration and client for Ollama models.
 type baseModel struct {
 	client  chatClient
+	shower  showClient
 	name    string
 	baseURL string
 	options map[string]interface{}
+	// keepAlive controls how long Ollama keeps the model loaded after a request
+	keepAlive *api.Duration
+	// grammar is an optional GBNF grammar constraining generation
+	grammar string
+	// runningUsage attaches running token counts to intermediate streaming chunks
+	runningUsage bool
 }
 
 // SyncGenerator generates content synchronously (non-streaming).
@@ -56,6 +72,17 @@ type Config struct {
 	HTTPClient *http.Client
 	// Options are model-specific options (temperature, top_p, etc.)
 	Options map[string]interface{}
+	// KeepAlive controls how long the model stays loaded in memory after a request
+	// (default: Ollama server default, usually 5 minutes)
+	KeepAlive time.Duration
+	// Grammar is an optional GBNF grammar that constrains decoding. It is forwarded as the
+	// "grammar" option, which llama.cpp-based runners honor; other runners ignore it.
+	// For JSON output, prefer setting ResponseSchema or ResponseMIMEType on the request,
+	// which maps to Ollama's native structured outputs.
+	Grammar string
+	// StreamRunningUsage attaches running token counts to intermediate streaming chunks.
+	// The turn-complete chunk always carries the totals.
+	StreamRunningUsage bool
 }
 
 // NewModel creates a new Ollama model that implements model.LLM interface.
@@ -132,19 +159,118 @@ func newBaseModel(ctx context.Context, cfg *Config) (*baseModel, error) {
 	// Create Ollama client
 	client := api.NewClient(parsedURL, httpClient)
 
+	var keepAlive *api.Duration
+	if cfg.KeepAlive > 0 {
+		keepAlive = &api.Duration{Duration: cfg.KeepAlive}
+	}
+
 	return &baseModel{
-		client:  client,
-		name:    cfg.ModelName,
-		baseURL: baseURL,
-		options: cfg.Options,
+		client:       client,
+		shower:       client,
+		name:         cfg.ModelName,
+		baseURL:      baseURL,
+		options:      cfg.Options,
+		keepAlive:    keepAlive,
+		grammar:      cfg.Grammar,
+		runningUsage: cfg.StreamRunningUsage,
 	}, nil
 }
 
+// Warmup preloads the model into Ollama's memory so the first user request
+// does not pay the model load cost. It sends a chat request with no messages,
+// which Ollama treats as a load-only request.
+func (b *baseModel) Warmup(ctx context.Context) error {
+	slog.InfoContext(ctx, "Warming up Ollama model",
+		"model", b.name)
+	start := time.Now()
+
+	chatReq := &api.ChatRequest{
+		Model:     b.name,
+		Messages:  []api.Message{},
+		Stream:    new(bool), // false
+		KeepAlive: b.keepAlive,
+	}
+
+	err := b.client.Chat(ctx, chatReq, func(resp api.ChatResponse) error {
+		return nil
+	})
+	duration := time.Since(start)
+	if err != nil {
+		slog.WarnContext(ctx, "Ollama model warm-up failed",
+			"model", b.name,
+			"duration_ms", duration.Milliseconds(),
+			"error", err)
+		return fmt.Errorf("ollama warm-up failed: %w", err)
+	}
+
+	slog.InfoContext(ctx, "Ollama model warm-up completed",
+		"model", b.name,
+		"duration_ms", duration.Milliseconds())
+	return nil
+}
+
 // Name returns the model name.
 func (m *Model) Name() string {
 	return m.syncGen.name
 }
 
+// Capabilities queries the Ollama show API for the model's capabilities.
+func (b *baseModel) Capabilities(ctx context.Context) (capability.Capabilities, error) {
+	resp, err := b.shower.Show(ctx, &api.ShowRequest{Model: b.name})
+	if err != nil {
+		return capability.Capabilities{}, fmt.Errorf("ollama show failed: %w", err)
+	}
+
+	caps := capability.Capabilities{
+		ContextWindow: contextLength(resp.ModelInfo),
+	}
+	for _, c := range resp.Capabilities {
+		switch c {
+		case ollamatypes.CapabilityTools:
+			caps.SupportsTools = true
+		case ollamatypes.CapabilityVision:
+			caps.SupportsVision = true
+		case ollamatypes.CapabilityCompletion:
+			// Ollama can constrain any completion model's output with format: json
+			caps.SupportsJSONMode = true
+		}
+	}
+
+	slog.InfoContext(ctx, "Detected Ollama model capabilities",
+		"model", b.name,
+		"tools", caps.SupportsTools,
+		"vision", caps.SupportsVision,
+		"json_mode", caps.SupportsJSONMode,
+		"context_window", caps.ContextWindow)
+	return caps, nil
+}
+
+// contextLength extracts the "<architecture>.context_length" entry from Ollama model info.
+func contextLength(info map[string]any) int {
+	arch, _ := info["general.architecture"].(string)
+	if arch == "" {
+		return 0
+	}
+	switch v := info[arch+".context_length"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+// Capabilities queries the Ollama show API for the model's capabilities.
+func (m *Model) Capabilities(ctx context.Context) (capability.Capabilities, error) {
+	return m.syncGen.Capabilities(ctx)
+}
+
+// Warmup preloads the model so the first generation does not pay the load cost.
+func (m *Model) Warmup(ctx context.Context) error {
+	return m.syncGen.Warmup(ctx)
+}
+
 // GenerateContent implements the model.LLM interface.
 // It delegates to the appropriate generator based on the stream parameter.
 func (m *Model) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
@@ -174,10 +300,12 @@ func (g *SyncGenerator) generate(ctx context.Context, req *model.LLMRequest) ite
 
 		// Build Ollama chat request
 		chatReq := &api.ChatRequest{
-			Model:    g.name,
-			Messages: messages,
-			Options:  g.options,
-			Stream:   new(bool), // false
+			Model:     g.name,
+			Messages:  messages,
+			Options:   g.requestOptions(),
+			Format:    responseFormat(req),
+			Stream:    new(bool), // false
+			KeepAlive: g.keepAlive,
 		}
 
 		// Log start of API call
@@ -242,10 +370,12 @@ func (g *StreamGenerator) generate(ctx context.Context, req *model.LLMRequest) i
 
 		// Build Ollama chat request with streaming
 		chatReq := &api.ChatRequest{
-			Model:    g.name,
-			Messages: messages,
-			Options:  g.options,
-			Stream:   ptrBool(true),
+			Model:     g.name,
+			Messages:  messages,
+			Options:   g.requestOptions(),
+			Format:    responseFormat(req),
+			Stream:    ptrBool(true),
+			KeepAlive: g.keepAlive,
 		}
 
 		// Log start of streaming API call
@@ -257,6 +387,7 @@ func (g *StreamGenerator) generate(ctx context.Context, req *model.LLMRequest) i
 
 		var chunkCount int
 		var lastResponse *api.ChatResponse
+		var usage usageAccumulator
 
 		err = g.client.Chat(ctx, chatReq, func(resp api.ChatResponse) error {
 			// Check if context is canceled before processing each chunk
@@ -268,10 +399,19 @@ func (g *StreamGenerator) generate(ctx context.Context, req *model.LLMRequest) i
 
 			chunkCount++
 			lastResponse = &resp
+			usage.add(&resp)
 			llmResp := convertChatResponseToLLMResponse(&resp)
 			llmResp.Partial = !resp.Done
 			llmResp.TurnComplete = resp.Done
 
+			// Only the final chunk carries totals; intermediate chunks carry
+			// running counts when enabled and no usage otherwise.
+			if resp.Done || g.runningUsage {
+				llmResp.UsageMetadata = usage.metadata()
+			} else {
+				llmResp.UsageMetadata = nil
+			}
+
 			if !yield(llmResp, nil) {
 				// Consumer stopped - signal to stop the stream immediately
 				slog.InfoContext(ctx, "Consumer stopped streaming",
@@ -306,14 +446,95 @@ func (g *StreamGenerator) generate(ctx context.Context, req *model.LLMRequest) i
 		}
 		if lastResponse != nil {
 			logArgs = append(logArgs,
-				"prompt_tokens", lastResponse.PromptEvalCount,
-				"completion_tokens", lastResponse.EvalCount,
-				"total_tokens", lastResponse.PromptEvalCount+lastResponse.EvalCount)
+				"prompt_tokens", usage.promptTokens,
+				"completion_tokens", usage.completionTokens(),
+				"total_tokens", usage.promptTokens+usage.completionTokens())
 		}
 		slog.InfoContext(ctx, "Ollama streaming API call completed", logArgs...)
 	}
 }
 
+// requestOptions returns the model options for a chat request, including the grammar if configured.
+func (b *baseModel) requestOptions() map[string]interface{} {
+	if b.grammar == "" {
+		return b.options
+	}
+	opts := make(map[string]interface{}, len(b.options)+1)
+	for k, v := range b.options {
+		opts[k] = v
+	}
+	opts["grammar"] = b.grammar
+	return opts
+}
+
+// responseFormat maps the request's structured output settings to Ollama's format field.
+// A response schema takes precedence over a JSON MIME type.
+func responseFormat(req *model.LLMRequest) json.RawMessage {
+	if req == nil || req.Config == nil {
+		return nil
+	}
+	if req.Config.ResponseSchema != nil {
+		format, err := json.Marshal(schema.ToJSONSchema(req.Config.ResponseSchema))
+		if err == nil {
+			return format
+		}
+		slog.Warn("Failed to encode response schema, falling back to JSON mode", "error", err)
+		return json.RawMessage(`"json"`)
+	}
+	if req.Config.ResponseMIMEType == "application/json" {
+		return json.RawMessage(`"json"`)
+	}
+	return nil
+}
+
+// usageAccumulator aggregates token counts across streaming chunks.
+// Ollama reports counts only on the final chunk, so until then completion
+// tokens are estimated from the number of content chunks (about one token each).
+type usageAccumulator struct {
+	promptTokens    int
+	evalTokens      int
+	contentChunks   int
+	reportedByModel bool
+}
+
+// add records the counts carried by a streaming chunk.
+func (u *usageAccumulator) add(resp *api.ChatResponse) {
+	if resp.Message.Content != "" || resp.Message.Thinking != "" {
+		u.contentChunks++
+	}
+	if resp.PromptEvalCount > u.promptTokens {
+		u.promptTokens = resp.PromptEvalCount
+	}
+	// Counts are totals of the request so far, not increments, like PromptEvalCount
+	if resp.EvalCount > u.evalTokens {
+		u.evalTokens = resp.EvalCount
+	}
+	if resp.EvalCount > 0 {
+		u.reportedByModel = true
+	}
+}
+
+// completionTokens returns the reported completion count, or the running estimate.
+func (u *usageAccumulator) completionTokens() int {
+	if u.reportedByModel {
+		return u.evalTokens
+	}
+	return u.contentChunks
+}
+
+// metadata returns the accumulated usage, or nil if nothing has been counted yet.
+func (u *usageAccumulator) metadata() *genai.GenerateContentResponseUsageMetadata {
+	completion := u.completionTokens()
+	if u.promptTokens == 0 && completion == 0 {
+		return nil
+	}
+	return &genai.GenerateContentResponseUsageMetadata{
+		PromptTokenCount:     int32(u.promptTokens),
+		CandidatesTokenCount: int32(completion),
+		TotalTokenCount:      int32(u.promptTokens + completion),
+	}
+}
+
 // convertContentsToMessages converts genai.Content to Ollama messages.
 func convertContentsToMessages(contents []*genai.Content) ([]api.Message, error) {
 	messages := make([]api.Message, 0, len(contents))
@@ -6,7 +6,9 @@ import (
 	"testing"
 	"time"
 
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
 	"github.com/ollama/ollama/api"
+	ollamatypes "github.com/ollama/ollama/types/model"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
 )
@@ -727,3 +729,281 @@ func TestGenerateContentStreamingSwitch(t *testing.T) {
 		})
 	}
 }
+
+// TestWarmup verifies that Warmup sends a load-only chat request and surfaces errors.
+func TestWarmup(t *testing.T) {
+	tests := []struct {
+		name     string
+		chatFunc func(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error
+		wantErr  bool
+	}{
+		{
+			name: "model loaded",
+			chatFunc: func(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+				if len(req.Messages) != 0 {
+					t.Errorf("warm-up request has %d messages, want 0", len(req.Messages))
+				}
+				if req.KeepAlive == nil || req.KeepAlive.Duration != 10*time.Minute {
+					t.Errorf("warm-up request keep_alive = %v, want 10m", req.KeepAlive)
+				}
+				return fn(api.ChatResponse{Model: req.Model, Done: true, DoneReason: "load"})
+			},
+			wantErr: false,
+		},
+		{
+			name: "server unavailable",
+			chatFunc: func(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+				return errors.New("connection refused")
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := baseModel{
+				client:    &mockClient{chatFunc: tt.chatFunc},
+				name:      "test-model",
+				baseURL:   "http://localhost:11434",
+				keepAlive: &api.Duration{Duration: 10 * time.Minute},
+			}
+			m := &Model{
+				syncGen:   &SyncGenerator{baseModel: base},
+				streamGen: &StreamGenerator{baseModel: base},
+			}
+
+			err := m.Warmup(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Warmup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// mockShowClient is a mock implementation of the showClient interface for testing.
+type mockShowClient struct {
+	resp *api.ShowResponse
+	err  error
+}
+
+// Show implements the showClient interface.
+func (m *mockShowClient) Show(ctx context.Context, req *api.ShowRequest) (*api.ShowResponse, error) {
+	return m.resp, m.err
+}
+
+// TestCapabilities verifies capability detection from the Ollama show API.
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name    string
+		shower  *mockShowClient
+		want    capability.Capabilities
+		wantErr bool
+	}{
+		{
+			name: "tool and vision capable model",
+			shower: &mockShowClient{resp: &api.ShowResponse{
+				Capabilities: []ollamatypes.Capability{
+					ollamatypes.CapabilityCompletion,
+					ollamatypes.CapabilityTools,
+					ollamatypes.CapabilityVision,
+				},
+				ModelInfo: map[string]any{
+					"general.architecture": "llama",
+					"llama.context_length": float64(131072),
+				},
+			}},
+			want: capability.Capabilities{
+				SupportsTools:    true,
+				SupportsVision:   true,
+				SupportsJSONMode: true,
+				ContextWindow:    131072,
+			},
+		},
+		{
+			name: "completion-only model without model info",
+			shower: &mockShowClient{resp: &api.ShowResponse{
+				Capabilities: []ollamatypes.Capability{ollamatypes.CapabilityCompletion},
+			}},
+			want: capability.Capabilities{SupportsJSONMode: true},
+		},
+		{
+			name:    "show API error",
+			shower:  &mockShowClient{err: errors.New("model not found")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			base := baseModel{
+				client: &mockClient{},
+				shower: tt.shower,
+				name:   "test-model",
+			}
+			m := &Model{
+				syncGen:   &SyncGenerator{baseModel: base},
+				streamGen: &StreamGenerator{baseModel: base},
+			}
+
+			got, err := m.Capabilities(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Capabilities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Capabilities() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestGrammarAndFormat verifies the grammar option and structured output format are forwarded.
+func TestGrammarAndFormat(t *testing.T) {
+	tests := []struct {
+		name       string
+		grammar    string
+		config     *genai.GenerateContentConfig
+		wantFormat string
+	}{
+		{
+			name:    "grammar only",
+			grammar: `root ::= "yes" | "no"`,
+		},
+		{
+			name:       "json mime type",
+			config:     &genai.GenerateContentConfig{ResponseMIMEType: "application/json"},
+			wantFormat: `"json"`,
+		},
+		{
+			name: "response schema",
+			config: &genai.GenerateContentConfig{ResponseSchema: &genai.Schema{
+				Type:       genai.TypeObject,
+				Properties: map[string]*genai.Schema{"ok": {Type: genai.TypeBoolean}},
+			}},
+			wantFormat: `{"properties":{"ok":{"type":"boolean"}},"type":"object"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := map[string]interface{}{"temperature": 0.1}
+			var gotReq *api.ChatRequest
+			gen := &SyncGenerator{baseModel: baseModel{
+				client: &mockClient{chatFunc: func(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+					gotReq = req
+					return fn(api.ChatResponse{Message: api.Message{Role: "assistant", Content: "{}"}, Done: true})
+				}},
+				name:    "test-model",
+				options: options,
+				grammar: tt.grammar,
+			}}
+
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Test", genai.RoleUser)},
+				Config:   tt.config,
+			}
+			for range gen.generate(context.Background(), req) {
+			}
+
+			if gotReq == nil {
+				t.Fatal("chat request was not sent")
+			}
+			if got, _ := gotReq.Options["grammar"].(string); got != tt.grammar {
+				t.Errorf("grammar option = %q, want %q", got, tt.grammar)
+			}
+			if _, leaked := options["grammar"]; leaked {
+				t.Error("grammar was written into the shared options map")
+			}
+			if string(gotReq.Format) != tt.wantFormat {
+				t.Errorf("format = %s, want %s", gotReq.Format, tt.wantFormat)
+			}
+		})
+	}
+}
+
+func TestStreamUsageAccumulation(t *testing.T) {
+	chunks := []api.ChatResponse{
+		{Message: api.Message{Role: "assistant", Content: "Hello"}},
+		{Message: api.Message{Role: "assistant", Content: " world"}},
+		{Message: api.Message{Role: "assistant", Content: "!"}, Done: true},
+	}
+	chunks[2].PromptEvalCount = 10
+	chunks[2].EvalCount = 5
+
+	tests := []struct {
+		name         string
+		runningUsage bool
+		wantInterim  []int32
+	}{
+		{name: "totals on final chunk only", runningUsage: false, wantInterim: nil},
+		{name: "running usage", runningUsage: true, wantInterim: []int32{1, 2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gen := &StreamGenerator{baseModel: baseModel{
+				client: &mockClient{chatFunc: func(ctx context.Context, req *api.ChatRequest, fn api.ChatResponseFunc) error {
+					for _, chunk := range chunks {
+						if err := fn(chunk); err != nil {
+							return err
+						}
+					}
+					return nil
+				}},
+				name:         "test-model",
+				options:      make(map[string]interface{}),
+				runningUsage: tt.runningUsage,
+			}}
+
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("Test", genai.RoleUser)},
+			}
+
+			var responses []*model.LLMResponse
+			for resp, err := range gen.generate(context.Background(), req) {
+				if err != nil {
+					t.Fatalf("generate() error = %v", err)
+				}
+				responses = append(responses, resp)
+			}
+			if len(responses) != 3 {
+				t.Fatalf("got %d responses, want 3", len(responses))
+			}
+
+			for i, resp := range responses[:2] {
+				if tt.wantInterim == nil {
+					if resp.UsageMetadata != nil {
+						t.Errorf("chunk %d usage = %+v, want nil", i, resp.UsageMetadata)
+					}
+					continue
+				}
+				if resp.UsageMetadata == nil {
+					t.Fatalf("chunk %d usage is nil", i)
+				}
+				if got := resp.UsageMetadata.CandidatesTokenCount; got != tt.wantInterim[i] {
+					t.Errorf("chunk %d candidates tokens = %d, want %d", i, got, tt.wantInterim[i])
+				}
+			}
+
+			final := responses[2].UsageMetadata
+			if final == nil {
+				t.Fatal("final chunk has no usage metadata")
+			}
+			if final.PromptTokenCount != 10 || final.CandidatesTokenCount != 5 || final.TotalTokenCount != 15 {
+				t.Errorf("final usage = %+v, want prompt 10, candidates 5, total 15", final)
+			}
+		})
+	}
+}
+
+func TestUsageAccumulator_RunningCounts(t *testing.T) {
+	// Servers that report counts before the final chunk send totals so far, not increments
+	var usage usageAccumulator
+	for _, counts := range [][2]int{{10, 2}, {10, 4}, {10, 5}} {
+		resp := api.ChatResponse{Message: api.Message{Role: "assistant", Content: "x"}}
+		resp.PromptEvalCount, resp.EvalCount = counts[0], counts[1]
+		usage.add(&resp)
+	}
+	if got := usage.metadata(); got.PromptTokenCount != 10 || got.CandidatesTokenCount != 5 || got.TotalTokenCount != 15 {
+		t.Errorf("metadata() = %+v, want prompt 10, candidates 5, total 15", got)
+	}
+}
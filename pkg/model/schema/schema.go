@@ -0,0 +1,69 @@
+// Package schema converts genai response schemas into standard JSON Schema for backends
+// that accept JSON Schema constrained decoding (Ollama structured outputs, TGI grammars).
+package schema
+
+import (
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// ToJSONSchema converts a genai.Schema into a JSON Schema document.
+// genai uses upper-case OpenAPI type names ("OBJECT"); JSON Schema expects lower-case.
+func ToJSONSchema(s *genai.Schema) map[string]any {
+	if s == nil {
+		return nil
+	}
+
+	out := make(map[string]any)
+	if s.Type != genai.TypeUnspecified && s.Type != "" {
+		out["type"] = strings.ToLower(string(s.Type))
+	}
+	if s.Description != "" {
+		out["description"] = s.Description
+	}
+	if len(s.Enum) > 0 {
+		out["enum"] = s.Enum
+	}
+	if s.Format != "" {
+		out["format"] = s.Format
+	}
+	if len(s.Properties) > 0 {
+		props := make(map[string]any, len(s.Properties))
+		for name, prop := range s.Properties {
+			props[name] = ToJSONSchema(prop)
+		}
+		out["properties"] = props
+	}
+	if len(s.Required) > 0 {
+		out["required"] = s.Required
+	}
+	if s.Items != nil {
+		out["items"] = ToJSONSchema(s.Items)
+	}
+	if len(s.AnyOf) > 0 {
+		anyOf := make([]any, 0, len(s.AnyOf))
+		for _, sub := range s.AnyOf {
+			anyOf = append(anyOf, ToJSONSchema(sub))
+		}
+		out["anyOf"] = anyOf
+	}
+	if s.MinItems != nil {
+		out["minItems"] = *s.MinItems
+	}
+	if s.MaxItems != nil {
+		out["maxItems"] = *s.MaxItems
+	}
+	if s.Minimum != nil {
+		out["minimum"] = *s.Minimum
+	}
+	if s.Maximum != nil {
+		out["maximum"] = *s.Maximum
+	}
+	if s.Nullable != nil && *s.Nullable {
+		if t, ok := out["type"].(string); ok {
+			out["type"] = []string{t, "null"}
+		}
+	}
+	return out
+}
@@ -0,0 +1,35 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+
+	"google.golang.org/genai"
+)
+
+func TestToJSONSchema(t *testing.T) {
+	minScore := float64(0)
+	in := &genai.Schema{
+		Type: genai.TypeObject,
+		Properties: map[string]*genai.Schema{
+			"score":  {Type: genai.TypeInteger, Minimum: &minScore},
+			"issues": {Type: genai.TypeArray, Items: &genai.Schema{Type: genai.TypeString}},
+			"status": {Type: genai.TypeString, Enum: []string{"pass", "fail"}, Nullable: genai.Ptr(true)},
+		},
+		Required: []string{"score"},
+	}
+
+	got, err := json.Marshal(ToJSONSchema(in))
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+
+	want := `{"properties":{"issues":{"items":{"type":"string"},"type":"array"},"score":{"minimum":0,"type":"integer"},"status":{"enum":["pass","fail"],"type":["string","null"]}},"required":["score"],"type":"object"}`
+	if string(got) != want {
+		t.Errorf("ToJSONSchema() = %s, want %s", got, want)
+	}
+
+	if ToJSONSchema(nil) != nil {
+		t.Error("ToJSONSchema(nil) should return nil")
+	}
+}
@@ -0,0 +1,488 @@
+// Package tgi implements the model.LLM interface for Hugging Face Text Generation Inference (TGI) servers.
+package tgi
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"com.github.dimetron.adk-go-agi/pkg/model/capability"
+	"com.github.dimetron.adk-go-agi/pkg/model/schema"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// maxStreamLineSize bounds a single server-sent event line from generate_stream
+const maxStreamLineSize = 1024 * 1024
+
+// Grammar constrains TGI decoding to a JSON schema or regular expression.
+type Grammar struct {
+	// Type is "json" (Value is a JSON Schema) or "regex" (Value is a pattern string)
+	Type string `json:"type"`
+	// Value is the schema or pattern
+	Value any `json:"value"`
+}
+
+// Parameters are the TGI generation parameters sent with every request.
+type Parameters struct {
+	// MaxNewTokens is the maximum number of tokens to generate
+	MaxNewTokens int `json:"max_new_tokens,omitempty"`
+	// Temperature controls sampling randomness
+	Temperature *float64 `json:"temperature,omitempty"`
+	// TopP is the nucleus sampling probability
+	TopP *float64 `json:"top_p,omitempty"`
+	// TopK limits sampling to the K most likely tokens
+	TopK int `json:"top_k,omitempty"`
+	// RepetitionPenalty penalizes repeated tokens
+	RepetitionPenalty *float64 `json:"repetition_penalty,omitempty"`
+	// Stop is a list of sequences that end generation
+	Stop []string `json:"stop,omitempty"`
+	// Seed makes sampling deterministic
+	Seed *int64 `json:"seed,omitempty"`
+	// DoSample enables sampling instead of greedy decoding
+	DoSample bool `json:"do_sample,omitempty"`
+	// Details requests finish reason and token metadata (always enabled by the client)
+	Details bool `json:"details"`
+	// DecoderInputDetails returns prefill tokens, used to count prompt tokens (non-streaming only)
+	DecoderInputDetails bool `json:"decoder_input_details,omitempty"`
+	// Grammar constrains generation to a schema or pattern. A request ResponseSchema
+	// or JSON ResponseMIMEType overrides it per call.
+	Grammar *Grammar `json:"grammar,omitempty"`
+}
+
+// Config holds configuration for creating a TGI model.
+type Config struct {
+	// ModelName is a display name for the served model (TGI serves a single model per endpoint)
+	ModelName string
+	// BaseURL is the TGI endpoint (default: "http://localhost:8080")
+	BaseURL string
+	// Token is an optional bearer token (e.g., for Hugging Face Inference Endpoints)
+	Token string
+	// HTTPClient is an optional custom HTTP client
+	HTTPClient *http.Client
+	// Parameters are the default generation parameters
+	Parameters Parameters
+	// PromptFormatter renders the conversation into a raw prompt (default: ChatML)
+	PromptFormatter func(system string, contents []*genai.Content) string
+}
+
+// Model implements the model.LLM interface for a TGI server.
+type Model struct {
+	httpClient *http.Client
+	name       string
+	baseURL    string
+	token      string
+	params     Parameters
+	format     func(system string, contents []*genai.Content) string
+}
+
+// generateRequest is the body of /generate and /generate_stream requests.
+type generateRequest struct {
+	Inputs     string     `json:"inputs"`
+	Parameters Parameters `json:"parameters"`
+	Stream     bool       `json:"stream,omitempty"`
+}
+
+// token is a single generated token.
+type token struct {
+	ID      int     `json:"id"`
+	Text    string  `json:"text"`
+	LogProb float64 `json:"logprob"`
+	Special bool    `json:"special"`
+}
+
+// details carries generation metadata returned by TGI.
+type details struct {
+	FinishReason    string  `json:"finish_reason"`
+	GeneratedTokens int     `json:"generated_tokens"`
+	Prefill         []token `json:"prefill"`
+}
+
+// generateResponse is the body returned by /generate.
+type generateResponse struct {
+	GeneratedText string   `json:"generated_text"`
+	Details       *details `json:"details"`
+}
+
+// streamResponse is a single event of /generate_stream.
+type streamResponse struct {
+	Token         token    `json:"token"`
+	GeneratedText *string  `json:"generated_text"`
+	Details       *details `json:"details"`
+	Error         string   `json:"error"`
+}
+
+// infoResponse is the subset of /info used for capability detection.
+type infoResponse struct {
+	ModelID        string `json:"model_id"`
+	MaxInputTokens int    `json:"max_input_tokens"`
+	MaxTotalTokens int    `json:"max_total_tokens"`
+}
+
+// NewModel creates a new TGI model that implements model.LLM interface.
+func NewModel(ctx context.Context, cfg *Config) (*Model, error) {
+	if cfg == nil {
+		return nil, fmt.Errorf("config cannot be nil")
+	}
+	if cfg.ModelName == "" {
+		return nil, fmt.Errorf("model name is required")
+	}
+
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = "http://localhost:8080"
+	}
+	if _, err := url.ParseRequestURI(baseURL); err != nil {
+		return nil, fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	// An overall client timeout would cut off long streams, so requests are bounded by their
+	// context, and the transport bounds how long the server takes to answer. /generate answers
+	// once the text is generated, so its header timeout is long; /generate_stream answers at once.
+	httpClient := cfg.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   30 * time.Second, // Connection timeout
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ResponseHeaderTimeout: 5 * time.Minute, // Wait for the generated text of /generate
+				ExpectContinueTimeout: 1 * time.Second,
+				IdleConnTimeout:       90 * time.Second,
+			},
+		}
+	}
+
+	format := cfg.PromptFormatter
+	if format == nil {
+		format = ChatMLPrompt
+	}
+
+	return &Model{
+		httpClient: httpClient,
+		name:       cfg.ModelName,
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      cfg.Token,
+		params:     cfg.Parameters,
+		format:     format,
+	}, nil
+}
+
+// Name returns the model name.
+func (m *Model) Name() string {
+	return m.name
+}
+
+// Capabilities queries the TGI /info endpoint for the served model's limits.
+// The raw generate API has no native tool calling, so SupportsTools is always false.
+func (m *Model) Capabilities(ctx context.Context) (capability.Capabilities, error) {
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, m.baseURL+"/info", nil)
+	if err != nil {
+		return capability.Capabilities{}, fmt.Errorf("failed to create TGI info request: %w", err)
+	}
+	if m.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.token)
+	}
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return capability.Capabilities{}, fmt.Errorf("tgi info request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return capability.Capabilities{}, fmt.Errorf("tgi info request failed with status %d", resp.StatusCode)
+	}
+
+	var info infoResponse
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return capability.Capabilities{}, fmt.Errorf("failed to decode TGI info: %w", err)
+	}
+
+	return capability.Capabilities{
+		SupportsJSONMode: true, // TGI grammars support JSON schema constrained output
+		ContextWindow:    info.MaxTotalTokens,
+	}, nil
+}
+
+// GenerateContent implements the model.LLM interface.
+func (m *Model) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if stream {
+		return m.generateStream(ctx, req)
+	}
+	return m.generate(ctx, req)
+}
+
+// generate implements synchronous generation via /generate.
+func (m *Model) generate(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+
+		body := m.buildRequest(req, false)
+		slog.InfoContext(ctx, "Starting TGI API call",
+			"model", m.name,
+			"stream", false,
+			"prompt_bytes", len(body.Inputs))
+		start := time.Now()
+
+		resp, err := m.post(ctx, "/generate", body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		var out generateResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			yield(nil, fmt.Errorf("failed to decode TGI response: %w", err))
+			return
+		}
+
+		llmResp := convertResponse(out.GeneratedText, out.Details, body.Parameters.Stop)
+		slog.InfoContext(ctx, "TGI API call completed",
+			"model", m.name,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"finish_reason", llmResp.FinishReason)
+		yield(llmResp, nil)
+	}
+}
+
+// generateStream implements streaming generation via /generate_stream.
+func (m *Model) generateStream(ctx context.Context, req *model.LLMRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if err := ctx.Err(); err != nil {
+			return
+		}
+
+		body := m.buildRequest(req, true)
+		slog.InfoContext(ctx, "Starting TGI streaming API call",
+			"model", m.name,
+			"stream", true,
+			"prompt_bytes", len(body.Inputs))
+		start := time.Now()
+
+		resp, err := m.post(ctx, "/generate_stream", body)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		var chunkCount int
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 0, 64*1024), maxStreamLineSize)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			data, ok := strings.CutPrefix(line, "data:")
+			if !ok {
+				continue
+			}
+
+			var event streamResponse
+			if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &event); err != nil {
+				yield(nil, fmt.Errorf("failed to decode TGI stream event: %w", err))
+				return
+			}
+			if event.Error != "" {
+				yield(nil, fmt.Errorf("tgi streaming failed: %s", event.Error))
+				return
+			}
+
+			chunkCount++
+			if event.GeneratedText != nil || event.Details != nil {
+				var text string
+				if event.GeneratedText != nil {
+					text = *event.GeneratedText
+				}
+				final := convertResponse(text, event.Details, body.Parameters.Stop)
+				slog.InfoContext(ctx, "TGI streaming API call completed",
+					"model", m.name,
+					"duration_ms", time.Since(start).Milliseconds(),
+					"chunks_received", chunkCount)
+				yield(final, nil)
+				return
+			}
+
+			if event.Token.Special {
+				continue
+			}
+			partial := &model.LLMResponse{
+				Content: genai.NewContentFromText(event.Token.Text, genai.RoleModel),
+				Partial: true,
+			}
+			if !yield(partial, nil) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			yield(nil, fmt.Errorf("tgi streaming failed: %w", err))
+			return
+		}
+		if ctx.Err() == nil {
+			yield(nil, fmt.Errorf("tgi stream ended without a final response"))
+		}
+	}
+}
+
+// buildRequest converts an LLMRequest into a TGI request, applying per-request overrides.
+func (m *Model) buildRequest(req *model.LLMRequest, stream bool) generateRequest {
+	params := m.params
+	params.Details = true
+	params.DecoderInputDetails = !stream
+	params.Stop = append([]string(nil), m.params.Stop...)
+
+	var system string
+	if cfg := req.Config; cfg != nil {
+		if cfg.SystemInstruction != nil {
+			system = textOf(cfg.SystemInstruction)
+		}
+		if cfg.Temperature != nil {
+			t := float64(*cfg.Temperature)
+			params.Temperature = &t
+		}
+		if cfg.TopP != nil {
+			p := float64(*cfg.TopP)
+			params.TopP = &p
+		}
+		if cfg.MaxOutputTokens > 0 {
+			params.MaxNewTokens = int(cfg.MaxOutputTokens)
+		}
+		params.Stop = append(params.Stop, cfg.StopSequences...)
+		switch {
+		case cfg.ResponseSchema != nil:
+			params.Grammar = &Grammar{Type: "json", Value: schema.ToJSONSchema(cfg.ResponseSchema)}
+		case cfg.ResponseMIMEType == "application/json":
+			params.Grammar = &Grammar{Type: "json", Value: map[string]any{"type": "object"}}
+		}
+	}
+
+	return generateRequest{
+		Inputs:     m.format(system, req.Contents),
+		Parameters: params,
+		Stream:     stream,
+	}
+}
+
+// post sends a JSON request to the TGI server and checks the status code.
+func (m *Model) post(ctx context.Context, path string, body generateRequest) (*http.Response, error) {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode TGI request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, m.baseURL+path, bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TGI request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	if m.token != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+m.token)
+	}
+
+	resp, err := m.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("tgi request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("tgi request failed with status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// convertResponse maps TGI output and details to an LLMResponse.
+func convertResponse(text string, d *details, stop []string) *model.LLMResponse {
+	llmResp := &model.LLMResponse{
+		TurnComplete: true,
+		FinishReason: genai.FinishReasonStop,
+	}
+
+	if d != nil {
+		switch d.FinishReason {
+		case "length":
+			llmResp.FinishReason = genai.FinishReasonMaxTokens
+		case "stop_sequence":
+			// TGI includes the matched stop sequence in the output
+			for _, s := range stop {
+				if trimmed, ok := strings.CutSuffix(text, s); ok {
+					text = trimmed
+					break
+				}
+			}
+		}
+
+		if d.GeneratedTokens > 0 || len(d.Prefill) > 0 {
+			llmResp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+				PromptTokenCount:     int32(len(d.Prefill)),
+				CandidatesTokenCount: int32(d.GeneratedTokens),
+				TotalTokenCount:      int32(len(d.Prefill) + d.GeneratedTokens),
+			}
+		}
+	}
+
+	llmResp.Content = genai.NewContentFromText(text, genai.RoleModel)
+	return llmResp
+}
+
+// ChatMLPrompt renders a conversation with the ChatML template used by most instruction-tuned models.
+func ChatMLPrompt(system string, contents []*genai.Content) string {
+	var b strings.Builder
+	if system != "" {
+		fmt.Fprintf(&b, "<|im_start|>system\n%s<|im_end|>\n", system)
+	}
+	for _, content := range contents {
+		if content == nil {
+			continue
+		}
+		role := content.Role
+		switch role {
+		case "", genai.RoleUser:
+			role = "user"
+		case genai.RoleModel:
+			role = "assistant"
+		}
+		fmt.Fprintf(&b, "<|im_start|>%s\n%s<|im_end|>\n", role, textOf(content))
+	}
+	b.WriteString("<|im_start|>assistant\n")
+	return b.String()
+}
+
+// textOf concatenates the text parts of content, rendering tool traffic as text.
+func textOf(content *genai.Content) string {
+	var b strings.Builder
+	for _, part := range content.Parts {
+		if part == nil {
+			continue
+		}
+		b.WriteString(part.Text)
+		if part.FunctionCall != nil {
+			fmt.Fprintf(&b, "[FunctionCall: %s]", part.FunctionCall.Name)
+		}
+		if part.FunctionResponse != nil {
+			fmt.Fprintf(&b, "[FunctionResponse: %s]", part.FunctionResponse.Name)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,291 @@
+package tgi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func newTestModel(t *testing.T, handler http.HandlerFunc) *Model {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	m, err := NewModel(context.Background(), &Config{
+		ModelName:  "tgi-test",
+		BaseURL:    srv.URL,
+		Token:      "secret",
+		Parameters: Parameters{MaxNewTokens: 64, Stop: []string{"<|im_end|>"}},
+	})
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+	return m
+}
+
+func testRequest() *model.LLMRequest {
+	return &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("Hello", genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText("Be brief.", genai.RoleUser),
+			StopSequences:     []string{"END"},
+		},
+	}
+}
+
+func TestNewModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     *Config
+		wantErr bool
+	}{
+		{name: "valid config", cfg: &Config{ModelName: "llama", BaseURL: "http://localhost:8080"}},
+		{name: "default base URL", cfg: &Config{ModelName: "llama"}},
+		{name: "nil config", cfg: nil, wantErr: true},
+		{name: "empty model name", cfg: &Config{BaseURL: "http://localhost:8080"}, wantErr: true},
+		{name: "invalid URL", cfg: &Config{ModelName: "llama", BaseURL: "://bad"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := NewModel(context.Background(), tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewModel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && m.Name() != tt.cfg.ModelName {
+				t.Errorf("Name() = %q, want %q", m.Name(), tt.cfg.ModelName)
+			}
+			// Streams may outlast any fixed timeout, so the default client bounds only the
+			// wait for the response headers
+			if !tt.wantErr && (m.httpClient.Timeout != 0 || m.httpClient.Transport.(*http.Transport).ResponseHeaderTimeout == 0) {
+				t.Errorf("default client timeout = %v, want none with a response header timeout", m.httpClient.Timeout)
+			}
+		})
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	m := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generate" {
+			t.Errorf("path = %q, want /generate", r.URL.Path)
+		}
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q, want bearer token", got)
+		}
+
+		var req generateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("failed to decode request: %v", err)
+		}
+		if !strings.Contains(req.Inputs, "<|im_start|>system\nBe brief.") {
+			t.Errorf("prompt missing system instruction: %q", req.Inputs)
+		}
+		if !req.Parameters.Details || req.Parameters.MaxNewTokens != 64 {
+			t.Errorf("unexpected parameters: %+v", req.Parameters)
+		}
+		if len(req.Parameters.Stop) != 2 {
+			t.Errorf("stop = %v, want config and request stop sequences", req.Parameters.Stop)
+		}
+
+		_ = json.NewEncoder(w).Encode(generateResponse{
+			GeneratedText: "Hi there!END",
+			Details: &details{
+				FinishReason:    "stop_sequence",
+				GeneratedTokens: 4,
+				Prefill:         make([]token, 6),
+			},
+		})
+	})
+
+	var responses []*model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), testRequest(), false) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 1 {
+		t.Fatalf("got %d responses, want 1", len(responses))
+	}
+	resp := responses[0]
+	if got := resp.Content.Parts[0].Text; got != "Hi there!" {
+		t.Errorf("text = %q, want stop sequence trimmed", got)
+	}
+	if resp.FinishReason != genai.FinishReasonStop {
+		t.Errorf("FinishReason = %v, want STOP", resp.FinishReason)
+	}
+	if resp.UsageMetadata == nil || resp.UsageMetadata.PromptTokenCount != 6 || resp.UsageMetadata.TotalTokenCount != 10 {
+		t.Errorf("UsageMetadata = %+v, want 6 prompt / 10 total tokens", resp.UsageMetadata)
+	}
+}
+
+func TestGenerateStream(t *testing.T) {
+	m := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/generate_stream" {
+			t.Errorf("path = %q, want /generate_stream", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, text := range []string{"Hel", "lo"} {
+			fmt.Fprintf(w, "data:{\"token\":{\"id\":1,\"text\":%q,\"special\":false},\"generated_text\":null,\"details\":null}\n\n", text)
+		}
+		fmt.Fprint(w, "data:{\"token\":{\"id\":2,\"text\":\"</s>\",\"special\":true},\"generated_text\":\"Hello\",\"details\":{\"finish_reason\":\"length\",\"generated_tokens\":3}}\n\n")
+	})
+
+	var partials []string
+	var final *model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), testRequest(), true) {
+		if err != nil {
+			t.Fatalf("GenerateContent() error = %v", err)
+		}
+		if resp.Partial {
+			partials = append(partials, resp.Content.Parts[0].Text)
+			continue
+		}
+		final = resp
+	}
+
+	if strings.Join(partials, "|") != "Hel|lo" {
+		t.Errorf("partials = %v, want [Hel lo]", partials)
+	}
+	if final == nil || !final.TurnComplete {
+		t.Fatal("missing final turn-complete response")
+	}
+	if final.Content.Parts[0].Text != "Hello" || final.FinishReason != genai.FinishReasonMaxTokens {
+		t.Errorf("final = %q/%v, want Hello/MAX_TOKENS", final.Content.Parts[0].Text, final.FinishReason)
+	}
+	if final.UsageMetadata == nil || final.UsageMetadata.CandidatesTokenCount != 3 {
+		t.Errorf("UsageMetadata = %+v, want 3 generated tokens", final.UsageMetadata)
+	}
+}
+
+func TestGenerate_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  bool
+		handler http.HandlerFunc
+	}{
+		{
+			name:   "http error status",
+			stream: false,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "model overloaded", http.StatusServiceUnavailable)
+			},
+		},
+		{
+			name:   "stream error event",
+			stream: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "data:{\"error\":\"Input validation error\",\"error_type\":\"validation\"}\n\n")
+			},
+		},
+		{
+			name:   "stream ends early",
+			stream: true,
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprint(w, "data:{\"token\":{\"id\":1,\"text\":\"Hi\",\"special\":false}}\n\n")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestModel(t, tt.handler)
+
+			var gotErr error
+			for _, err := range m.GenerateContent(context.Background(), testRequest(), tt.stream) {
+				if err != nil {
+					gotErr = err
+				}
+			}
+			if gotErr == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestChatMLPrompt(t *testing.T) {
+	got := ChatMLPrompt("sys", []*genai.Content{
+		genai.NewContentFromText("hi", genai.RoleUser),
+		genai.NewContentFromText("hello", genai.RoleModel),
+		nil,
+	})
+	want := "<|im_start|>system\nsys<|im_end|>\n" +
+		"<|im_start|>user\nhi<|im_end|>\n" +
+		"<|im_start|>assistant\nhello<|im_end|>\n" +
+		"<|im_start|>assistant\n"
+	if got != want {
+		t.Errorf("ChatMLPrompt() = %q, want %q", got, want)
+	}
+}
+
+func TestCapabilities(t *testing.T) {
+	m := newTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/info" {
+			t.Errorf("path = %q, want /info", r.URL.Path)
+		}
+		_ = json.NewEncoder(w).Encode(infoResponse{ModelID: "mistral", MaxInputTokens: 4095, MaxTotalTokens: 4096})
+	})
+
+	caps, err := m.Capabilities(context.Background())
+	if err != nil {
+		t.Fatalf("Capabilities() error = %v", err)
+	}
+	if caps.SupportsTools || !caps.SupportsJSONMode || caps.ContextWindow != 4096 {
+		t.Errorf("Capabilities() = %+v, want no tools, JSON mode, 4096 context", caps)
+	}
+}
+
+func TestGenerate_Grammar(t *testing.T) {
+	var got generateRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_ = json.NewEncoder(w).Encode(generateResponse{GeneratedText: `{"ok":true}`})
+	}))
+	defer srv.Close()
+
+	m, err := NewModel(context.Background(), &Config{
+		ModelName:  "tgi-test",
+		BaseURL:    srv.URL,
+		Parameters: Parameters{Grammar: &Grammar{Type: "regex", Value: "(yes|no)"}},
+	})
+	if err != nil {
+		t.Fatalf("NewModel() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *genai.GenerateContentConfig
+		wantType string
+	}{
+		{name: "configured grammar", config: nil, wantType: "regex"},
+		{name: "response schema overrides", config: &genai.GenerateContentConfig{
+			ResponseSchema: &genai.Schema{Type: genai.TypeObject},
+		}, wantType: "json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := &model.LLMRequest{
+				Contents: []*genai.Content{genai.NewContentFromText("ok?", genai.RoleUser)},
+				Config:   tt.config,
+			}
+			for _, err := range m.GenerateContent(context.Background(), req, false) {
+				if err != nil {
+					t.Fatalf("GenerateContent() error = %v", err)
+				}
+			}
+			if got.Parameters.Grammar == nil || got.Parameters.Grammar.Type != tt.wantType {
+				t.Errorf("grammar = %+v, want type %q", got.Parameters.Grammar, tt.wantType)
+			}
+		})
+	}
+}
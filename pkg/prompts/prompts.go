@@ -0,0 +1,122 @@
+// Package prompts provides the instruction templates of the pipeline stages. Each
+// template is a Go text/template named after its stage agent, embedded in the binary
+// and overridable by a <name>.md file in a prompt directory, so prompts can be iterated
+// on without a rebuild.
+package prompts
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// DefaultCoverageTarget is the test coverage target in percent used when none is configured
+const DefaultCoverageTarget = 85
+
+// ErrNotFound is returned for a prompt that is neither in the prompt directory nor embedded
+var ErrNotFound = errors.New("prompt not found")
+
+//go:embed defaults/*.md
+var defaults embed.FS
+
+// Vars are the variables available to prompt templates
+type Vars struct {
+	// Language is the programming language of the generated code
+	Language string
+	// CoverageTarget is the test coverage target in percent
+	CoverageTarget float64
+}
+
+// DefaultVars returns the variables of the built-in pipeline: Go code with the default coverage target
+func DefaultVars() Vars {
+	return Vars{Language: "Go", CoverageTarget: DefaultCoverageTarget}
+}
+
+// Library loads prompt templates from a directory, falling back to the embedded defaults
+type Library struct {
+	dir string
+}
+
+// New creates a library that reads <name>.md templates from dir before the embedded
+// defaults. An empty dir uses only the embedded defaults. Templates are read on every
+// Render, so edits apply to the next pipeline built.
+func New(dir string) *Library {
+	return &Library{dir: dir}
+}
+
+// Has reports whether the library has a template for name
+func (l *Library) Has(name string) bool {
+	_, _, err := l.read(name)
+	return err == nil
+}
+
+// Render renders the template for name with vars
+func (l *Library) Render(name string, vars Vars) (string, error) {
+	text, source, err := l.read(name)
+	if err != nil {
+		return "", err
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse prompt %s: %w", source, err)
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s: %w", source, err)
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// Names returns the names of the embedded default templates, sorted
+func Names() []string {
+	entries, err := fs.ReadDir(defaults, "defaults")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, strings.TrimSuffix(entry.Name(), ".md"))
+	}
+	slices.Sort(names)
+	return names
+}
+
+// Default renders the embedded default template for name with DefaultVars. It panics
+// if the template is missing or invalid, so it is only for built-in stage names.
+func Default(name string) string {
+	text, err := New("").Render(name, DefaultVars())
+	if err != nil {
+		panic(err)
+	}
+	return text
+}
+
+// read returns the template text for name and where it was read from
+func (l *Library) read(name string) (text, source string, err error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", "", fmt.Errorf("invalid prompt name %q", name)
+	}
+	file := name + ".md"
+	if l.dir != "" {
+		path := filepath.Join(l.dir, file)
+		data, err := os.ReadFile(path)
+		if err == nil {
+			return string(data), path, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", fmt.Errorf("failed to read prompt %s: %w", path, err)
+		}
+	}
+	data, err := defaults.ReadFile("defaults/" + file)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrNotFound, name)
+	}
+	return string(data), "defaults/" + file, nil
+}
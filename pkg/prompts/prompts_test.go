@@ -0,0 +1,91 @@
+package prompts
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaults(t *testing.T) {
+	names := Names()
+	if len(names) == 0 {
+		t.Fatal("Names() is empty, want the embedded defaults")
+	}
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			text := Default(name)
+			if strings.TrimSpace(text) == "" || strings.Contains(text, "{{") || strings.HasSuffix(text, "\n") {
+				t.Errorf("Default(%q) = %q, want a rendered template without a trailing newline", name, text)
+			}
+		})
+	}
+}
+
+func TestLibrary_Render(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"DesignAgent.md":  "Design in {{.Language}} for {{.CoverageTarget}}% coverage. {design_feedback?}\n",
+		"LicenseAgent.md": "Add license headers.",
+		"BrokenAgent.md":  "{{.Language",
+		"UnknownAgent.md": "{{.Framework}}",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		dir         string
+		prompt      string
+		want        string
+		wantContain string
+		wantErr     string
+	}{
+		{name: "directory overrides default", dir: dir, prompt: "DesignAgent", want: "Design in Rust for 90% coverage. {design_feedback?}"},
+		{name: "directory only prompt", dir: dir, prompt: "LicenseAgent", want: "Add license headers."},
+		{name: "falls back to default", dir: dir, prompt: "TDDExpertAgent", wantContain: "You are a Rust Testing Expert. Write tests for code files. Target >90% coverage."},
+		{name: "defaults only", prompt: "DesignAgent", wantContain: "You are a Rust Software Architect."},
+		{name: "missing", dir: dir, prompt: "MissingAgent", wantErr: "prompt not found"},
+		{name: "invalid name", dir: dir, prompt: "../DesignAgent", wantErr: "invalid prompt name"},
+		{name: "parse error", dir: dir, prompt: "BrokenAgent", wantErr: "failed to parse prompt"},
+		{name: "unknown variable", dir: dir, prompt: "UnknownAgent", wantErr: "failed to render prompt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(tt.dir).Render(tt.prompt, Vars{Language: "Rust", CoverageTarget: 90})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Render() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if tt.want != "" && got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+			if !strings.Contains(got, tt.wantContain) {
+				t.Errorf("Render() = %q, want it to contain %q", got, tt.wantContain)
+			}
+		})
+	}
+}
+
+func TestLibrary_Has(t *testing.T) {
+	lib := New(t.TempDir())
+	if !lib.Has("DesignAgent") {
+		t.Error(`Has("DesignAgent") = false, want the embedded default`)
+	}
+	if lib.Has("MissingAgent") {
+		t.Error(`Has("MissingAgent") = true, want false`)
+	}
+	if _, err := lib.Render("MissingAgent", DefaultVars()); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Render() error = %v, want ErrNotFound", err)
+	}
+}
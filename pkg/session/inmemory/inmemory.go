@@ -0,0 +1,77 @@
+// Package inmemory keeps ADK sessions in memory like session.InMemoryService, with sessions
+// that the parallel branches of an invocation can read while the runner appends to them.
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/session"
+)
+
+// Service is a session.Service that keeps sessions in the ADK in-memory service. The
+// in-memory service of ADK v0.1.0 appends an event to the session the runner holds without
+// a lock, while the branches of a parallel agent read the events of that session, so
+// Service returns sessions whose events are read under a lock that AppendEvent holds. It is
+// safe for concurrent use.
+type Service struct {
+	session.Service
+}
+
+// New returns an empty in-memory session service
+func New() *Service {
+	return &Service{Service: session.InMemoryService()}
+}
+
+// Create implements session.Service
+func (s *Service) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	resp, err := s.Service.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Session = &lockedSession{Session: resp.Session}
+	return resp, nil
+}
+
+// Get implements session.Service
+func (s *Service) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	resp, err := s.Service.Get(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Session = &lockedSession{Session: resp.Session}
+	return resp, nil
+}
+
+// AppendEvent implements session.Service. Sessions of the in-memory service that Service did
+// not return, such as those of List, are passed on as they are.
+func (s *Service) AppendEvent(ctx context.Context, sess session.Session, event *session.Event) error {
+	locked, ok := sess.(*lockedSession)
+	if !ok {
+		return s.Service.AppendEvent(ctx, sess, event)
+	}
+	locked.mu.Lock()
+	defer locked.mu.Unlock()
+	return s.Service.AppendEvent(ctx, locked.Session, event)
+}
+
+// lockedSession is a session of the in-memory service whose events and update time are read
+// under mu, which AppendEvent holds while it changes them
+type lockedSession struct {
+	session.Session
+
+	mu sync.RWMutex
+}
+
+func (s *lockedSession) Events() session.Events {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Session.Events()
+}
+
+func (s *lockedSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.Session.LastUpdateTime()
+}
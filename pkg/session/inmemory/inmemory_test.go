@@ -0,0 +1,66 @@
+package inmemory
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+func TestService_ConcurrentReads(t *testing.T) {
+	ctx := context.Background()
+	s := New()
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	sess := created.Session
+
+	// Branches of a parallel agent read the events while the runner appends to the session
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Go(func() {
+			for range 100 {
+				for event := range sess.Events().All() {
+					_ = event.Author
+				}
+				_ = sess.LastUpdateTime()
+			}
+		})
+	}
+	for range 100 {
+		event := session.NewEvent("invocation")
+		event.Author = "agent"
+		event.LLMResponse.Content = genai.NewContentFromText("text", genai.RoleModel)
+		event.Actions.StateDelta = map[string]any{"key": "value"}
+		if err := s.AppendEvent(ctx, sess, event); err != nil {
+			t.Fatalf("AppendEvent() error = %v", err)
+		}
+	}
+	wg.Wait()
+
+	if got := sess.Events().Len(); got != 100 {
+		t.Errorf("session has %d events, want 100", got)
+	}
+	got, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "user", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if n := got.Session.Events().Len(); n != 100 {
+		t.Errorf("stored session has %d events, want 100", n)
+	}
+	if value, err := got.Session.State().Get("key"); err != nil || value != "value" {
+		t.Errorf("state key = %v, %v, want value", value, err)
+	}
+
+	// Sessions the service did not wrap, such as those of List, are appended as they are
+	listed, err := s.List(ctx, &session.ListRequest{AppName: "app", UserID: "user"})
+	if err != nil || len(listed.Sessions) != 1 {
+		t.Fatalf("List() = %v, %v, want one session", listed, err)
+	}
+	if err := s.AppendEvent(ctx, listed.Sessions[0], session.NewEvent("invocation")); err != nil {
+		t.Errorf("AppendEvent() of a listed session error = %v", err)
+	}
+}
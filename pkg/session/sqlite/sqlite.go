@@ -0,0 +1,563 @@
+// Package sqlite stores ADK sessions in a SQLite database, so conversations and their
+// state survive server restarts.
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"maps"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/adk/session"
+
+	// Registers the pure Go sqlite driver with database/sql
+	_ "modernc.org/sqlite"
+)
+
+// schema creates the tables of the session database. States and events are stored as JSON.
+const schema = `
+CREATE TABLE IF NOT EXISTS sessions (
+	app_name    TEXT NOT NULL,
+	user_id     TEXT NOT NULL,
+	id          TEXT NOT NULL,
+	state       TEXT NOT NULL,
+	update_time INTEGER NOT NULL,
+	PRIMARY KEY (app_name, user_id, id)
+);
+CREATE TABLE IF NOT EXISTS events (
+	seq         INTEGER PRIMARY KEY AUTOINCREMENT,
+	app_name    TEXT NOT NULL,
+	user_id     TEXT NOT NULL,
+	session_id  TEXT NOT NULL,
+	timestamp   INTEGER NOT NULL,
+	event       TEXT NOT NULL,
+	FOREIGN KEY (app_name, user_id, session_id) REFERENCES sessions (app_name, user_id, id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS events_session ON events (app_name, user_id, session_id, seq);
+CREATE TABLE IF NOT EXISTS app_states (
+	app_name TEXT PRIMARY KEY,
+	state    TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS user_states (
+	app_name TEXT NOT NULL,
+	user_id  TEXT NOT NULL,
+	state    TEXT NOT NULL,
+	PRIMARY KEY (app_name, user_id)
+);
+`
+
+// Service is a session.Service that stores sessions, their events, and the app and user
+// states in a SQLite database. It is safe for concurrent use.
+type Service struct {
+	db *sql.DB
+}
+
+// New opens the SQLite database at path, creating it and its tables if needed
+func New(path string) (*Service, error) {
+	// Paths with characters such as ? or # are escaped, so they are not read as URI parameters
+	dsn := url.URL{
+		Scheme:   "file",
+		OmitHost: true,
+		Path:     path,
+		RawQuery: url.Values{"_pragma": {"busy_timeout(5000)", "journal_mode(WAL)", "foreign_keys(1)"}}.Encode(),
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session database %s: %w", path, err)
+	}
+	// SQLite allows one writer at a time, so one connection avoids busy errors between them
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create session tables in %s: %w", path, err)
+	}
+	return &Service{db: db}, nil
+}
+
+// Close closes the database
+func (s *Service) Close() error {
+	return s.db.Close()
+}
+
+// Create implements session.Service
+func (s *Service) Create(ctx context.Context, req *session.CreateRequest) (*session.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("app_name and user_id are required, got app_name: %q, user_id: %q", req.AppName, req.UserID)
+	}
+	id := req.SessionID
+	if id == "" {
+		id = uuid.NewString()
+	}
+	appDelta, userDelta, sessionState := splitState(req.State)
+	now := time.Now()
+
+	var created *storedSession
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var exists bool
+		if err := tx.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM sessions WHERE app_name = ? AND user_id = ? AND id = ?)`,
+			req.AppName, req.UserID, id).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("session %s already exists", id)
+		}
+		state, err := marshalState(sessionState)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO sessions (app_name, user_id, id, state, update_time) VALUES (?, ?, ?, ?, ?)`,
+			req.AppName, req.UserID, id, state, now.UnixNano()); err != nil {
+			return err
+		}
+		appState, err := updateState(ctx, tx, `app_states`, appDelta, req.AppName)
+		if err != nil {
+			return err
+		}
+		userState, err := updateState(ctx, tx, `user_states`, userDelta, req.AppName, req.UserID)
+		if err != nil {
+			return err
+		}
+		created = newStoredSession(req.AppName, req.UserID, id, mergeStates(appState, userState, sessionState), nil, now)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return &session.CreateResponse{Session: created}, nil
+}
+
+// Get implements session.Service
+func (s *Service) Get(ctx context.Context, req *session.GetRequest) (*session.GetResponse, error) {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return nil, fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+	var got *storedSession
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		var state string
+		var updated int64
+		err := tx.QueryRowContext(ctx, `SELECT state, update_time FROM sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+			req.AppName, req.UserID, req.SessionID).Scan(&state, &updated)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("session %s not found", req.SessionID)
+		}
+		if err != nil {
+			return err
+		}
+		merged, err := s.mergedState(ctx, tx, req.AppName, req.UserID, state)
+		if err != nil {
+			return err
+		}
+		events, err := loadEvents(ctx, tx, req)
+		if err != nil {
+			return err
+		}
+		got = newStoredSession(req.AppName, req.UserID, req.SessionID, merged, events, time.Unix(0, updated))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	return &session.GetResponse{Session: got}, nil
+}
+
+// List implements session.Service. The sessions are listed without their events.
+func (s *Service) List(ctx context.Context, req *session.ListRequest) (*session.ListResponse, error) {
+	if req.AppName == "" {
+		return nil, fmt.Errorf("app_name is required, got app_name: %q", req.AppName)
+	}
+	sessions := make([]session.Session, 0)
+	err := s.inTx(ctx, func(tx *sql.Tx) error {
+		query := `SELECT user_id, id, state, update_time FROM sessions WHERE app_name = ?`
+		args := []any{req.AppName}
+		if req.UserID != "" {
+			query += ` AND user_id = ?`
+			args = append(args, req.UserID)
+		}
+		rows, err := tx.QueryContext(ctx, query+` ORDER BY user_id, id`, args...)
+		if err != nil {
+			return err
+		}
+		type row struct {
+			userID, id, state string
+			updated           int64
+		}
+		var listed []row
+		for rows.Next() {
+			var r row
+			if err := rows.Scan(&r.userID, &r.id, &r.state, &r.updated); err != nil {
+				rows.Close()
+				return err
+			}
+			listed = append(listed, r)
+		}
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		for _, r := range listed {
+			merged, err := s.mergedState(ctx, tx, req.AppName, r.userID, r.state)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, newStoredSession(req.AppName, r.userID, r.id, merged, nil, time.Unix(0, r.updated)))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return &session.ListResponse{Sessions: sessions}, nil
+}
+
+// Delete implements session.Service. Deleting a session that does not exist is not an error.
+func (s *Service) Delete(ctx context.Context, req *session.DeleteRequest) error {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return fmt.Errorf("app_name, user_id, session_id are required, got app_name: %q, user_id: %q, session_id: %q", req.AppName, req.UserID, req.SessionID)
+	}
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+		req.AppName, req.UserID, req.SessionID); err != nil {
+		return fmt.Errorf("failed to delete session: %w", err)
+	}
+	return nil
+}
+
+// AppendEvent implements session.Service. It stores the event and its state delta, without
+// the temporary keys, and applies the delta to sess. Partial events are not stored.
+func (s *Service) AppendEvent(ctx context.Context, sess session.Session, event *session.Event) error {
+	if sess == nil {
+		return fmt.Errorf("session is nil")
+	}
+	if event == nil {
+		return fmt.Errorf("event is nil")
+	}
+	if event.Partial {
+		return nil
+	}
+	stored, ok := sess.(*storedSession)
+	if !ok {
+		return fmt.Errorf("unexpected session type %T", sess)
+	}
+
+	// Temporary keys last only for the invocation, so they are neither stored nor kept
+	if len(event.Actions.StateDelta) > 0 {
+		delta := make(map[string]any, len(event.Actions.StateDelta))
+		for key, value := range event.Actions.StateDelta {
+			if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+				delta[key] = value
+			}
+		}
+		event.Actions.StateDelta = delta
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	appDelta, userDelta, sessionDelta := splitState(event.Actions.StateDelta)
+
+	err = s.inTx(ctx, func(tx *sql.Tx) error {
+		var state string
+		err := tx.QueryRowContext(ctx, `SELECT state FROM sessions WHERE app_name = ? AND user_id = ? AND id = ?`,
+			stored.appName, stored.userID, stored.id).Scan(&state)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("session %s not found, cannot apply event", stored.id)
+		}
+		if err != nil {
+			return err
+		}
+		if len(sessionDelta) > 0 {
+			merged, err := unmarshalState(state)
+			if err != nil {
+				return err
+			}
+			maps.Copy(merged, sessionDelta)
+			if state, err = marshalState(merged); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET state = ?, update_time = ? WHERE app_name = ? AND user_id = ? AND id = ?`,
+			state, event.Timestamp.UnixNano(), stored.appName, stored.userID, stored.id); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, `INSERT INTO events (app_name, user_id, session_id, timestamp, event) VALUES (?, ?, ?, ?, ?)`,
+			stored.appName, stored.userID, stored.id, event.Timestamp.UnixNano(), string(data)); err != nil {
+			return err
+		}
+		if _, err := updateState(ctx, tx, `app_states`, appDelta, stored.appName); err != nil {
+			return err
+		}
+		_, err = updateState(ctx, tx, `user_states`, userDelta, stored.appName, stored.userID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to append event: %w", err)
+	}
+	stored.appendEvent(event)
+	return nil
+}
+
+// inTx runs fn in a transaction, which is committed when fn returns nil
+func (s *Service) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// mergedState returns the session state with the app and user states under their prefixes
+func (s *Service) mergedState(ctx context.Context, tx *sql.Tx, appName, userID, sessionState string) (map[string]any, error) {
+	state, err := unmarshalState(sessionState)
+	if err != nil {
+		return nil, err
+	}
+	appState, err := updateState(ctx, tx, `app_states`, nil, appName)
+	if err != nil {
+		return nil, err
+	}
+	userState, err := updateState(ctx, tx, `user_states`, nil, appName, userID)
+	if err != nil {
+		return nil, err
+	}
+	return mergeStates(appState, userState, state), nil
+}
+
+// updateState merges delta into the app or user state in table, keyed by keys, and returns
+// the state; an empty delta only reads it
+func updateState(ctx context.Context, tx *sql.Tx, table string, delta map[string]any, keys ...any) (map[string]any, error) {
+	where := `app_name = ?`
+	columns := `app_name`
+	if len(keys) == 2 {
+		where += ` AND user_id = ?`
+		columns += `, user_id`
+	}
+	var data string
+	err := tx.QueryRowContext(ctx, `SELECT state FROM `+table+` WHERE `+where, keys...).Scan(&data)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	state, err := unmarshalState(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(delta) == 0 {
+		return state, nil
+	}
+	maps.Copy(state, delta)
+	if data, err = marshalState(state); err != nil {
+		return nil, err
+	}
+	placeholders := strings.Repeat(`?, `, len(keys)) + `?`
+	if _, err := tx.ExecContext(ctx, `INSERT INTO `+table+` (`+columns+`, state) VALUES (`+placeholders+`)
+		ON CONFLICT DO UPDATE SET state = excluded.state`, append(keys, data)...); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// loadEvents returns the events of the session of req, filtered as req asks, in order
+func loadEvents(ctx context.Context, tx *sql.Tx, req *session.GetRequest) ([]*session.Event, error) {
+	query := `SELECT seq, event FROM events WHERE app_name = ? AND user_id = ? AND session_id = ?`
+	args := []any{req.AppName, req.UserID, req.SessionID}
+	if !req.After.IsZero() {
+		query += ` AND timestamp >= ?`
+		args = append(args, req.After.UnixNano())
+	}
+	if req.NumRecentEvents > 0 {
+		// The most recent events, still in the order they were appended
+		query += ` ORDER BY seq DESC LIMIT ?`
+		args = append(args, req.NumRecentEvents)
+	}
+	query = `SELECT event FROM (` + query + `) ORDER BY seq`
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*session.Event
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var event session.Event
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return nil, fmt.Errorf("failed to decode event: %w", err)
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
+
+// splitState splits state into the app, user, and session states, without the prefixes of
+// the app and user keys and without the temporary keys
+func splitState(state map[string]any) (app, user, sess map[string]any) {
+	app, user, sess = make(map[string]any), make(map[string]any), make(map[string]any)
+	for key, value := range state {
+		if k, ok := strings.CutPrefix(key, session.KeyPrefixApp); ok {
+			app[k] = value
+		} else if k, ok := strings.CutPrefix(key, session.KeyPrefixUser); ok {
+			user[k] = value
+		} else if !strings.HasPrefix(key, session.KeyPrefixTemp) {
+			sess[key] = value
+		}
+	}
+	return app, user, sess
+}
+
+// mergeStates returns the session state with the app and user states under their prefixes
+func mergeStates(app, user, sess map[string]any) map[string]any {
+	merged := maps.Clone(sess)
+	if merged == nil {
+		merged = make(map[string]any)
+	}
+	for key, value := range app {
+		merged[session.KeyPrefixApp+key] = value
+	}
+	for key, value := range user {
+		merged[session.KeyPrefixUser+key] = value
+	}
+	return merged
+}
+
+// marshalState encodes state as JSON
+func marshalState(state map[string]any) (string, error) {
+	if state == nil {
+		state = map[string]any{}
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode state: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalState decodes a JSON state; an empty string is an empty state
+func unmarshalState(data string) (map[string]any, error) {
+	state := make(map[string]any)
+	if data == "" {
+		return state, nil
+	}
+	if err := json.Unmarshal([]byte(data), &state); err != nil {
+		return nil, fmt.Errorf("failed to decode state: %w", err)
+	}
+	return state, nil
+}
+
+// storedSession is a session read from the database
+type storedSession struct {
+	appName, userID, id string
+
+	mu        sync.RWMutex
+	state     map[string]any
+	events    []*session.Event
+	updatedAt time.Time
+}
+
+// newStoredSession returns a session with its merged state and events
+func newStoredSession(appName, userID, id string, state map[string]any, events []*session.Event, updatedAt time.Time) *storedSession {
+	return &storedSession{appName: appName, userID: userID, id: id, state: state, events: events, updatedAt: updatedAt}
+}
+
+func (s *storedSession) ID() string      { return s.id }
+func (s *storedSession) AppName() string { return s.appName }
+func (s *storedSession) UserID() string  { return s.userID }
+
+func (s *storedSession) State() session.State {
+	return &state{mu: &s.mu, values: s.state}
+}
+
+func (s *storedSession) Events() session.Events {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return events(s.events)
+}
+
+func (s *storedSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// appendEvent applies a stored event to the session
+func (s *storedSession) appendEvent(event *session.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.Copy(s.state, event.Actions.StateDelta)
+	s.events = append(s.events, event)
+	s.updatedAt = event.Timestamp
+}
+
+// events is the session.Events of a stored session
+type events []*session.Event
+
+func (e events) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, event := range e {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+func (e events) Len() int {
+	return len(e)
+}
+
+func (e events) At(i int) *session.Event {
+	if i >= 0 && i < len(e) {
+		return e[i]
+	}
+	return nil
+}
+
+// state is the session.State of a stored session. Values set by agents are stored with
+// the state delta of their event.
+type state struct {
+	mu     *sync.RWMutex
+	values map[string]any
+}
+
+func (s *state) Get(key string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.values[key]
+	if !ok {
+		return nil, session.ErrStateKeyNotExist
+	}
+	return value, nil
+}
+
+func (s *state) Set(key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *state) All() iter.Seq2[string, any] {
+	return func(yield func(string, any) bool) {
+		s.mu.RLock()
+		values := maps.Clone(s.values)
+		s.mu.RUnlock()
+		for key, value := range values {
+			if !yield(key, value) {
+				return
+			}
+		}
+	}
+}
+
+var _ session.Service = (*Service)(nil)
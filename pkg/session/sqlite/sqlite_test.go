@@ -0,0 +1,257 @@
+package sqlite
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// newService opens a service on a database in a temporary directory and returns its path
+func newService(t *testing.T) (*Service, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "sessions.db")
+	s, err := New(path)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s, path
+}
+
+// appendText appends a text event by author with stateDelta to sess
+func appendText(t *testing.T, s *Service, sess session.Session, author, text string, stateDelta map[string]any) {
+	t.Helper()
+	event := session.NewEvent("invocation")
+	event.Author = author
+	event.LLMResponse.Content = genai.NewContentFromText(text, genai.RoleModel)
+	event.Actions.StateDelta = stateDelta
+	if err := s.AppendEvent(context.Background(), sess, event); err != nil {
+		t.Fatalf("AppendEvent() error = %v", err)
+	}
+}
+
+func TestService_Persistence(t *testing.T) {
+	ctx := context.Background()
+	s, path := newService(t)
+	created, err := s.Create(ctx, &session.CreateRequest{
+		AppName:   "CodePipelineAgent",
+		UserID:    "user",
+		SessionID: "s1",
+		State:     map[string]any{"task": "calculator", "app:model": "gemini", "user:name": "dev", "temp:scratch": "x"},
+	})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	appendText(t, s, created.Session, "CodeWriterAgent", "Created calc.go", map[string]any{"generated_code": "package calc", "temp:draft": "y"})
+	appendText(t, s, created.Session, "CodeReviewerAgent", "Approved", map[string]any{"review_comments": "Approved", "user:name": "reviewer"})
+
+	if got, err := created.Session.State().Get("review_comments"); err != nil || got != "Approved" {
+		t.Errorf("created session review_comments = %v, %v; want the appended delta", got, err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// A new service on the same database sees everything the first one stored
+	reopened, err := New(path)
+	if err != nil {
+		t.Fatalf("New() on reopen error = %v", err)
+	}
+	defer reopened.Close()
+	got, err := reopened.Get(ctx, &session.GetRequest{AppName: "CodePipelineAgent", UserID: "user", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	wantState := map[string]any{
+		"task":            "calculator",
+		"generated_code":  "package calc",
+		"review_comments": "Approved",
+		"app:model":       "gemini",
+		"user:name":       "reviewer",
+	}
+	state := make(map[string]any)
+	for key, value := range got.Session.State().All() {
+		state[key] = value
+	}
+	if len(state) != len(wantState) {
+		t.Errorf("state = %v, want %v", state, wantState)
+	}
+	for key, want := range wantState {
+		if state[key] != want {
+			t.Errorf("state[%q] = %v, want %v", key, state[key], want)
+		}
+	}
+
+	events := got.Session.Events()
+	if events.Len() != 2 {
+		t.Fatalf("events = %d, want 2", events.Len())
+	}
+	first := events.At(0)
+	if first.Author != "CodeWriterAgent" || first.Content.Parts[0].Text != "Created calc.go" {
+		t.Errorf("first event = %s %+v, want the CodeWriterAgent text", first.Author, first.Content)
+	}
+	if _, ok := first.Actions.StateDelta["temp:draft"]; ok {
+		t.Error("temporary state key stored with the event")
+	}
+	if events.At(1).Author != "CodeReviewerAgent" || events.At(2) != nil {
+		t.Errorf("events out of order: %s, %v", events.At(1).Author, events.At(2))
+	}
+	if got.Session.LastUpdateTime().IsZero() {
+		t.Error("LastUpdateTime() is zero")
+	}
+}
+
+func TestService_Get(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t)
+	created, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "s1"})
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	for _, text := range []string{"one", "two", "three"} {
+		appendText(t, s, created.Session, "agent", text, nil)
+	}
+	partial := session.NewEvent("invocation")
+	partial.Partial = true
+	if err := s.AppendEvent(ctx, created.Session, partial); err != nil {
+		t.Fatalf("AppendEvent() of a partial event error = %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		req     *session.GetRequest
+		want    []string
+		wantErr bool
+	}{
+		{name: "all events", req: &session.GetRequest{AppName: "app", UserID: "user", SessionID: "s1"}, want: []string{"one", "two", "three"}},
+		{name: "recent events", req: &session.GetRequest{AppName: "app", UserID: "user", SessionID: "s1", NumRecentEvents: 2}, want: []string{"two", "three"}},
+		{name: "events after", req: &session.GetRequest{AppName: "app", UserID: "user", SessionID: "s1", After: time.Now().Add(time.Hour)}},
+		{name: "unknown session", req: &session.GetRequest{AppName: "app", UserID: "user", SessionID: "missing"}, wantErr: true},
+		{name: "other user", req: &session.GetRequest{AppName: "app", UserID: "other", SessionID: "s1"}, wantErr: true},
+		{name: "missing session ID", req: &session.GetRequest{AppName: "app", UserID: "user"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.Get(ctx, tt.req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Get() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var texts []string
+			for event := range got.Session.Events().All() {
+				texts = append(texts, event.Content.Parts[0].Text)
+			}
+			if len(texts) != len(tt.want) {
+				t.Fatalf("events = %q, want %q", texts, tt.want)
+			}
+			for i := range texts {
+				if texts[i] != tt.want[i] {
+					t.Errorf("events = %q, want %q", texts, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestService_ListAndDelete(t *testing.T) {
+	ctx := context.Background()
+	s, _ := newService(t)
+	for _, req := range []*session.CreateRequest{
+		{AppName: "app", UserID: "alice", SessionID: "a1"},
+		{AppName: "app", UserID: "alice", SessionID: "a2", State: map[string]any{"app:shared": "yes"}},
+		{AppName: "app", UserID: "bob", SessionID: "b1"},
+		{AppName: "other", UserID: "alice", SessionID: "o1"},
+	} {
+		if _, err := s.Create(ctx, req); err != nil {
+			t.Fatalf("Create(%s) error = %v", req.SessionID, err)
+		}
+	}
+	if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "alice", SessionID: "a1"}); err == nil {
+		t.Error("Create() of an existing session succeeded")
+	}
+	generated, err := s.Create(ctx, &session.CreateRequest{AppName: "generated", UserID: "alice"})
+	if err != nil || generated.Session.ID() == "" {
+		t.Errorf("Create() without an ID = %v, %v; want a generated ID", generated, err)
+	}
+
+	tests := []struct {
+		name   string
+		req    *session.ListRequest
+		wantID []string
+	}{
+		{name: "app and user", req: &session.ListRequest{AppName: "app", UserID: "alice"}, wantID: []string{"a1", "a2"}},
+		{name: "all users of an app", req: &session.ListRequest{AppName: "app"}, wantID: []string{"a1", "a2", "b1"}},
+		{name: "unknown app", req: &session.ListRequest{AppName: "missing"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(ctx, tt.req)
+			if err != nil {
+				t.Fatalf("List() error = %v", err)
+			}
+			var ids []string
+			for _, sess := range got.Sessions {
+				ids = append(ids, sess.ID())
+				// The app state is shared by all the sessions of the app
+				if value, err := sess.State().Get("app:shared"); err != nil || value != "yes" {
+					t.Errorf("session %s app:shared = %v, %v; want yes", sess.ID(), value, err)
+				}
+			}
+			if len(ids) != len(tt.wantID) {
+				t.Fatalf("List() = %q, want %q", ids, tt.wantID)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantID[i] {
+					t.Errorf("List() = %q, want %q", ids, tt.wantID)
+				}
+			}
+		})
+	}
+
+	a1, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "alice", SessionID: "a1"})
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	appendText(t, s, a1.Session, "agent", "hello", nil)
+	if err := s.Delete(ctx, &session.DeleteRequest{AppName: "app", UserID: "alice", SessionID: "a1"}); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := s.Get(ctx, &session.GetRequest{AppName: "app", UserID: "alice", SessionID: "a1"}); err == nil {
+		t.Error("Get() of a deleted session succeeded")
+	}
+	var events int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM events`).Scan(&events); err != nil || events != 0 {
+		t.Errorf("events left after Delete() = %d, %v; want 0", events, err)
+	}
+	event := session.NewEvent("invocation")
+	if err := s.AppendEvent(ctx, a1.Session, event); err == nil {
+		t.Error("AppendEvent() to a deleted session succeeded")
+	}
+}
+
+func TestNew_Path(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"sessions.db", "my sessions?v=1#2.db", "100%.db"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			s, err := New(path)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if _, err := s.Create(ctx, &session.CreateRequest{AppName: "app", UserID: "user", SessionID: "s1"}); err != nil {
+				t.Fatalf("Create() error = %v", err)
+			}
+			s.Close()
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("database is not at %s: %v", path, err)
+			}
+		})
+	}
+}
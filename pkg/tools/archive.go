@@ -0,0 +1,374 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"cmp"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Formats of the archive tool
+const (
+	// ArchiveZip is a zip file
+	ArchiveZip = "zip"
+	// ArchiveTarGz is a gzip-compressed tar file
+	ArchiveTarGz = "tar.gz"
+)
+
+// MaxArchiveSize is the maximum total size of the files packed into an archive (100MB)
+const MaxArchiveSize = 100 * 1024 * 1024
+
+// ArchiveInput defines the input parameters for the archive tool
+type ArchiveInput struct {
+	// Path is the relative path of the directory to pack (defaults to the workspace root)
+	Path string `json:"path,omitempty"`
+	// Output is the relative path of the archive to create, such as dist/project.zip
+	// (defaults to workspace.zip or workspace.tar.gz)
+	Output string `json:"output,omitempty"`
+	// Format is zip or tar.gz (defaults to the extension of Output, then zip)
+	Format string `json:"format,omitempty"`
+}
+
+// ArchiveOutput defines the output structure for the archive tool
+type ArchiveOutput struct {
+	// Path is the relative path of the created archive
+	Path string `json:"path,omitempty"`
+	// Format is the format of the archive
+	Format string `json:"format,omitempty"`
+	// Files is the number of files in the archive
+	Files int `json:"files"`
+	// Size is the size of the archive in bytes
+	Size int64 `json:"size"`
+	// Success indicates whether the archive was created
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// archiveFormat returns the format of an archive from format or, when it is empty, from
+// the extension of output
+func archiveFormat(format, output string) (string, error) {
+	switch format {
+	case ArchiveZip, ArchiveTarGz:
+		return format, nil
+	case "tgz":
+		return ArchiveTarGz, nil
+	case "":
+	default:
+		return "", fmt.Errorf("unknown format %q: use %s or %s", format, ArchiveZip, ArchiveTarGz)
+	}
+	if strings.HasSuffix(output, ".tar.gz") || strings.HasSuffix(output, ".tgz") {
+		return ArchiveTarGz, nil
+	}
+	return ArchiveZip, nil
+}
+
+// CreateArchive packs a directory of the workspace into an archive file within it. The
+// .git directory, symlinks, and the archive itself are left out.
+func CreateArchive(ctx context.Context, workspaceDir string, input ArchiveInput) (*ArchiveOutput, error) {
+	start := time.Now()
+	slog.Info("Starting archive operation",
+		"path", input.Path,
+		"output", input.Output,
+		"format", input.Format,
+		"workspace", workspaceDir)
+
+	format, err := archiveFormat(input.Format, input.Output)
+	if err != nil {
+		return nil, err
+	}
+	output := cmp.Or(input.Output, "workspace."+format)
+	resolvedDir, err := resolveWorkspacePath(workspaceDir, cmp.Or(input.Path, "."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	resolvedOutput, err := resolveWorkspacePath(workspaceDir, output)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve output: %w", err)
+	}
+	if info, err := os.Stat(resolvedDir); err != nil {
+		return nil, fmt.Errorf("failed to archive %s: %w", cmp.Or(input.Path, "."), err)
+	} else if !info.IsDir() {
+		return nil, fmt.Errorf("failed to archive %s: not a directory", cmp.Or(input.Path, "."))
+	}
+	if err := os.MkdirAll(filepath.Dir(resolvedOutput), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", output, err)
+	}
+
+	// Write to a temporary file next to the archive, so a failure leaves no partial archive
+	tmp, err := os.CreateTemp(filepath.Dir(resolvedOutput), "."+filepath.Base(resolvedOutput)+".tmp-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	skip := func(path string) bool { return path == resolvedOutput || path == tmp.Name() }
+	files, err := writeArchive(ctx, tmp, resolvedDir, format, "", skip, nil)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		slog.Error("Failed to create archive",
+			"path", input.Path,
+			"output", output,
+			"error", err)
+		return nil, fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	if err := os.Rename(tmp.Name(), resolvedOutput); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", output, err)
+	}
+	info, err := os.Stat(resolvedOutput)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat %s: %w", output, err)
+	}
+
+	slog.Info("Archive completed successfully",
+		"path", input.Path,
+		"output", output,
+		"files", files,
+		"size_bytes", info.Size(),
+		"duration_ms", time.Since(start).Milliseconds())
+	return &ArchiveOutput{Path: output, Format: format, Files: files, Size: info.Size(), Success: true}, nil
+}
+
+// WriteArchive writes an archive of dir in format to w, leaving out the .git directory and
+// symlinks, and returns the number of files written. It lets callers such as an HTTP
+// handler stream an archive without creating a file.
+func WriteArchive(ctx context.Context, w io.Writer, dir, format string) (int, error) {
+	format, err := archiveFormat(format, "")
+	if err != nil {
+		return 0, err
+	}
+	return writeArchive(ctx, w, dir, format, "", func(string) bool { return false }, nil)
+}
+
+// ArchiveEntry is a file added to an archive from memory
+type ArchiveEntry struct {
+	// Name is the slash-separated path of the file in the archive
+	Name string
+	// Content is the content of the file
+	Content []byte
+}
+
+// WriteExportArchive writes an archive in format to w with files, the slash-separated paths
+// of files of dir, under prefix, followed by entries, and returns the number of files
+// written. The files config denies or ignores are left out, as are those WriteArchive
+// leaves out. dir may be empty for an archive of entries only.
+func WriteExportArchive(ctx context.Context, w io.Writer, config Config, dir string, files []string, prefix, format string, entries []ArchiveEntry) (int, error) {
+	format, err := archiveFormat(format, "")
+	if err != nil {
+		return 0, err
+	}
+	var ignore ignoreMatcher
+	if dir != "" {
+		if ignore, err = loadIgnore(dir, config); err != nil {
+			return 0, err
+		}
+	}
+	include := make(map[string]bool, len(files))
+	for _, file := range files {
+		include[path.Clean(filepath.ToSlash(file))] = true
+	}
+	skip := func(p string) bool {
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return true
+		}
+		rel = filepath.ToSlash(rel)
+		return !include[rel] || config.denied(rel) || ignore.ignored(rel, false)
+	}
+	return writeArchive(ctx, w, dir, format, prefix, skip, entries)
+}
+
+// archiveWriter adds files to a zip or tar.gz archive
+type archiveWriter interface {
+	add(name string, info fs.FileInfo, r io.Reader) error
+	Close() error
+}
+
+// writeArchive writes the regular files under dir, except those skip reports, to w with
+// their names prefixed by prefix, followed by entries
+func writeArchive(ctx context.Context, w io.Writer, dir, format, prefix string, skip func(path string) bool, entries []ArchiveEntry) (int, error) {
+	var archive archiveWriter
+	if format == ArchiveTarGz {
+		archive = newTarGzWriter(w)
+	} else {
+		archive = &zipWriter{zip.NewWriter(w)}
+	}
+
+	files := 0
+	var total int64
+	walk := func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() || skip(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if total += info.Size(); total > MaxArchiveSize {
+			return fmt.Errorf("files exceed %d bytes; archive a subdirectory", MaxArchiveSize)
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := archive.add(prefix+filepath.ToSlash(rel), info, f); err != nil {
+			return fmt.Errorf("failed to add %s: %w", rel, err)
+		}
+		files++
+		return nil
+	}
+	var err error
+	if dir != "" {
+		err = filepath.WalkDir(dir, walk)
+	}
+	for _, entry := range entries {
+		if err != nil {
+			break
+		}
+		if total += int64(len(entry.Content)); total > MaxArchiveSize {
+			err = fmt.Errorf("files exceed %d bytes; archive a subdirectory", MaxArchiveSize)
+			break
+		}
+		info := entryInfo{name: path.Base(entry.Name), size: int64(len(entry.Content)), modTime: time.Now()}
+		if err = archive.add(entry.Name, info, bytes.NewReader(entry.Content)); err != nil {
+			err = fmt.Errorf("failed to add %s: %w", entry.Name, err)
+			break
+		}
+		files++
+	}
+	if closeErr := archive.Close(); err == nil {
+		err = closeErr
+	}
+	return files, err
+}
+
+// entryInfo is the file information of an ArchiveEntry
+type entryInfo struct {
+	name    string
+	size    int64
+	modTime time.Time
+}
+
+func (e entryInfo) Name() string       { return e.name }
+func (e entryInfo) Size() int64        { return e.size }
+func (e entryInfo) Mode() fs.FileMode  { return 0644 }
+func (e entryInfo) ModTime() time.Time { return e.modTime }
+func (e entryInfo) IsDir() bool        { return false }
+func (e entryInfo) Sys() any           { return nil }
+
+// zipWriter adds files to a zip archive
+type zipWriter struct {
+	*zip.Writer
+}
+
+func (z *zipWriter) add(name string, info fs.FileInfo, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := z.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
+
+// tarGzWriter adds files to a gzip-compressed tar archive
+type tarGzWriter struct {
+	gzip *gzip.Writer
+	tar  *tar.Writer
+}
+
+func newTarGzWriter(w io.Writer) *tarGzWriter {
+	gz := gzip.NewWriter(w)
+	return &tarGzWriter{gzip: gz, tar: tar.NewWriter(gz)}
+}
+
+func (t *tarGzWriter) add(name string, info fs.FileInfo, r io.Reader) error {
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	// The owner of the workspace files means nothing to whoever extracts the archive
+	header.Uid, header.Gid, header.Uname, header.Gname = 0, 0, "", ""
+	if err := t.tar.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(t.tar, r)
+	return err
+}
+
+func (t *tarGzWriter) Close() error {
+	if err := t.tar.Close(); err != nil {
+		return err
+	}
+	return t.gzip.Close()
+}
+
+// ArchiveTool creates a new archive tool that packs the workspace directory into a zip or tar.gz file
+func ArchiveTool() tool.Tool {
+	return NewArchiveToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewArchiveToolWithWorkspace creates a new archive tool with a custom workspace directory
+func NewArchiveToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "archive",
+			Description: "Pack the workspace, or a directory in it, into a zip or tar.gz file within the workspace, for example to export the project. The .git directory is left out. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input ArchiveInput) *ArchiveOutput {
+			observe := observeTool("archive")
+			output, err := CreateArchive(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &ArchiveOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create archive tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,242 @@
+package tools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zipContents returns the files in the zip archive at path by name
+func zipContents(t *testing.T, path string) map[string]string {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatalf("failed to open %s: %v", path, err)
+	}
+	defer r.Close()
+	files := make(map[string]string)
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed to open %s: %v", f.Name, err)
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", f.Name, err)
+		}
+		files[f.Name] = string(data)
+	}
+	return files
+}
+
+// tarGzContents returns the files in the tar.gz archive data by name
+func tarGzContents(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("failed to open gzip stream: %v", err)
+	}
+	r := tar.NewReader(gz)
+	files := make(map[string]string)
+	for {
+		header, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar header: %v", err)
+		}
+		content, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", header.Name, err)
+		}
+		files[header.Name] = string(content)
+	}
+	return files
+}
+
+func TestCreateArchive(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"go.mod":           "module example.com/calc\n",
+		"calc.go":          "package calc\n",
+		"cmd/calc/main.go": "package main\n",
+		".git/HEAD":        "ref: refs/heads/main\n",
+	})
+	want := map[string]string{
+		"go.mod":           "module example.com/calc\n",
+		"calc.go":          "package calc\n",
+		"cmd/calc/main.go": "package main\n",
+	}
+
+	output, err := CreateArchive(ctx, workspaceDir, ArchiveInput{})
+	if err != nil {
+		t.Fatalf("CreateArchive() error = %v", err)
+	}
+	if !output.Success || output.Path != "workspace.zip" || output.Format != ArchiveZip || output.Files != 3 || output.Size == 0 {
+		t.Errorf("CreateArchive() = %+v, want workspace.zip with 3 files", output)
+	}
+	if got := zipContents(t, filepath.Join(workspaceDir, "workspace.zip")); !reflect.DeepEqual(got, want) {
+		t.Errorf("archive = %v, want %v", got, want)
+	}
+
+	// The earlier archive is packed, but the new one is not
+	output, err = CreateArchive(ctx, workspaceDir, ArchiveInput{Output: "dist/calc.tgz"})
+	if err != nil {
+		t.Fatalf("CreateArchive() error = %v", err)
+	}
+	if output.Format != ArchiveTarGz || output.Files != 4 {
+		t.Errorf("CreateArchive() = %+v, want a tar.gz with 4 files", output)
+	}
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "dist", "calc.tgz"))
+	if err != nil {
+		t.Fatalf("failed to read archive: %v", err)
+	}
+	got := tarGzContents(t, data)
+	if _, ok := got["dist/calc.tgz"]; ok || got["calc.go"] != "package calc\n" {
+		t.Errorf("archive = %v, want the workspace without itself", got)
+	}
+
+	output, err = CreateArchive(ctx, workspaceDir, ArchiveInput{Path: "cmd", Output: "cmd.zip"})
+	if err != nil {
+		t.Fatalf("CreateArchive() error = %v", err)
+	}
+	if got := zipContents(t, filepath.Join(workspaceDir, "cmd.zip")); !reflect.DeepEqual(got, map[string]string{"calc/main.go": "package main\n"}) {
+		t.Errorf("archive of cmd = %v, want calc/main.go", got)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(workspaceDir, ".*.tmp-*")); len(matches) != 0 {
+		t.Errorf("temporary files left behind: %v", matches)
+	}
+}
+
+func TestCreateArchive_InvalidInput(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{"calc.go": "package calc\n"})
+
+	tests := []struct {
+		name        string
+		input       ArchiveInput
+		errContains string
+	}{
+		{name: "unknown format", input: ArchiveInput{Format: "rar"}, errContains: `unknown format "rar"`},
+		{name: "path traversal", input: ArchiveInput{Path: "../"}, errContains: "path traversal detected"},
+		{name: "output traversal", input: ArchiveInput{Output: "../export.zip"}, errContains: "path traversal detected"},
+		{name: "missing directory", input: ArchiveInput{Path: "missing"}, errContains: "failed to archive missing"},
+		{name: "file", input: ArchiveInput{Path: "calc.go"}, errContains: "not a directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CreateArchive(context.Background(), workspaceDir, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("CreateArchive() error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestWriteArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"calc.go": "package calc\n", ".git/HEAD": "ref: refs/heads/main\n"})
+
+	var buf bytes.Buffer
+	files, err := WriteArchive(context.Background(), &buf, dir, ArchiveTarGz)
+	if err != nil {
+		t.Fatalf("WriteArchive() error = %v", err)
+	}
+	if got := tarGzContents(t, buf.Bytes()); files != 1 || !reflect.DeepEqual(got, map[string]string{"calc.go": "package calc\n"}) {
+		t.Errorf("WriteArchive() = %d files %v, want calc.go", files, got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := WriteArchive(ctx, io.Discard, dir, ArchiveZip); !errors.Is(err, context.Canceled) {
+		t.Errorf("WriteArchive() with a canceled context error = %v, want context.Canceled", err)
+	}
+}
+
+func TestWriteExportArchive(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"calc.go":       "package calc\n",
+		"other.go":      "package calc\n",
+		".env":          "TOKEN=secret\n",
+		".gitignore":    "build/\n",
+		"build/calc":    "binary",
+		".git/HEAD":     "ref: refs/heads/main\n",
+		"secrets/key":   "key",
+		"pkg/util.go":   "package pkg\n",
+		"pkg/notes.txt": "notes",
+	})
+	entries := []ArchiveEntry{{Name: "transcript.md", Content: []byte("# Transcript\n")}}
+	config := Config{DeniedPaths: []string{".env", "secrets"}}
+
+	tests := []struct {
+		name  string
+		dir   string
+		paths []string
+		want  map[string]string
+		files int
+	}{
+		{
+			name:  "workspace and entries",
+			dir:   dir,
+			paths: []string{"calc.go", "./pkg/util.go"},
+			want:  map[string]string{"workspace/calc.go": "package calc\n", "workspace/pkg/util.go": "package pkg\n", "transcript.md": "# Transcript\n"},
+			files: 3,
+		},
+		{
+			name:  "denied and ignored files",
+			dir:   dir,
+			paths: []string{"calc.go", ".env", "build/calc", "secrets/key", ".git/HEAD"},
+			want:  map[string]string{"workspace/calc.go": "package calc\n", "transcript.md": "# Transcript\n"},
+			files: 2,
+		},
+		{
+			name:  "entries only",
+			want:  map[string]string{"transcript.md": "# Transcript\n"},
+			files: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := filepath.Join(t.TempDir(), "export.zip")
+			f, err := os.Create(out)
+			if err != nil {
+				t.Fatalf("failed to create %s: %v", out, err)
+			}
+			files, err := WriteExportArchive(context.Background(), f, config, tt.dir, tt.paths, "workspace/", ArchiveZip, entries)
+			f.Close()
+			if err != nil {
+				t.Fatalf("WriteExportArchive() error = %v", err)
+			}
+			if got := zipContents(t, out); files != tt.files || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("WriteExportArchive() = %d files %v, want %d files %v", files, got, tt.files, tt.want)
+			}
+		})
+	}
+
+	if _, err := WriteExportArchive(context.Background(), io.Discard, config, dir, nil, "", "rar", nil); err == nil {
+		t.Error("WriteExportArchive() with an unknown format error = nil")
+	}
+}
+
+func TestArchiveTool_ToolCreation(t *testing.T) {
+	if ArchiveTool() == nil {
+		t.Fatal("ArchiveTool() returned nil")
+	}
+	if got := NewArchiveToolWithWorkspace(t.TempDir()).Name(); got != "archive" {
+		t.Errorf("Name() = %q, want %q", got, "archive")
+	}
+}
@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// Original encodings of text files that fileRead converts to UTF-8, reported in
+// FileReadOutput.OriginalEncoding
+const (
+	// CharsetUTF8BOM is UTF-8 with a byte order mark, which is removed
+	CharsetUTF8BOM = "utf-8-bom"
+	// CharsetUTF16LE is little-endian UTF-16 with a byte order mark
+	CharsetUTF16LE = "utf-16le"
+	// CharsetUTF16BE is big-endian UTF-16 with a byte order mark
+	CharsetUTF16BE = "utf-16be"
+	// CharsetWindows1252 is assumed for text that is not valid UTF-8, the most common legacy
+	// encoding of seeded files; it is a superset of ISO-8859-1
+	CharsetWindows1252 = "windows-1252"
+)
+
+// bomCharset returns the charset and decoder of content starting with a byte order mark,
+// or an empty charset
+func bomCharset(head []byte) (string, *encoding.Decoder) {
+	switch {
+	case bytes.HasPrefix(head, []byte{0xef, 0xbb, 0xbf}):
+		return CharsetUTF8BOM, unicode.UTF8BOM.NewDecoder()
+	case bytes.HasPrefix(head, []byte{0xff, 0xfe}):
+		return CharsetUTF16LE, unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM).NewDecoder()
+	case bytes.HasPrefix(head, []byte{0xfe, 0xff}):
+		return CharsetUTF16BE, unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder()
+	}
+	return "", nil
+}
+
+// decodeText converts text content to UTF-8 and returns its original charset. UTF-8
+// content without a byte order mark, and binary content, which contains NUL bytes, are
+// returned unchanged with an empty charset.
+func decodeText(content []byte) ([]byte, string) {
+	charset, decoder := bomCharset(content)
+	if decoder == nil {
+		if utf8.Valid(content) || bytes.IndexByte(content, 0) >= 0 {
+			return content, ""
+		}
+		charset, decoder = CharsetWindows1252, charmap.Windows1252.NewDecoder()
+	}
+	decoded, err := decoder.Bytes(content)
+	if err != nil {
+		return content, ""
+	}
+	return decoded, charset
+}
@@ -0,0 +1,75 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     []byte
+		want        string
+		wantCharset string
+	}{
+		{"utf-8", []byte("héllo\n"), "héllo\n", ""},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhéllo\n"), "héllo\n", CharsetUTF8BOM},
+		{"utf-16le", []byte("\xff\xfeh\x00\xe9\x00\n\x00"), "hé\n", CharsetUTF16LE},
+		{"utf-16be", []byte("\xfe\xff\x00h\x00\xe9\x00\n"), "hé\n", CharsetUTF16BE},
+		{"windows-1252", []byte("caf\xe9 \x93quoted\x94\n"), "café “quoted”\n", CharsetWindows1252},
+		{"binary", []byte("\x00\x01\xe9\xff"), "\x00\x01\xe9\xff", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, charset := decodeText(tt.content)
+			if string(got) != tt.want || charset != tt.wantCharset {
+				t.Errorf("decodeText(%q) = %q, %q, want %q, %q", tt.content, got, charset, tt.want, tt.wantCharset)
+			}
+		})
+	}
+}
+
+func TestFileReadTool_Encodings(t *testing.T) {
+	workspaceDir := t.TempDir()
+	files := map[string][]byte{
+		"bom.txt":    []byte("\xef\xbb\xbfone\ntwo\n"),
+		"utf16.txt":  []byte("\xff\xfeo\x00n\x00e\x00\n\x00t\x00w\x00o\x00\n\x00"),
+		"latin1.txt": []byte("one\ntw\xf6\n"),
+		"utf8.txt":   []byte("one\ntwo\n"),
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(workspaceDir, name), content, 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		input        FileReadInput
+		wantContent  string
+		wantEncoding string
+	}{
+		{"bom", FileReadInput{Path: "bom.txt"}, "one\ntwo\n", CharsetUTF8BOM},
+		{"bom range", FileReadInput{Path: "bom.txt", StartLine: 1, EndLine: 1}, "one\n", CharsetUTF8BOM},
+		{"utf-16", FileReadInput{Path: "utf16.txt"}, "one\ntwo\n", CharsetUTF16LE},
+		{"utf-16 range", FileReadInput{Path: "utf16.txt", StartLine: 2}, "two\n", CharsetUTF16LE},
+		{"windows-1252", FileReadInput{Path: "latin1.txt"}, "one\ntwö\n", CharsetWindows1252},
+		{"windows-1252 range", FileReadInput{Path: "latin1.txt", StartLine: 2}, "twö\n", CharsetWindows1252},
+		{"utf-8", FileReadInput{Path: "utf8.txt"}, "one\ntwo\n", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeFileRead(workspaceDir, ToolConfig, tt.input)
+			if err != nil {
+				t.Fatalf("executeFileRead() error = %v", err)
+			}
+			if output.Content != tt.wantContent || output.OriginalEncoding != tt.wantEncoding {
+				t.Errorf("executeFileRead() = %q, %q, want %q, %q", output.Content, output.OriginalEncoding, tt.wantContent, tt.wantEncoding)
+			}
+			if output.Version != contentVersion(files[tt.input.Path]) {
+				t.Errorf("executeFileRead() version = %q, want the version of the original bytes", output.Version)
+			}
+		})
+	}
+}
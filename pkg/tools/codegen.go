@@ -0,0 +1,285 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Code generators run by the codegen tools; both must be listed in AllowedCommands
+const (
+	// ProtocCommand is the protocol buffer compiler, run with the protoc-gen-go plugins
+	ProtocCommand = "protoc"
+	// OapiCodegenCommand generates Go code from OpenAPI specifications
+	OapiCodegenCommand = "oapi-codegen"
+)
+
+// ProtocInput defines the input parameters for the protoc tool
+type ProtocInput struct {
+	// Files are the relative paths of the .proto files to compile
+	Files []string `json:"files"`
+	// ImportPaths are the relative directories searched for imports (defaults to the workspace root)
+	ImportPaths []string `json:"importPaths,omitempty"`
+	// OutDir is the relative directory of the generated Go files (defaults to the workspace root)
+	OutDir string `json:"outDir,omitempty"`
+	// GRPC also generates gRPC client and server stubs with protoc-gen-go-grpc
+	GRPC bool `json:"grpc,omitempty"`
+}
+
+// OapiCodegenInput defines the input parameters for the oapiCodegen tool
+type OapiCodegenInput struct {
+	// Spec is the relative path of the OpenAPI specification, in YAML or JSON
+	Spec string `json:"spec"`
+	// Output is the relative path of the Go file to generate
+	Output string `json:"output"`
+	// Package is the package name of the generated file (defaults to the name of its directory)
+	Package string `json:"package,omitempty"`
+	// Generate lists what to generate, such as types, client, std-http-server, or
+	// strict-server (defaults to the oapi-codegen defaults)
+	Generate []string `json:"generate,omitempty"`
+}
+
+// CodegenOutput defines the output structure for the codegen tools
+type CodegenOutput struct {
+	// Generated are the workspace files the generator created or changed, as slash-separated relative paths
+	Generated []string `json:"generated"`
+	// Output is the output of the generator, which explains failures
+	Output string `json:"output,omitempty"`
+	// ExitCode is the exit code of the generator
+	ExitCode int `json:"exitCode"`
+	// Success indicates whether the generator exited with code 0
+	Success bool `json:"success"`
+	// Error contains the error message if the generator could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// codegenPath checks that a path argument of a generator stays in the workspace and cannot
+// be taken for a flag, and returns it slash-separated
+func codegenPath(workspaceDir, p string) (string, error) {
+	if strings.HasPrefix(p, "-") {
+		return "", fmt.Errorf("invalid path %q", p)
+	}
+	if _, err := resolveWorkspacePath(workspaceDir, p); err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	return filepath.ToSlash(filepath.Clean(p)), nil
+}
+
+// RunProtoc compiles .proto files of the workspace into Go code. The command must be
+// listed in AllowedCommands. Compile errors are reported in the output, not returned.
+func RunProtoc(ctx context.Context, workspaceDir string, input ProtocInput) (*CodegenOutput, error) {
+	if len(input.Files) == 0 {
+		return nil, fmt.Errorf("files are required")
+	}
+	outDir, err := codegenPath(workspaceDir, cmp.Or(input.OutDir, "."))
+	if err != nil {
+		return nil, err
+	}
+	importPaths := input.ImportPaths
+	if len(importPaths) == 0 {
+		importPaths = []string{"."}
+	}
+
+	var args []string
+	for _, p := range importPaths {
+		p, err := codegenPath(workspaceDir, p)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, "--proto_path="+p)
+	}
+	args = append(args, "--go_out="+outDir, "--go_opt=paths=source_relative")
+	if input.GRPC {
+		args = append(args, "--go-grpc_out="+outDir, "--go-grpc_opt=paths=source_relative")
+	}
+	for _, file := range input.Files {
+		file, err := codegenPath(workspaceDir, file)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, file)
+	}
+	return runCodegen(ctx, workspaceDir, ProtocCommand, args)
+}
+
+// RunOapiCodegen generates Go code from an OpenAPI specification of the workspace. The
+// command must be listed in AllowedCommands. Generator errors are reported in the output,
+// not returned.
+func RunOapiCodegen(ctx context.Context, workspaceDir string, input OapiCodegenInput) (*CodegenOutput, error) {
+	if input.Spec == "" || input.Output == "" {
+		return nil, fmt.Errorf("spec and output are required")
+	}
+	spec, err := codegenPath(workspaceDir, input.Spec)
+	if err != nil {
+		return nil, err
+	}
+	output, err := codegenPath(workspaceDir, input.Output)
+	if err != nil {
+		return nil, err
+	}
+	pkg := input.Package
+	if pkg == "" {
+		pkg = path.Base(path.Dir(output))
+		if pkg == "." {
+			pkg = "api"
+		}
+	}
+	if strings.HasPrefix(pkg, "-") {
+		return nil, fmt.Errorf("invalid package %q", pkg)
+	}
+
+	args := []string{"-package", pkg, "-o", output}
+	if len(input.Generate) > 0 {
+		args = append(args, "-generate", strings.Join(input.Generate, ","))
+	}
+	args = append(args, spec)
+	return runCodegen(ctx, workspaceDir, OapiCodegenCommand, args)
+}
+
+// runCodegen runs a generator in the workspace root and reports the files it wrote
+func runCodegen(ctx context.Context, workspaceDir, command string, args []string) (*CodegenOutput, error) {
+	start := time.Now()
+	resolvedWorkspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace directory: %w", err)
+	}
+	before, err := fileStates(resolvedWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %w", err)
+	}
+
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{Command: command, Args: args})
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := fileStates(resolvedWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list workspace files: %w", err)
+	}
+	output := &CodegenOutput{
+		Generated: []string{},
+		Output:    strings.TrimSpace(result.Stdout + result.Stderr),
+		ExitCode:  result.ExitCode,
+		Success:   result.Success,
+	}
+	for file, state := range after {
+		if previous, ok := before[file]; !ok || previous != state {
+			output.Generated = append(output.Generated, file)
+		}
+	}
+	slices.Sort(output.Generated)
+
+	slog.Info("Code generation completed",
+		"command", command,
+		"exit_code", output.ExitCode,
+		"generated", len(output.Generated),
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// fileState is the size and modification time of a file, which change when it is written
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// fileStates returns the states of the regular files under dir by slash-separated
+// relative path, skipping git metadata
+func fileStates(dir string) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		states[filepath.ToSlash(rel)] = fileState{size: info.Size(), modTime: info.ModTime()}
+		return nil
+	})
+	return states, err
+}
+
+// ProtocTool creates a new protoc tool that compiles .proto files within the workspace directory
+func ProtocTool() tool.Tool {
+	return NewProtocToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewProtocToolWithWorkspace creates a new protoc tool with a custom workspace directory
+func NewProtocToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "protoc",
+			Description: "Compile .proto files of the workspace into Go code with protoc and protoc-gen-go, and optionally gRPC stubs. Returns the generated files and the compiler errors. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input ProtocInput) *CodegenOutput {
+			observe := observeTool("protoc")
+			output, err := RunProtoc(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &CodegenOutput{
+					ExitCode: -1,
+					Error:    err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create protoc tool: %v", err))
+	}
+	return t
+}
+
+// OapiCodegenTool creates a new oapiCodegen tool that generates Go code from OpenAPI specifications within the workspace directory
+func OapiCodegenTool() tool.Tool {
+	return NewOapiCodegenToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewOapiCodegenToolWithWorkspace creates a new oapiCodegen tool with a custom workspace directory
+func NewOapiCodegenToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "oapiCodegen",
+			Description: "Generate a Go file of types, a client, or server stubs from an OpenAPI specification of the workspace with oapi-codegen. Returns the generated files and the generator errors. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input OapiCodegenInput) *CodegenOutput {
+			observe := observeTool("oapiCodegen")
+			output, err := RunOapiCodegen(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &CodegenOutput{
+					ExitCode: -1,
+					Error:    err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create oapiCodegen tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,151 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// fakeGenerator puts a shell script named command running script first on PATH
+func fakeGenerator(t *testing.T, command, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, command), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake %s: %v", command, err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestRunProtoc(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"proto/greet.proto": "syntax = \"proto3\";\n",
+		"gen/stale.pb.go":   "package gen\n",
+	})
+	// Record the arguments, write a new file, and change an existing one
+	fakeGenerator(t, ProtocCommand, `echo "$@" > args.txt; echo "package gen" > gen/greet.pb.go; echo "// changed" >> gen/stale.pb.go`)
+
+	output, err := RunProtoc(context.Background(), workspaceDir, ProtocInput{Files: []string{"proto/greet.proto"}, ImportPaths: []string{"proto"}, OutDir: "gen", GRPC: true})
+	if err != nil {
+		t.Fatalf("RunProtoc() error = %v", err)
+	}
+	if want := []string{"args.txt", "gen/greet.pb.go", "gen/stale.pb.go"}; !output.Success || !slices.Equal(output.Generated, want) {
+		t.Errorf("RunProtoc() = %+v, want %v generated", output, want)
+	}
+	args, err := os.ReadFile(filepath.Join(workspaceDir, "args.txt"))
+	if err != nil {
+		t.Fatalf("failed to read arguments: %v", err)
+	}
+	want := "--proto_path=proto --go_out=gen --go_opt=paths=source_relative --go-grpc_out=gen --go-grpc_opt=paths=source_relative proto/greet.proto\n"
+	if string(args) != want {
+		t.Errorf("protoc arguments = %q, want %q", args, want)
+	}
+
+	// Compile errors are reported, not returned
+	fakeGenerator(t, ProtocCommand, `echo "greet.proto:3:1: Expected top-level statement." >&2; exit 1`)
+	output, err = RunProtoc(context.Background(), workspaceDir, ProtocInput{Files: []string{"proto/greet.proto"}})
+	if err != nil {
+		t.Fatalf("RunProtoc() error = %v", err)
+	}
+	if output.Success || output.ExitCode != 1 || !strings.Contains(output.Output, "Expected top-level statement") || len(output.Generated) != 0 {
+		t.Errorf("RunProtoc() = %+v, want the compile error", output)
+	}
+}
+
+func TestRunOapiCodegen(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{"api/openapi.yaml": "openapi: 3.0.0\n"})
+	fakeGenerator(t, OapiCodegenCommand, `echo "$@" > args.txt; mkdir -p internal/petstore; echo "package petstore" > internal/petstore/api.gen.go`)
+
+	output, err := RunOapiCodegen(context.Background(), workspaceDir, OapiCodegenInput{
+		Spec:     "api/openapi.yaml",
+		Output:   "internal/petstore/api.gen.go",
+		Generate: []string{"types", "std-http-server"},
+	})
+	if err != nil {
+		t.Fatalf("RunOapiCodegen() error = %v", err)
+	}
+	if want := []string{"args.txt", "internal/petstore/api.gen.go"}; !output.Success || !slices.Equal(output.Generated, want) {
+		t.Errorf("RunOapiCodegen() = %+v, want %v generated", output, want)
+	}
+	args, err := os.ReadFile(filepath.Join(workspaceDir, "args.txt"))
+	if err != nil {
+		t.Fatalf("failed to read arguments: %v", err)
+	}
+	if want := "-package petstore -o internal/petstore/api.gen.go -generate types,std-http-server api/openapi.yaml\n"; string(args) != want {
+		t.Errorf("oapi-codegen arguments = %q, want %q", args, want)
+	}
+}
+
+func TestCodegen_InvalidInput(t *testing.T) {
+	workspaceDir := t.TempDir()
+	tests := []struct {
+		name        string
+		run         func() error
+		errContains string
+	}{
+		{
+			name: "protoc without files",
+			run: func() error {
+				_, err := RunProtoc(context.Background(), workspaceDir, ProtocInput{})
+				return err
+			},
+			errContains: "files are required",
+		},
+		{
+			name: "protoc flag as file",
+			run: func() error {
+				_, err := RunProtoc(context.Background(), workspaceDir, ProtocInput{Files: []string{"--plugin=evil"}})
+				return err
+			},
+			errContains: "invalid path",
+		},
+		{
+			name: "protoc import path traversal",
+			run: func() error {
+				_, err := RunProtoc(context.Background(), workspaceDir, ProtocInput{Files: []string{"a.proto"}, ImportPaths: []string{"../"}})
+				return err
+			},
+			errContains: "path traversal detected",
+		},
+		{
+			name: "oapi-codegen without output",
+			run: func() error {
+				_, err := RunOapiCodegen(context.Background(), workspaceDir, OapiCodegenInput{Spec: "openapi.yaml"})
+				return err
+			},
+			errContains: "spec and output are required",
+		},
+		{
+			name: "oapi-codegen output traversal",
+			run: func() error {
+				_, err := RunOapiCodegen(context.Background(), workspaceDir, OapiCodegenInput{Spec: "openapi.yaml", Output: "../api.go"})
+				return err
+			},
+			errContains: "path traversal detected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestCodegenTools_ToolCreation(t *testing.T) {
+	if ProtocTool() == nil || OapiCodegenTool() == nil {
+		t.Fatal("codegen tool constructor returned nil")
+	}
+	if got := NewProtocToolWithWorkspace(t.TempDir()).Name(); got != "protoc" {
+		t.Errorf("Name() = %q, want %q", got, "protoc")
+	}
+	if got := NewOapiCodegenToolWithWorkspace(t.TempDir()).Name(); got != "oapiCodegen" {
+		t.Errorf("Name() = %q, want %q", got, "oapiCodegen")
+	}
+}
@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultMaxOutputBytes is the default maximum size of the content a tool returns in one call (100KB)
+const DefaultMaxOutputBytes = 100 * 1024
+
+// Config holds the limits the tools apply
+type Config struct {
+	// MaxOutputBytes caps the content a tool returns in one call, so a large file cannot blow
+	// the prompt budget; larger content is truncated with a marker and can be paged (0 disables)
+	MaxOutputBytes int
+	// MaxFileSize is the maximum size of a file that is read, written, edited, or searched
+	// (0 uses MaxFileSize)
+	MaxFileSize int64
+	// FileOperationTimeout is the timeout for file I/O operations (0 uses FileOperationTimeout)
+	FileOperationTimeout time.Duration
+	// AllowedExtensions restricts the files that can be read, written, edited, or searched to
+	// these extensions, such as .go (empty allows all)
+	AllowedExtensions []string
+	// DeniedPaths are glob patterns of workspace paths the tools never touch, such as .env or
+	// secrets/**; a denied directory denies everything in it
+	DeniedPaths []string
+	// IgnorePatterns are gitignore-style patterns of paths that listing and search skip and
+	// fileRead refuses, in addition to the patterns of IgnoreFiles
+	IgnorePatterns []string
+	// SecretScan is what happens to writes containing likely secrets: SecretScanBlock
+	// rejects them and SecretScanRedact redacts the secrets (empty disables)
+	SecretScan string
+	// SecretPatterns are the secrets to detect (nil uses DefaultSecretPatterns)
+	SecretPatterns []SecretPattern
+	// SymlinkPolicy is how the tools treat symlinks in workspace paths: SymlinkFollow (the
+	// default when empty) follows the ones that stay within the workspace, and SymlinkDeny
+	// rejects them all
+	SymlinkPolicy string
+	// ReadOnly disables the tools that change the workspace or run commands, leaving only
+	// the tools that inspect it
+	ReadOnly bool
+}
+
+// ToolConfig is the configuration of the tools created without one
+var ToolConfig = DefaultConfig()
+
+// DefaultConfig returns the default configuration of the tools
+func DefaultConfig() Config {
+	return Config{
+		MaxOutputBytes:       DefaultMaxOutputBytes,
+		MaxFileSize:          MaxFileSize,
+		FileOperationTimeout: FileOperationTimeout,
+		IgnorePatterns:       DefaultIgnorePatterns,
+		SecretScan:           SecretScanBlock,
+		SymlinkPolicy:        SymlinkFollow,
+	}
+}
+
+// maxFileSize returns the maximum file size in bytes
+func (c Config) maxFileSize() int64 {
+	if c.MaxFileSize > 0 {
+		return c.MaxFileSize
+	}
+	return MaxFileSize
+}
+
+// fileOperationTimeout returns the timeout for file I/O operations
+func (c Config) fileOperationTimeout() time.Duration {
+	if c.FileOperationTimeout > 0 {
+		return c.FileOperationTimeout
+	}
+	return FileOperationTimeout
+}
+
+// denied reports whether the slash-separated workspace path rel, or a directory containing
+// it, matches one of DeniedPaths
+func (c Config) denied(rel string) bool {
+	rel = strings.TrimPrefix(rel, "./")
+	if len(c.DeniedPaths) == 0 || rel == "." || rel == "" {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := range segments {
+		prefix := strings.Join(segments[:i+1], "/")
+		for _, pattern := range c.DeniedPaths {
+			if matchGlob(filepath.ToSlash(pattern), prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// allowedExtension reports whether the file at path has one of AllowedExtensions
+func (c Config) allowedExtension(path string) bool {
+	if len(c.AllowedExtensions) == 0 {
+		return true
+	}
+	ext := filepath.Ext(path)
+	for _, allowed := range c.AllowedExtensions {
+		if !strings.HasPrefix(allowed, ".") {
+			allowed = "." + allowed
+		}
+		if strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPath returns an error if the workspace path userPath is denied
+func (c Config) checkPath(userPath string) error {
+	if c.denied(filepath.ToSlash(filepath.Clean(userPath))) {
+		return fmt.Errorf("access denied: %s", userPath)
+	}
+	return nil
+}
+
+// checkWritable returns an error if the configuration is read-only
+func (c Config) checkWritable(operation string) error {
+	if c.ReadOnly {
+		return fmt.Errorf("%s is disabled in read-only mode", operation)
+	}
+	return nil
+}
+
+// resolveFile resolves the workspace file userPath like resolveWorkspacePath, and returns an
+// error if the path, or the file it points to through symlinks, is denied or its extension
+// is not allowed
+func (c Config) resolveFile(workspaceDir, userPath string) (string, error) {
+	resolved, target, err := c.resolvePath(workspaceDir, userPath)
+	if err != nil {
+		return "", err
+	}
+	for _, path := range []string{userPath, target} {
+		if err := c.checkPath(path); err != nil {
+			return "", fmt.Errorf("access denied: %s", userPath)
+		}
+		if !c.allowedExtension(path) {
+			return "", fmt.Errorf("file extension not allowed: %s (allowed: %s)", userPath, strings.Join(c.AllowedExtensions, ", "))
+		}
+	}
+	return resolved, nil
+}
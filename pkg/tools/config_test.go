@@ -0,0 +1,155 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConfig_Limits(t *testing.T) {
+	workspaceDir := t.TempDir()
+	config := Config{MaxFileSize: 16, FileOperationTimeout: time.Second}
+	if err := os.WriteFile(filepath.Join(workspaceDir, "big.txt"), []byte(strings.Repeat("x", 32)), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	if _, err := executeFileRead(workspaceDir, config, FileReadInput{Path: "big.txt"}); err == nil || !strings.Contains(err.Error(), "max 16 bytes") {
+		t.Errorf("executeFileRead() error = %v, want a file too large error", err)
+	}
+	if _, err := executeFileWrite(workspaceDir, config, FileWriteInput{Path: "new.txt", Content: strings.Repeat("y", 17)}); err == nil || !strings.Contains(err.Error(), "max 16 bytes") {
+		t.Errorf("executeFileWrite() error = %v, want a content too large error", err)
+	}
+	if _, err := executeFileEdit(workspaceDir, config, FileEditInput{Path: "big.txt", Mode: FileEditAppend, Content: "z"}); err == nil || !strings.Contains(err.Error(), "max 16 bytes") {
+		t.Errorf("executeFileEdit() error = %v, want a file too large error", err)
+	}
+	output, err := executeSearch(workspaceDir, config, SearchInput{Pattern: "x"})
+	if err != nil || output.FilesSearched != 0 {
+		t.Errorf("executeSearch() = %+v, %v, want the large file skipped", output, err)
+	}
+	// The defaults apply to a zero configuration
+	if _, err := executeFileRead(workspaceDir, Config{}, FileReadInput{Path: "big.txt"}); err != nil {
+		t.Errorf("executeFileRead() with a zero configuration error = %v", err)
+	}
+}
+
+func TestConfig_Paths(t *testing.T) {
+	workspaceDir := t.TempDir()
+	for path, content := range map[string]string{
+		"main.go":          "package main\n",
+		"README.md":        "# main\n",
+		".env":             "TOKEN=main\n",
+		"secrets/key.go":   "package secrets // main\n",
+		"config/prod.yaml": "main: true\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(workspaceDir, path)), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(workspaceDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+	config := Config{
+		AllowedExtensions: []string{".go", "md"},
+		DeniedPaths:       []string{".env", "secrets", "config/*.yaml"},
+	}
+
+	tests := []struct {
+		path        string
+		errContains string
+	}{
+		{path: "main.go"},
+		{path: "README.md"},
+		{path: ".env", errContains: "access denied"},
+		{path: "secrets/key.go", errContains: "access denied"},
+		{path: "./secrets/../secrets/key.go", errContains: "access denied"},
+		{path: "config/prod.yaml", errContains: "access denied"},
+		{path: "go.sum", errContains: "file extension not allowed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, readErr := executeFileRead(workspaceDir, config, FileReadInput{Path: tt.path})
+			_, writeErr := executeFileWrite(workspaceDir, config, FileWriteInput{Path: tt.path, Content: "changed\n"})
+			for _, err := range []error{readErr, writeErr} {
+				if tt.errContains == "" && err != nil {
+					t.Errorf("error = %v, want none", err)
+				}
+				if tt.errContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errContains)) {
+					t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+				}
+			}
+		})
+	}
+
+	if _, err := executeFileStat(workspaceDir, config, FileStatInput{Path: ".env"}); err == nil {
+		t.Error("executeFileStat() of a denied path error = nil, want an error")
+	}
+	list, err := executeFileList(workspaceDir, config, FileListInput{Recursive: true})
+	if err != nil {
+		t.Fatalf("executeFileList() error = %v", err)
+	}
+	for _, entry := range list.Entries {
+		if entry.Path == ".env" || strings.HasPrefix(entry.Path, "secrets") || entry.Path == "config/prod.yaml" {
+			t.Errorf("executeFileList() listed denied path %s", entry.Path)
+		}
+	}
+	search, err := executeSearch(workspaceDir, config, SearchInput{Pattern: "main"})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	for _, match := range search.Matches {
+		if match.Path != "main.go" && match.Path != "README.md" {
+			t.Errorf("executeSearch() matched %s, want only allowed files", match.Path)
+		}
+	}
+}
+
+func TestConfig_ReadOnly(t *testing.T) {
+	workspaceDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(workspaceDir, "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	config := Config{ReadOnly: true}
+	patch := "--- a/main.go\n+++ b/main.go\n@@ -1 +1 @@\n-package main\n+package app\n"
+
+	if _, err := executeFileWrite(workspaceDir, config, FileWriteInput{Path: "main.go", Content: "package app\n"}); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("executeFileWrite() error = %v, want a read-only error", err)
+	}
+	if _, err := executeFileEdit(workspaceDir, config, FileEditInput{Path: "main.go", Mode: FileEditAppend, Content: "// x"}); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("executeFileEdit() error = %v, want a read-only error", err)
+	}
+	if _, err := executeApplyPatch(workspaceDir, config, PatchInput{Patch: patch}); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("executeApplyPatch() error = %v, want a read-only error", err)
+	}
+	// Inspection still works
+	if output, err := executeApplyPatch(workspaceDir, config, PatchInput{Patch: patch, DryRun: true}); err != nil || !output.Success {
+		t.Errorf("executeApplyPatch() dry run = %+v, %v, want success", output, err)
+	}
+	if output, err := executeFileRead(workspaceDir, config, FileReadInput{Path: "main.go"}); err != nil || output.Content != "package main\n" {
+		t.Errorf("executeFileRead() = %+v, %v, want the unchanged file", output, err)
+	}
+}
+
+func TestReadOnlyCommands(t *testing.T) {
+	previous := ToolConfig
+	ToolConfig.ReadOnly = true
+	t.Cleanup(func() { ToolConfig = previous })
+	workspaceDir := t.TempDir()
+	ctx := context.Background()
+
+	if _, err := RunCommand(ctx, workspaceDir, ExecInput{Command: "go", Args: []string{"build", "./..."}}); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("RunCommand() error = %v, want a read-only error", err)
+	}
+	if _, err := RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModTidy}); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("RunGoMod() error = %v, want a read-only error", err)
+	}
+	if _, err := RunGit(ctx, workspaceDir, "init", "--quiet"); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("RunGit(init) error = %v, want a read-only error", err)
+	}
+	// Inspecting the repository is still allowed
+	if _, err := RunGit(ctx, workspaceDir, "status"); err != nil && strings.Contains(err.Error(), "read-only mode") {
+		t.Errorf("RunGit(status) error = %v, want it run", err)
+	}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// CharsPerToken is the rough number of bytes per token used for token estimates
+const CharsPerToken = 4
+
+// CountInput defines the input parameters for the count tool
+type CountInput struct {
+	// Paths are relative paths of files, or globs such as *.go or pkg/**/*.go, as in fileList
+	Paths []string `json:"paths"`
+}
+
+// FileCount holds the counts of a file
+type FileCount struct {
+	// Path is the slash-separated path of the file, relative to the workspace
+	Path string `json:"path"`
+	// Lines is the number of lines
+	Lines int `json:"lines"`
+	// Bytes is the file size in bytes
+	Bytes int64 `json:"bytes"`
+	// Tokens is the estimated number of tokens, about one per CharsPerToken bytes
+	Tokens int `json:"tokens"`
+	// Binary indicates a file with NUL bytes, which is not counted in lines or tokens
+	Binary bool `json:"binary,omitempty"`
+}
+
+// CountOutput defines the output structure for the count tool
+type CountOutput struct {
+	// Files are the counts of each file, in the order of Paths and then in lexical order
+	Files []FileCount `json:"files"`
+	// TotalLines is the sum of the lines of all files
+	TotalLines int `json:"totalLines"`
+	// TotalTokens is the sum of the estimated tokens of all files
+	TotalTokens int `json:"totalTokens"`
+	// Truncated indicates that a glob matched more than MaxFileListEntries entries and only the
+	// first are counted
+	Truncated bool `json:"truncated,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeCount is the core logic for counting lines and tokens, extracted for testability
+func executeCount(workspaceDir string, config Config, input CountInput) (*CountOutput, error) {
+	start := time.Now()
+	slog.Info("Starting count operation",
+		"paths", input.Paths,
+		"workspace", workspaceDir)
+
+	if len(input.Paths) == 0 {
+		return nil, fmt.Errorf("at least one path is required")
+	}
+
+	output := &CountOutput{Files: []FileCount{}}
+	seen := map[string]bool{}
+	for _, p := range input.Paths {
+		var paths []string
+		if strings.ContainsAny(p, "*?[") {
+			list, err := executeFileList(workspaceDir, config, FileListInput{Pattern: p})
+			if err != nil {
+				return nil, err
+			}
+			output.Truncated = output.Truncated || list.Truncated
+			for _, entry := range list.Entries {
+				// Files the configuration does not allow are left out of glob matches
+				if !entry.IsDir && config.allowedExtension(entry.Path) {
+					paths = append(paths, entry.Path)
+				}
+			}
+		} else {
+			paths = []string{p}
+		}
+
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			count, err := countFile(workspaceDir, config, path)
+			if err != nil {
+				slog.Error("Failed to count file",
+					"path", path,
+					"error", err)
+				return nil, err
+			}
+			output.Files = append(output.Files, *count)
+			output.TotalLines += count.Lines
+			output.TotalTokens += count.Tokens
+		}
+	}
+
+	slog.Info("Count completed successfully",
+		"files", len(output.Files),
+		"total_lines", output.TotalLines,
+		"total_tokens", output.TotalTokens,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// countFile counts the lines and estimated tokens of the workspace file at path
+func countFile(workspaceDir string, config Config, path string) (*FileCount, error) {
+	resolved, err := config.resolveFile(workspaceDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := checkIgnored(workspaceDir, config, path, false); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return nil, fmt.Errorf("failed to count %s: is a directory", path)
+	}
+	if maxSize := config.maxFileSize(); info.Size() > maxSize {
+		return nil, fmt.Errorf("file too large: %d bytes (max %d bytes)", info.Size(), maxSize)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count %s: %w", path, err)
+	}
+	count := &FileCount{Path: path, Bytes: info.Size()}
+	if bytes.IndexByte(data, 0) >= 0 {
+		count.Binary = true
+		return count, nil
+	}
+	count.Lines = bytes.Count(data, []byte("\n"))
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		count.Lines++
+	}
+	count.Tokens = (len(data) + CharsPerToken - 1) / CharsPerToken
+	return count, nil
+}
+
+// CountTool creates a new count tool that counts the lines and tokens of files within the workspace directory
+func CountTool() tool.Tool {
+	return NewCountToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewCountToolWithWorkspace creates a new count tool with a custom workspace directory
+func NewCountToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewCountToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewCountToolWithConfig creates a new count tool with a custom workspace directory and configuration
+func NewCountToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "count",
+			Description: "Count the lines and estimate the tokens of files in the workspace, given as paths or globs such as **/*.go, with totals. Use it to decide which files to read in full and which to read in line ranges or search. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input CountInput) *CountOutput {
+			observe := observeTool("count")
+			output, err := executeCount(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &CountOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create count tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCountTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"calc/calc.go":      "package calc\n\nfunc Add(a, b int) int { return a + b }",
+		"calc/calc_test.go": "package calc\n",
+		"logo.png":          "\x89PNG\x00\x00",
+		"vendor/dep.go":     "package dep\n",
+	})
+
+	tests := []struct {
+		name        string
+		config      Config
+		paths       []string
+		want        CountOutput
+		errContains string
+	}{
+		{
+			name:  "file",
+			paths: []string{"main.go"},
+			want: CountOutput{
+				Files:       []FileCount{{Path: "main.go", Lines: 3, Bytes: 29, Tokens: 8}},
+				TotalLines:  3,
+				TotalTokens: 8,
+			},
+		},
+		{
+			name:  "glob",
+			paths: []string{"**/*.go", "main.go"},
+			want: CountOutput{
+				Files: []FileCount{
+					{Path: "calc/calc.go", Lines: 3, Bytes: 53, Tokens: 14},
+					{Path: "calc/calc_test.go", Lines: 1, Bytes: 13, Tokens: 4},
+					{Path: "main.go", Lines: 3, Bytes: 29, Tokens: 8},
+				},
+				TotalLines:  7,
+				TotalTokens: 26,
+			},
+		},
+		{
+			name:  "binary",
+			paths: []string{"logo.png"},
+			want:  CountOutput{Files: []FileCount{{Path: "logo.png", Bytes: 6, Binary: true}}},
+		},
+		{
+			name:   "allowed extensions",
+			config: Config{AllowedExtensions: []string{".go"}},
+			paths:  []string{"*"},
+			want: CountOutput{
+				Files:       []FileCount{{Path: "main.go", Lines: 3, Bytes: 29, Tokens: 8}},
+				TotalLines:  3,
+				TotalTokens: 8,
+			},
+		},
+		{
+			name:        "no paths",
+			errContains: "at least one path is required",
+		},
+		{
+			name:        "directory",
+			paths:       []string{"calc"},
+			errContains: "is a directory",
+		},
+		{
+			name:        "missing file",
+			paths:       []string{"missing.go"},
+			errContains: "failed to count missing.go",
+		},
+		{
+			name:        "path traversal",
+			paths:       []string{"../outside.go"},
+			errContains: "path traversal detected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := tt.config
+			if config.IgnorePatterns == nil {
+				config.IgnorePatterns = DefaultIgnorePatterns
+			}
+			output, err := executeCount(workspaceDir, config, CountInput{Paths: tt.paths})
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeCount() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeCount() error = %v", err)
+			}
+			if !reflect.DeepEqual(*output, tt.want) {
+				t.Errorf("executeCount() = %+v, want %+v", *output, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// DefaultDiffContext is the default number of unchanged lines shown around each change
+const DefaultDiffContext = 3
+
+// maxDiffEdits bounds the search for the shortest diff; files that differ in more lines are
+// diffed as a removal of all changed lines followed by their replacement
+const maxDiffEdits = 2000
+
+// DiffInput defines the input parameters for the diff tool
+type DiffInput struct {
+	// Path is the relative path of the original file (within the workspace directory)
+	Path string `json:"path"`
+	// NewPath is the relative path of the file to compare Path with
+	NewPath string `json:"newPath,omitempty"`
+	// Content is compared with Path when NewPath is empty
+	Content string `json:"content,omitempty"`
+	// Context is the number of unchanged lines shown around each change (0 uses 3, -1 shows none)
+	Context int `json:"context,omitempty"`
+}
+
+// DiffOutput defines the output structure for the diff tool
+type DiffOutput struct {
+	// Diff is the unified diff from Path to NewPath or Content, empty when they are identical
+	Diff string `json:"diff,omitempty"`
+	// Identical indicates whether the contents are the same
+	Identical bool `json:"identical"`
+	// Added is the number of added lines
+	Added int `json:"added"`
+	// Removed is the number of removed lines
+	Removed int `json:"removed"`
+	// Truncated indicates that the diff was cut at Config.MaxOutputBytes
+	Truncated bool `json:"truncated,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// diffLine is an unchanged (' '), removed ('-'), or added ('+') line of a diff, including its
+// newline unless it is the last line of a file without one
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// executeDiff is the core logic for diffing files, extracted for testability
+func executeDiff(workspaceDir string, config Config, input DiffInput) (*DiffOutput, error) {
+	start := time.Now()
+	slog.Info("Starting diff operation",
+		"path", input.Path,
+		"new_path", input.NewPath,
+		"workspace", workspaceDir)
+
+	if input.Path == "" {
+		return nil, fmt.Errorf("path is required")
+	}
+	oldText, err := readDiffFile(workspaceDir, config, input.Path)
+	if err != nil {
+		slog.Error("Failed to read file",
+			"path", input.Path,
+			"error", err)
+		return nil, err
+	}
+	newName, newText := input.Path, input.Content
+	if input.NewPath != "" {
+		newName = input.NewPath
+		if newText, err = readDiffFile(workspaceDir, config, input.NewPath); err != nil {
+			slog.Error("Failed to read file",
+				"path", input.NewPath,
+				"error", err)
+			return nil, err
+		}
+	}
+
+	context := input.Context
+	switch {
+	case context == 0:
+		context = DefaultDiffContext
+	case context < 0:
+		context = 0
+	}
+
+	lines := diffLines(splitLines(oldText), splitLines(newText))
+	output := &DiffOutput{Identical: oldText == newText}
+	for _, line := range lines {
+		switch line.op {
+		case '+':
+			output.Added++
+		case '-':
+			output.Removed++
+		}
+	}
+	if !output.Identical {
+		output.Diff = unifiedDiff(input.Path, newName, lines, context)
+		if limit := config.MaxOutputBytes; limit > 0 && len(output.Diff) > limit {
+			output.Diff = truncateUTF8(output.Diff, limit)
+			output.Truncated = true
+		}
+	}
+
+	slog.Info("Diff completed successfully",
+		"path", input.Path,
+		"new_path", newName,
+		"added", output.Added,
+		"removed", output.Removed,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// readDiffFile returns the content of the workspace file at path
+func readDiffFile(workspaceDir string, config Config, path string) (string, error) {
+	resolved, err := config.resolveFile(workspaceDir, path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := checkIgnored(workspaceDir, config, path, false); err != nil {
+		return "", err
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if info.IsDir() {
+		return "", fmt.Errorf("%s is a directory", path)
+	}
+	if maxSize := config.maxFileSize(); info.Size() > maxSize {
+		return "", fmt.Errorf("file too large: %d bytes (max %d bytes)", info.Size(), maxSize)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	return string(data), nil
+}
+
+// splitLines splits text after each newline
+func splitLines(text string) []string {
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diffLines returns a shortest diff from a to b
+func diffLines(a, b []string) []diffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]diffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, text := range a[:prefix] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	lines = append(lines, myersDiff(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, diffLine{' ', text})
+	}
+	return lines
+}
+
+// myersDiff returns a shortest diff from a to b with the Myers algorithm, or all of a removed
+// and all of b added when they differ in more than maxDiffEdits lines
+func myersDiff(a, b []string) []diffLine {
+	n, m := len(a), len(b)
+	// v[offset+k] is the furthest x reached on diagonal k = x-y; trace[d] keeps diagonals
+	// -d-1 to d+1 of v before step d, to walk the path back
+	offset := n + m + 1
+	v := make([]int, 2*offset+1)
+	var trace [][]int
+	for d := 0; d <= n+m; d++ {
+		if d > maxDiffEdits {
+			return replaceLines(a, b)
+		}
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrackDiff(a, b, trace)
+			}
+		}
+	}
+	return replaceLines(a, b)
+}
+
+// backtrackDiff walks the path found by myersDiff back from the end of a and b
+func backtrackDiff(a, b []string, trace [][]int) []diffLine {
+	var reversed []diffLine
+	x, y := len(a), len(b)
+	for d := len(trace) - 1; d >= 0; d-- {
+		// at returns the furthest x on diagonal k before step d
+		at := func(k int) int { return trace[d][k+d+1] }
+		k := x - y
+		prevK := k - 1
+		if k == -d || (k != d && at(k-1) < at(k+1)) {
+			prevK = k + 1
+		}
+		prevX := at(prevK)
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			x--
+			y--
+			reversed = append(reversed, diffLine{' ', a[x]})
+		}
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, diffLine{'+', b[prevY]})
+			} else {
+				reversed = append(reversed, diffLine{'-', a[prevX]})
+			}
+		}
+		x, y = prevX, prevY
+	}
+	slices.Reverse(reversed)
+	return reversed
+}
+
+// replaceLines returns a diff that removes all of a and adds all of b
+func replaceLines(a, b []string) []diffLine {
+	lines := make([]diffLine, 0, len(a)+len(b))
+	for _, text := range a {
+		lines = append(lines, diffLine{'-', text})
+	}
+	for _, text := range b {
+		lines = append(lines, diffLine{'+', text})
+	}
+	return lines
+}
+
+// unifiedDiff formats lines as a unified diff from oldName to newName, with context unchanged
+// lines around each change. It can be applied with applyPatch.
+func unifiedDiff(oldName, newName string, lines []diffLine, context int) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "--- a/%s\n+++ b/%s\n", oldName, newName)
+	oldLine, newLine := 0, 0
+	for i := 0; i < len(lines); {
+		if lines[i].op == ' ' {
+			oldLine++
+			newLine++
+			i++
+			continue
+		}
+		// A hunk ends once more than 2*context unchanged lines follow its last change
+		end := i + 1
+		for j := end; j < len(lines) && j-end <= 2*context; j++ {
+			if lines[j].op != ' ' {
+				end = j + 1
+			}
+		}
+		first := max(i-context, 0)
+		last := min(end+context, len(lines))
+		oldStart, newStart := oldLine-(i-first), newLine-(i-first)
+		oldCount, newCount := 0, 0
+		for _, line := range lines[first:last] {
+			if line.op != '+' {
+				oldCount++
+			}
+			if line.op != '-' {
+				newCount++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n", hunkRange(oldStart, oldCount), hunkRange(newStart, newCount))
+		for _, line := range lines[first:last] {
+			sb.WriteByte(line.op)
+			sb.WriteString(line.text)
+			if !strings.HasSuffix(line.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		// Skip the hunk, counting the lines it covers from i
+		for _, line := range lines[i:last] {
+			if line.op != '+' {
+				oldLine++
+			}
+			if line.op != '-' {
+				newLine++
+			}
+		}
+		i = last
+	}
+	return sb.String()
+}
+
+// hunkRange formats the start line and line count of a hunk; an empty range starts at the
+// line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// DiffTool creates a new diff tool that compares files within the workspace directory
+func DiffTool() tool.Tool {
+	return NewDiffToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewDiffToolWithWorkspace creates a new diff tool with a custom workspace directory
+func NewDiffToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewDiffToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewDiffToolWithConfig creates a new diff tool with a custom workspace directory and configuration
+func NewDiffToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "diff",
+			Description: "Compare a file in the workspace with another file (newPath) or with the given content, and return a unified diff of the changes with the number of added and removed lines. Use it to review precise changes instead of reading whole files. The diff can be applied with applyPatch. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input DiffInput) *DiffOutput {
+			observe := observeTool("diff")
+			output, err := executeDiff(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &DiffOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create diff tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDiffTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"old.go":    "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"new.go":    "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"same.go":   "package calc\n\nfunc Add(a, b int) int {\n\treturn a + b\n}\n",
+		"no_eol.go": "package calc",
+	})
+
+	tests := []struct {
+		name        string
+		input       DiffInput
+		want        DiffOutput
+		errContains string
+	}{
+		{
+			name:  "two files",
+			input: DiffInput{Path: "old.go", NewPath: "new.go"},
+			want: DiffOutput{
+				Diff:  "--- a/old.go\n+++ b/new.go\n@@ -1,5 +1,6 @@\n package calc\n \n+// Add returns a + b\n func Add(a, b int) int {\n \treturn a + b\n }\n",
+				Added: 1,
+			},
+		},
+		{
+			name:  "content",
+			input: DiffInput{Path: "old.go", Content: "package calc\n\nfunc Add(a, b int) int {\n\treturn b + a\n}\n", Context: 1},
+			want: DiffOutput{
+				Diff:    "--- a/old.go\n+++ b/old.go\n@@ -3,3 +3,3 @@\n func Add(a, b int) int {\n-\treturn a + b\n+\treturn b + a\n }\n",
+				Added:   1,
+				Removed: 1,
+			},
+		},
+		{
+			name:  "identical",
+			input: DiffInput{Path: "old.go", NewPath: "same.go"},
+			want:  DiffOutput{Identical: true},
+		},
+		{
+			name:  "missing newline",
+			input: DiffInput{Path: "no_eol.go", Content: "package calc\n"},
+			want: DiffOutput{
+				Diff:    "--- a/no_eol.go\n+++ b/no_eol.go\n@@ -1,1 +1,1 @@\n-package calc\n\\ No newline at end of file\n+package calc\n",
+				Added:   1,
+				Removed: 1,
+			},
+		},
+		{
+			name:  "empty content",
+			input: DiffInput{Path: "no_eol.go"},
+			want: DiffOutput{
+				Diff:    "--- a/no_eol.go\n+++ b/no_eol.go\n@@ -1,1 +0,0 @@\n-package calc\n\\ No newline at end of file\n",
+				Removed: 1,
+			},
+		},
+		{
+			name:        "missing file",
+			input:       DiffInput{Path: "old.go", NewPath: "missing.go"},
+			errContains: "failed to read file missing.go",
+		},
+		{
+			name:        "path traversal",
+			input:       DiffInput{Path: "../outside.go", Content: "x"},
+			errContains: "path traversal detected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeDiff(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeDiff() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeDiff() error = %v", err)
+			}
+			if *output != tt.want {
+				t.Errorf("executeDiff() = %+v, want %+v", *output, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffTool_Hunks(t *testing.T) {
+	workspaceDir := t.TempDir()
+	var lines []string
+	for i := 1; i <= 30; i++ {
+		lines = append(lines, fmt.Sprintf("line %d", i))
+	}
+	old := strings.Join(lines, "\n") + "\n"
+	lines[1], lines[7], lines[25] = "changed 2", "changed 8", "changed 26"
+	lines = append(lines[:15], lines[16:]...)
+	changed := strings.Join(lines, "\n") + "\n"
+	writeFiles(t, workspaceDir, map[string]string{"lines.txt": old})
+
+	output, err := executeDiff(workspaceDir, ToolConfig, DiffInput{Path: "lines.txt", Content: changed})
+	if err != nil {
+		t.Fatalf("executeDiff() error = %v", err)
+	}
+	// Changes 6 lines apart share a hunk; the others get their own
+	var headers []string
+	for line := range strings.SplitSeq(output.Diff, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			headers = append(headers, line)
+		}
+	}
+	want := []string{"@@ -1,11 +1,11 @@", "@@ -13,7 +13,6 @@", "@@ -23,7 +22,7 @@"}
+	if strings.Join(headers, "|") != strings.Join(want, "|") || output.Added != 3 || output.Removed != 4 {
+		t.Errorf("executeDiff() headers = %q, added %d, removed %d, want %q, 3, 4", headers, output.Added, output.Removed, want)
+	}
+
+	// The diff applies with applyPatch and produces the new content
+	if _, err := executeApplyPatch(workspaceDir, ToolConfig, PatchInput{Patch: output.Diff}); err != nil {
+		t.Fatalf("executeApplyPatch() error = %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(workspaceDir, "lines.txt"))
+	if err != nil {
+		t.Fatalf("failed to read patched file: %v", err)
+	}
+	if string(got) != changed {
+		t.Errorf("patched file = %q, want %q", got, changed)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"empty", "", "", ""},
+		{"added", "", "a\nb\n", "+a\n+b\n"},
+		{"removed", "a\nb\n", "", "-a\n-b\n"},
+		{"replaced", "a\nb\nc\n", "a\nx\nc\n", " a\n-b\n+x\n c\n"},
+		{"moved", "a\nb\nc\n", "b\nc\na\n", "-a\n b\n c\n+a\n"},
+		{"interleaved", "a\nb\nc\nd\n", "b\nx\nd\ny\n", "-a\n b\n-c\n+x\n d\n+y\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			for _, line := range diffLines(splitLines(tt.a), splitLines(tt.b)) {
+				sb.WriteByte(line.op)
+				sb.WriteString(line.text)
+			}
+			if sb.String() != tt.want {
+				t.Errorf("diffLines(%q, %q) = %q, want %q", tt.a, tt.b, sb.String(), tt.want)
+			}
+		})
+	}
+}
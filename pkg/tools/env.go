@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// AllowedEnvVars lists the environment variables the env tool created by EnvTool may read;
+// nothing else in the environment, such as credentials, is visible to agents
+var AllowedEnvVars []string
+
+// EnvInput defines the input parameters for the env tool
+type EnvInput struct {
+	// Names are the variables to read (defaults to all allowed variables)
+	Names []string `json:"names,omitempty"`
+}
+
+// EnvOutput defines the output structure for the env tool
+type EnvOutput struct {
+	// Variables are the values of the requested variables that are set
+	Variables map[string]string `json:"variables"`
+	// Unset are the requested variables that are allowed but not set
+	Unset []string `json:"unset,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// ReadEnv returns the values of the requested environment variables, all of which must be
+// in allowed
+func ReadEnv(allowed []string, input EnvInput) (*EnvOutput, error) {
+	names := input.Names
+	if len(names) == 0 {
+		names = allowed
+	}
+	output := &EnvOutput{Variables: make(map[string]string, len(names))}
+	for _, name := range names {
+		if !slices.Contains(allowed, name) {
+			slog.Warn("Environment variable not allowed", "name", name)
+			return nil, fmt.Errorf("environment variable not allowed: %q (allowed: %v)", name, allowed)
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			output.Variables[name] = value
+		} else {
+			output.Unset = append(output.Unset, name)
+		}
+	}
+	slog.Info("Environment read completed",
+		"names", names,
+		"unset", output.Unset)
+	return output, nil
+}
+
+// EnvTool creates a new env tool that reads the environment variables in AllowedEnvVars
+func EnvTool() tool.Tool {
+	return NewEnvTool(AllowedEnvVars)
+}
+
+// NewEnvTool creates a new env tool that reads only the environment variables in allowed
+func NewEnvTool(allowed []string) tool.Tool {
+	allowed = slices.Clone(allowed)
+	description := "Read environment variables set by the operator, such as the target Go version, instead of guessing their values. Leave names empty to read all of them."
+	if len(allowed) > 0 {
+		description += " Available variables: " + strings.Join(allowed, ", ") + "."
+	} else {
+		description += " No variables are available."
+	}
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "env",
+			Description: description,
+		},
+		func(ctx tool.Context, input EnvInput) *EnvOutput {
+			observe := observeTool("env")
+			output, err := ReadEnv(allowed, input)
+			observe(err)
+			if err != nil {
+				return &EnvOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create env tool: %v", err))
+	}
+	return t
+}
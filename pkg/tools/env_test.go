@@ -0,0 +1,76 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadEnv(t *testing.T) {
+	t.Setenv("TARGET_GO_VERSION", "1.24")
+	t.Setenv("SERVICE_PORT", "8080")
+	t.Setenv("SECRET_TOKEN", "hunter2")
+	allowed := []string{"TARGET_GO_VERSION", "SERVICE_PORT", "DEPLOY_REGION"}
+
+	tests := []struct {
+		name        string
+		input       EnvInput
+		want        *EnvOutput
+		errContains string
+	}{
+		{
+			name:  "all allowed",
+			input: EnvInput{},
+			want: &EnvOutput{
+				Variables: map[string]string{"TARGET_GO_VERSION": "1.24", "SERVICE_PORT": "8080"},
+				Unset:     []string{"DEPLOY_REGION"},
+			},
+		},
+		{
+			name:  "by name",
+			input: EnvInput{Names: []string{"TARGET_GO_VERSION"}},
+			want:  &EnvOutput{Variables: map[string]string{"TARGET_GO_VERSION": "1.24"}},
+		},
+		{
+			name:        "not allowed",
+			input:       EnvInput{Names: []string{"TARGET_GO_VERSION", "SECRET_TOKEN"}},
+			errContains: `environment variable not allowed: "SECRET_TOKEN"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := ReadEnv(allowed, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("ReadEnv() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ReadEnv() error = %v", err)
+			}
+			if !reflect.DeepEqual(output, tt.want) {
+				t.Errorf("ReadEnv() = %+v, want %+v", output, tt.want)
+			}
+		})
+	}
+
+	// Nothing is readable without an allow-list
+	if _, err := ReadEnv(nil, EnvInput{Names: []string{"HOME"}}); err == nil {
+		t.Error("ReadEnv() without allowed variables error = nil, want an error")
+	}
+}
+
+func TestEnvTool_ToolCreation(t *testing.T) {
+	if EnvTool() == nil {
+		t.Fatal("EnvTool() returned nil")
+	}
+	env := NewEnvTool([]string{"TARGET_GO_VERSION"})
+	if got := env.Name(); got != "env" {
+		t.Errorf("Name() = %q, want %q", got, "env")
+	}
+	if !strings.Contains(env.Description(), "TARGET_GO_VERSION") {
+		t.Errorf("Description() = %q, want the allowed variables", env.Description())
+	}
+}
@@ -0,0 +1,266 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// CommandTimeout is the default timeout for commands run in the workspace
+const CommandTimeout = 5 * time.Minute
+
+// MaxCommandOutput is the maximum number of bytes kept from each of stdout and stderr (1MB)
+const MaxCommandOutput = 1024 * 1024
+
+// AllowedCommands lists the executables the exec tool may run
+var AllowedCommands = []string{"go", "gofmt", "gosec", LintCommand, ProtocCommand, OapiCodegenCommand}
+
+// allowedGoCommands are the go subcommands RunCommand runs, with the subcommands they
+// allow in turn (nil allows all). Subcommands such as run, generate, and install, which
+// run or install programs on the host, are not allowed, and neither is -C, which comes
+// before the subcommand.
+var allowedGoCommands = map[string][]string{
+	"build":   nil,
+	"test":    nil,
+	"vet":     nil,
+	"fmt":     nil,
+	"get":     nil,
+	"list":    nil,
+	"env":     nil,
+	"version": nil,
+	"mod":     {"init", "tidy", "edit", "download", "graph", "verify", "why"},
+	"tool":    {"cover"},
+}
+
+// deniedGoFlags are the go flags that name a program for go to run
+var deniedGoFlags = []string{"exec", "toolexec", "vettool"}
+
+// goPathFlags are the go flags that name a file or directory go writes or changes to,
+// which must be within the workspace
+var goPathFlags = []string{"o", "outputdir", "coverprofile", "cpuprofile", "memprofile",
+	"blockprofile", "mutexprofile", "trace", "modfile", "pkgdir", "overlay"}
+
+// ExecInput defines the input parameters for the exec tool
+type ExecInput struct {
+	// Command is the executable to run; it must be listed in AllowedCommands
+	Command string `json:"command"`
+	// Args are the command arguments
+	Args []string `json:"args,omitempty"`
+	// Dir is the relative working directory within the workspace (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+	// TimeoutSeconds overrides the default command timeout
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// ExecOutput defines the output structure for the exec tool
+type ExecOutput struct {
+	// Stdout is the standard output of the command
+	Stdout string `json:"stdout,omitempty"`
+	// Stderr is the standard error of the command
+	Stderr string `json:"stderr,omitempty"`
+	// ExitCode is the exit code of the command
+	ExitCode int `json:"exitCode"`
+	// Success indicates whether the command exited with code 0
+	Success bool `json:"success"`
+	// Error contains the error message if the command could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// RunCommand runs an allowed command in the workspace directory, with the executor set
+// for the workspace by SetExecutor. A command that runs but exits non-zero is not an
+// error; its exit code is reported in the output. Commands cannot be run in read-only mode.
+func RunCommand(ctx context.Context, workspaceDir string, input ExecInput) (*ExecOutput, error) {
+	if err := ToolConfig.checkWritable("running commands"); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	slog.Info("Starting command",
+		"command", input.Command,
+		"args", input.Args,
+		"dir", input.Dir,
+		"workspace", workspaceDir)
+
+	if !slices.Contains(AllowedCommands, input.Command) {
+		slog.Warn("Command not allowed", "command", input.Command)
+		return nil, fmt.Errorf("command not allowed: %q (allowed: %v)", input.Command, AllowedCommands)
+	}
+
+	dir := input.Dir
+	if dir == "" {
+		dir = "."
+	}
+	resolvedDir, err := resolveWorkspacePath(workspaceDir, dir)
+	if err != nil {
+		slog.Error("Failed to resolve working directory",
+			"dir", input.Dir,
+			"error", err)
+		return nil, fmt.Errorf("failed to resolve working directory: %w", err)
+	}
+
+	if input.Command == "go" {
+		if err := checkGoCommand(workspaceDir, resolvedDir, input.Args); err != nil {
+			slog.Warn("Go command not allowed", "args", input.Args, "error", err)
+			return nil, err
+		}
+	}
+
+	timeout := CommandTimeout
+	if input.TimeoutSeconds > 0 {
+		timeout = time.Duration(input.TimeoutSeconds) * time.Second
+	}
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	absWorkspace, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace directory: %w", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := executorFor(absWorkspace).Command(cmdCtx, absWorkspace, resolvedDir, input.Command, input.Args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if cmdCtx.Err() == context.DeadlineExceeded {
+		slog.Error("Command timed out",
+			"command", input.Command,
+			"timeout", timeout)
+		return nil, fmt.Errorf("command timeout exceeded (%v)", timeout)
+	}
+
+	output := &ExecOutput{
+		Stdout: truncateOutput(stdout.Bytes()),
+		Stderr: truncateOutput(stderr.Bytes()),
+	}
+
+	var exitErr *exec.ExitError
+	switch {
+	case runErr == nil:
+		output.Success = true
+	case errors.As(runErr, &exitErr):
+		output.ExitCode = exitErr.ExitCode()
+	default:
+		slog.Error("Failed to run command",
+			"command", input.Command,
+			"error", runErr)
+		return nil, fmt.Errorf("failed to run %s: %w", input.Command, runErr)
+	}
+
+	slog.Info("Command completed",
+		"command", input.Command,
+		"exit_code", output.ExitCode,
+		"duration_ms", time.Since(start).Milliseconds())
+
+	return output, nil
+}
+
+// checkGoCommand returns an error unless args run one of allowedGoCommands without
+// changing anything outside the workspace: no denied flags, no go env -w or -u, and
+// path flags that stay within the workspace. Relative paths are resolved against the
+// absolute directory dir the command runs in.
+func checkGoCommand(workspaceDir, dir string, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("go command is required")
+	}
+	subcommands, ok := allowedGoCommands[args[0]]
+	if !ok {
+		return fmt.Errorf("go %s is not allowed (allowed: %v)", args[0], slices.Sorted(maps.Keys(allowedGoCommands)))
+	}
+	if subcommands != nil {
+		i := slices.IndexFunc(args[1:], func(arg string) bool { return !strings.HasPrefix(arg, "-") })
+		if i < 0 || !slices.Contains(subcommands, args[1+i]) {
+			return fmt.Errorf("go %s is only allowed with %s", args[0], strings.Join(subcommands, ", "))
+		}
+	}
+	absWorkspace, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace directory: %w", err)
+	}
+	for i := 1; i < len(args); i++ {
+		// The arguments after -args go to the test binary
+		if args[i] == "-args" || args[i] == "--args" {
+			break
+		}
+		if !strings.HasPrefix(args[i], "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(args[i], "-"), "=")
+		name = strings.TrimPrefix(name, "test.")
+		switch {
+		case slices.Contains(deniedGoFlags, name):
+			return fmt.Errorf("go flag -%s is not allowed", name)
+		case args[0] == "env" && (name == "w" || name == "u"):
+			return fmt.Errorf("go env -%s is not allowed", name)
+		case slices.Contains(goPathFlags, name):
+			if !hasValue {
+				if i+1 == len(args) {
+					continue
+				}
+				i++
+				value = args[i]
+			}
+			if !filepath.IsAbs(value) {
+				value = filepath.Join(dir, value)
+			}
+			rel, err := filepath.Rel(absWorkspace, value)
+			if err == nil {
+				_, err = resolveWorkspacePath(workspaceDir, rel)
+			}
+			if err != nil {
+				return fmt.Errorf("go flag -%s must name a path in the workspace: %w", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// truncateOutput converts command output to a string, keeping at most MaxCommandOutput bytes
+func truncateOutput(b []byte) string {
+	if len(b) <= MaxCommandOutput {
+		return string(b)
+	}
+	return string(b[:MaxCommandOutput]) + fmt.Sprintf("\n... output truncated (%d bytes total)", len(b))
+}
+
+// ExecTool creates a new exec tool that runs allowed commands within the workspace directory
+func ExecTool() tool.Tool {
+	return NewExecToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewExecToolWithWorkspace creates a new exec tool with a custom workspace directory
+func NewExecToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "exec",
+			Description: fmt.Sprintf("Run a command in the workspace directory and return its output and exit code. Allowed commands: %v. go runs only the subcommands build, test, vet, fmt, get, list, env, version, mod, and tool cover, and writes only within the workspace.", AllowedCommands),
+		},
+		func(ctx tool.Context, input ExecInput) *ExecOutput {
+			observe := observeTool("exec")
+			output, err := RunCommand(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &ExecOutput{
+					ExitCode: -1,
+					Error:    err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create exec tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,193 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        ExecInput
+		setupFunc    func(t *testing.T, workspaceDir string)
+		wantErr      bool
+		errContains  string
+		wantSuccess  bool
+		wantStdout   string
+		wantStderr   string
+		wantExitCode int
+	}{
+		{
+			name:        "go version",
+			input:       ExecInput{Command: "go", Args: []string{"version"}},
+			wantSuccess: true,
+			wantStdout:  "go version",
+		},
+		{
+			name:        "command not allowed",
+			input:       ExecInput{Command: "rm", Args: []string{"-rf", "."}},
+			wantErr:     true,
+			errContains: "command not allowed",
+		},
+		{
+			name:        "directory traversal",
+			input:       ExecInput{Command: "go", Args: []string{"version"}, Dir: "../.."},
+			wantErr:     true,
+			errContains: "path traversal",
+		},
+		{
+			name:        "go run not allowed",
+			input:       ExecInput{Command: "go", Args: []string{"run", "main.go"}},
+			wantErr:     true,
+			errContains: "go run is not allowed",
+		},
+		{
+			name:        "build output outside the workspace",
+			input:       ExecInput{Command: "go", Args: []string{"build", "-o", "/tmp/agi-exec-test", "./..."}},
+			wantErr:     true,
+			errContains: "go flag -o must name a path in the workspace",
+		},
+		{
+			name:  "build failure reports exit code",
+			input: ExecInput{Command: "go", Args: []string{"build", "./..."}},
+			setupFunc: func(t *testing.T, workspaceDir string) {
+				t.Helper()
+				writeTestFile(t, workspaceDir, "go.mod", "module example.com/broken\n\ngo 1.21\n")
+				writeTestFile(t, workspaceDir, "main.go", "package main\n\nfunc main() { undefinedFunc() }\n")
+			},
+			wantSuccess:  false,
+			wantStderr:   "undefined: undefinedFunc",
+			wantExitCode: 1,
+		},
+		{
+			name:  "runs in subdirectory",
+			input: ExecInput{Command: "gofmt", Args: []string{"-l", "."}, Dir: "pkg"},
+			setupFunc: func(t *testing.T, workspaceDir string) {
+				t.Helper()
+				writeTestFile(t, workspaceDir, "pkg/ugly.go", "package pkg\nfunc  F( ) {}\n")
+			},
+			wantSuccess: true,
+			wantStdout:  "ugly.go",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			if tt.setupFunc != nil {
+				tt.setupFunc(t, workspaceDir)
+			}
+
+			output, err := RunCommand(context.Background(), workspaceDir, tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RunCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				if !strings.Contains(err.Error(), tt.errContains) {
+					t.Errorf("RunCommand() error = %v, want error containing %q", err, tt.errContains)
+				}
+				return
+			}
+
+			if output.Success != tt.wantSuccess {
+				t.Errorf("Success = %v, want %v (stderr: %s)", output.Success, tt.wantSuccess, output.Stderr)
+			}
+			if output.ExitCode != tt.wantExitCode {
+				t.Errorf("ExitCode = %d, want %d", output.ExitCode, tt.wantExitCode)
+			}
+			if !strings.Contains(output.Stdout, tt.wantStdout) {
+				t.Errorf("Stdout = %q, want it to contain %q", output.Stdout, tt.wantStdout)
+			}
+			if !strings.Contains(output.Stderr, tt.wantStderr) {
+				t.Errorf("Stderr = %q, want it to contain %q", output.Stderr, tt.wantStderr)
+			}
+		})
+	}
+}
+
+func TestCheckGoCommand(t *testing.T) {
+	workspaceDir := t.TempDir()
+	subDir := filepath.Join(workspaceDir, "pkg")
+
+	tests := []struct {
+		name        string
+		dir         string
+		args        []string
+		errContains string
+	}{
+		{name: "build", args: []string{"build", "./..."}},
+		{name: "test with coverage", args: []string{"test", "-coverprofile=.cover/cover.out", "./..."}},
+		{name: "build output in a subdirectory", dir: subDir, args: []string{"build", "-o", "../bin/app", "."}},
+		{name: "mod tidy", args: []string{"mod", "tidy"}},
+		{name: "tool cover", args: []string{"tool", "cover", "-func=cover.out"}},
+		{name: "read env", args: []string{"env", "GOOS"}},
+		{name: "test binary args", args: []string{"test", ".", "-args", "-o", "/tmp/out"}},
+		{name: "no subcommand", errContains: "go command is required"},
+		{name: "run", args: []string{"run", "."}, errContains: "go run is not allowed"},
+		{name: "generate", args: []string{"generate", "./..."}, errContains: "go generate is not allowed"},
+		{name: "install", args: []string{"install", "."}, errContains: "go install is not allowed"},
+		{name: "write env", args: []string{"env", "-w", "GOFLAGS=-toolexec=sh"}, errContains: "go env -w is not allowed"},
+		{name: "mod subcommand", args: []string{"mod", "vendor"}, errContains: "go mod is only allowed with"},
+		{name: "other tool", args: []string{"tool", "compile"}, errContains: "go tool is only allowed with"},
+		{name: "toolexec", args: []string{"build", "-toolexec=sh", "."}, errContains: "go flag -toolexec is not allowed"},
+		{name: "test exec", args: []string{"test", "--exec", "sh", "."}, errContains: "go flag -exec is not allowed"},
+		{name: "absolute output", args: []string{"build", "-o=/usr/local/bin/app", "."}, errContains: "go flag -o must name a path in the workspace"},
+		{name: "output traversal", dir: subDir, args: []string{"build", "-o", "../../app", "."}, errContains: "go flag -o must name a path in the workspace"},
+		{name: "directory outside", args: []string{"-C", "/etc", "build"}, errContains: "go -C is not allowed"},
+		{name: "profile outside", args: []string{"test", "-test.cpuprofile=/tmp/cpu.out", "."}, errContains: "go flag -cpuprofile must name a path in the workspace"},
+		{name: "git directory", args: []string{"build", "-o", ".git/hooks/post-commit", "."}, errContains: "in the git directory"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGoCommand(workspaceDir, cmp.Or(tt.dir, workspaceDir), tt.args)
+			if tt.errContains == "" && err != nil {
+				t.Errorf("checkGoCommand(%q) error = %v", tt.args, err)
+			}
+			if tt.errContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errContains)) {
+				t.Errorf("checkGoCommand(%q) error = %v, want it to contain %q", tt.args, err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	short := []byte("hello")
+	if got := truncateOutput(short); got != "hello" {
+		t.Errorf("truncateOutput(short) = %q, want %q", got, "hello")
+	}
+
+	long := make([]byte, MaxCommandOutput+10)
+	got := truncateOutput(long)
+	if !strings.Contains(got, "output truncated") {
+		t.Error("truncateOutput(long) did not mark the output as truncated")
+	}
+	if len(got) > MaxCommandOutput+100 {
+		t.Errorf("truncateOutput(long) length = %d, want about %d", len(got), MaxCommandOutput)
+	}
+}
+
+func TestExecTool(t *testing.T) {
+	if ExecTool() == nil {
+		t.Fatal("ExecTool() returned nil")
+	}
+	if got := NewExecToolWithWorkspace(t.TempDir()).Name(); got != "exec" {
+		t.Errorf("Name() = %q, want %q", got, "exec")
+	}
+}
+
+// writeTestFile writes content to path relative to dir, creating parent directories
+func writeTestFile(t *testing.T, dir, path, content string) {
+	t.Helper()
+	full := filepath.Join(dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
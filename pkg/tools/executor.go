@@ -0,0 +1,145 @@
+package tools
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// Defaults of DockerExecutor
+const (
+	// DefaultSandboxNetwork is the container network: none blocks all network access
+	DefaultSandboxNetwork = "none"
+	// sandboxWorkspace is where the workspace is mounted in the container
+	sandboxWorkspace = "/workspace"
+)
+
+// Executor creates the processes that RunCommand runs
+type Executor interface {
+	// Command returns a command that runs name with args in dir; workspaceDir and dir are
+	// absolute, and dir is within workspaceDir
+	Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd
+}
+
+// HostExecutor runs commands directly on the host. It is the default executor.
+type HostExecutor struct{}
+
+// Command returns a command that runs name on the host
+func (HostExecutor) Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	return cmd
+}
+
+// DockerExecutor runs each command in a throwaway container that sees only the workspace,
+// bind-mounted at /workspace, so untrusted generated code cannot touch the host. The
+// container runs as the current user, so files it creates stay editable.
+type DockerExecutor struct {
+	// Image is the container image; it must provide the commands that are run, such as go
+	Image string
+	// Network is the container network (defaults to none, which blocks module downloads)
+	Network string
+	// Memory limits the container memory, such as 2g (empty leaves it unlimited)
+	Memory string
+	// CPUs limits the CPUs the container may use, such as 1.5 (empty leaves it unlimited)
+	CPUs string
+	// PidsLimit limits the number of processes in the container (0 leaves it unlimited)
+	PidsLimit int
+	// ModCacheDir is a host Go module cache mounted into the container, so commands do not
+	// download modules every time (empty disables)
+	ModCacheDir string
+	// Docker is the docker CLI (defaults to docker)
+	Docker string
+}
+
+// Command returns a docker run command that runs name in a new container
+func (e *DockerExecutor) Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd {
+	docker := e.Docker
+	if docker == "" {
+		docker = "docker"
+	}
+	network := e.Network
+	if network == "" {
+		network = DefaultSandboxNetwork
+	}
+	workdir := sandboxWorkspace
+	if rel, err := filepath.Rel(workspaceDir, dir); err == nil {
+		workdir = path.Join(sandboxWorkspace, filepath.ToSlash(rel))
+	}
+
+	container := "agi-exec-" + randomSuffix()
+	runArgs := []string{"run", "--rm", "--name", container,
+		"--network", network,
+		"--volume", workspaceDir + ":" + sandboxWorkspace,
+		"--workdir", workdir,
+		// The user may have no home directory in the image, so keep the Go caches in /tmp
+		"--env", "HOME=/tmp",
+		"--env", "GOCACHE=/tmp/go-build",
+		"--env", "GOPATH=/tmp/go",
+	}
+	if uid, gid := os.Getuid(), os.Getgid(); uid >= 0 {
+		runArgs = append(runArgs, "--user", strconv.Itoa(uid)+":"+strconv.Itoa(gid))
+	}
+	if e.Memory != "" {
+		runArgs = append(runArgs, "--memory", e.Memory)
+	}
+	if e.CPUs != "" {
+		runArgs = append(runArgs, "--cpus", e.CPUs)
+	}
+	if e.PidsLimit > 0 {
+		runArgs = append(runArgs, "--pids-limit", strconv.Itoa(e.PidsLimit))
+	}
+	if e.ModCacheDir != "" {
+		runArgs = append(runArgs, "--volume", e.ModCacheDir+":/tmp/go/pkg/mod")
+	}
+	runArgs = append(append(runArgs, e.Image, name), args...)
+
+	cmd := exec.CommandContext(ctx, docker, runArgs...)
+	cmd.Dir = workspaceDir
+	// Killing the docker CLI on a timeout leaves the container running, so remove it too
+	cmd.Cancel = func() error {
+		_ = exec.Command(docker, "rm", "--force", container).Run()
+		return cmd.Process.Kill()
+	}
+	return cmd
+}
+
+// randomSuffix returns a random hex string that makes container names unique
+func randomSuffix() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// executors maps absolute workspace directories to the executor of their commands
+var executors sync.Map
+
+// SetExecutor makes RunCommand, and so every tool that runs commands, use executor for
+// the commands it runs in workspaceDir. A nil executor restores the HostExecutor.
+func SetExecutor(workspaceDir string, executor Executor) error {
+	absWorkspace, err := filepath.Abs(workspaceDir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace directory: %w", err)
+	}
+	if executor == nil {
+		executors.Delete(absWorkspace)
+		return nil
+	}
+	executors.Store(absWorkspace, executor)
+	return nil
+}
+
+// executorFor returns the executor of the commands run in the absolute workspaceDir
+func executorFor(workspaceDir string) Executor {
+	if executor, ok := executors.Load(workspaceDir); ok {
+		return executor.(Executor)
+	}
+	return HostExecutor{}
+}
@@ -0,0 +1,95 @@
+package tools
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDockerExecutor_Command(t *testing.T) {
+	workspaceDir := t.TempDir()
+	executor := &DockerExecutor{
+		Image:       "golang:1.25",
+		Memory:      "2g",
+		CPUs:        "1.5",
+		PidsLimit:   256,
+		ModCacheDir: "/cache/mod",
+	}
+
+	cmd := executor.Command(context.Background(), workspaceDir, filepath.Join(workspaceDir, "pkg", "calc"), "go", "test", "./...")
+	args := strings.Join(cmd.Args, " ")
+	for _, want := range []string{
+		"docker run --rm --name agi-exec-",
+		"--network none",
+		"--volume " + workspaceDir + ":/workspace",
+		"--workdir /workspace/pkg/calc",
+		"--memory 2g",
+		"--cpus 1.5",
+		"--pids-limit 256",
+		"--volume /cache/mod:/tmp/go/pkg/mod",
+		"golang:1.25 go test ./...",
+	} {
+		if !strings.Contains(args, want) {
+			t.Errorf("command = %q, want it to contain %q", args, want)
+		}
+	}
+	if cmd.Cancel == nil {
+		t.Error("Cancel is nil, want the container removed on timeout")
+	}
+
+	// Limits are only set when configured
+	cmd = (&DockerExecutor{Image: "golang", Network: "bridge", Docker: "podman"}).Command(context.Background(), workspaceDir, workspaceDir, "go", "build")
+	args = strings.Join(cmd.Args, " ")
+	if !strings.HasPrefix(args, "podman run") || !strings.Contains(args, "--network bridge") || !strings.Contains(args, "--workdir /workspace ") {
+		t.Errorf("command = %q, want podman on the bridge network in /workspace", args)
+	}
+	for _, flag := range []string{"--memory", "--cpus", "--pids-limit"} {
+		if slices.Contains(cmd.Args, flag) {
+			t.Errorf("command = %q, want no %s", args, flag)
+		}
+	}
+}
+
+// recordingExecutor runs commands on the host and records their names
+type recordingExecutor struct {
+	names []string
+}
+
+func (e *recordingExecutor) Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd {
+	e.names = append(e.names, name)
+	return HostExecutor{}.Command(ctx, workspaceDir, dir, name, args...)
+}
+
+func TestSetExecutor(t *testing.T) {
+	workspaceDir := t.TempDir()
+	executor := &recordingExecutor{}
+	if err := SetExecutor(workspaceDir, executor); err != nil {
+		t.Fatalf("SetExecutor() error = %v", err)
+	}
+	defer SetExecutor(workspaceDir, nil)
+
+	output, err := RunCommand(context.Background(), workspaceDir, ExecInput{Command: "go", Args: []string{"env", "GOOS"}})
+	if err != nil || !output.Success {
+		t.Fatalf("RunCommand() = %+v, %v, want success", output, err)
+	}
+	if !slices.Equal(executor.names, []string{"go"}) {
+		t.Errorf("executor ran %v, want go", executor.names)
+	}
+
+	// Other workspaces, and the workspace after a reset, run on the host
+	if _, err := RunCommand(context.Background(), t.TempDir(), ExecInput{Command: "go", Args: []string{"env", "GOOS"}}); err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+	if err := SetExecutor(workspaceDir, nil); err != nil {
+		t.Fatalf("SetExecutor(nil) error = %v", err)
+	}
+	if _, err := RunCommand(context.Background(), workspaceDir, ExecInput{Command: "go", Args: []string{"env", "GOOS"}}); err != nil {
+		t.Fatalf("RunCommand() error = %v", err)
+	}
+	if len(executor.names) != 1 {
+		t.Errorf("executor ran %v, want only the first command", executor.names)
+	}
+}
@@ -0,0 +1,179 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Edit modes of the fileEdit tool
+const (
+	// FileEditAppend adds the content after the last line, creating the file if needed
+	FileEditAppend = "append"
+	// FileEditInsert adds the content before a line
+	FileEditInsert = "insert"
+	// FileEditReplace replaces a line range with the content, or deletes it for empty content
+	FileEditReplace = "replace"
+)
+
+// FileEditInput defines the input parameters for the fileEdit tool
+type FileEditInput struct {
+	// Path is the relative path to the file to edit (within the workspace directory)
+	Path string `json:"path"`
+	// Mode is append, insert, or replace
+	Mode string `json:"mode"`
+	// Content are the lines to add; a trailing newline is optional
+	Content string `json:"content"`
+	// Line is the line the content is inserted before in insert mode; the line after the last one appends
+	Line int `json:"line,omitempty"`
+	// StartLine is the first line replaced in replace mode
+	StartLine int `json:"startLine,omitempty"`
+	// EndLine is the last line replaced in replace mode, inclusive (defaults to StartLine)
+	EndLine int `json:"endLine,omitempty"`
+}
+
+// FileEditOutput defines the output structure for the fileEdit tool
+type FileEditOutput struct {
+	// Path is the path of the file that was edited
+	Path string `json:"path,omitempty"`
+	// TotalLines is the number of lines in the file after the edit
+	TotalLines int `json:"totalLines"`
+	// Redacted are the kinds of likely secrets that were redacted before writing
+	Redacted []string `json:"redacted,omitempty"`
+	// Success indicates whether the edit was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeFileEdit is the core logic for editing files, extracted for testability
+func executeFileEdit(workspaceDir string, config Config, input FileEditInput) (*FileEditOutput, error) {
+	start := time.Now()
+	slog.Info("Starting file edit operation",
+		"path", input.Path,
+		"mode", input.Mode,
+		"content_size_bytes", len(input.Content),
+		"workspace", workspaceDir)
+
+	if err := config.checkWritable("fileEdit"); err != nil {
+		return nil, err
+	}
+	maxSize := config.maxFileSize()
+	if int64(len(input.Content)) > maxSize {
+		return nil, fmt.Errorf("content too large: %d bytes (max %d bytes)", len(input.Content), maxSize)
+	}
+	text, redacted, err := config.scanSecrets(input.Path, input.Content)
+	if err != nil {
+		return nil, err
+	}
+	var added []string
+	if text != "" {
+		added = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	// The file is locked from reading to writing, so concurrent edits are not lost
+	resolvedPath, err := config.resolveFile(workspaceDir, input.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	unlock := lockPaths(resolvedPath)
+	defer unlock()
+
+	content, err := readFileLines(workspaceDir, config, input.Path, nil)
+	switch {
+	case err == nil:
+	case errors.Is(err, os.ErrNotExist) && input.Mode == FileEditAppend:
+		content = &fileLines{eofNewline: true}
+	default:
+		slog.Error("Failed to read file",
+			"path", input.Path,
+			"error", err)
+		return nil, err
+	}
+	lines := content.lines
+
+	switch input.Mode {
+	case FileEditAppend:
+		lines = append(lines, added...)
+	case FileEditInsert:
+		if input.Line < 1 || input.Line > len(lines)+1 {
+			return nil, fmt.Errorf("line %d is out of range: the file has %d lines", input.Line, len(lines))
+		}
+		if len(added) == 0 {
+			return nil, fmt.Errorf("content is required in insert mode")
+		}
+		lines = append(lines[:input.Line-1], append(added, lines[input.Line-1:]...)...)
+	case FileEditReplace:
+		end := input.EndLine
+		if end == 0 {
+			end = input.StartLine
+		}
+		if input.StartLine < 1 || end < input.StartLine || end > len(lines) {
+			return nil, fmt.Errorf("lines %d-%d are out of range: the file has %d lines", input.StartLine, end, len(lines))
+		}
+		lines = append(lines[:input.StartLine-1], append(added, lines[end:]...)...)
+	default:
+		return nil, fmt.Errorf("unknown mode %q (use %s, %s, or %s)", input.Mode, FileEditAppend, FileEditInsert, FileEditReplace)
+	}
+
+	// Edits always end the file with a newline
+	edited := &fileLines{lines: lines, eofNewline: true}
+	if size := int64(len(strings.Join(lines, "\n"))); size > maxSize {
+		return nil, fmt.Errorf("edited file too large: %d bytes (max %d bytes)", size, maxSize)
+	}
+	if err := writeFileLines(workspaceDir, config, input.Path, edited); err != nil {
+		slog.Error("Failed to write file",
+			"path", input.Path,
+			"error", err)
+		return nil, err
+	}
+
+	slog.Info("File edit completed successfully",
+		"path", input.Path,
+		"mode", input.Mode,
+		"total_lines", len(lines),
+		"duration_ms", time.Since(start).Milliseconds())
+	return &FileEditOutput{Path: input.Path, TotalLines: len(lines), Redacted: redacted, Success: true}, nil
+}
+
+// FileEditTool creates a new fileEdit tool that edits files within the workspace directory
+func FileEditTool() tool.Tool {
+	return NewFileEditToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewFileEditToolWithWorkspace creates a new fileEdit tool with a custom workspace directory
+func NewFileEditToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewFileEditToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewFileEditToolWithConfig creates a new fileEdit tool with a custom workspace directory and configuration
+func NewFileEditToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "fileEdit",
+			Description: "Edit part of a file in the workspace directory without rewriting it: mode append adds lines at the end, insert adds lines before line, and replace replaces lines startLine to endLine (empty content deletes them). Line numbers start at 1; read the file first to get them. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input FileEditInput) *FileEditOutput {
+			observe := observeTool("fileEdit")
+			output, err := executeFileEdit(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &FileEditOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create fileEdit tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,164 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileEditTool(t *testing.T) {
+	const original = "line 1\nline 2\nline 3\n"
+
+	tests := []struct {
+		name        string
+		original    string
+		input       FileEditInput
+		want        string
+		wantLines   int
+		errContains string
+	}{
+		{
+			name:      "append",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditAppend, Content: "line 4\nline 5\n"},
+			want:      "line 1\nline 2\nline 3\nline 4\nline 5\n",
+			wantLines: 5,
+		},
+		{
+			name:      "append to a file without trailing newline",
+			original:  "line 1",
+			input:     FileEditInput{Mode: FileEditAppend, Content: "line 2"},
+			want:      "line 1\nline 2\n",
+			wantLines: 2,
+		},
+		{
+			name:      "append creates the file",
+			input:     FileEditInput{Mode: FileEditAppend, Content: "package calc"},
+			want:      "package calc\n",
+			wantLines: 1,
+		},
+		{
+			name:      "insert at the start",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditInsert, Line: 1, Content: "// header"},
+			want:      "// header\nline 1\nline 2\nline 3\n",
+			wantLines: 4,
+		},
+		{
+			name:      "insert after the last line",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditInsert, Line: 4, Content: "line 4"},
+			want:      "line 1\nline 2\nline 3\nline 4\n",
+			wantLines: 4,
+		},
+		{
+			name:      "replace a range",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditReplace, StartLine: 2, EndLine: 3, Content: "two\nthree\nfour\n"},
+			want:      "line 1\ntwo\nthree\nfour\n",
+			wantLines: 4,
+		},
+		{
+			name:      "replace a single line",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditReplace, StartLine: 2, Content: "two"},
+			want:      "line 1\ntwo\nline 3\n",
+			wantLines: 3,
+		},
+		{
+			name:      "delete lines",
+			original:  original,
+			input:     FileEditInput{Mode: FileEditReplace, StartLine: 1, EndLine: 2},
+			want:      "line 3\n",
+			wantLines: 1,
+		},
+		{
+			name:        "insert out of range",
+			original:    original,
+			input:       FileEditInput{Mode: FileEditInsert, Line: 5, Content: "x"},
+			want:        original,
+			errContains: "line 5 is out of range: the file has 3 lines",
+		},
+		{
+			name:        "insert without content",
+			original:    original,
+			input:       FileEditInput{Mode: FileEditInsert, Line: 1},
+			want:        original,
+			errContains: "content is required",
+		},
+		{
+			name:        "replace out of range",
+			original:    original,
+			input:       FileEditInput{Mode: FileEditReplace, StartLine: 2, EndLine: 4, Content: "x"},
+			want:        original,
+			errContains: "lines 2-4 are out of range",
+		},
+		{
+			name:        "insert into a missing file",
+			input:       FileEditInput{Mode: FileEditInsert, Line: 1, Content: "x"},
+			errContains: "failed to read file",
+		},
+		{
+			name:        "unknown mode",
+			original:    original,
+			input:       FileEditInput{Mode: "prepend", Content: "x"},
+			want:        original,
+			errContains: `unknown mode "prepend"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			path := filepath.Join(workspaceDir, "file.txt")
+			if tt.original != "" {
+				if err := os.WriteFile(path, []byte(tt.original), 0644); err != nil {
+					t.Fatalf("failed to create test file: %v", err)
+				}
+			}
+
+			tt.input.Path = "file.txt"
+			output, err := executeFileEdit(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeFileEdit() error = %v, want it to contain %q", err, tt.errContains)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("executeFileEdit() error = %v", err)
+				}
+				if !output.Success || output.TotalLines != tt.wantLines || output.Path != "file.txt" {
+					t.Errorf("executeFileEdit() = %+v, want success with %d lines", output, tt.wantLines)
+				}
+			}
+
+			if tt.want == "" {
+				return
+			}
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read file: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileEditTool_PathTraversal(t *testing.T) {
+	_, err := executeFileEdit(t.TempDir(), ToolConfig, FileEditInput{Path: "../evil.txt", Mode: FileEditAppend, Content: "x"})
+	if err == nil || !strings.Contains(err.Error(), "path traversal detected") {
+		t.Errorf("executeFileEdit() error = %v, want a path traversal error", err)
+	}
+}
+
+func TestFileEditTool_ToolCreation(t *testing.T) {
+	if FileEditTool() == nil {
+		t.Fatal("FileEditTool() returned nil")
+	}
+	if got := NewFileEditToolWithWorkspace(t.TempDir()).Name(); got != "fileEdit" {
+		t.Errorf("Name() = %q, want %q", got, "fileEdit")
+	}
+}
@@ -0,0 +1,237 @@
+package tools
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxFileListEntries is the maximum number of entries returned by the fileList tool
+const MaxFileListEntries = 1000
+
+// errFileListLimit stops the walk of executeFileList once MaxFileListEntries entries are found
+var errFileListLimit = errors.New("file list entry limit reached")
+
+// FileListInput defines the input parameters for the fileList tool
+type FileListInput struct {
+	// Path is the relative path of the directory to list (defaults to the workspace root)
+	Path string `json:"path,omitempty"`
+	// Pattern is a glob that entries must match, such as *.go or cmd/**/*_test.go; patterns
+	// without a slash match the base name, and ** matches any number of directories
+	Pattern string `json:"pattern,omitempty"`
+	// Recursive lists the entries of subdirectories too; patterns with a slash or ** imply it
+	Recursive bool `json:"recursive,omitempty"`
+}
+
+// FileEntry describes a file or directory in the workspace
+type FileEntry struct {
+	// Path is the slash-separated path of the entry, relative to the listed directory
+	Path string `json:"path"`
+	// IsDir indicates whether the entry is a directory
+	IsDir bool `json:"isDir,omitempty"`
+	// Size is the file size in bytes
+	Size int64 `json:"size"`
+	// ModTime is the last modification time in RFC 3339 format
+	ModTime string `json:"modTime"`
+}
+
+// FileListOutput defines the output structure for the fileList tool
+type FileListOutput struct {
+	// Path is the directory that was listed
+	Path string `json:"path,omitempty"`
+	// Entries are the matching files and directories in lexical order
+	Entries []FileEntry `json:"entries"`
+	// Truncated indicates that only the first MaxFileListEntries entries are returned
+	Truncated bool `json:"truncated,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeFileList is the core logic for listing files, extracted for testability
+func executeFileList(workspaceDir string, config Config, input FileListInput) (*FileListOutput, error) {
+	start := time.Now()
+	slog.Info("Starting file list operation",
+		"path", input.Path,
+		"pattern", input.Pattern,
+		"recursive", input.Recursive,
+		"workspace", workspaceDir)
+
+	pattern := filepath.ToSlash(input.Pattern)
+	if !validGlob(pattern) {
+		return nil, fmt.Errorf("invalid pattern %q", input.Pattern)
+	}
+	recursive := input.Recursive || strings.Contains(pattern, "/") || strings.Contains(pattern, "**")
+
+	dir := cmp.Or(input.Path, ".")
+	resolvedDir, err := resolveWorkspacePath(workspaceDir, dir)
+	if err != nil {
+		slog.Error("Failed to resolve path",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := config.checkPath(dir); err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(resolvedDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("failed to list %s: not a directory", dir)
+	}
+	workspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	ignore, err := loadIgnore(workspace, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIgnored(workspaceDir, config, dir, true); err != nil {
+		return nil, err
+	}
+
+	output := &FileListOutput{Path: dir, Entries: []FileEntry{}}
+	err = filepath.WalkDir(resolvedDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == resolvedDir {
+			return nil
+		}
+		rel, err := filepath.Rel(resolvedDir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		// Denied and ignored paths are left out of the listing
+		if wsRel, err := filepath.Rel(workspace, p); err == nil && (config.denied(filepath.ToSlash(wsRel)) || ignore.ignored(filepath.ToSlash(wsRel), d.IsDir())) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// The .git directory is listed but never descended into
+		descend := recursive && d.Name() != ".git"
+
+		if pattern == "" || matchGlob(pattern, rel) {
+			if len(output.Entries) == MaxFileListEntries {
+				output.Truncated = true
+				return errFileListLimit
+			}
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			entry := FileEntry{Path: rel, IsDir: d.IsDir(), ModTime: info.ModTime().UTC().Format(time.RFC3339)}
+			if !d.IsDir() {
+				entry.Size = info.Size()
+			}
+			output.Entries = append(output.Entries, entry)
+		}
+		if d.IsDir() && !descend {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errFileListLimit) {
+		slog.Error("Failed to list files",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to list %s: %w", dir, err)
+	}
+
+	slog.Info("File list completed successfully",
+		"path", input.Path,
+		"entries", len(output.Entries),
+		"truncated", output.Truncated,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// matchGlob reports whether the slash-separated path rel matches pattern. A pattern
+// without a slash matches the base name; ** matches zero or more directories.
+func matchGlob(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") && pattern != "**" {
+		pattern = "**/" + pattern
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(rel, "/"))
+}
+
+// validGlob reports whether every segment of the slash-separated glob is a valid pattern
+func validGlob(glob string) bool {
+	for _, segment := range strings.Split(glob, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern[1:], segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
+
+// FileListTool creates a new fileList tool that lists files within the workspace directory
+func FileListTool() tool.Tool {
+	return NewFileListToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewFileListToolWithWorkspace creates a new fileList tool with a custom workspace directory
+func NewFileListToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewFileListToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewFileListToolWithConfig creates a new fileList tool with a custom workspace directory and configuration
+func NewFileListToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "fileList",
+			Description: "List files and directories in the workspace directory with their size and modification time. Filter with a glob pattern such as *.go or **/*_test.go and set recursive to include subdirectories. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input FileListInput) *FileListOutput {
+			observe := observeTool("fileList")
+			output, err := executeFileList(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &FileListOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create fileList tool: %v", err))
+	}
+	return t
+}
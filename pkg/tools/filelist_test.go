@@ -0,0 +1,210 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileListTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":                   "module example.com/calc\n",
+		"calc.go":                  "package calc\n",
+		"calc_test.go":             "package calc\n",
+		"cmd/calc/main.go":         "package main\n",
+		"cmd/calc/main_test.go":    "package main\n",
+		"internal/util/util.go":    "package util\n",
+		".github/workflows/ci.yml": "on: push\n",
+		".git/HEAD":                "ref: refs/heads/main\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		input       FileListInput
+		want        []string
+		errContains string
+	}{
+		{
+			name:  "top level",
+			input: FileListInput{},
+			want:  []string{".git", ".github", "calc.go", "calc_test.go", "cmd", "go.mod", "internal"},
+		},
+		{
+			name:  "base name pattern",
+			input: FileListInput{Pattern: "*.go"},
+			want:  []string{"calc.go", "calc_test.go"},
+		},
+		{
+			name:  "recursive base name pattern",
+			input: FileListInput{Pattern: "*_test.go", Recursive: true},
+			want:  []string{"calc_test.go", "cmd/calc/main_test.go"},
+		},
+		{
+			name:  "double star pattern",
+			input: FileListInput{Pattern: "cmd/**/*.go"},
+			want:  []string{"cmd/calc/main.go", "cmd/calc/main_test.go"},
+		},
+		{
+			name:  "path pattern",
+			input: FileListInput{Pattern: "*/*/*.go"},
+			want:  []string{"cmd/calc/main.go", "cmd/calc/main_test.go", "internal/util/util.go"},
+		},
+		{
+			name:  "subdirectory",
+			input: FileListInput{Path: "cmd", Recursive: true},
+			want:  []string{"calc", "calc/main.go", "calc/main_test.go"},
+		},
+		{
+			name:  "recursive skips .git",
+			input: FileListInput{Path: ".", Pattern: "**/*.yml"},
+			want:  []string{".github/workflows/ci.yml"},
+		},
+		{
+			name:  "no matches",
+			input: FileListInput{Pattern: "*.rs", Recursive: true},
+			want:  []string{},
+		},
+		{
+			name:        "invalid pattern",
+			input:       FileListInput{Pattern: "[a-"},
+			errContains: "invalid pattern",
+		},
+		{
+			name:        "path traversal",
+			input:       FileListInput{Path: "../"},
+			errContains: "path traversal detected",
+		},
+		{
+			name:        "missing directory",
+			input:       FileListInput{Path: "missing"},
+			errContains: "failed to list missing",
+		},
+		{
+			name:        "file",
+			input:       FileListInput{Path: "calc.go"},
+			errContains: "not a directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeFileList(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeFileList() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeFileList() error = %v", err)
+			}
+			got := []string{}
+			for _, entry := range output.Entries {
+				got = append(got, entry.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("entries = %v, want %v", got, tt.want)
+			}
+			if output.Truncated {
+				t.Errorf("Truncated = true, want false")
+			}
+		})
+	}
+}
+
+func TestFileListTool_Metadata(t *testing.T) {
+	workspaceDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(workspaceDir, "pkg"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	path := filepath.Join(workspaceDir, "calc.go")
+	if err := os.WriteFile(path, []byte("package calc\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+
+	output, err := executeFileList(workspaceDir, ToolConfig, FileListInput{})
+	if err != nil {
+		t.Fatalf("executeFileList() error = %v", err)
+	}
+	want := []FileEntry{
+		{Path: "calc.go", Size: 13, ModTime: "2025-03-01T12:00:00Z"},
+		{Path: "pkg", IsDir: true, ModTime: output.Entries[1].ModTime},
+	}
+	if !slices.Equal(output.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", output.Entries, want)
+	}
+	if output.Path != "." {
+		t.Errorf("Path = %q, want %q", output.Path, ".")
+	}
+}
+
+func TestFileListTool_Truncated(t *testing.T) {
+	workspaceDir := t.TempDir()
+	for i := range MaxFileListEntries + 5 {
+		name := filepath.Join(workspaceDir, fmt.Sprintf("f%04d.txt", i))
+		if err := os.WriteFile(name, nil, 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	output, err := executeFileList(workspaceDir, ToolConfig, FileListInput{})
+	if err != nil {
+		t.Fatalf("executeFileList() error = %v", err)
+	}
+	if len(output.Entries) != MaxFileListEntries || !output.Truncated {
+		t.Errorf("got %d entries, truncated %v, want %d truncated", len(output.Entries), output.Truncated, MaxFileListEntries)
+	}
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{pattern: "*.go", path: "calc.go", want: true},
+		{pattern: "*.go", path: "pkg/calc.go", want: true},
+		{pattern: "*.go", path: "pkg", want: false},
+		{pattern: "pkg/*.go", path: "pkg/calc.go", want: true},
+		{pattern: "pkg/*.go", path: "pkg/sub/calc.go", want: false},
+		{pattern: "pkg/**", path: "pkg/sub/calc.go", want: true},
+		{pattern: "**/testdata/*", path: "a/b/testdata/in.txt", want: true},
+		{pattern: "**/testdata/*", path: "testdata/in.txt", want: true},
+		{pattern: "**", path: "anything/at/all", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			if got := matchGlob(tt.pattern, tt.path); got != tt.want {
+				t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileListTool_ToolCreation(t *testing.T) {
+	if FileListTool() == nil {
+		t.Fatal("FileListTool() returned nil")
+	}
+	if got := NewFileListToolWithWorkspace(t.TempDir()).Name(); got != "fileList" {
+		t.Errorf("Name() = %q, want %q", got, "fileList")
+	}
+}
@@ -0,0 +1,124 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// FileStatInput defines the input parameters for the fileStat tool
+type FileStatInput struct {
+	// Path is the relative path of the file or directory (within the workspace directory)
+	Path string `json:"path"`
+}
+
+// FileStatOutput defines the output structure for the fileStat tool
+type FileStatOutput struct {
+	// Path is the path that was checked
+	Path string `json:"path,omitempty"`
+	// Exists indicates whether the path exists; the other fields are only set when it does
+	Exists bool `json:"exists"`
+	// IsDir indicates whether the path is a directory
+	IsDir bool `json:"isDir,omitempty"`
+	// Size is the file size in bytes
+	Size int64 `json:"size"`
+	// Mode is the file mode and permissions, such as -rw-r--r--
+	Mode string `json:"mode,omitempty"`
+	// ModTime is the last modification time in RFC 3339 format
+	ModTime string `json:"modTime,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeFileStat is the core logic for reading file metadata, extracted for testability
+func executeFileStat(workspaceDir string, config Config, input FileStatInput) (*FileStatOutput, error) {
+	start := time.Now()
+	slog.Info("Starting file stat operation",
+		"path", input.Path,
+		"workspace", workspaceDir)
+
+	resolvedPath, err := resolveWorkspacePath(workspaceDir, input.Path)
+	if err != nil {
+		slog.Error("Failed to resolve path",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := config.checkPath(input.Path); err != nil {
+		return nil, err
+	}
+
+	output := &FileStatOutput{Path: input.Path}
+	info, err := os.Stat(resolvedPath)
+	// A missing path is an answer, not a failure
+	if errors.Is(err, fs.ErrNotExist) {
+		slog.Info("File stat completed successfully",
+			"path", input.Path,
+			"exists", false,
+			"duration_ms", time.Since(start).Milliseconds())
+		return output, nil
+	}
+	if err != nil {
+		slog.Error("Failed to stat file",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to stat %s: %w", input.Path, err)
+	}
+
+	output.Exists = true
+	output.IsDir = info.IsDir()
+	output.Mode = info.Mode().String()
+	output.ModTime = info.ModTime().UTC().Format(time.RFC3339)
+	if !info.IsDir() {
+		output.Size = info.Size()
+	}
+
+	slog.Info("File stat completed successfully",
+		"path", input.Path,
+		"exists", true,
+		"is_dir", output.IsDir,
+		"size_bytes", output.Size,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// FileStatTool creates a new fileStat tool that returns the metadata of a path within the workspace directory
+func FileStatTool() tool.Tool {
+	return NewFileStatToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewFileStatToolWithWorkspace creates a new fileStat tool with a custom workspace directory
+func NewFileStatToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewFileStatToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewFileStatToolWithConfig creates a new fileStat tool with a custom workspace directory and configuration
+func NewFileStatToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "fileStat",
+			Description: "Get the metadata of a file or directory in the workspace without reading it: whether it exists, whether it is a directory, its size in bytes, mode, and modification time. Use it to skip large files or check that a path exists. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input FileStatInput) *FileStatOutput {
+			observe := observeTool("fileStat")
+			output, err := executeFileStat(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &FileStatOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create fileStat tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,91 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFileStatTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"calc.go":       "package calc\n",
+		"scripts/build": "#!/bin/sh\n",
+	})
+	modTime := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(filepath.Join(workspaceDir, "calc.go"), modTime, modTime); err != nil {
+		t.Fatalf("failed to set modification time: %v", err)
+	}
+	// Set the modes explicitly, so the umask does not change them
+	for path, mode := range map[string]os.FileMode{"calc.go": 0644, "scripts": 0755, "scripts/build": 0755} {
+		if err := os.Chmod(filepath.Join(workspaceDir, path), mode); err != nil {
+			t.Fatalf("failed to set the mode of %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		want        FileStatOutput
+		errContains string
+	}{
+		{
+			name: "file",
+			path: "calc.go",
+			want: FileStatOutput{Path: "calc.go", Exists: true, Size: 13, Mode: "-rw-r--r--", ModTime: "2025-03-01T12:00:00Z"},
+		},
+		{
+			name: "executable",
+			path: "scripts/build",
+			want: FileStatOutput{Path: "scripts/build", Exists: true, Size: 10, Mode: "-rwxr-xr-x"},
+		},
+		{
+			name: "directory",
+			path: "scripts",
+			want: FileStatOutput{Path: "scripts", Exists: true, IsDir: true, Mode: "drwxr-xr-x"},
+		},
+		{
+			name: "missing",
+			path: "missing.go",
+			want: FileStatOutput{Path: "missing.go"},
+		},
+		{
+			name:        "path traversal",
+			path:        "../outside.txt",
+			errContains: "path traversal detected",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeFileStat(workspaceDir, ToolConfig, FileStatInput{Path: tt.path})
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeFileStat() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeFileStat() error = %v", err)
+			}
+			// Only the modification time of calc.go is fixed
+			if tt.want.ModTime == "" && output.Exists {
+				tt.want.ModTime = output.ModTime
+			}
+			if *output != tt.want {
+				t.Errorf("executeFileStat() = %+v, want %+v", *output, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileStatTool_ToolCreation(t *testing.T) {
+	if FileStatTool() == nil {
+		t.Fatal("FileStatTool() returned nil")
+	}
+	if got := NewFileStatToolWithWorkspace(t.TempDir()).Name(); got != "fileStat" {
+		t.Errorf("Name() = %q, want %q", got, "fileStat")
+	}
+}
@@ -1,13 +1,21 @@
 package tools
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
@@ -16,24 +24,69 @@ import (
 // DefaultWorkspaceDir is the default directory for file operations
 const DefaultWorkspaceDir = "./workspace"
 
-// MaxFileSize is the maximum file size allowed for read/write operations (10MB)
+// MaxFileSize is the default maximum file size allowed for read/write operations (10MB)
 const MaxFileSize = 10 * 1024 * 1024
 
-// FileOperationTimeout is the timeout for file I/O operations
+// FileOperationTimeout is the default timeout for file I/O operations
 const FileOperationTimeout = 30 * time.Second
 
+// SyncFileWrites makes file writes flush the data to disk before renaming it into place,
+// which survives power loss at the cost of slower writes
+var SyncFileWrites = false
+
+// Symlink policies of Config.SymlinkPolicy
+const (
+	// SymlinkFollow follows symlinks in workspace paths whose targets are within the workspace
+	SymlinkFollow = "follow"
+	// SymlinkDeny rejects workspace paths that go through a symlink
+	SymlinkDeny = "deny"
+)
+
+// Encodings of the content of the fileRead and fileWrite tools
+const (
+	// EncodingUTF8 is plain UTF-8 text, the default
+	EncodingUTF8 = "utf8"
+	// EncodingBase64 is standard base64 of the raw bytes, for binary files such as images
+	EncodingBase64 = "base64"
+)
+
 // FileReadInput defines the input parameters for the fileRead tool
 type FileReadInput struct {
 	// Path is the relative path to the file to read (within the workspace directory)
 	Path string `json:"path"`
+	// StartLine is the first line to read, starting at 1 (0 reads from the beginning)
+	StartLine int `json:"startLine,omitempty"`
+	// EndLine is the last line to read, inclusive (0 reads to the end of the file)
+	EndLine int `json:"endLine,omitempty"`
+	// Encoding is the encoding of the returned content: utf8 (default) or base64 for binary files
+	Encoding string `json:"encoding,omitempty"`
+	// Offset is the byte offset to continue a truncated read from, as returned in nextOffset
+	Offset int `json:"offset,omitempty"`
 }
 
 // FileReadOutput defines the output structure for the fileRead tool
 type FileReadOutput struct {
-	// Content is the content of the file
+	// Content is the content of the file, or of the requested lines
 	Content string `json:"content,omitempty"`
 	// Path is the path of the file that was read
 	Path string `json:"path,omitempty"`
+	// StartLine is the first line returned when a line range was requested
+	StartLine int `json:"startLine,omitempty"`
+	// EndLine is the last line returned when a line range was requested
+	EndLine int `json:"endLine,omitempty"`
+	// TotalLines is the number of lines in the file
+	TotalLines int `json:"totalLines"`
+	// Encoding is base64 when Content is base64 of the raw bytes, and empty for UTF-8 text
+	Encoding string `json:"encoding,omitempty"`
+	// Truncated indicates that the content was cut at Config.MaxOutputBytes
+	Truncated bool `json:"truncated,omitempty"`
+	// NextOffset is the offset to read the rest of a truncated whole-file read from
+	NextOffset int `json:"nextOffset,omitempty"`
+	// Version identifies the content of the whole file; pass it as ifVersion to fileWrite
+	Version string `json:"version,omitempty"`
+	// OriginalEncoding is the encoding of a text file that was converted to UTF-8, such as
+	// utf-16le or windows-1252, and empty for UTF-8 files
+	OriginalEncoding string `json:"originalEncoding,omitempty"`
 	// Error contains the error message if the operation failed
 	Error string `json:"error,omitempty"`
 }
@@ -44,12 +97,21 @@ type FileWriteInput struct {
 	Path string `json:"path"`
 	// Content is the content to write to the file
 	Content string `json:"content"`
+	// Encoding is the encoding of Content: utf8 (default) or base64 for binary files
+	Encoding string `json:"encoding,omitempty"`
+	// IfVersion makes the write fail unless the file still has this version, as returned by
+	// fileRead, so a change made by another stage in between is not overwritten
+	IfVersion string `json:"ifVersion,omitempty"`
 }
 
 // FileWriteOutput defines the output structure for the fileWrite tool
 type FileWriteOutput struct {
 	// Path is the path of the file that was written
 	Path string `json:"path,omitempty"`
+	// Version identifies the written content, for the ifVersion of a later write
+	Version string `json:"version,omitempty"`
+	// Redacted are the kinds of likely secrets that were redacted before writing
+	Redacted []string `json:"redacted,omitempty"`
 	// Success indicates whether the write operation was successful
 	Success bool `json:"success"`
 	// Error contains the error message if the operation failed
@@ -57,20 +119,48 @@ type FileWriteOutput struct {
 }
 
 // executeFileRead is the core logic for reading files, extracted for testability
-func executeFileRead(workspaceDir string, input FileReadInput) (*FileReadOutput, error) {
+func executeFileRead(workspaceDir string, config Config, input FileReadInput) (*FileReadOutput, error) {
 	start := time.Now()
 	slog.Info("Starting file read operation",
 		"path", input.Path,
+		"start_line", input.StartLine,
+		"end_line", input.EndLine,
+		"encoding", input.Encoding,
+		"offset", input.Offset,
 		"workspace", workspaceDir)
 
+	ranged := input.StartLine != 0 || input.EndLine != 0
+	encoding, err := contentEncoding(input.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	if encoding == EncodingBase64 && ranged {
+		return nil, fmt.Errorf("line ranges cannot be read with %s encoding", EncodingBase64)
+	}
+	if input.StartLine < 0 || input.EndLine < 0 {
+		return nil, fmt.Errorf("line numbers must be positive: start %d, end %d", input.StartLine, input.EndLine)
+	}
+	if input.EndLine != 0 && input.EndLine < input.StartLine {
+		return nil, fmt.Errorf("end line %d is before start line %d", input.EndLine, input.StartLine)
+	}
+	if input.Offset < 0 {
+		return nil, fmt.Errorf("offset must be positive: %d", input.Offset)
+	}
+	if input.Offset > 0 && ranged {
+		return nil, fmt.Errorf("offset cannot be combined with line ranges; continue with startLine instead")
+	}
+
 	// Validate and resolve the path within workspace
-	resolvedPath, err := resolveWorkspacePath(workspaceDir, input.Path)
+	resolvedPath, err := config.resolveFile(workspaceDir, input.Path)
 	if err != nil {
 		slog.Error("Failed to resolve path",
 			"path", input.Path,
 			"error", err)
 		return nil, fmt.Errorf("failed to resolve path: %w", err)
 	}
+	if err := checkIgnored(workspaceDir, config, input.Path, false); err != nil {
+		return nil, err
+	}
 
 	// Check file size before reading to prevent reading huge files
 	info, err := os.Stat(resolvedPath)
@@ -82,25 +172,44 @@ func executeFileRead(workspaceDir string, input FileReadInput) (*FileReadOutput,
 		return nil, fmt.Errorf("failed to read file %s: %w", input.Path, err)
 	}
 
-	if info.Size() > MaxFileSize {
+	// Line ranges are streamed, so only whole reads are limited by the file size
+	maxSize := config.maxFileSize()
+	if info.Size() > maxSize && !ranged {
 		slog.Warn("File too large",
 			"path", input.Path,
 			"size_bytes", info.Size(),
-			"max_size_bytes", MaxFileSize)
-		return nil, fmt.Errorf("file too large: %d bytes (max %d bytes)", info.Size(), MaxFileSize)
+			"max_size_bytes", maxSize)
+		return nil, fmt.Errorf("file too large: %d bytes (max %d bytes); read it in line ranges with startLine and endLine", info.Size(), maxSize)
 	}
 
 	// Use context with timeout for file read operation
-	readCtx, cancel := context.WithTimeout(context.Background(), FileOperationTimeout)
+	timeout := config.fileOperationTimeout()
+	readCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Perform file read with timeout
 	done := make(chan struct{})
-	var content []byte
+	var output *FileReadOutput
 	var readErr error
 
 	go func() {
-		content, readErr = os.ReadFile(resolvedPath)
+		if ranged {
+			output, readErr = readLineRange(resolvedPath, max(input.StartLine, 1), input.EndLine, config)
+		} else {
+			var content []byte
+			if content, readErr = os.ReadFile(resolvedPath); readErr == nil {
+				// Text in other encodings is converted, so the model does not see garbled content
+				text, charset := content, ""
+				if encoding == EncodingUTF8 {
+					text, charset = decodeText(content)
+				}
+				output, readErr = readPage(text, input.Offset, config.MaxOutputBytes, encoding)
+				if readErr == nil {
+					output.Version = contentVersion(content)
+					output.OriginalEncoding = charset
+				}
+			}
+		}
 		close(done)
 	}()
 
@@ -113,22 +222,157 @@ func executeFileRead(workspaceDir string, input FileReadInput) (*FileReadOutput,
 				"duration_ms", time.Since(start).Milliseconds())
 			return nil, fmt.Errorf("failed to read file %s: %w", input.Path, readErr)
 		}
+		// Binary content does not survive a round trip through a string, so it must be read as base64
+		if encoding == EncodingUTF8 && !utf8.ValidString(output.Content) {
+			return nil, fmt.Errorf("file %s is not valid UTF-8 text; read it with encoding %s", input.Path, EncodingBase64)
+		}
 
 		slog.Info("File read completed successfully",
 			"path", input.Path,
-			"size_bytes", len(content),
+			"size_bytes", len(output.Content),
+			"total_lines", output.TotalLines,
+			"truncated", output.Truncated,
 			"duration_ms", time.Since(start).Milliseconds())
 
-		return &FileReadOutput{
-			Content: string(content),
-			Path:    input.Path,
-		}, nil
+		output.Path = input.Path
+		return output, nil
 	case <-readCtx.Done():
 		slog.Error("File read operation timed out",
 			"path", input.Path,
-			"timeout", FileOperationTimeout)
-		return nil, fmt.Errorf("file read timeout exceeded (%v)", FileOperationTimeout)
+			"timeout", timeout)
+		return nil, fmt.Errorf("file read timeout exceeded (%v)", timeout)
+	}
+}
+
+// readPage returns the part of the whole file content that starts at offset, cut at limit
+// bytes (0 disables). UTF-8 text is cut after a line where possible and gets a truncation
+// marker; base64 content is cut so that its encoding fits.
+func readPage(content []byte, offset, limit int, encoding string) (*FileReadOutput, error) {
+	if offset > len(content) {
+		return nil, fmt.Errorf("offset %d is beyond the end of the file (%d bytes)", offset, len(content))
+	}
+	output := &FileReadOutput{TotalLines: countLines(string(content))}
+	page := content[offset:]
+	if encoding == EncodingBase64 {
+		// Base64 encodes 3 bytes in 4 characters
+		limit = max(limit/4*3, min(limit, 3))
+	}
+	if limit > 0 && len(page) > limit {
+		cut := limit
+		if encoding == EncodingUTF8 {
+			if i := bytes.LastIndexByte(page[:limit], '\n'); i >= 0 {
+				cut = i + 1
+			} else {
+				// Do not split a multi-byte character
+				for cut > 0 && !utf8.RuneStart(page[cut]) {
+					cut--
+				}
+				if cut == 0 {
+					_, cut = utf8.DecodeRune(page)
+				}
+			}
+		}
+		page = page[:cut]
+		output.Truncated = true
+		output.NextOffset = offset + cut
+	}
+
+	if encoding == EncodingBase64 {
+		output.Content = base64.StdEncoding.EncodeToString(page)
+		output.Encoding = EncodingBase64
+		return output, nil
+	}
+	output.Content = string(page)
+	if output.Truncated {
+		output.Content += fmt.Sprintf("\n[... truncated at byte %d of %d; call fileRead with offset %d to continue]", output.NextOffset, len(content), output.NextOffset)
+	}
+	return output, nil
+}
+
+// readLineRange reads lines start to end (0 for the last line) of the file at path and
+// counts all of its lines, stopping after the last full line within config.MaxOutputBytes
+func readLineRange(path string, start, end int, config Config) (*FileReadOutput, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
 	}
+	defer f.Close()
+
+	output := &FileReadOutput{StartLine: start}
+	limit, maxSize := config.MaxOutputBytes, config.maxFileSize()
+	var content strings.Builder
+	// The whole file is hashed while it is read, for its version
+	hash := sha256.New()
+	raw := bufio.NewReader(io.TeeReader(f, hash))
+	// Files with a byte order mark are converted as they are read; other text that is not
+	// valid UTF-8 is converted line by line
+	var source io.Reader = raw
+	head, _ := raw.Peek(3)
+	charset, decoder := bomCharset(head)
+	if decoder != nil {
+		source = decoder.Reader(raw)
+		output.OriginalEncoding = charset
+	}
+	reader := bufio.NewReader(source)
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" && decoder == nil && !utf8.ValidString(line) {
+			if text, charset := decodeText([]byte(line)); charset != "" {
+				line, output.OriginalEncoding = string(text), charset
+			}
+		}
+		if line != "" {
+			output.TotalLines++
+			if output.TotalLines >= start && (end == 0 || output.TotalLines <= end) && !output.Truncated {
+				if int64(content.Len()+len(line)) > maxSize {
+					return nil, fmt.Errorf("lines %d-%d exceed %d bytes; request a smaller range", start, output.TotalLines, maxSize)
+				}
+				// At least one line is returned, however long, so that reading makes progress
+				if limit > 0 && content.Len() > 0 && content.Len()+len(line) > limit {
+					output.Truncated = true
+					continue
+				}
+				content.WriteString(line)
+				output.EndLine = output.TotalLines
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if start > output.TotalLines {
+		return nil, fmt.Errorf("start line %d is beyond the end of the file (%d lines)", start, output.TotalLines)
+	}
+	output.Version = hex.EncodeToString(hash.Sum(nil)[:8])
+	output.Content = content.String()
+	if output.Truncated {
+		output.Content += fmt.Sprintf("\n[... truncated after line %d; call fileRead with startLine %d to continue]", output.EndLine, output.EndLine+1)
+	}
+	return output, nil
+}
+
+// contentEncoding validates the encoding of tool content, which defaults to utf8
+func contentEncoding(encoding string) (string, error) {
+	switch encoding {
+	case "", EncodingUTF8:
+		return EncodingUTF8, nil
+	case EncodingBase64:
+		return EncodingBase64, nil
+	default:
+		return "", fmt.Errorf("unknown encoding %q: use %s or %s", encoding, EncodingUTF8, EncodingBase64)
+	}
+}
+
+// countLines returns the number of lines in content; a last line without a newline counts
+func countLines(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
 }
 
 // FileReadTool creates a new fileRead tool that reads the content of a file within the workspace directory
@@ -138,13 +382,20 @@ func FileReadTool() tool.Tool {
 
 // NewFileReadToolWithWorkspace creates a new fileRead tool with a custom workspace directory
 func NewFileReadToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewFileReadToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewFileReadToolWithConfig creates a new fileRead tool with a custom workspace directory and configuration
+func NewFileReadToolWithConfig(workspaceDir string, config Config) tool.Tool {
 	t, err := functiontool.New(
 		functiontool.Config{
 			Name:        "fileRead",
-			Description: "Read the content of a file from the workspace directory. All paths are relative to the workspace.",
+			Description: "Read the content of a file from the workspace directory, or only lines startLine to endLine of it. The total line count is returned, so large files can be read in slices. Text in UTF-16, with a byte order mark, or in a legacy encoding is converted to UTF-8 and originalEncoding reports the original encoding. Set encoding to base64 to read binary files such as images whole. Large content is truncated; continue with the returned nextOffset, or with the next startLine for line ranges. All paths are relative to the workspace.",
 		},
 		func(ctx tool.Context, input FileReadInput) *FileReadOutput {
-			output, err := executeFileRead(workspaceDir, input)
+			observe := observeTool("fileRead")
+			output, err := executeFileRead(workspaceDir, config, input)
+			observe(err)
 			if err != nil {
 				return &FileReadOutput{
 					Error: err.Error(),
@@ -160,24 +411,50 @@ func NewFileReadToolWithWorkspace(workspaceDir string) tool.Tool {
 }
 
 // executeFileWrite is the core logic for writing files, extracted for testability
-func executeFileWrite(workspaceDir string, input FileWriteInput) (*FileWriteOutput, error) {
+func executeFileWrite(workspaceDir string, config Config, input FileWriteInput) (*FileWriteOutput, error) {
 	start := time.Now()
 	slog.Info("Starting file write operation",
 		"path", input.Path,
 		"content_size_bytes", len(input.Content),
+		"encoding", input.Encoding,
 		"workspace", workspaceDir)
 
+	if err := config.checkWritable("fileWrite"); err != nil {
+		return nil, err
+	}
+	encoding, err := contentEncoding(input.Encoding)
+	if err != nil {
+		return nil, err
+	}
+	data := []byte(input.Content)
+	if encoding == EncodingBase64 {
+		if data, err = base64.StdEncoding.DecodeString(input.Content); err != nil {
+			return nil, fmt.Errorf("invalid base64 content: %w", err)
+		}
+	}
+	// Decoded text is scanned like plain text, so secrets cannot be written by encoding them;
+	// binary content can only be blocked, as redacting would corrupt it
+	scan := config
+	if encoding == EncodingBase64 && !utf8.Valid(data) && scan.SecretScan == SecretScanRedact {
+		scan.SecretScan = SecretScanBlock
+	}
+	content, redacted, err := scan.scanSecrets(input.Path, string(data))
+	if err != nil {
+		return nil, err
+	}
+	data = []byte(content)
+
 	// Check content size before writing
-	if len(input.Content) > MaxFileSize {
+	if maxSize := config.maxFileSize(); int64(len(data)) > maxSize {
 		slog.Warn("Content too large",
 			"path", input.Path,
-			"size_bytes", len(input.Content),
-			"max_size_bytes", MaxFileSize)
-		return nil, fmt.Errorf("content too large: %d bytes (max %d bytes)", len(input.Content), MaxFileSize)
+			"size_bytes", len(data),
+			"max_size_bytes", maxSize)
+		return nil, fmt.Errorf("content too large: %d bytes (max %d bytes)", len(data), maxSize)
 	}
 
 	// Validate and resolve the path within workspace
-	resolvedPath, err := resolveWorkspacePath(workspaceDir, input.Path)
+	resolvedPath, err := config.resolveFile(workspaceDir, input.Path)
 	if err != nil {
 		slog.Error("Failed to resolve path",
 			"path", input.Path,
@@ -196,7 +473,8 @@ func executeFileWrite(workspaceDir string, input FileWriteInput) (*FileWriteOutp
 	}
 
 	// Use context with timeout for file write operation
-	writeCtx, cancel := context.WithTimeout(context.Background(), FileOperationTimeout)
+	timeout := config.fileOperationTimeout()
+	writeCtx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
 	// Perform file write with timeout
@@ -204,8 +482,15 @@ func executeFileWrite(workspaceDir string, input FileWriteInput) (*FileWriteOutp
 	var writeErr error
 
 	go func() {
-		writeErr = os.WriteFile(resolvedPath, []byte(input.Content), 0644)
-		close(done)
+		defer close(done)
+		unlock := lockPaths(resolvedPath)
+		defer unlock()
+		if input.IfVersion != "" {
+			if writeErr = checkVersion(resolvedPath, input.Path, input.IfVersion); writeErr != nil {
+				return
+			}
+		}
+		writeErr = writeFileAtomic(resolvedPath, data)
 	}()
 
 	select {
@@ -220,18 +505,20 @@ func executeFileWrite(workspaceDir string, input FileWriteInput) (*FileWriteOutp
 
 		slog.Info("File write completed successfully",
 			"path", input.Path,
-			"size_bytes", len(input.Content),
+			"size_bytes", len(data),
 			"duration_ms", time.Since(start).Milliseconds())
 
 		return &FileWriteOutput{
-			Path:    input.Path,
-			Success: true,
+			Path:     input.Path,
+			Version:  contentVersion(data),
+			Redacted: redacted,
+			Success:  true,
 		}, nil
 	case <-writeCtx.Done():
 		slog.Error("File write operation timed out",
 			"path", input.Path,
-			"timeout", FileOperationTimeout)
-		return nil, fmt.Errorf("file write timeout exceeded (%v)", FileOperationTimeout)
+			"timeout", timeout)
+		return nil, fmt.Errorf("file write timeout exceeded (%v)", timeout)
 	}
 }
 
@@ -242,13 +529,20 @@ func FileWriteTool() tool.Tool {
 
 // NewFileWriteToolWithWorkspace creates a new fileWrite tool with a custom workspace directory
 func NewFileWriteToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewFileWriteToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewFileWriteToolWithConfig creates a new fileWrite tool with a custom workspace directory and configuration
+func NewFileWriteToolWithConfig(workspaceDir string, config Config) tool.Tool {
 	t, err := functiontool.New(
 		functiontool.Config{
 			Name:        "fileWrite",
-			Description: "Write content to a file in the workspace directory. Creates the file if it doesn't exist, or overwrites it if it does. All paths are relative to the workspace.",
+			Description: "Write content to a file in the workspace directory. Creates the file if it doesn't exist, or overwrites it if it does. Set ifVersion to the version returned by fileRead to write only if no one changed the file since. Set encoding to base64 to write binary files such as images. All paths are relative to the workspace.",
 		},
 		func(ctx tool.Context, input FileWriteInput) *FileWriteOutput {
-			output, err := executeFileWrite(workspaceDir, input)
+			observe := observeTool("fileWrite")
+			output, err := executeFileWrite(workspaceDir, config, input)
+			observe(err)
 			if err != nil {
 				return &FileWriteOutput{
 					Success: false,
@@ -264,26 +558,125 @@ func NewFileWriteToolWithWorkspace(workspaceDir string) tool.Tool {
 	return t
 }
 
+// fileLines is the content of a file split into lines
+type fileLines struct {
+	lines []string
+	// eofNewline indicates whether the last line ends with a newline
+	eofNewline bool
+}
+
+// readFileLines returns the content of the workspace file at path, taking files already
+// changed by the same operation from contents, where nil marks a deleted file
+func readFileLines(workspaceDir string, config Config, path string, contents map[string]*fileLines) (*fileLines, error) {
+	if content, ok := contents[path]; ok {
+		return content, nil
+	}
+	resolved, err := config.resolveFile(workspaceDir, path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if maxSize := config.maxFileSize(); info.Size() > maxSize {
+		return nil, fmt.Errorf("file too large: %d bytes (max %d bytes)", info.Size(), maxSize)
+	}
+	data, err := os.ReadFile(resolved)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	text := string(data)
+	content := &fileLines{eofNewline: text == "" || strings.HasSuffix(text, "\n")}
+	if text != "" {
+		content.lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+	return content, nil
+}
+
+// writeFileLines writes content to the workspace file at path
+func writeFileLines(workspaceDir string, config Config, path string, content *fileLines) error {
+	resolved, err := config.resolveFile(workspaceDir, path)
+	if err != nil {
+		return fmt.Errorf("failed to resolve path: %w", err)
+	}
+	text := strings.Join(content.lines, "\n")
+	if content.eofNewline && len(content.lines) > 0 {
+		text += "\n"
+	}
+	if err := os.MkdirAll(filepath.Dir(resolved), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	if err := writeFileAtomic(resolved, []byte(text)); err != nil {
+		return fmt.Errorf("failed to write file %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place,
+// so readers never see a partly written file. An existing file keeps its permissions; new
+// files get 0644.
+func writeFileAtomic(path string, data []byte) (err error) {
+	perm := os.FileMode(0644)
+	if info, statErr := os.Stat(path); statErr == nil {
+		perm = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err = tmp.Write(data); err != nil {
+		return err
+	}
+	if SyncFileWrites {
+		if err = tmp.Sync(); err != nil {
+			return err
+		}
+	}
+	if err = tmp.Chmod(perm); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // resolveWorkspacePath validates and resolves a user-provided path within the workspace directory.
 // It prevents directory traversal attacks and ensures all operations stay within the workspace.
 func resolveWorkspacePath(workspaceDir, userPath string) (string, error) {
+	resolved, _, err := ToolConfig.resolvePath(workspaceDir, userPath)
+	return resolved, err
+}
+
+// resolvePath resolves userPath like resolveWorkspacePath with the symlink policy of c, and
+// also returns the workspace-relative path it points to once its symlinks are followed
+func (c Config) resolvePath(workspaceDir, userPath string) (resolved, target string, err error) {
 	// Clean the user path to remove any ".." or other traversal attempts
 	cleanUserPath := filepath.Clean(userPath)
 
 	// Prevent absolute paths
 	if filepath.IsAbs(cleanUserPath) {
-		return "", fmt.Errorf("absolute paths are not allowed: %s", userPath)
+		return "", "", fmt.Errorf("absolute paths are not allowed: %s", userPath)
 	}
 
 	// Get absolute path of workspace
 	absWorkspace, err := filepath.Abs(workspaceDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve workspace directory: %w", err)
+		return "", "", fmt.Errorf("failed to resolve workspace directory: %w", err)
 	}
 
 	// Ensure workspace directory exists
 	if err := os.MkdirAll(absWorkspace, 0755); err != nil {
-		return "", fmt.Errorf("failed to create workspace directory: %w", err)
+		return "", "", fmt.Errorf("failed to create workspace directory: %w", err)
 	}
 
 	// Join workspace with user path
@@ -292,15 +685,86 @@ func resolveWorkspacePath(workspaceDir, userPath string) (string, error) {
 	// Get absolute path of the result
 	absFullPath, err := filepath.Abs(fullPath)
 	if err != nil {
-		return "", fmt.Errorf("failed to resolve file path: %w", err)
+		return "", "", fmt.Errorf("failed to resolve file path: %w", err)
 	}
 
 	// Ensure the resolved path is still within the workspace
 	// This prevents directory traversal attacks
 	if !strings.HasPrefix(absFullPath, absWorkspace+string(filepath.Separator)) &&
 		absFullPath != absWorkspace {
-		return "", fmt.Errorf("path traversal detected: %s escapes workspace directory", userPath)
+		return "", "", fmt.Errorf("path traversal detected: %s escapes workspace directory", userPath)
+	}
+
+	// A symlink within the workspace could still point outside of it
+	target, err = checkSymlinks(absWorkspace, cleanUserPath, userPath, c.SymlinkPolicy)
+	if err != nil {
+		return "", "", err
+	}
+
+	// Git runs on the host, so a file written to .git, such as a config setting
+	// core.fsmonitor, would run commands outside the sandbox
+	if inGitDir(cleanUserPath) || inGitDir(target) {
+		return "", "", fmt.Errorf("access denied: %s is in the git directory", userPath)
+	}
+
+	return absFullPath, target, nil
+}
+
+// inGitDir reports whether the workspace-relative path rel is .git or within it
+func inGitDir(rel string) bool {
+	for segment := range strings.SplitSeq(filepath.ToSlash(rel), "/") {
+		if strings.EqualFold(segment, ".git") {
+			return true
+		}
 	}
+	return false
+}
 
-	return absFullPath, nil
+// checkSymlinks returns an error if a symlink on the path rel within the absolute workspace
+// directory is denied by policy or points outside the workspace, and otherwise returns the
+// workspace-relative path that rel points to. Components that do not exist yet, such as the
+// file of a write, cannot be symlinks.
+func checkSymlinks(absWorkspace, rel, userPath, policy string) (string, error) {
+	realWorkspace, err := filepath.EvalSymlinks(absWorkspace)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve workspace directory: %w", err)
+	}
+	current := realWorkspace
+	segments := strings.Split(rel, string(filepath.Separator))
+	for i, segment := range segments {
+		if segment == "" || segment == "." {
+			continue
+		}
+		next := filepath.Join(current, segment)
+		info, err := os.Lstat(next)
+		if err != nil {
+			// The rest of the path does not exist, so it cannot contain symlinks
+			current = filepath.Join(append([]string{current}, segments[i:]...)...)
+			break
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			current = next
+			continue
+		}
+		switch policy {
+		case "", SymlinkFollow:
+		case SymlinkDeny:
+			return "", fmt.Errorf("symlinks are not allowed: %s", userPath)
+		default:
+			return "", fmt.Errorf("unknown symlink policy %q (use %s or %s)", policy, SymlinkFollow, SymlinkDeny)
+		}
+		target, err := filepath.EvalSymlinks(next)
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve symlink in %s: %w", userPath, err)
+		}
+		if target != realWorkspace && !strings.HasPrefix(target, realWorkspace+string(filepath.Separator)) {
+			return "", fmt.Errorf("path traversal detected: %s is a symlink that escapes workspace directory", userPath)
+		}
+		current = target
+	}
+	target, err := filepath.Rel(realWorkspace, current)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", userPath, err)
+	}
+	return target, nil
 }
@@ -1,8 +1,12 @@
 package tools
 
 import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -114,7 +118,7 @@ func TestFileReadTool(t *testing.T) {
 
 			// Execute the file read directly
 			input := FileReadInput{Path: tt.relativePath}
-			output, err := executeFileRead(workspaceDir, input)
+			output, err := executeFileRead(workspaceDir, ToolConfig, input)
 
 			// Check error expectations
 			if (err != nil) != tt.wantErr {
@@ -141,6 +145,253 @@ func TestFileReadTool(t *testing.T) {
 	}
 }
 
+func TestFileReadTool_LineRange(t *testing.T) {
+	workspaceDir := t.TempDir()
+	content := "line 1\nline 2\nline 3\nline 4\nline 5"
+	if err := os.WriteFile(filepath.Join(workspaceDir, "lines.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		input       FileReadInput
+		want        FileReadOutput
+		errContains string
+	}{
+		{
+			name:  "whole file",
+			input: FileReadInput{Path: "lines.txt"},
+			want:  FileReadOutput{Path: "lines.txt", Content: content, TotalLines: 5},
+		},
+		{
+			name:  "middle lines",
+			input: FileReadInput{Path: "lines.txt", StartLine: 2, EndLine: 3},
+			want:  FileReadOutput{Path: "lines.txt", Content: "line 2\nline 3\n", StartLine: 2, EndLine: 3, TotalLines: 5},
+		},
+		{
+			name:  "to the end",
+			input: FileReadInput{Path: "lines.txt", StartLine: 4},
+			want:  FileReadOutput{Path: "lines.txt", Content: "line 4\nline 5", StartLine: 4, EndLine: 5, TotalLines: 5},
+		},
+		{
+			name:  "from the beginning",
+			input: FileReadInput{Path: "lines.txt", EndLine: 1},
+			want:  FileReadOutput{Path: "lines.txt", Content: "line 1\n", StartLine: 1, EndLine: 1, TotalLines: 5},
+		},
+		{
+			name:  "end beyond the file",
+			input: FileReadInput{Path: "lines.txt", StartLine: 5, EndLine: 100},
+			want:  FileReadOutput{Path: "lines.txt", Content: "line 5", StartLine: 5, EndLine: 5, TotalLines: 5},
+		},
+		{
+			name:        "start beyond the file",
+			input:       FileReadInput{Path: "lines.txt", StartLine: 6},
+			errContains: "start line 6 is beyond the end of the file (5 lines)",
+		},
+		{
+			name:        "end before start",
+			input:       FileReadInput{Path: "lines.txt", StartLine: 3, EndLine: 2},
+			errContains: "end line 2 is before start line 3",
+		},
+		{
+			name:        "negative line",
+			input:       FileReadInput{Path: "lines.txt", StartLine: -1},
+			errContains: "line numbers must be positive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeFileRead(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeFileRead() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeFileRead() error = %v", err)
+			}
+			// Ranged reads report the version of the whole file too
+			tt.want.Version = contentVersion([]byte(content))
+			if *output != tt.want {
+				t.Errorf("executeFileRead() = %+v, want %+v", *output, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileReadTool_LargeFileRange(t *testing.T) {
+	workspaceDir := t.TempDir()
+	line := strings.Repeat("x", 1023) + "\n"
+	content := "first\n" + strings.Repeat(line, MaxFileSize/len(line)+1)
+	if err := os.WriteFile(filepath.Join(workspaceDir, "large.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	if _, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "large.txt"}); err == nil || !strings.Contains(err.Error(), "read it in line ranges") {
+		t.Errorf("executeFileRead() of the whole file error = %v, want a file too large error", err)
+	}
+	output, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "large.txt", StartLine: 1, EndLine: 1})
+	if err != nil {
+		t.Fatalf("executeFileRead() error = %v", err)
+	}
+	if output.Content != "first\n" || output.TotalLines != MaxFileSize/len(line)+2 {
+		t.Errorf("executeFileRead() = %q with %d lines, want the first line of %d", output.Content, output.TotalLines, MaxFileSize/len(line)+2)
+	}
+	output, err = executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "large.txt", StartLine: 1, EndLine: MaxFileSize})
+	if err != nil {
+		t.Fatalf("executeFileRead() of all lines error = %v", err)
+	}
+	if !output.Truncated || len(output.Content) > ToolConfig.MaxOutputBytes+100 {
+		t.Errorf("executeFileRead() of all lines = %d bytes, truncated %v, want it truncated", len(output.Content), output.Truncated)
+	}
+
+	// Without an output limit the file size limit applies
+	if _, err := executeFileRead(workspaceDir, Config{}, FileReadInput{Path: "large.txt", StartLine: 1, EndLine: MaxFileSize}); err == nil || !strings.Contains(err.Error(), "request a smaller range") {
+		t.Errorf("executeFileRead() of all lines error = %v, want a range too large error", err)
+	}
+}
+
+func TestFileReadTool_Paging(t *testing.T) {
+	workspaceDir := t.TempDir()
+	content := "line one\nline two\nline three\nline four\n"
+	if err := os.WriteFile(filepath.Join(workspaceDir, "lines.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	config := Config{MaxOutputBytes: 20}
+
+	// Whole-file reads are cut after a line and continue at NextOffset
+	var pages []string
+	input := FileReadInput{Path: "lines.txt"}
+	for range 10 {
+		output, err := executeFileRead(workspaceDir, config, input)
+		if err != nil {
+			t.Fatalf("executeFileRead(%+v) error = %v", input, err)
+		}
+		if !output.Truncated {
+			pages = append(pages, output.Content)
+			break
+		}
+		page, marker, ok := strings.Cut(output.Content, "\n[... truncated")
+		if !ok || !strings.Contains(marker, fmt.Sprintf("offset %d", output.NextOffset)) {
+			t.Fatalf("executeFileRead() content = %q, want a truncation marker", output.Content)
+		}
+		pages = append(pages, page)
+		input.Offset = output.NextOffset
+	}
+	want := []string{"line one\nline two\n", "line three\n", "line four\n"}
+	if !reflect.DeepEqual(pages, want) {
+		t.Errorf("pages = %q, want %q", pages, want)
+	}
+
+	// Line ranges are cut after a full line and continue at the next start line
+	output, err := executeFileRead(workspaceDir, config, FileReadInput{Path: "lines.txt", StartLine: 2})
+	if err != nil {
+		t.Fatalf("executeFileRead() error = %v", err)
+	}
+	if !output.Truncated || output.EndLine != 3 || !strings.HasPrefix(output.Content, "line two\nline three\n\n[... truncated after line 3; call fileRead with startLine 4") {
+		t.Errorf("executeFileRead() = %+v, want lines 2-3 and a truncation marker", output)
+	}
+
+	// Base64 pages are whole chunks of the raw bytes
+	output, err = executeFileRead(workspaceDir, config, FileReadInput{Path: "lines.txt", Encoding: EncodingBase64, Offset: 29})
+	if err != nil {
+		t.Fatalf("executeFileRead() error = %v", err)
+	}
+	decoded, _ := base64.StdEncoding.DecodeString(output.Content)
+	if string(decoded) != "line four\n" || output.Truncated {
+		t.Errorf("executeFileRead() decoded = %q, truncated %v, want the rest of the file", decoded, output.Truncated)
+	}
+
+	for _, input := range []FileReadInput{
+		{Path: "lines.txt", Offset: len(content) + 1},
+		{Path: "lines.txt", Offset: -1},
+		{Path: "lines.txt", Offset: 4, StartLine: 2},
+	} {
+		if _, err := executeFileRead(workspaceDir, config, input); err == nil {
+			t.Errorf("executeFileRead(%+v) error = nil, want an error", input)
+		}
+	}
+}
+
+func TestFileReadWrite_Base64(t *testing.T) {
+	workspaceDir := t.TempDir()
+	// The header of a PNG image, which is not valid UTF-8
+	binary := []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n', 0x00, 0xff}
+	encoded := base64.StdEncoding.EncodeToString(binary)
+
+	output, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "assets/logo.png", Content: encoded, Encoding: EncodingBase64})
+	if err != nil || !output.Success {
+		t.Fatalf("executeFileWrite() = %+v, %v, want success", output, err)
+	}
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "assets", "logo.png"))
+	if err != nil {
+		t.Fatalf("failed to read written file: %v", err)
+	}
+	if !bytes.Equal(data, binary) {
+		t.Errorf("written file = %v, want %v", data, binary)
+	}
+
+	read, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "assets/logo.png", Encoding: EncodingBase64})
+	if err != nil {
+		t.Fatalf("executeFileRead() error = %v", err)
+	}
+	if read.Content != encoded || read.Encoding != EncodingBase64 {
+		t.Errorf("executeFileRead() = %+v, want content %q in base64", read, encoded)
+	}
+
+	tests := []struct {
+		name        string
+		run         func() error
+		errContains string
+	}{
+		{
+			name: "binary read as text",
+			run: func() error {
+				_, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "assets/logo.png"})
+				return err
+			},
+			errContains: "not valid UTF-8 text; read it with encoding base64",
+		},
+		{
+			name: "base64 line range",
+			run: func() error {
+				_, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "assets/logo.png", StartLine: 1, Encoding: EncodingBase64})
+				return err
+			},
+			errContains: "line ranges cannot be read with base64 encoding",
+		},
+		{
+			name: "unknown read encoding",
+			run: func() error {
+				_, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "assets/logo.png", Encoding: "latin1"})
+				return err
+			},
+			errContains: `unknown encoding "latin1"`,
+		},
+		{
+			name: "invalid base64",
+			run: func() error {
+				_, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "bad.bin", Content: "not base64!", Encoding: EncodingBase64})
+				return err
+			},
+			errContains: "invalid base64 content",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, "bad.bin")); !os.IsNotExist(err) {
+		t.Errorf("invalid base64 created bad.bin: %v", err)
+	}
+}
+
 func TestFileWriteTool(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -236,7 +487,7 @@ func TestFileWriteTool(t *testing.T) {
 				Path:    tt.relativePath,
 				Content: tt.content,
 			}
-			output, err := executeFileWrite(workspaceDir, input)
+			output, err := executeFileWrite(workspaceDir, ToolConfig, input)
 
 			// Check error expectations
 			if (err != nil) != tt.wantErr {
@@ -294,7 +545,7 @@ func TestFileReadWrite_Integration(t *testing.T) {
 		Content: originalContent,
 	}
 
-	writeOutput, err := executeFileWrite(workspaceDir, writeInput)
+	writeOutput, err := executeFileWrite(workspaceDir, ToolConfig, writeInput)
 	if err != nil {
 		t.Fatalf("failed to write file: %v", err)
 	}
@@ -306,7 +557,7 @@ func TestFileReadWrite_Integration(t *testing.T) {
 	// Read content back
 	readInput := FileReadInput{Path: relativePath}
 
-	readOutput, err := executeFileRead(workspaceDir, readInput)
+	readOutput, err := executeFileRead(workspaceDir, ToolConfig, readInput)
 	if err != nil {
 		t.Fatalf("failed to read file: %v", err)
 	}
@@ -319,7 +570,7 @@ func TestFileReadWrite_Integration(t *testing.T) {
 	updatedContent := "Updated content"
 	writeInput.Content = updatedContent
 
-	writeOutput, err = executeFileWrite(workspaceDir, writeInput)
+	writeOutput, err = executeFileWrite(workspaceDir, ToolConfig, writeInput)
 	if err != nil {
 		t.Fatalf("failed to update file: %v", err)
 	}
@@ -329,7 +580,7 @@ func TestFileReadWrite_Integration(t *testing.T) {
 	}
 
 	// Read updated content
-	readOutput, err = executeFileRead(workspaceDir, readInput)
+	readOutput, err = executeFileRead(workspaceDir, ToolConfig, readInput)
 	if err != nil {
 		t.Fatalf("failed to read updated file: %v", err)
 	}
@@ -339,6 +590,56 @@ func TestFileReadWrite_Integration(t *testing.T) {
 	}
 }
 
+func TestWriteFileAtomic(t *testing.T) {
+	workspaceDir := t.TempDir()
+	path := filepath.Join(workspaceDir, "run.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\necho old\n"), 0755); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+
+	SyncFileWrites = true
+	defer func() { SyncFileWrites = false }()
+	if err := writeFileAtomic(path, []byte("#!/bin/sh\necho new\n")); err != nil {
+		t.Fatalf("writeFileAtomic() error = %v", err)
+	}
+
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(got) != "#!/bin/sh\necho new\n" {
+		t.Errorf("file = %q, want the new content", got)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("failed to stat file: %v", err)
+	}
+	if info.Mode().Perm() != 0755 {
+		t.Errorf("mode = %v, want the original 0755 kept", info.Mode().Perm())
+	}
+
+	// A failed rename must not leave the temporary file behind
+	if err := os.Mkdir(filepath.Join(workspaceDir, "dir"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, "dir", "keep.txt"), nil, 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	if err := writeFileAtomic(filepath.Join(workspaceDir, "dir"), []byte("x")); err == nil {
+		t.Error("writeFileAtomic() over a directory succeeded, want an error")
+	}
+
+	entries, err := os.ReadDir(workspaceDir)
+	if err != nil {
+		t.Fatalf("failed to read workspace: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "run.sh" && entry.Name() != "dir" {
+			t.Errorf("unexpected file %s left in the workspace", entry.Name())
+		}
+	}
+}
+
 func TestResolveWorkspacePath_Security(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -384,6 +685,23 @@ func TestResolveWorkspacePath_Security(t *testing.T) {
 			userPath: "./file.txt",
 			wantErr:  false,
 		},
+		{
+			name:        "git directory",
+			userPath:    ".git/config",
+			wantErr:     true,
+			errContains: "in the git directory",
+		},
+		{
+			name:        "nested git directory",
+			userPath:    "sub/.GIT/hooks/pre-commit",
+			wantErr:     true,
+			errContains: "in the git directory",
+		},
+		{
+			name:     "git-like file name",
+			userPath: ".gitignore",
+			wantErr:  false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -425,6 +743,118 @@ func TestResolveWorkspacePath_Security(t *testing.T) {
 }
 
 // TestFileReadTool_ToolCreation tests that the tool creation functions work correctly
+func TestResolveWorkspacePath_Symlinks(t *testing.T) {
+	workspaceDir := t.TempDir()
+	outsideDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("secret"), 0644); err != nil {
+		t.Fatalf("failed to create outside file: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(workspaceDir, "pkg"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(workspaceDir, "pkg", "calc.go"), []byte("package calc\n"), 0644); err != nil {
+		t.Fatalf("failed to create test file: %v", err)
+	}
+	links := map[string]string{
+		"outside":     outsideDir,
+		"secret.txt":  filepath.Join(outsideDir, "secret.txt"),
+		"missing.txt": filepath.Join(outsideDir, "missing.txt"),
+		"internal":    filepath.Join(workspaceDir, "pkg"),
+		"relative.go": filepath.Join("pkg", "calc.go"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(workspaceDir, name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		path        string
+		errContains string
+	}{
+		{name: "directory outside", policy: SymlinkFollow, path: "outside/secret.txt", errContains: "escapes workspace directory"},
+		{name: "new file in directory outside", policy: SymlinkFollow, path: "outside/new.txt", errContains: "escapes workspace directory"},
+		{name: "file outside", policy: SymlinkFollow, path: "secret.txt", errContains: "escapes workspace directory"},
+		{name: "dangling", policy: SymlinkFollow, path: "missing.txt", errContains: "failed to resolve symlink"},
+		{name: "directory inside", policy: SymlinkFollow, path: "internal/calc.go"},
+		{name: "relative file inside", policy: SymlinkFollow, path: "relative.go"},
+		{name: "no symlink", policy: SymlinkFollow, path: "pkg/calc.go"},
+		{name: "denied", policy: SymlinkDeny, path: "internal/calc.go", errContains: "symlinks are not allowed"},
+		{name: "denied without symlink", policy: SymlinkDeny, path: "pkg/calc.go"},
+		{name: "unknown policy", policy: "ignore", path: "relative.go", errContains: "unknown symlink policy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := ToolConfig
+			ToolConfig.SymlinkPolicy = tt.policy
+			t.Cleanup(func() { ToolConfig = previous })
+
+			_, err := resolveWorkspacePath(workspaceDir, tt.path)
+			if tt.errContains == "" && err != nil {
+				t.Errorf("resolveWorkspacePath(%q) error = %v", tt.path, err)
+			}
+			if tt.errContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errContains)) {
+				t.Errorf("resolveWorkspacePath(%q) error = %v, want it to contain %q", tt.path, err, tt.errContains)
+			}
+		})
+	}
+
+	// The tools cannot write through a symlink to a file outside the workspace
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "outside/new.txt", Content: "x"}); err == nil {
+		t.Error("executeFileWrite() through a symlink error = nil, want an error")
+	}
+	if _, err := os.Stat(filepath.Join(outsideDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("file outside the workspace was written: %v", err)
+	}
+}
+
+func TestResolveFile_SymlinkTarget(t *testing.T) {
+	workspaceDir := t.TempDir()
+	for _, dir := range []string{"secrets", ".git"} {
+		if err := os.MkdirAll(filepath.Join(workspaceDir, dir), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+	}
+	for name, content := range map[string]string{".env": "TOKEN=x\n", "secrets/key.go": "package secrets\n", "main.go": "package main\n", ".git/config.go": "[core]\n"} {
+		if err := os.WriteFile(filepath.Join(workspaceDir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+	for name, target := range map[string]string{"env.go": ".env", "keys": "secrets", "app.go": "main.go", "gitconfig.go": ".git/config.go"} {
+		if err := os.Symlink(target, filepath.Join(workspaceDir, name)); err != nil {
+			t.Fatalf("failed to create symlink: %v", err)
+		}
+	}
+	config := Config{DeniedPaths: []string{".env", "secrets"}, AllowedExtensions: []string{".go"}}
+
+	tests := []struct {
+		path        string
+		errContains string
+	}{
+		{path: "env.go", errContains: "access denied"},
+		{path: "keys/key.go", errContains: "access denied"},
+		{path: "keys/new.go", errContains: "access denied"},
+		{path: "gitconfig.go", errContains: "access denied"},
+		{path: "app.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			_, err := config.resolveFile(workspaceDir, tt.path)
+			if tt.errContains == "" && err != nil {
+				t.Errorf("resolveFile(%q) error = %v", tt.path, err)
+			}
+			if tt.errContains != "" && (err == nil || !strings.Contains(err.Error(), tt.errContains)) {
+				t.Errorf("resolveFile(%q) error = %v, want it to contain %q", tt.path, err, tt.errContains)
+			}
+		})
+	}
+	if _, err := executeFileRead(workspaceDir, config, FileReadInput{Path: "env.go"}); err == nil {
+		t.Error("executeFileRead() through a symlink to .env error = nil, want an error")
+	}
+}
+
 func TestFileReadTool_ToolCreation(t *testing.T) {
 	t.Run("default workspace", func(t *testing.T) {
 		tool := FileReadTool()
@@ -0,0 +1,516 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Identity used for the commits created in the workspace
+const (
+	GitAuthorName  = "adk-go-agi"
+	GitAuthorEmail = "adk-go-agi@localhost"
+)
+
+// MaxGitDiffSize is the maximum number of bytes of diff returned by the gitDiff tool (100KB)
+const MaxGitDiffSize = 100 * 1024
+
+// Number of commits returned by the gitLog tool
+const (
+	defaultGitLogCount = 10
+	maxGitLogCount     = 100
+)
+
+// gitMu serializes commits, since parallel stages and their tools may commit at the same time
+var gitMu sync.Mutex
+
+// readOnlyGitCommands are the git commands RunGit runs in read-only mode, as they only
+// inspect the repository
+var readOnlyGitCommands = []string{"status", "diff", "log", "rev-parse"}
+
+// gitRepositoryEnv are the environment variables that point git at another repository than
+// the one of its directory, which RunGit leaves out
+var gitRepositoryEnv = []string{"GIT_DIR", "GIT_WORK_TREE", "GIT_INDEX_FILE", "GIT_OBJECT_DIRECTORY", "GIT_COMMON_DIR"}
+
+// RunGit runs git in dir with the workspace identity and returns its combined output. Git
+// does not search the parents of dir for a repository, so a workspace that is not a
+// repository of its own fails rather than changing an enclosing checkout. Hooks and the
+// fsmonitor command of the repository configuration are not run, as code in the sandbox can
+// change the configuration and git runs on the host. In read-only mode only the commands
+// that inspect the repository can be run.
+func RunGit(ctx context.Context, dir string, args ...string) (string, error) {
+	if len(args) == 0 {
+		return "", fmt.Errorf("git command is required")
+	}
+	if ToolConfig.ReadOnly && !slices.Contains(readOnlyGitCommands, args[0]) {
+		return "", fmt.Errorf("git %s is disabled in read-only mode", strings.Join(args, " "))
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+	// Git compares the ceiling with the real path of its directory
+	if realDir, err := filepath.EvalSymlinks(absDir); err == nil {
+		absDir = realDir
+	}
+	cmd := exec.CommandContext(ctx, "git", append([]string{
+		"-c", "user.name=" + GitAuthorName,
+		"-c", "user.email=" + GitAuthorEmail,
+		"-c", "commit.gpgsign=false",
+		"-c", "core.fsmonitor=",
+		"-c", "core.hooksPath=/dev/null",
+	}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = slices.DeleteFunc(os.Environ(), func(kv string) bool {
+		name, _, _ := strings.Cut(kv, "=")
+		return slices.Contains(gitRepositoryEnv, name)
+	})
+	cmd.Env = append(cmd.Env, "GIT_CEILING_DIRECTORIES="+filepath.Dir(absDir))
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("git %s failed: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// CommitWorkspace stages all changes in workspaceDir and commits them. It reports
+// whether a commit was created; a clean workspace is not an error.
+func CommitWorkspace(ctx context.Context, workspaceDir, subject, body string) (bool, error) {
+	gitMu.Lock()
+	defer gitMu.Unlock()
+
+	if _, err := RunGit(ctx, workspaceDir, "add", "--all"); err != nil {
+		return false, err
+	}
+	status, err := RunGit(ctx, workspaceDir, "status", "--porcelain")
+	if err != nil {
+		return false, err
+	}
+	if strings.TrimSpace(status) == "" {
+		return false, nil
+	}
+
+	args := []string{"commit", "--quiet", "--no-verify", "-m", subject}
+	if body != "" {
+		args = append(args, "-m", body)
+	}
+	if _, err := RunGit(ctx, workspaceDir, args...); err != nil {
+		return false, err
+	}
+	slog.Info("Committed workspace changes", "workspace", workspaceDir, "subject", subject)
+	return true, nil
+}
+
+// GitInitInput defines the input parameters for the gitInit tool
+type GitInitInput struct{}
+
+// GitAddInput defines the input parameters for the gitAdd tool
+type GitAddInput struct {
+	// Paths are the relative paths to stage (defaults to all changes)
+	Paths []string `json:"paths,omitempty"`
+}
+
+// GitCommitInput defines the input parameters for the gitCommit tool
+type GitCommitInput struct {
+	// Message is the commit message
+	Message string `json:"message"`
+	// All stages all changes before committing
+	All bool `json:"all,omitempty"`
+}
+
+// GitOutput defines the output structure for the gitInit, gitAdd, and gitCommit tools
+type GitOutput struct {
+	// Output is the output of git
+	Output string `json:"output,omitempty"`
+	// Commit is the hash of the created commit, for gitCommit
+	Commit string `json:"commit,omitempty"`
+	// Success indicates whether the operation was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// GitStatusInput defines the input parameters for the gitStatus tool
+type GitStatusInput struct {
+	// Path limits the status to a relative path within the workspace
+	Path string `json:"path,omitempty"`
+}
+
+// GitFileStatus describes a changed file in the workspace
+type GitFileStatus struct {
+	// Path is the path of the file, relative to the workspace
+	Path string `json:"path"`
+	// Status is the two-letter git status code: the staged status, then the unstaged
+	// one, such as " M" for a modified file or "??" for an untracked one
+	Status string `json:"status"`
+}
+
+// GitStatusOutput defines the output structure for the gitStatus tool
+type GitStatusOutput struct {
+	// Branch is the current branch
+	Branch string `json:"branch,omitempty"`
+	// Files are the changed and untracked files
+	Files []GitFileStatus `json:"files,omitempty"`
+	// Clean indicates that there are no changes
+	Clean bool `json:"clean"`
+	// Success indicates whether the operation was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// GitDiffInput defines the input parameters for the gitDiff tool
+type GitDiffInput struct {
+	// Path limits the diff to a relative path within the workspace
+	Path string `json:"path,omitempty"`
+	// Ref is the commit to compare the workspace with, such as HEAD~1 (defaults to the index)
+	Ref string `json:"ref,omitempty"`
+	// Staged shows the staged changes instead of the unstaged ones
+	Staged bool `json:"staged,omitempty"`
+	// Stat shows a summary of the changed files instead of the full diff
+	Stat bool `json:"stat,omitempty"`
+}
+
+// GitDiffOutput defines the output structure for the gitDiff tool
+type GitDiffOutput struct {
+	// Diff is the unified diff, or the summary for stat
+	Diff string `json:"diff"`
+	// Truncated indicates that the diff was cut at MaxGitDiffSize bytes
+	Truncated bool `json:"truncated,omitempty"`
+	// Success indicates whether the operation was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// GitLogInput defines the input parameters for the gitLog tool
+type GitLogInput struct {
+	// Path limits the log to commits that changed a relative path within the workspace
+	Path string `json:"path,omitempty"`
+	// MaxCount is the number of commits to return (defaults to 10, at most 100)
+	MaxCount int `json:"maxCount,omitempty"`
+}
+
+// GitCommitInfo describes a commit
+type GitCommitInfo struct {
+	// Hash is the abbreviated commit hash
+	Hash string `json:"hash"`
+	// Author is the author name
+	Author string `json:"author"`
+	// Date is the author date in RFC 3339 format
+	Date string `json:"date"`
+	// Subject is the first line of the commit message
+	Subject string `json:"subject"`
+}
+
+// GitLogOutput defines the output structure for the gitLog tool
+type GitLogOutput struct {
+	// Commits are the commits, newest first
+	Commits []GitCommitInfo `json:"commits"`
+	// Success indicates whether the operation was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeGitInit initializes a repository in the workspace unless it already has one
+func executeGitInit(ctx context.Context, workspaceDir string) (*GitOutput, error) {
+	slog.Info("Starting git init operation", "workspace", workspaceDir)
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+	_, err := os.Stat(filepath.Join(workspaceDir, ".git"))
+	if err == nil {
+		return &GitOutput{Output: "The workspace is already a git repository.", Success: true}, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to stat .git: %w", err)
+	}
+	out, err := RunGit(ctx, workspaceDir, "init")
+	if err != nil {
+		return nil, err
+	}
+	return &GitOutput{Output: strings.TrimSpace(out), Success: true}, nil
+}
+
+// executeGitAdd stages changes in the workspace
+func executeGitAdd(ctx context.Context, workspaceDir string, input GitAddInput) (*GitOutput, error) {
+	slog.Info("Starting git add operation", "paths", input.Paths, "workspace", workspaceDir)
+	paths, err := gitPaths(workspaceDir, input.Paths...)
+	if err != nil {
+		return nil, err
+	}
+
+	gitMu.Lock()
+	defer gitMu.Unlock()
+	if _, err := RunGit(ctx, workspaceDir, append([]string{"add", "--all", "--"}, paths...)...); err != nil {
+		return nil, err
+	}
+	status, err := RunGit(ctx, workspaceDir, "diff", "--cached", "--stat")
+	if err != nil {
+		return nil, err
+	}
+	return &GitOutput{Output: strings.TrimSpace(status), Success: true}, nil
+}
+
+// executeGitCommit commits the staged changes in the workspace
+func executeGitCommit(ctx context.Context, workspaceDir string, input GitCommitInput) (*GitOutput, error) {
+	start := time.Now()
+	slog.Info("Starting git commit operation", "all", input.All, "workspace", workspaceDir)
+	if strings.TrimSpace(input.Message) == "" {
+		return nil, fmt.Errorf("message is required")
+	}
+
+	gitMu.Lock()
+	defer gitMu.Unlock()
+	if input.All {
+		if _, err := RunGit(ctx, workspaceDir, "add", "--all"); err != nil {
+			return nil, err
+		}
+	}
+	// A commit without staged changes fails; report it clearly instead of git's output
+	if _, err := RunGit(ctx, workspaceDir, "diff", "--cached", "--quiet"); err == nil {
+		return nil, fmt.Errorf("nothing to commit: stage changes with gitAdd or set all")
+	}
+	out, err := RunGit(ctx, workspaceDir, "commit", "--quiet", "--no-verify", "-m", input.Message)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := RunGit(ctx, workspaceDir, "rev-parse", "--short", "HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	slog.Info("Git commit completed successfully",
+		"commit", strings.TrimSpace(hash),
+		"duration_ms", time.Since(start).Milliseconds())
+	return &GitOutput{Output: strings.TrimSpace(out), Commit: strings.TrimSpace(hash), Success: true}, nil
+}
+
+// executeGitStatus reports the changed files in the workspace
+func executeGitStatus(ctx context.Context, workspaceDir string, input GitStatusInput) (*GitStatusOutput, error) {
+	slog.Info("Starting git status operation", "path", input.Path, "workspace", workspaceDir)
+	paths, err := gitPaths(workspaceDir, input.Path)
+	if err != nil {
+		return nil, err
+	}
+	out, err := RunGit(ctx, workspaceDir, append([]string{"status", "--porcelain=v1", "--branch", "--untracked-files=all", "--"}, paths...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &GitStatusOutput{Success: true}
+	for line := range strings.SplitSeq(strings.TrimRight(out, "\n"), "\n") {
+		if branch, ok := strings.CutPrefix(line, "## "); ok {
+			branch, _, _ = strings.Cut(branch, "...")
+			output.Branch = strings.TrimPrefix(branch, "No commits yet on ")
+			continue
+		}
+		if len(line) < 4 {
+			continue
+		}
+		path := line[3:]
+		// Renames are reported as "old -> new"
+		if _, newPath, ok := strings.Cut(path, " -> "); ok {
+			path = newPath
+		}
+		output.Files = append(output.Files, GitFileStatus{Path: strings.Trim(path, `"`), Status: line[:2]})
+	}
+	output.Clean = len(output.Files) == 0
+	return output, nil
+}
+
+// executeGitDiff returns the changes in the workspace as a unified diff
+func executeGitDiff(ctx context.Context, workspaceDir string, input GitDiffInput) (*GitDiffOutput, error) {
+	slog.Info("Starting git diff operation",
+		"path", input.Path,
+		"ref", input.Ref,
+		"staged", input.Staged,
+		"workspace", workspaceDir)
+	if strings.HasPrefix(input.Ref, "-") {
+		return nil, fmt.Errorf("invalid ref %q", input.Ref)
+	}
+	paths, err := gitPaths(workspaceDir, input.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"diff", "--no-color", "--no-ext-diff"}
+	if input.Staged {
+		args = append(args, "--cached")
+	}
+	if input.Stat {
+		args = append(args, "--stat")
+	}
+	if input.Ref != "" {
+		args = append(args, input.Ref)
+	}
+	out, err := RunGit(ctx, workspaceDir, append(append(args, "--"), paths...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &GitDiffOutput{
+		Diff:      truncateUTF8(out, MaxGitDiffSize),
+		Truncated: len(out) > MaxGitDiffSize,
+		Success:   true,
+	}, nil
+}
+
+// executeGitLog returns the most recent commits in the workspace
+func executeGitLog(ctx context.Context, workspaceDir string, input GitLogInput) (*GitLogOutput, error) {
+	slog.Info("Starting git log operation", "path", input.Path, "max_count", input.MaxCount, "workspace", workspaceDir)
+	count := input.MaxCount
+	if count <= 0 {
+		count = defaultGitLogCount
+	}
+	count = min(count, maxGitLogCount)
+	paths, err := gitPaths(workspaceDir, input.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{"log", fmt.Sprintf("--max-count=%d", count), "--format=%h%x00%an%x00%aI%x00%s", "--"}
+	out, err := RunGit(ctx, workspaceDir, append(args, paths...)...)
+	if err != nil {
+		// A repository without commits has no log
+		if strings.Contains(err.Error(), "does not have any commits") {
+			return &GitLogOutput{Commits: []GitCommitInfo{}, Success: true}, nil
+		}
+		return nil, err
+	}
+
+	output := &GitLogOutput{Commits: []GitCommitInfo{}, Success: true}
+	for line := range strings.SplitSeq(strings.TrimSpace(out), "\n") {
+		fields := strings.Split(line, "\x00")
+		if len(fields) != 4 {
+			continue
+		}
+		output.Commits = append(output.Commits, GitCommitInfo{Hash: fields[0], Author: fields[1], Date: fields[2], Subject: fields[3]})
+	}
+	return output, nil
+}
+
+// gitPaths returns the non-empty relative paths, rejecting any outside the workspace, for
+// use after "--"
+func gitPaths(workspaceDir string, paths ...string) ([]string, error) {
+	var cleaned []string
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		if _, err := resolveWorkspacePath(workspaceDir, path); err != nil {
+			return nil, err
+		}
+		cleaned = append(cleaned, filepath.Clean(path))
+	}
+	return cleaned, nil
+}
+
+// newGitTool creates a git tool named name that runs fn in the workspace directory
+func newGitTool[In, Out any](name, description string, fn func(ctx context.Context, input In) (*Out, error), failed func(err error) *Out) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{Name: name, Description: description},
+		func(ctx tool.Context, input In) *Out {
+			observe := observeTool(name)
+			output, err := fn(ctx, input)
+			observe(err)
+			if err != nil {
+				return failed(err)
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create %s tool: %v", name, err))
+	}
+	return t
+}
+
+// GitTools creates the git tools for the default workspace directory
+func GitTools() []tool.Tool {
+	return NewGitToolsWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGitToolsWithWorkspace creates the gitInit, gitStatus, gitDiff, gitAdd, gitCommit,
+// and gitLog tools with a custom workspace directory
+func NewGitToolsWithWorkspace(workspaceDir string) []tool.Tool {
+	return []tool.Tool{
+		NewGitInitToolWithWorkspace(workspaceDir),
+		NewGitStatusToolWithWorkspace(workspaceDir),
+		NewGitDiffToolWithWorkspace(workspaceDir),
+		NewGitAddToolWithWorkspace(workspaceDir),
+		NewGitCommitToolWithWorkspace(workspaceDir),
+		NewGitLogToolWithWorkspace(workspaceDir),
+	}
+}
+
+// NewGitInitToolWithWorkspace creates a gitInit tool that initializes a repository in the workspace
+func NewGitInitToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitInit",
+		"Initialize a git repository in the workspace directory, unless it already is one.",
+		func(ctx context.Context, _ GitInitInput) (*GitOutput, error) {
+			return executeGitInit(ctx, workspaceDir)
+		},
+		func(err error) *GitOutput { return &GitOutput{Error: err.Error()} })
+}
+
+// NewGitStatusToolWithWorkspace creates a gitStatus tool that lists the changed files in the workspace
+func NewGitStatusToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitStatus",
+		"Show the current branch and the changed, staged, and untracked files in the workspace git repository, with their two-letter git status codes.",
+		func(ctx context.Context, input GitStatusInput) (*GitStatusOutput, error) {
+			return executeGitStatus(ctx, workspaceDir, input)
+		},
+		func(err error) *GitStatusOutput { return &GitStatusOutput{Error: err.Error()} })
+}
+
+// NewGitDiffToolWithWorkspace creates a gitDiff tool that shows the changes in the workspace
+func NewGitDiffToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitDiff",
+		"Show the changes in the workspace git repository as a unified diff: unstaged changes by default, staged ones with staged, or everything since a commit with ref (such as HEAD~1). Use stat for a summary of the changed files. Prefer it to re-reading files to see what changed.",
+		func(ctx context.Context, input GitDiffInput) (*GitDiffOutput, error) {
+			return executeGitDiff(ctx, workspaceDir, input)
+		},
+		func(err error) *GitDiffOutput { return &GitDiffOutput{Error: err.Error()} })
+}
+
+// NewGitAddToolWithWorkspace creates a gitAdd tool that stages changes in the workspace
+func NewGitAddToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitAdd",
+		"Stage changes in the workspace git repository for the next commit: the given paths, or all changes when no paths are given. Returns a summary of the staged changes.",
+		func(ctx context.Context, input GitAddInput) (*GitOutput, error) {
+			return executeGitAdd(ctx, workspaceDir, input)
+		},
+		func(err error) *GitOutput { return &GitOutput{Error: err.Error()} })
+}
+
+// NewGitCommitToolWithWorkspace creates a gitCommit tool that commits the staged changes in the workspace
+func NewGitCommitToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitCommit",
+		"Commit the staged changes in the workspace git repository with a message; set all to stage every change first. Returns the commit hash.",
+		func(ctx context.Context, input GitCommitInput) (*GitOutput, error) {
+			return executeGitCommit(ctx, workspaceDir, input)
+		},
+		func(err error) *GitOutput { return &GitOutput{Error: err.Error()} })
+}
+
+// NewGitLogToolWithWorkspace creates a gitLog tool that lists the recent commits in the workspace
+func NewGitLogToolWithWorkspace(workspaceDir string) tool.Tool {
+	return newGitTool("gitLog",
+		"List the most recent commits in the workspace git repository, newest first, optionally only those that changed a path.",
+		func(ctx context.Context, input GitLogInput) (*GitLogOutput, error) {
+			return executeGitLog(ctx, workspaceDir, input)
+		},
+		func(err error) *GitLogOutput { return &GitLogOutput{Error: err.Error()} })
+}
@@ -0,0 +1,217 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGitTools(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+
+	if _, err := executeGitInit(ctx, workspaceDir); err != nil {
+		t.Fatalf("executeGitInit() error = %v", err)
+	}
+	output, err := executeGitInit(ctx, workspaceDir)
+	if err != nil || !strings.Contains(output.Output, "already a git repository") {
+		t.Fatalf("executeGitInit() on a repository = %+v, %v, want it left as is", output, err)
+	}
+
+	log, err := executeGitLog(ctx, workspaceDir, GitLogInput{})
+	if err != nil || len(log.Commits) != 0 {
+		t.Fatalf("executeGitLog() without commits = %+v, %v, want no commits", log, err)
+	}
+
+	writeFiles(t, workspaceDir, map[string]string{"calc.go": "package calc\n", "README.md": "# Calc\n"})
+	status, err := executeGitStatus(ctx, workspaceDir, GitStatusInput{})
+	if err != nil {
+		t.Fatalf("executeGitStatus() error = %v", err)
+	}
+	wantFiles := []GitFileStatus{{Path: "README.md", Status: "??"}, {Path: "calc.go", Status: "??"}}
+	if status.Clean || !reflect.DeepEqual(status.Files, wantFiles) {
+		t.Errorf("executeGitStatus() = %+v, want untracked %+v", status, wantFiles)
+	}
+
+	if _, err := executeGitCommit(ctx, workspaceDir, GitCommitInput{Message: "Add calc"}); err == nil || !strings.Contains(err.Error(), "nothing to commit") {
+		t.Errorf("executeGitCommit() without staged changes error = %v, want nothing to commit", err)
+	}
+	added, err := executeGitAdd(ctx, workspaceDir, GitAddInput{Paths: []string{"calc.go"}})
+	if err != nil || !strings.Contains(added.Output, "calc.go") || strings.Contains(added.Output, "README.md") {
+		t.Fatalf("executeGitAdd() = %+v, %v, want only calc.go staged", added, err)
+	}
+	commit, err := executeGitCommit(ctx, workspaceDir, GitCommitInput{Message: "Add calc"})
+	if err != nil || commit.Commit == "" {
+		t.Fatalf("executeGitCommit() = %+v, %v, want a commit hash", commit, err)
+	}
+	if _, err := executeGitCommit(ctx, workspaceDir, GitCommitInput{Message: "Add README", All: true}); err != nil {
+		t.Fatalf("executeGitCommit() with all error = %v", err)
+	}
+
+	status, err = executeGitStatus(ctx, workspaceDir, GitStatusInput{})
+	if err != nil || !status.Clean || status.Branch == "" {
+		t.Errorf("executeGitStatus() after commit = %+v, %v, want a clean branch", status, err)
+	}
+
+	writeFiles(t, workspaceDir, map[string]string{"calc.go": "package calc\n\nfunc Add(a, b int) int { return a + b }\n"})
+	diff, err := executeGitDiff(ctx, workspaceDir, GitDiffInput{})
+	if err != nil || !strings.Contains(diff.Diff, "+func Add(a, b int) int") {
+		t.Errorf("executeGitDiff() = %+v, %v, want the unstaged change", diff, err)
+	}
+	diff, err = executeGitDiff(ctx, workspaceDir, GitDiffInput{Ref: "HEAD~1", Stat: true})
+	if err != nil || !strings.Contains(diff.Diff, "README.md") || !strings.Contains(diff.Diff, "calc.go") {
+		t.Errorf("executeGitDiff() since HEAD~1 = %+v, %v, want a summary of both files", diff, err)
+	}
+	diff, err = executeGitDiff(ctx, workspaceDir, GitDiffInput{Path: "README.md"})
+	if err != nil || diff.Diff != "" {
+		t.Errorf("executeGitDiff() of an unchanged path = %+v, %v, want no diff", diff, err)
+	}
+
+	log, err = executeGitLog(ctx, workspaceDir, GitLogInput{})
+	if err != nil {
+		t.Fatalf("executeGitLog() error = %v", err)
+	}
+	var subjects []string
+	for _, c := range log.Commits {
+		subjects = append(subjects, c.Subject)
+		if c.Author != GitAuthorName || c.Hash == "" || c.Date == "" {
+			t.Errorf("commit = %+v, want hash, date, and the workspace author", c)
+		}
+	}
+	if !reflect.DeepEqual(subjects, []string{"Add README", "Add calc"}) {
+		t.Errorf("log subjects = %v, want newest first", subjects)
+	}
+	log, err = executeGitLog(ctx, workspaceDir, GitLogInput{Path: "calc.go", MaxCount: 5})
+	if err != nil || len(log.Commits) != 1 || log.Commits[0].Subject != "Add calc" {
+		t.Errorf("executeGitLog() of calc.go = %+v, %v, want one commit", log, err)
+	}
+}
+
+func TestGitTools_InvalidInput(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+	if _, err := executeGitInit(ctx, workspaceDir); err != nil {
+		t.Fatalf("executeGitInit() error = %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		run         func() error
+		errContains string
+	}{
+		{
+			name: "diff option as ref",
+			run: func() error {
+				_, err := executeGitDiff(ctx, workspaceDir, GitDiffInput{Ref: "--output=/tmp/x"})
+				return err
+			},
+			errContains: "invalid ref",
+		},
+		{
+			name: "add outside the workspace",
+			run: func() error {
+				_, err := executeGitAdd(ctx, workspaceDir, GitAddInput{Paths: []string{"../x"}})
+				return err
+			},
+			errContains: "path traversal detected",
+		},
+		{
+			name: "status outside the workspace",
+			run: func() error {
+				_, err := executeGitStatus(ctx, workspaceDir, GitStatusInput{Path: "/etc"})
+				return err
+			},
+			errContains: "absolute paths are not allowed",
+		},
+		{
+			name: "commit without message",
+			run: func() error {
+				_, err := executeGitCommit(ctx, workspaceDir, GitCommitInput{All: true})
+				return err
+			},
+			errContains: "message is required",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestRunGit_EnclosingRepository(t *testing.T) {
+	ctx := context.Background()
+	// The workspace lies in a checkout of the user but is not a repository of its own
+	parent := t.TempDir()
+	if _, err := RunGit(ctx, parent, "init", "--quiet"); err != nil {
+		t.Fatalf("RunGit(init) error = %v", err)
+	}
+	workspaceDir := filepath.Join(parent, "workspace")
+	writeFiles(t, workspaceDir, map[string]string{"calc.go": "package calc\n"})
+
+	if _, err := executeGitStatus(ctx, workspaceDir, GitStatusInput{}); err == nil || !strings.Contains(err.Error(), "not a git repository") {
+		t.Errorf("executeGitStatus() error = %v, want not a git repository", err)
+	}
+	if _, err := executeGitAdd(ctx, workspaceDir, GitAddInput{}); err == nil {
+		t.Error("executeGitAdd() error = nil, want the enclosing repository left alone")
+	}
+	if _, err := CommitWorkspace(ctx, workspaceDir, "stage", ""); err == nil {
+		t.Error("CommitWorkspace() error = nil, want the enclosing repository left alone")
+	}
+	if out, err := RunGit(ctx, parent, "status", "--porcelain", "--untracked-files=no"); err != nil || out != "" {
+		t.Errorf("enclosing repository status = %q, %v, want nothing staged", out, err)
+	}
+	if _, err := RunGit(ctx, parent); err == nil {
+		t.Error("RunGit() without a command error = nil")
+	}
+}
+
+func TestRunGit_RepositoryConfig(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+	if _, err := RunGit(ctx, workspaceDir, "init", "--quiet"); err != nil {
+		t.Fatalf("RunGit(init) error = %v", err)
+	}
+	// Code in the sandbox can change the repository configuration to run commands on the host
+	marker := filepath.Join(t.TempDir(), "ran")
+	hook := "#!/bin/sh\ntouch " + marker + "\n"
+	writeFiles(t, workspaceDir, map[string]string{"hooks/post-commit": hook, "calc.go": "package calc\n"})
+	if err := os.Chmod(filepath.Join(workspaceDir, "hooks", "post-commit"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, kv := range [][2]string{{"core.fsmonitor", "touch " + marker + " #"}, {"core.hooksPath", filepath.Join(workspaceDir, "hooks")}} {
+		if _, err := RunGit(ctx, workspaceDir, "config", kv[0], kv[1]); err != nil {
+			t.Fatalf("RunGit(config %s) error = %v", kv[0], err)
+		}
+	}
+
+	if _, err := CommitWorkspace(ctx, workspaceDir, "stage", ""); err != nil {
+		t.Fatalf("CommitWorkspace() error = %v", err)
+	}
+	if _, err := executeGitStatus(ctx, workspaceDir, GitStatusInput{}); err != nil {
+		t.Fatalf("executeGitStatus() error = %v", err)
+	}
+	if _, err := os.Stat(marker); !os.IsNotExist(err) {
+		t.Errorf("repository configuration ran a command: %v", err)
+	}
+}
+
+func TestGitTools_ToolCreation(t *testing.T) {
+	if got := len(GitTools()); got != 6 {
+		t.Fatalf("GitTools() returned %d tools, want 6", got)
+	}
+	var names []string
+	for _, tool := range NewGitToolsWithWorkspace(t.TempDir()) {
+		names = append(names, tool.Name())
+	}
+	want := []string{"gitInit", "gitStatus", "gitDiff", "gitAdd", "gitCommit", "gitLog"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("tool names = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,246 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Operations of the goMod tool
+const (
+	// GoModInit creates go.mod
+	GoModInit = "init"
+	// GoModTidy adds missing and removes unused requirements
+	GoModTidy = "tidy"
+	// GoModGet adds or updates requirements
+	GoModGet = "get"
+)
+
+// DefaultModulePath is the module path used by the init operation when none is given
+const DefaultModulePath = "workspace"
+
+// GoModInput defines the input parameters for the goMod tool
+type GoModInput struct {
+	// Operation is init, tidy, or get
+	Operation string `json:"operation"`
+	// Module is the module path for init (defaults to DefaultModulePath)
+	Module string `json:"module,omitempty"`
+	// Packages are the modules or packages for get, optionally with a version such as
+	// github.com/google/uuid@v1.6.0
+	Packages []string `json:"packages,omitempty"`
+	// Dir is the relative directory of the module within the workspace (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+}
+
+// GoModule describes a requirement in go.mod
+type GoModule struct {
+	// Path is the module path
+	Path string `json:"path"`
+	// Version is the required version
+	Version string `json:"version"`
+	// PreviousVersion is the version required before the operation, for updated modules
+	PreviousVersion string `json:"previousVersion,omitempty"`
+	// Indirect indicates that no package of the module is imported directly
+	Indirect bool `json:"indirect,omitempty"`
+}
+
+// GoModOutput defines the output structure for the goMod tool
+type GoModOutput struct {
+	// Module is the module path in go.mod
+	Module string `json:"module,omitempty"`
+	// Added are the requirements the operation added
+	Added []GoModule `json:"added,omitempty"`
+	// Updated are the requirements whose version changed
+	Updated []GoModule `json:"updated,omitempty"`
+	// Removed are the requirements the operation removed
+	Removed []GoModule `json:"removed,omitempty"`
+	// Output is the output of the go command, which explains failures
+	Output string `json:"output,omitempty"`
+	// ExitCode is the exit code of the go command
+	ExitCode int `json:"exitCode"`
+	// Success indicates whether the go command exited with code 0
+	Success bool `json:"success"`
+	// Error contains the error message if the operation could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// goModFile is the part of the `go mod edit -json` output the goMod tool uses
+type goModFile struct {
+	Module struct {
+		Path string
+	}
+	Require []struct {
+		Path     string
+		Version  string
+		Indirect bool
+	}
+}
+
+// RunGoMod runs an init, tidy, or get operation on the module in the workspace and
+// reports the requirements it changed. Like RunCommand, a go command that exits non-zero
+// is not an error; its output and exit code are reported. It cannot be run in read-only mode.
+func RunGoMod(ctx context.Context, workspaceDir string, input GoModInput) (*GoModOutput, error) {
+	if err := ToolConfig.checkWritable("go mod " + input.Operation); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	slog.Info("Starting go mod operation",
+		"operation", input.Operation,
+		"packages", input.Packages,
+		"dir", input.Dir,
+		"workspace", workspaceDir)
+
+	var args []string
+	switch input.Operation {
+	case GoModInit:
+		resolvedDir, err := resolveWorkspacePath(workspaceDir, cmp.Or(input.Dir, "."))
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve module directory: %w", err)
+		}
+		if _, err := os.Stat(filepath.Join(resolvedDir, "go.mod")); err == nil {
+			return nil, fmt.Errorf("go.mod already exists in %s", cmp.Or(input.Dir, "."))
+		}
+		module := input.Module
+		if module == "" {
+			module = DefaultModulePath
+		}
+		if strings.HasPrefix(module, "-") {
+			return nil, fmt.Errorf("invalid module path %q", module)
+		}
+		args = []string{"mod", "init", module}
+	case GoModTidy:
+		args = []string{"mod", "tidy"}
+	case GoModGet:
+		if len(input.Packages) == 0 {
+			return nil, fmt.Errorf("packages are required for get")
+		}
+		for _, pkg := range input.Packages {
+			if pkg == "" || strings.HasPrefix(pkg, "-") {
+				return nil, fmt.Errorf("invalid package %q", pkg)
+			}
+		}
+		args = append([]string{"get"}, input.Packages...)
+	default:
+		return nil, fmt.Errorf("unknown operation %q (use %s, %s, or %s)", input.Operation, GoModInit, GoModTidy, GoModGet)
+	}
+
+	before, err := readGoMod(ctx, workspaceDir, input.Dir)
+	if err != nil {
+		return nil, err
+	}
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{Command: "go", Args: args, Dir: input.Dir})
+	if err != nil {
+		return nil, err
+	}
+	after, err := readGoMod(ctx, workspaceDir, input.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	output := diffGoMod(before, after)
+	output.Output = strings.TrimSpace(result.Stderr + "\n" + result.Stdout)
+	output.ExitCode = result.ExitCode
+	output.Success = result.Success
+
+	slog.Info("Go mod operation completed",
+		"operation", input.Operation,
+		"exit_code", output.ExitCode,
+		"added", len(output.Added),
+		"updated", len(output.Updated),
+		"removed", len(output.Removed),
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// readGoMod reads go.mod in dir within the workspace, returning an empty file if there is none
+func readGoMod(ctx context.Context, workspaceDir, dir string) (*goModFile, error) {
+	resolvedDir, err := resolveWorkspacePath(workspaceDir, cmp.Or(dir, "."))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve module directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(resolvedDir, "go.mod")); errors.Is(err, os.ErrNotExist) {
+		return &goModFile{}, nil
+	}
+
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{Command: "go", Args: []string{"mod", "edit", "-json"}, Dir: dir})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("failed to read go.mod: %s", strings.TrimSpace(result.Stderr))
+	}
+	var file goModFile
+	if err := json.Unmarshal([]byte(result.Stdout), &file); err != nil {
+		return nil, fmt.Errorf("failed to parse go.mod: %w", err)
+	}
+	return &file, nil
+}
+
+// diffGoMod compares the requirements of two versions of go.mod
+func diffGoMod(before, after *goModFile) *GoModOutput {
+	output := &GoModOutput{Module: after.Module.Path}
+	previous := make(map[string]string, len(before.Require))
+	for _, req := range before.Require {
+		previous[req.Path] = req.Version
+	}
+	current := make(map[string]bool, len(after.Require))
+	for _, req := range after.Require {
+		current[req.Path] = true
+		module := GoModule{Path: req.Path, Version: req.Version, Indirect: req.Indirect}
+		version, ok := previous[req.Path]
+		switch {
+		case !ok:
+			output.Added = append(output.Added, module)
+		case version != req.Version:
+			module.PreviousVersion = version
+			output.Updated = append(output.Updated, module)
+		}
+	}
+	for _, req := range before.Require {
+		if !current[req.Path] {
+			output.Removed = append(output.Removed, GoModule{Path: req.Path, Version: req.Version, Indirect: req.Indirect})
+		}
+	}
+	return output
+}
+
+// GoModTool creates a new goMod tool that manages go.mod within the workspace directory
+func GoModTool() tool.Tool {
+	return NewGoModToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoModToolWithWorkspace creates a new goMod tool with a custom workspace directory
+func NewGoModToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goMod",
+			Description: "Manage the Go module in the workspace: operation init creates go.mod (with module as its path), tidy adds the requirements of imported packages and removes unused ones, and get adds or updates the modules in packages (such as github.com/google/uuid@v1.6.0). Reports the requirements added, updated, and removed, and the go command output when it fails.",
+		},
+		func(ctx tool.Context, input GoModInput) *GoModOutput {
+			observe := observeTool("goMod")
+			output, err := RunGoMod(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &GoModOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goMod tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunGoMod(t *testing.T) {
+	// Keep module resolution offline
+	t.Setenv("GOPROXY", "off")
+	t.Setenv("GOFLAGS", "-mod=mod")
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+
+	output, err := RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModInit, Module: "example.com/greet"})
+	if err != nil {
+		t.Fatalf("RunGoMod(init) error = %v", err)
+	}
+	if !output.Success || output.Module != "example.com/greet" {
+		t.Errorf("RunGoMod(init) = %+v, want module example.com/greet", output)
+	}
+	if _, err := RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModInit}); err == nil || !strings.Contains(err.Error(), "go.mod already exists") {
+		t.Errorf("RunGoMod(init) on a module error = %v, want go.mod already exists", err)
+	}
+
+	// A local module replaces a download, so tidy works offline
+	writeFiles(t, workspaceDir, map[string]string{
+		"dep/go.mod": "module example.com/dep\n\ngo 1.21\n",
+		"dep/dep.go": "package dep\n\n// Name is the module name\nconst Name = \"dep\"\n",
+		"greet.go":   "package greet\n\nimport \"example.com/dep\"\n\nvar _ = dep.Name\n",
+	})
+	goMod := filepath.Join(workspaceDir, "go.mod")
+	data, err := os.ReadFile(goMod)
+	if err != nil {
+		t.Fatalf("failed to read go.mod: %v", err)
+	}
+	if err := os.WriteFile(goMod, append(data, "\nreplace example.com/dep => ./dep\n"...), 0644); err != nil {
+		t.Fatalf("failed to write go.mod: %v", err)
+	}
+
+	output, err = RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModTidy})
+	if err != nil {
+		t.Fatalf("RunGoMod(tidy) error = %v", err)
+	}
+	want := []GoModule{{Path: "example.com/dep", Version: "v0.0.0-00010101000000-000000000000"}}
+	if !output.Success || !reflect.DeepEqual(output.Added, want) || output.Removed != nil {
+		t.Errorf("RunGoMod(tidy) = %+v, want %+v added", output, want)
+	}
+
+	if err := os.Remove(filepath.Join(workspaceDir, "greet.go")); err != nil {
+		t.Fatalf("failed to remove greet.go: %v", err)
+	}
+	output, err = RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModTidy})
+	if err != nil {
+		t.Fatalf("RunGoMod(tidy) error = %v", err)
+	}
+	if !output.Success || output.Added != nil || !reflect.DeepEqual(output.Removed, want) {
+		t.Errorf("RunGoMod(tidy) = %+v, want %+v removed", output, want)
+	}
+
+	// Failures of the go command are reported, not returned
+	output, err = RunGoMod(ctx, workspaceDir, GoModInput{Operation: GoModGet, Packages: []string{"example.invalid/missing@v1.0.0"}})
+	if err != nil {
+		t.Fatalf("RunGoMod(get) error = %v", err)
+	}
+	if output.Success || output.ExitCode == 0 || !strings.Contains(output.Output, "example.invalid/missing") {
+		t.Errorf("RunGoMod(get) = %+v, want a failure naming the module", output)
+	}
+}
+
+func TestRunGoMod_InvalidInput(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       GoModInput
+		errContains string
+	}{
+		{name: "unknown operation", input: GoModInput{Operation: "vendor"}, errContains: `unknown operation "vendor"`},
+		{name: "get without packages", input: GoModInput{Operation: GoModGet}, errContains: "packages are required"},
+		{name: "flag as package", input: GoModInput{Operation: GoModGet, Packages: []string{"-modfile=/tmp/go.mod"}}, errContains: "invalid package"},
+		{name: "flag as module", input: GoModInput{Operation: GoModInit, Module: "-x"}, errContains: "invalid module path"},
+		{name: "path traversal", input: GoModInput{Operation: GoModTidy, Dir: "../"}, errContains: "path traversal detected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RunGoMod(context.Background(), t.TempDir(), tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("RunGoMod() error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestGoModTool_ToolCreation(t *testing.T) {
+	if GoModTool() == nil {
+		t.Fatal("GoModTool() returned nil")
+	}
+	if got := NewGoModToolWithWorkspace(t.TempDir()).Name(); got != "goMod" {
+		t.Errorf("Name() = %q, want %q", got, "goMod")
+	}
+}
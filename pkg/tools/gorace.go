@@ -0,0 +1,279 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxDataRaces is the maximum number of distinct data races returned by the goRace tool
+const MaxDataRaces = 20
+
+// maxRaceFrames caps the frames kept of each stack of a data race
+const maxRaceFrames = 8
+
+// Lines of the race detector output
+const (
+	raceSeparator = "=================="
+	raceWarning   = "WARNING: DATA RACE"
+)
+
+// raceAddress matches the memory address in the access lines of a race report
+var raceAddress = regexp.MustCompile(` at 0x[0-9a-f]+`)
+
+// raceFrameOffset matches the program counter offset after the file and line of a frame
+var raceFrameOffset = regexp.MustCompile(` \+0x[0-9a-f]+$`)
+
+// GoRaceInput defines the input parameters for the goRace tool
+type GoRaceInput struct {
+	// Dir is the relative directory within the workspace to run the tests in (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+	// Packages are the package patterns to test (defaults to ./...)
+	Packages []string `json:"packages,omitempty"`
+	// Run restricts the run to tests matching this regular expression
+	Run string `json:"run,omitempty"`
+	// Count runs each test this many times, which makes rare races more likely to show (defaults to 1)
+	Count int `json:"count,omitempty"`
+	// TimeoutSeconds overrides the default command timeout
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// RaceStack is one stack of a data race report
+type RaceStack struct {
+	// Kind is the access or goroutine creation, such as "Write by goroutine 7" or
+	// "Goroutine 7 (running) created at"
+	Kind string `json:"kind"`
+	// Frames are the calls within the workspace, innermost first, as "function file:line";
+	// frames of the standard library and other modules are left out unless none is in the
+	// workspace, in which case the innermost is kept with the base name of its file
+	Frames []string `json:"frames"`
+}
+
+// DataRace is a data race found by the race detector
+type DataRace struct {
+	// Package is the import path of the package whose tests found the race
+	Package string `json:"package"`
+	// Test is the test that was running, if any
+	Test string `json:"test,omitempty"`
+	// Stacks are the two conflicting accesses followed by where their goroutines were created
+	Stacks []RaceStack `json:"stacks"`
+}
+
+// GoRaceOutput defines the output structure for the goRace tool
+type GoRaceOutput struct {
+	// Races are the distinct data races found
+	Races []DataRace `json:"races"`
+	// Truncated indicates that only the first MaxDataRaces races are returned
+	Truncated bool `json:"truncated,omitempty"`
+	// Packages are the results of each tested package
+	Packages []GoTestPackage `json:"packages"`
+	// Passed indicates whether every package built and passed its tests without races
+	Passed bool `json:"passed"`
+	// Failures is the output of the failed tests and builds without the race reports,
+	// truncated to MaxTestFailureOutput bytes
+	Failures string `json:"failures,omitempty"`
+	// Success indicates whether the tests ran; it is true even when they fail
+	Success bool `json:"success"`
+	// Error contains the error message if the tests could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// RunGoRace runs `go test -race -json` in the workspace directory and returns the data
+// races found, with their stacks trimmed to the workspace code, and the result of each
+// package. Races and failing tests are not an error.
+func RunGoRace(ctx context.Context, workspaceDir string, input GoRaceInput) (*GoRaceOutput, error) {
+	packages := input.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	for _, pkg := range packages {
+		if strings.HasPrefix(pkg, "-") {
+			return nil, fmt.Errorf("invalid package pattern %q", pkg)
+		}
+	}
+
+	args := []string{"test", "-race", "-json"}
+	if input.Count > 0 {
+		args = append(args, "-count="+strconv.Itoa(input.Count))
+	}
+	if input.Run != "" {
+		args = append(args, "-run="+input.Run)
+	}
+	args = append(args, packages...)
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{
+		Command:        "go",
+		Args:           args,
+		Dir:            input.Dir,
+		TimeoutSeconds: input.TimeoutSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// RunCommand has already checked that the directory resolves
+	workspace, _ := resolveWorkspacePath(workspaceDir, ".")
+
+	races, stdout, truncated := parseRaceEvents(result.Stdout, workspace)
+	tests := parseTestEvents(stdout)
+	if len(tests.Packages) == 0 {
+		if !result.Success {
+			return nil, fmt.Errorf("go test -race exited with code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+		}
+		return nil, fmt.Errorf("go test -race reported no packages")
+	}
+	output := &GoRaceOutput{
+		Races:     races,
+		Truncated: truncated,
+		Packages:  tests.Packages,
+		Passed:    tests.Passed && len(races) == 0,
+		Failures:  tests.Failures,
+		Success:   true,
+	}
+	if !result.Success && tests.Passed && len(races) == 0 {
+		output.Passed = false
+		output.Failures = truncateUTF8(strings.TrimSpace(result.Stderr+"\n"+output.Failures), MaxTestFailureOutput)
+	}
+
+	slog.Info("Go race tests completed",
+		"dir", input.Dir,
+		"packages", len(output.Packages),
+		"races", len(output.Races),
+		"passed", output.Passed)
+	return output, nil
+}
+
+// parseRaceEvents extracts the data race reports from `go test -json` output and returns
+// the distinct races, the output without the reports, and whether races were left out
+// beyond MaxDataRaces. Frame paths are made relative to the absolute workspace directory.
+func parseRaceEvents(stdout, workspace string) ([]DataRace, string, bool) {
+	races := []DataRace{}
+	seen := map[string]bool{}
+	truncated := false
+	// The report in progress of each package and test
+	reports := map[string][]string{}
+
+	var rest strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxCommandOutput)
+	for scanner.Scan() {
+		line := scanner.Text()
+		var e testEvent
+		if err := json.Unmarshal([]byte(line), &e); err != nil || e.Action != "output" {
+			rest.WriteString(line + "\n")
+			continue
+		}
+		key := e.Package + "\x00" + e.Test
+		text := strings.TrimRight(e.Output, "\n")
+		report, inReport := reports[key]
+		switch {
+		case text == raceWarning:
+			reports[key] = []string{}
+		case text == raceSeparator && inReport:
+			delete(reports, key)
+			race := parseRaceReport(report, workspace)
+			race.Package, race.Test = e.Package, e.Test
+			signature := fmt.Sprint(race.Stacks)
+			if seen[signature] {
+				continue
+			}
+			seen[signature] = true
+			if len(races) == MaxDataRaces {
+				truncated = true
+				continue
+			}
+			races = append(races, race)
+		case inReport:
+			reports[key] = append(report, text)
+		case text != raceSeparator:
+			rest.WriteString(line + "\n")
+		}
+	}
+	return races, rest.String(), truncated
+}
+
+// parseRaceReport converts the lines of a race report between its warning and its closing
+// separator into stacks
+func parseRaceReport(lines []string, workspace string) DataRace {
+	race := DataRace{Stacks: []RaceStack{}}
+	var stack *RaceStack
+	// outside is the innermost frame of the current stack, kept when no frame is in the workspace
+	function, outside := "", ""
+	flush := func() {
+		if stack != nil && len(stack.Frames) == 0 && outside != "" {
+			stack.Frames = append(stack.Frames, outside)
+		}
+	}
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+			continue
+		case !strings.HasPrefix(line, " ") && strings.HasSuffix(line, ":"):
+			// A section such as "Read at 0x00c0000182a8 by goroutine 8:"
+			flush()
+			race.Stacks = append(race.Stacks, RaceStack{
+				Kind:   raceAddress.ReplaceAllString(strings.TrimSuffix(line, ":"), ""),
+				Frames: []string{},
+			})
+			stack = &race.Stacks[len(race.Stacks)-1]
+			outside = ""
+		case stack == nil:
+			continue
+		case strings.HasPrefix(line, "      "):
+			// The file and line of the function before it, such as "/src/calc/calc.go:7 +0x36"
+			file := raceFrameOffset.ReplaceAllString(trimmed, "")
+			rel, err := filepath.Rel(workspace, file)
+			if err != nil || !filepath.IsAbs(file) || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				if outside == "" {
+					outside = function + " " + filepath.Base(file)
+				}
+				continue
+			}
+			if len(stack.Frames) < maxRaceFrames {
+				stack.Frames = append(stack.Frames, function+" "+filepath.ToSlash(rel))
+			}
+		default:
+			// A function such as "example.com/calc/calc.(*Counter).Inc()", shortened to calc.(*Counter).Inc
+			function = strings.TrimSuffix(trimmed, "()")
+			function = function[strings.LastIndex(function, "/")+1:]
+		}
+	}
+	flush()
+	return race
+}
+
+// GoRaceTool creates a new goRace tool that runs the Go tests with the race detector within the workspace directory
+func GoRaceTool() tool.Tool {
+	return NewGoRaceToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoRaceToolWithWorkspace creates a new goRace tool with a custom workspace directory
+func NewGoRaceToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goRace",
+			Description: "Run go test -race in the workspace directory and return the data races found, each with the conflicting accesses and the goroutine creations trimmed to the workspace code, and the pass/fail status of each package. Use it to verify concurrency claims; set count to run the tests several times, since races do not show on every run.",
+		},
+		func(ctx tool.Context, input GoRaceInput) *GoRaceOutput {
+			observe := observeTool("goRace")
+			output, err := RunGoRace(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &GoRaceOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goRace tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// raceReport formats the events of a race report in test of pkg under workspace, with the
+// read of the race at line of calc.go
+func raceReport(pkg, test, workspace string, line int) string {
+	var sb strings.Builder
+	for _, text := range []string{
+		"==================",
+		"WARNING: DATA RACE",
+		"Read at 0x00c0000182b8 by goroutine 8:",
+		"  example.com/calc/calc.(*Counter).Inc()",
+		"      " + workspace + "/calc/calc.go:" + strconv.Itoa(line) + " +0x36",
+		"  example.com/calc/calc.TestRace.func1()",
+		"      " + workspace + "/calc/calc_test.go:9 +0x31",
+		"",
+		"Previous write at 0x00c0000182b8 by goroutine 7:",
+		"  example.com/calc/calc.(*Counter).Inc()",
+		"      " + workspace + "/calc/calc.go:7 +0x116",
+		"  testing.tRunner()",
+		"      /usr/local/go/src/testing/testing.go:2193 +0x21c",
+		"",
+		"Goroutine 7 (running) created at:",
+		"  testing.(*T).Run()",
+		"      /usr/local/go/src/testing/testing.go:2258 +0xb12",
+		"  main.main()",
+		"      _testmain.go:46 +0x164",
+		"==================",
+	} {
+		fmt.Fprintf(&sb, `{"Action":"output","Package":%q,"Test":%q,"Output":%q}`+"\n", pkg, test, text+"\n")
+	}
+	return sb.String()
+}
+
+func TestParseRaceEvents(t *testing.T) {
+	workspace := "/src/calc"
+	stdout := `{"Action":"run","Package":"example.com/calc/calc","Test":"TestRace"}
+{"Action":"output","Package":"example.com/calc/calc","Test":"TestRace","Output":"=== RUN   TestRace\n"}
+` + raceReport("example.com/calc/calc", "TestRace", workspace, 7) +
+		raceReport("example.com/calc/calc", "TestRace", workspace, 7) +
+		`{"Action":"output","Package":"example.com/calc/calc","Test":"TestRace","Output":"    testing.go:1865: race detected during execution of test\n"}
+{"Action":"output","Package":"example.com/calc/calc","Test":"TestRace","Output":"--- FAIL: TestRace (0.00s)\n"}
+{"Action":"fail","Package":"example.com/calc/calc","Test":"TestRace","Elapsed":0}
+{"Action":"fail","Package":"example.com/calc/calc","Elapsed":0.01}
+`
+	races, rest, truncated := parseRaceEvents(stdout, workspace)
+
+	want := []DataRace{{
+		Package: "example.com/calc/calc",
+		Test:    "TestRace",
+		Stacks: []RaceStack{
+			{Kind: "Read by goroutine 8", Frames: []string{"calc.(*Counter).Inc calc/calc.go:7", "calc.TestRace.func1 calc/calc_test.go:9"}},
+			{Kind: "Previous write by goroutine 7", Frames: []string{"calc.(*Counter).Inc calc/calc.go:7"}},
+			{Kind: "Goroutine 7 (running) created at", Frames: []string{"testing.(*T).Run testing.go:2258"}},
+		},
+	}}
+	if !reflect.DeepEqual(races, want) {
+		t.Errorf("races = %+v, want %+v", races, want)
+	}
+	if truncated {
+		t.Errorf("truncated = true, want false")
+	}
+	if strings.Contains(rest, "DATA RACE") || strings.Contains(rest, "=====") {
+		t.Errorf("rest = %q, want no race reports", rest)
+	}
+
+	tests := parseTestEvents(rest)
+	if !strings.Contains(tests.Failures, "race detected during execution of test") {
+		t.Errorf("Failures = %q, want the failed test", tests.Failures)
+	}
+}
+
+func TestParseRaceEventsTruncated(t *testing.T) {
+	var stdout strings.Builder
+	for i := range MaxDataRaces + 1 {
+		stdout.WriteString(raceReport("example.com/calc/calc", "TestRace", "/src/calc", i+1))
+	}
+	races, _, truncated := parseRaceEvents(stdout.String(), "/src/calc")
+	if len(races) != MaxDataRaces || !truncated {
+		t.Errorf("parseRaceEvents() = %d races, truncated %v, want %d races, truncated", len(races), truncated, MaxDataRaces)
+	}
+}
+
+func TestRunGoRace(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"go.mod":       "module example.com/calc\n\ngo 1.22\n",
+		"calc/calc.go": "package calc\n\n// Counter counts without synchronization\ntype Counter struct{ n int }\n\n// Inc increments the counter\nfunc (c *Counter) Inc() { c.n++ }\n",
+		"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestRace(t *testing.T) {\n\tc := &Counter{}\n\tdone := make(chan bool)\n\tgo func() {\n\t\tc.Inc()\n\t\tdone <- true\n\t}()\n\tc.Inc()\n\t<-done\n}\n\n" +
+			"func TestSerial(t *testing.T) {\n\tc := &Counter{}\n\tc.Inc()\n\tif c.n != 1 {\n\t\tt.Fatal(\"wrong count\")\n\t}\n}\n",
+	})
+
+	tests := []struct {
+		name        string
+		input       GoRaceInput
+		wantPassed  bool
+		wantRace    string
+		errContains string
+	}{
+		{
+			name:     "racy test",
+			input:    GoRaceInput{},
+			wantRace: "calc.(*Counter).Inc calc/calc.go:7",
+		},
+		{
+			name:       "serial test",
+			input:      GoRaceInput{Run: "TestSerial", Count: 2},
+			wantPassed: true,
+		},
+		{
+			name:        "flag as package",
+			input:       GoRaceInput{Packages: []string{"-exec=sh"}},
+			errContains: "invalid package pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := RunGoRace(context.Background(), workspaceDir, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("RunGoRace() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				if strings.Contains(err.Error(), "-race") {
+					t.Skipf("race detector unavailable: %v", err)
+				}
+				t.Fatalf("RunGoRace() error = %v", err)
+			}
+			if !output.Success || output.Passed != tt.wantPassed {
+				t.Errorf("RunGoRace() = %+v, want success with passed %v", output, tt.wantPassed)
+			}
+			if tt.wantRace == "" {
+				if len(output.Races) != 0 {
+					t.Errorf("Races = %+v, want none", output.Races)
+				}
+				return
+			}
+			if len(output.Races) != 1 || output.Races[0].Test != "TestRace" {
+				t.Fatalf("Races = %+v, want one race in TestRace", output.Races)
+			}
+			if frames := fmt.Sprint(output.Races[0].Stacks); !strings.Contains(frames, tt.wantRace) {
+				t.Errorf("Stacks = %s, want them to contain %q", frames, tt.wantRace)
+			}
+			if strings.Contains(output.Failures, "DATA RACE") {
+				t.Errorf("Failures = %q, want no race reports", output.Failures)
+			}
+		})
+	}
+}
+
+func TestGoRaceTool(t *testing.T) {
+	if GoRaceTool() == nil {
+		t.Fatal("GoRaceTool() returned nil")
+	}
+	if got := NewGoRaceToolWithWorkspace(t.TempDir()).Name(); got != "goRace" {
+		t.Errorf("Name() = %q, want %q", got, "goRace")
+	}
+}
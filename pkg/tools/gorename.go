@@ -0,0 +1,522 @@
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/imports"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// maxRenameErrors is the maximum number of type errors reported by the goRename tool
+const maxRenameErrors = 10
+
+// goRenameLoadMode is what the goRename tool loads of the packages of a module. Dependencies
+// are type-checked from source too, since overlays invalidate their export data and the
+// export data of a newer Go toolchain may not be readable.
+const goRenameLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedImports | packages.NeedDeps |
+	packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// GoRenameInput defines the input parameters for the goRename tool
+type GoRenameInput struct {
+	// File is the relative path of a Go file that declares or uses the symbol
+	File string `json:"file"`
+	// Line is the 1-based line of the symbol in File
+	Line int `json:"line"`
+	// Name is the current name of the symbol; for a method or field it is the bare name
+	Name string `json:"name"`
+	// NewName renames the symbol everywhere it is declared and used
+	NewName string `json:"newName,omitempty"`
+	// MoveTo moves the top-level declaration of the symbol to this Go file of the same
+	// package instead, creating the file if needed and fixing the imports of both files
+	MoveTo string `json:"moveTo,omitempty"`
+	// DryRun reports the files that would change without writing them
+	DryRun bool `json:"dryRun,omitempty"`
+}
+
+// GoRenameOutput defines the output structure for the goRename tool
+type GoRenameOutput struct {
+	// Files are the changed files, relative to the workspace
+	Files []string `json:"files"`
+	// Occurrences is the number of identifiers renamed
+	Occurrences int `json:"occurrences,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// goModule holds the type-checked packages of the module containing a workspace file
+type goModule struct {
+	dir       string
+	workspace string
+	fset      *token.FileSet
+	packages  []*packages.Package
+}
+
+// RunGoRename renames or moves a Go symbol with the packages of its module type-checked,
+// so only the identifiers that refer to the symbol change. The changes are type-checked
+// again before they are written, and a change that breaks the build is refused. The
+// packages are listed with go list on the host, not with the executor of the workspace.
+func RunGoRename(ctx context.Context, workspaceDir string, input GoRenameInput) (*GoRenameOutput, error) {
+	start := time.Now()
+	slog.Info("Starting go rename operation",
+		"file", input.File,
+		"line", input.Line,
+		"name", input.Name,
+		"new_name", input.NewName,
+		"move_to", input.MoveTo,
+		"workspace", workspaceDir)
+
+	switch {
+	case input.File == "" || input.Line < 1 || input.Name == "":
+		return nil, fmt.Errorf("file, line, and name are required")
+	case (input.NewName == "") == (input.MoveTo == ""):
+		return nil, fmt.Errorf("set exactly one of newName and moveTo")
+	case input.NewName != "" && (!token.IsIdentifier(input.NewName) || input.NewName == "_"):
+		return nil, fmt.Errorf("invalid new name %q", input.NewName)
+	case input.NewName == input.Name:
+		return nil, fmt.Errorf("the new name is the current name")
+	}
+	file, err := resolveWorkspacePath(workspaceDir, input.File)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+
+	module, err := loadGoModule(ctx, workspaceDir, file, nil)
+	if err != nil {
+		return nil, err
+	}
+	pkg, obj, err := module.lookup(file, input.Line, input.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &GoRenameOutput{Files: []string{}}
+	var changes map[string][]byte
+	if input.NewName != "" {
+		changes, output.Occurrences, err = module.rename(pkg, obj, input.NewName)
+	} else {
+		var target string
+		if target, err = resolveWorkspacePath(workspaceDir, input.MoveTo); err == nil {
+			changes, err = module.move(pkg, obj, target)
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to change symbol",
+			"name", input.Name,
+			"error", err)
+		return nil, err
+	}
+
+	// The changed module must still type-check
+	checked, err := loadGoModule(ctx, workspaceDir, file, changes)
+	if err != nil {
+		return nil, err
+	}
+	if errs := checked.errors(); len(errs) > 0 {
+		return nil, fmt.Errorf("the change would break the build: %s", strings.Join(errs, "; "))
+	}
+
+	paths := slices.Sorted(maps.Keys(changes))
+	for _, path := range paths {
+		rel, err := filepath.Rel(module.workspace, path)
+		if err != nil {
+			return nil, err
+		}
+		output.Files = append(output.Files, filepath.ToSlash(rel))
+	}
+	if !input.DryRun {
+		unlock := lockPaths(paths...)
+		defer unlock()
+		for _, path := range paths {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+			}
+			if err := writeFileAtomic(path, changes[path]); err != nil {
+				return nil, fmt.Errorf("failed to write file %s: %w", path, err)
+			}
+		}
+	}
+
+	slog.Info("Go rename completed successfully",
+		"name", input.Name,
+		"files", len(output.Files),
+		"occurrences", output.Occurrences,
+		"dry_run", input.DryRun,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// loadGoModule type-checks the packages of the module containing the absolute path file,
+// with the file contents of overlay replacing those on disk
+func loadGoModule(ctx context.Context, workspaceDir, file string, overlay map[string][]byte) (*goModule, error) {
+	workspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	dir := filepath.Dir(file)
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			break
+		}
+		if dir == workspace || !strings.HasPrefix(dir, workspace) {
+			return nil, fmt.Errorf("no go.mod found for %s in the workspace", file)
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, CommandTimeout)
+	defer cancel()
+	module := &goModule{dir: dir, workspace: workspace, fset: token.NewFileSet()}
+	module.packages, err = packages.Load(&packages.Config{
+		Context: ctx,
+		Mode:    goRenameLoadMode,
+		Dir:     dir,
+		Fset:    module.fset,
+		Tests:   true,
+		Overlay: overlay,
+		// Only the declarations of dependencies matter, so their function bodies are left out,
+		// which makes type-checking them much faster
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			if strings.HasPrefix(filename, dir+string(filepath.Separator)) {
+				return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+			}
+			file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+			if file != nil {
+				for _, decl := range file.Decls {
+					if fn, ok := decl.(*ast.FuncDecl); ok {
+						fn.Body = nil
+					}
+				}
+			}
+			return file, err
+		},
+	}, "./...")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load packages: %w", err)
+	}
+	// The generated main packages of the tests are not part of the module
+	module.packages = slices.DeleteFunc(module.packages, func(pkg *packages.Package) bool {
+		return strings.HasSuffix(pkg.ID, ".test")
+	})
+	if overlay == nil {
+		if errs := module.errors(); len(errs) > 0 {
+			return nil, fmt.Errorf("the packages must type-check before a rename: %s", strings.Join(errs, "; "))
+		}
+	}
+	return module, nil
+}
+
+// errors returns the first maxRenameErrors errors of the packages of the module, with
+// workspace paths
+func (m *goModule) errors() []string {
+	var errs []string
+	for _, pkg := range m.packages {
+		for _, err := range pkg.Errors {
+			errs = append(errs, strings.ReplaceAll(err.Error(), m.workspace+string(filepath.Separator), ""))
+		}
+	}
+	errs = slices.Compact(slices.Sorted(slices.Values(errs)))
+	return errs[:min(len(errs), maxRenameErrors)]
+}
+
+// lookup returns the package and object of the identifier name on line of file
+func (m *goModule) lookup(file string, line int, name string) (*packages.Package, types.Object, error) {
+	for _, pkg := range m.packages {
+		for _, syntax := range pkg.Syntax {
+			if m.fset.File(syntax.Pos()).Name() != file {
+				continue
+			}
+			var obj types.Object
+			ast.Inspect(syntax, func(n ast.Node) bool {
+				ident, ok := n.(*ast.Ident)
+				if obj != nil || !ok || ident.Name != name || m.fset.Position(ident.Pos()).Line != line {
+					return obj == nil
+				}
+				// The identifier of an embedded field uses the type, which is renamed with the field
+				if obj = pkg.TypesInfo.Uses[ident]; obj == nil {
+					obj = pkg.TypesInfo.Defs[ident]
+				}
+				return obj == nil
+			})
+			switch obj.(type) {
+			case nil:
+				return nil, nil, fmt.Errorf("no symbol %s on line %d of %s", name, line, filepath.Base(file))
+			case *types.PkgName:
+				return nil, nil, fmt.Errorf("%s is an import; imports cannot be renamed", name)
+			}
+			if obj.Pkg() == nil || !strings.HasPrefix(m.fset.Position(obj.Pos()).Filename, m.dir+string(filepath.Separator)) {
+				return nil, nil, fmt.Errorf("%s is declared outside the module and cannot be changed", name)
+			}
+			return pkg, obj, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("%s is not a Go file of a package of the module", filepath.Base(file))
+}
+
+// objectKey identifies the object declared at pos across the packages of the module, which
+// type-check some files more than once, such as a package with and without its tests
+func (m *goModule) objectKey(pos token.Pos) string {
+	position := m.fset.Position(pos)
+	return fmt.Sprintf("%s:%d", position.Filename, position.Offset)
+}
+
+// rename returns the contents of the files that change when obj of pkg is renamed to
+// newName, and the number of identifiers renamed
+func (m *goModule) rename(pkg *packages.Package, obj types.Object, newName string) (map[string][]byte, int, error) {
+	if err := checkRenameConflict(pkg, obj, newName); err != nil {
+		return nil, 0, err
+	}
+	key, declPkg := m.objectKey(obj.Pos()), obj.Pkg().Path()
+	if !token.IsExported(newName) && obj.Exported() {
+		// Unexporting a symbol breaks its uses in other packages
+		for _, p := range m.packages {
+			for ident, use := range p.TypesInfo.Uses {
+				if p.Types.Path() != declPkg && m.objectKey(use.Pos()) == key {
+					return nil, 0, fmt.Errorf("%s is used by package %s at %s and cannot be unexported", obj.Name(), p.Types.Path(), m.fset.Position(ident.Pos()))
+				}
+			}
+		}
+	}
+
+	// A renamed type also renames the fields that embed it
+	targets := map[string]bool{key: true}
+	if _, ok := obj.(*types.TypeName); ok {
+		for _, p := range m.packages {
+			for ident, def := range p.TypesInfo.Defs {
+				if v, ok := def.(*types.Var); ok && v.Embedded() && targets[m.objectKey(p.TypesInfo.Uses[ident].Pos())] {
+					targets[m.objectKey(v.Pos())] = true
+				}
+			}
+		}
+	}
+
+	// The identifiers to rename by file, as offsets; files type-checked more than once are
+	// only counted once
+	edits := map[string]map[int]bool{}
+	for _, p := range m.packages {
+		for _, idents := range []map[*ast.Ident]types.Object{p.TypesInfo.Defs, p.TypesInfo.Uses} {
+			for ident, o := range idents {
+				if o == nil || ident.Name != obj.Name() || !targets[m.objectKey(o.Pos())] {
+					continue
+				}
+				position := m.fset.Position(ident.Pos())
+				if edits[position.Filename] == nil {
+					edits[position.Filename] = map[int]bool{}
+				}
+				edits[position.Filename][position.Offset] = true
+			}
+		}
+	}
+
+	changes := map[string][]byte{}
+	occurrences := 0
+	for file, offsets := range edits {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read file: %w", err)
+		}
+		// Replace from the end, so the earlier offsets stay valid
+		for _, offset := range slices.Backward(slices.Sorted(maps.Keys(offsets))) {
+			src = slices.Concat(src[:offset], []byte(newName), src[offset+len(obj.Name()):])
+			occurrences++
+		}
+		changes[file] = src
+	}
+	return changes, occurrences, nil
+}
+
+// checkRenameConflict returns an error if newName is already declared where obj is, so the
+// rename would merge or shadow two symbols
+func checkRenameConflict(pkg *packages.Package, obj types.Object, newName string) error {
+	if v, ok := obj.(*types.Var); ok && v.IsField() {
+		for _, recv := range fieldOwners(pkg, v) {
+			if o, _, _ := types.LookupFieldOrMethod(recv, true, obj.Pkg(), newName); o != nil {
+				return fmt.Errorf("%s already has a field or method %s", recv, newName)
+			}
+		}
+		return nil
+	}
+	if fn, ok := obj.(*types.Func); ok {
+		if recv := fn.Signature().Recv(); recv != nil {
+			if o, _, _ := types.LookupFieldOrMethod(recv.Type(), true, obj.Pkg(), newName); o != nil {
+				return fmt.Errorf("%s already has a field or method %s", recv.Type(), newName)
+			}
+			return nil
+		}
+	}
+	if scope := obj.Parent(); scope != nil {
+		if scope.Lookup(newName) != nil {
+			return fmt.Errorf("%s is already declared in the scope of %s", newName, obj.Name())
+		}
+		// Uses of obj in nested scopes must not see another symbol called newName
+		for ident, use := range pkg.TypesInfo.Uses {
+			if use != obj {
+				continue
+			}
+			if inner := pkg.Types.Scope().Innermost(ident.Pos()); inner != nil {
+				if _, o := inner.LookupParent(newName, ident.Pos()); o != nil && o.Parent() != scope {
+					return fmt.Errorf("%s would be shadowed by %s at %s", obj.Name(), newName, pkg.Fset.Position(ident.Pos()))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fieldOwners returns the struct types of pkg that declare the field v
+func fieldOwners(pkg *packages.Package, v *types.Var) []types.Type {
+	var owners []types.Type
+	for _, def := range pkg.TypesInfo.Defs {
+		tn, ok := def.(*types.TypeName)
+		if !ok {
+			continue
+		}
+		if s, ok := tn.Type().Underlying().(*types.Struct); ok {
+			for field := range s.Fields() {
+				if field == v {
+					owners = append(owners, tn.Type())
+				}
+			}
+		}
+	}
+	return owners
+}
+
+// move returns the contents of the files that change when the top-level declaration of obj
+// of pkg moves to the absolute path target
+func (m *goModule) move(pkg *packages.Package, obj types.Object, target string) (map[string][]byte, error) {
+	if filepath.Ext(target) != ".go" || filepath.Dir(target) != filepath.Dir(m.fset.Position(obj.Pos()).Filename) {
+		return nil, fmt.Errorf("the declaration can only move to a Go file in the directory of its package")
+	}
+	fn, isFunc := obj.(*types.Func)
+	if !isFunc && obj.Parent() != obj.Pkg().Scope() {
+		return nil, fmt.Errorf("only top-level declarations can be moved")
+	}
+
+	var file *ast.File
+	var decl ast.Decl
+	for _, syntax := range pkg.Syntax {
+		for _, d := range syntax.Decls {
+			switch d := d.(type) {
+			case *ast.FuncDecl:
+				if isFunc && d.Name.Pos() == fn.Pos() {
+					file, decl = syntax, d
+				}
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					var names []*ast.Ident
+					switch spec := spec.(type) {
+					case *ast.TypeSpec:
+						names = []*ast.Ident{spec.Name}
+					case *ast.ValueSpec:
+						names = spec.Names
+					}
+					for _, name := range names {
+						if name.Pos() == obj.Pos() {
+							file, decl = syntax, d
+						}
+					}
+				}
+				if decl == d && len(d.Specs) > 1 {
+					return nil, fmt.Errorf("%s is declared in a group with other declarations and cannot be moved alone", obj.Name())
+				}
+			}
+		}
+	}
+	if decl == nil {
+		return nil, fmt.Errorf("no declaration of %s found", obj.Name())
+	}
+	source := m.fset.Position(file.Pos()).Filename
+	if source == target {
+		return nil, fmt.Errorf("%s is already declared in %s", obj.Name(), filepath.Base(target))
+	}
+
+	src, err := os.ReadFile(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	// The declaration moves with its documentation and trailing newline
+	startPos := decl.Pos()
+	if doc := declDoc(decl); doc != nil {
+		startPos = doc.Pos()
+	}
+	start, end := m.fset.Position(startPos).Offset, m.fset.Position(decl.End()).Offset
+	if end < len(src) && src[end] == '\n' {
+		end++
+	}
+	text := slices.Clone(src[start:end])
+	rest := slices.Concat(src[:start], src[end:])
+
+	dst, err := os.ReadFile(target)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		dst = []byte("package " + file.Name.Name + "\n")
+	case err != nil:
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	dst = slices.Concat(dst, []byte("\n"), text)
+
+	changes := map[string][]byte{}
+	for path, content := range map[string][]byte{source: rest, target: dst} {
+		// Imports the declaration used move with it
+		fixed, err := imports.Process(path, content, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fix the imports of %s: %w", filepath.Base(path), err)
+		}
+		changes[path] = fixed
+	}
+	return changes, nil
+}
+
+// declDoc returns the documentation of a top-level declaration
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch decl := decl.(type) {
+	case *ast.FuncDecl:
+		return decl.Doc
+	case *ast.GenDecl:
+		return decl.Doc
+	}
+	return nil
+}
+
+// GoRenameTool creates a new goRename tool that renames and moves Go symbols within the workspace directory
+func GoRenameTool() tool.Tool {
+	return NewGoRenameToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoRenameToolWithWorkspace creates a new goRename tool with a custom workspace directory
+func NewGoRenameToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goRename",
+			Description: "Rename a Go symbol (type, function, method, field, variable, or constant) everywhere it is used, or move its top-level declaration to another file of its package, with the code type-checked instead of replacing text. Give the file and line of a declaration or use and the current name, then newName or moveTo. The code must type-check before, and a change that would break the build is refused. Set dryRun to list the files that would change.",
+		},
+		func(ctx tool.Context, input GoRenameInput) *GoRenameOutput {
+			observe := observeTool("goRename")
+			output, err := RunGoRename(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &GoRenameOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goRename tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,212 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// renameWorkspace is a module with a package used by another package and by tests
+var renameWorkspace = map[string]string{
+	"go.mod": "module example.com/calc\n\ngo 1.22\n",
+	"calc/calc.go": `package calc
+
+import "strings"
+
+// Calculator adds numbers
+type Calculator struct {
+	Total int
+}
+
+// Add adds n to the total
+func (c *Calculator) Add(n int) {
+	c.Total += n
+}
+
+// Name returns the name of the calculator
+func Name() string {
+	return strings.ToUpper("calc")
+}
+
+func sum(values []int) int {
+	total := 0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+`,
+	"calc/calc_test.go": `package calc
+
+import "testing"
+
+func TestAdd(t *testing.T) {
+	var c Calculator
+	c.Add(2)
+	if c.Total != 2 || sum([]int{1, 1}) != 2 {
+		t.Fail()
+	}
+}
+`,
+	"cmd/main.go": `package main
+
+import "example.com/calc/calc"
+
+type wrapper struct {
+	*calc.Calculator
+}
+
+func main() {
+	w := wrapper{&calc.Calculator{}}
+	w.Add(1)
+	println(w.Calculator.Total, calc.Name())
+}
+`,
+}
+
+func TestRunGoRename(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       GoRenameInput
+		want        GoRenameOutput
+		wantFiles   map[string][]string
+		errContains string
+	}{
+		{
+			name:  "type with embedded field",
+			input: GoRenameInput{File: "cmd/main.go", Line: 6, Name: "Calculator", NewName: "Adder"},
+			want:  GoRenameOutput{Files: []string{"calc/calc.go", "calc/calc_test.go", "cmd/main.go"}, Occurrences: 6},
+			wantFiles: map[string][]string{
+				"calc/calc.go": {"type Adder struct", "func (c *Adder) Add"},
+				"cmd/main.go":  {"*calc.Adder\n", "wrapper{&calc.Adder{}}", "w.Adder.Total"},
+			},
+		},
+		{
+			name:  "field",
+			input: GoRenameInput{File: "calc/calc.go", Line: 7, Name: "Total", NewName: "Sum"},
+			want:  GoRenameOutput{Files: []string{"calc/calc.go", "calc/calc_test.go", "cmd/main.go"}, Occurrences: 4},
+			wantFiles: map[string][]string{
+				"calc/calc.go":      {"\tSum int", "c.Sum += n"},
+				"calc/calc_test.go": {"c.Sum != 2"},
+			},
+		},
+		{
+			name:  "local variable",
+			input: GoRenameInput{File: "calc/calc.go", Line: 23, Name: "total", NewName: "acc"},
+			want:  GoRenameOutput{Files: []string{"calc/calc.go"}, Occurrences: 3},
+			wantFiles: map[string][]string{
+				"calc/calc.go": {"acc := 0", "acc += v", "return acc", "strings.ToUpper"},
+			},
+		},
+		{
+			name:  "move",
+			input: GoRenameInput{File: "calc/calc.go", Line: 16, Name: "Name", MoveTo: "calc/name.go"},
+			want:  GoRenameOutput{Files: []string{"calc/calc.go", "calc/name.go"}},
+			wantFiles: map[string][]string{
+				"calc/name.go": {"package calc\n\nimport \"strings\"\n\n// Name returns the name of the calculator\nfunc Name() string {"},
+			},
+		},
+		{
+			name:        "conflict",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 16, Name: "Name", NewName: "sum"},
+			errContains: "sum is already declared",
+		},
+		{
+			name:        "method conflict",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 11, Name: "Add", NewName: "Total"},
+			errContains: "already has a field or method Total",
+		},
+		{
+			name:        "unexport used symbol",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 16, Name: "Name", NewName: "name"},
+			errContains: "cannot be unexported",
+		},
+		{
+			name:        "no symbol",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 1, Name: "Total", NewName: "Sum"},
+			errContains: "no symbol Total on line 1",
+		},
+		{
+			name:        "standard library",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 17, Name: "ToUpper", NewName: "Upper"},
+			errContains: "declared outside the module",
+		},
+		{
+			name:        "invalid name",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 6, Name: "Calculator", NewName: "1x"},
+			errContains: "invalid new name",
+		},
+		{
+			name:        "rename and move",
+			input:       GoRenameInput{File: "calc/calc.go", Line: 6, Name: "Calculator", NewName: "Adder", MoveTo: "calc/adder.go"},
+			errContains: "set exactly one of newName and moveTo",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Each case type-checks a module, which takes a few seconds
+			t.Parallel()
+			workspaceDir := t.TempDir()
+			writeFiles(t, workspaceDir, renameWorkspace)
+
+			output, err := RunGoRename(context.Background(), workspaceDir, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("RunGoRename() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunGoRename() error = %v", err)
+			}
+			if !reflect.DeepEqual(*output, tt.want) {
+				t.Errorf("RunGoRename() = %+v, want %+v", *output, tt.want)
+			}
+			for path, wants := range tt.wantFiles {
+				content, err := os.ReadFile(filepath.Join(workspaceDir, path))
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", path, err)
+				}
+				for _, want := range wants {
+					if !strings.Contains(string(content), want) {
+						t.Errorf("%s = %q, want it to contain %q", path, content, want)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestRunGoRename_DryRun(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, renameWorkspace)
+
+	input := GoRenameInput{File: "calc/calc.go", Line: 6, Name: "Calculator", NewName: "Adder", DryRun: true}
+	output, err := RunGoRename(context.Background(), workspaceDir, input)
+	if err != nil {
+		t.Fatalf("RunGoRename() error = %v", err)
+	}
+	if len(output.Files) != 3 {
+		t.Errorf("RunGoRename() files = %v, want 3 files", output.Files)
+	}
+	content, err := os.ReadFile(filepath.Join(workspaceDir, "calc/calc.go"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if string(content) != renameWorkspace["calc/calc.go"] {
+		t.Error("RunGoRename() changed a file in a dry run")
+	}
+}
+
+func TestGoRenameTool(t *testing.T) {
+	if GoRenameTool() == nil {
+		t.Fatal("GoRenameTool() returned nil")
+	}
+	if got := NewGoRenameToolWithWorkspace(t.TempDir()).Name(); got != "goRename" {
+		t.Errorf("Name() = %q, want %q", got, "goRename")
+	}
+}
@@ -0,0 +1,390 @@
+package tools
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxGoSymbolFiles is the maximum number of Go files the goSymbols tool parses in one call
+const MaxGoSymbolFiles = 500
+
+// errGoSymbolsLimit stops the walk of executeGoSymbols once MaxGoSymbolFiles files are parsed
+var errGoSymbolsLimit = errors.New("go symbols file limit reached")
+
+// GoSymbolsInput defines the input parameters for the goSymbols tool
+type GoSymbolsInput struct {
+	// Path is the relative path of a Go file or of a directory whose Go files are indexed,
+	// including subdirectories (defaults to the workspace root)
+	Path string `json:"path,omitempty"`
+	// IncludeTests also indexes _test.go files
+	IncludeTests bool `json:"includeTests,omitempty"`
+}
+
+// GoFunc describes an exported function or method
+type GoFunc struct {
+	// Name is the function name
+	Name string `json:"name"`
+	// Signature is the declaration without its body, such as func (c *Calc) Add(a, b int) int
+	Signature string `json:"signature"`
+	// File is the slash-separated path of the file, relative to the workspace
+	File string `json:"file"`
+	// Line is the 1-based line of the declaration
+	Line int `json:"line"`
+}
+
+// GoType describes an exported type
+type GoType struct {
+	// Name is the type name
+	Name string `json:"name"`
+	// Kind is struct, interface, func, map, slice, array, chan, pointer, or alias, or named
+	// for a type defined from another named type
+	Kind string `json:"kind"`
+	// Definition is the underlying type of types other than structs and interfaces
+	Definition string `json:"definition,omitempty"`
+	// Fields are the exported fields of a struct, such as Name string, and the methods of an
+	// interface
+	Fields []string `json:"fields,omitempty"`
+	// Methods are the exported methods declared on the type
+	Methods []GoFunc `json:"methods,omitempty"`
+	// File is the slash-separated path of the file, relative to the workspace
+	File string `json:"file"`
+	// Line is the 1-based line of the declaration
+	Line int `json:"line"`
+}
+
+// GoPackage describes the exported symbols of a package
+type GoPackage struct {
+	// Name is the package name
+	Name string `json:"name"`
+	// Dir is the slash-separated directory of the package, relative to the workspace
+	Dir string `json:"dir"`
+	// Files are the indexed files of the package
+	Files []string `json:"files"`
+	// Types are the exported types with their methods
+	Types []GoType `json:"types,omitempty"`
+	// Functions are the exported functions
+	Functions []GoFunc `json:"functions,omitempty"`
+}
+
+// GoSymbolsOutput defines the output structure for the goSymbols tool
+type GoSymbolsOutput struct {
+	// Packages are the indexed packages, ordered by directory and name
+	Packages []GoPackage `json:"packages"`
+	// ParseErrors are the errors of files that do not parse, which are left out
+	ParseErrors []string `json:"parseErrors,omitempty"`
+	// Truncated indicates that only the first MaxGoSymbolFiles files are indexed
+	Truncated bool `json:"truncated,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// goSymbolIndex collects the symbols of the parsed files by package
+type goSymbolIndex struct {
+	fset     *token.FileSet
+	packages map[string]*GoPackage
+	// methods are the exported methods by package key and receiver type name
+	methods map[string]map[string][]GoFunc
+	files   int
+}
+
+// executeGoSymbols is the core logic for indexing Go symbols, extracted for testability
+func executeGoSymbols(workspaceDir string, config Config, input GoSymbolsInput) (*GoSymbolsOutput, error) {
+	start := time.Now()
+	slog.Info("Starting go symbols operation",
+		"path", input.Path,
+		"include_tests", input.IncludeTests,
+		"workspace", workspaceDir)
+
+	dir := cmp.Or(input.Path, ".")
+	root, err := resolveWorkspacePath(workspaceDir, dir)
+	if err != nil {
+		slog.Error("Failed to resolve path",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := config.checkPath(dir); err != nil {
+		return nil, err
+	}
+	workspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	ignore, err := loadIgnore(workspace, config)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(root); err != nil {
+		return nil, fmt.Errorf("failed to index %s: %w", dir, err)
+	}
+
+	output := &GoSymbolsOutput{Packages: []GoPackage{}}
+	index := &goSymbolIndex{
+		fset:     token.NewFileSet(),
+		packages: map[string]*GoPackage{},
+		methods:  map[string]map[string][]GoFunc{},
+	}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(workspace, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			// Like the go command, skip vendor, testdata, and directories starting with . or _
+			name := d.Name()
+			if p != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if config.denied(rel) || ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || !strings.HasSuffix(rel, ".go") || config.denied(rel) || ignore.ignored(rel, false) {
+			return nil
+		}
+		if !input.IncludeTests && strings.HasSuffix(rel, "_test.go") {
+			return nil
+		}
+		if index.files == MaxGoSymbolFiles {
+			output.Truncated = true
+			return errGoSymbolsLimit
+		}
+		info, err := d.Info()
+		if err != nil || info.Size() > config.maxFileSize() {
+			return err
+		}
+		index.files++
+		if err := index.add(p, rel); err != nil {
+			output.ParseErrors = append(output.ParseErrors, err.Error())
+		}
+		return nil
+	})
+	if err != nil && !errors.Is(err, errGoSymbolsLimit) {
+		slog.Error("Failed to index Go files",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to index %s: %w", dir, err)
+	}
+	output.Packages = index.result()
+
+	slog.Info("Go symbols completed successfully",
+		"path", dir,
+		"files", index.files,
+		"packages", len(output.Packages),
+		"parse_errors", len(output.ParseErrors),
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// add parses the Go file at p and adds its exported symbols, reporting the file as rel
+func (x *goSymbolIndex) add(p, rel string) error {
+	src, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	// Positions and errors refer to the workspace path
+	file, err := parser.ParseFile(x.fset, rel, src, parser.SkipObjectResolution)
+	if err != nil {
+		return err
+	}
+	dir := path.Dir(rel)
+	key := dir + " " + file.Name.Name
+	pkg, ok := x.packages[key]
+	if !ok {
+		pkg = &GoPackage{Name: file.Name.Name, Dir: dir}
+		x.packages[key] = pkg
+	}
+	pkg.Files = append(pkg.Files, rel)
+
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			if !decl.Name.IsExported() {
+				continue
+			}
+			// The signature is the declaration without its documentation and body
+			signature := *decl
+			signature.Doc, signature.Body = nil, nil
+			fn := GoFunc{
+				Name:      decl.Name.Name,
+				Signature: x.format(&signature),
+				File:      rel,
+				Line:      x.fset.Position(decl.Pos()).Line,
+			}
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				pkg.Functions = append(pkg.Functions, fn)
+				continue
+			}
+			recv := receiverTypeName(decl.Recv.List[0].Type)
+			if x.methods[key] == nil {
+				x.methods[key] = map[string][]GoFunc{}
+			}
+			x.methods[key][recv] = append(x.methods[key][recv], fn)
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				if spec, ok := spec.(*ast.TypeSpec); ok && spec.Name.IsExported() {
+					pkg.Types = append(pkg.Types, x.goType(spec, rel))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// goType describes the type declared by spec in the file rel
+func (x *goSymbolIndex) goType(spec *ast.TypeSpec, rel string) GoType {
+	t := GoType{Name: spec.Name.Name, File: rel, Line: x.fset.Position(spec.Pos()).Line}
+	switch typ := spec.Type.(type) {
+	case *ast.StructType:
+		t.Kind = "struct"
+		for _, field := range typ.Fields.List {
+			fieldType := x.format(field.Type)
+			if len(field.Names) == 0 {
+				// An embedded field is exported when its type name is
+				if ast.IsExported(receiverTypeName(field.Type)) {
+					t.Fields = append(t.Fields, fieldType)
+				}
+				continue
+			}
+			for _, name := range field.Names {
+				if name.IsExported() {
+					t.Fields = append(t.Fields, name.Name+" "+fieldType)
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		t.Kind = "interface"
+		for _, method := range typ.Methods.List {
+			if len(method.Names) == 0 {
+				t.Fields = append(t.Fields, x.format(method.Type))
+				continue
+			}
+			for _, name := range method.Names {
+				t.Fields = append(t.Fields, name.Name+strings.TrimPrefix(x.format(method.Type), "func"))
+			}
+		}
+	default:
+		t.Definition = x.format(spec.Type)
+		switch def := typ.(type) {
+		case *ast.FuncType:
+			t.Kind = "func"
+		case *ast.MapType:
+			t.Kind = "map"
+		case *ast.ArrayType:
+			t.Kind = "slice"
+			if def.Len != nil {
+				t.Kind = "array"
+			}
+		case *ast.ChanType:
+			t.Kind = "chan"
+		case *ast.StarExpr:
+			t.Kind = "pointer"
+		default:
+			t.Kind = "named"
+		}
+	}
+	if spec.Assign.IsValid() {
+		t.Kind = "alias"
+	}
+	return t
+}
+
+// format prints the syntax tree node as Go source
+func (x *goSymbolIndex) format(node any) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, x.fset, node); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// result returns the packages in the order of their directories and names, with the methods
+// attached to their types
+func (x *goSymbolIndex) result() []GoPackage {
+	packages := make([]GoPackage, 0, len(x.packages))
+	for key, pkg := range x.packages {
+		for i := range pkg.Types {
+			pkg.Types[i].Methods = x.methods[key][pkg.Types[i].Name]
+		}
+		packages = append(packages, *pkg)
+	}
+	slices.SortFunc(packages, func(a, b GoPackage) int {
+		return cmp.Or(cmp.Compare(a.Dir, b.Dir), cmp.Compare(a.Name, b.Name))
+	})
+	return packages
+}
+
+// receiverTypeName returns the type name of a method receiver or embedded field expression
+func receiverTypeName(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexExpr:
+		return receiverTypeName(expr.X)
+	case *ast.IndexListExpr:
+		return receiverTypeName(expr.X)
+	case *ast.SelectorExpr:
+		return expr.Sel.Name
+	case *ast.Ident:
+		return expr.Name
+	default:
+		return ""
+	}
+}
+
+// GoSymbolsTool creates a new goSymbols tool that indexes the Go symbols within the workspace directory
+func GoSymbolsTool() tool.Tool {
+	return NewGoSymbolsToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoSymbolsToolWithWorkspace creates a new goSymbols tool with a custom workspace directory
+func NewGoSymbolsToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewGoSymbolsToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewGoSymbolsToolWithConfig creates a new goSymbols tool with a custom workspace directory and configuration
+func NewGoSymbolsToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goSymbols",
+			Description: "Index the Go files of a directory in the workspace, including subdirectories, and return each package with its exported types (kind, fields, and methods) and functions with their signatures, files, and lines. Use it to plan tests or a review without reading every file. Set includeTests to index _test.go files too. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input GoSymbolsInput) *GoSymbolsOutput {
+			observe := observeTool("goSymbols")
+			output, err := executeGoSymbols(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &GoSymbolsOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goSymbols tool: %v", err))
+	}
+	return t
+}
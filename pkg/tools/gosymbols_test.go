@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGoSymbolsTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"go.mod": "module example.com/calc\n",
+		"calc/calc.go": `package calc
+
+// Calculator adds numbers
+type Calculator struct {
+	Precision int
+	memory    float64
+	Logger
+}
+
+// Add returns a + b
+func (c *Calculator) Add(a, b float64) float64 {
+	return a + b
+}
+
+func (c *Calculator) reset() {}
+
+// Logger logs results
+type Logger interface {
+	Log(msg string) error
+}
+
+type Op func(a, b float64) float64
+
+type ID = string
+
+// New creates a Calculator
+func New(precision int) *Calculator {
+	return &Calculator{Precision: precision}
+}
+
+func helper() {}
+`,
+		"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {}\n",
+		"calc/broken.go":    "package calc\n\nfunc Broken( {\n",
+		"main.go":           "package main\n\nfunc main() {}\n",
+		"vendor/dep/dep.go": "package dep\n\nfunc Dep() {}\n",
+		"testdata/data.go":  "package data\n\nfunc Data() {}\n",
+	})
+
+	calc := GoPackage{
+		Name:  "calc",
+		Dir:   "calc",
+		Files: []string{"calc/calc.go"},
+		Types: []GoType{
+			{
+				Name:   "Calculator",
+				Kind:   "struct",
+				Fields: []string{"Precision int", "Logger"},
+				Methods: []GoFunc{
+					{Name: "Add", Signature: "func (c *Calculator) Add(a, b float64) float64", File: "calc/calc.go", Line: 11},
+				},
+				File: "calc/calc.go",
+				Line: 4,
+			},
+			{Name: "Logger", Kind: "interface", Fields: []string{"Log(msg string) error"}, File: "calc/calc.go", Line: 18},
+			{Name: "Op", Kind: "func", Definition: "func(a, b float64) float64", File: "calc/calc.go", Line: 22},
+			{Name: "ID", Kind: "alias", Definition: "string", File: "calc/calc.go", Line: 24},
+		},
+		Functions: []GoFunc{
+			{Name: "New", Signature: "func New(precision int) *Calculator", File: "calc/calc.go", Line: 27},
+		},
+	}
+
+	output, err := executeGoSymbols(workspaceDir, ToolConfig, GoSymbolsInput{})
+	if err != nil {
+		t.Fatalf("executeGoSymbols() error = %v", err)
+	}
+	want := []GoPackage{{Name: "main", Dir: ".", Files: []string{"main.go"}}, calc}
+	if !reflect.DeepEqual(output.Packages, want) {
+		t.Errorf("executeGoSymbols() packages = %+v, want %+v", output.Packages, want)
+	}
+	if len(output.ParseErrors) != 1 || !strings.HasPrefix(output.ParseErrors[0], "calc/broken.go:3") {
+		t.Errorf("executeGoSymbols() parse errors = %q, want an error in calc/broken.go", output.ParseErrors)
+	}
+
+	// Test files are indexed on request
+	output, err = executeGoSymbols(workspaceDir, ToolConfig, GoSymbolsInput{Path: "calc/calc_test.go", IncludeTests: true})
+	if err != nil {
+		t.Fatalf("executeGoSymbols() error = %v", err)
+	}
+	tests := []GoPackage{{
+		Name:      "calc",
+		Dir:       "calc",
+		Files:     []string{"calc/calc_test.go"},
+		Functions: []GoFunc{{Name: "TestAdd", Signature: "func TestAdd(t *testing.T)", File: "calc/calc_test.go", Line: 5}},
+	}}
+	if !reflect.DeepEqual(output.Packages, tests) {
+		t.Errorf("executeGoSymbols() packages = %+v, want %+v", output.Packages, tests)
+	}
+
+	for _, path := range []string{"../outside", "missing"} {
+		if _, err := executeGoSymbols(workspaceDir, ToolConfig, GoSymbolsInput{Path: path}); err == nil {
+			t.Errorf("executeGoSymbols(%s) error = nil, want an error", path)
+		}
+	}
+}
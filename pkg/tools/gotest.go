@@ -0,0 +1,340 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxTestFailureOutput is the maximum number of bytes of failure output returned by the goTest tool
+const MaxTestFailureOutput = 16 * 1024
+
+// maxTestOutputPerFailure caps the output kept for each failed test or build, so one
+// verbose failure cannot crowd out the others
+const maxTestOutputPerFailure = 4 * 1024
+
+// Package results reported in GoTestPackage.Status
+const (
+	TestStatusPass = "pass"
+	TestStatusFail = "fail"
+	// TestStatusSkip marks packages without test files
+	TestStatusSkip = "skip"
+)
+
+// packageCoverage matches the coverage line go test prints for each package
+var packageCoverage = regexp.MustCompile(`coverage: ([0-9.]+)% of statements`)
+
+// GoTestInput defines the input parameters for the goTest tool
+type GoTestInput struct {
+	// Dir is the relative directory within the workspace to run the tests in (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+	// Packages are the package patterns to test (defaults to ./...)
+	Packages []string `json:"packages,omitempty"`
+	// Run restricts the run to tests matching this regular expression
+	Run string `json:"run,omitempty"`
+	// TimeoutSeconds overrides the default command timeout
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+// GoTestPackage is the result of a single package
+type GoTestPackage struct {
+	// Package is the import path of the package
+	Package string `json:"package"`
+	// Status is pass, fail, or skip for packages without test files
+	Status string `json:"status"`
+	// Coverage is the statement coverage of the package in percent
+	Coverage float64 `json:"coverage"`
+	// FailedTests are the names of the failed tests
+	FailedTests []string `json:"failedTests,omitempty"`
+	// Elapsed is the package test time in seconds
+	Elapsed float64 `json:"elapsed"`
+}
+
+// GoTestOutput defines the output structure for the goTest tool
+type GoTestOutput struct {
+	// Packages are the results of each tested package
+	Packages []GoTestPackage `json:"packages"`
+	// Passed indicates whether every package built and passed its tests
+	Passed bool `json:"passed"`
+	// Coverage is the total statement coverage across the tested packages in percent
+	Coverage float64 `json:"coverage"`
+	// Failures is the output of the failed tests and builds, truncated to MaxTestFailureOutput bytes
+	Failures string `json:"failures,omitempty"`
+	// Success indicates whether the tests ran; it is true even when they fail
+	Success bool `json:"success"`
+	// Error contains the error message if the tests could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// testEvent is the subset of a `go test -json` event read by RunGoTest
+type testEvent struct {
+	Action     string
+	Package    string
+	ImportPath string
+	Test       string
+	Output     string
+	Elapsed    float64
+}
+
+// RunGoTest runs `go test -json -coverprofile` in the workspace directory and returns
+// the result of each package and the total coverage. Failing tests are not an error.
+func RunGoTest(ctx context.Context, workspaceDir string, input GoTestInput) (*GoTestOutput, error) {
+	packages := input.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	for _, pkg := range packages {
+		if strings.HasPrefix(pkg, "-") {
+			return nil, fmt.Errorf("invalid package pattern %q", pkg)
+		}
+	}
+
+	profileDir, err := CoverProfileDir(workspaceDir)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(profileDir)
+	profile := filepath.Join(profileDir, "cover.out")
+	// go test runs in Dir, possibly in a sandbox, so it gets the profile relative to Dir
+	profileArg, err := filepath.Rel(filepath.Join(workspaceDir, input.Dir), profile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve coverage profile: %w", err)
+	}
+
+	args := []string{"test", "-json", "-coverprofile=" + profileArg}
+	if input.Run != "" {
+		args = append(args, "-run="+input.Run)
+	}
+	args = append(args, packages...)
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{
+		Command:        "go",
+		Args:           args,
+		Dir:            input.Dir,
+		TimeoutSeconds: input.TimeoutSeconds,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	output := parseTestEvents(result.Stdout)
+	if len(output.Packages) == 0 {
+		if !result.Success {
+			return nil, fmt.Errorf("go test exited with code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+		}
+		return nil, fmt.Errorf("go test reported no packages")
+	}
+	if !result.Success && output.Passed {
+		// Setup errors such as invalid flags fail the run without a failed package
+		output.Passed = false
+		output.Failures = truncateUTF8(strings.TrimSpace(result.Stderr+"\n"+output.Failures), MaxTestFailureOutput)
+	}
+	if data, err := os.ReadFile(profile); err == nil {
+		output.Coverage = parseCoverProfile(string(data))
+	}
+	output.Success = true
+
+	slog.Info("Go tests completed",
+		"dir", input.Dir,
+		"packages", len(output.Packages),
+		"passed", output.Passed,
+		"coverage", output.Coverage)
+	return output, nil
+}
+
+// CoverProfileDir creates a directory for coverage profiles in workspaceDir and returns its
+// path, which the caller removes. Commands run by a sandbox executor see only the workspace,
+// so a profile written anywhere else would be lost. `go test ./...` skips the directory, as
+// its name starts with a dot.
+func CoverProfileDir(workspaceDir string) (string, error) {
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(workspaceDir, ".agi-cover-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create coverage profile directory: %w", err)
+	}
+	return dir, nil
+}
+
+// parseTestEvents converts `go test -json` output into package results and the output of
+// the failed tests and builds
+func parseTestEvents(stdout string) *GoTestOutput {
+	output := &GoTestOutput{Passed: true}
+	packages := make(map[string]*GoTestPackage)
+	var order []string
+	pkg := func(name string) *GoTestPackage {
+		p, ok := packages[name]
+		if !ok {
+			p = &GoTestPackage{Package: name}
+			packages[name] = p
+			order = append(order, name)
+		}
+		return p
+	}
+
+	// Output is kept per package and test until the test or package fails
+	logs := make(map[string]*strings.Builder)
+	logKey := func(pkg, test string) string { return pkg + "\x00" + test }
+	logOf := func(key string) string {
+		if b, ok := logs[key]; ok {
+			return b.String()
+		}
+		return ""
+	}
+	var failures []string
+
+	scanner := bufio.NewScanner(strings.NewReader(stdout))
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxCommandOutput)
+	for scanner.Scan() {
+		var e testEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			continue
+		}
+		switch e.Action {
+		case "build-output":
+			// Build errors are reported against the package being built, as "pkg [pkg.test]"
+			e.Package, _, _ = strings.Cut(e.ImportPath, " ")
+			fallthrough
+		case "output":
+			b, ok := logs[logKey(e.Package, e.Test)]
+			if !ok {
+				b = &strings.Builder{}
+				logs[logKey(e.Package, e.Test)] = b
+			}
+			if b.Len() < maxTestOutputPerFailure {
+				b.WriteString(e.Output)
+			}
+			if e.Test == "" {
+				if m := packageCoverage.FindStringSubmatch(e.Output); m != nil && e.Package != "" {
+					pkg(e.Package).Coverage, _ = strconv.ParseFloat(m[1], 64)
+				}
+			}
+		case "pass", "fail", "skip":
+			if e.Package == "" {
+				continue
+			}
+			p := pkg(e.Package)
+			if e.Test != "" {
+				if e.Action == "fail" {
+					p.FailedTests = append(p.FailedTests, e.Test)
+					failures = append(failures, logOf(logKey(e.Package, e.Test)))
+				}
+				continue
+			}
+			p.Status = e.Action
+			p.Elapsed = e.Elapsed
+			if e.Action == "fail" {
+				output.Passed = false
+				if len(p.FailedTests) == 0 {
+					// A package that fails without failed tests did not build or crashed
+					failures = append(failures, logOf(logKey(e.Package, "")))
+				}
+			}
+		}
+	}
+
+	for _, name := range order {
+		p := packages[name]
+		if p.Status == "" {
+			// Packages whose result is missing, as in truncated output, count as failed
+			p.Status = TestStatusFail
+			output.Passed = false
+		}
+		output.Packages = append(output.Packages, *p)
+	}
+
+	var b strings.Builder
+	for _, failure := range failures {
+		b.WriteString(truncateUTF8(strings.TrimSpace(failure), maxTestOutputPerFailure))
+		b.WriteString("\n")
+	}
+	output.Failures = truncateUTF8(strings.TrimSpace(b.String()), MaxTestFailureOutput)
+	return output
+}
+
+// parseCoverProfile returns the percentage of statements covered in a coverage profile,
+// counting blocks reported by several packages once
+func parseCoverProfile(profile string) float64 {
+	covered := make(map[string]bool)
+	statements := make(map[string]int)
+	for _, line := range strings.Split(profile, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 || strings.HasPrefix(line, "mode:") {
+			continue
+		}
+		n, err := strconv.Atoi(fields[1])
+		if err != nil {
+			continue
+		}
+		count, err := strconv.Atoi(fields[2])
+		if err != nil {
+			continue
+		}
+		statements[fields[0]] = n
+		covered[fields[0]] = covered[fields[0]] || count > 0
+	}
+
+	total, hit := 0, 0
+	for block, n := range statements {
+		total += n
+		if covered[block] {
+			hit += n
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return float64(hit) * 100 / float64(total)
+}
+
+// truncateUTF8 shortens s to at most maxBytes bytes without splitting a character and
+// marks the cut
+func truncateUTF8(s string, maxBytes int) string {
+	if len(s) <= maxBytes {
+		return s
+	}
+	cut := maxBytes
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + fmt.Sprintf("\n... output truncated (%d bytes total)", len(s))
+}
+
+// GoTestTool creates a new goTest tool that runs the Go tests within the workspace directory
+func GoTestTool() tool.Tool {
+	return NewGoTestToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoTestToolWithWorkspace creates a new goTest tool with a custom workspace directory
+func NewGoTestToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goTest",
+			Description: "Run go test with coverage in the workspace directory and return the pass/fail status and coverage of each package, the total coverage, and the output of failed tests.",
+		},
+		func(ctx tool.Context, input GoTestInput) *GoTestOutput {
+			observe := observeTool("goTest")
+			output, err := RunGoTest(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &GoTestOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goTest tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,262 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestParseTestEvents(t *testing.T) {
+	stdout := `{"Action":"start","Package":"example.com/calc"}
+{"Action":"run","Package":"example.com/calc","Test":"TestAdd"}
+{"Action":"output","Package":"example.com/calc","Test":"TestAdd","Output":"=== RUN   TestAdd\n"}
+{"Action":"pass","Package":"example.com/calc","Test":"TestAdd","Elapsed":0}
+{"Action":"output","Package":"example.com/calc","Output":"coverage: 75.0% of statements\n"}
+{"Action":"pass","Package":"example.com/calc","Elapsed":0.01}
+{"Action":"run","Package":"example.com/div","Test":"TestDiv"}
+{"Action":"output","Package":"example.com/div","Test":"TestDiv","Output":"    div_test.go:9: Div(1, 0) did not fail\n"}
+{"Action":"fail","Package":"example.com/div","Test":"TestDiv","Elapsed":0}
+{"Action":"output","Package":"example.com/div","Output":"coverage: 50.0% of statements\n"}
+{"Action":"fail","Package":"example.com/div","Elapsed":0.02}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-output","Output":"broken/broken.go:3:27: cannot use \"x\" as int value\n"}
+{"ImportPath":"example.com/broken [example.com/broken.test]","Action":"build-fail"}
+{"Action":"fail","Package":"example.com/broken","Elapsed":0,"FailedBuild":"example.com/broken [example.com/broken.test]"}
+{"Action":"output","Package":"example.com/none","Output":"?   \texample.com/none\t[no test files]\n"}
+{"Action":"skip","Package":"example.com/none","Elapsed":0}
+not json
+`
+	got := parseTestEvents(stdout)
+
+	want := []GoTestPackage{
+		{Package: "example.com/calc", Status: TestStatusPass, Coverage: 75, Elapsed: 0.01},
+		{Package: "example.com/div", Status: TestStatusFail, Coverage: 50, FailedTests: []string{"TestDiv"}, Elapsed: 0.02},
+		{Package: "example.com/broken", Status: TestStatusFail},
+		{Package: "example.com/none", Status: TestStatusSkip},
+	}
+	if !reflect.DeepEqual(got.Packages, want) {
+		t.Errorf("Packages = %+v, want %+v", got.Packages, want)
+	}
+	if got.Passed {
+		t.Errorf("Passed = true, want false")
+	}
+	for _, failure := range []string{"Div(1, 0) did not fail", `cannot use "x" as int value`} {
+		if !strings.Contains(got.Failures, failure) {
+			t.Errorf("Failures = %q, want it to contain %q", got.Failures, failure)
+		}
+	}
+	if strings.Contains(got.Failures, "RUN   TestAdd") {
+		t.Errorf("Failures = %q, want no output of passed tests", got.Failures)
+	}
+}
+
+func TestParseCoverProfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile string
+		want    float64
+	}{
+		{
+			name: "partial coverage",
+			profile: `mode: set
+example.com/calc/calc.go:3.24,3.38 1 1
+example.com/calc/calc.go:5.24,7.2 3 0
+`,
+			want: 25,
+		},
+		{
+			name: "block reported twice counts once",
+			profile: `mode: set
+example.com/calc/calc.go:3.24,3.38 1 0
+example.com/calc/calc.go:3.24,3.38 1 1
+example.com/calc/calc.go:5.24,5.38 1 0
+`,
+			want: 50,
+		},
+		{
+			name:    "empty",
+			profile: "mode: set\n",
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseCoverProfile(tt.profile); got != tt.want {
+				t.Errorf("parseCoverProfile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	if got := truncateUTF8("short", 10); got != "short" {
+		t.Errorf("truncateUTF8() = %q, want the input unchanged", got)
+	}
+	// Cutting at byte 4 would split the second "é"
+	got := truncateUTF8("aéébbbb", 4)
+	if !strings.HasPrefix(got, "aé\n... output truncated (9 bytes total)") {
+		t.Errorf("truncateUTF8() = %q, want it cut before the split character", got)
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("truncateUTF8() = %q, want valid UTF-8", got)
+	}
+}
+
+func TestRunGoTest(t *testing.T) {
+	workspaceDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":            "module example.com/calc\n\ngo 1.22\n",
+		"calc/calc.go":      "package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Sub(a, b int) int { return a - b }\n",
+		"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n",
+		"one/one.go":        "package one\n\nfunc One() int { return 2 }\n",
+		"one/one_test.go":   "package one\n\nimport \"testing\"\n\nfunc TestOne(t *testing.T) {\n\tif One() != 1 {\n\t\tt.Errorf(\"One() = %d, want 1\", One())\n\t}\n}\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name         string
+		input        GoTestInput
+		wantPassed   bool
+		wantStatuses map[string]string
+		wantFailure  string
+		wantCoverage float64
+		errContains  string
+	}{
+		{
+			name:  "all packages",
+			input: GoTestInput{},
+			wantStatuses: map[string]string{
+				"example.com/calc/calc": TestStatusPass,
+				"example.com/calc/one":  TestStatusFail,
+			},
+			wantFailure:  "One() = 2, want 1",
+			wantCoverage: 100 * 2 / 3.0,
+		},
+		{
+			name:         "selected package",
+			input:        GoTestInput{Packages: []string{"./calc"}},
+			wantPassed:   true,
+			wantStatuses: map[string]string{"example.com/calc/calc": TestStatusPass},
+			wantCoverage: 50,
+		},
+		{
+			name:         "selected tests in a directory",
+			input:        GoTestInput{Dir: "one", Run: "TestNothing"},
+			wantPassed:   true,
+			wantStatuses: map[string]string{"example.com/calc/one": TestStatusPass},
+		},
+		{
+			name:        "flag as package",
+			input:       GoTestInput{Packages: []string{"-exec=sh"}},
+			errContains: "invalid package pattern",
+		},
+		{
+			name:        "directory outside the workspace",
+			input:       GoTestInput{Dir: "../"},
+			errContains: "failed to resolve working directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := RunGoTest(context.Background(), workspaceDir, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("RunGoTest() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunGoTest() error = %v", err)
+			}
+			if !output.Success || output.Passed != tt.wantPassed {
+				t.Errorf("RunGoTest() = %+v, want success with passed %v", output, tt.wantPassed)
+			}
+			statuses := make(map[string]string)
+			for _, pkg := range output.Packages {
+				statuses[pkg.Package] = pkg.Status
+			}
+			if !reflect.DeepEqual(statuses, tt.wantStatuses) {
+				t.Errorf("package statuses = %v, want %v", statuses, tt.wantStatuses)
+			}
+			if !strings.Contains(output.Failures, tt.wantFailure) {
+				t.Errorf("Failures = %q, want it to contain %q", output.Failures, tt.wantFailure)
+			}
+			if diff := output.Coverage - tt.wantCoverage; diff > 0.01 || diff < -0.01 {
+				t.Errorf("Coverage = %v, want %v", output.Coverage, tt.wantCoverage)
+			}
+		})
+	}
+}
+
+// workspaceExecutor runs commands on the host and records the files they are told to
+// write with -coverprofile, resolved against their directory, as a sandbox sees only
+// the workspace
+type workspaceExecutor struct {
+	profiles []string
+}
+
+func (e *workspaceExecutor) Command(ctx context.Context, workspaceDir, dir, name string, args ...string) *exec.Cmd {
+	for _, arg := range args {
+		if profile, ok := strings.CutPrefix(arg, "-coverprofile="); ok {
+			if !filepath.IsAbs(profile) {
+				profile = filepath.Join(dir, profile)
+			}
+			e.profiles = append(e.profiles, profile)
+		}
+	}
+	return HostExecutor{}.Command(ctx, workspaceDir, dir, name, args...)
+}
+
+func TestRunGoTest_CoverProfileInWorkspace(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"go.mod":            "module example.com/calc\n\ngo 1.22\n",
+		"calc/calc.go":      "package calc\n\nfunc Add(a, b int) int { return a + b }\n\nfunc Sub(a, b int) int { return a - b }\n",
+		"calc/calc_test.go": "package calc\n\nimport \"testing\"\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t\tt.Fatal(\"wrong sum\")\n\t}\n}\n",
+	})
+	executor := &workspaceExecutor{}
+	if err := SetExecutor(workspaceDir, executor); err != nil {
+		t.Fatalf("SetExecutor() error = %v", err)
+	}
+	defer SetExecutor(workspaceDir, nil)
+
+	output, err := RunGoTest(context.Background(), workspaceDir, GoTestInput{Dir: "calc"})
+	if err != nil {
+		t.Fatalf("RunGoTest() error = %v", err)
+	}
+	if output.Coverage != 50 {
+		t.Errorf("Coverage = %v, want 50", output.Coverage)
+	}
+	if len(executor.profiles) != 1 {
+		t.Fatalf("coverage profiles = %v, want one", executor.profiles)
+	}
+	if rel, err := filepath.Rel(workspaceDir, executor.profiles[0]); err != nil || !filepath.IsLocal(rel) {
+		t.Errorf("coverage profile %s is outside the workspace %s", executor.profiles[0], workspaceDir)
+	}
+	if dirs, _ := filepath.Glob(filepath.Join(workspaceDir, ".agi-cover-*")); len(dirs) > 0 {
+		t.Errorf("coverage profile directories left in the workspace: %v", dirs)
+	}
+}
+
+func TestGoTestTool(t *testing.T) {
+	if GoTestTool() == nil {
+		t.Fatal("GoTestTool() returned nil")
+	}
+	if got := NewGoTestToolWithWorkspace(t.TempDir()).Name(); got != "goTest" {
+		t.Errorf("Name() = %q, want %q", got, "goTest")
+	}
+}
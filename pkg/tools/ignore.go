@@ -0,0 +1,120 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFiles are the files at the workspace root whose gitignore-style patterns the file
+// tools apply: listing and search skip the paths they match, and fileRead refuses them
+var IgnoreFiles = []string{".gitignore", ".agiignore"}
+
+// DefaultIgnorePatterns are the patterns ignored in addition to IgnoreFiles by default:
+// vendored dependencies that waste the context of agents
+var DefaultIgnorePatterns = []string{"vendor/", "node_modules/"}
+
+// ignoreRule is one line of an ignore file
+type ignoreRule struct {
+	// segments are the slash-separated segments of the pattern
+	segments []string
+	// negate re-includes the paths the rule matches (a leading !)
+	negate bool
+	// dirOnly matches only directories (a trailing /)
+	dirOnly bool
+}
+
+// ignoreMatcher holds ignore rules in order; the last matching rule decides
+type ignoreMatcher []ignoreRule
+
+// parse adds the rules of the gitignore-style patterns in lines to m
+func (m ignoreMatcher) parse(lines []string) ignoreMatcher {
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var rule ignoreRule
+		if rule.negate = strings.HasPrefix(line, "!"); rule.negate {
+			line = line[1:]
+		}
+		if rule.dirOnly = strings.HasSuffix(line, "/"); rule.dirOnly {
+			line = strings.TrimRight(line, "/")
+		}
+		// A pattern with a slash is relative to the workspace root; one without matches at any depth
+		if !strings.Contains(line, "/") {
+			line = "**/" + line
+		}
+		line = strings.TrimPrefix(line, "/")
+		if line == "" || !validGlob(line) {
+			continue
+		}
+		rule.segments = strings.Split(line, "/")
+		m = append(m, rule)
+	}
+	return m
+}
+
+// ignored reports whether the slash-separated workspace path rel is ignored. Like git, a
+// path in an ignored directory cannot be re-included.
+func (m ignoreMatcher) ignored(rel string, isDir bool) bool {
+	if len(m) == 0 || rel == "." || rel == "" {
+		return false
+	}
+	segments := strings.Split(rel, "/")
+	for i := 1; i < len(segments); i++ {
+		if m.match(segments[:i], true) {
+			return true
+		}
+	}
+	return m.match(segments, isDir)
+}
+
+// match applies the rules to the path segments
+func (m ignoreMatcher) match(segments []string, isDir bool) bool {
+	ignored := false
+	for _, rule := range m {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		if matchSegments(rule.segments, segments) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+// loadIgnore returns the ignore rules of the workspace at the absolute path workspace:
+// config.IgnorePatterns followed by the patterns of IgnoreFiles
+func loadIgnore(workspace string, config Config) (ignoreMatcher, error) {
+	m := ignoreMatcher{}.parse(config.IgnorePatterns)
+	for _, name := range IgnoreFiles {
+		data, err := os.ReadFile(filepath.Join(workspace, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		m = m.parse(strings.Split(string(data), "\n"))
+	}
+	return m, nil
+}
+
+// checkIgnored returns an error if the workspace path userPath is ignored
+func checkIgnored(workspaceDir string, config Config, userPath string, isDir bool) error {
+	workspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	ignore, err := loadIgnore(workspace, config)
+	if err != nil {
+		return err
+	}
+	if ignore.ignored(filepath.ToSlash(filepath.Clean(userPath)), isDir) {
+		return fmt.Errorf("%s is ignored by the workspace ignore patterns (%s)", userPath, strings.Join(IgnoreFiles, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	m := ignoreMatcher{}.parse([]string{
+		"# build output",
+		"bin/",
+		"*.log",
+		"!keep.log",
+		"/docs/generated",
+		"assets/**/*.min.js",
+		"",
+	})
+
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{path: "bin", isDir: true, want: true},
+		{path: "bin/agi", want: true},
+		{path: "cmd/bin", isDir: true, want: true},
+		{path: "bin", want: false},
+		{path: "server.log", want: true},
+		{path: "logs/server.log", want: true},
+		{path: "keep.log", want: false},
+		{path: "docs/generated", isDir: true, want: true},
+		{path: "docs/generated/api.md", want: true},
+		{path: "pkg/docs/generated", isDir: true, want: false},
+		{path: "assets/js/app.min.js", want: true},
+		{path: "assets/js/app.js", want: false},
+		{path: "main.go", want: false},
+		{path: ".", isDir: true, want: false},
+	}
+	for _, tt := range tests {
+		if got := m.ignored(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestIgnore_FileTools(t *testing.T) {
+	workspaceDir := t.TempDir()
+	for path, content := range map[string]string{
+		".gitignore":                     "dist/\n",
+		".agiignore":                     "fixtures/*.json\n",
+		"main.go":                        "package main // TODO\n",
+		"dist/main.js":                   "// TODO\n",
+		"node_modules/left-pad/index.js": "// TODO\n",
+		"vendor/example.com/lib/lib.go":  "package lib // TODO\n",
+		"fixtures/big.json":              "{\"todo\": \"TODO\"}\n",
+		"fixtures/README.md":             "TODO\n",
+	} {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(workspaceDir, path)), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(workspaceDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to create test file: %v", err)
+		}
+	}
+
+	list, err := executeFileList(workspaceDir, ToolConfig, FileListInput{Pattern: "**"})
+	if err != nil {
+		t.Fatalf("executeFileList() error = %v", err)
+	}
+	var listed []string
+	for _, entry := range list.Entries {
+		listed = append(listed, entry.Path)
+	}
+	want := []string{".agiignore", ".gitignore", "fixtures", "fixtures/README.md", "main.go"}
+	if !slices.Equal(listed, want) {
+		t.Errorf("executeFileList() = %v, want %v", listed, want)
+	}
+
+	search, err := executeSearch(workspaceDir, ToolConfig, SearchInput{Pattern: "TODO"})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	var matched []string
+	for _, match := range search.Matches {
+		matched = append(matched, match.Path)
+	}
+	slices.Sort(matched)
+	if want := []string{"fixtures/README.md", "main.go"}; !slices.Equal(matched, want) {
+		t.Errorf("executeSearch() matched %v, want %v", matched, want)
+	}
+
+	if _, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "dist/main.js"}); err == nil || !strings.Contains(err.Error(), "is ignored") {
+		t.Errorf("executeFileRead() of an ignored file error = %v, want an ignored error", err)
+	}
+	if _, err := executeFileList(workspaceDir, ToolConfig, FileListInput{Path: "vendor"}); err == nil || !strings.Contains(err.Error(), "is ignored") {
+		t.Errorf("executeFileList() of an ignored directory error = %v, want an ignored error", err)
+	}
+	// Without the default patterns, vendored code is visible
+	if _, err := executeFileRead(workspaceDir, Config{}, FileReadInput{Path: "vendor/example.com/lib/lib.go"}); err != nil {
+		t.Errorf("executeFileRead() without default patterns error = %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// LintCommand is the linter executable run by the lint tool
+const LintCommand = "golangci-lint"
+
+// LintInput defines the input parameters for the lint tool
+type LintInput struct {
+	// Dir is the relative directory within the workspace to lint (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+	// Linters restricts the run to the named linters (defaults to the golangci-lint configuration)
+	Linters []string `json:"linters,omitempty"`
+}
+
+// LintIssue is a single finding reported by golangci-lint
+type LintIssue struct {
+	// Linter is the name of the linter that reported the issue
+	Linter string `json:"linter"`
+	// Rule is the check of the linter that reported the issue, such as a vet analyzer or a staticcheck code, if known
+	Rule string `json:"rule,omitempty"`
+	// File is the path of the file, relative to the linted directory
+	File string `json:"file"`
+	// Line is the 1-based line of the issue
+	Line int `json:"line"`
+	// Column is the 1-based column of the issue, or 0 if unknown
+	Column int `json:"column,omitempty"`
+	// Message describes the issue
+	Message string `json:"message"`
+}
+
+// String formats the issue as file:line:column: message (linter/rule)
+func (i LintIssue) String() string {
+	pos := fmt.Sprintf("%s:%d", i.File, i.Line)
+	if i.Column > 0 {
+		pos += fmt.Sprintf(":%d", i.Column)
+	}
+	source := i.Linter
+	if i.Rule != "" {
+		source += "/" + i.Rule
+	}
+	return fmt.Sprintf("%s: %s (%s)", pos, i.Message, source)
+}
+
+// LintOutput defines the output structure for the lint tool
+type LintOutput struct {
+	// Issues are the findings reported by the linters
+	Issues []LintIssue `json:"issues"`
+	// Success indicates whether the linters ran; it is true even when they report issues
+	Success bool `json:"success"`
+	// Error contains the error message if the linters could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// lintRuleCode matches the rule code that linters such as staticcheck and gosec put in
+// front of their messages, as in "SA4006: this value is never used"
+var lintRuleCode = regexp.MustCompile(`^([A-Z]{1,3}[0-9]{3,4}): `)
+
+// golangciReport is the subset of the golangci-lint JSON report read by RunLint
+type golangciReport struct {
+	Issues []struct {
+		FromLinter string
+		Text       string
+		Pos        struct {
+			Filename string
+			Line     int
+			Column   int
+		}
+	}
+}
+
+// RunLint runs golangci-lint in the workspace directory and returns its findings. The
+// command must be listed in AllowedCommands. Reported issues are not an error.
+func RunLint(ctx context.Context, workspaceDir string, input LintInput) (*LintOutput, error) {
+	args := []string{"run", "--output.json.path=stdout", "--show-stats=false", "--issues-exit-code=0"}
+	if len(input.Linters) > 0 {
+		args = append(args, "--default=none", "--enable="+strings.Join(input.Linters, ","))
+	}
+	args = append(args, "./...")
+
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{Command: LintCommand, Args: args, Dir: input.Dir})
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success {
+		return nil, fmt.Errorf("%s exited with code %d: %s", LintCommand, result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+
+	issues, err := parseLintReport(result.Stdout)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Lint completed", "dir", input.Dir, "issues", len(issues))
+	return &LintOutput{Issues: issues, Success: true}, nil
+}
+
+// parseLintReport converts a golangci-lint JSON report into lint issues
+func parseLintReport(stdout string) ([]LintIssue, error) {
+	var report golangciReport
+	if err := json.Unmarshal([]byte(strings.TrimSpace(stdout)), &report); err != nil {
+		return nil, fmt.Errorf("invalid %s report: %w", LintCommand, err)
+	}
+
+	issues := make([]LintIssue, 0, len(report.Issues))
+	for _, issue := range report.Issues {
+		rule, message := lintRule(issue.FromLinter, issue.Text)
+		issues = append(issues, LintIssue{
+			Linter:  issue.FromLinter,
+			Rule:    rule,
+			File:    issue.Pos.Filename,
+			Line:    issue.Pos.Line,
+			Column:  issue.Pos.Column,
+			Message: message,
+		})
+	}
+	return issues, nil
+}
+
+// lintRule splits the rule a golangci-lint issue text starts with from its message:
+// a rule code, or the analyzer name of govet issues
+func lintRule(linter, text string) (string, string) {
+	if m := lintRuleCode.FindStringSubmatch(text); m != nil {
+		return m[1], text[len(m[0]):]
+	}
+	if linter == VetLinter {
+		if analyzer, message, ok := strings.Cut(text, ": "); ok && vetAnalyzerName.MatchString(analyzer) {
+			return analyzer, message
+		}
+	}
+	return "", text
+}
+
+// LintTool creates a new lint tool that runs golangci-lint within the workspace directory
+func LintTool() tool.Tool {
+	return NewLintToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// GolangciLintTool creates the lint tool, which runs golangci-lint; it is LintTool
+// named after the linter, for use next to GoVetTool
+func GolangciLintTool() tool.Tool {
+	return LintTool()
+}
+
+// NewLintToolWithWorkspace creates a new lint tool with a custom workspace directory
+func NewLintToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "lint",
+			Description: "Run golangci-lint in the workspace directory and return its findings as structured issues with file, line, linter, and message.",
+		},
+		func(ctx tool.Context, input LintInput) *LintOutput {
+			observe := observeTool("lint")
+			output, err := RunLint(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &LintOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create lint tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,156 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseLintReport(t *testing.T) {
+	tests := []struct {
+		name    string
+		stdout  string
+		want    []LintIssue
+		wantErr bool
+	}{
+		{
+			name: "issues",
+			stdout: `{"Issues":[{"FromLinter":"errcheck","Text":"Error return value is not checked","Pos":{"Filename":"pkg/calc/calc.go","Line":12,"Column":9}},
+				{"FromLinter":"unused","Text":"func helper is unused","Pos":{"Filename":"pkg/calc/util.go","Line":3}}],"Report":{}}`,
+			want: []LintIssue{
+				{Linter: "errcheck", File: "pkg/calc/calc.go", Line: 12, Column: 9, Message: "Error return value is not checked"},
+				{Linter: "unused", File: "pkg/calc/util.go", Line: 3, Message: "func helper is unused"},
+			},
+		},
+		{
+			name: "rule codes",
+			stdout: `{"Issues":[{"FromLinter":"staticcheck","Text":"SA4006: this value of x is never used","Pos":{"Filename":"calc.go","Line":5}},
+				{"FromLinter":"govet","Text":"printf: fmt.Sprintf format %d has arg x of wrong type","Pos":{"Filename":"calc.go","Line":8}},
+				{"FromLinter":"errcheck","Text":"fix: the error is not checked","Pos":{"Filename":"calc.go","Line":9}}]}`,
+			want: []LintIssue{
+				{Linter: "staticcheck", Rule: "SA4006", File: "calc.go", Line: 5, Message: "this value of x is never used"},
+				{Linter: "govet", Rule: "printf", File: "calc.go", Line: 8, Message: "fmt.Sprintf format %d has arg x of wrong type"},
+				{Linter: "errcheck", File: "calc.go", Line: 9, Message: "fix: the error is not checked"},
+			},
+		},
+		{
+			name:   "no issues",
+			stdout: `{"Issues":null}`,
+			want:   []LintIssue{},
+		},
+		{
+			name:    "not JSON",
+			stdout:  "level=error msg=\"no go files\"",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseLintReport(tt.stdout)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseLintReport() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLintReport() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLintIssue_String(t *testing.T) {
+	issue := LintIssue{Linter: "errcheck", File: "calc.go", Line: 12, Column: 9, Message: "unchecked error"}
+	if got, want := issue.String(), "calc.go:12:9: unchecked error (errcheck)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	issue.Column = 0
+	if got, want := issue.String(), "calc.go:12: unchecked error (errcheck)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+	issue = LintIssue{Linter: "govet", Rule: "printf", File: "calc.go", Line: 3, Message: "wrong format"}
+	if got, want := issue.String(), "calc.go:3: wrong format (govet/printf)"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	tests := []struct {
+		name        string
+		script      string
+		input       LintInput
+		wantIssues  int
+		wantArgs    string
+		errContains string
+	}{
+		{
+			name:       "reports issues",
+			script:     `echo '{"Issues":[{"FromLinter":"errcheck","Text":"unchecked","Pos":{"Filename":"calc.go","Line":3}}]}'`,
+			wantIssues: 1,
+			wantArgs:   "run --output.json.path=stdout --show-stats=false --issues-exit-code=0 ./...",
+		},
+		{
+			name:     "selected linters",
+			script:   `echo '{"Issues":[]}'`,
+			input:    LintInput{Linters: []string{"errcheck", "govet"}},
+			wantArgs: "--default=none --enable=errcheck,govet ./...",
+		},
+		{
+			name:        "linter failure",
+			script:      `echo "typecheck failed" >&2; exit 3`,
+			errContains: "exited with code 3: typecheck failed",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			argsFile := filepath.Join(t.TempDir(), "args")
+			fakeLinter(t, "echo \"$@\" > "+argsFile+"\n"+tt.script)
+
+			output, err := RunLint(context.Background(), t.TempDir(), tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("RunLint() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunLint() error = %v", err)
+			}
+			if !output.Success || len(output.Issues) != tt.wantIssues {
+				t.Errorf("RunLint() = %+v, want success with %d issues", output, tt.wantIssues)
+			}
+			args, err := os.ReadFile(argsFile)
+			if err != nil {
+				t.Fatalf("failed to read linter arguments: %v", err)
+			}
+			if !strings.Contains(string(args), tt.wantArgs) {
+				t.Errorf("linter args = %q, want them to contain %q", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestLintTool(t *testing.T) {
+	if LintTool() == nil {
+		t.Fatal("LintTool() returned nil")
+	}
+	if got := GolangciLintTool().Name(); got != "lint" {
+		t.Errorf("GolangciLintTool().Name() = %q, want %q", got, "lint")
+	}
+	if got := NewLintToolWithWorkspace(t.TempDir()).Name(); got != "lint" {
+		t.Errorf("Name() = %q, want %q", got, "lint")
+	}
+}
+
+// fakeLinter puts a golangci-lint shell script running script first on PATH
+func fakeLinter(t *testing.T, script string) {
+	t.Helper()
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, LintCommand), []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("failed to write fake linter: %v", err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
@@ -0,0 +1,81 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+)
+
+// pathLock is the lock of one file, shared by the operations that wait for it
+type pathLock struct {
+	sync.Mutex
+	// waiters is the number of operations holding or waiting for the lock
+	waiters int
+}
+
+// pathLocks serializes the changes to each file, so concurrent stages cannot interleave
+// their writes or lose each other's edits. Locks are keyed by resolved path and dropped
+// when no operation needs them.
+var pathLocks = struct {
+	sync.Mutex
+	locks map[string]*pathLock
+}{locks: make(map[string]*pathLock)}
+
+// lockPaths locks the resolved paths, in lexical order so that operations locking several
+// files cannot deadlock, and returns the function that unlocks them
+func lockPaths(paths ...string) (unlock func()) {
+	paths = slices.Compact(slices.Sorted(slices.Values(paths)))
+	locks := make([]*pathLock, len(paths))
+	pathLocks.Lock()
+	for i, path := range paths {
+		lock, ok := pathLocks.locks[path]
+		if !ok {
+			lock = &pathLock{}
+			pathLocks.locks[path] = lock
+		}
+		lock.waiters++
+		locks[i] = lock
+	}
+	pathLocks.Unlock()
+
+	for _, lock := range locks {
+		lock.Lock()
+	}
+	return func() {
+		pathLocks.Lock()
+		defer pathLocks.Unlock()
+		for i, lock := range locks {
+			lock.Unlock()
+			if lock.waiters--; lock.waiters == 0 {
+				delete(pathLocks.locks, paths[i])
+			}
+		}
+	}
+}
+
+// contentVersion returns the version of file content that fileRead reports and fileWrite
+// checks: a short hash that changes whenever the content does
+func contentVersion(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkVersion returns an error unless the file at the resolved path has version; it must
+// be called with the path locked
+func checkVersion(resolvedPath, path, version string) error {
+	content, err := os.ReadFile(resolvedPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("file %s was deleted since it was read; read the workspace again", path)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", path, err)
+	}
+	if current := contentVersion(content); current != version {
+		return fmt.Errorf("file %s changed since it was read (version %s, expected %s); read it again and reapply your change", path, current, version)
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestLockPaths_ConcurrentEdits(t *testing.T) {
+	workspaceDir := t.TempDir()
+	const writers = 20
+
+	var wg sync.WaitGroup
+	for i := range writers {
+		wg.Go(func() {
+			if _, err := executeFileEdit(workspaceDir, ToolConfig, FileEditInput{Path: "log.txt", Mode: FileEditAppend, Content: fmt.Sprintf("entry %d", i)}); err != nil {
+				t.Errorf("executeFileEdit() error = %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "log.txt"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	if lines := strings.Count(string(data), "\n"); lines != writers {
+		t.Errorf("file has %d lines, want %d: concurrent edits were lost", lines, writers)
+	}
+	if len(pathLocks.locks) != 0 {
+		t.Errorf("%d path locks left, want none", len(pathLocks.locks))
+	}
+}
+
+func TestFileWrite_IfVersion(t *testing.T) {
+	workspaceDir := t.TempDir()
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "calc.go", Content: "package calc\n"}); err != nil {
+		t.Fatalf("executeFileWrite() error = %v", err)
+	}
+	read, err := executeFileRead(workspaceDir, ToolConfig, FileReadInput{Path: "calc.go"})
+	if err != nil {
+		t.Fatalf("executeFileRead() error = %v", err)
+	}
+
+	// Another stage changes the file after it was read
+	other, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "calc.go", Content: "package calc\n\nfunc Add() {}\n"})
+	if err != nil {
+		t.Fatalf("executeFileWrite() error = %v", err)
+	}
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "calc.go", Content: "package calc\n\nfunc Div() {}\n", IfVersion: read.Version}); err == nil || !strings.Contains(err.Error(), "changed since it was read") {
+		t.Errorf("executeFileWrite() with a stale version error = %v, want a changed file error", err)
+	}
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "calc.go", Content: "package calc\n\nfunc Add() {}\nfunc Div() {}\n", IfVersion: other.Version}); err != nil {
+		t.Errorf("executeFileWrite() with the current version error = %v", err)
+	}
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "missing.go", Content: "package calc\n", IfVersion: read.Version}); err == nil || !strings.Contains(err.Error(), "was deleted") {
+		t.Errorf("executeFileWrite() of a missing file with a version error = %v, want a deleted file error", err)
+	}
+}
@@ -0,0 +1,49 @@
+package tools
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Prometheus metrics of the tool invocations, labeled by tool name. They are collected
+// from the start and exported once registered with RegisterMetrics.
+var (
+	toolInvocations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_tool_invocations_total",
+		Help: "Number of tool invocations.",
+	}, []string{"tool"})
+	toolErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "agi_tool_errors_total",
+		Help: "Number of tool invocations that returned an error.",
+	}, []string{"tool"})
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "agi_tool_duration_seconds",
+		Help:    "Duration of tool invocations in seconds.",
+		Buckets: []float64{0.001, 0.01, 0.1, 0.5, 1, 5, 30, 120, 300},
+	}, []string{"tool"})
+)
+
+// RegisterMetrics registers the tool metrics with registerer, such as
+// prometheus.DefaultRegisterer or the registry of a metrics endpoint
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{toolInvocations, toolErrors, toolDuration} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// observeTool starts an invocation of the tool called name; the returned function records
+// it with the error the tool returned
+func observeTool(name string) func(err error) {
+	start := time.Now()
+	return func(err error) {
+		toolInvocations.WithLabelValues(name).Inc()
+		if err != nil {
+			toolErrors.WithLabelValues(name).Inc()
+		}
+		toolDuration.WithLabelValues(name).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveTool(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if err := RegisterMetrics(registry); err != nil {
+		t.Fatalf("RegisterMetrics() error = %v", err)
+	}
+	invocations := testutil.ToFloat64(toolInvocations.WithLabelValues("metricsTest"))
+	failures := testutil.ToFloat64(toolErrors.WithLabelValues("metricsTest"))
+
+	observeTool("metricsTest")(nil)
+	observeTool("metricsTest")(errors.New("file not found"))
+
+	if got := testutil.ToFloat64(toolInvocations.WithLabelValues("metricsTest")) - invocations; got != 2 {
+		t.Errorf("invocations = %v, want 2", got)
+	}
+	if got := testutil.ToFloat64(toolErrors.WithLabelValues("metricsTest")) - failures; got != 1 {
+		t.Errorf("errors = %v, want 1", got)
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		t.Fatalf("Gather() error = %v", err)
+	}
+	names := make(map[string]bool)
+	for _, family := range families {
+		names[family.GetName()] = true
+	}
+	for _, name := range []string{"agi_tool_invocations_total", "agi_tool_errors_total", "agi_tool_duration_seconds"} {
+		if !names[name] {
+			t.Errorf("registry has no %s metric", name)
+		}
+	}
+
+	// The metrics can be registered only once per registry
+	if err := RegisterMetrics(registry); err == nil {
+		t.Error("RegisterMetrics() twice error = nil, want an already registered error")
+	}
+}
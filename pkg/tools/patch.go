@@ -0,0 +1,512 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxPatchFuzz is the maximum fuzz factor accepted by the applyPatch tool
+const MaxPatchFuzz = 3
+
+// Patched file statuses reported in PatchedFile.Status
+const (
+	PatchStatusCreated  = "created"
+	PatchStatusModified = "modified"
+	PatchStatusDeleted  = "deleted"
+	PatchStatusRenamed  = "renamed"
+)
+
+// hunkHeader matches a unified diff hunk header such as @@ -12,7 +12,8 @@ func main()
+var hunkHeader = regexp.MustCompile(`^@@ -(\d+)(?:,\d+)? \+(\d+)(?:,\d+)? @@`)
+
+// PatchInput defines the input parameters for the applyPatch tool
+type PatchInput struct {
+	// Patch is a unified diff, as produced by diff -u or git diff, touching one or more files
+	Patch string `json:"patch"`
+	// DryRun checks that the patch applies without changing any file
+	DryRun bool `json:"dryRun,omitempty"`
+	// Fuzz is the number of leading and trailing context lines of a hunk that may be ignored
+	// when the hunk does not match exactly, at most MaxPatchFuzz; above 0, trailing whitespace
+	// is also ignored
+	Fuzz int `json:"fuzz,omitempty"`
+}
+
+// PatchedFile describes the change the patch made to a file
+type PatchedFile struct {
+	// Path is the path of the file, relative to the workspace
+	Path string `json:"path"`
+	// Status is created, modified, deleted, or renamed
+	Status string `json:"status"`
+	// Hunks is the number of hunks applied to the file
+	Hunks int `json:"hunks"`
+	// Fuzzy indicates that at least one hunk only applied with fuzz or at a shifted line
+	Fuzzy bool `json:"fuzzy,omitempty"`
+}
+
+// PatchOutput defines the output structure for the applyPatch tool
+type PatchOutput struct {
+	// Files are the files changed, or that would be changed in a dry run
+	Files []PatchedFile `json:"files"`
+	// DryRun indicates that no file was changed
+	DryRun bool `json:"dryRun,omitempty"`
+	// Redacted are the kinds of likely secrets that were redacted from the added lines
+	Redacted []string `json:"redacted,omitempty"`
+	// Success indicates whether the whole patch applied
+	Success bool `json:"success"`
+	// Error contains the error message if the patch did not apply; no file is changed then
+	Error string `json:"error,omitempty"`
+}
+
+// filePatch is the part of a unified diff touching one file; an empty path is /dev/null
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []hunk
+}
+
+// hunk is a hunk of a unified diff
+type hunk struct {
+	// oldStart is the 1-based line the hunk starts at in the original file
+	oldStart int
+	lines    []hunkLine
+}
+
+// hunkLine is a context (' '), removed ('-'), or added ('+') line of a hunk
+type hunkLine struct {
+	op   byte
+	text string
+	// noNewline marks the last line of a file without a trailing newline
+	noNewline bool
+}
+
+// executeApplyPatch is the core logic for applying patches, extracted for testability.
+// The patch applies completely or not at all.
+func executeApplyPatch(workspaceDir string, config Config, input PatchInput) (*PatchOutput, error) {
+	start := time.Now()
+	slog.Info("Starting apply patch operation",
+		"patch_size_bytes", len(input.Patch),
+		"dry_run", input.DryRun,
+		"fuzz", input.Fuzz,
+		"workspace", workspaceDir)
+
+	// A dry run only checks the patch, so it works in read-only mode
+	if !input.DryRun {
+		if err := config.checkWritable("applyPatch"); err != nil {
+			return nil, err
+		}
+	}
+	if input.Fuzz < 0 || input.Fuzz > MaxPatchFuzz {
+		return nil, fmt.Errorf("fuzz must be between 0 and %d, got %d", MaxPatchFuzz, input.Fuzz)
+	}
+	patches, err := parseUnifiedDiff(input.Patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var redacted []string
+	for i := range patches {
+		found, err := scanPatchSecrets(config, &patches[i])
+		if err != nil {
+			return nil, err
+		}
+		redacted = append(redacted, found...)
+	}
+
+	// The patched files are locked from reading to writing, so concurrent changes are not lost
+	var paths []string
+	for _, patch := range patches {
+		for _, path := range []string{patch.oldPath, patch.newPath} {
+			if path == "" {
+				continue
+			}
+			resolved, err := config.resolveFile(workspaceDir, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve path: %w", err)
+			}
+			paths = append(paths, resolved)
+		}
+	}
+	unlock := lockPaths(paths...)
+	defer unlock()
+
+	// Files are patched in memory first, so a failing hunk leaves the workspace untouched
+	contents := make(map[string]*fileLines)
+	var deleted []string
+	output := &PatchOutput{Files: []PatchedFile{}, DryRun: input.DryRun, Redacted: redacted}
+	for _, patch := range patches {
+		file, content, err := applyFilePatch(workspaceDir, config, patch, contents, input.Fuzz)
+		if err != nil {
+			slog.Error("Failed to apply patch",
+				"path", patchPath(patch),
+				"error", err)
+			return nil, err
+		}
+		if file.Status == PatchStatusDeleted || file.Status == PatchStatusRenamed {
+			deleted = append(deleted, patch.oldPath)
+			contents[patch.oldPath] = nil
+		}
+		if content != nil {
+			contents[file.Path] = content
+		}
+		output.Files = append(output.Files, file)
+	}
+
+	if !input.DryRun {
+		for _, file := range output.Files {
+			content := contents[file.Path]
+			if content == nil {
+				continue
+			}
+			if err := writeFileLines(workspaceDir, config, file.Path, content); err != nil {
+				return nil, err
+			}
+		}
+		for _, path := range deleted {
+			if contents[path] != nil {
+				// A later patch recreated the file
+				continue
+			}
+			resolved, err := config.resolveFile(workspaceDir, path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve path: %w", err)
+			}
+			if err := os.Remove(resolved); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		}
+	}
+	output.Success = true
+
+	slog.Info("Apply patch completed successfully",
+		"files", len(output.Files),
+		"dry_run", input.DryRun,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// scanPatchSecrets applies config.SecretScan to the lines patch adds, redacting them in place
+func scanPatchSecrets(config Config, patch *filePatch) ([]string, error) {
+	var added []*hunkLine
+	var texts []string
+	for i := range patch.hunks {
+		for j := range patch.hunks[i].lines {
+			if line := &patch.hunks[i].lines[j]; line.op == '+' {
+				added = append(added, line)
+				texts = append(texts, line.text)
+			}
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	text, found, err := config.scanSecrets(patchPath(*patch), strings.Join(texts, "\n"))
+	if err != nil || len(found) == 0 {
+		return nil, err
+	}
+	// Redaction keeps line breaks, so the lines map back one to one
+	for i, line := range strings.Split(text, "\n") {
+		added[i].text = line
+	}
+	return found, nil
+}
+
+// applyFilePatch applies patch to the current content of its file, taken from contents
+// when an earlier patch changed it, and returns the result; the content is nil for
+// deleted files
+func applyFilePatch(workspaceDir string, config Config, patch filePatch, contents map[string]*fileLines, fuzz int) (PatchedFile, *fileLines, error) {
+	file := PatchedFile{Path: patch.newPath, Hunks: len(patch.hunks)}
+	var original *fileLines
+	switch {
+	case patch.oldPath == "":
+		file.Status = PatchStatusCreated
+		if current, err := readFileLines(workspaceDir, config, patch.newPath, contents); err == nil && current != nil {
+			return file, nil, fmt.Errorf("cannot create %s: file already exists", patch.newPath)
+		} else if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return file, nil, err
+		}
+		original = &fileLines{eofNewline: true}
+	default:
+		file.Status = PatchStatusModified
+		if patch.newPath == "" {
+			file.Path = patch.oldPath
+			file.Status = PatchStatusDeleted
+		} else if patch.newPath != patch.oldPath {
+			file.Status = PatchStatusRenamed
+		}
+		current, err := readFileLines(workspaceDir, config, patch.oldPath, contents)
+		if err != nil {
+			return file, nil, err
+		}
+		if current == nil {
+			return file, nil, fmt.Errorf("cannot patch %s: file was deleted by an earlier patch", patch.oldPath)
+		}
+		original = current
+	}
+
+	result, fuzzy, err := applyHunks(original, patch.hunks, fuzz)
+	if err != nil {
+		return file, nil, fmt.Errorf("failed to patch %s: %w", patchPath(patch), err)
+	}
+	file.Fuzzy = fuzzy
+	if file.Status == PatchStatusDeleted {
+		if len(result.lines) > 0 {
+			return file, nil, fmt.Errorf("cannot delete %s: the patch leaves %d lines", patch.oldPath, len(result.lines))
+		}
+		return file, nil, nil
+	}
+	return file, result, nil
+}
+
+// applyHunks applies hunks in order to content and reports whether any hunk needed fuzz
+// or a shifted position
+func applyHunks(content *fileLines, hunks []hunk, fuzz int) (*fileLines, bool, error) {
+	lines := append([]string(nil), content.lines...)
+	eofNewline := content.eofNewline
+	fuzzy := false
+	offset, minPos := 0, 0
+	for i, h := range hunks {
+		old, replacement := h.sides()
+		expected := max(h.oldStart-1, 0) + offset
+		pos, skipStart, skipEnd := -1, 0, 0
+		for f := 0; f <= fuzz && pos < 0; f++ {
+			pos, skipStart, skipEnd = findHunk(lines, h, expected, minPos, f)
+		}
+		if pos < 0 {
+			return nil, false, fmt.Errorf("hunk %d (@@ -%d) does not match the file", i+1, h.oldStart)
+		}
+		if pos != expected || skipStart > 0 || skipEnd > 0 {
+			fuzzy = true
+		}
+		// Context lines ignored by fuzz stay as they are in the file
+		old = old[skipStart : len(old)-skipEnd]
+		replacement = replacement[skipStart : len(replacement)-skipEnd]
+
+		atEOF := pos+len(old) == len(lines)
+		lines = append(lines[:pos], append(replacement, lines[pos+len(old):]...)...)
+		offset += len(replacement) - len(old)
+		minPos = pos + len(replacement)
+		if atEOF && skipEnd == 0 {
+			if last, ok := h.lastLine('+'); ok && last.noNewline {
+				eofNewline = false
+			} else if last, ok := h.lastLine('-'); ok && last.noNewline {
+				eofNewline = true
+			}
+		}
+	}
+	return &fileLines{lines: lines, eofNewline: eofNewline || len(lines) == 0}, fuzzy, nil
+}
+
+// findHunk finds where the original lines of h, less up to fuzz leading and trailing
+// context lines, occur in lines at or after minPos, preferring the position closest to
+// expected. It returns the position, or -1, and the number of context lines skipped.
+func findHunk(lines []string, h hunk, expected, minPos, fuzz int) (int, int, int) {
+	old, _ := h.sides()
+	skipStart := min(fuzz, h.leadingContext())
+	skipEnd := min(fuzz, h.trailingContext())
+	if skipStart+skipEnd > len(old) {
+		return -1, 0, 0
+	}
+	want := old[skipStart : len(old)-skipEnd]
+	if len(want) == 0 && len(old) > 0 {
+		// Without any original line left the hunk could go anywhere
+		return -1, 0, 0
+	}
+	expected += skipStart
+
+	matches := func(pos int) bool {
+		if pos < minPos || pos+len(want) > len(lines) {
+			return false
+		}
+		for i, line := range want {
+			got := lines[pos+i]
+			if fuzz > 0 {
+				got, line = strings.TrimRight(got, " \t\r"), strings.TrimRight(line, " \t\r")
+			}
+			if got != line {
+				return false
+			}
+		}
+		return true
+	}
+	for delta := 0; delta <= max(expected, len(lines)); delta++ {
+		if matches(expected - delta) {
+			return expected - delta, skipStart, skipEnd
+		}
+		if delta > 0 && matches(expected+delta) {
+			return expected + delta, skipStart, skipEnd
+		}
+	}
+	return -1, 0, 0
+}
+
+// sides returns the lines of the hunk before and after the change
+func (h hunk) sides() ([]string, []string) {
+	var old, replacement []string
+	for _, line := range h.lines {
+		if line.op != '+' {
+			old = append(old, line.text)
+		}
+		if line.op != '-' {
+			replacement = append(replacement, line.text)
+		}
+	}
+	return old, replacement
+}
+
+// leadingContext returns the number of context lines the hunk starts with
+func (h hunk) leadingContext() int {
+	n := 0
+	for n < len(h.lines) && h.lines[n].op == ' ' {
+		n++
+	}
+	return n
+}
+
+// trailingContext returns the number of context lines the hunk ends with
+func (h hunk) trailingContext() int {
+	n := 0
+	for n < len(h.lines) && h.lines[len(h.lines)-1-n].op == ' ' {
+		n++
+	}
+	return n
+}
+
+// lastLine returns the last line of the hunk on the side of op ('-' for the original,
+// '+' for the result), including context lines
+func (h hunk) lastLine(op byte) (hunkLine, bool) {
+	for i := len(h.lines) - 1; i >= 0; i-- {
+		if line := h.lines[i]; line.op == op || line.op == ' ' {
+			return line, true
+		}
+	}
+	return hunkLine{}, false
+}
+
+// parseUnifiedDiff parses a unified diff into per-file patches. Hunk line counts are not
+// trusted; a hunk ends at the next hunk or file header, as with git apply --recount.
+func parseUnifiedDiff(diff string) ([]filePatch, error) {
+	lines := strings.Split(strings.ReplaceAll(diff, "\r\n", "\n"), "\n")
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	var patches []filePatch
+	var current *filePatch
+	var currentHunk *hunk
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			patches = append(patches, filePatch{
+				oldPath: diffPath(strings.TrimPrefix(line, "--- "), "a/"),
+				newPath: diffPath(strings.TrimPrefix(lines[i+1], "+++ "), "b/"),
+			})
+			current, currentHunk = &patches[len(patches)-1], nil
+			if current.oldPath == "" && current.newPath == "" {
+				return nil, fmt.Errorf("invalid patch: file header at line %d has no path", i+1)
+			}
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("invalid patch: hunk at line %d has no file header", i+1)
+			}
+			m := hunkHeader.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("invalid patch: malformed hunk header at line %d: %q", i+1, line)
+			}
+			oldStart, _ := strconv.Atoi(m[1])
+			current.hunks = append(current.hunks, hunk{oldStart: oldStart})
+			currentHunk = &current.hunks[len(current.hunks)-1]
+		case currentHunk != nil && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "-") || strings.HasPrefix(line, "+")):
+			currentHunk.lines = append(currentHunk.lines, hunkLine{op: line[0], text: line[1:]})
+		case currentHunk != nil && strings.HasPrefix(line, `\`):
+			if n := len(currentHunk.lines); n > 0 {
+				currentHunk.lines[n-1].noNewline = true
+			}
+		case currentHunk != nil && line == "":
+			// Editors and models often strip the space of empty context lines
+			currentHunk.lines = append(currentHunk.lines, hunkLine{op: ' '})
+		default:
+			// Headers such as diff --git and index lines end the hunk
+			currentHunk = nil
+		}
+	}
+
+	if len(patches) == 0 {
+		return nil, fmt.Errorf("invalid patch: no file headers (--- and +++ lines) found")
+	}
+	for _, patch := range patches {
+		if len(patch.hunks) == 0 {
+			return nil, fmt.Errorf("invalid patch: no hunks for %s", patchPath(patch))
+		}
+		for _, h := range patch.hunks {
+			if len(h.lines) == 0 {
+				return nil, fmt.Errorf("invalid patch: empty hunk in %s", patchPath(patch))
+			}
+		}
+	}
+	return patches, nil
+}
+
+// diffPath returns the path of a --- or +++ header, without timestamp and the git prefix;
+// /dev/null is returned as an empty path
+func diffPath(header, prefix string) string {
+	path, _, _ := strings.Cut(header, "\t")
+	path = strings.TrimSpace(path)
+	if path == "/dev/null" {
+		return ""
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// patchPath returns the path a patch is reported under
+func patchPath(patch filePatch) string {
+	if patch.newPath != "" {
+		return patch.newPath
+	}
+	return patch.oldPath
+}
+
+// ApplyPatchTool creates a new applyPatch tool that applies unified diffs within the workspace directory
+func ApplyPatchTool() tool.Tool {
+	return NewApplyPatchToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewApplyPatchToolWithWorkspace creates a new applyPatch tool with a custom workspace directory
+func NewApplyPatchToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewApplyPatchToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewApplyPatchToolWithConfig creates a new applyPatch tool with a custom workspace directory and configuration
+func NewApplyPatchToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "applyPatch",
+			Description: "Apply a unified diff (diff -u or git diff format, paths relative to the workspace) to workspace files. Prefer it over fileWrite for small changes to existing files. The patch applies completely or not at all; set dryRun to check it first and fuzz (1-3) to tolerate slightly stale context lines.",
+		},
+		func(ctx tool.Context, input PatchInput) *PatchOutput {
+			observe := observeTool("applyPatch")
+			output, err := executeApplyPatch(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &PatchOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create applyPatch tool: %v", err))
+	}
+	return t
+}
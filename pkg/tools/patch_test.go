@@ -0,0 +1,301 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const calcSource = `package calc
+
+// Add returns the sum of a and b
+func Add(a, b int) int {
+	return a + b
+}
+
+// Sub returns the difference of a and b
+func Sub(a, b int) int {
+	return a - b
+}
+`
+
+func TestApplyPatchTool(t *testing.T) {
+	tests := []struct {
+		name        string
+		files       map[string]string
+		input       PatchInput
+		want        map[string]string
+		wantFiles   []PatchedFile
+		errContains string
+	}{
+		{
+			name:  "modify with git prefixes",
+			files: map[string]string{"calc.go": calcSource},
+			input: PatchInput{Patch: `diff --git a/calc.go b/calc.go
+index 1111111..2222222 100644
+--- a/calc.go
++++ b/calc.go
+@@ -8,4 +8,4 @@ func Add(a, b int) int {
+ // Sub returns the difference of a and b
+ func Sub(a, b int) int {
+-	return a - b
++	return a - b + 0
+ }
+`},
+			want:      map[string]string{"calc.go": strings.Replace(calcSource, "return a - b", "return a - b + 0", 1)},
+			wantFiles: []PatchedFile{{Path: "calc.go", Status: PatchStatusModified, Hunks: 1}},
+		},
+		{
+			name:  "several hunks with wrong counts and stripped blank context",
+			files: map[string]string{"calc.go": calcSource},
+			input: PatchInput{Patch: `--- calc.go
++++ calc.go
+@@ -3,3 +3,3 @@
+-// Add returns the sum of a and b
++// Add returns a + b
+ func Add(a, b int) int {
+ 	return a + b
+@@ -6,7 +6,9 @@
+ }
+
++// Mul returns the product of a and b
++func Mul(a, b int) int { return a * b }
++
+ // Sub returns the difference of a and b
+`},
+			want: map[string]string{"calc.go": strings.Replace(
+				strings.Replace(calcSource, "// Add returns the sum of a and b", "// Add returns a + b", 1),
+				"// Sub", "// Mul returns the product of a and b\nfunc Mul(a, b int) int { return a * b }\n\n// Sub", 1)},
+			wantFiles: []PatchedFile{{Path: "calc.go", Status: PatchStatusModified, Hunks: 2}},
+		},
+		{
+			name:  "shifted hunk",
+			files: map[string]string{"calc.go": "package calc\n\nimport \"fmt\"\n\n" + strings.TrimPrefix(calcSource, "package calc\n\n")},
+			input: PatchInput{Patch: `--- a/calc.go
++++ b/calc.go
+@@ -5,3 +5,3 @@
+ 	return a + b
+-}
++} // Add
+`},
+			want: map[string]string{"calc.go": "package calc\n\nimport \"fmt\"\n\n" +
+				strings.Replace(strings.TrimPrefix(calcSource, "package calc\n\n"), "\treturn a + b\n}", "\treturn a + b\n} // Add", 1)},
+			wantFiles: []PatchedFile{{Path: "calc.go", Status: PatchStatusModified, Hunks: 1, Fuzzy: true}},
+		},
+		{
+			name:  "stale context needs fuzz",
+			files: map[string]string{"calc.go": calcSource},
+			input: PatchInput{Fuzz: 1, Patch: `--- a/calc.go
++++ b/calc.go
+@@ -3,4 +3,4 @@
+ // Add returns the total of a and b
+ func Add(a, b int) int {
+-	return a + b
++	return b + a
+ }
+`},
+			want:      map[string]string{"calc.go": strings.Replace(calcSource, "return a + b", "return b + a", 1)},
+			wantFiles: []PatchedFile{{Path: "calc.go", Status: PatchStatusModified, Hunks: 1, Fuzzy: true}},
+		},
+		{
+			name:  "stale context without fuzz",
+			files: map[string]string{"calc.go": calcSource},
+			input: PatchInput{Patch: `--- a/calc.go
++++ b/calc.go
+@@ -3,4 +3,4 @@
+ // Add returns the total of a and b
+ func Add(a, b int) int {
+-	return a + b
++	return b + a
+ }
+`},
+			want:        map[string]string{"calc.go": calcSource},
+			errContains: "failed to patch calc.go: hunk 1 (@@ -3) does not match the file",
+		},
+		{
+			name:  "create, delete, and rename",
+			files: map[string]string{"old.go": "package calc\n", "gone.go": "package calc\n\nvar x = 1\n"},
+			input: PatchInput{Patch: `--- /dev/null
++++ b/pkg/new.go
+@@ -0,0 +1,3 @@
++package pkg
++
++const Answer = 42
+--- a/gone.go
++++ /dev/null
+@@ -1,3 +0,0 @@
+-package calc
+-
+-var x = 1
+--- a/old.go
++++ b/renamed.go
+@@ -1 +1 @@
+-package calc
++package renamed
+`},
+			want: map[string]string{
+				"pkg/new.go": "package pkg\n\nconst Answer = 42\n", "renamed.go": "package renamed\n",
+				"gone.go": "", "old.go": "",
+			},
+			wantFiles: []PatchedFile{
+				{Path: "pkg/new.go", Status: PatchStatusCreated, Hunks: 1},
+				{Path: "gone.go", Status: PatchStatusDeleted, Hunks: 1},
+				{Path: "renamed.go", Status: PatchStatusRenamed, Hunks: 1},
+			},
+		},
+		{
+			name:  "missing trailing newline",
+			files: map[string]string{"a.txt": "one\ntwo"},
+			input: PatchInput{Patch: `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,3 @@
+ one
+-two
+\ No newline at end of file
++two
++three
+\ No newline at end of file
+`},
+			want:      map[string]string{"a.txt": "one\ntwo\nthree"},
+			wantFiles: []PatchedFile{{Path: "a.txt", Status: PatchStatusModified, Hunks: 1}},
+		},
+		{
+			name:  "dry run",
+			files: map[string]string{"calc.go": calcSource},
+			input: PatchInput{DryRun: true, Patch: `--- a/calc.go
++++ b/calc.go
+@@ -1 +1 @@
+-package calc
++package calculator
+`},
+			want:      map[string]string{"calc.go": calcSource},
+			wantFiles: []PatchedFile{{Path: "calc.go", Status: PatchStatusModified, Hunks: 1}},
+		},
+		{
+			name:  "failing hunk leaves every file untouched",
+			files: map[string]string{"calc.go": calcSource, "b.go": "package calc\n"},
+			input: PatchInput{Patch: `--- a/b.go
++++ b/b.go
+@@ -1 +1 @@
+-package calc
++package b
+--- a/calc.go
++++ b/calc.go
+@@ -1 +1 @@
+-package other
++package calc
+`},
+			want:        map[string]string{"calc.go": calcSource, "b.go": "package calc\n"},
+			errContains: "failed to patch calc.go",
+		},
+		{
+			name:        "create existing file",
+			files:       map[string]string{"calc.go": calcSource},
+			input:       PatchInput{Patch: "--- /dev/null\n+++ b/calc.go\n@@ -0,0 +1 @@\n+package calc\n"},
+			errContains: "file already exists",
+		},
+		{
+			name:        "missing file",
+			input:       PatchInput{Patch: "--- a/missing.go\n+++ b/missing.go\n@@ -1 +1 @@\n-a\n+b\n"},
+			errContains: "failed to read file missing.go",
+		},
+		{
+			name:        "path traversal",
+			input:       PatchInput{Patch: "--- /dev/null\n+++ b/../evil.go\n@@ -0,0 +1 @@\n+package evil\n"},
+			errContains: "path traversal detected",
+		},
+		{
+			name:        "not a diff",
+			input:       PatchInput{Patch: "Replace Add with Sum"},
+			errContains: "no file headers",
+		},
+		{
+			name:        "invalid fuzz",
+			input:       PatchInput{Patch: "--- a\n+++ b\n@@ -1 +1 @@\n-a\n+b\n", Fuzz: 9},
+			errContains: "fuzz must be between 0 and 3",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspaceDir := t.TempDir()
+			for path, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(workspaceDir, path), []byte(content), 0644); err != nil {
+					t.Fatalf("failed to write %s: %v", path, err)
+				}
+			}
+
+			output, err := executeApplyPatch(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeApplyPatch() error = %v, want it to contain %q", err, tt.errContains)
+				}
+			} else {
+				if err != nil {
+					t.Fatalf("executeApplyPatch() error = %v", err)
+				}
+				if !output.Success || output.DryRun != tt.input.DryRun {
+					t.Errorf("output = %+v, want success with dry run %v", output, tt.input.DryRun)
+				}
+				if !reflect.DeepEqual(output.Files, tt.wantFiles) {
+					t.Errorf("Files = %+v, want %+v", output.Files, tt.wantFiles)
+				}
+			}
+
+			for path, want := range tt.want {
+				got, err := os.ReadFile(filepath.Join(workspaceDir, path))
+				if want == "" {
+					if !os.IsNotExist(err) {
+						t.Errorf("%s exists, want it deleted", path)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("failed to read %s: %v", path, err)
+				}
+				if string(got) != want {
+					t.Errorf("%s = %q, want %q", path, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParseUnifiedDiff(t *testing.T) {
+	patches, err := parseUnifiedDiff("--- a/x.go\t2024-01-01 10:00:00\n+++ b/x.go\t2024-01-02 10:00:00\n@@ -2,2 +2,2 @@ func x()\n a\n-b\n+c\n")
+	if err != nil {
+		t.Fatalf("parseUnifiedDiff() error = %v", err)
+	}
+	want := []filePatch{{
+		oldPath: "x.go",
+		newPath: "x.go",
+		hunks: []hunk{{oldStart: 2, lines: []hunkLine{
+			{op: ' ', text: "a"}, {op: '-', text: "b"}, {op: '+', text: "c"},
+		}}},
+	}}
+	if !reflect.DeepEqual(patches, want) {
+		t.Errorf("parseUnifiedDiff() = %+v, want %+v", patches, want)
+	}
+
+	for _, diff := range []string{
+		"@@ -1 +1 @@\n-a\n+b\n",
+		"--- a/x.go\n+++ b/x.go\n",
+		"--- a/x.go\n+++ b/x.go\n@@ -x +1 @@\n-a\n",
+		"--- /dev/null\n+++ /dev/null\n@@ -0,0 +1 @@\n+a\n",
+	} {
+		if _, err := parseUnifiedDiff(diff); err == nil {
+			t.Errorf("parseUnifiedDiff(%q) error = nil, want an error", diff)
+		}
+	}
+}
+
+func TestApplyPatchTool_ToolCreation(t *testing.T) {
+	if ApplyPatchTool() == nil {
+		t.Fatal("ApplyPatchTool() returned nil")
+	}
+	if got := NewApplyPatchToolWithWorkspace(t.TempDir()).Name(); got != "applyPatch" {
+		t.Errorf("Name() = %q, want %q", got, "applyPatch")
+	}
+}
@@ -0,0 +1,162 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// Actions of the scratchpad tool
+const (
+	// ScratchpadGet returns the value of a key
+	ScratchpadGet = "get"
+	// ScratchpadSet stores a value under a key
+	ScratchpadSet = "set"
+	// ScratchpadList returns the keys
+	ScratchpadList = "list"
+	// ScratchpadDelete removes a key
+	ScratchpadDelete = "delete"
+)
+
+// ScratchpadStateKey is the session state key the scratchpad is stored under, so stage
+// instructions can include it as {scratchpad?}
+const ScratchpadStateKey = "scratchpad"
+
+// MaxScratchpadKeys is the maximum number of keys in the scratchpad
+const MaxScratchpadKeys = 50
+
+// MaxScratchpadValueSize is the maximum size of a scratchpad value in bytes (8KB)
+const MaxScratchpadValueSize = 8 * 1024
+
+// ScratchpadInput defines the input parameters for the scratchpad tool
+type ScratchpadInput struct {
+	// Action is get, set, list, or delete
+	Action string `json:"action"`
+	// Key is the key to get, set, or delete
+	Key string `json:"key,omitempty"`
+	// Value is the value to set, such as a file list or a checklist
+	Value string `json:"value,omitempty"`
+}
+
+// ScratchpadOutput defines the output structure for the scratchpad tool
+type ScratchpadOutput struct {
+	// Key is the key that was read, set, or deleted
+	Key string `json:"key,omitempty"`
+	// Value is the value of the key for get
+	Value string `json:"value,omitempty"`
+	// Found indicates whether the key existed for get and delete
+	Found bool `json:"found,omitempty"`
+	// Keys are the keys in lexical order for list
+	Keys []string `json:"keys,omitempty"`
+	// Success indicates whether the operation was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// readScratchpad returns a copy of the scratchpad in state
+func readScratchpad(state session.State) (map[string]string, error) {
+	pad := make(map[string]string)
+	value, err := state.Get(ScratchpadStateKey)
+	if errors.Is(err, session.ErrStateKeyNotExist) {
+		return pad, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read scratchpad: %w", err)
+	}
+	switch value := value.(type) {
+	case map[string]string:
+		maps.Copy(pad, value)
+	// Session services that store state as JSON return generic maps
+	case map[string]any:
+		for k, v := range value {
+			if s, ok := v.(string); ok {
+				pad[k] = s
+			}
+		}
+	default:
+		return nil, fmt.Errorf("invalid scratchpad state of type %T", value)
+	}
+	return pad, nil
+}
+
+// executeScratchpad is the core logic of the scratchpad tool, extracted for testability
+func executeScratchpad(state session.State, input ScratchpadInput) (*ScratchpadOutput, error) {
+	slog.Info("Starting scratchpad operation",
+		"action", input.Action,
+		"key", input.Key)
+
+	if input.Action != ScratchpadList && input.Key == "" {
+		return nil, fmt.Errorf("key is required for %s", input.Action)
+	}
+	pad, err := readScratchpad(state)
+	if err != nil {
+		return nil, err
+	}
+
+	output := &ScratchpadOutput{Key: input.Key, Success: true}
+	switch input.Action {
+	case ScratchpadGet:
+		output.Value, output.Found = pad[input.Key]
+		return output, nil
+	case ScratchpadList:
+		output.Key = ""
+		output.Keys = slices.Sorted(maps.Keys(pad))
+		return output, nil
+	case ScratchpadSet:
+		if len(input.Value) > MaxScratchpadValueSize {
+			return nil, fmt.Errorf("value too large: %d bytes (max %d bytes); write large data to a file", len(input.Value), MaxScratchpadValueSize)
+		}
+		if _, ok := pad[input.Key]; !ok && len(pad) == MaxScratchpadKeys {
+			return nil, fmt.Errorf("scratchpad is full (%d keys); delete keys that are no longer needed", MaxScratchpadKeys)
+		}
+		pad[input.Key] = input.Value
+	case ScratchpadDelete:
+		_, output.Found = pad[input.Key]
+		delete(pad, input.Key)
+	default:
+		return nil, fmt.Errorf("unknown action %q: use get, set, list, or delete", input.Action)
+	}
+
+	if err := state.Set(ScratchpadStateKey, pad); err != nil {
+		return nil, fmt.Errorf("failed to write scratchpad: %w", err)
+	}
+	slog.Info("Scratchpad operation completed successfully",
+		"action", input.Action,
+		"key", input.Key,
+		"keys", len(pad))
+	return output, nil
+}
+
+// ScratchpadTool creates a new scratchpad tool that keeps small values in the session
+// state, where every stage of the run can read them
+func ScratchpadTool() tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "scratchpad",
+			Description: "Keep small string values, such as a planned file list or a task checklist, that later stages of the run can read. Actions: set a key to a value, get a key, list the keys, or delete a key. Write large data to files instead.",
+		},
+		func(ctx tool.Context, input ScratchpadInput) *ScratchpadOutput {
+			observe := observeTool("scratchpad")
+			output, err := executeScratchpad(ctx.State(), input)
+			observe(err)
+			if err != nil {
+				return &ScratchpadOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create scratchpad tool: %v", err))
+	}
+	return t
+}
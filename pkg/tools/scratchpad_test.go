@@ -0,0 +1,128 @@
+package tools
+
+import (
+	"iter"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+)
+
+// mapState is a session.State backed by a map
+type mapState map[string]any
+
+func (s mapState) Get(key string) (any, error) {
+	if value, ok := s[key]; ok {
+		return value, nil
+	}
+	return nil, session.ErrStateKeyNotExist
+}
+
+func (s mapState) Set(key string, value any) error {
+	s[key] = value
+	return nil
+}
+
+func (s mapState) All() iter.Seq2[string, any] {
+	return maps.All(s)
+}
+
+func TestScratchpad(t *testing.T) {
+	state := mapState{}
+	steps := []struct {
+		input ScratchpadInput
+		want  ScratchpadOutput
+	}{
+		{
+			input: ScratchpadInput{Action: ScratchpadGet, Key: "files"},
+			want:  ScratchpadOutput{Key: "files", Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadSet, Key: "files", Value: "calc.go\ncalc_test.go"},
+			want:  ScratchpadOutput{Key: "files", Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadSet, Key: "checklist", Value: "- [ ] Add\n- [ ] Div"},
+			want:  ScratchpadOutput{Key: "checklist", Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadGet, Key: "files"},
+			want:  ScratchpadOutput{Key: "files", Value: "calc.go\ncalc_test.go", Found: true, Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadList},
+			want:  ScratchpadOutput{Keys: []string{"checklist", "files"}, Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadDelete, Key: "files"},
+			want:  ScratchpadOutput{Key: "files", Found: true, Success: true},
+		},
+		{
+			input: ScratchpadInput{Action: ScratchpadList},
+			want:  ScratchpadOutput{Keys: []string{"checklist"}, Success: true},
+		},
+	}
+
+	for i, step := range steps {
+		output, err := executeScratchpad(state, step.input)
+		if err != nil {
+			t.Fatalf("step %d: executeScratchpad(%+v) error = %v", i, step.input, err)
+		}
+		if !reflect.DeepEqual(*output, step.want) {
+			t.Errorf("step %d: executeScratchpad(%+v) = %+v, want %+v", i, step.input, *output, step.want)
+		}
+	}
+
+	// Values written by a JSON-backed session service come back as generic maps
+	state = mapState{ScratchpadStateKey: map[string]any{"plan": "step 1"}}
+	output, err := executeScratchpad(state, ScratchpadInput{Action: ScratchpadGet, Key: "plan"})
+	if err != nil || output.Value != "step 1" {
+		t.Errorf("executeScratchpad() = %+v, %v, want step 1", output, err)
+	}
+}
+
+func TestScratchpad_InvalidInput(t *testing.T) {
+	full := make(map[string]string)
+	for i := range MaxScratchpadKeys {
+		full[strings.Repeat("k", i+1)] = "v"
+	}
+
+	tests := []struct {
+		name        string
+		state       mapState
+		input       ScratchpadInput
+		errContains string
+	}{
+		{name: "unknown action", input: ScratchpadInput{Action: "clear", Key: "a"}, errContains: `unknown action "clear"`},
+		{name: "missing key", input: ScratchpadInput{Action: ScratchpadSet, Value: "v"}, errContains: "key is required"},
+		{name: "value too large", input: ScratchpadInput{Action: ScratchpadSet, Key: "a", Value: strings.Repeat("x", MaxScratchpadValueSize+1)}, errContains: "value too large"},
+		{name: "full", state: mapState{ScratchpadStateKey: full}, input: ScratchpadInput{Action: ScratchpadSet, Key: "new", Value: "v"}, errContains: "scratchpad is full"},
+		{name: "invalid state", state: mapState{ScratchpadStateKey: "text"}, input: ScratchpadInput{Action: ScratchpadList}, errContains: "invalid scratchpad state"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state := tt.state
+			if state == nil {
+				state = mapState{}
+			}
+			_, err := executeScratchpad(state, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("executeScratchpad() error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+
+	// Existing keys can still be updated when the scratchpad is full
+	if _, err := executeScratchpad(mapState{ScratchpadStateKey: full}, ScratchpadInput{Action: ScratchpadSet, Key: "k", Value: "updated"}); err != nil {
+		t.Errorf("executeScratchpad() update of a full scratchpad error = %v", err)
+	}
+}
+
+func TestScratchpadTool_ToolCreation(t *testing.T) {
+	if got := ScratchpadTool().Name(); got != "scratchpad" {
+		t.Errorf("Name() = %q, want %q", got, "scratchpad")
+	}
+}
@@ -0,0 +1,251 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxSearchMatches is the maximum number of matches returned by the search tool
+const MaxSearchMatches = 200
+
+// MaxSearchContextLines is the maximum number of context lines around each match
+const MaxSearchContextLines = 5
+
+// maxSearchLineLength caps the length of the lines returned by the search tool
+const maxSearchLineLength = 500
+
+// errSearchLimit stops the walk of executeSearch once MaxSearchMatches matches are found
+var errSearchLimit = errors.New("search match limit reached")
+
+// SearchInput defines the input parameters for the search tool
+type SearchInput struct {
+	// Pattern is the regular expression (RE2 syntax) to search for
+	Pattern string `json:"pattern"`
+	// Path is the relative path of the directory or file to search (defaults to the workspace root)
+	Path string `json:"path,omitempty"`
+	// Glob restricts the search to files whose workspace-relative path matches this pattern, such as *.go or pkg/**/*.go
+	Glob string `json:"glob,omitempty"`
+	// ContextLines is the number of lines shown before and after each match (at most MaxSearchContextLines)
+	ContextLines int `json:"contextLines,omitempty"`
+	// IgnoreCase makes the pattern case-insensitive
+	IgnoreCase bool `json:"ignoreCase,omitempty"`
+}
+
+// SearchMatch is a line matching the search pattern
+type SearchMatch struct {
+	// Path is the slash-separated path of the file, relative to the workspace
+	Path string `json:"path"`
+	// Line is the 1-based line number of the match
+	Line int `json:"line"`
+	// Text is the matching line
+	Text string `json:"text"`
+	// Before are the lines preceding the match
+	Before []string `json:"before,omitempty"`
+	// After are the lines following the match
+	After []string `json:"after,omitempty"`
+}
+
+// SearchOutput defines the output structure for the search tool
+type SearchOutput struct {
+	// Matches are the matching lines in file and line order
+	Matches []SearchMatch `json:"matches"`
+	// FilesSearched is the number of files whose contents were searched
+	FilesSearched int `json:"filesSearched"`
+	// Truncated indicates that the search stopped after MaxSearchMatches matches
+	Truncated bool `json:"truncated,omitempty"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// executeSearch is the core logic for searching files, extracted for testability
+func executeSearch(workspaceDir string, config Config, input SearchInput) (*SearchOutput, error) {
+	start := time.Now()
+	slog.Info("Starting search operation",
+		"pattern", input.Pattern,
+		"path", input.Path,
+		"glob", input.Glob,
+		"workspace", workspaceDir)
+
+	if input.Pattern == "" {
+		return nil, fmt.Errorf("pattern is required")
+	}
+	expr := input.Pattern
+	if input.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", input.Pattern, err)
+	}
+	glob := filepath.ToSlash(input.Glob)
+	if glob != "" && !validGlob(glob) {
+		return nil, fmt.Errorf("invalid glob %q", input.Glob)
+	}
+	contextLines := min(max(input.ContextLines, 0), MaxSearchContextLines)
+
+	root, err := resolveWorkspacePath(workspaceDir, cmp.Or(input.Path, "."))
+	if err != nil {
+		slog.Error("Failed to resolve path",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to resolve path: %w", err)
+	}
+	if err := config.checkPath(cmp.Or(input.Path, ".")); err != nil {
+		return nil, err
+	}
+	workspace, err := resolveWorkspacePath(workspaceDir, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace: %w", err)
+	}
+	ignore, err := loadIgnore(workspace, config)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkIgnored(workspaceDir, config, cmp.Or(input.Path, "."), true); err != nil {
+		return nil, err
+	}
+
+	output := &SearchOutput{Matches: []SearchMatch{}}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(workspace, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if d.Name() == ".git" || config.denied(rel) || ignore.ignored(rel, true) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() || config.denied(rel) || !config.allowedExtension(rel) || ignore.ignored(rel, false) {
+			return nil
+		}
+		if glob != "" && !matchGlob(glob, rel) {
+			return nil
+		}
+		return searchFile(p, rel, re, contextLines, config.maxFileSize(), output)
+	})
+	if err != nil && !errors.Is(err, errSearchLimit) {
+		slog.Error("Failed to search files",
+			"path", input.Path,
+			"error", err)
+		return nil, fmt.Errorf("failed to search %s: %w", cmp.Or(input.Path, "."), err)
+	}
+
+	slog.Info("Search completed successfully",
+		"pattern", input.Pattern,
+		"files", output.FilesSearched,
+		"matches", len(output.Matches),
+		"truncated", output.Truncated,
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// searchFile adds the lines of the file at path matching re to output, reporting the file
+// as rel. Binary files and files over maxSize bytes are skipped.
+func searchFile(path, rel string, re *regexp.Regexp, contextLines int, maxSize int64, output *SearchOutput) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if info.Size() > maxSize {
+		return nil
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil
+	}
+	output.FilesSearched++
+
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 0, 64*1024), int(maxSize))
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	for i, line := range lines {
+		if !re.MatchString(line) {
+			continue
+		}
+		if len(output.Matches) == MaxSearchMatches {
+			output.Truncated = true
+			return errSearchLimit
+		}
+		match := SearchMatch{Path: rel, Line: i + 1, Text: truncateLine(line)}
+		for _, l := range lines[max(0, i-contextLines):i] {
+			match.Before = append(match.Before, truncateLine(l))
+		}
+		for _, l := range lines[i+1 : min(len(lines), i+1+contextLines)] {
+			match.After = append(match.After, truncateLine(l))
+		}
+		output.Matches = append(output.Matches, match)
+	}
+	return nil
+}
+
+// truncateLine shortens a line to maxSearchLineLength bytes
+func truncateLine(line string) string {
+	if len(line) <= maxSearchLineLength {
+		return line
+	}
+	cut := maxSearchLineLength
+	for cut > 0 && !utf8.RuneStart(line[cut]) {
+		cut--
+	}
+	return line[:cut] + "..."
+}
+
+// SearchTool creates a new search tool that searches file contents within the workspace directory
+func SearchTool() tool.Tool {
+	return NewSearchToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewSearchToolWithWorkspace creates a new search tool with a custom workspace directory
+func NewSearchToolWithWorkspace(workspaceDir string) tool.Tool {
+	return NewSearchToolWithConfig(workspaceDir, ToolConfig)
+}
+
+// NewSearchToolWithConfig creates a new search tool with a custom workspace directory and configuration
+func NewSearchToolWithConfig(workspaceDir string, config Config) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "search",
+			Description: "Search file contents in the workspace directory with a regular expression and return the matching lines with their line numbers and optional context lines. Filter files with a glob such as *.go. Use it to locate symbols without reading whole files. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input SearchInput) *SearchOutput {
+			observe := observeTool("search")
+			output, err := executeSearch(workspaceDir, config, input)
+			observe(err)
+			if err != nil {
+				return &SearchOutput{
+					Error: err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create search tool: %v", err))
+	}
+	return t
+}
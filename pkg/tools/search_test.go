@@ -0,0 +1,182 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSearchTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	files := map[string]string{
+		"calc.go":           "package calc\n\n// Add returns a + b\nfunc Add(a, b int) int {\n\treturn a + b\n}\n\nfunc Sub(a, b int) int {\n\treturn a - b\n}\n",
+		"calc_test.go":      "package calc\n\nfunc TestAdd(t *testing.T) {\n\tif Add(1, 2) != 3 {\n\t}\n}\n",
+		"pkg/util/util.go":  "package util\n\nfunc add(x int) int { return x + 1 }\n",
+		"README.md":         "# Calc\n\nUse Add to add numbers.\n",
+		"bin/calc":          "\x7fELF\x00\x00func Add",
+		".git/COMMIT_MSG":   "func Add",
+		"pkg/long/long.txt": "func Add " + strings.Repeat("é", 400) + "\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		input       SearchInput
+		want        []string
+		errContains string
+	}{
+		{
+			name:  "regex across the workspace",
+			input: SearchInput{Pattern: `func Add\b`},
+			want:  []string{"calc.go:4", "pkg/long/long.txt:1"},
+		},
+		{
+			name:  "glob filter",
+			input: SearchInput{Pattern: `Add`, Glob: "*.go"},
+			want:  []string{"calc.go:3", "calc.go:4", "calc_test.go:3", "calc_test.go:4"},
+		},
+		{
+			name:  "path glob filter",
+			input: SearchInput{Pattern: `Add`, Glob: "pkg/**/*.txt"},
+			want:  []string{"pkg/long/long.txt:1"},
+		},
+		{
+			name:  "ignore case in a directory",
+			input: SearchInput{Pattern: `func add`, Path: "pkg", IgnoreCase: true},
+			want:  []string{"pkg/long/long.txt:1", "pkg/util/util.go:3"},
+		},
+		{
+			name:  "single file",
+			input: SearchInput{Pattern: `^\treturn`, Path: "calc.go"},
+			want:  []string{"calc.go:5", "calc.go:9"},
+		},
+		{
+			name:  "no matches",
+			input: SearchInput{Pattern: `Multiply`},
+			want:  []string{},
+		},
+		{
+			name:        "missing pattern",
+			input:       SearchInput{},
+			errContains: "pattern is required",
+		},
+		{
+			name:        "invalid pattern",
+			input:       SearchInput{Pattern: `func (`},
+			errContains: "invalid pattern",
+		},
+		{
+			name:        "invalid glob",
+			input:       SearchInput{Pattern: `Add`, Glob: "[a-"},
+			errContains: "invalid glob",
+		},
+		{
+			name:        "path traversal",
+			input:       SearchInput{Pattern: `Add`, Path: "../"},
+			errContains: "path traversal detected",
+		},
+		{
+			name:        "missing path",
+			input:       SearchInput{Pattern: `Add`, Path: "missing"},
+			errContains: "failed to search missing",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := executeSearch(workspaceDir, ToolConfig, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("executeSearch() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("executeSearch() error = %v", err)
+			}
+			got := []string{}
+			for _, match := range output.Matches {
+				got = append(got, fmt.Sprintf("%s:%d", match.Path, match.Line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSearchTool_Context(t *testing.T) {
+	workspaceDir := t.TempDir()
+	content := "line 1\nline 2\nmatch 3\nline 4\nline 5\nline 6\nline 7\nline 8\nmatch 9\n"
+	if err := os.WriteFile(filepath.Join(workspaceDir, "a.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	output, err := executeSearch(workspaceDir, ToolConfig, SearchInput{Pattern: "^match", ContextLines: 2})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	want := []SearchMatch{
+		{Path: "a.txt", Line: 3, Text: "match 3", Before: []string{"line 1", "line 2"}, After: []string{"line 4", "line 5"}},
+		{Path: "a.txt", Line: 9, Text: "match 9", Before: []string{"line 7", "line 8"}},
+	}
+	if !reflect.DeepEqual(output.Matches, want) {
+		t.Errorf("matches = %+v, want %+v", output.Matches, want)
+	}
+	if output.FilesSearched != 1 {
+		t.Errorf("FilesSearched = %d, want 1", output.FilesSearched)
+	}
+
+	output, err = executeSearch(workspaceDir, ToolConfig, SearchInput{Pattern: "match 9", ContextLines: 100})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	if got := len(output.Matches[0].Before); got != MaxSearchContextLines {
+		t.Errorf("got %d context lines, want at most %d", got, MaxSearchContextLines)
+	}
+}
+
+func TestSearchTool_Limits(t *testing.T) {
+	workspaceDir := t.TempDir()
+	content := strings.Repeat("needle\n", MaxSearchMatches+10) + "needle " + strings.Repeat("é", 400) + "\n"
+	if err := os.WriteFile(filepath.Join(workspaceDir, "a.txt"), []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	output, err := executeSearch(workspaceDir, ToolConfig, SearchInput{Pattern: "needle"})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	if len(output.Matches) != MaxSearchMatches || !output.Truncated {
+		t.Errorf("got %d matches, truncated %v, want %d truncated", len(output.Matches), output.Truncated, MaxSearchMatches)
+	}
+
+	output, err = executeSearch(workspaceDir, ToolConfig, SearchInput{Pattern: "needle é"})
+	if err != nil {
+		t.Fatalf("executeSearch() error = %v", err)
+	}
+	text := output.Matches[0].Text
+	if len(text) > maxSearchLineLength+len("...") || !strings.HasSuffix(text, "é...") {
+		t.Errorf("long line = %q (%d bytes), want it cut after a whole character", text, len(text))
+	}
+}
+
+func TestSearchTool_ToolCreation(t *testing.T) {
+	if SearchTool() == nil {
+		t.Fatal("SearchTool() returned nil")
+	}
+	if got := NewSearchToolWithWorkspace(t.TempDir()).Name(); got != "search" {
+		t.Errorf("Name() = %q, want %q", got, "search")
+	}
+}
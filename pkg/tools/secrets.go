@@ -0,0 +1,85 @@
+package tools
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Secret scanning modes of Config.SecretScan
+const (
+	// SecretScanBlock rejects writes that contain a likely secret
+	SecretScanBlock = "block"
+	// SecretScanRedact replaces likely secrets with SecretRedaction and writes the rest
+	SecretScanRedact = "redact"
+)
+
+// SecretRedaction replaces the secrets in redacted writes
+const SecretRedaction = "[REDACTED]"
+
+// SecretPattern detects one kind of secret
+type SecretPattern struct {
+	// Name describes the secret in errors and logs, such as AWS access key
+	Name string
+	// Regexp matches the secret
+	Regexp *regexp.Regexp
+}
+
+// DefaultSecretPatterns are the secrets detected when Config.SecretPatterns is nil
+var DefaultSecretPatterns = []SecretPattern{
+	{Name: "AWS access key", Regexp: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "AWS secret key", Regexp: regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}`)},
+	{Name: "private key", Regexp: regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----[\s\S]*?(?:-----END (?:[A-Z]+ )*PRIVATE KEY(?: BLOCK)?-----|\z)`)},
+	{Name: "GitHub token", Regexp: regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{60,})\b`)},
+	{Name: "Slack token", Regexp: regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`)},
+	{Name: "Google API key", Regexp: regexp.MustCompile(`\bAIza[0-9A-Za-z_\-]{35}\b`)},
+	{Name: "Stripe key", Regexp: regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`)},
+	{Name: "API key", Regexp: regexp.MustCompile(`\bsk-(?:ant-|proj-)?[A-Za-z0-9_\-]{32,}`)},
+}
+
+// secretPatterns returns the secrets to detect
+func (c Config) secretPatterns() []SecretPattern {
+	if c.SecretPatterns != nil {
+		return c.SecretPatterns
+	}
+	return DefaultSecretPatterns
+}
+
+// scanSecrets applies SecretScan to content about to be written to path. It returns the
+// content with the secrets redacted and the kinds of secrets found, or an error when
+// writes with secrets are blocked. The secrets themselves are never logged.
+func (c Config) scanSecrets(path, content string) (string, []string, error) {
+	if c.SecretScan == "" {
+		return content, nil, nil
+	}
+	if c.SecretScan != SecretScanBlock && c.SecretScan != SecretScanRedact {
+		return "", nil, fmt.Errorf("unknown secret scan mode %q (use %s or %s)", c.SecretScan, SecretScanBlock, SecretScanRedact)
+	}
+
+	var found []string
+	for _, pattern := range c.secretPatterns() {
+		if !pattern.Regexp.MatchString(content) {
+			continue
+		}
+		found = append(found, pattern.Name)
+		if c.SecretScan == SecretScanRedact {
+			// Line breaks are kept, so line numbers of the rest of the content do not change
+			content = pattern.Regexp.ReplaceAllStringFunc(content, func(secret string) string {
+				return SecretRedaction + strings.Repeat("\n", strings.Count(secret, "\n"))
+			})
+		}
+	}
+	if len(found) == 0 {
+		return content, nil, nil
+	}
+
+	slog.Warn("Likely secrets in file content",
+		"path", path,
+		"secrets", found,
+		"mode", c.SecretScan)
+	if c.SecretScan == SecretScanBlock {
+		return "", nil, fmt.Errorf("content for %s contains a likely secret (%s); use a placeholder, such as a value read from an environment variable, instead", path, strings.Join(found, ", "))
+	}
+	return content, found, nil
+}
@@ -0,0 +1,109 @@
+package tools
+
+import (
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Fake secrets are assembled at run time so that secret scanners do not flag this file
+var (
+	fakeAWSKey      = "AKIA" + "Q3EGRXZ7TLVN4K2M"
+	fakeGitHubToken = "ghp_" + strings.Repeat("a1B2", 9)
+	fakePrivateKey  = "-----BEGIN RSA " + "PRIVATE KEY-----\nMIIEowIBAAKCAQEA\nx9s8\n-----END RSA " + "PRIVATE KEY-----"
+)
+
+func TestScanSecrets(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		content     string
+		want        string
+		wantFound   []string
+		errContains string
+	}{
+		{name: "clean", mode: SecretScanBlock, content: "key := os.Getenv(\"AWS_ACCESS_KEY_ID\")\n", want: "key := os.Getenv(\"AWS_ACCESS_KEY_ID\")\n"},
+		{name: "blocked", mode: SecretScanBlock, content: "key := \"" + fakeAWSKey + "\"\n", errContains: "contains a likely secret (AWS access key)"},
+		{name: "redacted", mode: SecretScanRedact, content: "key := \"" + fakeAWSKey + "\"\ntoken := \"" + fakeGitHubToken + "\"\n", want: "key := \"[REDACTED]\"\ntoken := \"[REDACTED]\"\n", wantFound: []string{"AWS access key", "GitHub token"}},
+		{name: "private key keeps lines", mode: SecretScanRedact, content: "const key = `" + fakePrivateKey + "`\nfunc main() {}\n", want: "const key = `[REDACTED]\n\n\n`\nfunc main() {}\n", wantFound: []string{"private key"}},
+		{name: "disabled", content: "key := \"" + fakeAWSKey + "\"\n", want: "key := \"" + fakeAWSKey + "\"\n"},
+		{name: "unknown mode", mode: "warn", content: "x", errContains: "unknown secret scan mode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found, err := Config{SecretScan: tt.mode}.scanSecrets("main.go", tt.content)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("scanSecrets() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("scanSecrets() error = %v", err)
+			}
+			if got != tt.want || !slices.Equal(found, tt.wantFound) {
+				t.Errorf("scanSecrets() = %q, %v, want %q, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestScanSecrets_FileTools(t *testing.T) {
+	workspaceDir := t.TempDir()
+	secret := "package config\n\nconst token = \"" + fakeGitHubToken + "\"\n"
+
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "config.go", Content: secret}); err == nil || !strings.Contains(err.Error(), "GitHub token") {
+		t.Errorf("executeFileWrite() error = %v, want a secret error", err)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, "config.go")); !os.IsNotExist(err) {
+		t.Errorf("blocked file was written: %v", err)
+	}
+
+	redact := Config{SecretScan: SecretScanRedact}
+	output, err := executeFileWrite(workspaceDir, redact, FileWriteInput{Path: "config.go", Content: secret})
+	if err != nil || !slices.Equal(output.Redacted, []string{"GitHub token"}) {
+		t.Fatalf("executeFileWrite() = %+v, %v, want the token redacted", output, err)
+	}
+	edit, err := executeFileEdit(workspaceDir, redact, FileEditInput{Path: "config.go", Mode: FileEditAppend, Content: "const key = \"" + fakeAWSKey + "\""})
+	if err != nil || !slices.Equal(edit.Redacted, []string{"AWS access key"}) {
+		t.Fatalf("executeFileEdit() = %+v, %v, want the key redacted", edit, err)
+	}
+	patch := "--- a/config.go\n+++ b/config.go\n@@ -3,2 +3,3 @@\n const token = \"[REDACTED]\"\n const key = \"[REDACTED]\"\n+const other = \"" + fakeGitHubToken + "\"\n"
+	if _, err := executeApplyPatch(workspaceDir, ToolConfig, PatchInput{Patch: patch}); err == nil || !strings.Contains(err.Error(), "GitHub token") {
+		t.Errorf("executeApplyPatch() error = %v, want a secret error", err)
+	}
+	patched, err := executeApplyPatch(workspaceDir, redact, PatchInput{Patch: patch})
+	if err != nil || !slices.Equal(patched.Redacted, []string{"GitHub token"}) {
+		t.Fatalf("executeApplyPatch() = %+v, %v, want the token redacted", patched, err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "config.go"))
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	want := "package config\n\nconst token = \"[REDACTED]\"\nconst key = \"[REDACTED]\"\nconst other = \"[REDACTED]\"\n"
+	if string(data) != want {
+		t.Errorf("file = %q, want %q", data, want)
+	}
+
+	// Base64 content is scanned after decoding, and binary content is never redacted
+	encoded := base64.StdEncoding.EncodeToString([]byte(secret))
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "secret.txt", Content: encoded, Encoding: EncodingBase64}); err == nil || !strings.Contains(err.Error(), "GitHub token") {
+		t.Errorf("executeFileWrite() of base64 content error = %v, want a secret error", err)
+	}
+	decoded, err := executeFileWrite(workspaceDir, redact, FileWriteInput{Path: "secret.txt", Content: encoded, Encoding: EncodingBase64})
+	if err != nil || !slices.Equal(decoded.Redacted, []string{"GitHub token"}) {
+		t.Fatalf("executeFileWrite() of base64 content = %+v, %v, want the token redacted", decoded, err)
+	}
+	binary := base64.StdEncoding.EncodeToString(append([]byte{0xff, 0xfe, 0x00}, fakeAWSKey...))
+	if _, err := executeFileWrite(workspaceDir, redact, FileWriteInput{Path: "image.png", Content: binary, Encoding: EncodingBase64}); err == nil || !strings.Contains(err.Error(), "AWS access key") {
+		t.Errorf("executeFileWrite() of binary content error = %v, want a secret error", err)
+	}
+	clean := base64.StdEncoding.EncodeToString([]byte{0x89, 'P', 'N', 'G', 0xff, 0x00})
+	if _, err := executeFileWrite(workspaceDir, ToolConfig, FileWriteInput{Path: "image.png", Content: clean, Encoding: EncodingBase64}); err != nil {
+		t.Errorf("executeFileWrite() of clean binary content error = %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package tools
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+
+	"google.golang.org/adk/tool"
+)
+
+// setTools maps the names of the tools in a Set to their constructors
+var setTools = map[string]func(workspaceDir string, config Config) tool.Tool{
+	"fileRead":    NewFileReadToolWithConfig,
+	"fileWrite":   NewFileWriteToolWithConfig,
+	"fileList":    NewFileListToolWithConfig,
+	"fileStat":    NewFileStatToolWithConfig,
+	"diff":        NewDiffToolWithConfig,
+	"count":       NewCountToolWithConfig,
+	"goSymbols":   NewGoSymbolsToolWithConfig,
+	"applyPatch":  NewApplyPatchToolWithConfig,
+	"fileEdit":    NewFileEditToolWithConfig,
+	"search":      NewSearchToolWithConfig,
+	"exec":        func(dir string, _ Config) tool.Tool { return NewExecToolWithWorkspace(dir) },
+	"lint":        func(dir string, _ Config) tool.Tool { return NewLintToolWithWorkspace(dir) },
+	"goVet":       func(dir string, _ Config) tool.Tool { return NewGoVetToolWithWorkspace(dir) },
+	"goTest":      func(dir string, _ Config) tool.Tool { return NewGoTestToolWithWorkspace(dir) },
+	"goRace":      func(dir string, _ Config) tool.Tool { return NewGoRaceToolWithWorkspace(dir) },
+	"goMod":       func(dir string, _ Config) tool.Tool { return NewGoModToolWithWorkspace(dir) },
+	"goRename":    func(dir string, _ Config) tool.Tool { return NewGoRenameToolWithWorkspace(dir) },
+	"snapshot":    func(dir string, _ Config) tool.Tool { return NewSnapshotToolWithWorkspace(dir) },
+	"gitInit":     func(dir string, _ Config) tool.Tool { return NewGitInitToolWithWorkspace(dir) },
+	"gitStatus":   func(dir string, _ Config) tool.Tool { return NewGitStatusToolWithWorkspace(dir) },
+	"gitDiff":     func(dir string, _ Config) tool.Tool { return NewGitDiffToolWithWorkspace(dir) },
+	"gitAdd":      func(dir string, _ Config) tool.Tool { return NewGitAddToolWithWorkspace(dir) },
+	"gitCommit":   func(dir string, _ Config) tool.Tool { return NewGitCommitToolWithWorkspace(dir) },
+	"gitLog":      func(dir string, _ Config) tool.Tool { return NewGitLogToolWithWorkspace(dir) },
+	"archive":     func(dir string, _ Config) tool.Tool { return NewArchiveToolWithWorkspace(dir) },
+	"sql":         func(dir string, _ Config) tool.Tool { return NewSQLToolWithWorkspace(dir) },
+	"protoc":      func(dir string, _ Config) tool.Tool { return NewProtocToolWithWorkspace(dir) },
+	"oapiCodegen": func(dir string, _ Config) tool.Tool { return NewOapiCodegenToolWithWorkspace(dir) },
+	"scratchpad":  func(string, Config) tool.Tool { return ScratchpadTool() },
+	"env":         func(string, Config) tool.Tool { return EnvTool() },
+}
+
+// mutatingTools are the tools that change the workspace or run commands
+var mutatingTools = map[string]bool{
+	"fileWrite":   true,
+	"applyPatch":  true,
+	"fileEdit":    true,
+	"exec":        true,
+	"goTest":      true,
+	"goVet":       true,
+	"lint":        true,
+	"goRace":      true,
+	"goMod":       true,
+	"goRename":    true,
+	"snapshot":    true,
+	"gitInit":     true,
+	"gitAdd":      true,
+	"gitCommit":   true,
+	"archive":     true,
+	"protoc":      true,
+	"oapiCodegen": true,
+}
+
+// IsMutating reports whether the tool called name changes the workspace or runs commands,
+// so it is left out in read-only mode
+func IsMutating(name string) bool {
+	return mutatingTools[name]
+}
+
+// Set creates tools by name that share one workspace directory and configuration
+type Set struct {
+	workspaceDir string
+	config       Config
+}
+
+// NewSet creates a tool set for workspaceDir with config
+func NewSet(workspaceDir string, config Config) *Set {
+	return &Set{workspaceDir: workspaceDir, config: config}
+}
+
+// Names returns the names of the tools of the set in lexical order, without the mutating
+// tools when the set is read-only
+func (s *Set) Names() []string {
+	names := slices.Sorted(maps.Keys(setTools))
+	if s.config.ReadOnly {
+		names = slices.DeleteFunc(names, IsMutating)
+	}
+	return names
+}
+
+// Tool creates the tool called name
+func (s *Set) Tool(name string) (tool.Tool, error) {
+	newTool, ok := setTools[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown tool %q (known: %v)", name, s.Names())
+	}
+	if s.config.ReadOnly && IsMutating(name) {
+		return nil, fmt.Errorf("tool %q is disabled in read-only mode", name)
+	}
+	return newTool(s.workspaceDir, s.config), nil
+}
+
+// Tools creates the tools called names, or every tool of the set when names is empty
+func (s *Set) Tools(names ...string) ([]tool.Tool, error) {
+	if len(names) == 0 {
+		names = s.Names()
+	}
+	result := make([]tool.Tool, 0, len(names))
+	for _, name := range names {
+		t, err := s.Tool(name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
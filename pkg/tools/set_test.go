@@ -0,0 +1,48 @@
+package tools
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSet(t *testing.T) {
+	set := NewSet(t.TempDir(), Config{DeniedPaths: []string{".env"}})
+
+	all, err := set.Tools()
+	if err != nil {
+		t.Fatalf("Tools() error = %v", err)
+	}
+	if len(all) != len(set.Names()) {
+		t.Errorf("Tools() returned %d tools, want %d", len(all), len(set.Names()))
+	}
+	for i, name := range set.Names() {
+		if all[i].Name() != name {
+			t.Errorf("Tools()[%d].Name() = %q, want %q", i, all[i].Name(), name)
+		}
+	}
+
+	selected, err := set.Tools("fileRead", "search")
+	if err != nil || len(selected) != 2 || selected[0].Name() != "fileRead" || selected[1].Name() != "search" {
+		t.Errorf("Tools(fileRead, search) = %v, %v", selected, err)
+	}
+	if _, err := set.Tool("rm"); err == nil || !strings.Contains(err.Error(), `unknown tool "rm"`) {
+		t.Errorf("Tool(rm) error = %v, want an unknown tool error", err)
+	}
+}
+
+func TestSet_ReadOnly(t *testing.T) {
+	set := NewSet(t.TempDir(), Config{ReadOnly: true})
+	for _, name := range set.Names() {
+		if IsMutating(name) {
+			t.Errorf("Names() contains mutating tool %q", name)
+		}
+	}
+	for _, name := range []string{"fileWrite", "exec", "gitCommit"} {
+		if _, err := set.Tool(name); err == nil || !strings.Contains(err.Error(), "read-only mode") {
+			t.Errorf("Tool(%s) error = %v, want a read-only error", name, err)
+		}
+	}
+	if _, err := set.Tools("fileRead", "search", "gitDiff"); err != nil {
+		t.Errorf("Tools() of inspection tools error = %v", err)
+	}
+}
@@ -0,0 +1,377 @@
+package tools
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// MaxSnapshots is the maximum number of snapshots a snapshot tool keeps at a time
+const MaxSnapshots = 10
+
+// Actions of the snapshot tool
+const (
+	// SnapshotCreate takes a snapshot of the workspace, replacing one with the same name
+	SnapshotCreate = "create"
+	// SnapshotDiff lists the files changed since a snapshot
+	SnapshotDiff = "diff"
+	// SnapshotRestore restores the workspace to a snapshot
+	SnapshotRestore = "restore"
+	// SnapshotDelete deletes a snapshot
+	SnapshotDelete = "delete"
+	// SnapshotList lists the snapshots
+	SnapshotList = "list"
+)
+
+// Snapshot is a copy of a workspace at one point in time, which can be compared with the
+// workspace or restored into it. Git metadata and symbolic links are not part of it.
+type Snapshot struct {
+	// Dir is the directory holding the copy
+	Dir string
+	// WorkspaceDir is the workspace the snapshot was taken of
+	WorkspaceDir string
+	// CreatedAt is when the snapshot was taken
+	CreatedAt time.Time
+}
+
+// SnapshotChange describes a file that differs between a snapshot and the workspace
+type SnapshotChange struct {
+	// Path is the slash-separated path of the file, relative to the workspace
+	Path string `json:"path"`
+	// Status is created, modified, or deleted
+	Status string `json:"status"`
+}
+
+// NewSnapshot copies workspaceDir, creating it if needed, to dir, or to a new temporary
+// directory if dir is empty. Call Remove to delete the copy once it is no longer needed.
+func NewSnapshot(workspaceDir, dir string) (*Snapshot, error) {
+	if err := os.MkdirAll(workspaceDir, 0755); err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "agi-snapshot-*"); err != nil {
+			return nil, err
+		}
+	}
+	if err := CopyTree(workspaceDir, dir); err != nil {
+		_ = os.RemoveAll(dir)
+		return nil, err
+	}
+	return &Snapshot{Dir: dir, WorkspaceDir: workspaceDir, CreatedAt: time.Now().UTC()}, nil
+}
+
+// Diff returns the files of the workspace that were created, modified, or deleted since
+// the snapshot was taken, in lexical order
+func (s *Snapshot) Diff() ([]SnapshotChange, error) {
+	before, err := treeFiles(s.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshot: %w", err)
+	}
+	after, err := treeFiles(s.WorkspaceDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace: %w", err)
+	}
+
+	changes := []SnapshotChange{}
+	for path := range after {
+		size, ok := before[path]
+		if !ok {
+			changes = append(changes, SnapshotChange{Path: path, Status: PatchStatusCreated})
+			continue
+		}
+		same := size == after[path]
+		if same {
+			if same, err = sameFile(filepath.Join(s.Dir, path), filepath.Join(s.WorkspaceDir, path)); err != nil {
+				return nil, err
+			}
+		}
+		if !same {
+			changes = append(changes, SnapshotChange{Path: path, Status: PatchStatusModified})
+		}
+	}
+	for path := range before {
+		if _, ok := after[path]; !ok {
+			changes = append(changes, SnapshotChange{Path: path, Status: PatchStatusDeleted})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// Restore replaces the contents of the workspace, except its git metadata, with the
+// contents of the snapshot
+func (s *Snapshot) Restore() error {
+	entries, err := os.ReadDir(s.WorkspaceDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Name() == ".git" {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.WorkspaceDir, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return CopyTree(s.Dir, s.WorkspaceDir)
+}
+
+// Remove deletes the copy of the workspace
+func (s *Snapshot) Remove() error {
+	return os.RemoveAll(s.Dir)
+}
+
+// CopyTree copies the regular files and directories under src into dst, overwriting
+// existing files. Git metadata and symbolic links are skipped.
+func CopyTree(src, dst string) error {
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case d.IsDir() && d.Name() == ".git":
+			return filepath.SkipDir
+		case d.IsDir():
+			return os.MkdirAll(target, 0755)
+		case d.Type().IsRegular():
+			return copyFile(path, target)
+		default:
+			return nil
+		}
+	})
+}
+
+// copyFile copies the regular file src to dst, keeping its permissions
+func copyFile(src, dst string) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// treeFiles returns the sizes of the regular files under dir by slash-separated relative
+// path, skipping git metadata like CopyTree
+func treeFiles(dir string) (map[string]int64, error) {
+	files := make(map[string]int64)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = info.Size()
+		return nil
+	})
+	return files, err
+}
+
+// sameFile reports whether the files a and b have the same contents
+func sameFile(a, b string) (bool, error) {
+	dataA, err := os.ReadFile(a)
+	if err != nil {
+		return false, err
+	}
+	dataB, err := os.ReadFile(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(dataA, dataB), nil
+}
+
+// SnapshotInput defines the input parameters for the snapshot tool
+type SnapshotInput struct {
+	// Action is create, diff, restore, delete, or list
+	Action string `json:"action"`
+	// Name identifies the snapshot (defaults to "default")
+	Name string `json:"name,omitempty"`
+}
+
+// SnapshotInfo describes a snapshot kept by the snapshot tool
+type SnapshotInfo struct {
+	// Name identifies the snapshot
+	Name string `json:"name"`
+	// CreatedAt is when the snapshot was taken, in RFC 3339 format
+	CreatedAt string `json:"createdAt"`
+}
+
+// SnapshotOutput defines the output structure for the snapshot tool
+type SnapshotOutput struct {
+	// Name is the snapshot the action applied to
+	Name string `json:"name,omitempty"`
+	// Changes are the files changed since the snapshot, for diff and restore
+	Changes []SnapshotChange `json:"changes,omitempty"`
+	// Snapshots are the snapshots kept, for list
+	Snapshots []SnapshotInfo `json:"snapshots,omitempty"`
+	// Success indicates whether the action was successful
+	Success bool `json:"success"`
+	// Error contains the error message if the operation failed
+	Error string `json:"error,omitempty"`
+}
+
+// snapshotStore keeps the named snapshots of one workspace
+type snapshotStore struct {
+	workspaceDir string
+	mu           sync.Mutex
+	snapshots    map[string]*Snapshot
+}
+
+// newSnapshotStore creates an empty snapshot store for workspaceDir
+func newSnapshotStore(workspaceDir string) *snapshotStore {
+	return &snapshotStore{workspaceDir: workspaceDir, snapshots: make(map[string]*Snapshot)}
+}
+
+// execute is the core logic of the snapshot tool, extracted for testability
+func (s *snapshotStore) execute(input SnapshotInput) (*SnapshotOutput, error) {
+	start := time.Now()
+	name := input.Name
+	if name == "" {
+		name = "default"
+	}
+	slog.Info("Starting snapshot operation",
+		"action", input.Action,
+		"name", name,
+		"workspace", s.workspaceDir)
+
+	if !slices.Contains([]string{SnapshotCreate, SnapshotDiff, SnapshotRestore, SnapshotDelete, SnapshotList}, input.Action) {
+		return nil, fmt.Errorf("unknown action %q (use %s, %s, %s, %s, or %s)", input.Action,
+			SnapshotCreate, SnapshotDiff, SnapshotRestore, SnapshotDelete, SnapshotList)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	output := &SnapshotOutput{Name: name, Success: true}
+	snapshot := s.snapshots[name]
+	if snapshot == nil && input.Action != SnapshotCreate && input.Action != SnapshotList {
+		return nil, fmt.Errorf("snapshot %q not found", name)
+	}
+
+	switch input.Action {
+	case SnapshotCreate:
+		if snapshot == nil && len(s.snapshots) >= MaxSnapshots {
+			return nil, fmt.Errorf("too many snapshots: delete one of the %d snapshots first", MaxSnapshots)
+		}
+		created, err := NewSnapshot(s.workspaceDir, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to snapshot workspace: %w", err)
+		}
+		if snapshot != nil {
+			_ = snapshot.Remove()
+		}
+		s.snapshots[name] = created
+	case SnapshotDiff, SnapshotRestore:
+		changes, err := snapshot.Diff()
+		if err != nil {
+			return nil, err
+		}
+		output.Changes = changes
+		if input.Action == SnapshotRestore {
+			if err := snapshot.Restore(); err != nil {
+				return nil, fmt.Errorf("failed to restore snapshot %q: %w", name, err)
+			}
+		}
+	case SnapshotDelete:
+		delete(s.snapshots, name)
+		if err := snapshot.Remove(); err != nil {
+			return nil, fmt.Errorf("failed to delete snapshot %q: %w", name, err)
+		}
+	case SnapshotList:
+		output.Name = ""
+		for _, key := range slices.Sorted(maps.Keys(s.snapshots)) {
+			output.Snapshots = append(output.Snapshots, SnapshotInfo{
+				Name:      key,
+				CreatedAt: s.snapshots[key].CreatedAt.Format(time.RFC3339),
+			})
+		}
+	}
+
+	slog.Info("Snapshot operation completed successfully",
+		"action", input.Action,
+		"name", name,
+		"changes", len(output.Changes),
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// SnapshotTool creates a new snapshot tool for the default workspace directory
+func SnapshotTool() tool.Tool {
+	return NewSnapshotToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewSnapshotToolWithWorkspace creates a new snapshot tool with a custom workspace
+// directory. Each tool keeps its own snapshots, outside the workspace, for as long as
+// the process runs.
+func NewSnapshotToolWithWorkspace(workspaceDir string) tool.Tool {
+	store := newSnapshotStore(workspaceDir)
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "snapshot",
+			Description: "Save and restore the state of the workspace to experiment safely: action create takes a named snapshot, diff lists the files created, modified, or deleted since it, restore puts the workspace back as it was (undoing every change since), delete removes it, and list shows all snapshots. Take a snapshot before a risky change and restore it if the change does not work out.",
+		},
+		func(ctx tool.Context, input SnapshotInput) *SnapshotOutput {
+			observe := observeTool("snapshot")
+			output, err := store.execute(input)
+			observe(err)
+			if err != nil {
+				return &SnapshotOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create snapshot tool: %v", err))
+	}
+	return t
+}
@@ -0,0 +1,197 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates files with the given contents under dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		full := filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"calc.go":         "package calc\n",
+		"calc_test.go":    "package calc\n",
+		"pkg/util.go":     "package pkg\n",
+		"README.md":       "# Calc\n",
+		".git/HEAD":       "ref: refs/heads/main\n",
+		"pkg/sub/keep.go": "package sub\n",
+	})
+
+	snapshot, err := NewSnapshot(workspaceDir, "")
+	if err != nil {
+		t.Fatalf("NewSnapshot() error = %v", err)
+	}
+	defer snapshot.Remove()
+	if _, err := os.Stat(filepath.Join(snapshot.Dir, ".git")); !os.IsNotExist(err) {
+		t.Errorf("snapshot contains .git, want git metadata skipped")
+	}
+
+	changes, err := snapshot.Diff()
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("Diff() = %+v before any change, want none", changes)
+	}
+
+	writeFiles(t, workspaceDir, map[string]string{
+		"calc.go":    "package calc\n\nfunc Add(a, b int) int { return a + b }\n",
+		"README.md":  "# Calc!\n",
+		"new/new.go": "package new\n",
+		".git/HEAD":  "ref: refs/heads/fix\n",
+	})
+	if err := os.Remove(filepath.Join(workspaceDir, "pkg", "util.go")); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+
+	changes, err = snapshot.Diff()
+	if err != nil {
+		t.Fatalf("Diff() error = %v", err)
+	}
+	want := []SnapshotChange{
+		{Path: "README.md", Status: PatchStatusModified},
+		{Path: "calc.go", Status: PatchStatusModified},
+		{Path: "new/new.go", Status: PatchStatusCreated},
+		{Path: "pkg/util.go", Status: PatchStatusDeleted},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("Diff() = %+v, want %+v", changes, want)
+	}
+
+	if err := snapshot.Restore(); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if changes, _ := snapshot.Diff(); len(changes) != 0 {
+		t.Errorf("Diff() = %+v after Restore(), want none", changes)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, "new")); !os.IsNotExist(err) {
+		t.Errorf("new directory exists after Restore(), want it removed")
+	}
+	if data, _ := os.ReadFile(filepath.Join(workspaceDir, ".git", "HEAD")); string(data) != "ref: refs/heads/fix\n" {
+		t.Errorf(".git/HEAD = %q, want it untouched by Restore()", data)
+	}
+
+	if err := snapshot.Remove(); err != nil {
+		t.Fatalf("Remove() error = %v", err)
+	}
+	if _, err := os.Stat(snapshot.Dir); !os.IsNotExist(err) {
+		t.Errorf("snapshot directory exists after Remove()")
+	}
+}
+
+func TestSnapshotTool(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{"calc.go": "package calc\n"})
+	store := newSnapshotStore(workspaceDir)
+	defer func() {
+		for _, snapshot := range store.snapshots {
+			_ = snapshot.Remove()
+		}
+	}()
+
+	steps := []struct {
+		name        string
+		files       map[string]string
+		input       SnapshotInput
+		wantChanges []SnapshotChange
+		wantNames   []string
+		errContains string
+	}{
+		{name: "create", input: SnapshotInput{Action: SnapshotCreate}},
+		{
+			name:        "diff",
+			files:       map[string]string{"calc.go": "package broken\n", "extra.go": "package calc\n"},
+			input:       SnapshotInput{Action: SnapshotDiff},
+			wantChanges: []SnapshotChange{{Path: "calc.go", Status: PatchStatusModified}, {Path: "extra.go", Status: PatchStatusCreated}},
+		},
+		{name: "create a second snapshot", input: SnapshotInput{Action: SnapshotCreate, Name: "broken"}},
+		{name: "list", input: SnapshotInput{Action: SnapshotList}, wantNames: []string{"broken", "default"}},
+		{
+			name:        "restore",
+			input:       SnapshotInput{Action: SnapshotRestore, Name: "default"},
+			wantChanges: []SnapshotChange{{Path: "calc.go", Status: PatchStatusModified}, {Path: "extra.go", Status: PatchStatusCreated}},
+		},
+		{name: "diff after restore", input: SnapshotInput{Action: SnapshotDiff}, wantChanges: []SnapshotChange{}},
+		{name: "delete", input: SnapshotInput{Action: SnapshotDelete, Name: "broken"}},
+		{name: "diff a deleted snapshot", input: SnapshotInput{Action: SnapshotDiff, Name: "broken"}, errContains: `snapshot "broken" not found`},
+		{name: "unknown action", input: SnapshotInput{Action: "undo"}, errContains: `unknown action "undo"`},
+	}
+
+	for _, step := range steps {
+		writeFiles(t, workspaceDir, step.files)
+		output, err := store.execute(step.input)
+		if step.errContains != "" {
+			if err == nil || !strings.Contains(err.Error(), step.errContains) {
+				t.Fatalf("%s: execute() error = %v, want it to contain %q", step.name, err, step.errContains)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: execute() error = %v", step.name, err)
+		}
+		if !output.Success || !reflect.DeepEqual(output.Changes, step.wantChanges) {
+			t.Errorf("%s: execute() = %+v, want success with changes %+v", step.name, output, step.wantChanges)
+		}
+		var names []string
+		for _, info := range output.Snapshots {
+			names = append(names, info.Name)
+		}
+		if !reflect.DeepEqual(names, step.wantNames) {
+			t.Errorf("%s: snapshots = %v, want %v", step.name, names, step.wantNames)
+		}
+	}
+
+	data, err := os.ReadFile(filepath.Join(workspaceDir, "calc.go"))
+	if err != nil || string(data) != "package calc\n" {
+		t.Errorf("calc.go = %q (%v), want the restored content", data, err)
+	}
+	if _, err := os.Stat(filepath.Join(workspaceDir, "extra.go")); !os.IsNotExist(err) {
+		t.Errorf("extra.go exists, want it removed by the restore")
+	}
+}
+
+func TestSnapshotTool_Limit(t *testing.T) {
+	store := newSnapshotStore(t.TempDir())
+	defer func() {
+		for _, snapshot := range store.snapshots {
+			_ = snapshot.Remove()
+		}
+	}()
+
+	for i := range MaxSnapshots {
+		if _, err := store.execute(SnapshotInput{Action: SnapshotCreate, Name: string(rune('a' + i))}); err != nil {
+			t.Fatalf("execute() error = %v", err)
+		}
+	}
+	if _, err := store.execute(SnapshotInput{Action: SnapshotCreate, Name: "a"}); err != nil {
+		t.Errorf("replacing a snapshot at the limit failed: %v", err)
+	}
+	if _, err := store.execute(SnapshotInput{Action: SnapshotCreate, Name: "extra"}); err == nil || !strings.Contains(err.Error(), "too many snapshots") {
+		t.Errorf("execute() error = %v, want too many snapshots", err)
+	}
+}
+
+func TestSnapshotTool_ToolCreation(t *testing.T) {
+	if SnapshotTool() == nil {
+		t.Fatal("SnapshotTool() returned nil")
+	}
+	if got := NewSnapshotToolWithWorkspace(t.TempDir()).Name(); got != "snapshot" {
+		t.Errorf("Name() = %q, want %q", got, "snapshot")
+	}
+}
@@ -0,0 +1,235 @@
+package tools
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+
+	// Registers the pure Go sqlite driver with database/sql
+	_ "modernc.org/sqlite"
+)
+
+// SQLDriver is the database/sql driver of the sql tool. Drivers other than the built-in
+// sqlite must be registered by importing them.
+var SQLDriver = "sqlite"
+
+// SQLDataSource is the data source name of the sql tool. The default is a new in-memory
+// SQLite database for every call; point it at a test database to validate SQL for
+// another engine.
+var SQLDataSource = ":memory:"
+
+// SQLTimeout is the timeout of a sql tool call
+const SQLTimeout = 30 * time.Second
+
+// MaxSQLRows is the maximum number of rows returned by the query of the sql tool
+const MaxSQLRows = 100
+
+// SQLInput defines the input parameters for the sql tool
+type SQLInput struct {
+	// Files are the relative paths of the SQL files to execute in order; a directory stands
+	// for its .sql files in lexical order, without *.down.sql migrations
+	Files []string `json:"files"`
+	// Query is an optional statement run after the files, such as a SELECT that checks the schema
+	Query string `json:"query,omitempty"`
+}
+
+// SQLOutput defines the output structure for the sql tool
+type SQLOutput struct {
+	// Executed are the files that ran without errors, in order
+	Executed []string `json:"executed"`
+	// FailedFile is the file whose execution failed
+	FailedFile string `json:"failedFile,omitempty"`
+	// Columns are the column names of the query result
+	Columns []string `json:"columns,omitempty"`
+	// Rows are the rows of the query result, with NULL for null values
+	Rows [][]string `json:"rows,omitempty"`
+	// Truncated indicates that only the first MaxSQLRows rows are returned
+	Truncated bool `json:"truncated,omitempty"`
+	// SQLError is the database error of the failed file or query
+	SQLError string `json:"sqlError,omitempty"`
+	// Success indicates whether all files and the query ran without errors
+	Success bool `json:"success"`
+	// Error contains the error message if the operation could not be run
+	Error string `json:"error,omitempty"`
+}
+
+// sqlFiles resolves the files and directories of paths to the SQL files to execute
+func sqlFiles(workspaceDir string, paths []string) ([]string, error) {
+	var files []string
+	for _, path := range paths {
+		resolved, err := resolveWorkspacePath(workspaceDir, path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		info, err := os.Stat(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		entries, err := os.ReadDir(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.Type().IsRegular() && strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".down.sql") {
+				files = append(files, filepath.ToSlash(filepath.Join(path, name)))
+			}
+		}
+	}
+	return files, nil
+}
+
+// RunSQL executes SQL files of the workspace, such as a schema or migrations, and an
+// optional query against SQLDataSource. Everything runs in a transaction that is rolled
+// back, so a configured test database is left unchanged by engines with transactional DDL.
+// Database errors are reported in the output rather than returned.
+func RunSQL(ctx context.Context, workspaceDir string, input SQLInput) (*SQLOutput, error) {
+	start := time.Now()
+	slog.Info("Starting sql operation",
+		"files", input.Files,
+		"query", input.Query != "",
+		"driver", SQLDriver,
+		"workspace", workspaceDir)
+
+	if len(input.Files) == 0 && input.Query == "" {
+		return nil, fmt.Errorf("files or a query are required")
+	}
+	files, err := sqlFiles(workspaceDir, input.Files)
+	if err != nil {
+		return nil, err
+	}
+	if len(input.Files) > 0 && len(files) == 0 {
+		return nil, fmt.Errorf("no .sql files in %s", strings.Join(input.Files, ", "))
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, SQLTimeout)
+	defer cancel()
+	db, err := sql.Open(SQLDriver, SQLDataSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s database: %w", SQLDriver, err)
+	}
+	defer db.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s database: %w", SQLDriver, err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	output := &SQLOutput{Executed: []string{}}
+	for _, file := range files {
+		resolved, err := resolveWorkspacePath(workspaceDir, file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve path: %w", err)
+		}
+		data, err := os.ReadFile(resolved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if _, err := tx.ExecContext(ctx, string(data)); err != nil {
+			slog.Info("SQL file failed",
+				"file", file,
+				"error", err)
+			output.FailedFile = file
+			output.SQLError = err.Error()
+			return output, nil
+		}
+		output.Executed = append(output.Executed, file)
+	}
+
+	if input.Query != "" {
+		if err := querySQL(ctx, tx, input.Query, output); err != nil {
+			slog.Info("SQL query failed", "error", err)
+			output.SQLError = err.Error()
+			return output, nil
+		}
+	}
+
+	output.Success = true
+	slog.Info("SQL operation completed successfully",
+		"files", len(output.Executed),
+		"rows", len(output.Rows),
+		"duration_ms", time.Since(start).Milliseconds())
+	return output, nil
+}
+
+// querySQL runs query in tx and stores its columns and rows in output
+func querySQL(ctx context.Context, tx *sql.Tx, query string, output *SQLOutput) error {
+	rows, err := tx.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if output.Columns, err = rows.Columns(); err != nil {
+		return err
+	}
+	for rows.Next() {
+		if len(output.Rows) == MaxSQLRows {
+			output.Truncated = true
+			break
+		}
+		values := make([]any, len(output.Columns))
+		pointers := make([]any, len(values))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		row := make([]string, len(values))
+		for i, value := range values {
+			switch value := value.(type) {
+			case nil:
+				row[i] = "NULL"
+			case []byte:
+				row[i] = string(value)
+			default:
+				row[i] = fmt.Sprint(value)
+			}
+		}
+		output.Rows = append(output.Rows, row)
+	}
+	return rows.Err()
+}
+
+// SQLTool creates a new sql tool that executes SQL files of the workspace directory against an ephemeral database
+func SQLTool() tool.Tool {
+	return NewSQLToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewSQLToolWithWorkspace creates a new sql tool with a custom workspace directory
+func NewSQLToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "sql",
+			Description: "Execute SQL files of the workspace, such as a schema or a migrations directory, in order against a fresh database, then run an optional query such as a SELECT to check the result. Reports the file that failed and the database error, so schema and migration files can be validated. Nothing is kept between calls. All paths are relative to the workspace.",
+		},
+		func(ctx tool.Context, input SQLInput) *SQLOutput {
+			observe := observeTool("sql")
+			output, err := RunSQL(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &SQLOutput{
+					Success: false,
+					Error:   err.Error(),
+				}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create sql tool: %v", err))
+	}
+	return t
+}
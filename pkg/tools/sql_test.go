@@ -0,0 +1,99 @@
+package tools
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRunSQL(t *testing.T) {
+	ctx := context.Background()
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{
+		"migrations/0001_users.up.sql":   "CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL, email TEXT);\nCREATE UNIQUE INDEX users_name ON users (name);\n",
+		"migrations/0001_users.down.sql": "DROP TABLE users;\n",
+		"migrations/0002_seed.up.sql":    "INSERT INTO users (name) VALUES ('ada');\nINSERT INTO users (name, email) VALUES ('bob', 'bob@example.com');\n",
+		"migrations/README.md":           "Apply in order\n",
+		"broken.sql":                     "CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users);\nINSERT INTO orders (id, total) VALUES (1, 10);\n",
+	})
+
+	output, err := RunSQL(ctx, workspaceDir, SQLInput{
+		Files: []string{"migrations"},
+		Query: "SELECT name, email FROM users ORDER BY id",
+	})
+	if err != nil {
+		t.Fatalf("RunSQL() error = %v", err)
+	}
+	want := &SQLOutput{
+		Executed: []string{"migrations/0001_users.up.sql", "migrations/0002_seed.up.sql"},
+		Columns:  []string{"name", "email"},
+		Rows:     [][]string{{"ada", "NULL"}, {"bob", "bob@example.com"}},
+		Success:  true,
+	}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("RunSQL() = %+v, want %+v", output, want)
+	}
+
+	// Each call starts from an empty database
+	output, err = RunSQL(ctx, workspaceDir, SQLInput{Files: []string{"migrations", "broken.sql"}})
+	if err != nil {
+		t.Fatalf("RunSQL() error = %v", err)
+	}
+	if output.Success || output.FailedFile != "broken.sql" || !strings.Contains(output.SQLError, "total") || len(output.Executed) != 2 {
+		t.Errorf("RunSQL() = %+v, want broken.sql to fail on the missing total column", output)
+	}
+
+	output, err = RunSQL(ctx, workspaceDir, SQLInput{Query: "SELECT * FROM users"})
+	if err != nil {
+		t.Fatalf("RunSQL() error = %v", err)
+	}
+	if output.Success || !strings.Contains(output.SQLError, "no such table: users") {
+		t.Errorf("RunSQL() = %+v, want no such table in a new database", output)
+	}
+}
+
+func TestRunSQL_Truncated(t *testing.T) {
+	query := "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 500) SELECT i FROM n"
+	output, err := RunSQL(context.Background(), t.TempDir(), SQLInput{Query: query})
+	if err != nil {
+		t.Fatalf("RunSQL() error = %v", err)
+	}
+	if !output.Success || len(output.Rows) != MaxSQLRows || !output.Truncated || output.Rows[0][0] != "1" {
+		t.Errorf("RunSQL() returned %d rows, truncated %v, want %d truncated", len(output.Rows), output.Truncated, MaxSQLRows)
+	}
+}
+
+func TestRunSQL_InvalidInput(t *testing.T) {
+	workspaceDir := t.TempDir()
+	writeFiles(t, workspaceDir, map[string]string{"docs/README.md": "no SQL here\n"})
+
+	tests := []struct {
+		name        string
+		input       SQLInput
+		errContains string
+	}{
+		{name: "nothing to run", input: SQLInput{}, errContains: "files or a query are required"},
+		{name: "missing file", input: SQLInput{Files: []string{"schema.sql"}}, errContains: "failed to read schema.sql"},
+		{name: "directory without SQL", input: SQLInput{Files: []string{"docs"}}, errContains: "no .sql files in docs"},
+		{name: "path traversal", input: SQLInput{Files: []string{"../schema.sql"}}, errContains: "path traversal detected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := RunSQL(context.Background(), workspaceDir, tt.input)
+			if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+				t.Errorf("RunSQL() error = %v, want it to contain %q", err, tt.errContains)
+			}
+		})
+	}
+}
+
+func TestSQLTool_ToolCreation(t *testing.T) {
+	if SQLTool() == nil {
+		t.Fatal("SQLTool() returned nil")
+	}
+	if got := NewSQLToolWithWorkspace(t.TempDir()).Name(); got != "sql" {
+		t.Errorf("Name() = %q, want %q", got, "sql")
+	}
+}
@@ -0,0 +1,209 @@
+package tools
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"google.golang.org/adk/tool"
+	"google.golang.org/adk/tool/functiontool"
+)
+
+// VetLinter is the linter name of issues reported by the goVet tool
+const VetLinter = "govet"
+
+// VetTypeCheckRule is the rule of goVet issues for code that does not compile
+const VetTypeCheckRule = "typecheck"
+
+// vetAnalyzerName matches the names of go vet analyzers, which double as their enabling flags
+var vetAnalyzerName = regexp.MustCompile(`^[a-z]+$`)
+
+// GoVetInput defines the input parameters for the goVet tool
+type GoVetInput struct {
+	// Dir is the relative directory within the workspace to vet (defaults to the workspace root)
+	Dir string `json:"dir,omitempty"`
+	// Packages are the package patterns to vet (defaults to ./...)
+	Packages []string `json:"packages,omitempty"`
+	// Analyzers restricts the run to the named analyzers, such as printf or copylocks (defaults to all)
+	Analyzers []string `json:"analyzers,omitempty"`
+}
+
+// vetDiagnostic is the subset of a go vet -json diagnostic read by RunGoVet
+type vetDiagnostic struct {
+	Posn    string `json:"posn"`
+	Message string `json:"message"`
+}
+
+// RunGoVet runs `go vet -json` in the workspace directory and returns its findings with
+// the analyzer as rule. Code that does not compile is reported as typecheck issues.
+func RunGoVet(ctx context.Context, workspaceDir string, input GoVetInput) (*LintOutput, error) {
+	args := []string{"vet", "-json"}
+	for _, analyzer := range input.Analyzers {
+		if !vetAnalyzerName.MatchString(analyzer) {
+			return nil, fmt.Errorf("invalid analyzer name %q", analyzer)
+		}
+		args = append(args, "-"+analyzer)
+	}
+	packages := input.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	for _, pkg := range packages {
+		if strings.HasPrefix(pkg, "-") {
+			return nil, fmt.Errorf("invalid package pattern %q", pkg)
+		}
+	}
+	args = append(args, packages...)
+
+	result, err := RunCommand(ctx, workspaceDir, ExecInput{Command: "go", Args: args, Dir: input.Dir})
+	if err != nil {
+		return nil, err
+	}
+	// RunCommand has already checked that the directory resolves
+	dir, _ := resolveWorkspacePath(workspaceDir, cmp.Or(input.Dir, "."))
+
+	// The JSON reports go to standard output, compile errors to standard error
+	issues, err := parseVetOutput(result.Stdout+"\n"+result.Stderr, dir)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Success && len(issues) == 0 {
+		return nil, fmt.Errorf("go vet exited with code %d: %s", result.ExitCode, strings.TrimSpace(result.Stderr))
+	}
+	slog.Info("Vet completed", "dir", input.Dir, "issues", len(issues))
+	return &LintOutput{Issues: issues, Success: true}, nil
+}
+
+// parseVetOutput converts the output of `go vet -json` into lint issues with paths
+// relative to dir. The output interleaves "# package" headers, one JSON report per
+// package mapping analyzers to diagnostics, and "vet: file:line:col: message" lines for
+// packages that do not compile.
+func parseVetOutput(output, dir string) ([]LintIssue, error) {
+	issues := []LintIssue{}
+	var report strings.Builder
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case line == "{":
+			report.Reset()
+			report.WriteString(line)
+		case report.Len() > 0:
+			report.WriteString(line)
+			if line != "}" {
+				continue
+			}
+			var packages map[string]map[string]json.RawMessage
+			if err := json.Unmarshal([]byte(report.String()), &packages); err != nil {
+				return nil, fmt.Errorf("invalid go vet report: %w", err)
+			}
+			report.Reset()
+			for _, analyzers := range packages {
+				for analyzer, raw := range analyzers {
+					// Analyzers that fail report an error object instead of diagnostics
+					var diagnostics []vetDiagnostic
+					if json.Unmarshal(raw, &diagnostics) != nil {
+						continue
+					}
+					for _, d := range diagnostics {
+						issue := vetIssue(d.Posn, dir)
+						issue.Rule = analyzer
+						issue.Message = d.Message
+						issues = append(issues, issue)
+					}
+				}
+			}
+		case strings.HasPrefix(line, "vet: "):
+			posn, message, ok := strings.Cut(strings.TrimPrefix(line, "vet: "), ": ")
+			if !ok {
+				continue
+			}
+			issue := vetIssue(posn, dir)
+			if issue.Line == 0 {
+				continue
+			}
+			issue.Rule = VetTypeCheckRule
+			issue.Message = message
+			issues = append(issues, issue)
+		}
+	}
+	sortLintIssues(issues)
+	return issues, nil
+}
+
+// vetIssue returns a govet issue at posn, a file:line:column position, with the file made
+// relative to dir
+func vetIssue(posn, dir string) LintIssue {
+	issue := LintIssue{Linter: VetLinter, File: posn}
+	rest, col, ok := cutLastColon(posn)
+	if !ok {
+		return issue
+	}
+	file, line, ok := cutLastColon(rest)
+	if !ok {
+		// Positions without a column
+		file, line, col = rest, col, 0
+	}
+	if filepath.IsAbs(file) && dir != "" {
+		if rel, err := filepath.Rel(dir, file); err == nil {
+			file = rel
+		}
+	}
+	issue.File = filepath.ToSlash(filepath.Clean(file))
+	issue.Line = line
+	issue.Column = col
+	return issue
+}
+
+// cutLastColon splits s at its last colon when the part after it is a number
+func cutLastColon(s string) (string, int, bool) {
+	i := strings.LastIndex(s, ":")
+	if i < 0 {
+		return s, 0, false
+	}
+	n, err := strconv.Atoi(s[i+1:])
+	if err != nil {
+		return s, 0, false
+	}
+	return s[:i], n, true
+}
+
+// sortLintIssues orders issues by file and position
+func sortLintIssues(issues []LintIssue) {
+	slices.SortStableFunc(issues, func(a, b LintIssue) int {
+		return cmp.Or(strings.Compare(a.File, b.File), cmp.Compare(a.Line, b.Line), cmp.Compare(a.Column, b.Column), strings.Compare(a.Rule, b.Rule))
+	})
+}
+
+// GoVetTool creates a new goVet tool that runs go vet within the workspace directory
+func GoVetTool() tool.Tool {
+	return NewGoVetToolWithWorkspace(DefaultWorkspaceDir)
+}
+
+// NewGoVetToolWithWorkspace creates a new goVet tool with a custom workspace directory
+func NewGoVetToolWithWorkspace(workspaceDir string) tool.Tool {
+	t, err := functiontool.New(
+		functiontool.Config{
+			Name:        "goVet",
+			Description: "Run go vet in the workspace directory and return its findings, including compile errors, as structured issues with file, line, rule (the vet analyzer), and message.",
+		},
+		func(ctx tool.Context, input GoVetInput) *LintOutput {
+			observe := observeTool("goVet")
+			output, err := RunGoVet(ctx, workspaceDir, input)
+			observe(err)
+			if err != nil {
+				return &LintOutput{Error: err.Error()}
+			}
+			return output
+		},
+	)
+	if err != nil {
+		panic(fmt.Sprintf("failed to create goVet tool: %v", err))
+	}
+	return t
+}
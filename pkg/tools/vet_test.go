@@ -0,0 +1,169 @@
+package tools
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseVetOutput(t *testing.T) {
+	output := `# example.com/calc
+{
+	"example.com/calc": {
+		"printf": [
+			{
+				"posn": "/work/calc/calc.go:5:39",
+				"end": "/work/calc/calc.go:5:41",
+				"message": "fmt.Sprintf format %d has arg \"x\" of wrong type string"
+			}
+		],
+		"assign": [
+			{
+				"posn": "/work/calc/calc.go:3:17",
+				"message": "self-assignment of x",
+				"suggested_fixes": [{"message": "Remove self-assignment", "edits": []}]
+			}
+		],
+		"buildtag": {
+			"error": "analysis failed"
+		}
+	}
+}
+# example.com/calc/broken
+vet: broken/broken.go:3:23: cannot use "s" (untyped string constant) as int value in return statement
+vet: analysis skipped
+`
+	want := []LintIssue{
+		{Linter: VetLinter, Rule: VetTypeCheckRule, File: "broken/broken.go", Line: 3, Column: 23, Message: `cannot use "s" (untyped string constant) as int value in return statement`},
+		{Linter: VetLinter, Rule: "assign", File: "calc/calc.go", Line: 3, Column: 17, Message: "self-assignment of x"},
+		{Linter: VetLinter, Rule: "printf", File: "calc/calc.go", Line: 5, Column: 39, Message: `fmt.Sprintf format %d has arg "x" of wrong type string`},
+	}
+
+	got, err := parseVetOutput(output, "/work")
+	if err != nil {
+		t.Fatalf("parseVetOutput() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseVetOutput() = %+v, want %+v", got, want)
+	}
+
+	if got, err := parseVetOutput("", "/work"); err != nil || len(got) != 0 {
+		t.Errorf("parseVetOutput(\"\") = %v, %v, want no issues", got, err)
+	}
+	if _, err := parseVetOutput("{\n\t\"x\": [\n}\n", "/work"); err == nil {
+		t.Errorf("parseVetOutput() of an invalid report error = nil, want an error")
+	}
+}
+
+func TestVetIssue(t *testing.T) {
+	tests := []struct {
+		posn string
+		want LintIssue
+	}{
+		{posn: "/work/calc.go:7:2", want: LintIssue{Linter: VetLinter, File: "calc.go", Line: 7, Column: 2}},
+		{posn: "./calc.go:7", want: LintIssue{Linter: VetLinter, File: "calc.go", Line: 7}},
+		{posn: "/other/calc.go:7:2", want: LintIssue{Linter: VetLinter, File: "../other/calc.go", Line: 7, Column: 2}},
+		{posn: "-", want: LintIssue{Linter: VetLinter, File: "-"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.posn, func(t *testing.T) {
+			if got := vetIssue(tt.posn, "/work"); got != tt.want {
+				t.Errorf("vetIssue() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunGoVet(t *testing.T) {
+	workspaceDir := t.TempDir()
+	files := map[string]string{
+		"go.mod":        "module example.com/calc\n\ngo 1.22\n",
+		"calc/calc.go":  "package calc\n\nimport \"fmt\"\n\nfunc Format() string { return fmt.Sprintf(\"%d\", \"x\") }\n",
+		"clean/calc.go": "package clean\n\nfunc Add(a, b int) int { return a + b }\n",
+	}
+	for path, content := range files {
+		full := filepath.Join(workspaceDir, path)
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(full, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %v", path, err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		input       GoVetInput
+		want        []string
+		errContains string
+	}{
+		{
+			name:  "all packages",
+			input: GoVetInput{},
+			want:  []string{"calc/calc.go:5:44: fmt.Sprintf format %d has arg \"x\" of wrong type string (govet/printf)"},
+		},
+		{
+			name:  "directory",
+			input: GoVetInput{Dir: "calc", Analyzers: []string{"printf"}},
+			want:  []string{"calc.go:5:44: fmt.Sprintf format %d has arg \"x\" of wrong type string (govet/printf)"},
+		},
+		{
+			name:  "other analyzers",
+			input: GoVetInput{Analyzers: []string{"assign"}},
+		},
+		{
+			name:  "clean package",
+			input: GoVetInput{Packages: []string{"./clean"}},
+		},
+		{
+			name:        "missing package",
+			input:       GoVetInput{Packages: []string{"./missing"}},
+			errContains: "go vet exited with code 1",
+		},
+		{
+			name:        "flag as analyzer",
+			input:       GoVetInput{Analyzers: []string{"vettool=/bin/sh"}},
+			errContains: "invalid analyzer name",
+		},
+		{
+			name:        "flag as package",
+			input:       GoVetInput{Packages: []string{"-vettool=/bin/sh"}},
+			errContains: "invalid package pattern",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output, err := RunGoVet(context.Background(), workspaceDir, tt.input)
+			if tt.errContains != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errContains) {
+					t.Fatalf("RunGoVet() error = %v, want it to contain %q", err, tt.errContains)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("RunGoVet() error = %v", err)
+			}
+			var got []string
+			for _, issue := range output.Issues {
+				got = append(got, issue.String())
+			}
+			if !output.Success || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RunGoVet() issues = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGoVetTool(t *testing.T) {
+	if GoVetTool() == nil {
+		t.Fatal("GoVetTool() returned nil")
+	}
+	if got := NewGoVetToolWithWorkspace(t.TempDir()).Name(); got != "goVet" {
+		t.Errorf("Name() = %q, want %q", got, "goVet")
+	}
+}
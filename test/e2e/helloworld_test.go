@@ -2,16 +2,20 @@ package e2e_test
 
 import (
 	"context"
+	"os"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"com.github.dimetron.adk-go-agi/pkg/agents"
+	"com.github.dimetron.adk-go-agi/pkg/model/fake"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/model"
 	"google.golang.org/adk/model/gemini"
+	"google.golang.org/adk/runner"
+	"google.golang.org/adk/session"
 	"google.golang.org/genai"
 )
 
@@ -27,10 +31,14 @@ var _ = Describe("Hello World E2E Test", func() {
 		// Create context with timeout for test
 		ctx, cancel = context.WithTimeout(context.Background(), 5*time.Minute)
 
-		// Initialize the model
-		var err error
-		llmModel, err = gemini.NewModel(ctx, "gemini-2.5-flash", &genai.ClientConfig{})
-		Expect(err).NotTo(HaveOccurred(), "Failed to create Gemini model")
+		// Initialize the model; fall back to a scripted fake when no Gemini credentials are available
+		if os.Getenv("GOOGLE_API_KEY") == "" && os.Getenv("GEMINI_API_KEY") == "" {
+			llmModel = fake.New("fake-model")
+		} else {
+			var err error
+			llmModel, err = gemini.NewModel(ctx, "gemini-2.5-flash", &genai.ClientConfig{})
+			Expect(err).NotTo(HaveOccurred(), "Failed to create Gemini model")
+		}
 
 		// Register cleanup
 		DeferCleanup(func() {
@@ -112,4 +120,47 @@ var _ = Describe("Hello World E2E Test", func() {
 			Expect(pipelineAgent.Description()).To(Equal("A simple test agent for validation."))
 		}, SpecTimeout(10*time.Second))
 	})
+
+	Context("when running the pipeline with a scripted model", func() {
+		It("should execute every stage and populate the pipeline state", func(ctx SpecContext) {
+			scripted := fake.New("scripted-model",
+				fake.Text("## Architecture Overview\nHello world CLI."),
+				fake.Text("Created cmd/hello/main.go"),
+				fake.Text("Created cmd/hello/main_test.go"),
+				fake.Text("No major issues found. Code follows Go best practices."),
+			)
+
+			By("creating the pipeline with the scripted model")
+			pipeline, err := agents.NewCodePipelineAgent(agents.PipelineConfig{
+				Model:        scripted,
+				WorkspaceDir: GinkgoT().TempDir(),
+				SkipTests:    true,
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			sessionService := session.InMemoryService()
+			r, err := runner.New(runner.Config{AppName: "e2e", Agent: pipeline, SessionService: sessionService})
+			Expect(err).NotTo(HaveOccurred())
+
+			created, err := sessionService.Create(ctx, &session.CreateRequest{AppName: "e2e", UserID: "user"})
+			Expect(err).NotTo(HaveOccurred())
+
+			By("running the pipeline")
+			var authors []string
+			msg := genai.NewContentFromText("Write a hello world CLI", genai.RoleUser)
+			for event, err := range r.Run(ctx, "user", created.Session.ID(), msg, agent.RunConfig{}) {
+				Expect(err).NotTo(HaveOccurred())
+				authors = append(authors, event.Author)
+			}
+			Expect(authors).To(Equal([]string{"DesignAgent", "CodeWriterAgent", "DependencyAgent", "BuildAgent", "TDDExpertAgent", "CodeReviewerAgent"}))
+			Expect(scripted.Calls()).To(Equal(4))
+
+			By("verifying the review was stored in session state")
+			got, err := sessionService.Get(ctx, &session.GetRequest{AppName: "e2e", UserID: "user", SessionID: created.Session.ID()})
+			Expect(err).NotTo(HaveOccurred())
+			review, err := got.Session.State().Get("review_comments")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(review).To(ContainSubstring("No major issues"))
+		}, SpecTimeout(30*time.Second))
+	})
 })
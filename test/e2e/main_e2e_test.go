@@ -1,13 +1,17 @@
 package e2e_test
 
 import (
+	"archive/zip"
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -75,6 +79,7 @@ var _ = Describe("Main CLI E2E Test", func() {
 
 			// Build the command arguments
 			args := []string{
+				"serve",
 				"web",
 				"-port", fmt.Sprintf("%d", port),
 				"api",
@@ -147,6 +152,41 @@ var _ = Describe("Main CLI E2E Test", func() {
 			body, err := io.ReadAll(resp.Body)
 			Expect(err).NotTo(HaveOccurred(), "Failed to read list apps response")
 			GinkgoWriter.Printf("List apps response: %s\n", string(body))
+
+			// The other pipelines are served as apps besides the root agent
+			for _, app := range []string{"CodePipelineAgent", "BugFixPipeline", "ChatAgent"} {
+				Expect(string(body)).To(ContainSubstring(app), "%s should be listed in the apps", app)
+			}
+		}, SpecTimeout(10*time.Second))
+
+		It("should export a session with its transcript", func(ctx SpecContext) {
+			client := &http.Client{Timeout: 5 * time.Second}
+			sessionsURL := fmt.Sprintf("%s/api/apps/CodePipelineAgent/users/user/sessions", baseURL)
+			resp, err := client.Post(sessionsURL, "application/json", strings.NewReader("{}"))
+			Expect(err).NotTo(HaveOccurred(), "Create session request failed")
+			var created struct {
+				ID string `json:"id"`
+			}
+			err = json.NewDecoder(resp.Body).Decode(&created)
+			resp.Body.Close()
+			Expect(err).NotTo(HaveOccurred(), "Failed to decode the created session")
+			Expect(created.ID).NotTo(BeEmpty(), "Created session should have an ID")
+
+			resp, err = client.Get(fmt.Sprintf("%s/%s/export", sessionsURL, created.ID))
+			Expect(err).NotTo(HaveOccurred(), "Export request failed")
+			defer resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK), "Export should return the archive")
+			Expect(resp.Header.Get("Content-Type")).To(Equal("application/zip"))
+
+			body, err := io.ReadAll(resp.Body)
+			Expect(err).NotTo(HaveOccurred(), "Failed to read the export")
+			archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+			Expect(err).NotTo(HaveOccurred(), "Export should be a zip archive")
+			var names []string
+			for _, f := range archive.File {
+				names = append(names, f.Name)
+			}
+			Expect(names).To(ContainElement("transcript.md"))
 		}, SpecTimeout(10*time.Second))
 
 		It("should expose the API endpoint", func(ctx SpecContext) {